@@ -0,0 +1,186 @@
+// Package agent runs the tool calling loop of the LLM commands: the model works towards a goal with a toolset,
+// within a budget of steps. Commands compose a Runner with their own goal and tools, the LLM providers
+// only send the steps of the conversation.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// EmptyContent is sent for the empty content of the messages, as the APIs require a value
+const EmptyContent = "emptyContent"
+
+// DefaultMaxSteps is the number of requests to the model when the budget does not set it
+const DefaultMaxSteps = 10
+
+// Tool choices of a step
+const (
+	ChoiceRequired = "required" // The model has to call a tool
+	ChoiceAuto     = "auto"     // The model decides to call a tool or answer
+	ChoiceNone     = "none"     // The model has to answer
+)
+
+// Tool is the definition of a tool offered to the model
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  any // JSON schema of the arguments
+}
+
+// ToolChoice is the tool use required in a step, Tool forces a specific tool
+type ToolChoice struct {
+	Mode string
+	Tool string
+}
+
+// Toolset is the set of tools of a command
+type Toolset interface {
+	// Tools returns the tools offered to the model, only the finalizer tools when finalize is set
+	Tools(finalize bool) []Tool
+	// Call runs the tool with its JSON arguments
+	Call(name, arguments string) (string, error)
+	// Initializer returns the tool forced in the first step, empty if any tool can be called first
+	Initializer() string
+}
+
+// Step is a request to the model, the conversation so far with the tools it can call
+type Step struct {
+	Messages []common.SessionMessage
+	Tools    []Tool
+	Choice   ToolChoice
+}
+
+// Model sends the steps to an LLM provider
+type Model interface {
+	// Send sends the step and returns the reply of the model, with its tool calls
+	Send(ctx context.Context, step Step) (common.SessionMessage, error)
+}
+
+// Goal is the task of the agent
+type Goal struct {
+	SystemPrompt string
+	UserPrompt   string
+}
+
+// Budget limits the run of the agent
+type Budget struct {
+	MaxSteps    int // Requests to the model, the finalizer tools are forced in the last step with tools
+	StepTimeout int // API timeout of each step in seconds, capped by the total run timeout
+}
+
+// Result is the outcome of the run
+type Result struct {
+	Content   string                   // Final answer of the model
+	ToolCalls []common.SessionToolCall // Tool calls of the run, in order
+	Steps     int
+}
+
+// Runner runs the tool calling loop of a goal
+type Runner struct {
+	model  Model
+	tools  Toolset
+	budget Budget
+}
+
+// NewRunner creates a runner sending the steps to the model with the tools of the toolset
+func NewRunner(model Model, tools Toolset, budget Budget) *Runner {
+	if budget.MaxSteps <= 0 {
+		budget.MaxSteps = DefaultMaxSteps
+	}
+	return &Runner{model: model, tools: tools, budget: budget}
+}
+
+// Run sends the goal to the model and runs its tool calls until it answers. In the last step of the budget
+// only the finalizer tools are offered, after that the model has to answer without tools.
+func (r *Runner) Run(goal Goal) (Result, error) {
+	messages := []common.SessionMessage{
+		{Role: common.RoleSystem, Content: goal.SystemPrompt},
+		{Role: common.RoleUser, Content: goal.UserPrompt},
+	}
+	logger.Debug("System prompt: " + goal.SystemPrompt)
+	logger.Debug("User prompt: " + goal.UserPrompt)
+
+	choice := ToolChoice{Mode: ChoiceRequired, Tool: r.tools.Initializer()}
+	var result Result
+	for step := 1; ; step++ {
+		finalize := false
+		if step == r.budget.MaxSteps {
+			logger.Warn("Reaching the maximum number of steps, forcing the finalizer tools")
+			finalize = true
+			choice = ToolChoice{Mode: ChoiceRequired}
+		}
+		if step > r.budget.MaxSteps {
+			logger.Warn("Maximum number of steps reached, stopping further tool calls")
+			choice = ToolChoice{Mode: ChoiceNone}
+		}
+
+		common.Session().SetConversation(messages)
+		reply, err := r.send(Step{Messages: messages, Tools: r.tools.Tools(finalize), Choice: choice})
+		if err != nil {
+			return result, err
+		}
+		result.Steps = step
+		common.Session().SetConversation(append(messages, reply))
+
+		if len(reply.ToolCalls) == 0 || step > r.budget.MaxSteps {
+			result.Content = reply.Content
+			if result.Content == "" {
+				result.Content = EmptyContent
+			}
+			return result, nil
+		}
+
+		reply.Role = common.RoleAssistant
+		if reply.Content == "" {
+			reply.Content = EmptyContent
+		}
+		messages = append(messages, reply)
+		for _, toolCall := range reply.ToolCalls {
+			content, err := r.call(toolCall)
+			response := toolResponse(toolCall.ID, content, err)
+			common.Report().AddToolCall(toolCall.Name, toolCall.Arguments, response.Content)
+			messages = append(messages, response)
+		}
+		result.ToolCalls = append(result.ToolCalls, reply.ToolCalls...)
+		logger.Debugf("Sending the next step with %d messages", len(messages))
+		choice = ToolChoice{Mode: ChoiceAuto}
+	}
+}
+
+// send sends the step with the timeout of a single request
+func (r *Runner) send(step Step) (common.SessionMessage, error) {
+	ctx, cancel := common.RunTimeoutContext(r.budget.StepTimeout)
+	defer cancel()
+	return r.model.Send(ctx, step)
+}
+
+// call runs the tool call. A panic of the tool is converted into an error, so the model can react to it
+// instead of the run being aborted.
+func (r *Runner) call(toolCall common.SessionToolCall) (result string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.Errorf("Tool %s panicked: %v\n%s", toolCall.Name, recovered, debug.Stack())
+			result = ""
+			err = fmt.Errorf("tool %s failed unexpectedly: %v", toolCall.Name, recovered)
+		}
+	}()
+	return r.tools.Call(toolCall.Name, toolCall.Arguments)
+}
+
+// toolResponse creates the message with the result of the tool call, the error is sent to the model
+func toolResponse(toolCallID, content string, err error) common.SessionMessage {
+	if err != nil {
+		logger.Warnf("Tool call %s failed: %v", toolCallID, err)
+		content = fmt.Sprintf("Error: %v", err)
+	}
+	if content == "" {
+		logger.Warnf("Tool call %s returned empty content, using placeholder", toolCallID)
+		content = EmptyContent
+	}
+	return common.SessionMessage{Role: common.RoleTool, Content: content, ToolCallID: toolCallID}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// scriptedModel replies with the scripted tool calls in order, then with the answer
+type scriptedModel struct {
+	calls  [][]common.SessionToolCall
+	steps  []Step
+	answer string
+	err    error
+}
+
+func (m *scriptedModel) Send(ctx context.Context, step Step) (common.SessionMessage, error) {
+	m.steps = append(m.steps, step)
+	if m.err != nil {
+		return common.SessionMessage{}, m.err
+	}
+	if len(m.steps) <= len(m.calls) {
+		return common.SessionMessage{ToolCalls: m.calls[len(m.steps)-1]}, nil
+	}
+	return common.SessionMessage{Content: m.answer}, nil
+}
+
+type testTools struct {
+	called []string
+}
+
+func (t *testTools) Tools(finalize bool) []Tool {
+	if finalize {
+		return []Tool{{Name: "post"}}
+	}
+	return []Tool{{Name: "read"}, {Name: "post"}}
+}
+
+func (t *testTools) Call(name, arguments string) (string, error) {
+	t.called = append(t.called, name)
+	switch name {
+	case "read":
+		return "contents of " + arguments, nil
+	case "panic":
+		panic("boom")
+	}
+	return "", errors.New("unknown tool")
+}
+
+func (t *testTools) Initializer() string {
+	return "read"
+}
+
+func TestRunnerRun(t *testing.T) {
+	model := &scriptedModel{
+		calls: [][]common.SessionToolCall{
+			{{ID: "1", Name: "read", Arguments: "main.go"}},
+			{{ID: "2", Name: "panic"}, {ID: "3", Name: "missing"}},
+		},
+		answer: "done",
+	}
+	tools := &testTools{}
+	result, err := NewRunner(model, tools, Budget{}).Run(Goal{SystemPrompt: "system", UserPrompt: "review"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Content != "done" || result.Steps != 3 || len(result.ToolCalls) != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if choice := model.steps[0].Choice; choice.Mode != ChoiceRequired || choice.Tool != "read" {
+		t.Errorf("Expected the initializer to be forced first, got %+v", choice)
+	}
+	if choice := model.steps[1].Choice; choice.Mode != ChoiceAuto || choice.Tool != "" {
+		t.Errorf("Expected any tool after the first step, got %+v", choice)
+	}
+
+	last := model.steps[2].Messages
+	if len(last) != 7 {
+		t.Fatalf("Expected the system, user, 2 assistant and 3 tool messages, got %d", len(last))
+	}
+	if last[2].Role != common.RoleAssistant || last[2].Content != EmptyContent {
+		t.Errorf("Expected the assistant message with placeholder content, got %+v", last[2])
+	}
+	if last[3].Content != "contents of main.go" || last[3].ToolCallID != "1" {
+		t.Errorf("Unexpected tool result: %+v", last[3])
+	}
+	if !strings.Contains(last[5].Content, "failed unexpectedly: boom") {
+		t.Errorf("Expected the panic to be sent to the model, got %q", last[5].Content)
+	}
+	if last[6].Content != "Error: unknown tool" {
+		t.Errorf("Expected the error to be sent to the model, got %q", last[6].Content)
+	}
+}
+
+func TestRunnerBudget(t *testing.T) {
+	call := []common.SessionToolCall{{ID: "1", Name: "read"}}
+	model := &scriptedModel{calls: [][]common.SessionToolCall{call, call, call, call}}
+	result, err := NewRunner(model, &testTools{}, Budget{MaxSteps: 2}).Run(Goal{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(model.steps) != 3 || result.Steps != 3 {
+		t.Fatalf("Expected the run to stop after the budget, got %d steps", len(model.steps))
+	}
+	if step := model.steps[1]; step.Choice.Mode != ChoiceRequired || len(step.Tools) != 1 || step.Tools[0].Name != "post" {
+		t.Errorf("Expected only the finalizer tools in the last step, got %+v", step)
+	}
+	if step := model.steps[2]; step.Choice.Mode != ChoiceNone {
+		t.Errorf("Expected no tool calls over the budget, got %+v", step.Choice)
+	}
+	if result.Content != EmptyContent {
+		t.Errorf("Expected the placeholder content, got %q", result.Content)
+	}
+}
+
+func TestRunnerError(t *testing.T) {
+	model := &scriptedModel{err: errors.New("overloaded")}
+	if _, err := NewRunner(model, &testTools{}, Budget{}).Run(Goal{}); err == nil || err.Error() != "overloaded" {
+		t.Errorf("Expected the error of the model, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/llm"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the credentials of the LLM and code review providers",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var authCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Validate the credentials against the provider APIs",
+	Long: `Resolve the LLM API key and the code review provider tokens, and validate each of them against its API.
+Credentials are resolved from the provider variable (e.g. GITHUB_TOKEN), the generic BITRISE_AI_ variable
+(e.g. BITRISE_AI_GITHUB_TOKEN), the file set in the _FILE variable (e.g. GITHUB_TOKEN_FILE) and the
+.bitrise.secrets.yml file of the Bitrise CLI, in this order.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, _ := cmd.Flags().GetString("provider")
+		model, _ := cmd.Flags().GetString("model")
+		codeReviewerName, _ := cmd.Flags().GetString("code-review")
+
+		failed := 0
+
+		// The LLM API key of the provider is always required
+		if !checkCredential(llm.APIKeyCredential(provider), true, func() error {
+			llmClient, err := llm.NewLLM(provider, model)
+			if err != nil {
+				return err
+			}
+			return llmClient.CheckAuth()
+		}) {
+			failed++
+		}
+
+		// Check the selected code review provider, or every configured one
+		reviewers := []string{review.ProviderGitHub, review.ProviderBitbucket, review.ProviderGitea}
+		if codeReviewerName != "" {
+			reviewers = []string{codeReviewerName}
+		}
+		for _, reviewerName := range reviewers {
+			credential, err := review.ProviderCredential(reviewerName)
+			if err != nil {
+				logger.Errorf("✗ %v", err)
+				failed++
+				continue
+			}
+
+			if !checkCredential(credential, codeReviewerName != "", func() error {
+				reviewer, err := review.NewReviewer(reviewerName)
+				if err != nil {
+					return err
+				}
+				return reviewer.CheckAuth()
+			}) {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			errMsg := fmt.Sprintf("%d credentials are missing or invalid", failed)
+			logger.Error(errMsg)
+			return errors.New(errMsg)
+		}
+		return nil
+	},
+}
+
+// checkCredential resolves the credential and validates it, returns false if it is invalid or a required one is missing
+func checkCredential(credential common.Credential, required bool, validate func() error) bool {
+	_, source, err := credential.Resolve()
+	if err != nil {
+		if required {
+			logger.Errorf("✗ %v", err)
+			return false
+		}
+		logger.Infof("- %s: not configured", credential.Name)
+		return true
+	}
+
+	if err := validate(); err != nil {
+		logger.Errorf("✗ %s from %s: %v", credential.Name, source, err)
+		return false
+	}
+
+	logger.Infof("✓ %s from %s: valid", credential.Name, source)
+	return true
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authCheckCmd)
+
+	authCheckCmd.Flags().StringP("provider", "p", "openai", "LLM provider of the API key")
+	authCheckCmd.Flags().StringP("model", "m", "gpt-4.1", "LLM model to create the client with")
+	authCheckCmd.Flags().StringP("code-review", "r", "", "Code review provider to check, checks every configured provider if not set")
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/spf13/cobra"
+)
+
+var exportMetricsCmd = &cobra.Command{
+	Use:   "export-metrics",
+	Short: "Export review statistics for dashboards",
+	Long: `Aggregate the saved run reports into a CSV or JSON dataset with the findings per category,
+the acceptance rate of the posted comments and the average review latency per day.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, _ := cmd.Flags().GetString("input")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		if input == "" {
+			errMsg := "input directory of the run reports is required"
+			logger.Error(errMsg)
+			return errors.New(errMsg)
+		}
+
+		reports, err := common.LoadRunReports(input)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to load run reports: %v", err)
+			logger.Errorf(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		logger.Infof("Loaded %d run reports from %s", len(reports), input)
+
+		var writer io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				errMsg := fmt.Sprintf("Failed to create output file: %v", err)
+				logger.Errorf(errMsg)
+				return common.WrapError(errMsg, err)
+			}
+			defer file.Close()
+			writer = file
+		}
+
+		if err := common.AggregateMetrics(reports).Write(writer, format); err != nil {
+			errMsg := fmt.Sprintf("Failed to export metrics: %v", err)
+			logger.Errorf(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportMetricsCmd)
+	exportMetricsCmd.Flags().StringP("input", "i", os.Getenv("BITRISE_DEPLOY_DIR"), "Directory of the saved run reports, searched recursively")
+	exportMetricsCmd.Flags().StringP("format", "f", common.MetricsFormatCSV, "Output format (csv, json)")
+	exportMetricsCmd.Flags().StringP("output", "o", "", "Output file, prints to stdout if not set")
+}
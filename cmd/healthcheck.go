@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/llm"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"
+	"github.com/spf13/cobra"
+)
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "Check that the LLM and code review providers are available before the review",
+	Long: `Call the LLM API and the code review provider API once each, and report whether they are available.
+Use it as a pre-flight step of the workflow to fail fast during a provider outage, instead of spending minutes in retries.
+With --repo and --pr the permissions of the code review provider token on the pull request are checked too.
+Exits with 40 if the LLM provider is not available, 30 if the code review provider is not available,
+20 if the token is missing a permission required to post the review.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, _ := cmd.Flags().GetString("provider")
+		model, _ := cmd.Flags().GetString("model")
+		codeReviewerName, _ := cmd.Flags().GetString("code-review")
+
+		if err := checkProviders(provider, model, codeReviewerName); err != nil {
+			return err
+		}
+
+		repo, _ := cmd.Flags().GetString("repo")
+		pr, _ := cmd.Flags().GetInt("pr")
+		if codeReviewerName == "" || repo == "" || pr <= 0 {
+			return nil
+		}
+		return checkPermissions(codeReviewerName, repo, pr)
+	},
+}
+
+// checkPermissions logs the permissions of the code review provider token on the pull request, with how to grant the missing ones
+func checkPermissions(codeReviewerName, repo string, pr int) error {
+	repoOwner, repoName, found := strings.Cut(repo, "/")
+	if !found {
+		return common.NewConfigError(errors.New("repository must be in the format 'owner/repo'"))
+	}
+	reviewer, err := review.NewReviewer(codeReviewerName)
+	if err != nil {
+		return common.NewConfigError(err)
+	}
+	access, err := reviewer.CheckWriteAccess(repoOwner, repoName, pr)
+	if err != nil {
+		logger.Errorf("✗ Permissions on %s#%d: %v", repo, pr, err)
+		return common.NewProviderError(err)
+	}
+
+	for _, permission := range access.Permissions {
+		switch {
+		case permission.Granted:
+			logger.Infof("✓ %s: granted", permission.Name)
+		case permission.Required:
+			logger.Errorf("✗ %s: missing, %s", permission.Name, permission.Remediation)
+		default:
+			logger.Warnf("- %s: missing, %s", permission.Name, permission.Remediation)
+		}
+	}
+	if access.MissingRequired() {
+		errMsg := "The token is missing the permissions to post the review"
+		logger.Error(errMsg)
+		return common.NewConfigError(errors.New(errMsg))
+	}
+	return nil
+}
+
+// checkProviders calls the LLM provider, and the code review provider if set, once each
+func checkProviders(provider, model, codeReviewerName string) error {
+	var failures []error
+	if err := checkHealth(fmt.Sprintf("LLM provider (%s)", provider), func() error {
+		llmClient, err := llm.NewLLM(provider, model)
+		if err != nil {
+			return common.NewConfigError(err)
+		}
+		if err := llmClient.CheckAuth(); err != nil {
+			return common.NewLLMError(err)
+		}
+		return nil
+	}); err != nil {
+		failures = append(failures, err)
+	}
+
+	if codeReviewerName != "" {
+		if err := checkHealth(fmt.Sprintf("Code review provider (%s)", codeReviewerName), func() error {
+			reviewer, err := review.NewReviewer(codeReviewerName)
+			if err != nil {
+				return common.NewConfigError(err)
+			}
+			if err := reviewer.CheckAuth(); err != nil {
+				return common.NewProviderError(err)
+			}
+			return nil
+		}); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	if len(failures) > 0 {
+		errMsg := fmt.Sprintf("%d providers are not available", len(failures))
+		logger.Error(errMsg)
+		return common.WrapError(errMsg, errors.Join(failures...))
+	}
+	return nil
+}
+
+// checkHealth calls the provider and logs whether it is available and how long the call took
+func checkHealth(name string, call func() error) error {
+	start := time.Now()
+	err := call()
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		logger.Errorf("✗ %s: not available after %s: %v", name, elapsed, err)
+		return err
+	}
+
+	logger.Infof("✓ %s: available (%s)", name, elapsed)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(healthcheckCmd)
+
+	healthcheckCmd.Flags().StringP("provider", "p", "openai", "LLM provider to check")
+	healthcheckCmd.Flags().StringP("model", "m", "gpt-4.1", "LLM model to create the client with")
+	healthcheckCmd.Flags().StringP("code-review", "r", "", "Code review provider to check (e.g. 'github'), only the LLM provider is checked if not set")
+	healthcheckCmd.Flags().String("repo", "", "Repository of the pull request to check the token permissions on, in the format 'owner/repo'")
+	healthcheckCmd.Flags().Int("pr", 0, "Pull request to check the token permissions on")
+}
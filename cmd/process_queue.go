@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/spf13/cobra"
+)
+
+var processQueueCmd = &cobra.Command{
+	Use:   "process-queue",
+	Short: "Run the reviews deferred because the LLM provider was overloaded",
+	Long: `Run the reviews queued by summarize --queue-file, from a scheduled workflow with a clone of the repository.
+Each queued commit is checked out and reviewed with the flags of its deferred run. Reviews still failing on an
+overloaded provider stay in the queue for the next run, reviews queued longer than --max-age are dropped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		queueFile, _ := cmd.Flags().GetString("queue-file")
+		maxAge, _ := cmd.Flags().GetDuration("max-age")
+		if queueFile == "" {
+			errMsg := "path of the queue file is required"
+			logger.Error(errMsg)
+			return common.NewConfigError(errors.New(errMsg))
+		}
+
+		queue, err := common.LoadReviewQueue(queueFile)
+		if err != nil {
+			logger.Error(err.Error())
+			return common.NewConfigError(err)
+		}
+		if len(queue.Reviews) == 0 {
+			logger.Info("No deferred reviews in the queue")
+			return nil
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			return common.WrapError("Failed to find the executable of the plugin", err)
+		}
+		client := git.NewClient(git.NewDefaultRunner("."))
+
+		var failed int
+		for _, queued := range append([]common.QueuedReview(nil), queue.Reviews...) {
+			if queued.Expired(maxAge) {
+				logger.Warnf("Dropping the review of %s, queued at %s", queued, common.FormatTimestamp(queued.QueuedAt))
+				queue.Remove(queued)
+				continue
+			}
+
+			logger.Infof("Running the deferred review of %s", queued)
+			deferred, err := runQueuedReview(executable, client, queued)
+			switch {
+			case err != nil:
+				logger.Errorf("The deferred review of %s failed: %v", queued, err)
+				queue.Remove(queued)
+				failed++
+			case deferred:
+				logger.Warnf("The LLM provider is still overloaded, keeping the review of %s in the queue", queued)
+				queue.Retried(queued)
+			default:
+				logger.Infof("The deferred review of %s is posted", queued)
+				queue.Remove(queued)
+			}
+			// Saved after each review, a timeout of the workflow must not rerun the posted ones
+			if err := queue.Save(queueFile); err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+		}
+
+		logger.Infof("%d deferred reviews left in the queue", len(queue.Reviews))
+		if failed > 0 {
+			return fmt.Errorf("%d deferred reviews failed", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(processQueueCmd)
+
+	processQueueCmd.Flags().String("queue-file", "", "JSON file of the deferred reviews, the --queue-file of the summarize command")
+	processQueueCmd.Flags().Duration("max-age", 72*time.Hour, "Drop the reviews queued longer than this, 0 keeps them until they run")
+}
+
+// runQueuedReview checks out the commit of the review and reruns the summarize command with its flags.
+// The rerun queues into a file of its own, a review queued there is deferred again.
+func runQueuedReview(executable string, client *git.Client, queued common.QueuedReview) (bool, error) {
+	if err := client.Checkout(queued.Commit); err != nil {
+		return false, err
+	}
+
+	retryQueue := filepath.Join(os.TempDir(), fmt.Sprintf("ai-reviewer-retry-%d.json", os.Getpid()))
+	defer os.Remove(retryQueue)
+
+	args := append([]string{"summarize", "--commit=" + queued.Commit, "--queue-file=" + retryQueue}, queued.Args...)
+	rerun := exec.Command(executable, args...)
+	rerun.Stdout = os.Stdout
+	rerun.Stderr = os.Stderr
+	if err := rerun.Run(); err != nil {
+		return false, err
+	}
+
+	retried, err := common.LoadReviewQueue(retryQueue)
+	if err != nil {
+		return false, err
+	}
+	return len(retried.Reviews) > 0, nil
+}
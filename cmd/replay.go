@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/llm"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay a saved review session for debugging",
+	Long: `Load a review session saved by summarize --session-dir, decrypted with the REVIEW_SESSION_KEY credential.
+Without flags the conversation with the LLM is printed, including the tool calls and their results.
+With --post the saved summary and line feedback are posted again, with --message the conversation
+is continued locally with a follow-up question, e.g. "Why did you flag the retry loop?".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessionPath, _ := cmd.Flags().GetString("session")
+		post, _ := cmd.Flags().GetBool("post")
+		message, _ := cmd.Flags().GetString("message")
+
+		if sessionPath == "" {
+			errMsg := "path of the saved session is required"
+			logger.Error(errMsg)
+			return errors.New(errMsg)
+		}
+
+		key, _, err := common.CredentialSession.Resolve()
+		if err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+
+		session, err := common.LoadSession(sessionPath, key)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to load session: %v", err)
+			logger.Errorf(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		logger.Infof("Loaded session of %s#%d with %d messages", session.Repository, session.PullRequest, len(session.Messages))
+
+		switch {
+		case post:
+			return replayPosting(cmd, session)
+		case message != "":
+			return continueSession(cmd, session, message)
+		}
+
+		fmt.Print(session.Transcript())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().String("session", "", "Path of the saved session file")
+	replayCmd.Flags().Bool("post", false, "Post the saved summary and line feedback again")
+	replayCmd.Flags().String("message", "", "Follow-up message to continue the conversation with")
+	replayCmd.Flags().StringP("code-review", "r", "", "Code review provider to post to, defaults to the provider of the session")
+	replayCmd.Flags().StringP("provider", "p", "", "LLM provider to continue with, defaults to the provider of the session")
+	replayCmd.Flags().StringP("model", "m", "", "LLM model to continue with, defaults to the model of the session")
+}
+
+// replayPosting re-runs the posting phase of the session, using the current checkout for the blame of the comments
+func replayPosting(cmd *cobra.Command, session *common.ReviewSession) error {
+	codeReviewerName, _ := cmd.Flags().GetString("code-review")
+	if codeReviewerName == "" {
+		codeReviewerName = session.CodeReview
+	}
+	if codeReviewerName == "" {
+		errMsg := "the session was not posted to a code review provider, set --code-review"
+		logger.Error(errMsg)
+		return errors.New(errMsg)
+	}
+
+	repoOwner, repoName, ok := strings.Cut(session.Repository, "/")
+	if !ok {
+		errMsg := fmt.Sprintf("invalid repository in the session: %s", session.Repository)
+		logger.Error(errMsg)
+		return errors.New(errMsg)
+	}
+
+	gitProvider, err := review.NewReviewer(codeReviewerName)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to create Client for Review Provider: %v", err)
+		logger.Errorf(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+
+	if session.Summary != "" {
+		if err := gitProvider.PostSummary(repoOwner, repoName, session.PullRequest, session.SummaryHeader, session.Summary); err != nil {
+			errMsg := fmt.Sprintf("Error posting summary: %v", err)
+			logger.Errorf(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		logger.Info("Summary posted")
+	}
+
+	if len(session.LineFeedback) > 0 {
+		lineLevel := common.LineLevelFeedback{Lines: common.GroupFindings(session.LineFeedback)}
+		client := git.NewClient(git.NewDefaultRunner("."))
+		if err := gitProvider.PostLineFeedback(client, repoOwner, repoName, session.PullRequest, session.CommitHash, lineLevel); err != nil {
+			errMsg := fmt.Sprintf("Error posting line feedback: %v", err)
+			logger.Errorf(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		logger.Infof("%d line feedback items posted", len(session.LineFeedback))
+	}
+	return nil
+}
+
+// continueSession sends the conversation with the follow-up message and prints the answer, nothing is posted
+func continueSession(cmd *cobra.Command, session *common.ReviewSession, message string) error {
+	provider, _ := cmd.Flags().GetString("provider")
+	model, _ := cmd.Flags().GetString("model")
+	if provider == "" {
+		provider = session.Provider
+	}
+	if model == "" {
+		model = session.Model
+	}
+
+	llmClient, err := llm.NewLLM(provider, model)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to create Client for LLM Provider: %v", err)
+		logger.Errorf(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+
+	messages := append(session.Messages, common.SessionMessage{Role: common.RoleUser, Content: message})
+	resp := llmClient.Continue(messages)
+	if resp.Error != nil {
+		errMsg := fmt.Sprintf("Error getting response from LLM: %v", resp.Error)
+		logger.Errorf(errMsg)
+		return common.WrapError(errMsg, resp.Error)
+	}
+
+	fmt.Println(resp.Content)
+	return nil
+}
@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
 	"github.com/spf13/cobra"
 )
 
 var (
 	logLevel string
+	caBundle string
 )
 
 var rootCmd = &cobra.Command{
@@ -14,8 +16,13 @@ var rootCmd = &cobra.Command{
 	Short: "Bitrise AI Reviewer - A plugin for code review using AI",
 	Long: `Bitrise AI Reviewer is a CLI plugin for the Bitrise CLI that helps review code changes using AI.
 It can analyze pull requests and provide feedback, suggestions, and potential issue detection.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		logger.SetLevel(logLevel)
+		common.LogProxySettings()
+		if err := common.SetCABundle(caBundle); err != nil {
+			return common.NewConfigError(err)
+		}
+		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
@@ -23,10 +30,21 @@ It can analyze pull requests and provide feedback, suggestions, and potential is
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	executedCmd, err := rootCmd.ExecuteC()
+	// The result is written for every review, including the ones failing on flags or settings
+	if executedCmd == summarizeCmd {
+		resultFile, _ := summarizeCmd.Flags().GetString("result-file")
+		saveRunResult(resultFile, err)
+	}
+	return err
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info",
 		"Set the logging level (debug, info, warn, error, dpanic, panic, fatal)")
+	rootCmd.PersistentFlags().StringVar(&caBundle, "ca-bundle", "",
+		"Path to a PEM encoded CA bundle to trust in addition to the system certificates")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return common.NewConfigError(err)
+	})
 }
@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/llm"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"
+	"github.com/spf13/cobra"
+)
+
+// Pull request of the self-test fixture
+const (
+	selftestRepoOwner = "bitrise-io"
+	selftestRepoName  = "selftest"
+	selftestPR        = 1
+)
+
+// selftestFiles are the files of the base and the head commit of the fixture repository
+var selftestFiles = []map[string]string{
+	{
+		// Small diffs get the quick review, the self-test runs the full one
+		"review.bitrise.yml": "quick_review:\n  max_changed_lines: 0\n",
+		"main.go":            "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"Hello\")\n}\n",
+	},
+	{
+		"main.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(divide(10, 2))\n}\n\nfunc divide(a, b int) int {\n\treturn a / b\n}\n",
+	},
+}
+
+// selftestFinding is the finding of the stubbed LLM, on line 10 of the head commit
+var selftestFinding = common.LineLevel{
+	File:     "main.go",
+	Line:     "\treturn a / b",
+	Category: common.CategoryBug,
+	Severity: common.SeverityHigh,
+	Title:    "Division by zero",
+	Body:     "divide panics if b is 0.",
+}
+
+const selftestFindingLine = 10
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Run the review pipeline against a fixture repository to verify the environment",
+	Long: `Create a temporary git repository with a synthetic change, and run the whole summarize pipeline on it
+with a stubbed LLM and an in-memory code review provider. Nothing is sent to the LLM or posted to a pull request.
+The self-test verifies git, the settings and the posting of the summary and the line feedback. With --online the
+credentials and the network access of the LLM provider, and the code review provider if set, are checked as well.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		online, _ := cmd.Flags().GetBool("online")
+		provider, _ := cmd.Flags().GetString("provider")
+		model, _ := cmd.Flags().GetString("model")
+		codeReviewerName, _ := cmd.Flags().GetString("code-review")
+
+		failed := 0
+		check := func(name string, err error) {
+			if err != nil {
+				logger.Errorf("✗ %s: %v", name, err)
+				failed++
+				return
+			}
+			logger.Infof("✓ %s", name)
+		}
+
+		repoPath, err := createSelftestRepo()
+		check("Fixture git repository", err)
+		if err != nil {
+			return common.WrapError("Self-test failed", err)
+		}
+		defer os.RemoveAll(repoPath)
+
+		reviewer := &selftestReviewer{}
+		check("Review pipeline", runSelftestPipeline(repoPath, reviewer))
+		check("Under review note posted", reviewer.checkUnderReview())
+		check("Summary posted", reviewer.checkSummary())
+		check("Line feedback posted", reviewer.checkLineFeedback())
+
+		if online {
+			check("Provider access", checkProviders(provider, model, codeReviewerName))
+		}
+
+		if failed > 0 {
+			errMsg := fmt.Sprintf("%d self-test checks failed", failed)
+			logger.Error(errMsg)
+			return errors.New(errMsg)
+		}
+		logger.Info("Self-test passed")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+
+	selftestCmd.Flags().Bool("online", false, "Also check the credentials and the network access of the providers")
+	selftestCmd.Flags().StringP("provider", "p", "openai", "LLM provider to check with --online")
+	selftestCmd.Flags().StringP("model", "m", "gpt-4.1", "LLM model to create the client with for --online")
+	selftestCmd.Flags().StringP("code-review", "r", "", "Code review provider to check with --online, only the LLM provider is checked if not set")
+}
+
+// createSelftestRepo creates the fixture repository, with the change on a branch off main
+func createSelftestRepo() (string, error) {
+	repoPath, err := os.MkdirTemp("", "ai-reviewer-selftest-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	run := func(args ...string) error {
+		command := exec.Command("git", append([]string{"-c", "user.name=Self-test", "-c", "user.email=selftest@bitrise.io", "-c", "commit.gpgsign=false"}, args...)...)
+		command.Dir = repoPath
+		if output, err := command.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+	commit := func(files map[string]string, message string) error {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0o644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+		if err := run("add", "-A"); err != nil {
+			return err
+		}
+		return run("commit", "-m", message)
+	}
+
+	steps := []func() error{
+		func() error { return run("init", "-q") },
+		func() error { return commit(selftestFiles[0], "Initial commit") },
+		func() error { return run("branch", "-M", "main") },
+		func() error { return run("checkout", "-q", "-b", "selftest") },
+		func() error { return commit(selftestFiles[1], "Add divide helper") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			os.RemoveAll(repoPath)
+			return "", err
+		}
+	}
+	return repoPath, nil
+}
+
+// runSelftestPipeline runs the summarize command in the fixture repository with the stubbed clients
+func runSelftestPipeline(repoPath string, reviewer *selftestReviewer) error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(repoPath); err != nil {
+		return err
+	}
+	defer os.Chdir(workingDir)
+
+	originalReviewer, originalLLM := newReviewer, newLLM
+	defer func() { newReviewer, newLLM = originalReviewer, originalLLM }()
+	newReviewer = func(providerName string, opts ...review.Option) (review.Reviewer, error) {
+		return reviewer, nil
+	}
+	newLLM = func(providerName, modelName string, opts ...llm.Option) (llm.LLM, error) {
+		return &selftestLLM{}, nil
+	}
+
+	flags := map[string]string{
+		"code-review": common.ProviderGitHub,
+		"repo":        selftestRepoOwner + "/" + selftestRepoName,
+		"pr":          fmt.Sprintf("%d", selftestPR),
+		"commit":      "HEAD",
+		"branch":      "main",
+		"report-dir":  "",
+		"audit-dir":   "",
+		"session-dir": "",
+	}
+	for name, value := range flags {
+		if err := summarizeCmd.Flags().Set(name, value); err != nil {
+			return err
+		}
+	}
+	return summarizeCmd.RunE(summarizeCmd, nil)
+}
+
+// selftestLLM is a stubbed LLM posting a fixed summary and finding
+type selftestLLM struct {
+	gitProvider  *review.Reviewer
+	settings     *common.Settings
+	sections     common.Summary
+	lineFeedback []common.LineLevel
+}
+
+func (l *selftestLLM) Prompt(req llm.Request) llm.Response {
+	if req.SystemPrompt == "" || !strings.Contains(req.UserPrompt, selftestRepoName) {
+		return llm.Response{Error: errors.New("the prompt is missing the details of the pull request")}
+	}
+	if l.gitProvider == nil || l.settings == nil {
+		return llm.Response{Error: errors.New("the git provider and the settings are not set")}
+	}
+
+	l.lineFeedback = []common.LineLevel{selftestFinding}
+
+	summary := l.sections
+	summary.Summary = "Adds a divide helper."
+	summary.Walkthrough = []common.Walkthrough{{Files: "main.go", Summary: "Adds the divide helper"}}
+	summary.Stats = common.SummaryStats{FilesChanged: 1, Findings: len(l.lineFeedback)}
+	provider := *l.gitProvider
+	if err := provider.PostSummary(selftestRepoOwner, selftestRepoName, selftestPR, summary.Header(), summary.String(provider.GetProvider(), *l.settings)); err != nil {
+		return llm.Response{Error: err}
+	}
+	return llm.Response{Content: "Review completed"}
+}
+
+func (l *selftestLLM) Complete(req llm.Request) llm.Response {
+	return llm.Response{Content: "OK"}
+}
+
+func (l *selftestLLM) Continue(messages []common.SessionMessage) llm.Response {
+	return llm.Response{Error: errors.New("not supported by the self-test")}
+}
+
+func (l *selftestLLM) SetGitProvider(gitProvider *review.Reviewer) { l.gitProvider = gitProvider }
+func (l *selftestLLM) SetSettings(settings *common.Settings)       { l.settings = settings }
+func (l *selftestLLM) SetSummarySections(sections common.Summary)  { l.sections = sections }
+func (l *selftestLLM) GetLineFeedback() []common.LineLevel         { return l.lineFeedback }
+func (l *selftestLLM) CheckAuth() error                            { return nil }
+
+// selftestReviewer is an in-memory code review provider recording the posted comments
+type selftestReviewer struct {
+	underReview  bool
+	summary      string
+	lineFeedback []common.LineLevel
+	comments     map[string]string // Marker comments by their header, like the review lock
+}
+
+func (r *selftestReviewer) GetProvider() string { return common.ProviderGitHub }
+
+func (r *selftestReviewer) Capabilities() common.Capabilities {
+	return common.CapabilitiesOf(common.ProviderGitHub)
+}
+
+func (r *selftestReviewer) GetPullRequestDetails(repoOwner, repoName string, pr int) (common.PullRequest, error) {
+	return common.PullRequest{
+		Number:     pr,
+		Title:      "Add divide helper",
+		HeadBranch: "selftest",
+		BaseBranch: "main",
+		Author:     "selftest",
+	}, nil
+}
+
+func (r *selftestReviewer) GetRepositoryURL(repoOwner, repoName string) string {
+	return fmt.Sprintf("https://github.com/%s/%s", repoOwner, repoName)
+}
+
+func (r *selftestReviewer) GetRepositoryFile(repoOwner, repoName, path string) (string, error) {
+	return "", errors.New("not supported by the self-test")
+}
+
+func (r *selftestReviewer) GetPullRequestDiff(repoOwner, repoName string, pr int) (string, error) {
+	return "", errors.New("not supported by the self-test")
+}
+
+func (r *selftestReviewer) GetCommentBody(repoOwner, repoName string, pr int, header string) (string, error) {
+	return strings.TrimSpace(strings.TrimPrefix(r.comments[header], header)), nil
+}
+
+func (r *selftestReviewer) PostSummaryUnderReview(repoOwner, repoName string, pr int, header string) error {
+	r.underReview = true
+	return nil
+}
+
+func (r *selftestReviewer) PostSummary(repoOwner, repoName string, pr int, header, body string) error {
+	r.summary = body
+	return nil
+}
+
+func (r *selftestReviewer) SaveComment(repoOwner, repoName string, pr int, header, body string) error {
+	if r.comments == nil {
+		r.comments = map[string]string{}
+	}
+	r.comments[header] = body
+	return nil
+}
+
+func (r *selftestReviewer) DeleteComment(repoOwner, repoName string, pr int, header string) error {
+	delete(r.comments, header)
+	return nil
+}
+
+func (r *selftestReviewer) PostLineFeedback(client *git.Client, repoOwner, repoName string, pr int, commitHash string, lineFeedback common.LineLevelFeedback) error {
+	r.lineFeedback = append(r.lineFeedback, lineFeedback.Lines...)
+	return nil
+}
+
+func (r *selftestReviewer) GetReviewRequestComments(repoOwner, repoName string, pr int) ([]common.LineLevel, error) {
+	return nil, nil
+}
+
+func (r *selftestReviewer) GetClarification(repoOwner, repoName string, pr int) (common.Clarification, error) {
+	return common.Clarification{}, nil
+}
+
+func (r *selftestReviewer) CheckAuth() error { return nil }
+
+func (r *selftestReviewer) SearchMergedPullRequests(repoOwner, repoName string, keywords []string) ([]common.HistoryPullRequest, error) {
+	return nil, nil
+}
+
+func (r *selftestReviewer) CheckWriteAccess(repoOwner, repoName string, pr int) (common.WriteAccess, error) {
+	return common.WriteAccess{CanWrite: true}, nil
+}
+
+func (r *selftestReviewer) SetCommitStatus(repoOwner, repoName, commitHash string, status common.CommitStatus) error {
+	return nil
+}
+
+func (r *selftestReviewer) GetForcePushes(repoOwner, repoName string, pr int) ([]common.ForcePush, error) {
+	return nil, nil
+}
+
+func (r *selftestReviewer) MigrateComments(client *git.Client, repoOwner, repoName string, pr int, commitHash string) (int, error) {
+	return 0, nil
+}
+
+func (r *selftestReviewer) checkUnderReview() error {
+	if !r.underReview {
+		return errors.New("the under review note was not posted")
+	}
+	return nil
+}
+
+func (r *selftestReviewer) checkSummary() error {
+	if !strings.HasPrefix(r.summary, common.Summary{}.Header()) || !strings.Contains(r.summary, "Adds a divide helper.") {
+		return fmt.Errorf("unexpected summary: %q", r.summary)
+	}
+	return nil
+}
+
+func (r *selftestReviewer) checkLineFeedback() error {
+	if len(r.lineFeedback) != 1 {
+		return fmt.Errorf("expected 1 finding, got %d", len(r.lineFeedback))
+	}
+	if line := r.lineFeedback[0].LineNumber; line != selftestFindingLine {
+		return fmt.Errorf("expected the finding on line %d of %s, got line %d", selftestFindingLine, selftestFinding.File, line)
+	}
+	return nil
+}
@@ -3,8 +3,11 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
@@ -13,19 +16,105 @@ import (
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/prompt"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// quickReviewTimeout is the API timeout of quick reviews in seconds
+const quickReviewTimeout = 30
+
+// maxKnowledgeExamples is the number of applied suggestions shared with the LLM as style examples
+const maxKnowledgeExamples = 5
+
+// newReviewer and newLLM create the clients of the review, the selftest command replaces them with stubs
+var (
+	newReviewer = review.NewReviewer
+	newLLM      = llm.NewLLM
 )
 
 var summarizeCmd = &cobra.Command{
 	Use:   "summarize",
 	Short: "Summarize code changes using AI",
 	Long:  `Analyze code changes and provide summary using AI capabilities.`,
-	RunE: func(cmd *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		logger.Info("Running AI code review...")
+		defer common.Report().Print()
+		if reportDir, _ := cmd.Flags().GetString("report-dir"); reportDir != "" {
+			defer saveRunReport(reportDir)
+		}
+		if sessionDir, _ := cmd.Flags().GetString("session-dir"); sessionDir != "" {
+			defer saveSession(sessionDir)
+		}
+		if auditDir, _ := cmd.Flags().GetString("audit-dir"); auditDir != "" {
+			defer saveAuditLog(auditDir)
+		}
 
 		// Parse settings from command line flags
-		settings := parseSettings()
+		settings := parseSettings(cmd)
 		logger.Debugf("Using settings: %+v", settings)
 
+		if err := validateToolSettings(settings.Tools, settings.CustomTools); err != nil {
+			errMsg := fmt.Sprintf("Invalid tool settings: %v", err)
+			logger.Errorf(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
+		}
+		if err := common.ValidatePersonas(settings.Personas); err != nil {
+			errMsg := fmt.Sprintf("Invalid personas: %v", err)
+			logger.Error(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
+		}
+		todoIssuePattern, err := common.CompileTODOIssuePattern(settings.TODOs.IssuePattern)
+		if err != nil {
+			errMsg := fmt.Sprintf("Invalid todos.issue_pattern: %v", err)
+			logger.Error(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
+		}
+		if failOn := settings.Reviews.FailOnSeverity; failOn != "" && !common.IsValidSeverity(failOn) {
+			errMsg := fmt.Sprintf("Invalid fail_on_severity %s, expected high, medium or low", failOn)
+			logger.Error(errMsg)
+			return common.NewConfigError(errors.New(errMsg))
+		}
+		if err := settings.Reviews.Quota.Validate(); err != nil {
+			errMsg := fmt.Sprintf("Invalid reviews.quota: %v", err)
+			logger.Error(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
+		}
+		if err := common.ValidateFormatters(settings.Formatters); err != nil {
+			errMsg := fmt.Sprintf("Invalid formatters: %v", err)
+			logger.Error(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
+		}
+
+		if err := common.SetTimezone(settings.Timezone); err != nil {
+			errMsg := fmt.Sprintf("Invalid timezone: %v", err)
+			logger.Error(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
+		}
+
+		diagnosticsFormat, _ := cmd.Flags().GetString("diagnostics-format")
+		if diagnosticsFormat != "" && !common.IsValidDiagnosticsFormat(diagnosticsFormat) {
+			errMsg := fmt.Sprintf("Invalid diagnostics format %s, expected rdjson or problem-matcher", diagnosticsFormat)
+			logger.Error(errMsg)
+			return common.NewConfigError(errors.New(errMsg))
+		}
+		// The logs are written to the standard output, the diagnostics would be mixed with them
+		if diagnosticsFile, _ := cmd.Flags().GetString("diagnostics-file"); diagnosticsFormat != "" && diagnosticsFile == "" {
+			errMsg := "The --diagnostics-file flag is required with --diagnostics-format"
+			logger.Error(errMsg)
+			return common.NewConfigError(errors.New(errMsg))
+		}
+
+		common.SetStyle(settings.Style)
+		common.SetIdentity(settings.Identity)
+		common.SetPseudonymizeAuthors(settings.Pseudonymize)
+		git.SetCommandTimeout(time.Duration(settings.Timeouts.GitCommand) * time.Second)
+		git.SetFileContentBudget(settings.Reviews.FileContentBudget)
+		if settings.Timeouts.TotalRun > 0 {
+			common.SetRunDeadline(time.Now().Add(time.Duration(settings.Timeouts.TotalRun) * time.Second))
+		}
+		if err := common.LoadSummaryTemplate(settings.Reviews.SummaryTemplate); err != nil {
+			logger.Warnf("%v, falling back to the default summary layout", err)
+		}
+
 		codeReviewerName, _ := cmd.Flags().GetString("code-review")
 		repo, _ := cmd.Flags().GetString("repo")
 		logger.Info("Code review provider:", codeReviewerName)
@@ -35,7 +124,7 @@ var summarizeCmd = &cobra.Command{
 		if len(repoTags) != 2 {
 			errMsg := "repository must be in the format 'owner/repo'"
 			logger.Error(errMsg)
-			return errors.New(errMsg)
+			return common.NewConfigError(errors.New(errMsg))
 		}
 		repoOwner := repoTags[0]
 		repoName := repoTags[1]
@@ -45,25 +134,123 @@ var summarizeCmd = &cobra.Command{
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to parse PR number: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
 		}
 		logger.Infof("Pull Request: %d", pr)
+		common.Report().SetPullRequest(repo, pr)
+
+		// Assign the prompt variant, the same pull request always gets the same variant
+		promptVariantName, _ := cmd.Flags().GetString("prompt-variant")
+		promptVariant, err := common.SelectPromptVariant(settings.PromptVariants, promptVariantName, fmt.Sprintf("%s#%d", repo, pr))
+		if err != nil {
+			logger.Errorf("Failed to select prompt variant: %v", err)
+			return common.NewConfigError(err)
+		}
+		if promptVariant.Name != "" {
+			logger.Infof("Prompt variant: %s", promptVariant.Name)
+			common.SetPromptVariant(promptVariant)
+			common.Report().SetPromptVariant(promptVariant.Name)
+		}
+
+		// Preflight: an invalid LLM key would only fail after the preparation of the review
+		if skipPreflight, _ := cmd.Flags().GetBool("skip-preflight"); !skipPreflight {
+			if err := checkLLMKey(cmd); err != nil {
+				if deferErr := deferReview(cmd, nil, codeReviewerName, repo, pr, err); deferErr == nil {
+					return nil
+				}
+				return err
+			}
+		}
 
 		var gitProvider review.Reviewer
+		var previousSummary common.SummaryState
 
 		if codeReviewerName != "" {
-			gitProvider, err = review.NewReviewer(codeReviewerName)
+			gitProvider, err = newReviewer(codeReviewerName, review.WithTimeout(settings.Timeouts.ProviderCall))
 			if err != nil {
 				errMsg := fmt.Sprintf("Failed to create Client for Review Provider: %v", err)
 				logger.Errorf(errMsg)
-				return errors.New(errMsg)
+				return common.NewConfigError(common.WrapError(errMsg, err))
+			}
+
+			// Preflight: posting with a token missing permissions would only fail at the end of the review.
+			// Fork pull request builds often get read-only tokens, their reviews are logged instead.
+			reportOnly, _ := cmd.Flags().GetBool("report-only")
+			access, err := gitProvider.CheckWriteAccess(repoOwner, repoName, pr)
+			if err != nil {
+				logger.Warnf("Failed to check the write access of the token, assuming it can post: %v", err)
+			} else {
+				if access.Fork {
+					logger.Infof("The pull request is opened from a fork")
+				}
+				for _, permission := range access.Missing() {
+					logger.Warnf("The token is missing the %s permission: %s", permission.Name, permission.Remediation)
+				}
+				switch {
+				case !access.MissingRequired() || reportOnly:
+				case access.Fork:
+					logger.Warnf("The review can't be posted, %s. Switching to report-only mode: the findings are logged instead.", access.Reason)
+					reportOnly = true
+				default:
+					errMsg := "The token is missing the permissions to post the review, grant them as above or pass --report-only"
+					logger.Error(errMsg)
+					return common.NewConfigError(errors.New(errMsg))
+				}
+			}
+			for key, value := range map[string]bool{common.OutputForkPR: access.Fork, common.OutputReportOnly: reportOnly} {
+				if err := common.ExportOutput(key, strconv.FormatBool(value)); err != nil {
+					logger.Warnf("%v", err)
+				}
+			}
+			if reportOnly {
+				gitProvider = review.NewReportOnly(gitProvider)
+			}
+			if common.Pseudonyms().Enabled() {
+				logger.Info("Pseudonymizing the authors in the prompts")
+				gitProvider = review.NewPseudonymized(gitProvider, common.Pseudonyms())
+			}
+
+			// Concurrent runs, like the ones of a push and a manual trigger, would post duplicate comments and race on the summary.
+			// The previous summary is read after waiting, the other run may have just reviewed the same commit.
+			if settings.Reviews.Lock.Minutes > 0 && !reportOnly {
+				holder := common.NewLockHolder(time.Duration(settings.Reviews.Lock.Minutes) * time.Minute)
+				acquired, err := review.AcquireReviewLock(gitProvider, repoOwner, repoName, pr, holder, time.Duration(settings.Reviews.Lock.WaitMinutes)*time.Minute)
+				switch {
+				case err != nil:
+					logger.Warnf("Failed to take the review lock, reviewing without it: %v", err)
+				case !acquired:
+					logger.Info("Skipped: another run is reviewing the pull request")
+					if settings.Reviews.CommitStatus {
+						setSkippedCommitStatus(cmd, gitProvider, repoOwner, repoName, "Review skipped, another run is reviewing")
+					}
+					return nil
+				default:
+					defer review.ReleaseReviewLock(gitProvider, repoOwner, repoName, pr, holder)
+				}
+			}
+
+			// Read the state of the previous summary before it is replaced with the under review note
+			previous, err := gitProvider.GetCommentBody(repoOwner, repoName, pr, common.Summary{}.Header())
+			if err != nil {
+				logger.Warnf("Failed to get the previous summary, regenerating all of its sections: %v", err)
+			}
+			previousSummary = common.ParseSummaryState(previous)
+
+			// Rapid pushes trigger a review each, the ones right after a completed review are skipped
+			debounce := time.Duration(settings.Reviews.DebounceMinutes) * time.Minute
+			if force, _ := cmd.Flags().GetBool("force"); !force && previousSummary.ReviewedWithin(debounce) {
+				logger.Infof("Skipped: recently reviewed at %s, within the debounce window of %d minutes", common.FormatTimestamp(previousSummary.ReviewedAt), settings.Reviews.DebounceMinutes)
+				if settings.Reviews.CommitStatus {
+					setSkippedCommitStatus(cmd, gitProvider, repoOwner, repoName, "Review skipped, recently reviewed")
+				}
+				return nil
 			}
 
 			err = gitProvider.PostSummaryUnderReview(repoOwner, repoName, pr, common.Summary{}.Header())
 			if err != nil {
 				errMsg := fmt.Sprintf("Error posting initial review: %v", err)
 				logger.Errorf(errMsg)
-				return errors.New(errMsg)
+				return common.NewProviderError(common.WrapError(errMsg, err))
 			}
 		}
 
@@ -71,70 +258,497 @@ var summarizeCmd = &cobra.Command{
 		commitHash, _ := cmd.Flags().GetString("commit")
 		targetBranch, _ := cmd.Flags().GetString("branch")
 
-		git := git.NewClient(git.NewDefaultRunner("."))
+		finishCollectStage := common.Report().StartStage("Collect changes")
+		runner := git.NewDefaultRunner(".")
+		git := git.NewClient(runner)
 
 		commitHash, err = git.GetCommitHash(commitHash)
 		if err != nil {
 			errMsg := fmt.Sprintf("Error getting commit hash: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
+		common.Session().SetPullRequest(codeReviewerName, repo, pr, commitHash)
+		common.Audit().SetPullRequest(codeReviewerName, repo, pr, commitHash)
 
+		// The verdict is set on the commit when the run ends, repositories can require it to pass before merging.
+		// Skipped and deferred runs set their own verdict instead of the one of the findings.
+		var lineFeedback []common.LineLevel
+		var verdict common.CommitStatus
+		if gitProvider != nil && settings.Reviews.CommitStatus {
+			setCommitStatus(gitProvider, repoOwner, repoName, commitHash, common.NewCommitStatus(common.CommitStatusPending, "The review is running"))
+			defer func() {
+				status := common.VerdictStatus(err, len(lineFeedback))
+				if verdict.State != "" && err == nil {
+					status = verdict
+				}
+				setCommitStatus(gitProvider, repoOwner, repoName, commitHash, status)
+			}()
+		}
+
+		// Minimal checkouts may lack the target branch or the parent commit, fetch the diff from the provider then
+		providerDiff := false
 		diff, err := git.GetDiff(commitHash, targetBranch)
+		if err != nil && gitProvider != nil {
+			logger.Warnf("Failed to get the diff from the git history, fetching it from %s: %v", codeReviewerName, err)
+			diff, err = gitProvider.GetPullRequestDiff(repoOwner, repoName, pr)
+			providerDiff = err == nil
+		}
 
 		if err != nil {
 			errMsg := fmt.Sprintf("Error getting diff with parent: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+
+		// Empty diffs, like the ones of merge commits, and whitespace-only changes are not sent to the LLM
+		noChanges := !common.HasReviewableChanges(diff)
+		if err := common.ExportOutput(common.OutputNoChanges, strconv.FormatBool(noChanges)); err != nil {
+			logger.Warnf("%v", err)
+		}
+		if noChanges {
+			finishCollectStage()
+			logger.Info("Skipped: the diff has no reviewable changes")
+			verdict = common.NewCommitStatus(common.CommitStatusSuccess, "No reviewable changes")
+			if gitProvider == nil {
+				return nil
+			}
+			err := postNoteSummary(gitProvider, repoOwner, repoName, pr, common.Summary{
+				Tracking: common.SummaryState{Commit: commitHash, Files: common.DiffFileHashes(diff)},
+				Previous: previousSummary,
+			}, common.NoChangesNote)
+			if err != nil {
+				errMsg := fmt.Sprintf("Error posting summary: %v", err)
+				logger.Errorf(errMsg)
+				return common.NewProviderError(common.WrapError(errMsg, err))
+			}
+			return nil
+		}
+
+		// Rebased and squashed pull requests have new commits for the reviewed code, the posted comments are moved to their code
+		rewritten := gitProvider != nil && historyRewritten(gitProvider, git, repoOwner, repoName, pr, previousSummary, commitHash)
+		if rewritten {
+			logger.Infof("The history of the pull request was rewritten since the review of %s, moving the outdated comments", previousSummary.Commit)
+			if _, err := gitProvider.MigrateComments(git, repoOwner, repoName, pr, commitHash); err != nil {
+				logger.Warnf("Failed to move the outdated comments: %v", err)
+			}
+		}
+
+		// The languages of the changes are shared with the LLM, and can activate their rule packs and formatters
+		languages := common.DetectLanguages(diff)
+		if len(languages.Languages) > 0 {
+			logger.Infof("Languages of the changes: %s", languages)
+			settings.Languages.Apply(&settings, languages)
+		}
+
+		// Suggestions applied since the previous run are recorded in the knowledge base of the repository
+		var knowledge *common.KnowledgeBase
+		if knowledgeFile, _ := cmd.Flags().GetString("knowledge-file"); knowledgeFile != "" {
+			knowledge, err = common.LoadKnowledgeBase(knowledgeFile, repo)
+			if err != nil {
+				logger.Warnf("Skipping the knowledge base: %v", err)
+			} else {
+				if applied := knowledge.DetectApplied(pr); applied > 0 {
+					logger.Infof("%d suggestions of the previous reviews were applied", applied)
+				}
+				defer saveKnowledgeBase(knowledge, knowledgeFile)
+			}
 		}
 
 		// Get the file contents
-		fileContent, err := git.GetFileContents(commitHash, targetBranch)
+		var fileContent string
+		if providerDiff {
+			fileContent, err = git.GetFileContentsOfFiles(commitHash, common.ChangedFiles(diff))
+		} else {
+			fileContent, err = git.GetFileContents(commitHash, targetBranch)
+		}
 		if err != nil {
 			errMsg := fmt.Sprintf("Error getting file contents: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+
+		finishCollectStage()
+
+		// Check the licenses of the added dependencies
+		var sections common.Summary
+		var complianceReport *common.ComplianceReport
+		if settings.Compliance.Enabled {
+			finishComplianceStage := common.Report().StartStage("Compliance scan")
+			complianceReport = common.ScanCompliance(diff, settings.Compliance)
+			finishComplianceStage()
+			logger.Infof("Compliance scan: %d added dependencies, %d violations",
+				len(complianceReport.Dependencies), len(complianceReport.Violations))
+			sections.Compliance = complianceReport.String()
+		}
+
+		// Diff the changed API contracts against the base of the pull request
+		baseRef := commitHash + "^"
+		if targetBranch != "" {
+			if mergeBase, err := git.GetMergeBase(commitHash, targetBranch); err == nil {
+				baseRef = mergeBase
+			}
+		}
+		contractAnalysis := common.AnalyzeContracts(git, baseRef, commitHash, diff)
+		if contractAnalysis != nil {
+			logger.Infof("API contract changes detected in %d files, breaking: %t", len(contractAnalysis.Files), contractAnalysis.Breaking())
+			sections.ContractChanges = contractAnalysis.String()
+		}
+
+		// Dependency updates get a specialized review flow
+		// The pull request details are also embedded in the prompt, saving the round trip of the tool call
+		dependencyUpdate := false
+		var prDetails common.PullRequest
+		if gitProvider != nil {
+			prDetails, err = gitProvider.GetPullRequestDetails(repoOwner, repoName, pr)
+			if err != nil {
+				logger.Warnf("Failed to get pull request details, skipping dependency update detection: %v", err)
+			} else if common.IsDependencyUpdate(prDetails) {
+				logger.Info("Dependency update pull request detected, reviewing the update impact")
+				dependencyUpdate = true
+			}
+
+			// Mention the author in the summary on providers not notifying them of new comments
+			if err == nil && gitProvider.Capabilities().MentionAuthor {
+				sections.Author = prDetails.AuthorID
+			}
+		}
+
+		// Pull requests targeting release branches are reviewed with the stricter policy of the branch
+		baseBranch := prDetails.BaseBranch
+		if baseBranch == "" {
+			baseBranch = targetBranch
+		}
+		branchPolicy := common.MatchBranchPolicy(settings.BranchPolicies, baseBranch)
+		// The checklist can only be checked with the description of the pull request
+		checklistRequired := branchPolicy != nil && branchPolicy.RequireChecklist && prDetails.Number > 0
+		var checklist common.Checklist
+		if branchPolicy != nil {
+			logger.Infof("Applying the branch policy of %s", baseBranch)
+			settings = settings.ApplyBranchPolicy(branchPolicy)
+		}
+		if checklistRequired {
+			checklist = common.ParseChecklist(prDetails.Body)
+			if branchPolicy.ReleaseRisk {
+				sections.ReleaseRisk = checklist.String()
+			}
+		}
+
+		// The author can skip the run, or request a full or a security-only review with a commit trailer or a label
+		trigger := common.ParseReviewTrigger(prDetails, commitHash)
+		switch trigger.Mode {
+		case common.ReviewTriggerSkip:
+			logger.Infof("Skipped: requested by %s", trigger.Source)
+			verdict = common.NewCommitStatus(common.CommitStatusSuccess, "Review skipped, requested by "+trigger.Source)
+			err := postNoteSummary(gitProvider, repoOwner, repoName, pr, common.Summary{
+				Tracking: common.SummaryState{Commit: commitHash, Files: common.DiffFileHashes(diff)},
+				Previous: previousSummary,
+			}, "Review skipped, requested by "+trigger.Source+".")
+			if err != nil {
+				errMsg := fmt.Sprintf("Error posting summary: %v", err)
+				logger.Errorf(errMsg)
+				return common.NewProviderError(common.WrapError(errMsg, err))
+			}
+			return nil
+		case common.ReviewTriggerFull:
+			logger.Infof("Full review with the assertive profile, requested by %s", trigger.Source)
+			settings.Reviews.Profile = common.ProfileAssertive
+		case common.ReviewTriggerSecurity:
+			logger.Infof("Security-only review, requested by %s", trigger.Source)
+		}
+
+		// Small pull requests get a time-boxed, diff-only review with a fast model
+		quick, _ := cmd.Flags().GetBool("quick")
+		if !quick && !dependencyUpdate && trigger.Mode != common.ReviewTriggerFull && settings.QuickReview.Applies(diff) {
+			logger.Infof("%d changed lines, running a quick review", common.CountChangedLines(diff))
+			quick = true
+		}
+
+		// Busy pull requests over the review quota get the quick review, bounding the cost of the reviews
+		quotaReached := false
+		force, _ := cmd.Flags().GetBool("force")
+		if !quick && !dependencyUpdate && !force && trigger.Mode != common.ReviewTriggerFull && settings.Reviews.Quota.Exceeded(previousSummary.FullReviews) {
+			logger.Infof("Review quota reached: %d full reviews in the last %s, running a quick review", len(settings.Reviews.Quota.Recent(previousSummary.FullReviews)), settings.Reviews.Quota.PeriodName())
+			quick = true
+			quotaReached = true
+		}
+
+		// Single-file and tiny pull requests get a short summary paragraph, the full layout would only repeat it
+		compactSummary := false
+		if !quick {
+			changedFiles, changedLines := len(common.ChangedFiles(diff)), common.CountChangedLines(diff)
+			if settings.Reviews.CompactSummary.Applies(changedFiles, changedLines) {
+				logger.Infof("%d changed files and %d changed lines, posting a compact summary", changedFiles, changedLines)
+				settings = settings.ApplyCompactSummary()
+				compactSummary = true
+			}
 		}
 
 		// Setup LLM client
 		provider, _ := cmd.Flags().GetString("provider")
 		model, _ := cmd.Flags().GetString("model")
+		llmOptions := []llm.Option{
+			llm.WithFallbacks(settings.ModelFallbacks),
+			llm.WithAPITimeout(settings.Timeouts.LLMCall),
+			llm.WithToolRoles(settings.Tools[common.CommandSummarize]),
+			llm.WithCustomTools(settings.CustomTools),
+		}
+		if quick {
+			// The model passed explicitly is kept, and so is the default one of the providers without a fast model
+			quickModel := settings.QuickReview.Model
+			if quickModel == "" {
+				quickModel = llm.QuickModel(provider)
+			}
+			if quickModel != "" && !cmd.Flags().Changed("model") {
+				model = quickModel
+			}
+			llmOptions = append(llmOptions, llm.WithAPITimeout(quickReviewTimeout))
+		} else {
+			llmOptions = append(llmOptions, modelOptions(cmd, settings.ModelOptions)...)
+		}
+		// Quick and secrets-free reviews only use the diff, the MCP servers are not started for them
+		if !quick && !settings.SecretsFree && len(settings.MCPServers) > 0 {
+			mcpClients, mcpTools := common.ConnectMCPServers(settings.MCPServers)
+			for _, client := range mcpClients {
+				defer client.Close()
+			}
+			llmOptions = append(llmOptions, llm.WithMCPTools(mcpTools))
+		}
+		common.Report().SetModel(model)
+		common.SetReviewModel(model)
+		common.Session().SetModel(provider, model)
+		common.Audit().SetModel(provider, model)
 
-		llmClient, err := llm.NewLLM(provider, model)
+		llmClient, err := newLLM(provider, model, llmOptions...)
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to create Client for LLM Provider: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.NewConfigError(common.WrapError(errMsg, err))
 		}
 
 		if gitProvider != nil {
 			llmClient.SetGitProvider(&gitProvider)
+			sections.Permalinks = common.Permalinks{
+				RepoURL:    gitProvider.GetRepositoryURL(repoOwner, repoName),
+				CommitHash: commitHash,
+			}
 		}
 		llmClient.SetSettings(&settings)
+		// Compare the app size of the base and head builds
+		baseArtifact, _ := cmd.Flags().GetString("base-artifact")
+		headArtifact, _ := cmd.Flags().GetString("head-artifact")
+		if baseArtifact != "" && headArtifact != "" {
+			appSize, err := common.CompareAppSize(baseArtifact, headArtifact, settings.AppSize)
+			if err != nil {
+				logger.Warnf("Skipping app size impact: %v", err)
+			} else {
+				logger.Infof("App size: %d -> %d bytes", appSize.BaseSize, appSize.HeadSize)
+				sections.AppSize = appSize.String()
+			}
+		}
 
-		// Setup the prompt
-		req := llm.Request{
-			SystemPrompt: prompt.GetSystemPrompt(settings),
-			UserPrompt:   prompt.GetSummarizePrompt(settings, repoOwner, repoName, prStr, commitHash, targetBranch),
+		// The walkthrough is ranked by the impact of the changes instead of the order of the LLM
+		sections.Impacts = common.AnalyzeImpact(diff)
+		sections.Languages = languages.String()
+		if knowledge != nil {
+			sections.Applied = knowledge.AppliedThisQuarter()
 		}
+		sections.Tracking = common.SummaryState{
+			Commit:      commitHash,
+			Files:       common.DiffFileHashes(diff),
+			Blobs:       common.DiffFileBlobs(diff),
+			FullReviews: settings.Reviews.Quota.Track(previousSummary.FullReviews, !quick),
+		}
+		if quotaReached {
+			sections.QuotaNote = settings.Reviews.Quota.Note()
+		}
+		sections.Previous = previousSummary
+
+		// Files of the areas with a reviewer persona are reviewed in the voice of the persona
+		personaMatches := common.MatchPersonas(diff, settings.Personas)
+		if len(personaMatches) > 0 {
+			logger.Infof("%d reviewer personas apply to the changes", len(personaMatches))
+			sections.Personas = common.PersonaSummary(personaMatches)
+		}
+
+		// Added TODO comments are listed in the summary, the ones without an issue get a nitpick
+		if settings.TODOs.Enabled {
+			sections.TODOs = common.FindTODOs(diff, todoIssuePattern)
+		}
+
+		// Images and localization files are analyzed statically, instead of sending them to the LLM
+		assetAnalysis := common.AnalyzeAssets(git, baseRef, commitHash, diff)
+		if assetAnalysis != nil {
+			logger.Infof("Asset changes detected: %d images, %d localization files", len(assetAnalysis.Images), len(assetAnalysis.LocalizationFiles))
+			sections.Assets = assetAnalysis.String()
+		}
+
+		// Formatting is checked with the local formatters, the LLM does not comment on it
+		var formatterResults []common.FormatterResult
+		if len(settings.Formatters) > 0 && !settings.SecretsFree {
+			formatterResults = common.RunFormatters(settings.Formatters, diff)
+			logger.Infof("%d of %d formatters found formatting issues", len(common.Unformatted(formatterResults)), len(formatterResults))
+			if gitProvider != nil {
+				postFormattingComment(gitProvider, repoOwner, repoName, pr, formatterResults)
+			}
+		}
+		llmClient.SetSummarySections(sections)
+
+		// Setup the prompt, the sections are trimmed by their priority if they don't fit the context window of the model
+		req := llm.Request{SystemPrompt: prompt.GetSystemPrompt(settings)}
+		userPrompt := common.NewPromptAssembler(common.PromptBudget(model, settings.ModelOptions.ContextWindow), req.SystemPrompt)
+		if dependencyUpdate {
+			userPrompt.Add("task", common.PromptPriorityInstructions, prompt.GetDependencyUpdatePrompt(settings, repoOwner, repoName, prStr, commitHash, targetBranch))
+		} else {
+			userPrompt.Add("task", common.PromptPriorityInstructions, prompt.GetSummarizePrompt(settings, repoOwner, repoName, prStr, commitHash, targetBranch))
+		}
+		// Secrets-free reviews only share the diff
+		if !settings.SecretsFree {
+			userPrompt.Add("pull request details", common.PromptPriorityPullRequest, prompt.GetPullRequestContextPrompt(prDetails))
+		}
+		userPrompt.Add("languages", common.PromptPriorityRepoContext, prompt.GetLanguagesPrompt(languages))
+
+		// CI configuration changes get a dedicated review section
+		if ciConfigAnalysis := common.AnalyzeCIConfig(git, commitHash, diff); ciConfigAnalysis != nil {
+			logger.Infof("CI configuration changes detected in: %s", strings.Join(ciConfigAnalysis.Files, ", "))
+			userPrompt.Add("CI configuration", common.PromptPriorityInstructions, prompt.GetCIConfigPrompt(ciConfigAnalysis))
+		}
+
+		// Infrastructure-as-code changes get a dedicated set of review rules
+		if iacChanges := common.DetectIaCChanges(diff); len(iacChanges.Tools()) > 0 {
+			logger.Infof("Infrastructure-as-code changes detected: %s", strings.Join(iacChanges.Tools(), ", "))
+			userPrompt.Add("infrastructure as code", common.PromptPriorityInstructions, prompt.GetIaCPrompt(iacChanges))
+		}
+
+		// Documentation outside of the diff can only be checked with access to the repository
+		if settings.Reviews.DocumentationDrift && !settings.SecretsFree {
+			userPrompt.Add("documentation references", common.PromptPriorityRepoContext, prompt.GetDocDriftPrompt(common.FindDocReferences(diff)))
+		}
+		userPrompt.Add("copy review", common.PromptPriorityInstructions, prompt.GetCopyReviewPrompt(settings))
+		userPrompt.Add("formatters", common.PromptPriorityRepoContext, prompt.GetFormattersPrompt(formatterResults))
+		if knowledge != nil {
+			userPrompt.Add("accepted suggestions", common.PromptPriorityRepoContext, prompt.GetAcceptedSuggestionsPrompt(knowledge.Examples(common.ChangedFiles(diff), maxKnowledgeExamples)))
+		}
+
+		if migrationFiles := common.ChangedMigrationFiles(diff); len(migrationFiles) > 0 {
+			logger.Infof("Database migrations detected in: %s", strings.Join(migrationFiles, ", "))
+			userPrompt.Add("migrations", common.PromptPriorityInstructions, prompt.GetMigrationPrompt(migrationFiles, common.AnalyzeMigrations(diff)))
+		}
+
+		if flagChanges := common.DetectFeatureFlags(diff, settings.FeatureFlags); !flagChanges.IsEmpty() {
+			logger.Infof("Feature flags changed: %d introduced, %d removed", len(flagChanges.Added), len(flagChanges.Removed))
+			var remainingUsages map[string]string
+			if !settings.SecretsFree {
+				remainingUsages = common.FindFlagUsages(git, commitHash, flagChanges.Removed)
+			}
+			userPrompt.Add("feature flags", common.PromptPriorityInstructions, prompt.GetFeatureFlagPrompt(settings, flagChanges, remainingUsages))
+		}
+
+		userPrompt.Add("API contracts", common.PromptPriorityRepoContext, prompt.GetContractPrompt(contractAnalysis))
+		userPrompt.Add("assets", common.PromptPriorityRepoContext, prompt.GetAssetPrompt(assetAnalysis))
+
+		if providerDiff {
+			// Mechanical changes of large diffs are replaced with descriptions, saving the tokens of refactors
+			promptDiff, condensed := common.CondenseDiff(diff, settings.Reviews.CondenseDiffLines)
+			if condensed > 0 {
+				logger.Infof("Condensed %d mechanical changes of the diff, from about %d to %d tokens", condensed, common.EstimateTokens(diff), common.EstimateTokens(promptDiff))
+			}
+			userPrompt.Add("diff", common.PromptPriorityDiff, prompt.GetProviderDiffPrompt(promptDiff))
+		}
+
+		userPrompt.Add("personas", common.PromptPriorityInstructions, prompt.GetPersonaPrompt(personaMatches))
+		userPrompt.Add("branch policy", common.PromptPriorityInstructions, prompt.GetBranchPolicyPrompt(branchPolicy, baseBranch))
+		if compactSummary {
+			userPrompt.Add("compact summary", common.PromptPriorityInstructions, prompt.GetCompactSummaryPrompt())
+		} else if cached := sections.CachedWalkthrough(); settings.Reviews.Walkthrough && len(cached) > 0 {
+			logger.Infof("Reusing the walkthrough of %d unchanged rows of the previous review", len(cached))
+			userPrompt.Add("cached walkthrough", common.PromptPriorityComments, prompt.GetCachedWalkthroughPrompt(cached))
+		}
+
+		if hotPathMatches := common.MatchHotPaths(diff, settings.HotPaths); len(hotPathMatches) > 0 {
+			logger.Infof("Changes touch %d hot paths, applying stricter performance review", len(hotPathMatches))
+			userPrompt.Add("hot paths", common.PromptPriorityInstructions, prompt.GetHotPathPrompt(hotPathMatches))
+		}
+
+		if trigger.Mode == common.ReviewTriggerSecurity {
+			userPrompt.Add("security review", common.PromptPriorityInstructions, prompt.GetSecurityOnlyPrompt())
+		}
+
+		// Answers to the questions of the previous review
+		if gitProvider != nil && settings.Reviews.ClarificationQuestions > 0 {
+			clarification, err := gitProvider.GetClarification(repoOwner, repoName, pr)
+			if err != nil {
+				logger.Warnf("Failed to get the answers to the clarification questions: %v", err)
+			} else if clarification.Answered() {
+				logger.Infof("The author answered the clarification questions with %d replies", len(clarification.Answers))
+				userPrompt.Add("clarification answers", common.PromptPriorityComments, prompt.GetClarificationPrompt(clarification))
+			}
+		}
+
+		req.UserPrompt = userPrompt.String()
 
 		// Send the prompt and get the response
-		resp := llmClient.Prompt(req)
+		finishReviewStage := common.Report().StartStage("LLM review")
+		var resp llm.Response
+		if quick {
+			resp, lineFeedback = quickReview(llmClient, gitProvider, settings, sections, repoOwner, repoName, pr, diff, common.PromptBudget(model, settings.ModelOptions.ContextWindow))
+		} else {
+			resp = llmClient.Prompt(req)
+			lineFeedback = llmClient.GetLineFeedback()
+		}
+		finishReviewStage()
+		if trigger.Mode == common.ReviewTriggerSecurity {
+			lineFeedback = common.SecurityFindings(lineFeedback)
+		} else {
+			lineFeedback = append(lineFeedback, common.TODOFindings(sections.TODOs)...)
+		}
+		for _, ll := range lineFeedback {
+			common.Report().AddFinding(ll.Category)
+		}
+
+		var llmErr error
 		if resp.Error != nil {
 			errMsg := fmt.Sprintf("Error getting response from LLM: %v", resp.Error)
+			if common.RunDeadlineExceeded() {
+				errMsg = fmt.Sprintf("The review ran out of the total run timeout of %d seconds: %v", settings.Timeouts.TotalRun, resp.Error)
+			}
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			llmErr = common.NewLLMError(common.WrapError(errMsg, resp.Error))
+
+			// Reviews failing on an overloaded provider are retried by the process-queue command instead of failing the build
+			if deferErr := deferReview(cmd, gitProvider, codeReviewerName, repo, pr, resp.Error); deferErr == nil {
+				verdict = common.NewCommitStatus(common.CommitStatusPending, "The review is queued, the LLM provider is overloaded")
+				return nil
+			}
+
+			// Still post the line feedback collected before the failure
+			if (codeReviewerName == "" && diagnosticsFormat == "") || len(lineFeedback) == 0 {
+				return llmErr
+			}
+			logger.Warnf("Posting %d line feedback items collected before the failure", len(lineFeedback))
 		}
 
 		logger.Debug("LLM Response:")
 		logger.Debug(resp.Content)
 
-		// Send to the review provider
-		if codeReviewerName != "" {
+		// Send to the review provider, or write the findings for other tools
+		if codeReviewerName != "" || diagnosticsFormat != "" {
+			defer common.Report().StartStage("Post feedback")()
+
 			lineLevel := common.LineLevelFeedback{
-				Lines: llmClient.GetLineFeedback(),
+				Lines:            lineFeedback,
+				HistoryRewritten: rewritten,
+			}
+			if dependencyUpdate {
+				// Dependency updates get a merge confidence verdict instead of nitpicks
+				lineLevel.Lines = lineLevel.GetLineFeedback()
 			}
 			for idx, ll := range lineLevel.Lines {
+				// File comments are posted without a line
+				if ll.IsFileLevel() {
+					continue
+				}
+
 				// Get the line numbers
 				lineNumber, err := common.GetLineNumber(ll.File, []byte(fileContent), []byte(diff), ll.FirstLine())
 				var lastLineNumber int
@@ -191,7 +805,10 @@ var summarizeCmd = &cobra.Command{
 					logger.Debug("Detected indentation for file '", ll.File, "': '", fileIndentation, "'")
 
 					// Get the file diff to check if the change is for the diff
-					fileDiff, err := git.GetDiffForFile(commitHash, ll.File)
+					fileDiff := common.GetFileDiff(diff, ll.File)
+					if !providerDiff {
+						fileDiff, err = git.GetDiffForFile(commitHash, ll.File)
+					}
 					if err != nil {
 						errMsg := fmt.Sprintf("Error getting diff for file '%s': %v", ll.File, err)
 						logger.Errorf(errMsg)
@@ -213,16 +830,78 @@ var summarizeCmd = &cobra.Command{
 				}
 			}
 
-			err = gitProvider.PostLineFeedback(git, repoOwner, repoName, pr, commitHash, lineLevel)
-			if err != nil {
-				errMsg := fmt.Sprintf("Error posting line feedback: %v", err)
-				logger.Errorf(errMsg)
-				return errors.New(errMsg)
+			// Literal credentials are never posted, authors often apply the suggestions without reading them
+			blockSecretSuggestions(git, commitHash, lineLevel.Lines)
+
+			// The syntax is always checked, the extra requests of the LLM check are opt-in
+			finishVerifyStage := common.Report().StartStage("Verify suggestions")
+			verifySuggestions(llmClient, git, commitHash, lineLevel.Lines, settings.Reviews.VerifySuggestions && !settings.SecretsFree)
+			finishVerifyStage()
+
+			// The diagnostics list every location of the findings, they are not grouped
+			if diagnosticsFormat != "" {
+				diagnosticsFile, _ := cmd.Flags().GetString("diagnostics-file")
+				diagnostics, err := common.FormatDiagnostics(lineLevel.Lines, diagnosticsFormat)
+				if err == nil {
+					err = common.WriteDiagnostics(diagnosticsFile, diagnostics)
+				}
+				if err != nil {
+					errMsg := fmt.Sprintf("Error writing the diagnostics: %v", err)
+					logger.Errorf(errMsg)
+					return common.WrapError(errMsg, err)
+				}
 			}
+			if codeReviewerName != "" {
+				// Related findings across files are posted as one comment with cross-references
+				common.Session().SetLineFeedback(lineLevel.Lines)
+				lineLevel.Lines = common.GroupFindings(lineLevel.Lines)
 
+				err = gitProvider.PostLineFeedback(git, repoOwner, repoName, pr, commitHash, lineLevel)
+				if err != nil {
+					errMsg := fmt.Sprintf("Error posting line feedback: %v", err)
+					logger.Errorf(errMsg)
+					return common.NewProviderError(common.WrapError(errMsg, err))
+				}
+				if knowledge != nil {
+					knowledge.RecordPosted(pr, lineLevel.Lines)
+				}
+			}
+
+			if llmErr != nil {
+				logger.Warn("Partial review posted")
+				return llmErr
+			}
 			logger.Info("Review posted successfully!")
 		}
 
+		if complianceReport.Failed() {
+			errMsg := fmt.Sprintf("%d added dependencies violate the license policy", len(complianceReport.Violations))
+			logger.Errorf(errMsg)
+			return &common.FindingsError{Count: len(complianceReport.Violations), Message: errMsg}
+		}
+
+		if failOn := settings.Reviews.FailOnSeverity; failOn != "" {
+			if count := common.CountFindingsAtOrAbove(lineFeedback, failOn); count > 0 {
+				errMsg := fmt.Sprintf("%d findings of %s severity or higher", count, failOn)
+				logger.Errorf(errMsg)
+				return &common.FindingsError{Count: count, Message: errMsg}
+			}
+		}
+
+		if branchPolicy != nil {
+			if count := branchPolicy.CountFindings(lineFeedback); count > 0 {
+				errMsg := fmt.Sprintf("%d findings of the %s categories on the %s branch", count, strings.Join(branchPolicy.FailOnCategories, ", "), baseBranch)
+				logger.Errorf(errMsg)
+				return &common.FindingsError{Count: count, Message: errMsg}
+			}
+		}
+
+		if checklistRequired && !checklist.Complete() {
+			errMsg := fmt.Sprintf("A complete checklist in the pull request description is required on the %s branch", baseBranch)
+			logger.Errorf(errMsg)
+			return &common.FindingsError{Count: max(checklist.Unchecked, 1), Message: errMsg}
+		}
+
 		return nil
 	},
 }
@@ -233,16 +912,431 @@ func init() {
 	// LLM
 	summarizeCmd.Flags().StringP("provider", "p", "openai", "LLM provider to use for summarization")
 	summarizeCmd.Flags().StringP("model", "m", "gpt-4.1", "LLM model to use for summarization")
+	summarizeCmd.Flags().Float64("temperature", 0, "Sampling temperature of the model, overrides model_options.temperature")
+	summarizeCmd.Flags().Float64("top-p", 0, "Nucleus sampling probability mass of the model, overrides model_options.top_p")
+	summarizeCmd.Flags().String("reasoning-effort", "", "Reasoning effort of OpenAI reasoning models: low, medium or high")
+	summarizeCmd.Flags().Int("max-thinking-tokens", 0, "Extended thinking budget of Anthropic models")
+	summarizeCmd.Flags().Bool("force", false, "Review even if the pull request was reviewed within reviews.debounce_minutes, or got the full reviews of reviews.quota")
+	summarizeCmd.Flags().Bool("report-only", false, "Run the review without posting it, the summary and the findings are logged")
+	summarizeCmd.Flags().Bool("skip-preflight", false, "Skip validating the LLM API key before the review")
+	summarizeCmd.Flags().Bool("quick", false, "Run a quick, diff-only review with a fast model, selected automatically for small diffs")
+	summarizeCmd.Flags().Bool("allow-repository-commands", false, "Run the commands configured in the review.bitrise.yml of the repository, by default only the ones of the shared configuration of REVIEW_CONFIG_URL")
 	// Git
 	summarizeCmd.Flags().StringP("commit", "c", "", "Analyze changes in the specified commit's perspective")
 	summarizeCmd.Flags().Lookup("commit").NoOptDefVal = "HEAD"
 	summarizeCmd.Flags().StringP("branch", "b", "", "Target Branch to merge with")
 	// Code Review
-	summarizeCmd.Flags().StringP("code-review", "r", "", "Code review provider to use (e.g., github, gitlab)")
+	summarizeCmd.Flags().StringP("code-review", "r", "", "Code review provider to use (e.g., github, bitbucket, gitea)")
 	summarizeCmd.Flags().StringP("repo", "", "", "Repository name in the format 'owner/repo' (e.g., 'my-org/my-repo')")
 	summarizeCmd.Flags().StringP("pr", "", "", "Pull Request number to post the review to")
+	summarizeCmd.Flags().String("prompt-variant", "", "Name of the prompt variant to use instead of the weighted assignment")
+	summarizeCmd.Flags().String("report-dir", os.Getenv("BITRISE_DEPLOY_DIR"), "Directory to save the run report to, for the export-metrics command")
+	summarizeCmd.Flags().String("session-dir", "", "Directory to save the encrypted review session to, for the replay command")
+	summarizeCmd.Flags().String("knowledge-file", "", "JSON file of the suggestions posted on and applied in the repository, cache it between the builds to track the applied suggestions")
+	summarizeCmd.Flags().String("queue-file", "", "JSON file to queue the review in instead of failing when the LLM provider is overloaded, cache it between the builds and run the process-queue command on a schedule")
+	summarizeCmd.Flags().String("audit-dir", os.Getenv("BITRISE_DEPLOY_DIR"), "Directory to save the posted summary and comments to as markdown and JSON, for the audit trail")
+	summarizeCmd.Flags().String("diagnostics-format", "", "Write the findings in this format for other tools: rdjson for reviewdog, or problem-matcher for CI annotations")
+	summarizeCmd.Flags().String("diagnostics-file", "", "Path to write the diagnostics to, required with --diagnostics-format")
+	summarizeCmd.Flags().String("result-file", defaultResultFile(), "Path to write the machine readable result of the run to, also written when the run fails")
+	// App size
+	summarizeCmd.Flags().String("base-artifact", "", "Path or URL of the build artifact of the base branch, to report the app size impact")
+	summarizeCmd.Flags().String("head-artifact", "", "Path or URL of the build artifact of the pull request, to report the app size impact")
 }
 
-func parseSettings() common.Settings {
+func parseSettings(cmd *cobra.Command) common.Settings {
+	allowRepositoryCommands, _ := cmd.Flags().GetBool("allow-repository-commands")
+	common.AllowRepositoryCommands(allowRepositoryCommands)
 	return common.WithYamlFile()
 }
+
+// validateToolSettings checks the custom tools and the tool roles of the commands, only the review command uses tools
+func validateToolSettings(tools common.ToolSettings, customTools []common.CustomTool) error {
+	if err := llm.ValidateCustomTools(customTools); err != nil {
+		return err
+	}
+	for command, roles := range tools {
+		if command != common.CommandSummarize {
+			return fmt.Errorf("tool roles can only be set for the %s command, got %s", common.CommandSummarize, command)
+		}
+		if err := llm.ValidateToolRoles(roles, customTools); err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// modelOptions returns the sampling and reasoning options of the settings, overridden by the flags
+func modelOptions(cmd *cobra.Command, settings common.ModelOptions) []llm.Option {
+	if cmd.Flags().Changed("temperature") {
+		temperature, _ := cmd.Flags().GetFloat64("temperature")
+		settings.Temperature = &temperature
+	}
+	if cmd.Flags().Changed("top-p") {
+		topP, _ := cmd.Flags().GetFloat64("top-p")
+		settings.TopP = &topP
+	}
+	if cmd.Flags().Changed("reasoning-effort") {
+		settings.ReasoningEffort, _ = cmd.Flags().GetString("reasoning-effort")
+	}
+	if cmd.Flags().Changed("max-thinking-tokens") {
+		settings.MaxThinkingTokens, _ = cmd.Flags().GetInt("max-thinking-tokens")
+	}
+
+	var options []llm.Option
+	if settings.Temperature != nil {
+		options = append(options, llm.WithTemperature(*settings.Temperature))
+	}
+	if settings.TopP != nil {
+		options = append(options, llm.WithTopP(*settings.TopP))
+	}
+	if settings.ReasoningEffort != "" {
+		options = append(options, llm.WithReasoningEffort(settings.ReasoningEffort))
+	}
+	if settings.MaxThinkingTokens > 0 {
+		options = append(options, llm.WithMaxThinkingTokens(settings.MaxThinkingTokens))
+	}
+	return options
+}
+
+// checkLLMKey validates the LLM API key with a single small request, failing fast instead of after the preparation of the review
+func checkLLMKey(cmd *cobra.Command) error {
+	provider, _ := cmd.Flags().GetString("provider")
+	model, _ := cmd.Flags().GetString("model")
+	llmClient, err := newLLM(provider, model)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to create Client for LLM Provider: %v", err)
+		logger.Errorf(errMsg)
+		return common.NewConfigError(common.WrapError(errMsg, err))
+	}
+	if err := llmClient.CheckAuth(); err != nil {
+		errMsg := fmt.Sprintf("The API key of %s was rejected, check %s: %v", provider, llm.APIKeyCredential(provider).Name, err)
+		logger.Errorf(errMsg)
+		return common.NewLLMError(common.WrapError(errMsg, err))
+	}
+	return nil
+}
+
+// postNoteSummary replaces the under review note with a summary of only a note, when the review does not run
+func postNoteSummary(gitProvider review.Reviewer, repoOwner, repoName string, pr int, summary common.Summary, note string) error {
+	summary = summary.ReuseUnchanged()
+	body := summary.NoteString(gitProvider.GetProvider(), note)
+	common.Session().SetSummary(summary.Header(), body)
+	return gitProvider.PostSummary(repoOwner, repoName, pr, summary.Header(), body)
+}
+
+// postFormattingComment posts the formatting issues found by the formatters.
+// A clean run only updates the comment of a previous run, it does not add a new comment.
+func postFormattingComment(gitProvider review.Reviewer, repoOwner, repoName string, pr int, results []common.FormatterResult) {
+	if len(common.Unformatted(results)) == 0 {
+		previous, err := gitProvider.GetCommentBody(repoOwner, repoName, pr, common.FormattingHeader)
+		if err != nil || previous == "" {
+			return
+		}
+	}
+
+	body := common.FormatFormattingComment(gitProvider.GetProvider(), results)
+	if err := gitProvider.PostSummary(repoOwner, repoName, pr, common.FormattingHeader, body); err != nil {
+		logger.Warnf("Failed to post the formatting issues: %v", err)
+	}
+}
+
+// quickReview reviews the diff in a single request without tools and posts the compact summary.
+// Returns the response of the LLM and the line feedback to post.
+func quickReview(llmClient llm.LLM, gitProvider review.Reviewer, settings common.Settings, sections common.Summary, repoOwner, repoName string, pr int, diff string, promptBudget int) (llm.Response, []common.LineLevel) {
+	req := llm.Request{SystemPrompt: prompt.GetQuickReviewSystemPrompt(settings)}
+	userPrompt := common.NewPromptAssembler(promptBudget, req.SystemPrompt)
+	userPrompt.Add("task", common.PromptPriorityInstructions, prompt.GetQuickReviewPrompt())
+	userPrompt.Add("diff", common.PromptPriorityDiff, prompt.GetDiffPrompt(diff))
+	req.UserPrompt = userPrompt.String()
+	resp := llmClient.Complete(req)
+	if resp.Error != nil {
+		return resp, nil
+	}
+	common.Session().SetConversation([]common.SessionMessage{
+		{Role: common.RoleSystem, Content: req.SystemPrompt},
+		{Role: common.RoleUser, Content: req.UserPrompt},
+		{Role: common.RoleAssistant, Content: resp.Content},
+	})
+
+	result, err := common.ParseQuickReview(resp.Content)
+	if err != nil {
+		resp.Error = err
+		return resp, nil
+	}
+
+	if gitProvider != nil {
+		summary := sections
+		summary.Summary = result.Summary
+		summary.Stats = common.SummaryStats{Findings: len(result.Findings)}
+		summary = summary.ReuseUnchanged()
+
+		body := summary.QuickString(gitProvider.GetProvider(), settings)
+		common.Session().SetSummary(summary.Header(), body)
+		err := gitProvider.PostSummary(repoOwner, repoName, pr, summary.Header(), body)
+		if err != nil {
+			resp.Error = fmt.Errorf("failed to post summary: %w", err)
+		}
+	}
+
+	return resp, result.Findings
+}
+
+// saveRunReport saves the run report, failing to save it does not fail the review
+func saveRunReport(dir string) {
+	path, err := common.Report().Save(dir)
+	if err != nil {
+		logger.Warnf("Failed to save the run report: %v", err)
+		return
+	}
+	logger.Infof("Run report saved to %s", path)
+}
+
+// defaultResultFile returns the result file in the deploy directory on Bitrise, local runs don't write a result by default
+func defaultResultFile() string {
+	deployDir := os.Getenv("BITRISE_DEPLOY_DIR")
+	if deployDir == "" {
+		return ""
+	}
+	return filepath.Join(deployDir, common.ResultFileName)
+}
+
+// saveRunResult writes the result of the run, failing to write it does not change the outcome of the run
+func saveRunResult(path string, err error) {
+	if path == "" {
+		return
+	}
+	if err := common.NewRunResult(common.Report().Data(), err).Save(path); err != nil {
+		logger.Warnf("Failed to write the result: %v", err)
+		return
+	}
+	logger.Infof("Result written to %s", path)
+}
+
+// saveSession saves the encrypted review session, failing to save it does not fail the review
+func saveSession(dir string) {
+	key, _, err := common.CredentialSession.Resolve()
+	if err != nil {
+		logger.Warnf("Skipping saving the review session, it is only saved encrypted: %v", err)
+		return
+	}
+
+	path, err := common.Session().Save(dir, key)
+	if err != nil {
+		logger.Warnf("Failed to save the review session: %v", err)
+		return
+	}
+	logger.Infof("Review session saved to %s", path)
+}
+
+// saveAuditLog saves the posted content of the review, failing to save it does not fail the review
+func saveAuditLog(dir string) {
+	paths, err := common.Audit().Save(dir)
+	if err != nil {
+		logger.Warnf("Failed to save the audit log: %v", err)
+		return
+	}
+	logger.Infof("Audit log saved to %s", strings.Join(paths, " and "))
+}
+
+// errNotDeferred is returned by deferReview if the review is not queued and the run fails with the error of the LLM
+var errNotDeferred = errors.New("the review is not deferred")
+
+// deferReview queues the review failing on an overloaded LLM provider for the process-queue command, and replaces
+// the under review note with the deferred note. Returns errNotDeferred if the queue is not set or the error is not an overload.
+func deferReview(cmd *cobra.Command, gitProvider review.Reviewer, codeReviewerName, repo string, pr int, cause error) error {
+	queueFile, _ := cmd.Flags().GetString("queue-file")
+	if queueFile == "" || codeReviewerName == "" || !common.IsOverloadError(cause) {
+		return errNotDeferred
+	}
+
+	commitFlag, _ := cmd.Flags().GetString("commit")
+	commitHash, err := git.NewClient(git.NewDefaultRunner(".")).GetCommitHash(commitFlag)
+	if err != nil {
+		logger.Warnf("Failed to defer the review: %v", err)
+		return errNotDeferred
+	}
+	// The flags of the run are kept to rerun it, the commit is pinned and the rerun must not queue into the same file
+	var args []string
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if flag.Name != "commit" && flag.Name != "queue-file" {
+			args = append(args, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+
+	queue, err := common.LoadReviewQueue(queueFile)
+	if err != nil {
+		logger.Warnf("Failed to defer the review: %v", err)
+		return errNotDeferred
+	}
+	queued := common.QueuedReview{
+		CodeReview:  codeReviewerName,
+		Repository:  repo,
+		PullRequest: pr,
+		Commit:      commitHash,
+		Args:        args,
+		Reason:      cause.Error(),
+	}
+	queue.Enqueue(queued)
+	if err := queue.Save(queueFile); err != nil {
+		logger.Warnf("Failed to defer the review: %v", err)
+		return errNotDeferred
+	}
+	logger.Warnf("The LLM provider is overloaded, the review of %s is queued in %s: %v", queued, queueFile, cause)
+
+	if err := common.ExportOutput(common.OutputDeferred, "true"); err != nil {
+		logger.Warnf("%v", err)
+	}
+	if gitProvider != nil {
+		repoOwner, repoName, _ := strings.Cut(repo, "/")
+		if err := postNoteSummary(gitProvider, repoOwner, repoName, pr, common.Summary{}, common.DeferredNote); err != nil {
+			logger.Warnf("Failed to post the deferred note: %v", err)
+		}
+	}
+	return nil
+}
+
+// historyRewritten checks if the history of the pull request was rewritten since the previous review by a rebase, a squash or an amend.
+// The git history decides if it has both commits, the force pushes reported by the provider otherwise.
+func historyRewritten(gitProvider review.Reviewer, client *git.Client, repoOwner, repoName string, pr int, previous common.SummaryState, commitHash string) bool {
+	if previous.Commit == "" || git.SameCommit(previous.Commit, commitHash) {
+		return false
+	}
+	isAncestor, err := client.IsAncestor(previous.Commit, commitHash)
+	if err == nil {
+		return !isAncestor
+	}
+
+	logger.Debugf("Checking the force pushes of the pull request, the git history can't tell if it was rewritten: %v", err)
+	forcePushes, err := gitProvider.GetForcePushes(repoOwner, repoName, pr)
+	if err != nil {
+		logger.Warnf("Failed to get the force pushes of the pull request: %v", err)
+		return false
+	}
+	return common.ForcePushedSince(forcePushes, previous.ReviewedAt)
+}
+
+// setSkippedCommitStatus sets a success verdict on the head commit of a run skipped before its review started.
+// Repositories requiring the verdict would wait for it on the new commit forever otherwise.
+func setSkippedCommitStatus(cmd *cobra.Command, gitProvider review.Reviewer, repoOwner, repoName, description string) {
+	commit, _ := cmd.Flags().GetString("commit")
+	commitHash, err := git.NewClient(git.NewDefaultRunner(".")).GetCommitHash(commit)
+	if err != nil {
+		logger.Warnf("Failed to set the %s commit status: %v", common.CommitStatusContext, err)
+		return
+	}
+	setCommitStatus(gitProvider, repoOwner, repoName, commitHash, common.NewCommitStatus(common.CommitStatusSuccess, description))
+}
+
+// setCommitStatus sets the verdict of the review on the commit, failing to set it does not fail the review
+func setCommitStatus(gitProvider review.Reviewer, repoOwner, repoName, commitHash string, status common.CommitStatus) {
+	if err := gitProvider.SetCommitStatus(repoOwner, repoName, commitHash, status); err != nil {
+		logger.Warnf("Failed to set the %s commit status: %v", common.CommitStatusContext, err)
+		return
+	}
+	logger.Infof("Commit status %s set to %s: %s", common.CommitStatusContext, status.State, status.Description)
+}
+
+// saveKnowledgeBase saves the knowledge base, failing to save it does not fail the review
+func saveKnowledgeBase(knowledge *common.KnowledgeBase, path string) {
+	if err := knowledge.Save(path); err != nil {
+		logger.Warnf("Failed to save the knowledge base: %v", err)
+		return
+	}
+	logger.Infof("Knowledge base saved to %s", path)
+}
+
+// blockSecretSuggestions removes the suggestions adding a literal credential to the code, LLMs sometimes replace
+// a placeholder or an environment variable with a value seen in the context. Their comments are still posted,
+// with the credentials in their text redacted.
+func blockSecretSuggestions(client *git.Client, commitHash string, lines []common.LineLevel) {
+	for idx, ll := range lines {
+		if ll.Suggestion != "" {
+			original, err := client.GetFileContent(commitHash, ll.File)
+			if err != nil {
+				original = ll.Line
+			}
+			if secrets := common.IntroducedSecrets(original, ll.Suggestion); len(secrets) > 0 {
+				logger.Warnf("Blocked the suggestion for %s:%d: it adds a literal %s", ll.File, ll.LineNumber, secrets[0].Kind)
+				lines[idx].Suggestion = ""
+			}
+		}
+
+		var redacted, count int
+		lines[idx].Body, count = common.RedactSecrets(lines[idx].Body)
+		redacted += count
+		lines[idx].Prompt, count = common.RedactSecrets(lines[idx].Prompt)
+		redacted += count
+		if redacted > 0 {
+			logger.Warnf("Redacted %d literal credentials from the comment for %s:%d", redacted, ll.File, ll.LineNumber)
+		}
+	}
+}
+
+// verifySuggestions checks the suggestions before posting, as authors often apply them without reading.
+// Suggestions breaking the syntax of the file or failing the review of the LLM are removed, their comments are still posted.
+// Suggestions failing only a heuristic syntax check are kept with a warning in the comment.
+// The LLM review sends the code around the suggestion, it is opt-in and skipped when only the diff may be shared.
+func verifySuggestions(llmClient llm.LLM, client *git.Client, commitHash string, lines []common.LineLevel, withLLM bool) {
+	for idx, ll := range lines {
+		if ll.Suggestion == "" {
+			continue
+		}
+
+		content, err := client.GetFileContent(commitHash, ll.File)
+		if err != nil {
+			logger.Warnf("Skipping verification of the suggestion for %s: %v", ll.File, err)
+			continue
+		}
+
+		reason := ""
+		applied, err := common.ApplySuggestion(content, ll)
+		if err == nil {
+			err = common.CheckSuggestionSyntax(ll.File, content, applied)
+		}
+		var syntaxErr *common.SyntaxError
+		heuristicErr := errors.As(err, &syntaxErr) && syntaxErr.Heuristic
+		if err != nil && !heuristicErr {
+			reason = err.Error()
+		} else if withLLM {
+			reason = verifySuggestionWithLLM(llmClient, content, ll)
+		}
+
+		switch {
+		case reason != "":
+			logger.Infof("Dropping the suggestion for %s:%d: %s", ll.File, ll.LineNumber, reason)
+			lines[idx].Suggestion = ""
+		case heuristicErr:
+			// The warning is only added to the suggestions which are posted
+			logger.Infof("Annotating the suggestion for %s:%d: %v", ll.File, ll.LineNumber, err)
+			lines[idx].Body += "\n\n" + common.ApplyStyle("⚠️ Check the suggestion before applying it, "+err.Error()+".")
+		}
+	}
+}
+
+// verifySuggestionWithLLM asks the LLM if the suggestion applies cleanly and preserves behavior.
+// Returns why the suggestion failed, or an empty string if it passed or could not be verified.
+func verifySuggestionWithLLM(llmClient llm.LLM, content string, ll common.LineLevel) string {
+	codeContext, err := common.SuggestionContext(content, ll)
+	if err != nil {
+		return err.Error()
+	}
+
+	resp := llmClient.Complete(llm.Request{
+		SystemPrompt: prompt.GetSuggestionVerificationSystemPrompt(),
+		UserPrompt:   prompt.GetSuggestionVerificationPrompt(ll, codeContext),
+	})
+	if resp.Error != nil {
+		logger.Warnf("Failed to verify the suggestion for %s:%d: %v", ll.File, ll.LineNumber, resp.Error)
+		return ""
+	}
+
+	verdict, err := common.ParseSuggestionVerdict(resp.Content)
+	if err != nil {
+		logger.Warnf("Failed to verify the suggestion for %s:%d: %v", ll.File, ll.LineNumber, err)
+		return ""
+	}
+	if !verdict.Passed() {
+		return verdict.Reason
+	}
+	return ""
+}
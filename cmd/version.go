@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/version"
 	"github.com/spf13/cobra"
 )
@@ -10,12 +13,65 @@ import (
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
-	Long:  `Display the version of this plugin`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Bitrise AI Reviewer Plugin v%s\n", version.Version)
+	Long: `Display the version of this plugin, with the commit and the date of the build and the Go version.
+With --check-update the latest release is looked up, and a warning is printed if a newer version is available.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		checkUpdate, _ := cmd.Flags().GetBool("check-update")
+
+		info := version.Get()
+		if jsonOutput {
+			output := struct {
+				version.Info
+				LatestVersion   string `json:"latest_version,omitempty"`
+				UpdateAvailable bool   `json:"update_available,omitempty"`
+			}{Info: info}
+			if checkUpdate {
+				output.LatestVersion = latestPluginVersion()
+				output.UpdateAvailable = common.IsNewerVersion(output.LatestVersion, info.Version)
+			}
+
+			content, err := json.MarshalIndent(output, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode version: %w", err)
+			}
+			fmt.Println(string(content))
+			return nil
+		}
+
+		fmt.Printf("Bitrise AI Reviewer Plugin v%s\n", info.Version)
+		if info.Commit != "" {
+			fmt.Printf("Commit: %s\n", info.Commit)
+		}
+		if info.BuildDate != "" {
+			fmt.Printf("Build date: %s\n", info.BuildDate)
+		}
+		fmt.Printf("Go version: %s\n", info.GoVersion)
+
+		if checkUpdate {
+			if latest := latestPluginVersion(); common.IsNewerVersion(latest, info.Version) {
+				logger.Warnf("A newer version of the plugin is available: v%s, update with: bitrise plugin update ai-reviewer", latest)
+			} else if latest != "" {
+				logger.Infof("The plugin is up to date")
+			}
+		}
+		return nil
 	},
 }
 
+// latestPluginVersion returns the version of the latest release, empty if the check failed
+func latestPluginVersion() string {
+	latest, err := common.FetchLatestPluginVersion(common.PluginReleasesURL)
+	if err != nil {
+		logger.Warnf("Failed to check for updates: %v", err)
+		return ""
+	}
+	return latest
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().Bool("json", false, "Print the version and the build metadata as JSON")
+	versionCmd.Flags().Bool("check-update", false, "Check if a newer version of the plugin is available")
 }
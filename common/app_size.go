@@ -0,0 +1,169 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// artifactDownloadTimeout is the timeout in seconds for measuring a remote artifact
+const artifactDownloadTimeout = 300
+
+// AppSizeReport compares the size of the build artifacts of the base and the head of the pull request
+type AppSizeReport struct {
+	BaseSize int64
+	HeadSize int64
+	Settings AppSize
+}
+
+// CompareAppSize measures the base and head artifacts, given as local paths or URLs
+func CompareAppSize(baseArtifact, headArtifact string, settings AppSize) (*AppSizeReport, error) {
+	baseSize, err := ArtifactSize(baseArtifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the size of the base artifact: %w", err)
+	}
+
+	headSize, err := ArtifactSize(headArtifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the size of the head artifact: %w", err)
+	}
+
+	return &AppSizeReport{BaseSize: baseSize, HeadSize: headSize, Settings: settings}, nil
+}
+
+// ArtifactSize returns the size of a build artifact in bytes.
+// Local directories, like .app bundles, are measured by the total size of their files.
+func ArtifactSize(location string) (int64, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return remoteArtifactSize(location)
+	}
+
+	info, err := os.Stat(location)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+
+	var size int64
+	err = filepath.WalkDir(location, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// remoteArtifactSize uses the Content-Length of the artifact, and downloads it if the length is unknown
+func remoteArtifactSize(url string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactDownloadTimeout*time.Second)
+	defer cancel()
+
+	client := NewRetryableClient(DefaultRetryConfig()).StandardClient()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK && resp.ContentLength >= 0 {
+			return resp.ContentLength, nil
+		}
+	}
+
+	// Presigned artifact URLs often only allow GET requests
+	req, err = http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, NewAPIError("Artifact", resp.StatusCode, fmt.Errorf("failed to download artifact: HTTP %d", resp.StatusCode))
+	}
+	return io.Copy(io.Discard, resp.Body)
+}
+
+// Diff returns the size change in bytes
+func (r *AppSizeReport) Diff() int64 {
+	return r.HeadSize - r.BaseSize
+}
+
+// DiffPercent returns the size change relative to the base size
+func (r *AppSizeReport) DiffPercent() float64 {
+	if r.BaseSize == 0 {
+		return 0
+	}
+	return float64(r.Diff()) / float64(r.BaseSize) * 100
+}
+
+// ExceedsThreshold returns true if the size increase is above any of the configured thresholds
+func (r *AppSizeReport) ExceedsThreshold() bool {
+	if r.Settings.WarnIncreaseKB > 0 && r.Diff() > r.Settings.WarnIncreaseKB*1024 {
+		return true
+	}
+	return r.Settings.WarnIncreasePercent > 0 && r.DiffPercent() > r.Settings.WarnIncreasePercent
+}
+
+// String formats the app size impact as a single line
+func (r *AppSizeReport) String() string {
+	if r == nil {
+		return ""
+	}
+
+	sign := "+"
+	if r.Diff() < 0 {
+		sign = "-"
+	}
+	diff := r.Diff()
+	if diff < 0 {
+		diff = -diff
+	}
+
+	line := fmt.Sprintf("📦 **App size**: %s → %s (%s%s, %s%.2f%%)",
+		formatBytes(r.BaseSize), formatBytes(r.HeadSize), sign, formatBytes(diff), sign, abs(r.DiffPercent()))
+	if r.ExceedsThreshold() {
+		line += " ⚠️ The size increase exceeds the configured threshold."
+	}
+	return line
+}
+
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1024*1024*1024:
+		return fmt.Sprintf("%.2f GB", float64(size)/(1024*1024*1024))
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+func abs(value float64) float64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArtifactSize(t *testing.T) {
+	tempDir := t.TempDir()
+	bundle := filepath.Join(tempDir, "App.app")
+	os.MkdirAll(filepath.Join(bundle, "Frameworks"), 0755)
+	os.WriteFile(filepath.Join(bundle, "App"), make([]byte, 1000), 0644)
+	os.WriteFile(filepath.Join(bundle, "Frameworks", "Lib"), make([]byte, 500), 0644)
+
+	if size, err := ArtifactSize(bundle); err != nil || size != 1500 {
+		t.Errorf("Expected bundle size 1500, got %d (%v)", size, err)
+	}
+	if size, err := ArtifactSize(filepath.Join(bundle, "App")); err != nil || size != 1000 {
+		t.Errorf("Expected file size 1000, got %d (%v)", size, err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2048))
+	}))
+	defer server.Close()
+
+	if size, err := ArtifactSize(server.URL + "/app.ipa"); err != nil || size != 2048 {
+		t.Errorf("Expected remote size 2048, got %d (%v)", size, err)
+	}
+}
+
+func TestAppSizeReportString(t *testing.T) {
+	report := &AppSizeReport{BaseSize: 10 * 1024 * 1024, HeadSize: 11 * 1024 * 1024, Settings: AppSize{WarnIncreasePercent: 5}}
+
+	output := report.String()
+	if !strings.Contains(output, "10.00 MB → 11.00 MB (+1.00 MB, +10.00%)") {
+		t.Errorf("Unexpected app size line: %s", output)
+	}
+	if !strings.Contains(output, "exceeds the configured threshold") {
+		t.Errorf("Expected threshold warning: %s", output)
+	}
+
+	report.Settings = AppSize{WarnIncreaseKB: 2048}
+	if report.ExceedsThreshold() {
+		t.Error("Expected 1 MB increase to be below the 2048 KB threshold")
+	}
+}
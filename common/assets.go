@@ -0,0 +1,362 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+// maxListedAssets is the maximum number of items listed per group in the assets section
+const maxListedAssets = 20
+
+// assetDiffNote replaces the changes of assets in the diff shared with the LLM
+const assetDiffNote = "(asset change, summarized separately by the reviewer)"
+
+var (
+	imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".heic", ".bmp", ".ico"}
+
+	androidValuesDirRegex = regexp.MustCompile(`^values(-[a-z]{2,3}(-r[A-Z]{2})?|-b\+[A-Za-z0-9+]+)?$`)
+	stringsKeyRegex       = regexp.MustCompile(`^\s*"((?:[^"\\]|\\.)+)"\s*=`)
+	androidKeyRegex       = regexp.MustCompile(`<(?:string|plurals|string-array)\b[^>]*\bname="([^"]+)"`)
+)
+
+// AssetChange is a changed image resource with its size before and after the changes, -1 if the size is unknown
+type AssetChange struct {
+	File     string
+	Status   string
+	BaseSize int64
+	HeadSize int64
+}
+
+// MissingLocaleKey is a localization key added in one language but missing from other languages of the same strings file
+type MissingLocaleKey struct {
+	File    string
+	Key     string
+	Locales []string
+}
+
+// AssetAnalysis is the result of the analysis of changed images, asset catalogs and localization files
+type AssetAnalysis struct {
+	Images            []AssetChange      // Changed image resources
+	LocalizationFiles []string           // Changed localization files
+	MissingKeys       []MissingLocaleKey // Added keys missing from other languages
+	OrphanedAssets    []string           // Added assets without any reference in the repository
+}
+
+// IsAssetCatalogFile returns true for the files of Xcode asset catalogs
+func IsAssetCatalogFile(path string) bool {
+	return strings.Contains(path, ".xcassets/")
+}
+
+// IsImageResource returns true for image files, including the PDF and SVG images of asset catalogs
+func IsImageResource(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if IsAssetCatalogFile(path) && (ext == ".pdf" || ext == ".svg") {
+		return true
+	}
+	return slices.Contains(imageExtensions, ext)
+}
+
+// IsLocalizationFile returns true for iOS .strings files and Android strings.xml files
+func IsLocalizationFile(path string) bool {
+	_, _, ok := localizationGroup(path)
+	return ok
+}
+
+// localizationGroup returns the path shared by the languages of a localization file, and the language of the file
+func localizationGroup(path string) (string, string, bool) {
+	dir := filepath.Dir(path)
+	name := filepath.Base(dir)
+
+	if filepath.Ext(path) == ".strings" && strings.HasSuffix(name, ".lproj") {
+		group := filepath.Join(filepath.Dir(dir), "*.lproj", filepath.Base(path))
+		return group, strings.TrimSuffix(name, ".lproj"), true
+	}
+
+	if filepath.Base(path) == "strings.xml" && androidValuesDirRegex.MatchString(name) {
+		locale := strings.TrimPrefix(strings.TrimPrefix(name, "values"), "-")
+		if locale == "" {
+			locale = "default"
+		}
+		return filepath.Join(filepath.Dir(dir), "values*", "strings.xml"), locale, true
+	}
+
+	return "", "", false
+}
+
+// parseLocalizationKeys returns the keys defined in the lines of a localization file
+func parseLocalizationKeys(path string, lines []string) []string {
+	var keys []string
+	for _, line := range lines {
+		if filepath.Ext(path) == ".strings" {
+			if match := stringsKeyRegex.FindStringSubmatch(line); match != nil {
+				keys = append(keys, match[1])
+			}
+			continue
+		}
+
+		// Untranslatable strings are only defined in the default language
+		if match := androidKeyRegex.FindStringSubmatch(line); match != nil && !strings.Contains(line, `translatable="false"`) {
+			keys = append(keys, match[1])
+		}
+	}
+	return keys
+}
+
+// StripAssetDiffs replaces the changes of images and asset catalogs in the diff with a short note.
+// They are reported in the assets section of the summary, for the LLM they are only noise.
+func StripAssetDiffs(diff string) string {
+	for _, file := range diffparse.Parse(diff) {
+		if path := file.Path(); IsImageResource(path) || IsAssetCatalogFile(path) {
+			header, _, _ := strings.Cut(file.Raw, "\n")
+			diff = strings.Replace(diff, file.Raw, header+"\n"+assetDiffNote, 1)
+		}
+	}
+	return diff
+}
+
+// AnalyzeAssets analyzes the changed images, asset catalogs and localization files of the diff between the refs.
+// Returns nil if no assets were changed.
+func AnalyzeAssets(client *git.Client, baseRef, headRef, diff string) *AssetAnalysis {
+	analysis := &AssetAnalysis{}
+	addedKeys := map[string][]string{}
+	var addedAssets []string
+
+	for _, file := range diffparse.Parse(diff) {
+		path := file.Path()
+		switch {
+		case IsImageResource(path):
+			analysis.Images = append(analysis.Images, measureAsset(client, file, baseRef, headRef))
+			if file.Status == diffparse.StatusAdded {
+				addedAssets = append(addedAssets, path)
+			}
+		case IsLocalizationFile(path):
+			analysis.LocalizationFiles = append(analysis.LocalizationFiles, path)
+			var lines []string
+			for _, line := range file.AddedLines() {
+				lines = append(lines, line.Content)
+			}
+			if keys := parseLocalizationKeys(path, lines); len(keys) > 0 {
+				addedKeys[path] = keys
+			}
+		}
+	}
+
+	if len(analysis.Images) == 0 && len(analysis.LocalizationFiles) == 0 {
+		return nil
+	}
+
+	analysis.MissingKeys = findMissingLocaleKeys(client, headRef, addedKeys)
+	analysis.OrphanedAssets = findOrphanedAssets(client, headRef, addedAssets)
+	return analysis
+}
+
+// measureAsset returns the size of the asset before and after the changes
+func measureAsset(client *git.Client, file diffparse.FileDiff, baseRef, headRef string) AssetChange {
+	change := AssetChange{File: file.Path(), Status: file.Status, BaseSize: -1, HeadSize: -1}
+
+	if file.Status == diffparse.StatusAdded {
+		change.BaseSize = 0
+	} else if size, err := client.GetFileSize(baseRef, file.OldPath); err == nil {
+		change.BaseSize = size
+	}
+
+	if file.Status == diffparse.StatusDeleted {
+		change.HeadSize = 0
+	} else if size, err := client.GetFileSize(headRef, file.NewPath); err == nil {
+		change.HeadSize = size
+	}
+
+	return change
+}
+
+// findMissingLocaleKeys checks the added keys in the other languages of the changed localization files
+func findMissingLocaleKeys(client *git.Client, headRef string, addedKeys map[string][]string) []MissingLocaleKey {
+	if len(addedKeys) == 0 {
+		return nil
+	}
+
+	output, err := client.ListFiles(headRef)
+	if err != nil {
+		return nil
+	}
+
+	// Languages of the localization files, by the path shared by the languages
+	languages := map[string]map[string]string{}
+	for path := range strings.SplitSeq(output, "\n") {
+		if group, locale, ok := localizationGroup(path); ok {
+			if languages[group] == nil {
+				languages[group] = map[string]string{}
+			}
+			languages[group][locale] = path
+		}
+	}
+
+	definedKeys := map[string]map[string]bool{}
+	keysOf := func(path string) map[string]bool {
+		if keys, ok := definedKeys[path]; ok {
+			return keys
+		}
+		content, _ := client.GetFileContent(headRef, path)
+		keys := map[string]bool{}
+		for _, key := range parseLocalizationKeys(path, strings.Split(content, "\n")) {
+			keys[key] = true
+		}
+		definedKeys[path] = keys
+		return keys
+	}
+
+	var files []string
+	for file := range addedKeys {
+		files = append(files, file)
+	}
+	slices.Sort(files)
+
+	var missing []MissingLocaleKey
+	for _, file := range files {
+		group, _, _ := localizationGroup(file)
+		var locales []string
+		for locale := range languages[group] {
+			locales = append(locales, locale)
+		}
+		slices.Sort(locales)
+
+		for _, key := range addedKeys[file] {
+			missingKey := MissingLocaleKey{File: file, Key: key}
+			for _, locale := range locales {
+				if path := languages[group][locale]; path != file && !keysOf(path)[key] {
+					missingKey.Locales = append(missingKey.Locales, locale)
+				}
+			}
+			if len(missingKey.Locales) > 0 {
+				missing = append(missing, missingKey)
+			}
+		}
+	}
+	return missing
+}
+
+// findOrphanedAssets returns the added assets which are not referenced anywhere in the repository
+func findOrphanedAssets(client *git.Client, headRef string, addedAssets []string) []string {
+	var orphaned []string
+	checked := map[string]bool{}
+	for _, path := range addedAssets {
+		name, assetPath := assetReference(path)
+		if checked[assetPath] {
+			continue
+		}
+		checked[assetPath] = true
+
+		// git grep fails if there are no matches
+		output, _ := client.Grep(headRef, name, false, "")
+		referenced := false
+		for line := range strings.SplitSeq(output, "\n") {
+			file, _, _ := strings.Cut(strings.TrimPrefix(line, headRef+":"), ":")
+			if file != "" && file != assetPath && !strings.HasPrefix(file, assetPath+"/") {
+				referenced = true
+				break
+			}
+		}
+		if !referenced {
+			orphaned = append(orphaned, assetPath)
+		}
+	}
+	return orphaned
+}
+
+// assetReference returns the name the code refers to the asset by, and the path of the asset.
+// Asset catalog images are referenced by the name of their image set, Android resources by the file name without extension.
+func assetReference(path string) (string, string) {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasSuffix(part, ".imageset") && i > 0 && IsAssetCatalogFile(path) {
+			return strings.TrimSuffix(part, ".imageset"), strings.Join(parts[:i+1], "/")
+		}
+	}
+
+	name := filepath.Base(path)
+	dir := filepath.Base(filepath.Dir(path))
+	if strings.HasPrefix(dir, "drawable") || strings.HasPrefix(dir, "mipmap") {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name, path
+}
+
+// String formats the analysis as the assets section of the summary
+func (a *AssetAnalysis) String() string {
+	if a == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	if len(a.Images) > 0 {
+		var total int64
+		lines := []string{}
+		for _, image := range a.Images {
+			lines = append(lines, fmt.Sprintf("- `%s`: %s", image.File, image.sizeChange()))
+			if image.BaseSize >= 0 && image.HeadSize >= 0 {
+				total += image.HeadSize - image.BaseSize
+			}
+		}
+		builder.WriteString(fmt.Sprintf("**Images**: %d changed, %s in total\n", len(a.Images), formatSizeDelta(total)))
+		builder.WriteString(strings.Join(limitList(lines), "\n") + "\n")
+	}
+
+	if len(a.MissingKeys) > 0 {
+		lines := []string{}
+		for _, key := range a.MissingKeys {
+			lines = append(lines, fmt.Sprintf("- `%s` of `%s` is missing in: %s", key.Key, key.File, strings.Join(key.Locales, ", ")))
+		}
+		builder.WriteString("\n**Missing translations**\n")
+		builder.WriteString(strings.Join(limitList(lines), "\n") + "\n")
+	}
+
+	if len(a.OrphanedAssets) > 0 {
+		lines := []string{}
+		for _, asset := range a.OrphanedAssets {
+			lines = append(lines, fmt.Sprintf("- `%s`", asset))
+		}
+		builder.WriteString("\n**Possibly unused assets** (no reference found in the repository)\n")
+		builder.WriteString(strings.Join(limitList(lines), "\n") + "\n")
+	}
+
+	if builder.Len() == 0 {
+		builder.WriteString(fmt.Sprintf("%d localization files changed, all added keys are translated.\n", len(a.LocalizationFiles)))
+	}
+
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// sizeChange formats the size change of the asset
+func (c AssetChange) sizeChange() string {
+	switch {
+	case c.Status == diffparse.StatusAdded && c.HeadSize >= 0:
+		return "added, " + formatBytes(c.HeadSize)
+	case c.Status == diffparse.StatusDeleted && c.BaseSize >= 0:
+		return "removed, " + formatBytes(c.BaseSize)
+	case c.BaseSize < 0 || c.HeadSize < 0:
+		return c.Status + ", size unknown"
+	}
+	return fmt.Sprintf("%s → %s (%s)", formatBytes(c.BaseSize), formatBytes(c.HeadSize), formatSizeDelta(c.HeadSize-c.BaseSize))
+}
+
+// formatSizeDelta formats a size change with its sign
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + formatBytes(-delta)
+	}
+	return "+" + formatBytes(delta)
+}
+
+// limitList keeps the first items of a list, noting the number of the omitted items
+func limitList(lines []string) []string {
+	if len(lines) <= maxListedAssets {
+		return lines
+	}
+	return append(lines[:maxListedAssets:maxListedAssets], fmt.Sprintf("- and %d more", len(lines)-maxListedAssets))
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+// fakeRunner returns the output registered for the arguments of the command, and an error for other commands
+type fakeRunner map[string]string
+
+func (r fakeRunner) Run(name string, args ...string) (string, error) {
+	if output, ok := r[strings.Join(args, " ")]; ok {
+		return output, nil
+	}
+	return "", errors.New("exit status 1")
+}
+
+const assetDiff = `diff --git a/App/Assets.xcassets/Logo.imageset/logo.png b/App/Assets.xcassets/Logo.imageset/logo.png
+new file mode 100644
+index 0000000..1111111
+Binary files /dev/null and b/App/Assets.xcassets/Logo.imageset/logo.png differ
+diff --git a/App/Assets.xcassets/Logo.imageset/Contents.json b/App/Assets.xcassets/Logo.imageset/Contents.json
+new file mode 100644
+index 0000000..2222222
+--- /dev/null
++++ b/App/Assets.xcassets/Logo.imageset/Contents.json
+@@ -0,0 +1,3 @@
++{
++  "images" : [ { "filename" : "logo.png", "idiom" : "universal" } ]
++}
+diff --git a/app/src/main/res/drawable/banner.png b/app/src/main/res/drawable/banner.png
+index 3333333..4444444 100644
+Binary files a/app/src/main/res/drawable/banner.png and b/app/src/main/res/drawable/banner.png differ
+diff --git a/App/en.lproj/Localizable.strings b/App/en.lproj/Localizable.strings
+index 5555555..6666666 100644
+--- a/App/en.lproj/Localizable.strings
++++ b/App/en.lproj/Localizable.strings
+@@ -1,0 +2,2 @@
++"welcome_title" = "Welcome";
++"logout" = "Log out";
+diff --git a/App/Login.swift b/App/Login.swift
+index 7777777..8888888 100644
+--- a/App/Login.swift
++++ b/App/Login.swift
+@@ -10 +10 @@
+-let title = "Hi"
++let title = NSLocalizedString("welcome_title", comment: "")`
+
+func TestIsLocalizationFile(t *testing.T) {
+	tests := map[string]bool{
+		"App/en.lproj/Localizable.strings":           true,
+		"App/Base.lproj/Main.strings":                true,
+		"app/src/main/res/values/strings.xml":        true,
+		"app/src/main/res/values-de/strings.xml":     true,
+		"app/src/main/res/values-pt-rBR/strings.xml": true,
+		"app/src/main/res/values-night/strings.xml":  false,
+		"app/src/main/res/values/colors.xml":         false,
+		"App/Localizable.strings":                    false,
+	}
+	for path, expected := range tests {
+		if got := IsLocalizationFile(path); got != expected {
+			t.Errorf("IsLocalizationFile(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+}
+
+func TestStripAssetDiffs(t *testing.T) {
+	stripped := StripAssetDiffs(assetDiff)
+
+	if strings.Contains(stripped, "Binary files") || strings.Contains(stripped, `"images"`) {
+		t.Errorf("Expected the image and asset catalog changes to be stripped, got:\n%s", stripped)
+	}
+	if strings.Count(stripped, assetDiffNote) != 3 {
+		t.Errorf("Expected 3 stripped assets, got:\n%s", stripped)
+	}
+	if !strings.Contains(stripped, `+"welcome_title" = "Welcome";`) || !strings.Contains(stripped, "+let title = NSLocalizedString") {
+		t.Errorf("Expected the localization and code changes to be kept, got:\n%s", stripped)
+	}
+	if !strings.HasPrefix(stripped, "diff --git a/App/Assets.xcassets/Logo.imageset/logo.png") {
+		t.Errorf("Expected the diff to start with the first file header, got:\n%s", stripped)
+	}
+}
+
+func TestAnalyzeAssets(t *testing.T) {
+	runner := fakeRunner{
+		"cat-file -s head:App/Assets.xcassets/Logo.imageset/logo.png": "2048",
+		"cat-file -s base:app/src/main/res/drawable/banner.png":       "40960",
+		"cat-file -s head:app/src/main/res/drawable/banner.png":       "20480",
+		"ls-tree -r --name-only head": strings.Join([]string{
+			"App/Login.swift",
+			"App/en.lproj/Localizable.strings",
+			"App/de.lproj/Localizable.strings",
+			"App/fr.lproj/Localizable.strings",
+		}, "\n"),
+		"show head:App/de.lproj/Localizable.strings": `"welcome_title" = "Willkommen";`,
+		"show head:App/fr.lproj/Localizable.strings": `"title" = "Titre";`,
+	}
+	analysis := AnalyzeAssets(git.NewClient(runner), "base", "head", assetDiff)
+	if analysis == nil {
+		t.Fatal("Expected asset changes to be detected")
+	}
+
+	expectedImages := []AssetChange{
+		{File: "App/Assets.xcassets/Logo.imageset/logo.png", Status: diffparse.StatusAdded, BaseSize: 0, HeadSize: 2048},
+		{File: "app/src/main/res/drawable/banner.png", Status: diffparse.StatusModified, BaseSize: 40960, HeadSize: 20480},
+	}
+	if !reflect.DeepEqual(analysis.Images, expectedImages) {
+		t.Errorf("Expected images %+v, got %+v", expectedImages, analysis.Images)
+	}
+
+	expectedMissing := []MissingLocaleKey{
+		{File: "App/en.lproj/Localizable.strings", Key: "welcome_title", Locales: []string{"fr"}},
+		{File: "App/en.lproj/Localizable.strings", Key: "logout", Locales: []string{"de", "fr"}},
+	}
+	if !reflect.DeepEqual(analysis.MissingKeys, expectedMissing) {
+		t.Errorf("Expected missing keys %+v, got %+v", expectedMissing, analysis.MissingKeys)
+	}
+
+	// The logo is only referenced by its own Contents.json
+	expectedOrphaned := []string{"App/Assets.xcassets/Logo.imageset"}
+	if !reflect.DeepEqual(analysis.OrphanedAssets, expectedOrphaned) {
+		t.Errorf("Expected orphaned assets %v, got %v", expectedOrphaned, analysis.OrphanedAssets)
+	}
+
+	section := analysis.String()
+	for _, expected := range []string{
+		"**Images**: 2 changed, -18.0 KB in total",
+		"`App/Assets.xcassets/Logo.imageset/logo.png`: added, 2.0 KB",
+		"`app/src/main/res/drawable/banner.png`: 40.0 KB → 20.0 KB (-20.0 KB)",
+		"`logout` of `App/en.lproj/Localizable.strings` is missing in: de, fr",
+		"**Possibly unused assets**",
+	} {
+		if !strings.Contains(section, expected) {
+			t.Errorf("Expected the section to contain %q, got:\n%s", expected, section)
+		}
+	}
+}
+
+func TestAnalyzeAssetsWithoutAssets(t *testing.T) {
+	diff := GetFileDiff(assetDiff, "App/Login.swift")
+	if analysis := AnalyzeAssets(git.NewClient(fakeRunner{}), "base", "head", diff); analysis != nil {
+		t.Errorf("Expected no asset analysis for code changes, got %+v", analysis)
+	}
+}
@@ -0,0 +1,194 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/version"
+)
+
+// AuditFilePrefix is the file name prefix of the saved audit logs
+const AuditFilePrefix = "ai-review-audit-"
+
+// Kinds of the posted comments
+const (
+	AuditKindSummary = "summary" // Summary comment, updated in place on later runs
+	AuditKindReview  = "review"  // Body of the review with the nitpicks
+	AuditKindLine    = "line"    // Comment on lines of a file
+	AuditKindFile    = "file"    // Comment on a whole file
+)
+
+// AuditComment is a comment as it was posted on the pull request
+type AuditComment struct {
+	Kind     string    `json:"kind"`
+	File     string    `json:"file,omitempty"`
+	Line     int       `json:"line,omitempty"`
+	LastLine int       `json:"last_line,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Severity string    `json:"severity,omitempty"`
+	Body     string    `json:"body"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// NewLineAuditComment returns the audit comment of a posted finding
+func NewLineAuditComment(ll LineLevel, body string) AuditComment {
+	comment := AuditComment{
+		Kind:     AuditKindLine,
+		File:     ll.File,
+		Line:     ll.LineNumber,
+		Category: ll.Category,
+		Severity: ll.Severity,
+		Body:     body,
+	}
+	if ll.IsFileLevel() {
+		comment.Kind = AuditKindFile
+		comment.Line = 0
+	}
+	if ll.LastLineNumber > ll.LineNumber {
+		comment.LastLine = ll.LastLineNumber
+	}
+	return comment
+}
+
+// AuditLog is the archive of the content posted by a run, for the audit trail of regulated environments.
+// Unlike the review session it has no source code apart from the posted suggestions, so it is saved unencrypted.
+type AuditLog struct {
+	mu            sync.Mutex
+	StartedAt     time.Time      `json:"started_at"`
+	PluginVersion string         `json:"plugin_version"`
+	CodeReview    string         `json:"code_review"`
+	Repository    string         `json:"repository"`
+	PullRequest   int            `json:"pull_request"`
+	CommitHash    string         `json:"commit_hash"`
+	Provider      string         `json:"provider"`
+	Model         string         `json:"model"`
+	Comments      []AuditComment `json:"comments"`
+}
+
+var auditLog = NewAuditLog()
+
+// NewAuditLog creates an empty audit log
+func NewAuditLog() *AuditLog {
+	return &AuditLog{StartedAt: time.Now(), PluginVersion: version.Version, Comments: []AuditComment{}}
+}
+
+// Audit returns the audit log of the current run
+func Audit() *AuditLog {
+	return auditLog
+}
+
+// SetPullRequest sets the reviewed pull request and the code review provider it is posted to
+func (a *AuditLog) SetPullRequest(codeReview, repository string, pr int, commitHash string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.CodeReview = codeReview
+	a.Repository = repository
+	a.PullRequest = pr
+	a.CommitHash = commitHash
+}
+
+// SetModel sets the LLM provider and the model which produced the review
+func (a *AuditLog) SetModel(provider, model string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Provider = provider
+	a.Model = model
+}
+
+// RecordComment records a comment posted on the pull request
+func (a *AuditLog) RecordComment(comment AuditComment) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if comment.PostedAt.IsZero() {
+		comment.PostedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	a.Comments = append(a.Comments, comment)
+}
+
+// Markdown formats the audit log as a markdown document with the posted comments in order
+func (a *AuditLog) Markdown() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var builder strings.Builder
+	builder.WriteString("# AI review audit log\n\n")
+	fmt.Fprintf(&builder, "- Started: %s\n", a.StartedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&builder, "- Plugin version: %s\n", a.PluginVersion)
+	fmt.Fprintf(&builder, "- Code review: %s\n", a.CodeReview)
+	fmt.Fprintf(&builder, "- Pull request: %s#%d\n", a.Repository, a.PullRequest)
+	fmt.Fprintf(&builder, "- Commit: %s\n", a.CommitHash)
+	fmt.Fprintf(&builder, "- Model: %s %s\n", a.Provider, a.Model)
+	fmt.Fprintf(&builder, "- Comments posted: %d\n", len(a.Comments))
+
+	for idx, comment := range a.Comments {
+		fmt.Fprintf(&builder, "\n## %d. %s\n\n", idx+1, comment.title())
+		fmt.Fprintf(&builder, "- Posted: %s\n", comment.PostedAt.UTC().Format(time.RFC3339))
+		if comment.Category != "" {
+			fmt.Fprintf(&builder, "- Category: %s\n", comment.Category)
+		}
+		if comment.Severity != "" {
+			fmt.Fprintf(&builder, "- Severity: %s\n", comment.Severity)
+		}
+		// The fence is longer than any fence of the posted comment, so the comment is kept verbatim
+		fence := strings.Repeat("`", max(longestBacktickRun(comment.Body)+1, 3))
+		builder.WriteString("\n" + fence + "markdown\n" + comment.Body + "\n" + fence + "\n")
+	}
+	return builder.String()
+}
+
+// Save writes the audit log as markdown and JSON into the directory, returns the paths of the written files
+func (a *AuditLog) Save(dir string) ([]string, error) {
+	a.mu.Lock()
+	content, err := json.MarshalIndent(a, "", "  ")
+	a.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit log: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	base := filepath.Join(dir, AuditFilePrefix+a.StartedAt.Format("20060102-150405"))
+	if err := os.WriteFile(base+".md", []byte(a.Markdown()), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := os.WriteFile(base+".json", content, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return []string{base + ".md", base + ".json"}, nil
+}
+
+// title describes where the comment was posted
+func (c AuditComment) title() string {
+	switch c.Kind {
+	case AuditKindSummary:
+		return "Summary"
+	case AuditKindReview:
+		return "Review"
+	case AuditKindFile:
+		return "File comment on " + c.File
+	}
+	if c.LastLine > 0 {
+		return fmt.Sprintf("Line comment on %s:%d-%d", c.File, c.Line, c.LastLine)
+	}
+	return fmt.Sprintf("Line comment on %s:%d", c.File, c.Line)
+}
+
+// longestBacktickRun returns the length of the longest run of backticks in the text
+func longestBacktickRun(text string) int {
+	longest, current := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLineAuditComment(t *testing.T) {
+	comment := NewLineAuditComment(LineLevel{File: "main.go", LineNumber: 3, LastLineNumber: 5, Category: "bug", Severity: SeverityHigh}, "body")
+	if comment.Kind != AuditKindLine || comment.Line != 3 || comment.LastLine != 5 || comment.Category != "bug" || comment.Severity != SeverityHigh {
+		t.Errorf("Unexpected line comment: %+v", comment)
+	}
+	if comment.title() != "Line comment on main.go:3-5" {
+		t.Errorf("Unexpected title: %s", comment.title())
+	}
+
+	comment = NewLineAuditComment(LineLevel{File: "main.go", LineNumber: 3}, "body")
+	if comment.LastLine != 0 || comment.title() != "Line comment on main.go:3" {
+		t.Errorf("Unexpected single line comment: %+v", comment)
+	}
+}
+
+func TestAuditLogSave(t *testing.T) {
+	audit := NewAuditLog()
+	audit.StartedAt = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	audit.SetPullRequest(ProviderGitHub, "owner/repo", 42, "abc1234")
+	audit.SetModel("openai", "gpt-4.1")
+	audit.RecordComment(AuditComment{Kind: AuditKindSummary, Body: "## Summary\nAdds a helper."})
+	audit.RecordComment(AuditComment{Kind: AuditKindLine, File: "main.go", Line: 3, Body: "Use a constant\n```suggestion\nconst a = 1\n```"})
+
+	paths, err := audit.Save(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to save the audit log: %v", err)
+	}
+	if len(paths) != 2 || filepath.Base(paths[0]) != "ai-review-audit-20250310-120000.md" {
+		t.Fatalf("Unexpected paths: %v", paths)
+	}
+
+	markdown, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"- Pull request: owner/repo#42",
+		"- Model: openai gpt-4.1",
+		"## 1. Summary",
+		"## 2. Line comment on main.go:3",
+		// The comment with a code block is fenced with a longer fence
+		"````markdown\nUse a constant\n```suggestion\nconst a = 1\n```\n````",
+	} {
+		if !strings.Contains(string(markdown), expected) {
+			t.Errorf("Expected %q in the markdown, got:\n%s", expected, markdown)
+		}
+	}
+
+	content, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved AuditLog
+	if err := json.Unmarshal(content, &saved); err != nil {
+		t.Fatalf("Failed to parse the JSON audit log: %v", err)
+	}
+	if saved.CommitHash != "abc1234" || len(saved.Comments) != 2 || saved.Comments[1].File != "main.go" || saved.Comments[0].PostedAt.IsZero() {
+		t.Errorf("Unexpected JSON audit log: %s", content)
+	}
+}
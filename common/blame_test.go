@@ -0,0 +1,91 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+const porcelainBlame = `1111111111111111111111111111111111111111 1 1 2
+author Alice
+author-mail <alice@example.com>
+summary Initial commit
+filename main.go
+	package main
+1111111111111111111111111111111111111111 2 2
+	
+2222222222222222222222222222222222222222 5 3 1
+author Bob
+summary Add main
+previous 1111111111111111111111111111111111111111 main.go
+filename main.go
+	func main() {}
+`
+
+// countingRunner counts the runs of each command of the fake runner
+type countingRunner struct {
+	fakeRunner
+	runs map[string]int
+}
+
+func (r countingRunner) Run(name string, args ...string) (string, error) {
+	r.runs[strings.Join(args, " ")]++
+	return r.fakeRunner.Run(name, args...)
+}
+
+func TestBlameIsCachedPerCommitAndFile(t *testing.T) {
+	runner := countingRunner{
+		fakeRunner: fakeRunner{"blame --porcelain head -- main.go": porcelainBlame},
+		runs:       map[string]int{},
+	}
+	client := git.NewClient(runner)
+
+	expected := map[int]string{
+		1: "1111111111111111111111111111111111111111",
+		2: "1111111111111111111111111111111111111111",
+		3: "2222222222222222222222222222222222222222",
+	}
+	for line, commit := range expected {
+		blame, err := client.GetBlameForFileLine("head", "main.go", line)
+		if err != nil || blame != commit {
+			t.Errorf("Expected line %d to be blamed on %s, got %s (%v)", line, commit, blame, err)
+		}
+	}
+	if _, err := client.GetBlameForFileLine("head", "main.go", 4); err == nil {
+		t.Error("Expected an error for a line past the end of the file")
+	}
+	if runs := runner.runs["blame --porcelain head -- main.go"]; runs != 1 {
+		t.Errorf("Expected the file to be blamed once, got %d", runs)
+	}
+
+	// Failures are cached too, the file does not exist at the commit
+	for range 2 {
+		if _, err := client.GetBlameForFileLine("base", "main.go", 1); err == nil {
+			t.Error("Expected an error for a failed blame")
+		}
+	}
+	if runs := runner.runs["blame --porcelain base -- main.go"]; runs != 1 {
+		t.Errorf("Expected the failed blame to run once, got %d", runs)
+	}
+}
+
+func TestSameCommit(t *testing.T) {
+	full := "2222222222222222222222222222222222222222"
+	cases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{full, full, true},
+		{full, "2222222", true},
+		{"^2222222", full, true},
+		{full, "1111111", false},
+		{full, "222", false},
+		{"", "", true},
+	}
+	for _, c := range cases {
+		if got := git.SameCommit(c.a, c.b); got != c.expected {
+			t.Errorf("SameCommit(%q, %q) = %v, expected %v", c.a, c.b, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// checklistItemRegex matches the task list items of a markdown text, the first group is the check mark
+var checklistItemRegex = regexp.MustCompile(`(?m)^[ \t]*[-*+][ \t]+\[([ xX])\][ \t]+\S`)
+
+// BranchPolicy is a stricter review policy of the pull requests targeting the matching branches, like release branches
+type BranchPolicy struct {
+	Branches         []string `yaml:"branches"`           // Globs of the target branches, e.g. release/*
+	Profile          string   `yaml:"profile"`            // Overrides reviews.profile, empty keeps it
+	FailOnCategories []string `yaml:"fail_on_categories"` // Fails the run with exit code 10 on findings of these categories
+	RequireChecklist bool     `yaml:"require_checklist"`  // Fails the run if the description has no checklist or unchecked items
+	ReleaseRisk      bool     `yaml:"release_risk"`       // Adds a release risk assessment to the summary
+}
+
+// Matches checks if the policy applies to the pull requests targeting the branch
+func (p BranchPolicy) Matches(branch string) bool {
+	for _, pattern := range p.Branches {
+		if MatchGlob(pattern, branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountFindings returns the number of findings in the fail-on categories of the policy
+func (p BranchPolicy) CountFindings(lines []LineLevel) int {
+	count := 0
+	for _, l := range lines {
+		if slices.Contains(p.FailOnCategories, l.Category) {
+			count++
+		}
+	}
+	return count
+}
+
+// MatchBranchPolicy returns the first policy matching the target branch of the pull request, nil if none does
+func MatchBranchPolicy(policies []BranchPolicy, branch string) *BranchPolicy {
+	if branch == "" {
+		return nil
+	}
+	for _, policy := range policies {
+		if policy.Matches(branch) {
+			return &policy
+		}
+	}
+	return nil
+}
+
+// ApplyBranchPolicy returns the settings with the review profile of the policy
+func (s Settings) ApplyBranchPolicy(policy *BranchPolicy) Settings {
+	if policy != nil && policy.Profile != "" {
+		s.Reviews.Profile = policy.Profile
+	}
+	return s
+}
+
+// Checklist is the task list of the pull request description
+type Checklist struct {
+	Items     int
+	Unchecked int
+}
+
+// ParseChecklist counts the task list items of the pull request description
+func ParseChecklist(description string) Checklist {
+	var checklist Checklist
+	for _, match := range checklistItemRegex.FindAllStringSubmatch(description, -1) {
+		checklist.Items++
+		if match[1] == " " {
+			checklist.Unchecked++
+		}
+	}
+	return checklist
+}
+
+// Complete returns true if the description has a checklist with every item checked
+func (c Checklist) Complete() bool {
+	return c.Items > 0 && c.Unchecked == 0
+}
+
+// String describes the state of the checklist for the release risk section
+func (c Checklist) String() string {
+	switch {
+	case c.Items == 0:
+		return "⚠️ The pull request description has no checklist, it is required for this branch."
+	case c.Unchecked > 0:
+		return fmt.Sprintf("⚠️ %d of the %d checklist items are unchecked, the checklist is required for this branch.", c.Unchecked, c.Items)
+	default:
+		return fmt.Sprintf("✅ All %d checklist items are checked.", c.Items)
+	}
+}
+
+// FormatReleaseRisk joins the release risk assessment of the LLM with the notes of the branch policy
+func FormatReleaseRisk(assessment string, notes ...string) string {
+	parts := []string{}
+	if assessment = strings.TrimSpace(assessment); assessment != "" {
+		parts = append(parts, assessment)
+	}
+	for _, note := range notes {
+		if note != "" {
+			parts = append(parts, note)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
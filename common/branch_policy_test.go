@@ -0,0 +1,95 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestMatchBranchPolicy(t *testing.T) {
+	policies := []BranchPolicy{{Branches: []string{"release/*", "hotfix/*"}, Profile: ProfileAssertive}}
+
+	for _, branch := range []string{"release/1.2", "hotfix/crash-on-launch"} {
+		if policy := MatchBranchPolicy(policies, branch); policy == nil || policy.Profile != ProfileAssertive {
+			t.Errorf("Expected the assertive release policy for %s, got %+v", branch, policy)
+		}
+	}
+	for _, branch := range []string{"main", "feature/release/1.2", "release/1.2/fix", ""} {
+		if policy := MatchBranchPolicy(policies, branch); policy != nil {
+			t.Errorf("Expected no policy for %q, got %+v", branch, policy)
+		}
+	}
+
+	settings := WithDefaultSettings().ApplyBranchPolicy(MatchBranchPolicy(policies, "release/1.2"))
+	if settings.Reviews.Profile != ProfileAssertive {
+		t.Errorf("Expected the policy to override the profile, got %s", settings.Reviews.Profile)
+	}
+	if settings := WithDefaultSettings().ApplyBranchPolicy(nil); settings.Reviews.Profile != ProfileChill {
+		t.Errorf("Expected the profile unchanged without a policy, got %s", settings.Reviews.Profile)
+	}
+}
+
+func TestBranchPolicyCountFindings(t *testing.T) {
+	policy := BranchPolicy{FailOnCategories: []string{CategoryBug, CategorySecurity}}
+	lines := []LineLevel{
+		{Category: CategoryBug},
+		{Category: CategoryNitpick},
+		{Category: CategorySecurity},
+		{Category: CategoryPerformance},
+	}
+	if count := policy.CountFindings(lines); count != 2 {
+		t.Errorf("Expected 2 blocking findings, got %d", count)
+	}
+}
+
+func TestParseChecklist(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		expected    Checklist
+		complete    bool
+	}{
+		{
+			name:        "No checklist",
+			description: "Fixes the crash on launch.",
+		},
+		{
+			name:        "Partially checked",
+			description: "## Checklist\n- [x] Tests added\n* [ ] Release notes updated\n  - [X] QA signed off\n",
+			expected:    Checklist{Items: 3, Unchecked: 1},
+		},
+		{
+			name:        "Complete",
+			description: "- [x] Tests added\n- [x] Release notes updated",
+			expected:    Checklist{Items: 2},
+			complete:    true,
+		},
+		{
+			name:        "Empty items are ignored",
+			description: "- [ ]\n- [x] Tests added",
+			expected:    Checklist{Items: 1},
+			complete:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checklist := ParseChecklist(tt.description)
+			if checklist != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, checklist)
+			}
+			if checklist.Complete() != tt.complete {
+				t.Errorf("Expected complete to be %t", tt.complete)
+			}
+		})
+	}
+}
+
+func TestFormatReleaseRisk(t *testing.T) {
+	checklist := Checklist{Items: 2, Unchecked: 1}.String()
+	expected := "**Medium**: touches the payment flow\n\n" + checklist
+	if risk := FormatReleaseRisk(" **Medium**: touches the payment flow\n", checklist); risk != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, risk)
+	}
+	if risk := FormatReleaseRisk("", ""); risk != "" {
+		t.Errorf("Expected an empty section, got %q", risk)
+	}
+}
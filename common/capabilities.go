@@ -0,0 +1,52 @@
+package common
+
+// Capabilities lists the features of a code review provider the posted comments can rely on.
+// Formatting code checks the capabilities instead of the provider name, the markdown renderer
+// maps each unsupported feature to its nearest equivalent.
+type Capabilities struct {
+	Collapsible   bool // Renders <details> sections, otherwise the content is shown under a bold title
+	Suggestions   bool // Applies suggestion code blocks, otherwise the original and suggested code are shown
+	Callouts      bool // Renders note callouts, otherwise a plain quote with an icon is used
+	MentionAuthor bool // The summary mentions the author, for providers not notifying them of new comments
+	Diagrams      bool // Renders mermaid code blocks, otherwise an outline of the diagram is shown
+
+	MaxCommentLength int // Longer comments are split into multiple parts
+}
+
+var providerCapabilities = map[string]Capabilities{
+	ProviderGitHub: {
+		Collapsible: true,
+		Suggestions: true,
+		Callouts:    true,
+		Diagrams:    true,
+
+		MaxCommentLength: GitHubCommentLimit,
+	},
+	ProviderGitLab: {
+		Collapsible: true,
+		Suggestions: true,
+		Callouts:    true,
+		Diagrams:    true,
+
+		MaxCommentLength: GitLabCommentLimit,
+	},
+	ProviderBitbucket: {
+		MentionAuthor: true,
+
+		MaxCommentLength: BitbucketCommentLimit,
+	},
+	ProviderGitea: {
+		Collapsible: true,
+		Diagrams:    true,
+
+		MaxCommentLength: GiteaCommentLimit,
+	},
+}
+
+// CapabilitiesOf returns the capabilities of the provider, defaults to GitHub like the markdown renderer
+func CapabilitiesOf(provider string) Capabilities {
+	if capabilities, ok := providerCapabilities[provider]; ok {
+		return capabilities
+	}
+	return providerCapabilities[ProviderGitHub]
+}
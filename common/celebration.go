@@ -0,0 +1,53 @@
+package common
+
+// GetCelebration returns the celebration section configured in the settings.
+// The deprecated haiku setting turns the section off when disabled.
+func (s Settings) GetCelebration() Celebration {
+	celebration := s.Reviews.Celebration
+	switch {
+	case !s.Reviews.Haiku:
+		celebration.Type = CelebrationNone
+	case celebration.Type == "":
+		celebration.Type = CelebrationHaiku
+	case celebration.Type == CelebrationCustom && celebration.Prompt == "":
+		celebration.Type = CelebrationNone
+	}
+	return celebration
+}
+
+// Enabled returns true if the summary has a celebration section
+func (c Celebration) Enabled() bool {
+	switch c.Type {
+	case CelebrationHaiku, CelebrationLimerick, CelebrationCustom:
+		return true
+	}
+	return false
+}
+
+// GetTitle returns the heading of the celebration section
+func (c Celebration) GetTitle() string {
+	if c.Title != "" {
+		return c.Title
+	}
+
+	switch c.Type {
+	case CelebrationHaiku:
+		return "Haiku"
+	case CelebrationLimerick:
+		return "Limerick"
+	}
+	return "Takeaways"
+}
+
+// GetInstructions returns the instructions for the LLM on writing the celebration section
+func (c Celebration) GetInstructions() string {
+	switch c.Type {
+	case CelebrationHaiku:
+		return "A whimsical, short haiku to celebrate the changes as 'Bit Bot'. Format the haiku as a quote using the '>' symbol and feel free to use emojis where relevant."
+	case CelebrationLimerick:
+		return "A playful, five line limerick to celebrate the changes as 'Bit Bot'. Format the limerick as a quote using the '>' symbol and feel free to use emojis where relevant."
+	case CelebrationCustom:
+		return c.Prompt
+	}
+	return "Leave empty."
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+var (
+	stepRegex        = regexp.MustCompile(`^[+-]\s*-\s*([\w\-]+)(?:@[\w.\-]+)?:?\s*(?:\{\s*\})?\s*$`)
+	machineTypeRegex = regexp.MustCompile(`^([+-])\s*(machine_type_id|stack):\s*"?([\w.\-]+)"?\s*$`)
+	prTriggerRegex   = regexp.MustCompile(`^\+\s*-?\s*(pull_request_source_branch|pull_request_target_branch|pull_request_label):`)
+)
+
+// CIConfigAnalysis is the result of the static analysis of Bitrise CI configuration changes
+type CIConfigAnalysis struct {
+	Files            []string // Changed CI configuration files
+	ValidationErrors []string // Schema validation errors reported by the bitrise CLI
+	Findings         []string // Removed caches, machine type changes and new pull request triggers
+}
+
+// IsCIConfigFile returns true for bitrise.yml and step.yml files
+func IsCIConfigFile(path string) bool {
+	switch filepath.Base(path) {
+	case "bitrise.yml", "bitrise.yaml", "step.yml", "step.yaml":
+		return true
+	}
+	return false
+}
+
+// AnalyzeCIConfig analyzes the CI configuration changes of the diff, returns nil if no CI configuration was changed.
+// The configuration files are validated as of the reviewed commit, not the working directory.
+func AnalyzeCIConfig(client *git.Client, commitHash, diff string) *CIConfigAnalysis {
+	analysis := &CIConfigAnalysis{}
+
+	var removedSteps []string
+	addedSteps := map[string]bool{}
+	machineTypes := map[string][2]string{}
+
+	file := ""
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if IsCIConfigFile(file) {
+				analysis.Files = append(analysis.Files, file)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "--- ") || !IsCIConfigFile(file) {
+			continue
+		}
+
+		if match := stepRegex.FindStringSubmatch(line); match != nil {
+			if strings.HasPrefix(line, "-") {
+				removedSteps = append(removedSteps, match[1])
+			} else {
+				addedSteps[match[1]] = true
+			}
+		}
+
+		if match := machineTypeRegex.FindStringSubmatch(line); match != nil {
+			change := machineTypes[match[2]]
+			if match[1] == "-" {
+				change[0] = match[3]
+			} else {
+				change[1] = match[3]
+			}
+			machineTypes[match[2]] = change
+		}
+
+		if match := prTriggerRegex.FindStringSubmatch(line); match != nil {
+			analysis.Findings = append(analysis.Findings,
+				fmt.Sprintf("New pull request trigger (%s) in %s: check that secrets are not exposed to builds of untrusted forks", match[1], file))
+		}
+	}
+
+	if len(analysis.Files) == 0 {
+		return nil
+	}
+
+	reported := map[string]bool{}
+	for _, step := range removedSteps {
+		if strings.Contains(step, "cache") && !addedSteps[step] && !reported[step] {
+			reported[step] = true
+			analysis.Findings = append(analysis.Findings, fmt.Sprintf("Cache step removed: %s, builds may get slower", step))
+		}
+	}
+
+	for _, key := range []string{"machine_type_id", "stack"} {
+		change, ok := machineTypes[key]
+		if !ok || change[0] == change[1] {
+			continue
+		}
+		analysis.Findings = append(analysis.Findings,
+			fmt.Sprintf("%s changed from '%s' to '%s': check the credit and billing impact", key, change[0], change[1]))
+	}
+
+	for _, file := range analysis.Files {
+		if filepath.Base(file) != "bitrise.yml" && filepath.Base(file) != "bitrise.yaml" {
+			continue
+		}
+		content, err := client.GetFileContent(commitHash, file)
+		if err != nil || content == "" {
+			// The file was deleted by the changes
+			continue
+		}
+		if err := validateBitriseConfig(file, content); err != nil {
+			analysis.ValidationErrors = append(analysis.ValidationErrors, fmt.Sprintf("%s: %v", file, err))
+		}
+	}
+
+	return analysis
+}
+
+// validateBitriseConfig validates the content of the configuration file with the bitrise CLI, it is skipped if the CLI is not installed
+func validateBitriseConfig(file, content string) error {
+	if _, err := exec.LookPath("bitrise"); err != nil {
+		logger.Warnf("Skipping validation of %s, the bitrise CLI is not available: %v", file, err)
+		return nil
+	}
+
+	config, err := os.CreateTemp("", "bitrise-*.yml")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file: %v", err)
+	}
+	defer os.Remove(config.Name())
+	_, err = config.WriteString(content)
+	if closeErr := config.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write a temporary file: %v", err)
+	}
+
+	// The CLI exits with an error for invalid configurations, the reason is in the JSON of its output
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "bitrise", "validate", "--config", config.Name(), "--format", "json").Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to run bitrise validate: %v", err)
+	}
+
+	var result struct {
+		Data struct {
+			Config struct {
+				IsValid bool   `json:"is_valid"`
+				Error   string `json:"error"`
+			} `json:"config"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	if jsonErr := json.Unmarshal(output, &result); jsonErr != nil {
+		if err != nil {
+			return fmt.Errorf("bitrise validate failed with exit code %d", exitErr.ExitCode())
+		}
+		logger.Warnf("Failed to parse the validation result of %s: %v", file, jsonErr)
+		return nil
+	}
+
+	switch {
+	case result.Error != "":
+		return fmt.Errorf("%s", result.Error)
+	case !result.Data.Config.IsValid:
+		return fmt.Errorf("%s", result.Data.Config.Error)
+	}
+	return nil
+}
+
+// String formats the analysis as context for the LLM
+func (a *CIConfigAnalysis) String() string {
+	if a == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Changed CI configuration files: " + strings.Join(a.Files, ", ") + "\n")
+
+	if len(a.ValidationErrors) == 0 {
+		builder.WriteString("Schema validation: passed or skipped\n")
+	} else {
+		builder.WriteString("Schema validation errors:\n")
+		for _, validationErr := range a.ValidationErrors {
+			builder.WriteString("- " + validationErr + "\n")
+		}
+	}
+
+	if len(a.Findings) > 0 {
+		builder.WriteString("Static analysis findings:\n")
+		for _, finding := range a.Findings {
+			builder.WriteString("- " + finding + "\n")
+		}
+	}
+
+	return builder.String()
+}
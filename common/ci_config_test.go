@@ -0,0 +1,82 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+func TestAnalyzeCIConfig(t *testing.T) {
+	client := git.NewClient(fakeRunner{})
+	if analysis := AnalyzeCIConfig(client, "head", "+++ b/main.go\n+func main() {}\n"); analysis != nil {
+		t.Errorf("Expected no analysis without CI configuration changes, got %+v", analysis)
+	}
+
+	// The file does not exist at the commit, validation is skipped
+	diff := `diff --git a/ci/bitrise.yml b/ci/bitrise.yml
+--- a/ci/bitrise.yml
++++ b/ci/bitrise.yml
+@@ -1,12 +1,12 @@
+ trigger_map:
++- pull_request_source_branch: "*"
+ workflows:
+   primary:
+     meta:
+       bitrise.io:
+-        machine_type_id: g2.mac.medium
++        machine_type_id: g2.mac.large
+     steps:
+-    - restore-cache@2: {}
+-    - git-clone@8: {}
++    - git-clone@8: {}
+     - script@1:
+`
+
+	analysis := AnalyzeCIConfig(client, "head", diff)
+	if analysis == nil {
+		t.Fatal("Expected analysis of the CI configuration changes")
+	}
+	if len(analysis.Files) != 1 || analysis.Files[0] != "ci/bitrise.yml" {
+		t.Errorf("Expected ci/bitrise.yml to be analyzed, got %v", analysis.Files)
+	}
+
+	output := analysis.String()
+	expected := []string{
+		"New pull request trigger (pull_request_source_branch)",
+		"Cache step removed: restore-cache",
+		"machine_type_id changed from 'g2.mac.medium' to 'g2.mac.large'",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected analysis to contain %q, got:\n%s", e, output)
+		}
+	}
+	if strings.Contains(output, "git-clone") {
+		t.Errorf("Expected re-added steps not to be reported, got:\n%s", output)
+	}
+}
+
+func TestValidateBitriseConfig(t *testing.T) {
+	// A fake bitrise CLI failing the validation like the real one, with the reason in its output
+	bin := t.TempDir()
+	script := "#!/bin/sh\nif grep -q 'primary: {}' \"$3\"; then\n  echo '{\"data\":{\"config\":{\"is_valid\":false,\"error\":\"workflow primary has no steps\"}}}'\n  exit 1\nfi\necho '{\"data\":{\"config\":{\"is_valid\":true}}}'\n"
+	if err := os.WriteFile(filepath.Join(bin, "bitrise"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The content at the reviewed commit is validated, not the file of the working directory
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("bitrise.yml", []byte("workflows:\n  primary:\n    steps: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client := git.NewClient(fakeRunner{"show head:bitrise.yml": "workflows:\n  primary: {}\n"})
+	diff := "diff --git a/bitrise.yml b/bitrise.yml\n--- a/bitrise.yml\n+++ b/bitrise.yml\n@@ -1 +1,2 @@\n workflows:\n+  primary: {}\n"
+	analysis := AnalyzeCIConfig(client, "head", diff)
+	if analysis == nil || len(analysis.ValidationErrors) != 1 || analysis.ValidationErrors[0] != "bitrise.yml: workflow primary has no steps" {
+		t.Errorf("Expected the reason of the validator, got %+v", analysis)
+	}
+}
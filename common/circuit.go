@@ -0,0 +1,126 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// circuitBreakerThreshold is the number of consecutive failed requests to a host after which its remaining requests are skipped.
+// Retries count as separate requests, so the circuit trips after about two failed API calls.
+const circuitBreakerThreshold = 6
+
+// ErrCircuitOpen is the cause of the requests skipped because their host is unavailable
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitBreaker tracks the consecutive failures of a host, it stays open for the rest of the run once tripped
+type circuitBreaker struct {
+	host     string
+	failures int
+	open     bool
+	skipped  int
+	lastErr  string
+}
+
+var (
+	circuitMu       sync.Mutex
+	circuitBreakers = map[string]*circuitBreaker{}
+)
+
+// circuitBreakerTransport skips the requests to hosts with an open circuit instead of waiting for them to time out
+type circuitBreakerTransport struct {
+	base http.RoundTripper
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := checkCircuit(host); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil:
+		recordFailure(host, err.Error())
+	case resp.StatusCode >= http.StatusInternalServerError:
+		recordFailure(host, fmt.Sprintf("HTTP %d", resp.StatusCode))
+	default:
+		recordSuccess(host)
+	}
+	return resp, err
+}
+
+// checkCircuit returns an error if the circuit of the host is open
+func checkCircuit(host string) error {
+	circuitMu.Lock()
+	defer circuitMu.Unlock()
+
+	breaker, ok := circuitBreakers[host]
+	if !ok || !breaker.open {
+		return nil
+	}
+	breaker.skipped++
+	return &APIError{
+		Kind:    ErrorKindUnavailable,
+		Service: host,
+		Err:     fmt.Errorf("%w after %d consecutive failures, last error: %s", ErrCircuitOpen, breaker.failures, breaker.lastErr),
+	}
+}
+
+// recordFailure counts a failed request, opening the circuit of the host at the threshold
+func recordFailure(host, cause string) {
+	circuitMu.Lock()
+	defer circuitMu.Unlock()
+
+	breaker, ok := circuitBreakers[host]
+	if !ok {
+		breaker = &circuitBreaker{host: host}
+		circuitBreakers[host] = breaker
+	}
+	breaker.failures++
+	breaker.lastErr = cause
+	if !breaker.open && breaker.failures >= circuitBreakerThreshold {
+		breaker.open = true
+		logger.Warnf("%s failed %d times in a row, skipping the remaining requests to it", host, breaker.failures)
+	}
+}
+
+// recordSuccess resets the consecutive failures of the host
+func recordSuccess(host string) {
+	circuitMu.Lock()
+	defer circuitMu.Unlock()
+
+	if breaker, ok := circuitBreakers[host]; ok && !breaker.open {
+		breaker.failures = 0
+	}
+}
+
+// OpenCircuitsError returns a single error listing the unavailable hosts and the requests skipped because of them, nil if all hosts are available
+func OpenCircuitsError() error {
+	circuitMu.Lock()
+	defer circuitMu.Unlock()
+
+	var hosts []string
+	for _, host := range sortedKeys(circuitBreakers) {
+		breaker := circuitBreakers[host]
+		if breaker.open {
+			hosts = append(hosts, fmt.Sprintf("%s (%d consecutive failures, %d requests skipped, last error: %s)",
+				host, breaker.failures, breaker.skipped, breaker.lastErr))
+		}
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unavailable services: %s", strings.Join(hosts, "; "))
+}
+
+// resetCircuitBreakers closes all circuits, used by tests
+func resetCircuitBreakers() {
+	circuitMu.Lock()
+	defer circuitMu.Unlock()
+	circuitBreakers = map[string]*circuitBreaker{}
+}
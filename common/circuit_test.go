@@ -0,0 +1,82 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerSkipsUnavailableHost(t *testing.T) {
+	resetCircuitBreakers()
+	defer resetCircuitBreakers()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	config := DefaultRetryConfig()
+	config.RetryWaitMin, config.RetryWaitMax = time.Millisecond, time.Millisecond
+	client := NewRetryableClient(config).StandardClient()
+
+	// 4 attempts per call, the second call trips the circuit during its retries
+	for range 2 {
+		if resp, err := client.Get(server.URL); err == nil {
+			resp.Body.Close()
+		}
+	}
+	if calls.Load() != circuitBreakerThreshold {
+		t.Errorf("Expected %d requests before the circuit opened, got %d", circuitBreakerThreshold, calls.Load())
+	}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the request to be skipped, got %v", err)
+	}
+	if ExitCode(err) != ExitCodeProvider || Remediation(err) == "" {
+		t.Errorf("Expected an unavailable API error, got %v", err)
+	}
+	if calls.Load() != circuitBreakerThreshold {
+		t.Errorf("Expected no request to reach the host after the circuit opened, got %d", calls.Load())
+	}
+
+	summary := OpenCircuitsError()
+	if summary == nil || !strings.Contains(summary.Error(), strings.TrimPrefix(server.URL, "http://")) || !strings.Contains(summary.Error(), "HTTP 502") {
+		t.Errorf("Expected the host in the summary, got %v", summary)
+	}
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	resetCircuitBreakers()
+	defer resetCircuitBreakers()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other request fails, the failures are never consecutive
+		if calls.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultRetryConfig()
+	config.RetryWaitMin, config.RetryWaitMax = time.Millisecond, time.Millisecond
+	client := NewRetryableClient(config).StandardClient()
+
+	for range circuitBreakerThreshold {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Expected the request to succeed after a retry, got %v", err)
+		}
+		resp.Body.Close()
+	}
+	if err := OpenCircuitsError(); err != nil {
+		t.Errorf("Expected no open circuit, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ClarificationHeader identifies the comment with the questions to the author of the pull request
+const ClarificationHeader = "[bitrise-plugin-ai-reviewer]: questions"
+
+// clarificationQuestionRegex matches the numbered questions of the questions comment
+var clarificationQuestionRegex = regexp.MustCompile(`(?m)^\d+\. (.+)$`)
+
+// Clarification is the thread of the questions asked from the author of the pull request and the answers
+type Clarification struct {
+	Questions []string
+	Answers   []string
+}
+
+// Answered returns true if the author replied to the questions
+func (c Clarification) Answered() bool {
+	return len(c.Questions) > 0 && len(c.Answers) > 0
+}
+
+// FormatClarificationQuestions formats the comment asking the questions from the author
+func FormatClarificationQuestions(provider string, questions []string) string {
+	var builder strings.Builder
+	builder.WriteString(ClarificationHeader + "\n\n")
+	builder.WriteString("## Questions\n")
+	builder.WriteString("A few changes are ambiguous, reply to this comment to clarify them. The answers are taken into account on the next review.\n\n")
+	for i, question := range questions {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, strings.Join(strings.Fields(question), " ")))
+	}
+
+	return ApplyStyle(builder.String()) + commentFooter(NewMarkdownRenderer(provider)) + commentMetadata()
+}
+
+// ParseClarificationQuestions returns the questions of a questions comment
+func ParseClarificationQuestions(body string) []string {
+	var questions []string
+	for _, match := range clarificationQuestionRegex.FindAllStringSubmatch(body, -1) {
+		questions = append(questions, strings.TrimSpace(match[1]))
+	}
+	return questions
+}
+
+// IsPluginComment returns true for the comments posted by the plugin
+func IsPluginComment(body string) bool {
+	return strings.HasPrefix(strings.TrimSpace(body), "[bitrise-plugin-ai-reviewer]")
+}
@@ -0,0 +1,31 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClarificationQuestions(t *testing.T) {
+	questions := []string{"Is the retry count change intentional?", "Should the cache\nbe shared?"}
+
+	body := FormatClarificationQuestions(ProviderGitHub, questions)
+	if !IsPluginComment(body) {
+		t.Errorf("Expected the questions comment to be a plugin comment, got:\n%s", body)
+	}
+
+	parsed := ParseClarificationQuestions(body)
+	expected := []string{"Is the retry count change intentional?", "Should the cache be shared?"}
+	if !slices.Equal(parsed, expected) {
+		t.Errorf("Expected %v, got %v", expected, parsed)
+	}
+
+	if IsPluginComment("Yes, it is intentional") {
+		t.Error("Expected the answer of the author not to be a plugin comment")
+	}
+	if (Clarification{Questions: parsed}).Answered() {
+		t.Error("Expected the questions without replies to be unanswered")
+	}
+	if !(Clarification{Questions: parsed, Answers: []string{"Yes"}}).Answered() {
+		t.Error("Expected the questions with replies to be answered")
+	}
+}
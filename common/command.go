@@ -0,0 +1,124 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+const (
+	// commandTimeout is the timeout in seconds for commands run on behalf of the LLM
+	commandTimeout = 120
+	// maxCommandOutputLength limits the command output returned to the LLM
+	maxCommandOutputLength = 10000
+)
+
+// allowedCommands is the whitelist of commands the LLM can run, the path argument is appended as the last argument
+var allowedCommands = map[string][]string{
+	"terraform validate": {"terraform", "-chdir=%s", "validate", "-no-color"},
+	"kubeval":            {"kubeval", "--strict", "%s"},
+}
+
+// inheritedEnvironment are the only variables passed on to the processes of third parties, the CI secrets are not
+var inheritedEnvironment = []string{"PATH", "HOME"}
+
+// minimalEnvironment returns the inherited variables of the environment, to be extended with the configured ones
+func minimalEnvironment() []string {
+	var env []string
+	for _, name := range inheritedEnvironment {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// AllowedCommands returns the names of the whitelisted commands
+func AllowedCommands() []string {
+	names := make([]string, 0, len(allowedCommands))
+	for name := range allowedCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunCommand runs a whitelisted command on a path inside the repository
+func RunCommand(name, path string) (string, error) {
+	template, ok := allowedCommands[name]
+	if !ok {
+		return "", fmt.Errorf("command %s is not allowed, use one of: %s", name, strings.Join(AllowedCommands(), ", "))
+	}
+
+	path = filepath.Clean(path)
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, "../") {
+		return "", errors.New("path must be relative to the repository root")
+	}
+	// The path is an argument of the program, it must not be parsed as a flag
+	if strings.HasPrefix(path, "-") {
+		return "", errors.New("path cannot start with -")
+	}
+	// The providers and modules of a committed .terraform directory would run as plugins of terraform
+	if name == "terraform validate" {
+		if _, err := os.Stat(filepath.Join(path, ".terraform")); err == nil {
+			return "", fmt.Errorf("%s contains a .terraform directory, it is not validated as its plugins may come from the pull request", path)
+		}
+	}
+
+	if _, err := exec.LookPath(template[0]); err != nil {
+		return "", fmt.Errorf("%s is not installed on this machine", template[0])
+	}
+
+	args := make([]string, 0, len(template)-1)
+	for _, arg := range template[1:] {
+		args = append(args, strings.ReplaceAll(arg, "%s", path))
+	}
+
+	logger.Debugf("Running command: %s %s", template[0], strings.Join(args, " "))
+	return runCappedCommand(name, commandTimeout, template[0], args...)
+}
+
+// runCappedCommand runs the program with a timeout in seconds and truncates its output for the LLM.
+// The output is returned even if the command fails, as it usually describes the issues found.
+func runCappedCommand(name string, timeout int, program string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	// The arguments come from the LLM, the command can't read the secrets of the environment
+	command := exec.CommandContext(ctx, program, args...)
+	command.Env = minimalEnvironment()
+	output, err := command.CombinedOutput()
+	result := truncateOutput(string(output))
+
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("command %s timed out after %d seconds", name, timeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("failed to run %s: %v", name, err)
+		}
+		return fmt.Sprintf("%s failed with exit code %d:\n%s", name, exitErr.ExitCode(), result), nil
+	}
+
+	if strings.TrimSpace(result) == "" {
+		return fmt.Sprintf("%s succeeded without output", name), nil
+	}
+	return result, nil
+}
+
+// truncateOutput limits the output of a tool returned to the LLM
+func truncateOutput(output string) string {
+	if len(output) > maxCommandOutputLength {
+		return output[:maxCommandOutputLength] + "\n... output truncated ..."
+	}
+	return output
+}
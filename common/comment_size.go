@@ -0,0 +1,168 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// Size limits of a single comment in characters, longer comments are rejected by the providers
+const (
+	GitHubCommentLimit    = 65536
+	GitLabCommentLimit    = 1000000
+	BitbucketCommentLimit = 32768 // Not documented, kept conservative
+	GiteaCommentLimit     = 65536 // Not documented, kept at the limit of GitHub
+)
+
+// commentPrefix starts every comment posted by the plugin
+const commentPrefix = "[bitrise-plugin-ai-reviewer]: "
+
+// partOverhead is reserved in every part for the continuation header and the note linking to the next part
+const partOverhead = 200
+
+// MaxCommentLength returns the size limit of a comment on the provider
+func MaxCommentLength(provider string) int {
+	return CapabilitiesOf(provider).MaxCommentLength
+}
+
+// ContinuationHeader identifies the part of a split comment after the first one.
+// It does not start with the header of the comment, so looking up the comment by its header finds the first part only.
+func ContinuationHeader(header string, part, total int) string {
+	return fmt.Sprintf("%scontinued %s (part %d/%d)", commentPrefix, strings.TrimPrefix(header, commentPrefix), part, total)
+}
+
+// ContinuationPart returns the part number if the comment continues the comment with the header
+func ContinuationPart(body, header string) (int, bool) {
+	prefix := fmt.Sprintf("%scontinued %s (part ", commentPrefix, strings.TrimPrefix(header, commentPrefix))
+	if !strings.HasPrefix(body, prefix) {
+		return 0, false
+	}
+	number, _, found := strings.Cut(strings.TrimPrefix(body, prefix), "/")
+	if !found {
+		return 0, false
+	}
+	part, err := strconv.Atoi(number)
+	if err != nil || part < 2 {
+		return 0, false
+	}
+	return part, true
+}
+
+// SplitComment splits the comment into parts fitting the limit, the first part keeps the header of the comment.
+// The comment is split between paragraphs outside of code blocks and collapsible sections,
+// a single section longer than a whole part is trimmed.
+func SplitComment(header, body string, limit int) []string {
+	if utf8.RuneCountInString(body) <= limit {
+		return []string{body}
+	}
+
+	budget := max(limit-partOverhead, partOverhead)
+	var chunks []string
+	var current strings.Builder
+	for _, block := range commentBlocks(body) {
+		if utf8.RuneCountInString(block) > budget {
+			logger.Warnf("A section of the comment is longer than %d characters, trimming it", budget)
+			block = trimBlock(block, budget)
+		}
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+1+utf8.RuneCountInString(block) > budget {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+		current.WriteString(block)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+
+	parts := make([]string, len(chunks))
+	for idx, chunk := range chunks {
+		part := strings.TrimSpace(chunk)
+		if idx > 0 {
+			part = ContinuationHeader(header, idx+1, len(chunks)) + "\n\n" + part
+		}
+		if idx < len(chunks)-1 {
+			part += fmt.Sprintf("\n\n_Continued in the next comment (part %d/%d)_", idx+1, len(chunks))
+		}
+		parts[idx] = part
+	}
+	return parts
+}
+
+// commentBlocks splits the comment at the blank lines outside of code blocks and collapsible sections.
+// Joining the blocks with new lines gives back the comment.
+func commentBlocks(body string) []string {
+	var blocks []string
+	var current []string
+	var state blockState
+	for _, line := range strings.Split(body, "\n") {
+		current = append(current, line)
+		state.update(line)
+		if strings.TrimSpace(line) == "" && state.closed() {
+			blocks = append(blocks, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+// trimBlock cuts the block at a line boundary to fit the budget, closing the code blocks and collapsible sections left open
+func trimBlock(block string, budget int) string {
+	const note = "\n\n_Trimmed, the section is too long for a comment_"
+
+	var kept []string
+	var state blockState
+	length := 0
+	for _, line := range strings.Split(block, "\n") {
+		next := state
+		next.update(line)
+		if length+utf8.RuneCountInString(line)+1+len(note)+len(next.closing()) > budget {
+			break
+		}
+		kept = append(kept, line)
+		length += utf8.RuneCountInString(line) + 1
+		state = next
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n") + state.closing() + note
+}
+
+// blockState tracks the code blocks and collapsible sections open at a line of a comment
+type blockState struct {
+	inCodeBlock bool
+	openDetails int
+}
+
+func (s *blockState) update(line string) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "```") {
+		s.inCodeBlock = !s.inCodeBlock
+		return
+	}
+	if s.inCodeBlock {
+		return
+	}
+	s.openDetails += strings.Count(trimmed, "<details>") - strings.Count(trimmed, "</details>")
+	s.openDetails = max(s.openDetails, 0)
+}
+
+func (s blockState) closed() bool {
+	return !s.inCodeBlock && s.openDetails == 0
+}
+
+// closing returns the markup closing the open code block and collapsible sections
+func (s blockState) closing() string {
+	closing := ""
+	if s.inCodeBlock {
+		closing += "\n```"
+	}
+	closing += strings.Repeat("\n\n</details>", s.openDetails)
+	return closing
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitCommentShortComment(t *testing.T) {
+	body := "[bitrise-plugin-ai-reviewer]: summary\n\n## Summary\nShort"
+	parts := SplitComment("[bitrise-plugin-ai-reviewer]: summary", body, GitHubCommentLimit)
+	if len(parts) != 1 || parts[0] != body {
+		t.Errorf("Expected the comment unchanged, got %q", parts)
+	}
+}
+
+func TestSplitCommentParts(t *testing.T) {
+	header := Summary{}.Header()
+	var builder strings.Builder
+	builder.WriteString(header + "\n\n")
+	for idx := range 40 {
+		builder.WriteString(fmt.Sprintf("## Section %d\n%s\n\n", idx, strings.Repeat("word ", 20)))
+		builder.WriteString(fmt.Sprintf("<details>\n<summary>Files %d</summary>\n\n%s\n\n</details>\n\n", idx, strings.Repeat("file.go\n", 5)))
+		builder.WriteString("```go\nfunc main() {\n\n}\n```\n\n")
+	}
+
+	parts := SplitComment(header, builder.String(), 1500)
+	if len(parts) < 2 {
+		t.Fatalf("Expected the comment to be split, got %d parts", len(parts))
+	}
+	if !strings.HasPrefix(parts[0], header) {
+		t.Errorf("Expected the first part to keep the header, got:\n%s", parts[0])
+	}
+
+	for idx, part := range parts {
+		if utf8.RuneCountInString(part) > 1500 {
+			t.Errorf("Expected part %d to fit the limit, got %d characters", idx+1, utf8.RuneCountInString(part))
+		}
+		if strings.Count(part, "<details>") != strings.Count(part, "</details>") {
+			t.Errorf("Expected part %d not to split a collapsible section:\n%s", idx+1, part)
+		}
+		if strings.Count(part, "```")%2 != 0 {
+			t.Errorf("Expected part %d not to split a code block:\n%s", idx+1, part)
+		}
+		if idx > 0 {
+			if strings.HasPrefix(part, header) {
+				t.Errorf("Expected part %d not to be found by the header of the summary", idx+1)
+			}
+			if number, ok := ContinuationPart(part, header); !ok || number != idx+1 {
+				t.Errorf("Expected part %d to be recognized as a continuation, got %d, %v", idx+1, number, ok)
+			}
+			if !IsPluginComment(part) {
+				t.Errorf("Expected part %d to be a plugin comment", idx+1)
+			}
+		}
+		if idx < len(parts)-1 && !strings.Contains(part, fmt.Sprintf("(part %d/%d)", idx+1, len(parts))) {
+			t.Errorf("Expected part %d to link the next part:\n%s", idx+1, part)
+		}
+	}
+	for idx := range 40 {
+		if !strings.Contains(strings.Join(parts, "\n"), fmt.Sprintf("## Section %d\n", idx)) {
+			t.Errorf("Expected section %d to be posted", idx)
+		}
+	}
+}
+
+func TestSplitCommentTrimsLongSection(t *testing.T) {
+	header := Summary{}.Header()
+	body := header + "\n\n## Summary\nShort\n\n<details>\n<summary>Nitpicks</summary>\n\n```diff\n" +
+		strings.Repeat("+ a long line of the suggestion\n", 200) + "```\n\n</details>\n\n## Tips\nLast"
+
+	parts := SplitComment(header, body, 1000)
+	joined := strings.Join(parts, "\n")
+	for idx, part := range parts {
+		if utf8.RuneCountInString(part) > 1000 {
+			t.Errorf("Expected part %d to fit the limit, got %d characters", idx+1, utf8.RuneCountInString(part))
+		}
+		if strings.Count(part, "<details>") != strings.Count(part, "</details>") || strings.Count(part, "```")%2 != 0 {
+			t.Errorf("Expected the trimmed section to be closed in part %d:\n%s", idx+1, part)
+		}
+	}
+	if !strings.Contains(joined, "_Trimmed, the section is too long for a comment_") {
+		t.Errorf("Expected the long section to be trimmed, got:\n%s", joined)
+	}
+	if !strings.Contains(joined, "## Tips\nLast") {
+		t.Errorf("Expected the sections after the trimmed one to be kept, got:\n%s", joined)
+	}
+}
+
+func TestContinuationPart(t *testing.T) {
+	header := Summary{}.Header()
+	if _, ok := ContinuationPart(header+"\n\nbody", header); ok {
+		t.Error("Expected the first part not to be a continuation")
+	}
+	if _, ok := ContinuationPart(ContinuationHeader("[bitrise-plugin-ai-reviewer]: questions", 2, 3), header); ok {
+		t.Error("Expected the continuation of another comment not to match")
+	}
+	if part, ok := ContinuationPart(ContinuationHeader(header, 3, 4)+"\n\nbody", header); !ok || part != 3 {
+		t.Errorf("Expected part 3, got %d, %v", part, ok)
+	}
+}
+
+func TestMaxCommentLength(t *testing.T) {
+	if MaxCommentLength(ProviderGitHub) != GitHubCommentLimit || MaxCommentLength(ProviderBitbucket) != BitbucketCommentLimit {
+		t.Error("Expected the limit of the provider")
+	}
+}
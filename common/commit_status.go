@@ -0,0 +1,51 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CommitStatusContext is the context of the commit status with the verdict of the review,
+// repositories can require it to pass before merging
+const CommitStatusContext = "ai-review/verdict"
+
+// States of the commit status
+const (
+	CommitStatusPending = "pending" // The review is running or deferred
+	CommitStatusSuccess = "success" // No findings failing the policies
+	CommitStatusFailure = "failure" // Findings failing the fail_on_severity, license or branch policies
+	CommitStatusError   = "error"   // The review failed
+)
+
+// maxCommitStatusDescription is the length of the longest description GitHub accepts
+const maxCommitStatusDescription = 140
+
+// CommitStatus is the verdict of the review set on the reviewed commit
+type CommitStatus struct {
+	State       string
+	Description string
+	TargetURL   string // Link of the status, the build running the review
+}
+
+// NewCommitStatus creates a status linking the Bitrise build, with the description shortened to the limit of the providers
+func NewCommitStatus(state, description string) CommitStatus {
+	if runes := []rune(description); len(runes) > maxCommitStatusDescription {
+		description = string(runes[:maxCommitStatusDescription-1]) + "…"
+	}
+	return CommitStatus{State: state, Description: description, TargetURL: os.Getenv("BITRISE_BUILD_URL")}
+}
+
+// VerdictStatus returns the status of the outcome of the run: failure if the findings fail a policy, error if the run failed
+func VerdictStatus(err error, findings int) CommitStatus {
+	var findingsErr *FindingsError
+	switch {
+	case err == nil && findings == 0:
+		return NewCommitStatus(CommitStatusSuccess, "No findings")
+	case err == nil:
+		return NewCommitStatus(CommitStatusSuccess, fmt.Sprintf("%d findings, none blocking the merge", findings))
+	case errors.As(err, &findingsErr):
+		return NewCommitStatus(CommitStatusFailure, findingsErr.Message)
+	}
+	return NewCommitStatus(CommitStatusError, "The review failed, see the build log")
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestVerdictStatus(t *testing.T) {
+	t.Setenv("BITRISE_BUILD_URL", "https://app.bitrise.io/build/1")
+
+	tests := []struct {
+		name     string
+		err      error
+		findings int
+		state    string
+	}{
+		{name: "no findings", state: CommitStatusSuccess},
+		{name: "findings under the threshold", findings: 3, state: CommitStatusSuccess},
+		{name: "findings failing a policy", err: &FindingsError{Count: 2, Message: "2 findings of high severity or higher"}, findings: 3, state: CommitStatusFailure},
+		{name: "failed run", err: NewLLMError(errors.New("timeout")), state: CommitStatusError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := VerdictStatus(tt.err, tt.findings)
+			if status.State != tt.state || status.Description == "" {
+				t.Errorf("Unexpected status %+v, expected the %s state", status, tt.state)
+			}
+			if status.TargetURL != "https://app.bitrise.io/build/1" {
+				t.Errorf("Expected the status to link the build, got %s", status.TargetURL)
+			}
+		})
+	}
+}
+
+func TestNewCommitStatusTruncatesDescription(t *testing.T) {
+	status := NewCommitStatus(CommitStatusFailure, strings.Repeat("é", 200))
+	if count := utf8.RuneCountInString(status.Description); count != maxCommitStatusDescription {
+		t.Errorf("Expected a description of %d characters, got %d", maxCommitStatusDescription, count)
+	}
+	if !strings.HasSuffix(status.Description, "…") {
+		t.Errorf("Expected the truncated description to end with an ellipsis, got %s", status.Description)
+	}
+}
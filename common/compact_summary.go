@@ -0,0 +1,22 @@
+package common
+
+// CompactSummary are the thresholds of the pull requests getting a short summary paragraph instead of the full layout.
+// A pull request is compact if it changes at most MaxFiles files or at most MaxChangedLines lines, 0 disables a threshold.
+type CompactSummary struct {
+	MaxFiles        int `yaml:"max_files"`
+	MaxChangedLines int `yaml:"max_changed_lines"`
+}
+
+// Applies returns true if the changes are small enough for the compact summary
+func (c CompactSummary) Applies(changedFiles, changedLines int) bool {
+	return (c.MaxFiles > 0 && changedFiles <= c.MaxFiles) || (c.MaxChangedLines > 0 && changedLines <= c.MaxChangedLines)
+}
+
+// ApplyCompactSummary returns the settings of the compact summary: no walkthrough, which would repeat the summary,
+// no celebration and nothing to collapse
+func (s Settings) ApplyCompactSummary() Settings {
+	s.Reviews.Walkthrough = false
+	s.Reviews.CollapseWalkthrough = false
+	s.Reviews.Celebration = Celebration{Type: CelebrationNone}
+	return s
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompactSummaryApplies(t *testing.T) {
+	compact := CompactSummary{MaxFiles: 1, MaxChangedLines: 30}
+
+	tests := []struct {
+		name         string
+		compact      CompactSummary
+		changedFiles int
+		changedLines int
+		expected     bool
+	}{
+		{"single file", compact, 1, 500, true},
+		{"tiny diff", compact, 3, 12, true},
+		{"large diff", compact, 3, 31, false},
+		{"disabled", CompactSummary{}, 1, 1, false},
+		{"files only", CompactSummary{MaxFiles: 2}, 2, 1000, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.compact.Applies(test.changedFiles, test.changedLines); got != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyCompactSummary(t *testing.T) {
+	settings := WithDefaultSettings().ApplyCompactSummary()
+	summary := Summary{
+		Summary:      "Fixes the rounding of the totals.",
+		Walkthrough:  []Walkthrough{{Files: "main.go", Summary: "Rounds the totals"}},
+		Celebration:  "A haiku",
+		FeatureFlags: "`new-checkout` added",
+	}
+
+	result := summary.String("github", settings)
+	if !strings.Contains(result, "Fixes the rounding of the totals.") {
+		t.Errorf("Expected the summary, got: %s", result)
+	}
+	if strings.Contains(result, "Walkthrough") || strings.Contains(result, "A haiku") || strings.Contains(result, "<details>") {
+		t.Errorf("Expected no walkthrough, celebration or collapsed section, got: %s", result)
+	}
+	if !strings.Contains(result, "## Feature flags") {
+		t.Errorf("Expected the feature flags outside the walkthrough, got: %s", result)
+	}
+}
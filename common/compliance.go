@@ -0,0 +1,385 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+const (
+	// licenseLookupTimeout is the timeout in seconds for resolving the license of a dependency
+	licenseLookupTimeout = 15
+	// unknownLicense is reported when the license of a dependency cannot be resolved
+	unknownLicense = "unknown"
+)
+
+// Package ecosystems supported by the compliance scan
+const (
+	EcosystemGo        = "go"
+	EcosystemNPM       = "npm"
+	EcosystemCocoaPods = "cocoapods"
+)
+
+// copyleftLicenses are the SPDX identifier families of copyleft licenses, like GPL for GPL-2.0 and GPL-3.0
+var copyleftLicenses = []string{"GPL", "AGPL", "LGPL", "MPL", "EPL", "EUPL", "SSPL", "OSL", "CC-BY-SA"}
+
+var (
+	goRequireRegex     = regexp.MustCompile(`^\+\s*(?:require\s+)?([\w.\-~]+(?:/[\w.\-~]+)+)\s+(v[\w.\-+]+)(?:\s*//.*)?$`)
+	packageJSONRegex   = regexp.MustCompile(`^\+\s*"(@?[\w.\-]+(?:/[\w.\-]+)?)"\s*:\s*"[\^~>=<\s]*(\d[\w.\-+]*)"\s*,?$`)
+	podfileLockRegex   = regexp.MustCompile(`^\+\s{2}- "?([\w.\-+/]+) \(([\w.\-+]+)\)"?:?$`)
+	packageJSONSection = regexp.MustCompile(`"(dependencies|devDependencies|peerDependencies|optionalDependencies)"\s*:`)
+)
+
+// Dependency is a package added by the changes
+type Dependency struct {
+	Ecosystem string
+	Name      string
+	Version   string
+	License   string
+}
+
+// ComplianceViolation is a dependency with a license not allowed by the policy
+type ComplianceViolation struct {
+	Dependency Dependency
+	Reason     string
+}
+
+// ComplianceReport is the result of the license scan of the added dependencies
+type ComplianceReport struct {
+	Dependencies []Dependency
+	Violations   []ComplianceViolation
+	Blocking     bool
+}
+
+// ParseAddedDependencies returns the dependencies added in go.mod, package.json and Podfile.lock files of the diff
+func ParseAddedDependencies(diff string) []Dependency {
+	var dependencies []Dependency
+	seen := map[string]bool{}
+	removedNames := map[string]bool{}
+
+	file := ""
+	inPackageJSONDeps := false
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = filepath.Base(strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/"))
+			inPackageJSONDeps = false
+			continue
+		}
+		if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "diff --git") {
+			continue
+		}
+
+		// A removed line of the same dependency makes the added one a version bump, not a new dependency
+		removed := strings.HasPrefix(line, "-")
+		if removed {
+			line = "+" + line[1:]
+		}
+
+		var dependency *Dependency
+		switch file {
+		case "go.mod":
+			if strings.Contains(line, "// indirect") {
+				continue
+			}
+			if match := goRequireRegex.FindStringSubmatch(line); match != nil {
+				dependency = &Dependency{Ecosystem: EcosystemGo, Name: match[1], Version: match[2]}
+			}
+		case "package.json":
+			if packageJSONSection.MatchString(line) {
+				inPackageJSONDeps = true
+				continue
+			}
+			if strings.Contains(line, "}") {
+				inPackageJSONDeps = false
+				continue
+			}
+			if !inPackageJSONDeps {
+				continue
+			}
+			if match := packageJSONRegex.FindStringSubmatch(line); match != nil {
+				dependency = &Dependency{Ecosystem: EcosystemNPM, Name: match[1], Version: match[2]}
+			}
+		case "Podfile.lock":
+			if match := podfileLockRegex.FindStringSubmatch(line); match != nil {
+				dependency = &Dependency{Ecosystem: EcosystemCocoaPods, Name: match[1], Version: match[2]}
+			}
+		}
+
+		if dependency == nil {
+			continue
+		}
+		if removed {
+			removedNames[dependency.Ecosystem+":"+dependency.Name] = true
+			continue
+		}
+		key := dependency.Ecosystem + ":" + dependency.Name + "@" + dependency.Version
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		dependencies = append(dependencies, *dependency)
+	}
+
+	var added []Dependency
+	for _, dependency := range dependencies {
+		if !removedNames[dependency.Ecosystem+":"+dependency.Name] {
+			added = append(added, dependency)
+		}
+	}
+	return added
+}
+
+// ScanCompliance resolves the licenses of the dependencies added by the diff and checks them against the policy
+func ScanCompliance(diff string, policy Compliance) *ComplianceReport {
+	report := &ComplianceReport{Blocking: policy.Blocking}
+	for _, dependency := range ParseAddedDependencies(diff) {
+		license, err := resolveLicense(dependency)
+		if err != nil {
+			logger.Warnf("Failed to resolve license of %s %s: %v", dependency.Name, dependency.Version, err)
+			license = unknownLicense
+		}
+		dependency.License = license
+		report.Dependencies = append(report.Dependencies, dependency)
+
+		if reason := checkLicense(license, policy); reason != "" {
+			report.Violations = append(report.Violations, ComplianceViolation{Dependency: dependency, Reason: reason})
+		}
+	}
+	return report
+}
+
+// checkLicense returns why the license is not allowed by the policy, or an empty string if it is allowed.
+// An SPDX expression like "MIT OR GPL-2.0" is only flagged if every alternative is.
+func checkLicense(license string, policy Compliance) string {
+	disallowed := func(id string) bool {
+		for _, other := range policy.DisallowedLicenses {
+			if id == normalizeLicenseID(other) {
+				return true
+			}
+		}
+		return false
+	}
+	if evaluateLicense(license, disallowed) {
+		return "disallowed license"
+	}
+
+	copyleft := func(id string) bool {
+		for _, family := range copyleftLicenses {
+			if id == family || strings.HasPrefix(id, family+"-") {
+				return true
+			}
+		}
+		return false
+	}
+	if policy.FlagCopyleft && evaluateLicense(license, copyleft) {
+		return "copyleft license"
+	}
+
+	return ""
+}
+
+// normalizeLicenseID normalizes an SPDX identifier for comparison, GPL-3.0-only and GPL-3.0-or-later are both GPL-3.0
+func normalizeLicenseID(id string) string {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	id = strings.TrimSuffix(id, "+")
+	id = strings.TrimSuffix(id, "-ONLY")
+	return strings.TrimSuffix(id, "-OR-LATER")
+}
+
+// evaluateLicense returns true if the SPDX expression matches: every alternative of an OR, or any license of an AND
+func evaluateLicense(expression string, matches func(id string) bool) bool {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression))
+	parser := licenseParser{tokens: tokens, matches: matches}
+	return parser.parseOr()
+}
+
+// licenseParser is a recursive descent parser of SPDX license expressions
+type licenseParser struct {
+	tokens  []string
+	matches func(id string) bool
+}
+
+func (p *licenseParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return strings.ToUpper(p.tokens[0])
+}
+
+func (p *licenseParser) next() string {
+	token := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return token
+}
+
+func (p *licenseParser) parseOr() bool {
+	result := p.parseAnd()
+	for p.peek() == "OR" {
+		p.next()
+		// Not short-circuited, the tokens of the alternative are consumed either way
+		alternative := p.parseAnd()
+		result = result && alternative
+	}
+	return result
+}
+
+func (p *licenseParser) parseAnd() bool {
+	result := p.parseTerm()
+	for p.peek() == "AND" {
+		p.next()
+		other := p.parseTerm()
+		result = result || other
+	}
+	return result
+}
+
+// parseTerm parses a parenthesized expression or a license, the words of a license without SPDX identifier are joined
+func (p *licenseParser) parseTerm() bool {
+	if p.peek() == "(" {
+		p.next()
+		result := p.parseOr()
+		if p.peek() == ")" {
+			p.next()
+		}
+		return result
+	}
+
+	var words []string
+	for token := p.peek(); token != "" && token != "(" && token != ")" && token != "OR" && token != "AND"; token = p.peek() {
+		if token == "WITH" {
+			// The exception of the license, like Classpath-exception-2.0, doesn't change its family
+			p.next()
+			if p.peek() != "" {
+				p.next()
+			}
+			continue
+		}
+		words = append(words, p.next())
+	}
+	return len(words) > 0 && p.matches(normalizeLicenseID(strings.Join(words, " ")))
+}
+
+// resolveLicense looks up the license of the dependency in its package registry
+func resolveLicense(dependency Dependency) (string, error) {
+	switch dependency.Ecosystem {
+	case EcosystemGo, EcosystemNPM:
+		return resolveDepsDevLicense(dependency)
+	case EcosystemCocoaPods:
+		return resolveCocoaPodsLicense(dependency)
+	}
+	return "", fmt.Errorf("unsupported ecosystem: %s", dependency.Ecosystem)
+}
+
+// resolveDepsDevLicense looks up the license in the deps.dev API
+func resolveDepsDevLicense(dependency Dependency) (string, error) {
+	apiURL := fmt.Sprintf("https://api.deps.dev/v3/systems/%s/packages/%s/versions/%s",
+		dependency.Ecosystem, url.PathEscape(dependency.Name), url.PathEscape(dependency.Version))
+
+	var version struct {
+		Licenses []string `json:"licenses"`
+	}
+	if err := getLicenseJSON("deps.dev", apiURL, &version); err != nil {
+		return "", err
+	}
+	if len(version.Licenses) == 0 {
+		return unknownLicense, nil
+	}
+	return strings.Join(version.Licenses, " OR "), nil
+}
+
+// resolveCocoaPodsLicense looks up the license in the podspec published on CocoaPods trunk
+func resolveCocoaPodsLicense(dependency Dependency) (string, error) {
+	// Subspecs share the license of the root pod
+	name := strings.Split(dependency.Name, "/")[0]
+	apiURL := fmt.Sprintf("https://trunk.cocoapods.org/api/v1/pods/%s/specs/%s", url.PathEscape(name), url.PathEscape(dependency.Version))
+
+	var spec struct {
+		License json.RawMessage `json:"license"`
+	}
+	if err := getLicenseJSON("CocoaPods", apiURL, &spec); err != nil {
+		return "", err
+	}
+
+	// The license is either a string or an object with the license type
+	var license string
+	if err := json.Unmarshal(spec.License, &license); err == nil && license != "" {
+		return license, nil
+	}
+	var licenseObject struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(spec.License, &licenseObject); err == nil && licenseObject.Type != "" {
+		return licenseObject.Type, nil
+	}
+	return unknownLicense, nil
+}
+
+func getLicenseJSON(service, apiURL string, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), licenseLookupTimeout*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+
+	client := NewRetryableClient(DefaultRetryConfig()).StandardClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return NewAPIError(service, resp.StatusCode, fmt.Errorf("failed to get license: HTTP %d", resp.StatusCode))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Failed returns true if the report has violations and the policy is blocking
+func (r *ComplianceReport) Failed() bool {
+	return r != nil && r.Blocking && len(r.Violations) > 0
+}
+
+// String formats the report as a markdown section body
+func (r *ComplianceReport) String() string {
+	if r == nil || len(r.Dependencies) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	if len(r.Violations) == 0 {
+		builder.WriteString(fmt.Sprintf("✅ All %d added dependencies comply with the license policy.\n", len(r.Dependencies)))
+	} else {
+		icon := "⚠️"
+		if r.Blocking {
+			icon = "⛔"
+		}
+		builder.WriteString(fmt.Sprintf("%s %d of %d added dependencies violate the license policy:\n\n",
+			icon, len(r.Violations), len(r.Dependencies)))
+		for _, violation := range r.Violations {
+			builder.WriteString(fmt.Sprintf("- `%s` %s (%s): %s\n",
+				violation.Dependency.Name, violation.Dependency.Version, violation.Dependency.License, violation.Reason))
+		}
+	}
+
+	builder.WriteString("\n| Dependency | Version | License |\n")
+	builder.WriteString("|------------|---------|---------|\n")
+	for _, dependency := range r.Dependencies {
+		builder.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", dependency.Name, dependency.Version, dependency.License))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestParseAddedDependencies(t *testing.T) {
+	diff := `diff --git a/go.mod b/go.mod
+--- a/go.mod
++++ b/go.mod
+@@ -5,3 +5,5 @@ require (
+ 	github.com/spf13/cobra v1.9.1
+-	github.com/stretchr/testify v1.9.0
++	github.com/stretchr/testify v1.10.0
++	github.com/google/uuid v1.6.0
++	golang.org/x/text v0.24.0 // indirect
+ )
+diff --git a/web/package.json b/web/package.json
+--- a/web/package.json
++++ b/web/package.json
+@@ -1,6 +1,7 @@
+ {
++  "version": "1.2.0",
+   "dependencies": {
++    "@scope/pkg": "^2.1.0",
+-    "lodash": "^4.17.20",
++    "lodash": "^4.17.21",
+     "react": "^18.2.0"
+   }
+ }
+diff --git a/Podfile.lock b/Podfile.lock
+--- a/Podfile.lock
++++ b/Podfile.lock
+@@ -1,3 +1,4 @@
+ PODS:
++  - Alamofire (5.9.1)
++    - Alamofire (5.9.1)
+`
+
+	dependencies := ParseAddedDependencies(diff)
+	expected := []Dependency{
+		{Ecosystem: EcosystemGo, Name: "github.com/google/uuid", Version: "v1.6.0"},
+		{Ecosystem: EcosystemNPM, Name: "@scope/pkg", Version: "2.1.0"},
+		{Ecosystem: EcosystemCocoaPods, Name: "Alamofire", Version: "5.9.1"},
+	}
+
+	if len(dependencies) != len(expected) {
+		t.Fatalf("Expected %d dependencies, got %d: %+v", len(expected), len(dependencies), dependencies)
+	}
+	for i, e := range expected {
+		if dependencies[i] != e {
+			t.Errorf("Expected dependency %+v, got %+v", e, dependencies[i])
+		}
+	}
+}
+
+func TestCheckLicense(t *testing.T) {
+	policy := Compliance{DisallowedLicenses: []string{"SSPL-1.0"}, FlagCopyleft: true}
+
+	tests := []struct {
+		license  string
+		expected string
+	}{
+		{"MIT", ""},
+		{"Apache-2.0 OR MIT", ""},
+		{"GPL-3.0-only", "copyleft license"},
+		{"(MIT OR LGPL-2.1)", ""},
+		{"GPL-2.0-only OR LGPL-2.1-or-later", "copyleft license"},
+		{"MIT AND (GPL-2.0 WITH Classpath-exception-2.0 OR Apache-2.0)", ""},
+		{"MIT AND GPL-2.0+", "copyleft license"},
+		{"SSPL-1.0", "disallowed license"},
+		{"sspl-1.0-only", "disallowed license"},
+		{"SSPL-1.0 OR MIT", ""},
+		{"GPLX-1.0", ""},
+		{unknownLicense, ""},
+	}
+	for _, tt := range tests {
+		if reason := checkLicense(tt.license, policy); reason != tt.expected {
+			t.Errorf("checkLicense(%q) = %q, expected %q", tt.license, reason, tt.expected)
+		}
+	}
+
+	// Identifiers are compared exactly, AGPL-3.0 is not GPL-3.0
+	if reason := checkLicense("AGPL-3.0", Compliance{DisallowedLicenses: []string{"GPL-3.0"}}); reason != "" {
+		t.Errorf("Expected AGPL-3.0 not to match GPL-3.0, got %q", reason)
+	}
+
+	if reason := checkLicense("GPL-3.0-only", Compliance{}); reason != "" {
+		t.Errorf("Expected copyleft to be allowed when not flagged, got %q", reason)
+	}
+}
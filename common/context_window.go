@@ -0,0 +1,37 @@
+package common
+
+// defaultContextWindow is the context window of unknown models in tokens, the smallest of the known models
+const defaultContextWindow = 128000
+
+// promptContextShare is the share of the context window for the prompt, the rest is left for the tool results and the answer
+const promptContextShare = 0.5
+
+// modelContextWindows holds the context window of the known models in tokens
+var modelContextWindows = map[string]int{
+	"gpt-4.1":         1047576,
+	"gpt-4.1-mini":    1047576,
+	"gpt-4.1-nano":    1047576,
+	"gpt-4o":          128000,
+	"gpt-4o-mini":     128000,
+	"o3":              200000,
+	"o4-mini":         200000,
+	"claude-3-sonnet": 200000,
+	"claude-4-sonnet": 200000,
+	"claude-3-haiku":  200000,
+}
+
+// ContextWindow returns the context window of the model in tokens, the configured one overrides the known size
+func ContextWindow(model string, configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	if window, ok := modelContextWindows[model]; ok {
+		return window
+	}
+	return defaultContextWindow
+}
+
+// PromptBudget returns the tokens the prompt of the model can take
+func PromptBudget(model string, configured int) int {
+	return int(float64(ContextWindow(model, configured)) * promptContextShare)
+}
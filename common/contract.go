@@ -0,0 +1,125 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// ContractChange is a change of an API contract element, like an endpoint, schema, message or RPC
+type ContractChange struct {
+	Element     string
+	Description string
+	Breaking    bool
+}
+
+// ContractFile is the structural diff of a changed OpenAPI spec or proto file
+type ContractFile struct {
+	File    string
+	Changes []ContractChange
+}
+
+// ContractAnalysis is the result of the structural diff of the API contracts changed in the diff
+type ContractAnalysis struct {
+	Files []ContractFile
+}
+
+// IsContractFile returns true for proto files and files which may contain an OpenAPI spec
+func IsContractFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".proto", ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// AnalyzeContracts diffs the OpenAPI specs and proto files changed in the diff between the base and head revisions.
+// Returns nil if no API contract was changed.
+func AnalyzeContracts(client *git.Client, baseRef, headRef, diff string) *ContractAnalysis {
+	analysis := &ContractAnalysis{}
+	for _, file := range ChangedFiles(diff) {
+		if !IsContractFile(file) {
+			continue
+		}
+
+		// Missing files are added or deleted by the changes
+		base, _ := client.GetFileContent(baseRef, file)
+		head, _ := client.GetFileContent(headRef, file)
+
+		var changes []ContractChange
+		var err error
+		switch {
+		case filepath.Ext(file) == ".proto":
+			changes = DiffProto(base, head)
+		case isOpenAPISpec(base) || isOpenAPISpec(head):
+			changes, err = DiffOpenAPI(base, head)
+		default:
+			continue
+		}
+		if err != nil {
+			logger.Warnf("Skipping contract analysis of %s: %v", file, err)
+			continue
+		}
+
+		if len(changes) > 0 {
+			analysis.Files = append(analysis.Files, ContractFile{File: file, Changes: changes})
+		}
+	}
+
+	if len(analysis.Files) == 0 {
+		return nil
+	}
+	return analysis
+}
+
+// Breaking returns true if any of the contract changes is breaking
+func (f ContractFile) Breaking() bool {
+	for _, change := range f.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Breaking returns true if any of the contracts has breaking changes
+func (a *ContractAnalysis) Breaking() bool {
+	if a == nil {
+		return false
+	}
+	for _, file := range a.Files {
+		if file.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the analysis as a markdown section body with a compatibility verdict per contract
+func (a *ContractAnalysis) String() string {
+	if a == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, file := range a.Files {
+		verdict := "✅ Backward compatible"
+		if file.Breaking() {
+			verdict = "⛔ Breaking"
+		}
+		builder.WriteString(fmt.Sprintf("**%s**: %s\n", file.File, verdict))
+
+		for _, change := range file.Changes {
+			icon := "✅"
+			if change.Breaking {
+				icon = "⛔"
+			}
+			builder.WriteString(fmt.Sprintf("- %s `%s`: %s\n", icon, change.Element, change.Description))
+		}
+		builder.WriteString("\n")
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
@@ -0,0 +1,179 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffOpenAPI(t *testing.T) {
+	base := `openapi: 3.0.0
+paths:
+  /users:
+    get:
+      parameters:
+        - name: limit
+          in: query
+      responses:
+        200:
+          description: OK
+        404:
+          description: Not found
+  /users/{id}:
+    delete:
+      responses:
+        204:
+          description: Deleted
+components:
+  schemas:
+    User:
+      type: object
+      required: [id]
+      properties:
+        id:
+          type: string
+        name:
+          type: string
+        role:
+          type: string
+          enum: [admin, member]
+`
+	head := `openapi: 3.0.0
+paths:
+  /users:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          required: true
+        - name: cursor
+          in: query
+      responses:
+        200:
+          description: OK
+  /teams:
+    get:
+      responses:
+        200:
+          description: OK
+components:
+  schemas:
+    User:
+      type: object
+      required: [id]
+      properties:
+        id:
+          type: integer
+        role:
+          type: string
+          enum: [admin]
+        email:
+          type: string
+`
+
+	changes, err := DiffOpenAPI(base, head)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []ContractChange{
+		{Element: "DELETE /users/{id}", Description: "endpoint removed", Breaking: true},
+		{Element: "GET /users", Description: "parameter query:limit became required", Breaking: true},
+		{Element: "GET /users", Description: "optional parameter query:cursor added"},
+		{Element: "GET /users", Description: "response 404 removed", Breaking: true},
+		{Element: "GET /teams", Description: "endpoint added"},
+		{Element: "User", Description: "property id changed type from string to integer", Breaking: true},
+		{Element: "User", Description: "property name removed", Breaking: true},
+		{Element: "User", Description: "enum value member of property role removed", Breaking: true},
+		{Element: "User", Description: "optional property email added"},
+	}
+	assertContractChanges(t, expected, changes)
+}
+
+func TestDiffProto(t *testing.T) {
+	base := `syntax = "proto3";
+
+// User of the service
+message User {
+  string id = 1;
+  string name = 2;
+  int32 age = 3;
+  repeated string tags = 4;
+
+  enum Role {
+    ROLE_UNSPECIFIED = 0;
+    ROLE_ADMIN = 1;
+  }
+}
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc DeleteUser(DeleteUserRequest) returns (Empty) {
+    option deprecated = true;
+  }
+}
+`
+	head := `syntax = "proto3";
+
+message User {
+  reserved 3;
+  string id = 1;
+  string display_name = 2;
+  repeated int64 tags = 4;
+  oneof contact {
+    string email = 5;
+  }
+
+  enum Role {
+    ROLE_UNSPECIFIED = 0;
+  }
+}
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (stream User);
+}
+`
+
+	expected := []ContractChange{
+		{Element: "User", Description: "field 2 renamed from name to display_name, breaking JSON clients", Breaking: true},
+		{Element: "User", Description: "field age = 3 removed and reserved"},
+		{Element: "User", Description: "field tags = 4 changed type from string to int64", Breaking: true},
+		{Element: "User", Description: "field email = 5 added"},
+		{Element: "User.Role", Description: "enum value ROLE_ADMIN removed", Breaking: true},
+		{Element: "UserService.DeleteUser", Description: "RPC removed", Breaking: true},
+		{Element: "UserService.GetUser", Description: "RPC signature changed from (GetUserRequest) returns (User) to (GetUserRequest) returns (stream User)", Breaking: true},
+	}
+	assertContractChanges(t, expected, DiffProto(base, head))
+}
+
+func TestContractAnalysisString(t *testing.T) {
+	analysis := &ContractAnalysis{Files: []ContractFile{
+		{File: "api/user.proto", Changes: []ContractChange{{Element: "User", Description: "message removed", Breaking: true}}},
+		{File: "openapi.yaml", Changes: []ContractChange{{Element: "GET /teams", Description: "endpoint added"}}},
+	}}
+
+	output := analysis.String()
+	for _, expected := range []string{
+		"**api/user.proto**: ⛔ Breaking",
+		"- ⛔ `User`: message removed",
+		"**openapi.yaml**: ✅ Backward compatible",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected analysis to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if !analysis.Breaking() {
+		t.Error("Expected the analysis to be breaking")
+	}
+}
+
+func assertContractChanges(t *testing.T, expected, actual []ContractChange) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %+v", len(expected), len(actual), actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, expected[i], actual[i])
+		}
+	}
+}
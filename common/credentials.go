@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// BitriseSecretsFile is the secrets file of the Bitrise CLI, used for local runs
+const BitriseSecretsFile = ".bitrise.secrets.yml"
+
+// genericCredentialPrefix prefixes the provider independent variables of the credentials, e.g. BITRISE_AI_GITHUB_TOKEN
+const genericCredentialPrefix = "BITRISE_AI_"
+
+// Credential is a token or API key of a service, resolved from multiple sources
+type Credential struct {
+	Name string // Human readable name, e.g. "GitHub token"
+	Env  string // Environment variable of the provider, e.g. GITHUB_TOKEN
+}
+
+// Credentials of the supported services
+var (
+	CredentialGitHub       = Credential{Name: "GitHub token", Env: "GITHUB_TOKEN"}
+	CredentialBitbucket    = Credential{Name: "Bitbucket token", Env: "BITBUCKET_TOKEN"}
+	CredentialGitea        = Credential{Name: "Gitea token", Env: "GITEA_TOKEN"}
+	CredentialLLM          = Credential{Name: "LLM API key", Env: "LLM_API_KEY"} // Legacy key shared by the LLM providers
+	CredentialOpenAI       = Credential{Name: "OpenAI API key", Env: "OPENAI_API_KEY"}
+	CredentialAnthropic    = Credential{Name: "Anthropic API key", Env: "ANTHROPIC_API_KEY"}
+	CredentialSession      = Credential{Name: "session encryption key", Env: "REVIEW_SESSION_KEY"}
+	CredentialSentry       = Credential{Name: "Sentry token", Env: "SENTRY_AUTH_TOKEN"}
+	CredentialReleaseNotes = Credential{Name: "release notes GitHub token", Env: "RELEASE_NOTES_GITHUB_TOKEN"} // Sent to github.com, unlike a GitHub Enterprise token
+)
+
+// GenericEnv returns the provider independent environment variable of the credential
+func (c Credential) GenericEnv() string {
+	return genericCredentialPrefix + c.Env
+}
+
+// FileEnv returns the environment variable with the path of the file holding the credential
+func (c Credential) FileEnv() string {
+	return c.Env + "_FILE"
+}
+
+// Resolve returns the credential and the name of its source. The sources in order of precedence:
+// the environment variable of the provider, the generic BITRISE_AI_ variable, the file set in the _FILE variable,
+// and the secrets file of the Bitrise CLI.
+func (c Credential) Resolve() (string, string, error) {
+	for _, env := range []string{c.Env, c.GenericEnv()} {
+		if value := strings.TrimSpace(os.Getenv(env)); value != "" {
+			return value, env, nil
+		}
+	}
+
+	if path := os.Getenv(c.FileEnv()); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read the %s from %s: %w", c.Name, path, err)
+		}
+		if value := strings.TrimSpace(string(data)); value != "" {
+			return value, path, nil
+		}
+		return "", "", fmt.Errorf("the %s file %s is empty", c.Name, path)
+	}
+
+	secrets, err := readBitriseSecrets(BitriseSecretsFile)
+	if err != nil {
+		logger.Warnf("Failed to read %s: %v", BitriseSecretsFile, err)
+	}
+	for _, env := range []string{c.Env, c.GenericEnv()} {
+		if value := strings.TrimSpace(secrets[env]); value != "" {
+			return value, BitriseSecretsFile, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%s is not set, set the %s, %s or %s environment variable", c.Name, c.Env, c.GenericEnv(), c.FileEnv())
+}
+
+// readBitriseSecrets returns the variables of the Bitrise CLI secrets file, or nothing if the file doesn't exist
+func readBitriseSecrets(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets struct {
+		Envs []map[string]any `yaml:"envs"`
+	}
+	if err := yaml.Unmarshal(data, &secrets); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, env := range secrets.Envs {
+		for key, value := range env {
+			// Entries may have an opts key next to the variable
+			if key != "opts" {
+				values[key] = fmt.Sprint(value)
+			}
+		}
+	}
+	return values, nil
+}
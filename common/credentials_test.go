@@ -0,0 +1,60 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialResolve(t *testing.T) {
+	t.Chdir(t.TempDir())
+	credential := Credential{Name: "Test token", Env: "AI_REVIEWER_TEST_TOKEN"}
+
+	if _, _, err := credential.Resolve(); err == nil {
+		t.Error("Expected an error without any source")
+	}
+
+	// Bitrise CLI secrets file
+	secrets := "envs:\n- AI_REVIEWER_TEST_TOKEN: from-secrets\n  opts:\n    is_expand: false\n"
+	if err := os.WriteFile(BitriseSecretsFile, []byte(secrets), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	assertCredential(t, credential, "from-secrets", BitriseSecretsFile)
+
+	// Token file
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(credential.FileEnv(), tokenFile)
+	assertCredential(t, credential, "from-file", tokenFile)
+
+	// Generic variable
+	t.Setenv("BITRISE_AI_AI_REVIEWER_TEST_TOKEN", "from-generic")
+	assertCredential(t, credential, "from-generic", "BITRISE_AI_AI_REVIEWER_TEST_TOKEN")
+
+	// Provider variable
+	t.Setenv("AI_REVIEWER_TEST_TOKEN", "from-env")
+	assertCredential(t, credential, "from-env", "AI_REVIEWER_TEST_TOKEN")
+}
+
+func TestCredentialResolveMissingFile(t *testing.T) {
+	credential := Credential{Name: "Test token", Env: "AI_REVIEWER_TEST_TOKEN"}
+	t.Setenv(credential.FileEnv(), filepath.Join(t.TempDir(), "missing"))
+
+	if _, _, err := credential.Resolve(); err == nil {
+		t.Error("Expected an error for a missing token file")
+	}
+}
+
+func assertCredential(t *testing.T, credential Credential, expectedValue, expectedSource string) {
+	t.Helper()
+
+	value, source, err := credential.Resolve()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value != expectedValue || source != expectedSource {
+		t.Errorf("Expected %s from %s, got %s from %s", expectedValue, expectedSource, value, source)
+	}
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// customToolNamePattern matches the tool names accepted by the LLM providers
+var customToolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// CustomTool is a team specific tool backed by a shell command, e.g. a linter or an internal script.
+// The command is a Go template, the arguments of the call are shell-quoted when substituted, e.g. `swiftlint lint {{.path}}`.
+type CustomTool struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Parameters  map[string]any `yaml:"parameters"` // JSON schema of the arguments, an object without properties by default
+	Command     string         `yaml:"command"`
+	Timeout     int            `yaml:"timeout"` // in seconds, 120 by default
+}
+
+// Validate checks the name and the command template of the tool
+func (t CustomTool) Validate() error {
+	if !customToolNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid tool name %q, only letters, digits, _ and - are allowed", t.Name)
+	}
+	if t.Description == "" {
+		return fmt.Errorf("description of %s cannot be empty", t.Name)
+	}
+	if strings.TrimSpace(t.Command) == "" {
+		return fmt.Errorf("command of %s cannot be empty", t.Name)
+	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("timeout of %s cannot be negative", t.Name)
+	}
+	if _, err := t.template(); err != nil {
+		return fmt.Errorf("invalid command of %s: %v", t.Name, err)
+	}
+	return nil
+}
+
+// Schema returns the JSON schema of the arguments
+func (t CustomTool) Schema() map[string]any {
+	if len(t.Parameters) == 0 {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return t.Parameters
+}
+
+// Run substitutes the arguments of the call into the command and runs it in the repository root.
+// Like the built-in commands, the output is returned even if the command fails and it is truncated for the LLM.
+func (t CustomTool) Run(argumentsJSON string) (string, error) {
+	args := map[string]any{}
+	if strings.TrimSpace(argumentsJSON) != "" {
+		if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
+			return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+		}
+	}
+
+	command, err := t.render(args)
+	if err != nil {
+		return "", err
+	}
+
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = commandTimeout
+	}
+
+	logger.Debugf("Running custom tool %s: %s", t.Name, command)
+	return runCappedCommand(t.Name, timeout, "sh", "-c", command)
+}
+
+// render substitutes the shell-quoted arguments into the command, the declared but missing arguments are empty
+func (t CustomTool) render(args map[string]any) (string, error) {
+	tmpl, err := t.template()
+	if err != nil {
+		return "", err
+	}
+
+	if required, ok := t.Schema()["required"].([]any); ok {
+		for _, name := range required {
+			if _, ok := args[fmt.Sprint(name)]; !ok {
+				return "", fmt.Errorf("missing required argument %v", name)
+			}
+		}
+	}
+
+	quoted := map[string]string{}
+	if properties, ok := t.Schema()["properties"].(map[string]any); ok {
+		for name := range properties {
+			quoted[name] = shellQuote("")
+		}
+	}
+	for name, value := range args {
+		if _, ok := value.(string); !ok {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid argument %s: %v", name, err)
+			}
+			value = string(encoded)
+		}
+		quoted[name] = shellQuote(value.(string))
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, quoted); err != nil {
+		return "", errors.New("the command uses an argument which is not declared in the parameters")
+	}
+	return builder.String(), nil
+}
+
+func (t CustomTool) template() (*template.Template, error) {
+	return template.New(t.Name).Option("missingkey=error").Parse(t.Command)
+}
+
+// shellQuote quotes the value as a single argument of sh
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
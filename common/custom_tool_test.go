@@ -0,0 +1,95 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCustomToolValidate(t *testing.T) {
+	valid := CustomTool{Name: "run_swiftlint", Description: "Lints Swift files", Command: "swiftlint lint {{.path}}"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := map[string]CustomTool{
+		"name":        {Name: "run swiftlint", Description: "Lints", Command: "swiftlint"},
+		"description": {Name: "run_swiftlint", Command: "swiftlint"},
+		"command":     {Name: "run_swiftlint", Description: "Lints"},
+		"template":    {Name: "run_swiftlint", Description: "Lints", Command: "swiftlint {{.path"},
+		"timeout":     {Name: "run_swiftlint", Description: "Lints", Command: "swiftlint", Timeout: -1},
+	}
+	for name, tool := range invalid {
+		if err := tool.Validate(); err == nil {
+			t.Errorf("Expected an error for an invalid %s", name)
+		}
+	}
+}
+
+func TestCustomToolRender(t *testing.T) {
+	tool := CustomTool{
+		Name:        "query_feature_flags",
+		Description: "Queries the feature flags",
+		Command:     "flags query {{.flag}} --env {{.env}}",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"flag": map[string]any{"type": "string"},
+				"env":  map[string]any{"type": "string"},
+			},
+			"required": []any{"flag"},
+		},
+	}
+
+	command, err := tool.render(map[string]any{"flag": "new_login'; rm -rf ."})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `flags query 'new_login'\''; rm -rf .' --env ''`; command != expected {
+		t.Errorf("Expected %s, got %s", expected, command)
+	}
+
+	if _, err := tool.render(map[string]any{"env": "prod"}); err == nil {
+		t.Error("Expected an error for a missing required argument")
+	}
+
+	tool.Command = "flags query {{.other}}"
+	if _, err := tool.render(map[string]any{"flag": "new_login"}); err == nil {
+		t.Error("Expected an error for an undeclared argument")
+	}
+}
+
+func TestCustomToolRun(t *testing.T) {
+	tool := CustomTool{
+		Name:        "echo_args",
+		Description: "Echoes the arguments",
+		Command:     "echo {{.message}} {{.count}}",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"message": map[string]any{"type": "string"}, "count": map[string]any{"type": "integer"}},
+		},
+	}
+
+	output, err := tool.Run(`{"message": "hello $HOME", "count": 3}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(output) != "hello $HOME 3" {
+		t.Errorf("Expected the arguments to be passed literally, got %q", output)
+	}
+
+	tool.Command = "echo failed; exit 2"
+	output, err = tool.Run("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "exit code 2") || !strings.Contains(output, "failed") {
+		t.Errorf("Expected the output of the failed command, got %q", output)
+	}
+
+	// The arguments come from the LLM, the secrets of the environment are not passed on
+	t.Setenv("GITHUB_TOKEN", "secret")
+	tool.Command = `echo "token:$GITHUB_TOKEN"`
+	if output, err := tool.Run(""); err != nil || strings.TrimSpace(output) != "token:" {
+		t.Errorf("Expected the command to run without the token, got %q, %v", output, err)
+	}
+}
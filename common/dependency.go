@@ -0,0 +1,19 @@
+package common
+
+import "strings"
+
+// dependencyBots are the authors and branch prefixes of automated dependency update tools
+var dependencyBots = []string{"dependabot", "renovate"}
+
+// IsDependencyUpdate checks if the pull request is an automated dependency update (Dependabot, Renovate)
+func IsDependencyUpdate(pr PullRequest) bool {
+	author := strings.ToLower(pr.Author)
+	branch := strings.ToLower(pr.HeadBranch)
+
+	for _, bot := range dependencyBots {
+		if strings.Contains(author, bot) || strings.HasPrefix(branch, bot+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package common
+
+import "testing"
+
+func TestIsDependencyUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pr       PullRequest
+		expected bool
+	}{
+		{"dependabot author", PullRequest{Author: "dependabot[bot]", HeadBranch: "bump-lodash"}, true},
+		{"renovate author", PullRequest{Author: "Renovate-Bot", HeadBranch: "update-deps"}, true},
+		{"dependabot branch", PullRequest{Author: "alice", HeadBranch: "dependabot/npm_and_yarn/lodash-4.17.21"}, true},
+		{"renovate branch", PullRequest{Author: "alice", HeadBranch: "renovate/lodash"}, true},
+		{"human author", PullRequest{Author: "alice", HeadBranch: "feature/login"}, false},
+		{"bot name inside the branch", PullRequest{Author: "alice", HeadBranch: "feature/renovate-settings"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := IsDependencyUpdate(test.pr); actual != test.expected {
+				t.Errorf("Expected %v for %+v, got %v", test.expected, test.pr, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formats of the findings written for other tools, instead of posting them as comments
+const (
+	DiagnosticsFormatRDJSON         = "rdjson"          // Reviewdog Diagnostic Format, for reviewdog -f=rdjson
+	DiagnosticsFormatProblemMatcher = "problem-matcher" // One finding per line, for GitHub problem matchers and other CI annotations
+)
+
+// diagnosticsSource is the name of the tool reporting the diagnostics
+const diagnosticsSource = "bitrise-ai-reviewer"
+
+// rdjsonSeverities maps the severities of the findings to the reviewdog severities, findings without a severity are infos
+var rdjsonSeverities = map[string]string{
+	SeverityHigh:   "ERROR",
+	SeverityMedium: "WARNING",
+	SeverityLow:    "INFO",
+}
+
+// problemMatcherSeverities maps the severities of the findings to the GitHub annotation levels, findings without a severity are notices
+var problemMatcherSeverities = map[string]string{
+	SeverityHigh:   "error",
+	SeverityMedium: "warning",
+	SeverityLow:    "notice",
+}
+
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+type rdjsonSource struct {
+	Name string `json:"name"`
+}
+
+type rdjsonDiagnostic struct {
+	Message     string             `json:"message"`
+	Location    rdjsonLocation     `json:"location"`
+	Severity    string             `json:"severity"`
+	Code        *rdjsonCode        `json:"code,omitempty"`
+	Suggestions []rdjsonSuggestion `json:"suggestions,omitempty"`
+}
+
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+type rdjsonLocation struct {
+	Path  string       `json:"path"`
+	Range *rdjsonRange `json:"range,omitempty"`
+}
+
+// rdjsonRange is a range of whole lines, the columns are left out
+type rdjsonRange struct {
+	Start rdjsonPosition `json:"start"`
+	End   rdjsonPosition `json:"end"`
+}
+
+type rdjsonPosition struct {
+	Line int `json:"line"`
+}
+
+type rdjsonSuggestion struct {
+	Range rdjsonRange `json:"range"`
+	Text  string      `json:"text"`
+}
+
+// IsValidDiagnosticsFormat checks if the format is rdjson or problem-matcher
+func IsValidDiagnosticsFormat(format string) bool {
+	return format == DiagnosticsFormatRDJSON || format == DiagnosticsFormatProblemMatcher
+}
+
+// FormatDiagnostics formats the findings with resolved line numbers in the format.
+// Line findings without a line number are left out, like on the code review providers.
+func FormatDiagnostics(lines []LineLevel, format string) (string, error) {
+	var located []LineLevel
+	for _, l := range lines {
+		if l.File != "" && l.Body != "" && (l.LineNumber > 0 || l.IsFileLevel()) {
+			located = append(located, l)
+		}
+	}
+
+	switch format {
+	case DiagnosticsFormatRDJSON:
+		return formatRDJSON(located)
+	case DiagnosticsFormatProblemMatcher:
+		return formatProblemMatcher(located), nil
+	default:
+		return "", fmt.Errorf("unknown diagnostics format: %s", format)
+	}
+}
+
+// WriteDiagnostics writes the formatted findings to the path, creating its directory
+func WriteDiagnostics(path, diagnostics string) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create diagnostics directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(diagnostics), 0o644); err != nil {
+		return fmt.Errorf("failed to write diagnostics: %w", err)
+	}
+	return nil
+}
+
+func formatRDJSON(lines []LineLevel) (string, error) {
+	result := rdjsonResult{
+		Source:      rdjsonSource{Name: diagnosticsSource},
+		Diagnostics: make([]rdjsonDiagnostic, 0, len(lines)),
+	}
+	for _, l := range lines {
+		diagnostic := rdjsonDiagnostic{
+			Message:  l.diagnosticMessage(),
+			Location: rdjsonLocation{Path: l.File},
+			Severity: rdjsonSeverities[l.Severity],
+		}
+		if diagnostic.Severity == "" {
+			diagnostic.Severity = rdjsonSeverities[SeverityLow]
+		}
+		if l.Category != "" {
+			diagnostic.Code = &rdjsonCode{Value: l.Category}
+		}
+		if !l.IsFileLevel() {
+			lineRange := l.diagnosticRange()
+			diagnostic.Location.Range = &lineRange
+			if l.Suggestion != "" {
+				diagnostic.Suggestions = []rdjsonSuggestion{{Range: lineRange, Text: l.Suggestion}}
+			}
+		}
+		result.Diagnostics = append(result.Diagnostics, diagnostic)
+	}
+
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode diagnostics: %w", err)
+	}
+	return string(content) + "\n", nil
+}
+
+// formatProblemMatcher formats each finding as "file:line: severity: message", file findings as "file: severity: message"
+func formatProblemMatcher(lines []LineLevel) string {
+	var builder strings.Builder
+	for _, l := range lines {
+		severity := problemMatcherSeverities[l.Severity]
+		if severity == "" {
+			severity = problemMatcherSeverities[SeverityLow]
+		}
+
+		location := l.File
+		if !l.IsFileLevel() {
+			location = fmt.Sprintf("%s:%d", l.File, l.LineNumber)
+		}
+		message := l.diagnosticMessage()
+		if l.Category != "" {
+			message = "[" + l.Category + "] " + message
+		}
+		// The matchers read one finding per line
+		builder.WriteString(fmt.Sprintf("%s: %s: %s\n", location, severity, strings.Join(strings.Fields(message), " ")))
+	}
+	return builder.String()
+}
+
+// diagnosticRange returns the commented lines of the finding
+func (l LineLevel) diagnosticRange() rdjsonRange {
+	lastLine := l.LineNumber
+	if l.LastLineNumber > l.LineNumber {
+		lastLine = l.LastLineNumber
+	}
+	return rdjsonRange{Start: rdjsonPosition{Line: l.LineNumber}, End: rdjsonPosition{Line: lastLine}}
+}
+
+// diagnosticMessage returns the title and the body of the finding
+func (l LineLevel) diagnosticMessage() string {
+	if l.Title != "" {
+		return l.Title + ": " + l.Body
+	}
+	return l.Body
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var diagnosticsFindings = []LineLevel{
+	{File: "main.go", LineNumber: 3, LastLineNumber: 4, Category: CategoryBug, Severity: SeverityHigh, Title: "Missing check", Body: "The error is\nignored.", Suggestion: "if err != nil {\n\treturn err\n}"},
+	{File: "README.md", Scope: ScopeFile, Category: CategoryDocumentation, Body: "Outdated usage"},
+	{File: "util.go", LineNumber: 0, Category: CategoryNitpick, Body: "Line not found in the diff"},
+}
+
+func TestFormatDiagnosticsRDJSON(t *testing.T) {
+	output, err := FormatDiagnostics(diagnosticsFindings, DiagnosticsFormatRDJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var result rdjsonResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, output)
+	}
+	if result.Source.Name != diagnosticsSource || len(result.Diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics of %s, got:\n%s", diagnosticsSource, output)
+	}
+
+	line := result.Diagnostics[0]
+	if line.Message != "Missing check: The error is\nignored." || line.Severity != "ERROR" || line.Code.Value != CategoryBug {
+		t.Errorf("Unexpected line diagnostic: %+v", line)
+	}
+	if line.Location.Range == nil || line.Location.Range.Start.Line != 3 || line.Location.Range.End.Line != 4 {
+		t.Errorf("Expected the range of lines 3-4, got %+v", line.Location.Range)
+	}
+	if len(line.Suggestions) != 1 || line.Suggestions[0].Text != diagnosticsFindings[0].Suggestion {
+		t.Errorf("Expected the suggestion, got %+v", line.Suggestions)
+	}
+
+	file := result.Diagnostics[1]
+	if file.Location.Path != "README.md" || file.Location.Range != nil || file.Severity != "INFO" {
+		t.Errorf("Unexpected file diagnostic: %+v", file)
+	}
+}
+
+func TestFormatDiagnosticsProblemMatcher(t *testing.T) {
+	output, err := FormatDiagnostics(diagnosticsFindings, DiagnosticsFormatProblemMatcher)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "main.go:3: error: [bug] Missing check: The error is ignored.\n" +
+		"README.md: notice: [documentation] Outdated usage\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	if _, err := FormatDiagnostics(diagnosticsFindings, "sarif"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestWriteDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "diagnostics.txt")
+	if err := WriteDiagnostics(path, "main.go:3: error: Missing check\n"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "main.go:3: error: Missing check\n" {
+		t.Errorf("Unexpected diagnostics file: %q, %v", content, err)
+	}
+}
@@ -0,0 +1,227 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mermaid diagram types the summary renders
+const (
+	diagramFlowchart = "flowchart"
+	diagramClass     = "class"
+	diagramSequence  = "sequence"
+)
+
+// maxDiagramLines keeps the diagrams readable in a comment
+const maxDiagramLines = 80
+
+var (
+	flowchartHeaderRegex = regexp.MustCompile(`^(?:flowchart|graph)(?:\s+(?:TB|TD|BT|RL|LR))?$`)
+	flowchartEdgeRegex   = regexp.MustCompile(`\s*<?(?:--+|==+|-\.+-)[->ox]?\s*(?:\|([^|]*)\|\s*)?`)
+	flowchartNodeRegex   = regexp.MustCompile(`^([\w.-]+)\s*(?:\[\[|\[\(|\(\(|\[|\(|\{\{|\{|>)\s*"?(.*?)"?\s*(?:\]\]|\)\]|\)\)|\]|\)|\}\}|\})$`)
+	sequenceArrowRegex   = regexp.MustCompile(`\s*--?(?:>>|>|x|\))\s*`)
+	classRelationRegex   = regexp.MustCompile(`\s+(?:<\|--|--\|>|\*--|--\*|o--|--o|<--|-->|<\.\.|\.\.>|<\|\.\.|\.\.\|>|--|\.\.)\s+`)
+	// Blocks of the sequence diagrams closed by an end line
+	sequenceBlockRegex = regexp.MustCompile(`^(?:alt|opt|loop|par|critical|break|rect|box)\b`)
+)
+
+// Diagram is a Mermaid diagram of the changed structure or call flow, like restructured packages
+type Diagram struct {
+	Source string
+	kind   string
+}
+
+// ParseDiagram validates the syntax of the Mermaid diagram. Flowcharts, class and sequence diagrams are supported,
+// a surrounding mermaid code block is removed.
+func ParseDiagram(source string) (Diagram, error) {
+	source = strings.TrimSpace(source)
+	source = strings.TrimPrefix(source, "```mermaid")
+	source = strings.TrimSuffix(source, "```")
+	source = strings.TrimSpace(source)
+
+	lines := diagramStatements(source)
+	if len(lines) < 2 {
+		return Diagram{}, fmt.Errorf("diagram has no statements")
+	}
+	if len(lines) > maxDiagramLines {
+		return Diagram{}, fmt.Errorf("diagram has %d lines, at most %d are allowed", len(lines), maxDiagramLines)
+	}
+
+	diagram := Diagram{Source: source}
+	switch header := lines[0]; {
+	case flowchartHeaderRegex.MatchString(header):
+		diagram.kind = diagramFlowchart
+	case header == "classDiagram":
+		diagram.kind = diagramClass
+	case header == "sequenceDiagram":
+		diagram.kind = diagramSequence
+	default:
+		return Diagram{}, fmt.Errorf("unsupported diagram type: %s", header)
+	}
+
+	// The arrows of the async messages of the sequence diagrams have a closing parenthesis
+	statements := source
+	if diagram.kind == diagramSequence {
+		statements = sequenceArrowRegex.ReplaceAllString(source, " ")
+	}
+	if err := checkBrackets(statements); err != nil {
+		return Diagram{}, err
+	}
+
+	// Subgraphs and the blocks of sequence diagrams are closed by an end line
+	open := 0
+	for i, line := range lines[1:] {
+		if strings.Count(line, `"`)%2 != 0 {
+			return Diagram{}, fmt.Errorf("unclosed quote on line %d: %s", i+2, line)
+		}
+		switch {
+		case line == "end":
+			open--
+		case diagram.kind == diagramFlowchart && strings.HasPrefix(line, "subgraph "):
+			open++
+		case diagram.kind == diagramSequence && sequenceBlockRegex.MatchString(line):
+			open++
+		}
+		if open < 0 {
+			return Diagram{}, fmt.Errorf("unexpected end on line %d", i+2)
+		}
+	}
+	if open > 0 {
+		return Diagram{}, fmt.Errorf("%d blocks are not closed with end", open)
+	}
+
+	return diagram, nil
+}
+
+// Outline describes the diagram as a markdown list, for the providers which don't render Mermaid
+func (d Diagram) Outline() string {
+	lines := diagramStatements(d.Source)[1:]
+
+	labels := map[string]string{}
+	if d.kind == diagramFlowchart {
+		for _, line := range lines {
+			for _, node := range flowchartEdgeRegex.Split(line, -1) {
+				if match := flowchartNodeRegex.FindStringSubmatch(strings.TrimSpace(node)); match != nil {
+					labels[match[1]] = match[2]
+				}
+			}
+		}
+	}
+
+	var builder strings.Builder
+	depth := 0
+	for _, line := range lines {
+		if line == "end" || line == "}" {
+			depth = max(depth-1, 0)
+			continue
+		}
+
+		item := line
+		nested := false
+		switch d.kind {
+		case diagramFlowchart:
+			if title, found := strings.CutPrefix(line, "subgraph "); found {
+				item, nested = flowchartLabel(strings.TrimSpace(title), labels), true
+			} else if skipFlowchartStatement(line) {
+				continue
+			} else {
+				item = outlineFlowchartEdge(line, labels)
+			}
+		case diagramClass:
+			if name, found := strings.CutSuffix(line, "{"); found {
+				item, nested = strings.TrimSpace(strings.TrimPrefix(name, "class ")), true
+			} else {
+				item = classRelationRegex.ReplaceAllString(line, " → ")
+			}
+		case diagramSequence:
+			if strings.HasPrefix(line, "participant ") || strings.HasPrefix(line, "actor ") || strings.HasPrefix(line, "autonumber") {
+				continue
+			}
+			nested = sequenceBlockRegex.MatchString(line)
+			item = sequenceArrowRegex.ReplaceAllString(line, " → ")
+		}
+
+		builder.WriteString(strings.Repeat("  ", depth) + "- " + item + "\n")
+		if nested {
+			depth++
+		}
+	}
+	return builder.String()
+}
+
+// outlineFlowchartEdge replaces the nodes of the edge with their labels and the arrows with →
+func outlineFlowchartEdge(line string, labels map[string]string) string {
+	nodes := flowchartEdgeRegex.Split(line, -1)
+	edges := flowchartEdgeRegex.FindAllStringSubmatch(line, -1)
+
+	var builder strings.Builder
+	for i, node := range nodes {
+		builder.WriteString(flowchartLabel(strings.TrimSpace(node), labels))
+		if i < len(edges) {
+			builder.WriteString(" → ")
+			if label := strings.TrimSpace(edges[i][1]); label != "" {
+				builder.WriteString("(" + label + ") ")
+			}
+		}
+	}
+	return builder.String()
+}
+
+// flowchartLabel returns the label of the node, or its ID if it has none
+func flowchartLabel(node string, labels map[string]string) string {
+	if match := flowchartNodeRegex.FindStringSubmatch(node); match != nil {
+		return match[2]
+	}
+	if label, ok := labels[node]; ok && label != "" {
+		return label
+	}
+	return node
+}
+
+// skipFlowchartStatement returns true for the styling statements of the flowcharts
+func skipFlowchartStatement(line string) bool {
+	for _, keyword := range []string{"classDef ", "class ", "style ", "linkStyle ", "click ", "direction "} {
+		if strings.HasPrefix(line, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// diagramStatements returns the trimmed lines of the diagram without the empty lines and the %% comments
+func diagramStatements(source string) []string {
+	var lines []string
+	for line := range strings.SplitSeq(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "%%") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// checkBrackets returns an error if the brackets of the diagram outside of the quotes are not balanced
+func checkBrackets(source string) error {
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var stack []rune
+	quoted := false
+	for _, r := range source {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '(' || r == '[' || r == '{':
+			stack = append(stack, r)
+		case pairs[r] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[r] {
+				return fmt.Errorf("unbalanced %c in the diagram", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %c in the diagram", stack[len(stack)-1])
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDiagram(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{"flowchart", "flowchart LR\n  cmd[CLI] --> llm[LLM client]\n  llm --> review", false},
+		{"code block", "```mermaid\ngraph TD\n  A --> B\n```", false},
+		{"class diagram", "classDiagram\n  class Reviewer {\n    +PostSummary()\n  }\n  Reviewer <|-- GitHub", false},
+		{"sequence diagram", "sequenceDiagram\n  cmd->>llm: Prompt\n  alt tool call\n    llm-)cmd: post_summary\n  end", false},
+		{"unsupported type", "pie\n  \"a\" : 1", true},
+		{"no statements", "flowchart LR", true},
+		{"unbalanced bracket", "flowchart LR\n  A[CLI --> B", true},
+		{"unclosed subgraph", "flowchart LR\n  subgraph llm\n  A --> B", true},
+		{"unclosed quote", "flowchart LR\n  A[\"CLI] --> B", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseDiagram(test.source)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Expected error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDiagramOutline(t *testing.T) {
+	diagram, err := ParseDiagram("flowchart LR\n  subgraph cmd\n    summarize[Summarize command] -->|prompt| llm[LLM client]\n  end\n  llm --> review\n  style llm fill:#f9f")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "- cmd\n  - Summarize command → (prompt) LLM client\n- LLM client → review\n"
+	if outline := diagram.Outline(); outline != expected {
+		t.Errorf("Expected outline:\n%s\ngot:\n%s", expected, outline)
+	}
+}
+
+func TestRenderDiagram(t *testing.T) {
+	diagram, err := ParseDiagram("graph TD\n  A[CLI] --> B[LLM]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rendered := NewMarkdownRenderer(ProviderGitHub).Diagram(diagram); !strings.HasPrefix(rendered, "```mermaid\n") {
+		t.Errorf("Expected a mermaid code block on GitHub, got: %s", rendered)
+	}
+	if rendered := NewMarkdownRenderer(ProviderBitbucket).Diagram(diagram); rendered != "- CLI → LLM\n" {
+		t.Errorf("Expected an outline on Bitbucket, got: %s", rendered)
+	}
+}
@@ -0,0 +1,24 @@
+package common
+
+import "github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+
+// ChangedFiles returns the files added, changed or deleted in the diff, renamed files with both of their paths
+func ChangedFiles(diff string) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, file := range diffparse.Parse(diff) {
+		for _, path := range []string{file.OldPath, file.NewPath} {
+			if path != "" && !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+	}
+	return files
+}
+
+// GetFileDiff returns the section of the diff changing the file, or an empty string if the file is not changed
+func GetFileDiff(diff, file string) string {
+	fileDiff, _ := diffparse.FindFile(diffparse.Parse(diff), file)
+	return fileDiff.Raw
+}
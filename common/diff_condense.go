@@ -0,0 +1,247 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+// condensedPrefix marks the files and hunks of the diff replaced with a description of their changes
+const condensedPrefix = "[condensed] "
+
+// generatedMarkerLines is the number of lines at the top of a file searched for a generated code marker
+const generatedMarkerLines = 5
+
+// generatedMarkers are the comments marking generated files
+var generatedMarkers = []string{"Code generated", "DO NOT EDIT", "@generated", "<auto-generated"}
+
+// importLineRegex matches the import statements of the common languages, and the lines of Go import blocks
+var importLineRegex = regexp.MustCompile(`^(import\b|from\s+\S+\s+import\b|#import\b|#include\b|@import\b|using\s+[\w.]+;|require\b|(\w+\s+)?"[^"]+"$)`)
+
+// tokenRegex splits a line into identifiers and single non-space characters
+var tokenRegex = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*|\S`)
+
+// identifierRegex matches the identifier tokens
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// minRenamedLines is the number of changed lines of a rename, a single changed token of one line is a substantive change
+const minRenamedLines = 2
+
+// keywordTokens are the keywords and literals of the common languages, changing them is never a rename
+var keywordTokens = []string{
+	"true", "false", "True", "False", "nil", "null", "None", "undefined", "NaN", "this", "self", "super",
+	"if", "else", "for", "while", "do", "switch", "case", "default", "break", "continue", "return", "go", "defer",
+	"func", "function", "def", "fn", "var", "let", "const", "val", "static", "final", "public", "private", "protected",
+	"and", "or", "not", "is", "in", "new", "delete", "typeof", "instanceof", "async", "await", "try", "catch", "throw",
+}
+
+// indentationSensitiveExtensions are the files whose indentation changes their meaning
+var indentationSensitiveExtensions = []string{".py", ".yml", ".yaml", ".haml", ".pug", ".coffee"}
+
+// CondenseDiff replaces the mechanical changes of large diffs with one-line descriptions, keeping the full hunks of the
+// substantive changes: generated files, and hunks only reordering imports, changing whitespace or renaming an identifier.
+// Diffs with fewer changed lines than the threshold are returned unchanged, 0 disables it.
+// Returns the diff and the number of condensed files and hunks.
+func CondenseDiff(diff string, minChangedLines int) (string, int) {
+	if minChangedLines <= 0 || CountChangedLines(diff) < minChangedLines {
+		return diff, 0
+	}
+
+	condensed := 0
+	for _, file := range diffparse.Parse(diff) {
+		if len(file.Hunks) == 0 {
+			continue
+		}
+
+		if isGeneratedDiff(file) {
+			description := fmt.Sprintf("generated file, %d lines added and %d deleted", len(file.AddedLines()), len(file.DeletedLines()))
+			diff = strings.Replace(diff, file.Raw, fileDiffHeader(file)+"\n"+condensedPrefix+description, 1)
+			condensed++
+			continue
+		}
+
+		keepIndentation := slices.Contains(indentationSensitiveExtensions, path.Ext(file.Path()))
+		newIdentifiers := newSideIdentifiers(file)
+		lines := []string{fileDiffHeader(file)}
+		hunksCondensed := 0
+		for _, hunk := range file.Hunks {
+			if description := describeMechanicalHunk(hunk, keepIndentation, newIdentifiers); description != "" {
+				lines = append(lines, hunkHeader(hunk), condensedPrefix+description)
+				hunksCondensed++
+				continue
+			}
+			lines = append(lines, hunkLines(hunk)...)
+		}
+		if hunksCondensed > 0 {
+			diff = strings.Replace(diff, file.Raw, strings.Join(lines, "\n"), 1)
+			condensed += hunksCondensed
+		}
+	}
+	return diff, condensed
+}
+
+// isGeneratedDiff checks if the changed file is generated, by its path or by the marker comment at its top
+func isGeneratedDiff(file diffparse.FileDiff) bool {
+	if git.IsGeneratedFile(file.Path()) {
+		return true
+	}
+	for _, line := range file.AddedLines() {
+		if line.NewNumber > generatedMarkerLines {
+			break
+		}
+		for _, marker := range generatedMarkers {
+			if strings.Contains(line.Content, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// describeMechanicalHunk describes the changes of the hunk if they are mechanical, empty if the hunk has substantive changes.
+// Reordered lines are only mechanical for imports, reordered statements can change the behavior.
+// The identifiers of the new side of the file tell renames from changed references, a renamed identifier is gone.
+func describeMechanicalHunk(hunk diffparse.Hunk, keepIndentation bool, newIdentifiers map[string]bool) string {
+	var added, deleted, newSide, oldSide []string
+	for _, line := range hunk.Lines {
+		switch line.Kind {
+		case diffparse.LineAdded:
+			added = append(added, line.Content)
+			newSide = append(newSide, line.Content)
+		case diffparse.LineDeleted:
+			deleted = append(deleted, line.Content)
+			oldSide = append(oldSide, line.Content)
+		default:
+			newSide = append(newSide, line.Content)
+			oldSide = append(oldSide, line.Content)
+		}
+	}
+	if len(added) == 0 || len(added) != len(deleted) {
+		return ""
+	}
+
+	if slices.Equal(normalizeWhitespace(newSide, keepIndentation), normalizeWhitespace(oldSide, keepIndentation)) {
+		return fmt.Sprintf("whitespace-only changes in %d lines", len(added))
+	}
+
+	sortedAdded, sortedDeleted := normalizeWhitespace(added, false), normalizeWhitespace(deleted, false)
+	slices.Sort(sortedAdded)
+	slices.Sort(sortedDeleted)
+	if slices.Equal(sortedAdded, sortedDeleted) && allImports(sortedAdded) {
+		return fmt.Sprintf("%d imports reordered", len(added))
+	}
+
+	if from, to, ok := renamedIdentifier(deleted, added); ok && len(added) >= minRenamedLines && !newIdentifiers[from] &&
+		!slices.Contains(keywordTokens, from) && !slices.Contains(keywordTokens, to) {
+		return fmt.Sprintf("renamed `%s` to `%s` in %d lines", from, to, len(added))
+	}
+	return ""
+}
+
+// renamedIdentifier checks if each added line is the deleted line at the same position with the same identifier renamed
+func renamedIdentifier(deleted, added []string) (string, string, bool) {
+	from, to := "", ""
+	for idx := range deleted {
+		oldTokens, newTokens := tokenRegex.FindAllString(deleted[idx], -1), tokenRegex.FindAllString(added[idx], -1)
+		if len(oldTokens) != len(newTokens) {
+			return "", "", false
+		}
+		for pos := range oldTokens {
+			if oldTokens[pos] == newTokens[pos] {
+				continue
+			}
+			if from == "" && identifierRegex.MatchString(oldTokens[pos]) && identifierRegex.MatchString(newTokens[pos]) {
+				from, to = oldTokens[pos], newTokens[pos]
+			}
+			if oldTokens[pos] != from || newTokens[pos] != to {
+				return "", "", false
+			}
+		}
+	}
+	return from, to, from != ""
+}
+
+// newSideIdentifiers returns the identifiers of the added and unchanged lines of the file
+func newSideIdentifiers(file diffparse.FileDiff) map[string]bool {
+	identifiers := map[string]bool{}
+	for _, hunk := range file.Hunks {
+		for _, line := range hunk.Lines {
+			if line.Kind == diffparse.LineDeleted {
+				continue
+			}
+			for _, token := range tokenRegex.FindAllString(line.Content, -1) {
+				if identifierRegex.MatchString(token) {
+					identifiers[token] = true
+				}
+			}
+		}
+	}
+	return identifiers
+}
+
+// allImports checks if the lines are import statements, empty lines are allowed between them
+func allImports(lines []string) bool {
+	for _, line := range lines {
+		if line != "" && !importLineRegex.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeWhitespace collapses the whitespace of the lines, keeping their indentation if it changes the meaning
+func normalizeWhitespace(lines []string, keepIndentation bool) []string {
+	normalized := make([]string, len(lines))
+	for idx, line := range lines {
+		normalized[idx] = strings.Join(strings.Fields(line), " ")
+		if keepIndentation {
+			normalized[idx] = line[:len(line)-len(strings.TrimLeft(line, " \t"))] + normalized[idx]
+		}
+	}
+	return normalized
+}
+
+// fileDiffHeader returns the lines of the file diff before its first hunk
+func fileDiffHeader(file diffparse.FileDiff) string {
+	var header []string
+	for line := range strings.SplitSeq(file.Raw, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			break
+		}
+		header = append(header, line)
+	}
+	return strings.Join(header, "\n")
+}
+
+// hunkHeader formats the header of the hunk
+func hunkHeader(hunk diffparse.Hunk) string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	if hunk.Section != "" {
+		header += " " + hunk.Section
+	}
+	return header
+}
+
+// hunkLines formats the hunk with its header and lines
+func hunkLines(hunk diffparse.Hunk) []string {
+	lines := []string{hunkHeader(hunk)}
+	for _, line := range hunk.Lines {
+		prefix := " "
+		switch line.Kind {
+		case diffparse.LineAdded:
+			prefix = "+"
+		case diffparse.LineDeleted:
+			prefix = "-"
+		}
+		lines = append(lines, prefix+line.Content)
+		if line.NoNewline {
+			lines = append(lines, `\ No newline at end of file`)
+		}
+	}
+	return lines
+}
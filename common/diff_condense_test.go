@@ -0,0 +1,98 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+const condenseSourceDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -3,2 +3,2 @@ import (
+-	"os"
+ 	"fmt"
++	"os"
+@@ -10,2 +10,2 @@ func run() {
+-	total := count(items)
+-	log(total)
++	sum := count(items)
++	log(sum)
+@@ -20,1 +20,1 @@ func check() {
+-	return a > b
++	return a >= b
+diff --git a/api/client.pb.go b/api/client.pb.go
+index 3333333..4444444 100644
+--- a/api/client.pb.go
++++ b/api/client.pb.go
+@@ -1,2 +1,2 @@
+-// Code generated by protoc-gen-go. DO NOT EDIT.
+-// version: 1.0
++// Code generated by protoc-gen-go. DO NOT EDIT.
++// version: 1.1
+`
+
+func TestCondenseDiff(t *testing.T) {
+	condensed, count := CondenseDiff(condenseSourceDiff, 1)
+	if count != 3 {
+		t.Errorf("Expected 3 condensed changes, got %d:\n%s", count, condensed)
+	}
+	for _, expected := range []string{
+		"[condensed] 1 imports reordered",
+		"[condensed] renamed `total` to `sum` in 2 lines",
+		"-\treturn a > b\n+\treturn a >= b",
+		"+++ b/api/client.pb.go\n[condensed] generated file, 2 lines added and 2 deleted",
+	} {
+		if !strings.Contains(condensed, expected) {
+			t.Errorf("Expected the condensed diff to contain %q:\n%s", expected, condensed)
+		}
+	}
+	if strings.Contains(condensed, "log(sum)") {
+		t.Errorf("Expected the rename to be condensed:\n%s", condensed)
+	}
+}
+
+func TestCondenseDiffThreshold(t *testing.T) {
+	if condensed, count := CondenseDiff(condenseSourceDiff, 100); count != 0 || condensed != condenseSourceDiff {
+		t.Errorf("Expected diffs under the threshold unchanged, got %d condensed changes", count)
+	}
+	if _, count := CondenseDiff(condenseSourceDiff, 0); count != 0 {
+		t.Errorf("Expected 0 to disable condensing, got %d condensed changes", count)
+	}
+}
+
+func TestDescribeMechanicalHunkWhitespace(t *testing.T) {
+	diff := "diff --git a/a.py b/a.py\n--- a/a.py\n+++ b/a.py\n@@ -1,1 +1,1 @@\n-x = 1\n+    x = 1\n" +
+		"diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-x  :=  1\n+\tx := 1\n"
+
+	condensed, count := CondenseDiff(diff, 1)
+	if count != 1 || !strings.Contains(condensed, "+++ b/a.go\n@@ -1,1 +1,1 @@\n[condensed] whitespace-only changes in 1 lines") {
+		t.Errorf("Expected only the whitespace change of the Go file condensed, the indentation of Python is kept:\n%s", condensed)
+	}
+}
+
+func TestDescribeMechanicalHunkReorderedStatements(t *testing.T) {
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n-\tclose(done)\n-\twg.Wait()\n+\twg.Wait()\n+\tclose(done)\n"
+	if _, count := CondenseDiff(diff, 1); count != 0 {
+		t.Error("Expected reordered statements to be kept, they can change the behavior")
+	}
+}
+
+func TestDescribeMechanicalHunkChangedLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+	}{
+		{"literal", "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-\treturn false\n+\treturn true\n"},
+		{"single line", "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-\tmu.Lock()\n+\tmu.Unlock()\n"},
+		{"identifier still used", "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,3 +1,3 @@\n-\tif x < y {\n-\t\tlog(y)\n+\tif x < z {\n+\t\tlog(z)\n \t\treturn y\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if condensed, count := CondenseDiff(test.diff, 1); count != 0 || condensed != test.diff {
+				t.Errorf("Expected the change kept in full, got:\n%s", condensed)
+			}
+		})
+	}
+}
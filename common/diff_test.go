@@ -0,0 +1,49 @@
+package common
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const providerDiff = `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var a = 1
++var a = 2
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main
+`
+
+func TestChangedFiles(t *testing.T) {
+	expected := []string{"main.go", "old.go", "new.go"}
+	if files := ChangedFiles(providerDiff); !slices.Equal(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
+
+func TestGetFileDiff(t *testing.T) {
+	fileDiff := GetFileDiff(providerDiff, "main.go")
+	if !strings.HasPrefix(fileDiff, "diff --git a/main.go b/main.go") || !strings.Contains(fileDiff, "+var a = 2") {
+		t.Errorf("Expected the diff of main.go, got:\n%s", fileDiff)
+	}
+	if strings.Contains(fileDiff, "old.go") {
+		t.Errorf("Expected only the diff of main.go, got:\n%s", fileDiff)
+	}
+	if GetFileDiff(providerDiff, "missing.go") != "" {
+		t.Error("Expected no diff for an unchanged file")
+	}
+}
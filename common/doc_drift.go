@@ -0,0 +1,178 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+const (
+	// minSymbolLength skips short symbols, they match too many unrelated words in the docs
+	minSymbolLength = 4
+	// maxDocFileSize skips large generated documentation files
+	maxDocFileSize = 512 * 1024
+)
+
+// docDirectories are searched for documentation in addition to the markdown files of the repository root
+var docDirectories = []string{"docs", "doc", "documentation"}
+
+// exportedSymbolRegexes match declarations of exported functions and types in the supported languages
+var exportedSymbolRegexes = []*regexp.Regexp{
+	// Go
+	regexp.MustCompile(`\bfunc\s+(?:\([^)]*\)\s*)?([A-Z]\w*)\s*[\[(]`),
+	regexp.MustCompile(`\btype\s+([A-Z]\w*)\s`),
+	// JavaScript and TypeScript
+	regexp.MustCompile(`\bexport\s+(?:default\s+)?(?:async\s+)?(?:function\*?|class|const|interface|type|enum)\s+(\w+)`),
+	// Swift, Kotlin and Java
+	regexp.MustCompile(`\b(?:public|open)\s+(?:(?:static|final|override|abstract|data)\s+)*(?:func|fun|class|struct|protocol|interface|enum)\s+(\w+)`),
+	// Python, only top-level public declarations
+	regexp.MustCompile(`^(?:async\s+)?(?:def|class)\s+([A-Za-z]\w*)`),
+}
+
+// DocReference is a changed exported symbol referenced in the documentation
+type DocReference struct {
+	Symbol      string
+	File        string   // Source file the symbol was changed in
+	Docs        []string // Documentation files referencing the symbol
+	DocsChanged bool     // True if any of the documentation files were changed too
+}
+
+// ChangedExportedSymbols returns the exported symbols declared or modified in the diff by source file.
+// Symbols are taken from changed declaration lines and from the enclosing declaration in hunk headers.
+func ChangedExportedSymbols(diff string) map[string][]string {
+	symbols := map[string][]string{}
+	seen := map[string]bool{}
+
+	file := ""
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			continue
+		}
+		if strings.HasPrefix(line, "--- ") || file == "" || isDocFile(file) {
+			continue
+		}
+
+		var code string
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			// The hunk header contains the enclosing declaration after the line ranges
+			parts := strings.SplitN(line, "@@", 3)
+			if len(parts) == 3 {
+				code = strings.TrimSpace(parts[2])
+			}
+		case strings.HasPrefix(line, "+"), strings.HasPrefix(line, "-"):
+			code = line[1:]
+		default:
+			continue
+		}
+
+		for _, regex := range exportedSymbolRegexes {
+			match := regex.FindStringSubmatch(code)
+			if match == nil || len(match[1]) < minSymbolLength || strings.HasPrefix(match[1], "_") {
+				continue
+			}
+			if key := file + ":" + match[1]; !seen[key] {
+				seen[key] = true
+				symbols[file] = append(symbols[file], match[1])
+			}
+		}
+	}
+
+	return symbols
+}
+
+// FindDocReferences looks up the changed exported symbols of the diff in the documentation of the repository
+func FindDocReferences(diff string) []DocReference {
+	symbols := ChangedExportedSymbols(diff)
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	changedFiles := map[string]bool{}
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ b/") {
+			changedFiles[strings.TrimPrefix(line, "+++ b/")] = true
+		}
+	}
+
+	docs := readDocFiles()
+	var references []DocReference
+	for _, file := range sortedFiles(symbols) {
+		for _, symbol := range symbols[file] {
+			symbolRegex := regexp.MustCompile(`\b` + regexp.QuoteMeta(symbol) + `\b`)
+
+			reference := DocReference{Symbol: symbol, File: file}
+			for _, docFile := range sortedFiles(docs) {
+				if symbolRegex.MatchString(docs[docFile]) {
+					reference.Docs = append(reference.Docs, docFile)
+					reference.DocsChanged = reference.DocsChanged || changedFiles[docFile]
+				}
+			}
+			if len(reference.Docs) > 0 {
+				references = append(references, reference)
+			}
+		}
+	}
+
+	return references
+}
+
+// readDocFiles reads the markdown files of the repository root and the documentation directories
+func readDocFiles() map[string]string {
+	docs := map[string]string{}
+
+	read := func(path string) {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() > maxDocFileSize {
+			return
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Debugf("Failed to read documentation file %s: %v", path, err)
+			return
+		}
+		docs[filepath.ToSlash(path)] = string(content)
+	}
+
+	rootDocs, _ := filepath.Glob("*.md")
+	for _, path := range rootDocs {
+		read(path)
+	}
+
+	for _, dir := range docDirectories {
+		filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if !entry.IsDir() && isDocFile(path) {
+				read(path)
+			}
+			return nil
+		})
+	}
+
+	return docs
+}
+
+// isDocFile returns true for markdown and text documentation files
+func isDocFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".mdx", ".rst", ".adoc", ".txt":
+		return true
+	}
+	return false
+}
+
+func sortedFiles[T any](files map[string]T) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
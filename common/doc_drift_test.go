@@ -0,0 +1,75 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChangedExportedSymbols(t *testing.T) {
+	diff := `diff --git a/common/retry.go b/common/retry.go
+--- a/common/retry.go
++++ b/common/retry.go
+@@ -10,7 +10,7 @@ func NewRetryableClient(config RetryConfig) *retryablehttp.Client {
+-	retryClient.RetryMax = 3
++	retryClient.RetryMax = config.MaxRetries
+@@ -30,3 +30,6 @@ type RetryConfig struct {
++func (c RetryConfig) WithBackoff(backoff int) RetryConfig {
++func helper() {}
+diff --git a/web/api.ts b/web/api.ts
+--- a/web/api.ts
++++ b/web/api.ts
+@@ -1 +1,2 @@
++export async function fetchReviews(id: string) {
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1,2 @@
++func NotASymbol() {}
+`
+
+	expected := map[string][]string{
+		"common/retry.go": {"NewRetryableClient", "RetryConfig", "WithBackoff"},
+		"web/api.ts":      {"fetchReviews"},
+	}
+	if symbols := ChangedExportedSymbols(diff); !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("Expected %v, got %v", expected, symbols)
+	}
+}
+
+func TestFindDocReferences(t *testing.T) {
+	tempDir := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	os.WriteFile("README.md", []byte("Use `NewRetryableClient` to create a client."), 0644)
+	os.MkdirAll(filepath.Join("docs", "api"), 0755)
+	os.WriteFile(filepath.Join("docs", "api", "retry.md"), []byte("NewRetryableClient retries 3 times."), 0644)
+
+	diff := `--- a/common/retry.go
++++ b/common/retry.go
+@@ -10,7 +10,7 @@ func NewRetryableClient(config RetryConfig) *retryablehttp.Client {
+-	retryClient.RetryMax = 3
++	retryClient.RetryMax = config.MaxRetries
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-Use it.
++Use it!
+`
+
+	references := FindDocReferences(diff)
+	expected := []DocReference{
+		{Symbol: "NewRetryableClient", File: "common/retry.go", Docs: []string{"README.md", "docs/api/retry.md"}, DocsChanged: true},
+	}
+	if !reflect.DeepEqual(references, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, references)
+	}
+}
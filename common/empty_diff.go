@@ -0,0 +1,25 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+)
+
+// HasReviewableChanges checks if the diff changes any line besides whitespace.
+// Empty diffs of merge commits and diffs only changing whitespace, file modes, renames or binary files have nothing to review.
+func HasReviewableChanges(diff string) bool {
+	for _, file := range diffparse.Parse(diff) {
+		for _, hunk := range file.Hunks {
+			for _, line := range hunk.Lines {
+				if line.Kind != diffparse.LineContext && strings.TrimSpace(line.Content) != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// NoChangesNote is the summary of a diff without reviewable changes, posted instead of running the review
+const NoChangesNote = "No reviewable changes: the diff is empty or only changes whitespace, file modes or binary files."
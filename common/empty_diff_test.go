@@ -0,0 +1,62 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHasReviewableChanges(t *testing.T) {
+	tests := map[string]struct {
+		diff     string
+		expected bool
+	}{
+		"empty": {diff: "", expected: false},
+		"code change": {diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,2 @@
+ package main
+-var a = 1
++var a = 2
+`, expected: true},
+		"whitespace only": {diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-
++	
+ var a = 1
+`, expected: false},
+		"mode and binary": {diff: `diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+diff --git a/logo.png b/logo.png
+index 1111111..2222222 100644
+Binary files a/logo.png and b/logo.png differ
+`, expected: false},
+	}
+	for name, test := range tests {
+		if got := HasReviewableChanges(test.diff); got != test.expected {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, got)
+		}
+	}
+}
+
+func TestNoteString(t *testing.T) {
+	summary := Summary{
+		Tracking: SummaryState{Commit: "bbbbbbb222", Files: map[string]string{}},
+		Previous: SummaryState{Commit: "aaaaaaa111", Files: map[string]string{"main.go": "1234"}},
+	}.ReuseUnchanged()
+
+	output := summary.NoteString(ProviderGitHub, NoChangesNote)
+	if !strings.HasPrefix(output, summary.Header()) || !strings.Contains(output, "No reviewable changes") {
+		t.Errorf("Expected the no changes note, got:\n%s", output)
+	}
+	if state := ParseSummaryState(output); state.Commit != "bbbbbbb222" {
+		t.Errorf("Expected the state of the commit, got %+v", state)
+	}
+	if !strings.Contains(output, "_Updated for commit bbbbbbb (1 file changed)_") {
+		t.Errorf("Expected the changelog, got:\n%s", output)
+	}
+}
@@ -0,0 +1,231 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind classifies API failures by their remediation
+type ErrorKind string
+
+const (
+	ErrorKindAuth            ErrorKind = "auth"
+	ErrorKindPermission      ErrorKind = "permission"
+	ErrorKindRateLimit       ErrorKind = "rate_limit"
+	ErrorKindNotFound        ErrorKind = "not_found"
+	ErrorKindPayloadTooLarge ErrorKind = "payload_too_large"
+	ErrorKindOverloaded      ErrorKind = "overloaded"
+	ErrorKindUnavailable     ErrorKind = "unavailable"
+)
+
+// Exit codes of the plugin, a contract for the workflows branching on the outcome of the review
+const (
+	ExitCodeOK       = 0
+	ExitCodeError    = 1  // Unexpected failure, e.g. of a git command
+	ExitCodeFindings = 10 // Findings at or above the fail_on_severity threshold, license or branch policy violations
+	ExitCodeConfig   = 20 // Invalid flags, settings or missing credentials
+	ExitCodeProvider = 30 // Failed call to the code review provider or another service
+	ExitCodeLLM      = 40 // Failed call to the LLM provider
+)
+
+// ErrorCategory is the stage of the run an error comes from, each category has its own exit code
+type ErrorCategory string
+
+const (
+	ErrorCategoryFindings ErrorCategory = "findings"
+	ErrorCategoryConfig   ErrorCategory = "config"
+	ErrorCategoryProvider ErrorCategory = "provider"
+	ErrorCategoryLLM      ErrorCategory = "llm"
+)
+
+// llmServices are the services of the LLM providers, their API errors are LLM errors
+var llmServices = map[string]bool{
+	"OpenAI":    true,
+	"Anthropic": true,
+}
+
+// APIError is a typed error returned by the LLM and code review provider clients
+type APIError struct {
+	Kind       ErrorKind
+	Service    string // Name of the API, e.g. GitHub or OpenAI
+	StatusCode int
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (%s): %v", e.Service, e.Kind, e.Err)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Remediation returns a hint on how to fix the error
+func (e *APIError) Remediation() string {
+	switch e.Kind {
+	case ErrorKindAuth:
+		return fmt.Sprintf("%s rejected the credentials. Check that the API token is set and has not expired.", e.Service)
+	case ErrorKindPermission:
+		return fmt.Sprintf("%s denied access. Check that the API token has access to the repository and the required scopes (e.g. pull request read and write).", e.Service)
+	case ErrorKindRateLimit:
+		return fmt.Sprintf("%s rate limit reached. Retry later, or use a token with a higher quota.", e.Service)
+	case ErrorKindNotFound:
+		return fmt.Sprintf("%s could not find the resource. Check the repository, pull request number, model name and the API URL.", e.Service)
+	case ErrorKindPayloadTooLarge:
+		return fmt.Sprintf("%s rejected the request as too large. Narrow the review with path filters, or use a model with a larger context window.", e.Service)
+	case ErrorKindOverloaded:
+		return fmt.Sprintf("%s is overloaded. Retry later, or configure model_fallbacks to switch to another model.", e.Service)
+	case ErrorKindUnavailable:
+		return fmt.Sprintf("%s is unavailable, the remaining requests to it were skipped. Check the status page of the service and retry later, or run the healthcheck command before the review.", e.Service)
+	}
+	return ""
+}
+
+// Category returns the category of the error by the API it comes from
+func (e *APIError) Category() ErrorCategory {
+	if llmServices[e.Service] {
+		return ErrorCategoryLLM
+	}
+	return ErrorCategoryProvider
+}
+
+// statusOverloaded is the non-standard status code of the Anthropic API for overloaded servers
+const statusOverloaded = 529
+
+// ErrorKindFromStatus classifies an HTTP status code, returning an empty kind for unclassified codes
+func ErrorKindFromStatus(statusCode int) ErrorKind {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return ErrorKindAuth
+	case http.StatusForbidden:
+		return ErrorKindPermission
+	case http.StatusNotFound:
+		return ErrorKindNotFound
+	case http.StatusRequestEntityTooLarge:
+		return ErrorKindPayloadTooLarge
+	case http.StatusTooManyRequests:
+		return ErrorKindRateLimit
+	case http.StatusServiceUnavailable, statusOverloaded:
+		return ErrorKindOverloaded
+	}
+	return ""
+}
+
+// NewAPIError creates a typed error from the HTTP status code of a failed API call.
+// The error is returned unchanged if the status code is not classified.
+func NewAPIError(service string, statusCode int, err error) error {
+	kind := ErrorKindFromStatus(statusCode)
+	if kind == "" || err == nil {
+		return err
+	}
+
+	return &APIError{
+		Kind:       kind,
+		Service:    service,
+		StatusCode: statusCode,
+		Err:        err,
+	}
+}
+
+// wrappedError keeps the cause of an error in the chain while exposing a custom message
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e *wrappedError) Error() string {
+	return e.msg
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+// CategorizedError assigns the category of the stage it failed in to an error
+type CategorizedError struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *CategorizedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CategorizedError) Unwrap() error {
+	return e.Err
+}
+
+// NewConfigError marks an error caused by invalid flags, settings or credentials
+func NewConfigError(err error) error {
+	return &CategorizedError{Category: ErrorCategoryConfig, Err: err}
+}
+
+// NewProviderError marks an error of a code review provider call, including the failures not classified as an APIError
+func NewProviderError(err error) error {
+	return &CategorizedError{Category: ErrorCategoryProvider, Err: err}
+}
+
+// NewLLMError marks an error of an LLM call, including the failures not classified as an APIError
+func NewLLMError(err error) error {
+	return &CategorizedError{Category: ErrorCategoryLLM, Err: err}
+}
+
+// FindingsError fails the run because of the findings of the review
+type FindingsError struct {
+	Count   int // Number of findings at or above the threshold
+	Message string
+}
+
+func (e *FindingsError) Error() string {
+	return e.Message
+}
+
+// WrapError returns an error with the given message, keeping err in the chain for errors.As
+func WrapError(msg string, err error) error {
+	return &wrappedError{msg: msg, err: err}
+}
+
+// Remediation returns the remediation hint for the first API error in the chain
+func Remediation(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Remediation()
+	}
+	return ""
+}
+
+// Category returns the category of the outermost categorized error in the chain, or of the API error, empty for other errors
+func Category(err error) ErrorCategory {
+	var findingsErr *FindingsError
+	var categorizedErr *CategorizedError
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &findingsErr):
+		return ErrorCategoryFindings
+	case errors.As(err, &categorizedErr):
+		return categorizedErr.Category
+	case errors.As(err, &apiErr):
+		return apiErr.Category()
+	}
+	return ""
+}
+
+// ExitCode returns the process exit code for an error
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+
+	switch Category(err) {
+	case ErrorCategoryFindings:
+		return ExitCodeFindings
+	case ErrorCategoryConfig:
+		return ExitCodeConfig
+	case ErrorCategoryProvider:
+		return ExitCodeProvider
+	case ErrorCategoryLLM:
+		return ExitCodeLLM
+	}
+	return ExitCodeError
+}
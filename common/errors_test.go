@@ -0,0 +1,87 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	cause := errors.New("request failed")
+
+	if err := NewAPIError("GitHub", http.StatusInternalServerError, cause); err != cause {
+		t.Errorf("Expected unclassified status to return the original error, got %v", err)
+	}
+
+	err := NewAPIError("GitHub", http.StatusForbidden, cause)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %T", err)
+	}
+	if apiErr.Kind != ErrorKindPermission {
+		t.Errorf("Expected kind %s, got %s", ErrorKindPermission, apiErr.Kind)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected the cause to be kept in the error chain")
+	}
+}
+
+func TestExitCodeAndRemediation(t *testing.T) {
+	if ExitCode(nil) != 0 {
+		t.Errorf("Expected exit code 0 for nil error, got %d", ExitCode(nil))
+	}
+
+	plain := errors.New("something went wrong")
+	if ExitCode(plain) != ExitCodeError {
+		t.Errorf("Expected exit code %d, got %d", ExitCodeError, ExitCode(plain))
+	}
+	if Remediation(plain) != "" {
+		t.Errorf("Expected no remediation for plain error, got %s", Remediation(plain))
+	}
+
+	// Typed errors are found through wrapped messages
+	wrapped := WrapError("Error posting summary: HTTP 429", NewAPIError("Bitbucket", http.StatusTooManyRequests, plain))
+	if wrapped.Error() != "Error posting summary: HTTP 429" {
+		t.Errorf("Expected wrapped message to be kept, got %s", wrapped.Error())
+	}
+	if ExitCode(wrapped) != ExitCodeProvider {
+		t.Errorf("Expected exit code %d, got %d", ExitCodeProvider, ExitCode(wrapped))
+	}
+	if Remediation(wrapped) == "" {
+		t.Error("Expected remediation for rate limit error")
+	}
+}
+
+func TestOverloadedError(t *testing.T) {
+	err := NewAPIError("Anthropic", 529, errors.New("overloaded"))
+	if ExitCode(err) != ExitCodeLLM {
+		t.Errorf("Expected exit code %d, got %d", ExitCodeLLM, ExitCode(err))
+	}
+	var apiErr *APIError
+	if !errors.As(NewAPIError("OpenAI", http.StatusServiceUnavailable, errors.New("unavailable")), &apiErr) || apiErr.Kind != ErrorKindOverloaded {
+		t.Error("Expected service unavailable to be classified as overloaded")
+	}
+}
+
+func TestExitCodeContract(t *testing.T) {
+	cause := errors.New("request failed")
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"findings", &FindingsError{Count: 2, Message: "2 findings"}, ExitCodeFindings},
+		{"config", NewConfigError(cause), ExitCodeConfig},
+		{"unclassified provider failure", NewProviderError(cause), ExitCodeProvider},
+		{"provider API error", NewAPIError("Bitbucket", http.StatusUnauthorized, cause), ExitCodeProvider},
+		{"LLM API error", NewAPIError("Anthropic", http.StatusTooManyRequests, cause), ExitCodeLLM},
+		// The outermost category wins, an LLM call failing on a tool call to the provider is an LLM error
+		{"LLM wrapping provider", NewLLMError(WrapError("tool call failed", NewAPIError("GitHub", http.StatusNotFound, cause))), ExitCodeLLM},
+		{"uncategorized", cause, ExitCodeError},
+	}
+	for _, c := range cases {
+		if got := ExitCode(c.err); got != c.expected {
+			t.Errorf("%s: expected exit code %d, got %d", c.name, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// DefaultFeatureFlagPatterns match the flag key of the common feature flag SDKs in the first submatch:
+// LaunchDarkly, Unleash, Split, Optimizely, ConfigCat, Flagsmith and OpenFeature
+var DefaultFeatureFlagPatterns = []string{
+	`\b(?:boolVariation|stringVariation|intVariation|jsonVariation|variation|BoolVariation|StringVariation|IntVariation|JSONVariation)\(\s*["']([\w.\-:]+)["']`,
+	`\b(?:isEnabled|IsEnabled|is_enabled)\(\s*["']([\w.\-:]+)["']`,
+	`\b(?:getTreatment|get_treatment|GetTreatment)\([^"']*["']([\w.\-:]+)["']`,
+	`\b(?:isFeatureEnabled|is_feature_enabled|IsFeatureEnabled|decide)\(\s*["']([\w.\-:]+)["']`,
+	`\b(?:getValue|getValueAsync|GetValue)\(\s*["']([\w.\-:]+)["']`,
+	`\b(?:hasFeature|has_feature|getBooleanValue|GetBooleanValue|getBooleanDetails)\(\s*["']([\w.\-:]+)["']`,
+}
+
+// FeatureFlagChanges lists the feature flags introduced and removed by the changes
+type FeatureFlagChanges struct {
+	Added   []string
+	Removed []string
+}
+
+// DetectFeatureFlags finds the feature flag keys introduced or removed in the diff using the SDK patterns
+func DetectFeatureFlags(diff string, settings FeatureFlags) FeatureFlagChanges {
+	patterns := settings.SDKPatterns
+	if len(patterns) == 0 {
+		patterns = DefaultFeatureFlagPatterns
+	}
+
+	var regexes []*regexp.Regexp
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Warnf("Skipping invalid feature flag pattern %s: %v", pattern, err)
+			continue
+		}
+		regexes = append(regexes, regex)
+	}
+
+	added := map[string]bool{}
+	removed := map[string]bool{}
+	var addedOrder, removedOrder []string
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+
+		var target map[string]bool
+		var order *[]string
+		switch {
+		case strings.HasPrefix(line, "+"):
+			target, order = added, &addedOrder
+		case strings.HasPrefix(line, "-"):
+			target, order = removed, &removedOrder
+		default:
+			continue
+		}
+
+		for _, regex := range regexes {
+			for _, match := range regex.FindAllStringSubmatch(line, -1) {
+				if len(match) > 1 && match[1] != "" && !target[match[1]] {
+					target[match[1]] = true
+					*order = append(*order, match[1])
+				}
+			}
+		}
+	}
+
+	// Flags both added and removed were only moved around
+	var changes FeatureFlagChanges
+	for _, flag := range addedOrder {
+		if !removed[flag] {
+			changes.Added = append(changes.Added, flag)
+		}
+	}
+	for _, flag := range removedOrder {
+		if !added[flag] {
+			changes.Removed = append(changes.Removed, flag)
+		}
+	}
+	return changes
+}
+
+// IsEmpty returns true if no feature flags were introduced or removed
+func (c FeatureFlagChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// FindFlagUsages returns the remaining usages of the removed flags in the codebase at the commit
+func FindFlagUsages(client *git.Client, commitHash string, flags []string) map[string]string {
+	usages := map[string]string{}
+	for _, flag := range flags {
+		// git grep fails if there are no matches
+		output, err := client.Grep(commitHash, flag, false, "")
+		if err == nil && strings.TrimSpace(output) != "" {
+			usages[flag] = output
+		}
+	}
+	return usages
+}
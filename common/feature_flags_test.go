@@ -0,0 +1,32 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectFeatureFlags(t *testing.T) {
+	diff := `--- a/app/checkout.ts
++++ b/app/checkout.ts
+@@ -1,4 +1,4 @@
+-if (ldClient.variation("old-checkout", false)) {
++if (ldClient.boolVariation("new-checkout", false)) {
+-  unleash.isEnabled("moved-flag")
++    unleash.isEnabled("moved-flag")
++  client.getTreatment(user, "pricing.v2")
+`
+
+	changes := DetectFeatureFlags(diff, FeatureFlags{})
+	expected := FeatureFlagChanges{
+		Added:   []string{"new-checkout", "pricing.v2"},
+		Removed: []string{"old-checkout"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, changes)
+	}
+
+	custom := DetectFeatureFlags(diff, FeatureFlags{SDKPatterns: []string{`Flags\.on\("(\w+)"\)`, `(invalid`}})
+	if !custom.IsEmpty() {
+		t.Errorf("Expected custom patterns to replace the defaults, got %+v", custom)
+	}
+}
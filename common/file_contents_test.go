@@ -0,0 +1,63 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+func TestGetFileContentsOfFilesKeepsOrder(t *testing.T) {
+	runner := fakeRunner{}
+	var files []string
+	for idx := range 50 {
+		file := fmt.Sprintf("src/file%d.go", idx)
+		files = append(files, file)
+		runner["show head:"+file] = fmt.Sprintf("package file%d", idx)
+	}
+	files = append(files, "deleted.go")
+
+	output, err := git.NewClient(runner).GetFileContentsOfFiles("head", files)
+	if err != nil {
+		t.Fatalf("Failed to get file contents: %v", err)
+	}
+
+	last := -1
+	for idx := range 50 {
+		position := strings.Index(output, fmt.Sprintf("===== FILE: src/file%d.go =====\npackage file%d\n", idx, idx))
+		if position <= last {
+			t.Fatalf("Expected src/file%d.go after the previous file, got:\n%s", idx, output)
+		}
+		last = position
+	}
+	if strings.Contains(output, "deleted.go") {
+		t.Error("Expected files missing at the commit to be skipped")
+	}
+}
+
+func TestGetFileContentsOfFilesBudget(t *testing.T) {
+	git.SetFileContentBudget(56)
+	defer git.SetFileContentBudget(0)
+
+	runner := fakeRunner{
+		"show head:package-lock.json": strings.Repeat("lock\n", 10),
+		"show head:main.go":           "package main\nfunc main() {}\nvar a = 1\nvar b = 2",
+		"show head:util.go":           "package main\nfunc util() {}",
+	}
+	output, err := git.NewClient(runner).GetFileContentsOfFiles("head", []string{"package-lock.json", "main.go", "util.go"})
+	if err != nil {
+		t.Fatalf("Failed to get file contents: %v", err)
+	}
+
+	// The small source file fits, the larger one is cut at a line boundary and the lockfile gets no budget
+	if !strings.Contains(output, "===== FILE: util.go =====\npackage main\nfunc util() {}\n===== END =====") {
+		t.Errorf("Expected util.go in full, got:\n%s", output)
+	}
+	if !strings.Contains(output, "===== FILE: main.go =====\npackage main\nfunc main() {}\n===== END =====") {
+		t.Errorf("Expected main.go to be truncated to its first lines, got:\n%s", output)
+	}
+	if strings.Contains(output, "package-lock.json") {
+		t.Errorf("Expected the lockfile to be skipped, got:\n%s", output)
+	}
+}
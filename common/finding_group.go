@@ -0,0 +1,72 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// FindingLocation is a location of a finding grouped with other findings of the same topic
+type FindingLocation struct {
+	File           string
+	LineNumber     int
+	LastLineNumber int
+}
+
+// String formats the location as file:line or file:first-last, or only the file for file comments
+func (l FindingLocation) String() string {
+	if l.LineNumber <= 0 {
+		return l.File
+	}
+	if l.LastLineNumber > l.LineNumber {
+		return fmt.Sprintf("%s:%d-%d", l.File, l.LineNumber, l.LastLineNumber)
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.LineNumber)
+}
+
+// Location returns the location of the finding
+func (l LineLevel) Location() FindingLocation {
+	return FindingLocation{File: l.File, LineNumber: l.LineNumber, LastLineNumber: l.LastLineNumber}
+}
+
+// GetGroupFingerprint returns the identifier shared by the findings of the same issue at multiple locations.
+// It only depends on the category and the topic, so findings in different files match. Empty without a topic.
+func (l LineLevel) GetGroupFingerprint() string {
+	topic := strings.ToLower(strings.Join(strings.Fields(l.Topic), " "))
+	if topic == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(l.Category + "\n" + topic))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// GroupFindings clusters the findings sharing the same group fingerprint: the first finding of a group becomes
+// the primary comment listing the other locations, the rest become short cross-references to it.
+// Nitpicks and findings without a topic are left as is.
+func GroupFindings(lines []LineLevel) []LineLevel {
+	primaries := map[string]int{}
+	for idx, ll := range lines {
+		fingerprint := ll.GetGroupFingerprint()
+		if fingerprint == "" || ll.Category == CategoryNitpick || ll.LineNumber <= 0 {
+			continue
+		}
+
+		primaryIdx, ok := primaries[fingerprint]
+		if !ok {
+			primaries[fingerprint] = idx
+			continue
+		}
+
+		primary := lines[primaryIdx].Location()
+		lines[idx].PrimaryLocation = &primary
+		lines[primaryIdx].RelatedLocations = append(lines[primaryIdx].RelatedLocations, ll.Location())
+	}
+	return lines
+}
+
+// IsCrossReference returns true if the finding is a short reference to the primary comment of its group
+func (l LineLevel) IsCrossReference() bool {
+	return l.PrimaryLocation != nil
+}
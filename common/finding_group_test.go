@@ -0,0 +1,34 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupFindings(t *testing.T) {
+	lines := GroupFindings([]LineLevel{
+		{File: "a.go", Line: "resp, _ := http.Get(url)", LineNumber: 3, Category: CategoryBug, Topic: "unclosed-response-body", Body: "The response body is never closed"},
+		{File: "b.go", Line: "resp, _ := http.Get(u)", LineNumber: 7, LastLineNumber: 8, Category: CategoryBug, Topic: "Unclosed-Response-Body", Body: "Same"},
+		{File: "c.go", Line: "x := 1", LineNumber: 1, Category: CategoryBug, Body: "Unrelated"},
+		{File: "d.go", Line: "resp, _ := http.Get(v)", LineNumber: 5, Category: CategoryPerformance, Topic: "unclosed-response-body", Body: "Other category"},
+	})
+
+	if len(lines[0].RelatedLocations) != 1 || lines[0].RelatedLocations[0].String() != "b.go:7-8" {
+		t.Errorf("Expected the primary finding to list b.go:7-8, got %+v", lines[0].RelatedLocations)
+	}
+	if !lines[1].IsCrossReference() || lines[1].PrimaryLocation.String() != "a.go:3" {
+		t.Errorf("Expected a cross-reference to a.go:3, got %+v", lines[1].PrimaryLocation)
+	}
+	if lines[2].IsCrossReference() || lines[3].IsCrossReference() || len(lines[3].RelatedLocations) > 0 {
+		t.Error("Expected findings without a topic or of another category not to be grouped")
+	}
+
+	primary := lines[0].String(ProviderGitHub, nil, "")
+	if !strings.Contains(primary, "The response body is never closed") || !strings.Contains(primary, "- `b.go:7-8`") {
+		t.Errorf("Expected the primary comment to list the other locations, got:\n%s", primary)
+	}
+	reference := lines[1].String(ProviderGitHub, nil, "")
+	if !strings.Contains(reference, "Same issue as at `a.go:3`") || strings.Contains(reference, "\nSame\n") {
+		t.Errorf("Expected a short cross-reference comment, got:\n%s", reference)
+	}
+}
@@ -0,0 +1,247 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// FormattingHeader identifies the comment with the formatting issues of the changed files
+const FormattingHeader = "[bitrise-plugin-ai-reviewer]: formatting"
+
+// commandNotFoundExitCode is the exit code of sh if the program of the command is not installed
+const commandNotFoundExitCode = 127
+
+// formatterPresets are the check mode commands of the known formatters, used if the command is not configured.
+// A check mode command prints nothing and exits with 0 if the files are formatted.
+var formatterPresets = map[string]Formatter{
+	"gofmt":       {Command: "gofmt -d {{.files}}", Paths: []string{"**/*.go"}},
+	"swiftformat": {Command: "swiftformat --lint --quiet {{.files}}", Paths: []string{"**/*.swift"}},
+	"prettier": {Command: "prettier --check --log-level warn {{.files}}", Paths: []string{
+		"**/*.js", "**/*.jsx", "**/*.ts", "**/*.tsx", "**/*.css", "**/*.scss", "**/*.json", "**/*.md", "**/*.yml", "**/*.yaml",
+	}},
+}
+
+// Formatter is a local formatter run in check mode on the changed files, instead of asking the LLM about formatting.
+// The command is a Go template, {{.files}} is replaced with the shell-quoted changed files matching the paths.
+type Formatter struct {
+	Name    string   `yaml:"name"`    // gofmt, swiftformat, prettier or a custom name
+	Command string   `yaml:"command"` // Check mode command, defaults to the preset of the name
+	Paths   []string `yaml:"paths"`   // Globs of the formatted files, default to the preset of the name
+	Timeout int      `yaml:"timeout"` // in seconds, 120 by default
+}
+
+// FormatterResult is the outcome of a formatter on the changed files
+type FormatterResult struct {
+	Name   string
+	Files  []string // Checked files
+	Output string   // Patch or the list of unformatted files, empty if every file is formatted
+}
+
+// withPreset returns the formatter with the command and the paths of its preset where they are not configured
+func (f Formatter) withPreset() Formatter {
+	preset := formatterPresets[f.Name]
+	if f.Command == "" {
+		f.Command = preset.Command
+	}
+	if len(f.Paths) == 0 {
+		f.Paths = preset.Paths
+	}
+	return f
+}
+
+// Validate checks that the formatter has a command and paths, or a preset
+func (f Formatter) Validate() error {
+	if f.Name == "" {
+		return errors.New("formatter name cannot be empty")
+	}
+	resolved := f.withPreset()
+	if resolved.Command == "" || len(resolved.Paths) == 0 {
+		return fmt.Errorf("formatter %s needs a command and paths, presets are available for %s", f.Name, strings.Join(formatterNames(), ", "))
+	}
+	if f.Timeout < 0 {
+		return fmt.Errorf("timeout of formatter %s cannot be negative", f.Name)
+	}
+	if _, err := template.New(f.Name).Option("missingkey=error").Parse(resolved.Command); err != nil {
+		return fmt.Errorf("invalid command of formatter %s: %v", f.Name, err)
+	}
+	return nil
+}
+
+// ValidateFormatters checks the configured formatters
+func ValidateFormatters(formatters []Formatter) error {
+	for _, formatter := range formatters {
+		if err := formatter.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches returns the files matching the paths of the formatter
+func (f Formatter) Matches(files []string) []string {
+	var matches []string
+	for _, file := range files {
+		for _, pattern := range f.withPreset().Paths {
+			if MatchGlob(pattern, file) {
+				matches = append(matches, file)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// RunFormatters runs the formatters in check mode on the changed files of the diff which still exist.
+// Formatters which are not installed or fail to run are skipped with a warning.
+func RunFormatters(formatters []Formatter, diff string) []FormatterResult {
+	var existing []string
+	for _, file := range ChangedFiles(diff) {
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			existing = append(existing, file)
+		}
+	}
+
+	var results []FormatterResult
+	for _, formatter := range formatters {
+		files := formatter.Matches(existing)
+		if len(files) == 0 {
+			continue
+		}
+
+		output, err := formatter.withPreset().run(files)
+		if err != nil {
+			logger.Warnf("Skipping formatter %s: %v", formatter.Name, err)
+			continue
+		}
+		results = append(results, FormatterResult{Name: formatter.Name, Files: files, Output: output})
+	}
+	return results
+}
+
+// run runs the command on the files, returns its output if the files are not formatted
+func (f Formatter) run(files []string) (string, error) {
+	quoted := make([]string, len(files))
+	for idx, file := range files {
+		quoted[idx] = shellQuote(file)
+	}
+	tmpl, err := template.New(f.Name).Option("missingkey=error").Parse(f.Command)
+	if err != nil {
+		return "", err
+	}
+	var command strings.Builder
+	if err := tmpl.Execute(&command, map[string]string{"files": strings.Join(quoted, " ")}); err != nil {
+		return "", errors.New("the command can only use {{.files}}")
+	}
+
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = commandTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	logger.Debugf("Running formatter %s: %s", f.Name, command.String())
+	process := exec.CommandContext(ctx, "sh", "-c", command.String())
+	process.Env = minimalEnvironment()
+	output, err := process.CombinedOutput()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("timed out after %d seconds", timeout)
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == commandNotFoundExitCode:
+		return "", fmt.Errorf("not installed: %s", strings.TrimSpace(string(output)))
+	case err != nil && !errors.As(err, &exitErr):
+		return "", err
+	case err != nil && strings.TrimSpace(string(output)) == "":
+		return fmt.Sprintf("%s failed with exit code %d", f.Name, exitErr.ExitCode()), nil
+	}
+	return truncateOutput(strings.TrimSpace(string(output))), nil
+}
+
+// Unformatted returns the results with formatting issues
+func Unformatted(results []FormatterResult) []FormatterResult {
+	var unformatted []FormatterResult
+	for _, result := range results {
+		if result.Output != "" {
+			unformatted = append(unformatted, result)
+		}
+	}
+	return unformatted
+}
+
+// FormatFormattingComment formats the comment with the formatting issues of each formatter and their patches.
+// The comment of a previous run is updated to a note if every file is formatted.
+func FormatFormattingComment(provider string, results []FormatterResult) string {
+	renderer := NewMarkdownRenderer(provider)
+
+	var builder strings.Builder
+	builder.WriteString(FormattingHeader + "\n\n## Formatting\n")
+
+	unformatted := Unformatted(results)
+	if len(unformatted) == 0 {
+		builder.WriteString(renderer.Note("✅ The changed files are formatted."))
+		return builder.String()
+	}
+
+	builder.WriteString("The changed files are checked with the local formatters, run them to fix these issues:\n")
+	for _, result := range unformatted {
+		builder.WriteString("\n" + formatterSummary(result) + "\n")
+		language := ""
+		if isPatch(result.Output) {
+			language = "diff"
+		}
+		builder.WriteString(renderer.Collapsible("Output of "+result.Name, "```"+language+"\n"+result.Output+"\n```") + "\n")
+	}
+	return builder.String()
+}
+
+// formatterSummary summarizes the patch of the formatter, or the checked files named in its output if it did not print a patch
+func formatterSummary(result FormatterResult) string {
+	if !isPatch(result.Output) {
+		var named []string
+		for _, file := range result.Files {
+			if strings.Contains(result.Output, file) {
+				named = append(named, "`"+file+"`")
+			}
+		}
+		if len(named) == 0 {
+			return fmt.Sprintf("- **%s**: %d files checked, see the output", result.Name, len(result.Files))
+		}
+		return fmt.Sprintf("- **%s**: %d files are not formatted: %s", result.Name, len(named), strings.Join(named, ", "))
+	}
+
+	var files []string
+	for line := range strings.SplitSeq(result.Output, "\n") {
+		if path, found := strings.CutPrefix(line, "+++ "); found {
+			path = "`" + strings.TrimPrefix(strings.Fields(path)[0], "b/") + "`"
+			if !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+		}
+	}
+	return fmt.Sprintf("- **%s**: %d files, %d changed lines: %s", result.Name, len(files), CountChangedLines(result.Output), strings.Join(files, ", "))
+}
+
+// isPatch checks if the output of the formatter is a unified diff
+func isPatch(output string) bool {
+	return strings.Contains(output, "\n+++ ") && strings.Contains(output, "\n@@ ")
+}
+
+func formatterNames() []string {
+	names := make([]string, 0, len(formatterPresets))
+	for name := range formatterPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const formatterDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,1 +1,1 @@
+-package main
++package  main
+diff --git a/deleted.go b/deleted.go
+--- a/deleted.go
++++ /dev/null
+@@ -1,1 +0,0 @@
+-package main
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,1 +1,1 @@
+-# Title
++# New title
+`
+
+func TestFormatterValidate(t *testing.T) {
+	valid := []Formatter{
+		{Name: "gofmt"},
+		{Name: "ktlint", Command: "ktlint {{.files}}", Paths: []string{"**/*.kt"}},
+	}
+	if err := ValidateFormatters(valid); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := map[string]Formatter{
+		"name":     {Command: "ktlint {{.files}}", Paths: []string{"**/*.kt"}},
+		"command":  {Name: "ktlint", Paths: []string{"**/*.kt"}},
+		"paths":    {Name: "ktlint", Command: "ktlint {{.files}}"},
+		"template": {Name: "ktlint", Command: "ktlint {{.files", Paths: []string{"**/*.kt"}},
+		"timeout":  {Name: "gofmt", Timeout: -1},
+	}
+	for name, formatter := range invalid {
+		if err := formatter.Validate(); err == nil {
+			t.Errorf("Expected an error for an invalid %s", name)
+		}
+	}
+}
+
+func TestFormatterMatches(t *testing.T) {
+	files := []string{"main.go", "cmd/root.go", "App/View.swift", "README.md"}
+
+	got := Formatter{Name: "gofmt"}.Matches(files)
+	if strings.Join(got, ",") != "main.go,cmd/root.go" {
+		t.Errorf("Expected the Go files, got %v", got)
+	}
+	got = Formatter{Name: "gofmt", Paths: []string{"cmd/**"}}.Matches(files)
+	if strings.Join(got, ",") != "cmd/root.go" {
+		t.Errorf("Expected the configured paths to override the preset, got %v", got)
+	}
+}
+
+func TestRunFormatters(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, file := range []string{"main.go", "README.md"} {
+		if err := os.WriteFile(file, []byte("content\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := RunFormatters([]Formatter{
+		{Name: "dirty", Command: "echo checked {{.files}}; exit 1", Paths: []string{"**/*.go"}},
+		{Name: "clean", Command: "true {{.files}}", Paths: []string{"**/*.md"}},
+		{Name: "missing", Command: "not-installed-formatter {{.files}}", Paths: []string{"**/*.go"}},
+		{Name: "unmatched", Command: "false {{.files}}", Paths: []string{"**/*.swift"}},
+	}, formatterDiff)
+
+	if len(results) != 2 {
+		t.Fatalf("Expected the results of the dirty and clean formatters, got %+v", results)
+	}
+	if results[0].Name != "dirty" || results[0].Output != "checked main.go" {
+		t.Errorf("Expected only the existing Go file to be checked, got %+v", results[0])
+	}
+	if results[1].Name != "clean" || results[1].Output != "" {
+		t.Errorf("Expected no output of the clean formatter, got %+v", results[1])
+	}
+	if unformatted := Unformatted(results); len(unformatted) != 1 || unformatted[0].Name != "dirty" {
+		t.Errorf("Expected only the dirty formatter to be unformatted, got %+v", unformatted)
+	}
+}
+
+func TestFormatFormattingComment(t *testing.T) {
+	patch := "--- main.go.orig\n+++ main.go\n@@ -1,1 +1,1 @@\n-package  main\n+package main"
+	comment := FormatFormattingComment("github", []FormatterResult{
+		{Name: "gofmt", Files: []string{"main.go"}, Output: patch},
+		{Name: "prettier", Files: []string{"a.ts", "b.ts"}, Output: "[warn] a.ts"},
+		{Name: "swiftformat", Files: []string{"View.swift"}},
+		{Name: "ktlint", Files: []string{"Main.kt"}, Output: "1 style violation"},
+	})
+
+	for _, expected := range []string{
+		FormattingHeader,
+		"**gofmt**: 1 files, 2 changed lines: `main.go`",
+		"```diff\n" + patch,
+		"**prettier**: 1 files are not formatted: `a.ts`",
+		"**ktlint**: 1 files checked, see the output",
+	} {
+		if !strings.Contains(comment, expected) {
+			t.Errorf("Expected the comment to contain %q, got:\n%s", expected, comment)
+		}
+	}
+	if strings.Contains(comment, "swiftformat") {
+		t.Errorf("Expected no section for the formatted files, got:\n%s", comment)
+	}
+
+	clean := FormatFormattingComment("github", nil)
+	if !strings.Contains(clean, "The changed files are formatted") {
+		t.Errorf("Expected the clean note, got:\n%s", clean)
+	}
+}
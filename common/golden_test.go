@@ -0,0 +1,156 @@
+package common
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// updateGolden rewrites the golden files with the current output, run `go test ./common/ ./review/ -update` after intended formatting changes
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// assertGolden compares the output with the golden file of the test in testdata/golden
+func assertGolden(t *testing.T, name, output string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".md")
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s, run the tests with -update to create it: %v", path, err)
+	}
+	if output != string(expected) {
+		t.Errorf("Output differs from %s, run the tests with -update if the change is intended.\nExpected:\n%s\nGot:\n%s", path, expected, output)
+	}
+}
+
+func goldenSummary() Summary {
+	return Summary{
+		Summary: "Adds retries with exponential backoff to the upload client.",
+		Walkthrough: []Walkthrough{
+			{Files: "uploader/client.go, uploader/retry.go", Summary: "Wraps the uploads in a retry loop 🔁"},
+			{Files: "uploader/client_test.go", Summary: "Covers the retry limits"},
+		},
+		Celebration:     "Uploads that fail\nnow rise again, patient\nbackoff saves the day",
+		MergeConfidence: "✅ High: only patch releases",
+		Performance:     "The retry loop doesn't touch the hot paths.",
+		FeatureFlags:    "- `upload-retries` is introduced",
+		Stats:           SummaryStats{Findings: 2},
+		Permalinks:      Permalinks{RepoURL: "https://github.com/bitrise-io/uploader", CommitHash: "abc123"},
+		Author:          "octocat",
+	}
+}
+
+func TestSummaryGolden(t *testing.T) {
+	defer SetStyle(StyleRich)
+
+	german := goldenSummary()
+	german.Summary = "Fügt Wiederholungen mit exponentiellem Backoff hinzu 🚀"
+	german.Walkthrough[0].Summary = "Umschließt die Uploads mit einer Wiederholungsschleife"
+
+	secretsFree := WithDefaultSettings()
+	secretsFree.SecretsFree = true
+	expanded := WithDefaultSettings()
+	expanded.Reviews.CollapseWalkthrough = false
+	germanSettings := WithDefaultSettings()
+	germanSettings.Language = "de-DE"
+	noCelebration := WithDefaultSettings()
+	noCelebration.Reviews.Celebration = Celebration{Type: CelebrationNone}
+
+	// The test changes are listed first by the LLM, the ranking moves them last
+	ranked := goldenSummary()
+	ranked.Walkthrough = []Walkthrough{ranked.Walkthrough[1], ranked.Walkthrough[0], {Files: "README.md", Summary: "Documents the retries"}}
+	ranked.Impacts = map[string]FileImpact{
+		"uploader/client.go":      {Score: 3010, Level: ImpactHigh, Reason: "public API"},
+		"uploader/retry.go":       {Score: 2040, Level: ImpactMedium},
+		"uploader/client_test.go": {Score: 60, Level: ImpactLow, Reason: "tests"},
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		style    string
+		summary  Summary
+		settings Settings
+	}{
+		{"summary_github", ProviderGitHub, StyleRich, goldenSummary(), WithDefaultSettings()},
+		{"summary_github_expanded", ProviderGitHub, StyleRich, goldenSummary(), expanded},
+		{"summary_github_ranked", ProviderGitHub, StyleRich, ranked, expanded},
+		{"summary_github_plain", ProviderGitHub, StylePlain, goldenSummary(), WithDefaultSettings()},
+		{"summary_github_secrets_free", ProviderGitHub, StyleRich, goldenSummary(), secretsFree},
+		{"summary_github_german_plain", ProviderGitHub, StylePlain, german, germanSettings},
+		{"summary_bitbucket", ProviderBitbucket, StyleRich, goldenSummary(), WithDefaultSettings()},
+		{"summary_bitbucket_no_celebration", ProviderBitbucket, StyleRich, goldenSummary(), noCelebration},
+		{"summary_gitlab", ProviderGitLab, StyleRich, goldenSummary(), WithDefaultSettings()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetStyle(test.style)
+			assertGolden(t, test.name, test.summary.String(test.provider, test.settings))
+		})
+	}
+}
+
+func TestLineLevelGolden(t *testing.T) {
+	defer SetStyle(StyleRich)
+
+	bug := LineLevel{
+		File:           "uploader/client.go",
+		Line:           "\tfor i := 0; i <= maxRetries; i++ {",
+		Category:       CategoryBug,
+		Severity:       SeverityHigh,
+		LineNumber:     42,
+		LastLineNumber: 42,
+		Title:          "Off-by-one retry limit",
+		Body:           "The loop runs `maxRetries + 1` times.",
+		Prompt:         "Change the loop condition to i < maxRetries so the upload is retried at most maxRetries times",
+		Suggestion:     "\tfor i := 0; i < maxRetries; i++ {",
+		RelatedLocations: []FindingLocation{
+			{File: "uploader/download.go", LineNumber: 17},
+		},
+	}
+	nitpick := LineLevel{
+		File:       "uploader/retry.go",
+		Line:       "const backoff_base = 2",
+		Category:   CategoryNitpick,
+		LineNumber: 5,
+		Title:      "Naming",
+		Body:       "Go constants use mixedCaps.",
+		Suggestion: "const backoffBase = 2",
+	}
+	fileLevel := LineLevel{
+		File:     "uploader/client.go",
+		Category: CategoryRefactor,
+		Scope:    ScopeFile,
+		Title:    "Split the client",
+		Body:     "The file mixes the HTTP client and the retry policy.",
+		Prompt:   "Move the retry policy into retry.go",
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		style    string
+		line     LineLevel
+	}{
+		{"line_github_bug", ProviderGitHub, StyleRich, bug},
+		{"line_github_bug_plain", ProviderGitHub, StylePlain, bug},
+		{"line_github_nitpick", ProviderGitHub, StyleRich, nitpick},
+		{"line_github_file", ProviderGitHub, StyleRich, fileLevel},
+		{"line_bitbucket_bug", ProviderBitbucket, StyleRich, bug},
+		{"line_bitbucket_nitpick_plain", ProviderBitbucket, StylePlain, nitpick},
+		{"line_gitlab_bug", ProviderGitLab, StyleRich, bug},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetStyle(test.style)
+			assertGolden(t, test.name, test.line.String(test.provider, nil, "abc123"))
+		})
+	}
+}
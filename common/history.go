@@ -0,0 +1,115 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxHistoryResults limits the pull requests and the reverted commits of a history search
+const MaxHistoryResults = 10
+
+// HistoryPullRequest is a merged pull request matching the keywords of a history search
+type HistoryPullRequest struct {
+	Number      int
+	Title       string
+	Description string
+	URL         string
+	MergedAt    time.Time
+}
+
+// RevertedCommit is a revert commit of the history matching the keywords of a history search
+type RevertedCommit struct {
+	Hash    string
+	Date    string
+	Subject string
+	Body    string
+}
+
+var (
+	// historyCache keeps the results of the history searches of the run, the search APIs of the providers have strict rate limits
+	historyCache   = map[string]string{}
+	historyCacheMu sync.Mutex
+)
+
+// CachedHistorySearch returns the result of the history search of the keywords, running it only once per run
+func CachedHistorySearch(keywords []string, search func() (string, error)) (string, error) {
+	key := strings.ToLower(strings.Join(keywords, "\n"))
+
+	historyCacheMu.Lock()
+	result, ok := historyCache[key]
+	historyCacheMu.Unlock()
+	if ok {
+		return result, nil
+	}
+
+	result, err := search()
+	if err != nil {
+		return "", err
+	}
+
+	historyCacheMu.Lock()
+	historyCache[key] = result
+	historyCacheMu.Unlock()
+	return result, nil
+}
+
+// ParseRevertedCommits parses the commits of the git log searched with git.Client.SearchCommitMessages
+func ParseRevertedCommits(output string) []RevertedCommit {
+	var commits []RevertedCommit
+	for record := range strings.SplitSeq(output, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, RevertedCommit{
+			Hash:    fields[0],
+			Date:    fields[1],
+			Subject: fields[2],
+			Body:    strings.TrimSpace(fields[3]),
+		})
+	}
+	return commits
+}
+
+// FormatHistory lists the matching merged pull requests and reverted commits for the LLM
+func FormatHistory(pullRequests []HistoryPullRequest, reverts []RevertedCommit) string {
+	if len(pullRequests) == 0 && len(reverts) == 0 {
+		return "No merged pull requests or reverted commits match the keywords."
+	}
+
+	var builder strings.Builder
+	if len(reverts) > 0 {
+		builder.WriteString("Reverted commits:\n")
+		for _, commit := range reverts {
+			builder.WriteString(fmt.Sprintf("- %s (%s) %s\n", commit.Hash, commit.Date, commit.Subject))
+			if commit.Body != "" {
+				builder.WriteString("  " + truncateHistoryText(commit.Body) + "\n")
+			}
+		}
+	}
+	if len(pullRequests) > 0 {
+		if len(reverts) > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString("Merged pull requests:\n")
+		for _, pr := range pullRequests {
+			builder.WriteString(fmt.Sprintf("- #%d %s, merged %s: %s\n", pr.Number, pr.Title, FormatTimestamp(pr.MergedAt), pr.URL))
+			if pr.Description != "" {
+				builder.WriteString("  " + truncateHistoryText(pr.Description) + "\n")
+			}
+		}
+	}
+	return builder.String()
+}
+
+// truncateHistoryText keeps the beginning of a description on a single line
+func truncateHistoryText(text string) string {
+	const maxLength = 300
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > maxLength {
+		return string(runes[:maxLength]) + "..."
+	}
+	return string(runes)
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ForcePush is a force push of the head branch of the pull request, reported by the provider
+type ForcePush struct {
+	At time.Time
+}
+
+// ForcePushedSince checks if the head branch was force pushed after the time, like the time of the previous review.
+// The time is unknown for summaries posted by older versions, any force push counts then.
+func ForcePushedSince(forcePushes []ForcePush, since time.Time) bool {
+	for _, forcePush := range forcePushes {
+		if since.IsZero() || forcePush.At.After(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommentedLine returns the commented line of the diff hunk of a posted comment, without the prefix of the diff line.
+// The providers end the hunk with the commented line, or the last line of a multiline comment.
+func CommentedLine(diffHunk string) string {
+	lines := strings.Split(strings.TrimRight(diffHunk, "\n"), "\n")
+	last := lines[len(lines)-1]
+	if strings.HasPrefix(last, "@@") {
+		return ""
+	}
+	if len(last) > 0 && strings.ContainsRune("+- ", rune(last[0])) {
+		last = last[1:]
+	}
+	return last
+}
+
+// RelocateLine returns the line number of the anchor line in the content, 0 if it is not found.
+// Lines are compared ignoring the indentation, the nearest one to the previous line number wins if the anchor repeats.
+func RelocateLine(content, anchor string, previousLine int) int {
+	anchor = strings.TrimSpace(anchor)
+	if anchor == "" {
+		return 0
+	}
+
+	found := 0
+	for idx, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != anchor {
+			continue
+		}
+		lineNumber := idx + 1
+		if found == 0 || absInt(lineNumber-previousLine) < absInt(found-previousLine) {
+			found = lineNumber
+		}
+	}
+	return found
+}
+
+// MoveCommentHeader updates the location of the header of a posted line comment, keeping its file and fingerprint.
+// Returns the body unchanged if it has no line comment header.
+func MoveCommentHeader(body string, lineNumber, lastLineNumber int, blame string) string {
+	header, rest, _ := strings.Cut(body, "\n")
+	parts := strings.Split(header, ":")
+	if !strings.HasPrefix(header, commentPrefix) || len(parts) < 4 {
+		return body
+	}
+
+	location := fmt.Sprintf("%d", lineNumber)
+	if lastLineNumber > lineNumber {
+		location = fmt.Sprintf("%d-%d", lineNumber, lastLineNumber)
+	}
+	parts[2] = location
+	parts[3] = blame
+	if rest == "" {
+		return strings.Join(parts, ":")
+	}
+	return strings.Join(parts, ":") + "\n" + rest
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
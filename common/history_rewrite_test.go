@@ -0,0 +1,70 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForcePushedSince(t *testing.T) {
+	reviewedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	forcePushes := []ForcePush{{At: reviewedAt.Add(-time.Hour)}}
+
+	if ForcePushedSince(forcePushes, reviewedAt) {
+		t.Error("Expected the force push before the review to be ignored")
+	}
+	if !ForcePushedSince(append(forcePushes, ForcePush{At: reviewedAt.Add(time.Minute)}), reviewedAt) {
+		t.Error("Expected the force push after the review to be detected")
+	}
+	if !ForcePushedSince(forcePushes, time.Time{}) {
+		t.Error("Expected any force push to count without the time of the review")
+	}
+	if ForcePushedSince(nil, time.Time{}) {
+		t.Error("Expected no force push without events")
+	}
+}
+
+func TestCommentedLine(t *testing.T) {
+	tests := map[string]string{
+		"@@ -1,3 +1,4 @@\n func main() {\n+\tfmt.Println(\"hi\")\n": "\tfmt.Println(\"hi\")",
+		"@@ -10,2 +10,2 @@\n-\treturn nil\n":                        "\treturn nil",
+		"@@ -1 +1 @@":                                               "",
+	}
+	for hunk, expected := range tests {
+		if line := CommentedLine(hunk); line != expected {
+			t.Errorf("Expected %q for the hunk %q, got %q", expected, hunk, line)
+		}
+	}
+}
+
+func TestRelocateLine(t *testing.T) {
+	content := "package main\n\nfunc a() {\n\treturn nil\n}\n\nfunc b() {\n\treturn nil\n}\n"
+
+	if line := RelocateLine(content, "return nil", 7); line != 8 {
+		t.Errorf("Expected the nearest occurrence at line 8, got %d", line)
+	}
+	if line := RelocateLine(content, "  return nil", 2); line != 4 {
+		t.Errorf("Expected the nearest occurrence at line 4 ignoring the indentation, got %d", line)
+	}
+	if line := RelocateLine(content, "return err", 4); line != 0 {
+		t.Errorf("Expected no line for changed code, got %d", line)
+	}
+	if line := RelocateLine(content, "", 4); line != 0 {
+		t.Errorf("Expected no line for an empty anchor, got %d", line)
+	}
+}
+
+func TestMoveCommentHeader(t *testing.T) {
+	body := "[bitrise-plugin-ai-reviewer]: main.go:4:abc1234:0123456789ab\n**Bug**\n\nReturns nil."
+
+	moved := MoveCommentHeader(body, 8, 8, "def5678")
+	if expected := "[bitrise-plugin-ai-reviewer]: main.go:8:def5678:0123456789ab\n**Bug**\n\nReturns nil."; moved != expected {
+		t.Errorf("Unexpected moved comment:\n%s", moved)
+	}
+	if moved := MoveCommentHeader(body, 8, 10, "def5678"); !strings.HasPrefix(moved, "[bitrise-plugin-ai-reviewer]: main.go:8-10:def5678:0123456789ab\n") {
+		t.Errorf("Unexpected header of the moved multiline comment:\n%s", moved)
+	}
+	if moved := MoveCommentHeader("Some reply", 8, 8, "def5678"); moved != "Some reply" {
+		t.Errorf("Expected the comment without a header unchanged, got %s", moved)
+	}
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseRevertedCommits(t *testing.T) {
+	output := "67f8ef7\x1f2026-10-16\x1fRevert \"Add response cache\"\x1fThis reverts commit abc.\n\nThe cache served stale data.\n\x1e\n" +
+		"12ab34c\x1f2026-09-01\x1fRevert retries\x1f\x1e"
+
+	commits := ParseRevertedCommits(output)
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+	expected := RevertedCommit{Hash: "67f8ef7", Date: "2026-10-16", Subject: `Revert "Add response cache"`, Body: "This reverts commit abc.\n\nThe cache served stale data."}
+	if commits[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, commits[0])
+	}
+	if commits[1].Body != "" {
+		t.Errorf("Expected no body, got %q", commits[1].Body)
+	}
+}
+
+func TestFormatHistory(t *testing.T) {
+	if history := FormatHistory(nil, nil); !strings.HasPrefix(history, "No merged pull requests") {
+		t.Errorf("Expected no matches, got: %s", history)
+	}
+
+	history := FormatHistory(
+		[]HistoryPullRequest{{Number: 42, Title: "Add response cache", Description: "Caches the\nresponses", URL: "https://github.com/o/r/pull/42"}},
+		[]RevertedCommit{{Hash: "67f8ef7", Date: "2026-10-16", Subject: `Revert "Add response cache"`, Body: "The cache served stale data."}},
+	)
+	for _, expected := range []string{
+		"- 67f8ef7 (2026-10-16) Revert \"Add response cache\"\n  The cache served stale data.\n",
+		"- #42 Add response cache, merged unknown: https://github.com/o/r/pull/42\n  Caches the responses\n",
+	} {
+		if !strings.Contains(history, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, history)
+		}
+	}
+}
+
+func TestCachedHistorySearch(t *testing.T) {
+	calls := 0
+	search := func() (string, error) {
+		calls++
+		return "result", nil
+	}
+
+	for _, keywords := range [][]string{{"Response Cache"}, {"response cache"}} {
+		if result, err := CachedHistorySearch(keywords, search); err != nil || result != "result" {
+			t.Errorf("Expected the result, got %q (%v)", result, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single search, got %d", calls)
+	}
+
+	// Failed searches are not cached
+	failing := func() (string, error) { return "", errors.New("rate limited") }
+	if _, err := CachedHistorySearch([]string{"retries"}, failing); err == nil {
+		t.Error("Expected the error of the search")
+	}
+	if result, _ := CachedHistorySearch([]string{"retries"}, search); result != "result" {
+		t.Errorf("Expected the search to run again, got %q", result)
+	}
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HotPathMatch is a changed file touching a hot path configured in the settings
+type HotPathMatch struct {
+	File    string
+	Pattern string // The path glob or symbol matching the change
+}
+
+// MatchHotPaths returns the changes of the diff touching the hot paths, matched by path glob or by symbol name
+func MatchHotPaths(diff string, hotPaths HotPaths) []HotPathMatch {
+	if len(hotPaths.Paths) == 0 && len(hotPaths.Symbols) == 0 {
+		return nil
+	}
+
+	symbolRegexes := make([]*regexp.Regexp, len(hotPaths.Symbols))
+	for i, symbol := range hotPaths.Symbols {
+		symbolRegexes[i] = regexp.MustCompile(`\b` + regexp.QuoteMeta(symbol) + `\b`)
+	}
+
+	var matches []HotPathMatch
+	seen := map[string]bool{}
+	add := func(file, pattern string) {
+		if key := file + ":" + pattern; !seen[key] {
+			seen[key] = true
+			matches = append(matches, HotPathMatch{File: file, Pattern: pattern})
+		}
+	}
+
+	file := ""
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			for _, pattern := range hotPaths.Paths {
+				if MatchGlob(pattern, file) {
+					add(file, pattern)
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "--- ") {
+			continue
+		}
+
+		// Symbols are matched in the changed lines and in the enclosing declaration of the hunk header
+		if !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "@@") {
+			continue
+		}
+		for i, regex := range symbolRegexes {
+			if regex.MatchString(line) {
+				add(file, hotPaths.Symbols[i])
+			}
+		}
+	}
+
+	return matches
+}
+
+// MatchGlob matches a slash separated path against a glob pattern.
+// Besides the filepath.Match syntax '**' matches any number of directories.
+func MatchGlob(pattern, path string) bool {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// '**/' also matches no directory at all
+					i++
+					builder.WriteString("(?:.*/)?")
+				} else {
+					builder.WriteString(".*")
+				}
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+
+	matched, err := regexp.MatchString(builder.String(), path)
+	return err == nil && matched
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"internal/render/**", "internal/render/frame/frame.go", true},
+		{"internal/render/*.go", "internal/render/frame.go", true},
+		{"internal/render/*.go", "internal/render/frame/frame.go", false},
+		{"**/cache.go", "cache.go", true},
+		{"**/cache.go", "pkg/store/cache.go", true},
+		{"pkg/?.go", "pkg/a.go", true},
+		{"pkg/a.go", "pkg/a_go", false},
+	}
+	for _, tt := range tests {
+		if matched := MatchGlob(tt.pattern, tt.path); matched != tt.expected {
+			t.Errorf("MatchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.path, matched, tt.expected)
+		}
+	}
+}
+
+func TestMatchHotPaths(t *testing.T) {
+	diff := `--- a/internal/render/frame.go
++++ b/internal/render/frame.go
+@@ -1 +1,2 @@
++var x = 1
+--- a/api/handler.go
++++ b/api/handler.go
+@@ -10,3 +10,4 @@ func ServeFeed(w http.ResponseWriter, r *http.Request) {
++	items := loadItems()
+--- a/api/other.go
++++ b/api/other.go
+@@ -1 +1,2 @@
++// ServeFeedback is unrelated
+`
+
+	matches := MatchHotPaths(diff, HotPaths{Paths: []string{"internal/render/**"}, Symbols: []string{"ServeFeed"}})
+	expected := []HotPathMatch{
+		{File: "internal/render/frame.go", Pattern: "internal/render/**"},
+		{File: "api/handler.go", Pattern: "ServeFeed"},
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, matches)
+	}
+
+	if matches := MatchHotPaths(diff, HotPaths{}); matches != nil {
+		t.Errorf("Expected no matches without hot paths, got %+v", matches)
+	}
+}
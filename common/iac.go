@@ -0,0 +1,86 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IaC tools detected in the changes
+const (
+	IaCTerraform  = "terraform"
+	IaCKubernetes = "kubernetes"
+	IaCHelm       = "helm"
+)
+
+// IaCChanges lists the changed infrastructure-as-code files by tool
+type IaCChanges map[string][]string
+
+// DetectIaCChanges returns the Terraform, Kubernetes and Helm files changed in the diff
+func DetectIaCChanges(diff string) IaCChanges {
+	changes := IaCChanges{}
+
+	file := ""
+	detected := map[string]bool{}
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if tool := iacToolByPath(file); tool != "" {
+				changes.add(tool, file)
+				detected[file] = true
+			}
+			continue
+		}
+		if detected[file] || file == "/dev/null" {
+			continue
+		}
+
+		// Kubernetes manifests are plain YAML files, recognized by their content
+		ext := filepath.Ext(file)
+		if (ext == ".yml" || ext == ".yaml") && len(line) > 0 && (line[0] == '+' || line[0] == ' ') &&
+			strings.HasPrefix(strings.TrimSpace(line[1:]), "apiVersion:") {
+			changes.add(IaCKubernetes, file)
+			detected[file] = true
+		}
+	}
+
+	return changes
+}
+
+// iacToolByPath detects Terraform and Helm files by their path
+func iacToolByPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".tf"), strings.HasSuffix(path, ".tfvars"), strings.HasSuffix(path, ".tf.json"):
+		return IaCTerraform
+	case filepath.Base(path) == "Chart.yaml", filepath.Base(path) == "values.yaml",
+		isHelmTemplate(path):
+		return IaCHelm
+	}
+	return ""
+}
+
+// isHelmTemplate returns true for YAML and helper files in the templates directory of a chart
+func isHelmTemplate(path string) bool {
+	if !strings.HasPrefix(path, "templates/") && !strings.Contains(path, "/templates/") {
+		return false
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".tpl":
+		return true
+	}
+	return false
+}
+
+func (c IaCChanges) add(tool, file string) {
+	c[tool] = append(c[tool], file)
+}
+
+// Tools returns the detected IaC tools in a stable order
+func (c IaCChanges) Tools() []string {
+	var tools []string
+	for _, tool := range []string{IaCTerraform, IaCKubernetes, IaCHelm} {
+		if len(c[tool]) > 0 {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectIaCChanges(t *testing.T) {
+	diff := `diff --git a/infra/main.tf b/infra/main.tf
+--- a/infra/main.tf
++++ b/infra/main.tf
+@@ -1 +1,2 @@
++resource "aws_s3_bucket" "assets" {}
+diff --git a/deploy/app.yaml b/deploy/app.yaml
+--- a/deploy/app.yaml
++++ b/deploy/app.yaml
+@@ -1,2 +1,3 @@
+ apiVersion: apps/v1
+ kind: Deployment
++  replicas: 2
+diff --git a/charts/app/templates/service.yaml b/charts/app/templates/service.yaml
+--- a/charts/app/templates/service.yaml
++++ b/charts/app/templates/service.yaml
+@@ -1 +1,2 @@
++apiVersion: v1
+diff --git a/review.bitrise.yml b/review.bitrise.yml
+--- a/review.bitrise.yml
++++ b/review.bitrise.yml
+@@ -1 +1,2 @@
++language: en-US
+`
+
+	changes := DetectIaCChanges(diff)
+	expected := IaCChanges{
+		IaCTerraform:  {"infra/main.tf"},
+		IaCKubernetes: {"deploy/app.yaml"},
+		IaCHelm:       {"charts/app/templates/service.yaml"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+	if !reflect.DeepEqual(changes.Tools(), []string{IaCTerraform, IaCKubernetes, IaCHelm}) {
+		t.Errorf("Unexpected tools order: %v", changes.Tools())
+	}
+}
+
+func TestRunCommandRejectsUnsafeInput(t *testing.T) {
+	if _, err := RunCommand("rm -rf", "."); err == nil {
+		t.Error("Expected commands outside of the whitelist to be rejected")
+	}
+
+	for _, path := range []string{"/etc", "../other-repo", "infra/../../other-repo"} {
+		if _, err := RunCommand("kubeval", path); err == nil {
+			t.Errorf("Expected path %s outside of the repository to be rejected", path)
+		}
+	}
+
+	if _, err := RunCommand("kubeval", "--version"); err == nil || !strings.Contains(err.Error(), "cannot start with -") {
+		t.Errorf("Expected a path looking like a flag to be rejected, got %v", err)
+	}
+
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Join("infra", ".terraform", "providers"), 0755); err != nil {
+		t.Fatalf("Failed to create the .terraform directory: %v", err)
+	}
+	if _, err := RunCommand("terraform validate", "infra"); err == nil || !strings.Contains(err.Error(), ".terraform directory") {
+		t.Errorf("Expected a module with a committed .terraform directory to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/version"
+)
+
+// DefaultIdentityName is the name of the reviewer in the posted comments if not configured
+const DefaultIdentityName = "Bitrise AI"
+
+// Identity is how the reviewer presents itself in the posted comments.
+// The author of the comments is the account of the token, like the bot user of a GitHub App,
+// its name and avatar can only be changed on the provider.
+type Identity struct {
+	Name      string `yaml:"name"`
+	AvatarURL string `yaml:"avatar_url"`
+	Signature string `yaml:"signature"` // Markdown appended to every comment
+	Footer    bool   `yaml:"footer"`    // Adds the version of the plugin and the model to every comment, for auditability
+}
+
+// GetName returns the name of the reviewer, defaults to DefaultIdentityName
+func (i Identity) GetName() string {
+	if name := strings.TrimSpace(i.Name); name != "" {
+		return name
+	}
+	return DefaultIdentityName
+}
+
+// identity is the identity of the reviewer used by the run
+var identity Identity
+
+// reviewModel is the configured model of the review
+var reviewModel string
+
+// SetIdentity sets the identity of the reviewer used by the run
+func SetIdentity(i Identity) {
+	identity = i
+}
+
+// SetReviewModel records the configured model of the review, shown in the footer of the comments
+func SetReviewModel(model string) {
+	reviewModel = model
+}
+
+// commentFooter returns the signature and the footer of the identity appended to the posted comments
+func commentFooter(renderer MarkdownRenderer) string {
+	var parts []string
+	if signature := strings.TrimSpace(identity.Signature); signature != "" {
+		parts = append(parts, signature)
+	}
+
+	if identity.Footer {
+		footer := fmt.Sprintf("Reviewed by %s v%s", identity.GetName(), version.Version)
+		// The fallback model produced the review if the configured one failed
+		model := reviewModel
+		if fallbackModel != "" {
+			model = fallbackModel
+		}
+		if model != "" {
+			footer += " with model " + model
+		}
+		footer = "_" + footer + "_"
+		// Sized images need HTML, which the providers without collapsible sections don't render
+		if identity.AvatarURL != "" && renderer.Capabilities().Collapsible {
+			footer = fmt.Sprintf(`<img src="%s" alt="" width="16" height="16"> %s`, identity.AvatarURL, footer)
+		}
+		parts = append(parts, footer)
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return "\n\n---\n" + strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/version"
+)
+
+func TestCommentFooter(t *testing.T) {
+	defer SetIdentity(Identity{})
+	defer SetReviewModel("")
+
+	SetIdentity(Identity{})
+	if footer := commentFooter(NewMarkdownRenderer(ProviderGitHub)); footer != "" {
+		t.Errorf("Expected no footer by default, got %q", footer)
+	}
+
+	SetIdentity(Identity{Name: "Bit Bot", AvatarURL: "https://example.com/bot.png", Signature: "See our [guidelines](https://example.com)", Footer: true})
+	SetReviewModel("gpt-4.1")
+
+	expected := "\n\n---\nSee our [guidelines](https://example.com)\n\n" +
+		`<img src="https://example.com/bot.png" alt="" width="16" height="16"> ` +
+		"_Reviewed by Bit Bot v" + version.Version + " with model gpt-4.1_"
+	if footer := commentFooter(NewMarkdownRenderer(ProviderGitHub)); footer != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, footer)
+	}
+
+	// Bitbucket doesn't render the sized avatar image
+	if footer := commentFooter(NewMarkdownRenderer(ProviderBitbucket)); strings.Contains(footer, "<img") {
+		t.Errorf("Expected no avatar on Bitbucket, got:\n%s", footer)
+	}
+
+	SetFallbackModel("gpt-4.1-mini")
+	defer SetFallbackModel("")
+	if footer := commentFooter(NewMarkdownRenderer(ProviderGitHub)); !strings.Contains(footer, "with model gpt-4.1-mini_") {
+		t.Errorf("Expected the fallback model in the footer, got:\n%s", footer)
+	}
+}
+
+func TestIdentityInComments(t *testing.T) {
+	defer SetIdentity(Identity{})
+
+	SetIdentity(Identity{Name: "Bit Bot", Signature: "— the platform team"})
+
+	if initiated := (Summary{}).InitiatedString(ProviderGitHub); !strings.Contains(initiated, "Bit Bot is reviewing the PR") {
+		t.Errorf("Expected the name in the under review note, got:\n%s", initiated)
+	}
+
+	output := LineLevel{File: "main.go", LineNumber: 3, Body: "Missing error check"}.String(ProviderGitHub, nil, "abc123")
+	if !strings.HasSuffix(output, "Missing error check\n\n---\n— the platform team") {
+		t.Errorf("Expected the signature at the end of the comment, got:\n%s", output)
+	}
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+)
+
+// Impact levels of a changed file
+const (
+	ImpactHigh   = "High"
+	ImpactMedium = "Medium"
+	ImpactLow    = "Low"
+)
+
+// largeChangeLines is the number of changed lines making a source file change high impact
+const largeChangeLines = 100
+
+// FileImpact is the estimated impact of the changes of a file, used to rank the walkthrough
+type FileImpact struct {
+	Score  int    // Higher scores are listed first
+	Level  string // High, Medium or Low
+	Reason string // Why the file got its level, empty for regular source changes
+}
+
+// String formats the impact for the walkthrough table
+func (i FileImpact) String() string {
+	if i.Reason == "" {
+		return i.Level
+	}
+	return i.Level + " (" + i.Reason + ")"
+}
+
+// publicAPIPatterns match the declarations of exported symbols in the supported languages
+var publicAPIPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(func|type|var|const)\s+[A-Z]`),                                // Go
+	regexp.MustCompile(`^func\s+\([^)]*\)\s+[A-Z]`),                                     // Go methods
+	regexp.MustCompile(`^\s*(@\w+\s+)*(public|open)\s`),                                 // Swift, Kotlin, Java, C#
+	regexp.MustCompile(`^export\s`),                                                     // TypeScript, JavaScript
+	regexp.MustCompile(`^(async\s+)?(def|class)\s+[A-Za-z]`),                            // Python, top-level and not private
+	regexp.MustCompile(`^\s*pub(\([^)]*\))?\s+(fn|struct|enum|trait|mod|type|const)\s`), // Rust
+}
+
+// File kinds by their paths, tests and documentation have the lowest impact
+var (
+	testDirs      = []string{"test/", "tests/", "__tests__/", "spec/", "androidTest/", "UITests/"}
+	testSuffixes  = []string{"_test.go", "Test.java", "Test.kt", "Tests.swift", "Test.swift", "_spec.rb", "Tests.cs"}
+	docExtensions = []string{".md", ".rst", ".txt", ".adoc"}
+	configFiles   = []string{".yml", ".yaml", ".json", ".toml", ".xml", ".gradle", ".plist", ".lock", ".mod", ".sum", ".properties"}
+)
+
+// AnalyzeImpact estimates the impact of the changes of each file in the diff, by the kind of the file,
+// changes to exported symbols and the size of the change
+func AnalyzeImpact(diff string) map[string]FileImpact {
+	impacts := map[string]FileImpact{}
+	for _, file := range diffparse.Parse(diff) {
+		changed := 0
+		publicAPI := false
+		for _, hunk := range file.Hunks {
+			for _, line := range hunk.Lines {
+				if line.Kind == diffparse.LineContext {
+					continue
+				}
+				changed++
+				publicAPI = publicAPI || isPublicDeclaration(line.Content)
+			}
+		}
+
+		filePath := file.Path()
+		var impact FileImpact
+		switch {
+		case isTestFile(filePath):
+			impact = FileImpact{Score: changed, Level: ImpactLow, Reason: "tests"}
+		case hasExtension(filePath, docExtensions):
+			impact = FileImpact{Score: 500 + changed, Level: ImpactLow, Reason: "documentation"}
+		case strings.ToLower(path.Ext(filePath)) == ".proto" || (publicAPI && !hasExtension(filePath, configFiles)):
+			impact = FileImpact{Score: 3000 + changed, Level: ImpactHigh, Reason: "public API"}
+		case hasExtension(filePath, configFiles):
+			impact = FileImpact{Score: 1000 + changed, Level: ImpactMedium, Reason: "configuration"}
+		case changed >= largeChangeLines:
+			impact = FileImpact{Score: 2000 + changed, Level: ImpactHigh, Reason: "large change"}
+		default:
+			impact = FileImpact{Score: 2000 + changed, Level: ImpactMedium}
+		}
+		impacts[filePath] = impact
+	}
+	return impacts
+}
+
+// rankWalkthrough orders the walkthrough by the impact of its files, highest first, keeping the order of the LLM for equal impacts.
+// Rows listing multiple files get the impact of their most impactful file, rows of unknown files are listed last.
+func rankWalkthrough(walkthrough []Walkthrough, impacts map[string]FileImpact) []Walkthrough {
+	if len(impacts) == 0 {
+		return walkthrough
+	}
+
+	ranked := make([]Walkthrough, len(walkthrough))
+	scores := make([]int, len(walkthrough))
+	for idx, row := range walkthrough {
+		ranked[idx] = row
+		scores[idx] = -1
+		for _, file := range strings.Split(row.Files, ",") {
+			impact, ok := impacts[strings.TrimSpace(file)]
+			if ok && impact.Score > scores[idx] {
+				scores[idx] = impact.Score
+				ranked[idx].Impact = impact.String()
+			}
+		}
+	}
+
+	order := make([]int, len(ranked))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	sorted := make([]Walkthrough, len(ranked))
+	for idx, original := range order {
+		sorted[idx] = ranked[original]
+	}
+	return sorted
+}
+
+// isPublicDeclaration checks if the changed line declares an exported symbol
+func isPublicDeclaration(line string) bool {
+	for _, pattern := range publicAPIPatterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTestFile checks if the file is a test by the naming conventions of the supported languages
+func isTestFile(filePath string) bool {
+	for _, dir := range testDirs {
+		if strings.HasPrefix(filePath, dir) || strings.Contains(filePath, "/"+dir) {
+			return true
+		}
+	}
+	name := path.Base(filePath)
+	for _, suffix := range testSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(name, "test_") || strings.Contains(name, ".test.") || strings.Contains(name, ".spec.")
+}
+
+// hasExtension checks if the file has one of the extensions
+func hasExtension(filePath string, extensions []string) bool {
+	ext := strings.ToLower(path.Ext(filePath))
+	for _, extension := range extensions {
+		if ext == extension {
+			return true
+		}
+	}
+	return false
+}
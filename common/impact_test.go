@@ -0,0 +1,98 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+const impactDiff = `diff --git a/api/client.go b/api/client.go
+--- a/api/client.go
++++ b/api/client.go
+@@ -1,2 +1,3 @@
+ package api
++func NewClient() *Client { return &Client{} }
+ type client struct{}
+diff --git a/api/internal.go b/api/internal.go
+--- a/api/internal.go
++++ b/api/internal.go
+@@ -1 +1 @@
+-func parse() {}
++func parse() error { return nil }
+diff --git a/Sources/Upload.swift b/Sources/Upload.swift
+--- a/Sources/Upload.swift
++++ b/Sources/Upload.swift
+@@ -1 +1 @@
+-    public func upload() {}
++    public func upload() async {}
+diff --git a/web/index.ts b/web/index.ts
+--- a/web/index.ts
++++ b/web/index.ts
+@@ -1 +1,2 @@
+ const a = 1
++export const b = 2
+diff --git a/api/client_test.go b/api/client_test.go
+--- a/api/client_test.go
++++ b/api/client_test.go
+@@ -1 +1,2 @@
+ package api
++func TestNewClient(t *testing.T) {}
+diff --git a/bitrise.yml b/bitrise.yml
+--- a/bitrise.yml
++++ b/bitrise.yml
+@@ -1 +1 @@
+-format_version: 11
++format_version: 13
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-# API
++# API client`
+
+func TestAnalyzeImpact(t *testing.T) {
+	impacts := AnalyzeImpact(impactDiff)
+
+	expected := map[string]string{
+		"api/client.go":        "High (public API)",
+		"api/internal.go":      "Medium",
+		"Sources/Upload.swift": "High (public API)",
+		"web/index.ts":         "High (public API)",
+		"api/client_test.go":   "Low (tests)",
+		"bitrise.yml":          "Medium (configuration)",
+		"README.md":            "Low (documentation)",
+	}
+	for file, impact := range expected {
+		if got := impacts[file].String(); got != impact {
+			t.Errorf("Expected %s to have %s impact, got %s", file, impact, got)
+		}
+	}
+}
+
+func TestRankWalkthrough(t *testing.T) {
+	walkthrough := []Walkthrough{
+		{Files: "api/client_test.go", Summary: "Tests"},
+		{Files: "docs/unknown.md", Summary: "Not in the diff"},
+		{Files: "api/internal.go", Summary: "Parsing"},
+		{Files: "bitrise.yml, api/client.go", Summary: "Client"},
+		{Files: "README.md", Summary: "Docs"},
+	}
+
+	ranked := rankWalkthrough(walkthrough, AnalyzeImpact(impactDiff))
+	var order []string
+	for _, row := range ranked {
+		order = append(order, row.Summary)
+	}
+	if expected := []string{"Client", "Parsing", "Docs", "Tests", "Not in the diff"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected the order %v, got %v", expected, order)
+	}
+	if ranked[0].Impact != "High (public API)" || ranked[4].Impact != "" {
+		t.Errorf("Expected rows to get the impact of their most impactful file, got %+v", ranked)
+	}
+	if walkthrough[0].Impact != "" {
+		t.Error("Expected the walkthrough of the LLM not to be modified")
+	}
+
+	if unranked := rankWalkthrough(walkthrough, nil); !reflect.DeepEqual(unranked, walkthrough) {
+		t.Error("Expected the order of the LLM without impacts")
+	}
+}
@@ -0,0 +1,190 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+const (
+	// knowledgePendingWindow is how long a posted suggestion is checked for being applied
+	knowledgePendingWindow = 90 * 24 * time.Hour
+	// maxKnowledgeAccepted is the number of applied suggestions kept, the oldest ones are dropped
+	maxKnowledgeAccepted = 500
+	// minKnowledgeSuggestionLength is the length of the shortest suggestion tracked, shorter ones match by chance
+	minKnowledgeSuggestionLength = 12
+)
+
+// KnowledgeSuggestion is a suggestion posted on a pull request, and when the author applied it
+type KnowledgeSuggestion struct {
+	PullRequest int       `json:"pull_request"`
+	File        string    `json:"file"`
+	Category    string    `json:"category,omitempty"`
+	Original    string    `json:"original,omitempty"`
+	Suggestion  string    `json:"suggestion"`
+	PostedAt    time.Time `json:"posted_at"`
+	AppliedAt   time.Time `json:"applied_at,omitzero"`
+}
+
+// KnowledgeBase is the long-term record of the suggestions of a repository. Posted suggestions are pending until
+// a later run finds the suggested code in the file, then they are accepted. The accepted suggestions show the style
+// the team adopts, and are shared with the LLM as examples.
+type KnowledgeBase struct {
+	Repository string                `json:"repository"`
+	Pending    []KnowledgeSuggestion `json:"pending"`
+	Accepted   []KnowledgeSuggestion `json:"accepted"`
+}
+
+// LoadKnowledgeBase reads the knowledge base of the repository, a missing file or the file of another repository starts a new one
+func LoadKnowledgeBase(path, repository string) (*KnowledgeBase, error) {
+	knowledge := &KnowledgeBase{Repository: repository, Pending: []KnowledgeSuggestion{}, Accepted: []KnowledgeSuggestion{}}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return knowledge, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read knowledge base: %w", err)
+	}
+
+	var stored KnowledgeBase
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse knowledge base %s: %w", path, err)
+	}
+	if !strings.EqualFold(stored.Repository, repository) {
+		logger.Warnf("The knowledge base %s belongs to %s, starting a new one for %s", path, stored.Repository, repository)
+		return knowledge, nil
+	}
+	knowledge.Pending = append(knowledge.Pending, stored.Pending...)
+	knowledge.Accepted = append(knowledge.Accepted, stored.Accepted...)
+	return knowledge, nil
+}
+
+// Save writes the knowledge base, dropping the pending suggestions which were not applied in time
+func (k *KnowledgeBase) Save(path string) error {
+	since := now().Add(-knowledgePendingWindow)
+	k.Pending = slices.DeleteFunc(k.Pending, func(suggestion KnowledgeSuggestion) bool {
+		return suggestion.PostedAt.Before(since)
+	})
+	if len(k.Accepted) > maxKnowledgeAccepted {
+		k.Accepted = k.Accepted[len(k.Accepted)-maxKnowledgeAccepted:]
+	}
+
+	content, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode knowledge base: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create knowledge base directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write knowledge base: %w", err)
+	}
+	return nil
+}
+
+// DetectApplied accepts the pending suggestions of the pull request whose code is now in the checked out file,
+// returns the number of newly accepted suggestions
+func (k *KnowledgeBase) DetectApplied(pr int) int {
+	applied := 0
+	pending := make([]KnowledgeSuggestion, 0, len(k.Pending))
+	for _, suggestion := range k.Pending {
+		if suggestion.PullRequest == pr && fileContainsCode(suggestion.File, suggestion.Suggestion) {
+			suggestion.AppliedAt = now().UTC().Truncate(time.Second)
+			k.Accepted = append(k.Accepted, suggestion)
+			applied++
+			continue
+		}
+		pending = append(pending, suggestion)
+	}
+	k.Pending = pending
+	return applied
+}
+
+// RecordPosted adds the suggestions of the posted findings as pending. Suggestions already in the file,
+// like the ones only removing code, can't be told apart from applied ones, so they are not tracked.
+func (k *KnowledgeBase) RecordPosted(pr int, findings []LineLevel) {
+	for _, finding := range findings {
+		code := normalizeCode(finding.Suggestion)
+		if len(code) < minKnowledgeSuggestionLength || fileContainsCode(finding.File, finding.Suggestion) {
+			continue
+		}
+		if slices.ContainsFunc(k.Pending, func(suggestion KnowledgeSuggestion) bool {
+			return suggestion.PullRequest == pr && suggestion.File == finding.File && normalizeCode(suggestion.Suggestion) == code
+		}) {
+			continue
+		}
+		k.Pending = append(k.Pending, KnowledgeSuggestion{
+			PullRequest: pr,
+			File:        finding.File,
+			Category:    finding.Category,
+			Original:    finding.Line,
+			Suggestion:  finding.Suggestion,
+			PostedAt:    now().UTC().Truncate(time.Second),
+		})
+	}
+}
+
+// AppliedThisQuarter counts the suggestions applied since the start of the current calendar quarter
+func (k *KnowledgeBase) AppliedThisQuarter() int {
+	current := now()
+	start := time.Date(current.Year(), time.Month((int(current.Month())-1)/3*3+1), 1, 0, 0, 0, 0, current.Location())
+	count := 0
+	for _, suggestion := range k.Accepted {
+		if !suggestion.AppliedAt.Before(start) {
+			count++
+		}
+	}
+	return count
+}
+
+// Examples returns the most recently applied suggestions, the ones of the file types of the changes first
+func (k *KnowledgeBase) Examples(changedFiles []string, limit int) []KnowledgeSuggestion {
+	extensions := map[string]bool{}
+	for _, file := range changedFiles {
+		extensions[strings.ToLower(filepath.Ext(file))] = true
+	}
+
+	var matching, others []KnowledgeSuggestion
+	for idx := len(k.Accepted) - 1; idx >= 0; idx-- {
+		suggestion := k.Accepted[idx]
+		if extensions[strings.ToLower(filepath.Ext(suggestion.File))] {
+			matching = append(matching, suggestion)
+		} else {
+			others = append(others, suggestion)
+		}
+	}
+	examples := append(matching, others...)
+	if len(examples) > limit {
+		examples = examples[:limit]
+	}
+	return examples
+}
+
+// fileContainsCode checks if the checked out file contains the code, ignoring the indentation and blank lines
+func fileContainsCode(path, code string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	normalized := normalizeCode(code)
+	return normalized != "" && strings.Contains(normalizeCode(string(content)), normalized)
+}
+
+// normalizeCode trims the lines of the code and drops the blank ones
+func normalizeCode(code string) string {
+	var lines []string
+	for line := range strings.SplitSeq(code, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
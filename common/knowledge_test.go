@@ -0,0 +1,92 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKnowledgeBaseAppliedSuggestions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	reference := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reference }
+	defer func() { now = time.Now }()
+
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	knowledge, err := LoadKnowledgeBase(filepath.Join("cache", "knowledge.json"), "acme/app")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	knowledge.RecordPosted(42, []LineLevel{
+		{File: "main.go", Category: CategoryRefactor, Line: `println("hello")`, Suggestion: `fmt.Println("hello")`},
+		{File: "main.go", Suggestion: `fmt.Println("hello")`}, // Duplicate
+		{File: "main.go", Suggestion: `println("hello")`},     // Already in the file
+		{File: "main.go", Suggestion: "x := 1"},               // Too short
+	})
+	if len(knowledge.Pending) != 1 {
+		t.Fatalf("Expected 1 pending suggestion, got %+v", knowledge.Pending)
+	}
+
+	// The author applies the suggestion with a different indentation
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {\n    fmt.Println(\"hello\")\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if applied := knowledge.DetectApplied(7); applied != 0 {
+		t.Errorf("Expected the suggestions of other pull requests to stay pending, got %d applied", applied)
+	}
+	if applied := knowledge.DetectApplied(42); applied != 1 || len(knowledge.Pending) != 0 {
+		t.Errorf("Expected the suggestion to be applied, got %d applied, %d pending", applied, len(knowledge.Pending))
+	}
+	if count := knowledge.AppliedThisQuarter(); count != 1 {
+		t.Errorf("Expected 1 suggestion applied this quarter, got %d", count)
+	}
+
+	// Saved and loaded for the same repository only
+	if err := knowledge.Save(filepath.Join("cache", "knowledge.json")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	loaded, err := LoadKnowledgeBase(filepath.Join("cache", "knowledge.json"), "acme/app")
+	if err != nil || len(loaded.Accepted) != 1 || loaded.Accepted[0].Original != `println("hello")` {
+		t.Errorf("Expected the applied suggestion to be loaded, got %+v, %v", loaded, err)
+	}
+	other, err := LoadKnowledgeBase(filepath.Join("cache", "knowledge.json"), "acme/other")
+	if err != nil || len(other.Accepted) != 0 {
+		t.Errorf("Expected a new knowledge base for another repository, got %+v, %v", other, err)
+	}
+
+	// A quarter later the suggestion is not counted
+	now = func() time.Time { return reference.AddDate(0, 3, 0) }
+	if count := loaded.AppliedThisQuarter(); count != 0 {
+		t.Errorf("Expected no suggestions applied in the next quarter, got %d", count)
+	}
+}
+
+func TestKnowledgeBaseSaveDropsStalePending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knowledge.json")
+	knowledge := &KnowledgeBase{Repository: "acme/app", Pending: []KnowledgeSuggestion{
+		{File: "old.go", Suggestion: "stale suggestion", PostedAt: time.Now().AddDate(0, 0, -100)},
+		{File: "new.go", Suggestion: "fresh suggestion", PostedAt: time.Now()},
+	}}
+	if err := knowledge.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(knowledge.Pending) != 1 || knowledge.Pending[0].File != "new.go" {
+		t.Errorf("Expected only the fresh suggestion to be kept, got %+v", knowledge.Pending)
+	}
+}
+
+func TestKnowledgeBaseExamples(t *testing.T) {
+	knowledge := &KnowledgeBase{Accepted: []KnowledgeSuggestion{
+		{File: "a.go", Suggestion: "first"},
+		{File: "b.swift", Suggestion: "second"},
+		{File: "c.go", Suggestion: "third"},
+	}}
+	examples := knowledge.Examples([]string{"main.go"}, 2)
+	if len(examples) != 2 || examples[0].Suggestion != "third" || examples[1].Suggestion != "first" {
+		t.Errorf("Expected the recent Go suggestions first, got %+v", examples)
+	}
+}
@@ -0,0 +1,259 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+)
+
+// maxLanguagesListed is the number of languages named in the breakdown, the rest are summed up as other
+const maxLanguagesListed = 4
+
+// languageExtensions are the languages of the file extensions
+var languageExtensions = map[string]string{
+	".go":           "Go",
+	".swift":        "Swift",
+	".m":            "Objective-C",
+	".mm":           "Objective-C",
+	".kt":           "Kotlin",
+	".kts":          "Kotlin",
+	".java":         "Java",
+	".gradle":       "Groovy",
+	".groovy":       "Groovy",
+	".dart":         "Dart",
+	".js":           "JavaScript",
+	".jsx":          "JavaScript",
+	".mjs":          "JavaScript",
+	".ts":           "TypeScript",
+	".tsx":          "TypeScript",
+	".py":           "Python",
+	".rb":           "Ruby",
+	".rs":           "Rust",
+	".c":            "C",
+	".cc":           "C++",
+	".cpp":          "C++",
+	".hpp":          "C++",
+	".cs":           "C#",
+	".php":          "PHP",
+	".sh":           "Shell",
+	".bash":         "Shell",
+	".zsh":          "Shell",
+	".sql":          "SQL",
+	".html":         "HTML",
+	".css":          "CSS",
+	".scss":         "CSS",
+	".json":         "JSON",
+	".yml":          "YAML",
+	".yaml":         "YAML",
+	".xml":          "XML",
+	".plist":        "XML",
+	".md":           "Markdown",
+	".proto":        "Protocol Buffers",
+	".tf":           "Terraform",
+	".xcprivacy":    "XML",
+	".entitlements": "XML",
+}
+
+// languageFileNames are the languages of the files without a telling extension
+var languageFileNames = map[string]string{
+	"Dockerfile": "Dockerfile",
+	"Makefile":   "Makefile",
+	"Podfile":    "Ruby",
+	"Gemfile":    "Ruby",
+	"Fastfile":   "Ruby",
+	"Appfile":    "Ruby",
+	"Brewfile":   "Ruby",
+}
+
+// shebangLanguages are the languages of the scripts by the interpreter of their shebang line
+var shebangLanguages = map[string]string{
+	"sh":      "Shell",
+	"bash":    "Shell",
+	"zsh":     "Shell",
+	"python":  "Python",
+	"python3": "Python",
+	"ruby":    "Ruby",
+	"node":    "JavaScript",
+}
+
+// languageRulePacks are the rule packs activated by the languages, when the code uses the platform frameworks
+var languageRulePacks = map[string]struct {
+	pack    string
+	imports []string
+}{
+	"Swift":       {pack: RulePackIOS, imports: []string{"import UIKit", "import SwiftUI"}},
+	"Objective-C": {pack: RulePackIOS, imports: []string{"#import <UIKit/", "@import UIKit"}},
+	"Kotlin":      {pack: RulePackAndroid, imports: []string{"import android.", "import androidx."}},
+	"Java":        {pack: RulePackAndroid, imports: []string{"import android.", "import androidx."}},
+}
+
+// languageFormatters are the formatter presets of the languages
+var languageFormatters = map[string]string{
+	"Go":         "gofmt",
+	"Swift":      "swiftformat",
+	"JavaScript": "prettier",
+	"TypeScript": "prettier",
+	"CSS":        "prettier",
+}
+
+// Languages activates the rule packs and the formatters of the languages detected in the changes
+type Languages struct {
+	AutoRulePacks  bool `yaml:"auto_rule_packs"` // Enable the ios and android rule packs for Swift, Objective-C, Kotlin and Java code using the platform frameworks
+	AutoFormatters bool `yaml:"auto_formatters"` // Run the formatter presets of the detected languages, besides the configured formatters
+}
+
+// LanguageShare is the share of a language in the changed lines
+type LanguageShare struct {
+	Name    string
+	Lines   int
+	Percent int
+}
+
+// LanguageBreakdown is the share of each language in the changes, the largest first
+type LanguageBreakdown struct {
+	Languages []LanguageShare
+	RulePacks []string // Rule packs of the platform frameworks used by the changed code
+}
+
+// DetectLanguages detects the languages of the changed files by their extension, file name or shebang line,
+// and weighs them by the changed lines. Files of unknown languages are left out.
+func DetectLanguages(diff string) LanguageBreakdown {
+	lines := map[string]int{}
+	var packs []string
+	total := 0
+	for _, file := range diffparse.Parse(diff) {
+		content := changedContent(file)
+		language := detectLanguage(file.Path(), content)
+		if language == "" {
+			continue
+		}
+		changed := len(file.AddedLines()) + len(file.DeletedLines())
+		lines[language] += changed
+		total += changed
+
+		if rulePack, ok := languageRulePacks[language]; ok && !slices.Contains(packs, rulePack.pack) {
+			for _, imported := range rulePack.imports {
+				if strings.Contains(content, imported) {
+					packs = append(packs, rulePack.pack)
+					break
+				}
+			}
+		}
+	}
+
+	breakdown := LanguageBreakdown{RulePacks: packs}
+	for name, count := range lines {
+		share := LanguageShare{Name: name, Lines: count}
+		if total > 0 {
+			share.Percent = count * 100 / total
+		}
+		breakdown.Languages = append(breakdown.Languages, share)
+	}
+	slices.SortFunc(breakdown.Languages, func(a, b LanguageShare) int {
+		if a.Lines != b.Lines {
+			return b.Lines - a.Lines
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return breakdown
+}
+
+// String formats the breakdown, like "70% Go, 20% YAML, 10% other"
+func (b LanguageBreakdown) String() string {
+	var parts []string
+	rest := 100
+	for idx, language := range b.Languages {
+		if idx == maxLanguagesListed {
+			break
+		}
+		if language.Percent == 0 {
+			parts = append(parts, "<1% "+language.Name)
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d%% %s", language.Percent, language.Name))
+		rest -= language.Percent
+	}
+	if len(b.Languages) > maxLanguagesListed && rest > 0 {
+		parts = append(parts, fmt.Sprintf("%d%% other", rest))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Names returns the detected languages, the largest first
+func (b LanguageBreakdown) Names() []string {
+	names := make([]string, len(b.Languages))
+	for idx, language := range b.Languages {
+		names[idx] = language.Name
+	}
+	return names
+}
+
+// Apply activates the rule packs and the formatters of the detected languages which are not configured yet
+func (l Languages) Apply(settings *Settings, breakdown LanguageBreakdown) {
+	if l.AutoRulePacks {
+		for _, pack := range breakdown.RulePacks {
+			if !slices.Contains(settings.RulePacks, pack) {
+				settings.RulePacks = append(settings.RulePacks, pack)
+			}
+		}
+	}
+
+	if l.AutoFormatters {
+		for _, language := range breakdown.Names() {
+			name, ok := languageFormatters[language]
+			if !ok || slices.ContainsFunc(settings.Formatters, func(f Formatter) bool { return f.Name == name }) {
+				continue
+			}
+			settings.Formatters = append(settings.Formatters, Formatter{Name: name})
+		}
+	}
+}
+
+// detectLanguage detects the language of the file by its extension or name, then by the shebang line of its content
+func detectLanguage(path, content string) string {
+	base := filepath.Base(path)
+	if language, ok := languageFileNames[base]; ok {
+		return language
+	}
+
+	extension := strings.ToLower(filepath.Ext(base))
+	if extension == ".h" {
+		// Headers are shared by C, C++ and Objective-C, Objective-C ones declare interfaces or import frameworks
+		if strings.Contains(content, "@interface") || strings.Contains(content, "@protocol") || strings.Contains(content, "#import") {
+			return "Objective-C"
+		}
+		return "C"
+	}
+	if language, ok := languageExtensions[extension]; ok {
+		return language
+	}
+
+	if shebang, _, _ := strings.Cut(content, "\n"); strings.HasPrefix(shebang, "#!") {
+		fields := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+		if len(fields) == 0 {
+			return ""
+		}
+		interpreter := filepath.Base(fields[0])
+		if interpreter == "env" && len(fields) > 1 {
+			interpreter = fields[1]
+		}
+		return shebangLanguages[interpreter]
+	}
+	return ""
+}
+
+// changedContent returns the added lines of the file, or the deleted ones of deleted files, for content sniffing
+func changedContent(file diffparse.FileDiff) string {
+	lines := file.AddedLines()
+	if len(lines) == 0 {
+		lines = file.DeletedLines()
+	}
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(line.Content + "\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const languageDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,8 @@
+ package main
+-var a = 1
++var a = 2
++var b = 3
++var c = 4
++var d = 5
++var e = 6
++var f = 7
+diff --git a/bitrise.yml b/bitrise.yml
+--- a/bitrise.yml
++++ b/bitrise.yml
+@@ -1 +1 @@
+-format_version: 11
++format_version: 13
+diff --git a/scripts/release b/scripts/release
+new file mode 100755
+--- /dev/null
++++ b/scripts/release
+@@ -0,0 +1,2 @@
++#!/usr/bin/env bash
++set -e
+diff --git a/App/View.swift b/App/View.swift
+--- a/App/View.swift
++++ b/App/View.swift
+@@ -0,0 +1,2 @@
++import SwiftUI
++struct ContentView: View {}
+diff --git a/logo.png b/logo.png
+Binary files a/logo.png and b/logo.png differ
+`
+
+func TestDetectLanguages(t *testing.T) {
+	breakdown := DetectLanguages(languageDiff)
+
+	expected := []LanguageShare{
+		{Name: "Go", Lines: 7, Percent: 53},
+		{Name: "Shell", Lines: 2, Percent: 15},
+		{Name: "Swift", Lines: 2, Percent: 15},
+		{Name: "YAML", Lines: 2, Percent: 15},
+	}
+	if !reflect.DeepEqual(breakdown.Languages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, breakdown.Languages)
+	}
+	if !reflect.DeepEqual(breakdown.RulePacks, []string{RulePackIOS}) {
+		t.Errorf("Expected the iOS rule pack for the SwiftUI code, got %v", breakdown.RulePacks)
+	}
+	if got := breakdown.String(); got != "53% Go, 15% Shell, 15% Swift, 15% YAML" {
+		t.Errorf("Unexpected breakdown: %s", got)
+	}
+}
+
+func TestDetectLanguageHeaders(t *testing.T) {
+	if got := detectLanguage("Sources/View.h", "#import <UIKit/UIKit.h>\n@interface View : UIView\n"); got != "Objective-C" {
+		t.Errorf("Expected Objective-C, got %s", got)
+	}
+	if got := detectLanguage("src/util.h", "int util(void);\n"); got != "C" {
+		t.Errorf("Expected C, got %s", got)
+	}
+	if got := detectLanguage("ios/Podfile", ""); got != "Ruby" {
+		t.Errorf("Expected Ruby, got %s", got)
+	}
+	if got := detectLanguage("LICENSE", "MIT License\n"); got != "" {
+		t.Errorf("Expected no language, got %s", got)
+	}
+}
+
+func TestLanguageBreakdownOther(t *testing.T) {
+	breakdown := LanguageBreakdown{Languages: []LanguageShare{
+		{Name: "Go", Percent: 60}, {Name: "YAML", Percent: 20}, {Name: "JSON", Percent: 10},
+		{Name: "Shell", Percent: 5}, {Name: "Markdown", Percent: 3}, {Name: "SQL", Percent: 2},
+	}}
+	if got := breakdown.String(); !strings.HasSuffix(got, "5% Shell, 5% other") {
+		t.Errorf("Expected the rest summed up as other, got %s", got)
+	}
+}
+
+func TestLanguagesApply(t *testing.T) {
+	breakdown := DetectLanguages(languageDiff)
+	settings := Settings{Formatters: []Formatter{{Name: "gofmt", Paths: []string{"cmd/**"}}}}
+
+	Languages{}.Apply(&settings, breakdown)
+	if len(settings.RulePacks) != 0 || len(settings.Formatters) != 1 {
+		t.Errorf("Expected nothing to be activated by default, got %+v", settings)
+	}
+
+	Languages{AutoRulePacks: true, AutoFormatters: true}.Apply(&settings, breakdown)
+	if !reflect.DeepEqual(settings.RulePacks, []string{RulePackIOS}) {
+		t.Errorf("Expected the iOS rule pack, got %v", settings.RulePacks)
+	}
+	if len(settings.Formatters) != 2 || settings.Formatters[0].Paths[0] != "cmd/**" || settings.Formatters[1].Name != "swiftformat" {
+		t.Errorf("Expected swiftformat to be added and gofmt to be kept, got %+v", settings.Formatters)
+	}
+}
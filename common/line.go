@@ -5,9 +5,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
 )
 
 // ChangedLine represents a single line that was changed in a diff
@@ -16,17 +16,18 @@ type ChangedLine struct {
 	LineContent string
 }
 
+// GetFullLine returns the changed line matching the line in the diff of a single file, with its original indentation
 func GetFullLine(fileContent, diffContent, line string) string {
-	matches := getMatchingLines([]byte(fileContent), line)
-	changed := parseDiffChangedLines([]byte(diffContent), line)
-
-	for _, ln := range matches {
-		if changed[ln] {
-			return strings.Split(fileContent, "\n")[ln-1]
-		}
+	files := diffparse.Parse(diffContent)
+	if len(files) == 0 {
+		return ""
 	}
 
-	return ""
+	fullLine, err := GetOriginalLine(files[0].Path(), []byte(fileContent), []byte(diffContent), line)
+	if err != nil {
+		return ""
+	}
+	return fullLine
 }
 
 func GetOriginalLine(fileName string, fileContent []byte, diffContent []byte, matchLine string) (string, error) {
@@ -111,44 +112,12 @@ func getMatchingLines(fileContent []byte, matchLine string) []int {
 	return lineNumbers
 }
 
-// parseDiffChangedLines parses a git diff and returns a map of line numbers that were changed in the target file
+// parseDiffChangedLines returns the numbers of the lines added to the target file in the diff
 func parseDiffChangedLines(diff []byte, targetFile string) map[int]bool {
 	changed := map[int]bool{}
-	lines := strings.Split(string(diff), "\n")
-	re := regexp.MustCompile(`@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
-	var newLineNum int
-	inTargetFile := false
-	for _, line := range lines {
-		if strings.HasPrefix(line, "@@") {
-			// Diff hunk header
-			matches := re.FindStringSubmatch(line)
-			if len(matches) >= 2 {
-				var err error
-				newLineNum, err = strconv.Atoi(matches[1])
-				if err != nil {
-					continue
-				}
-			}
-			continue
-		}
-		if strings.HasPrefix(line, "+++") {
-			// Example: +++ b/foo.go
-			currentFile := strings.TrimPrefix(strings.TrimSpace(line[4:]), "b/")
-			inTargetFile = (currentFile == targetFile)
-			continue
-		}
-		if inTargetFile {
-			if len(line) == 0 || line[0] == '-' || line[0] == ' ' {
-				if len(line) > 0 && line[0] != '-' {
-					newLineNum++
-				}
-				continue
-			}
-			if line[0] == '+' {
-				// This is an added line in the new file (not context)
-				changed[newLineNum] = true
-				newLineNum++
-			}
+	if file, ok := diffparse.FindFile(diffparse.Parse(string(diff)), targetFile); ok {
+		for _, line := range file.AddedLines() {
+			changed[line.NewNumber] = true
 		}
 	}
 	return changed
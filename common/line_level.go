@@ -1,7 +1,10 @@
 package common
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
@@ -15,13 +18,56 @@ const (
 	CategoryNitpick       = "nitpick"
 	CategoryTestCoverage  = "test coverage"
 	CategorySecurity      = "security"
+	CategoryPerformance   = "performance"
+	// Categories of the mobile rule packs
+	CategoryMobilePerf      = "mobile-perf"
+	CategoryStoreCompliance = "store-compliance"
+	// Category of the user-facing copy review, kept separate from code issues
+	CategoryCopy = "copy"
 )
 
+// Scopes of a finding, file scoped findings are about the whole file and have no line
+const (
+	ScopeLine = "line"
+	ScopeFile = "file"
+)
+
+// Severities of a finding, only high severity is highlighted in the comments
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// severityRanks orders the severities, findings without a severity rank as low
+var severityRanks = map[string]int{
+	SeverityLow:    1,
+	SeverityMedium: 2,
+	SeverityHigh:   3,
+}
+
+// IsValidSeverity checks if the severity is high, medium or low
+func IsValidSeverity(severity string) bool {
+	return severityRanks[severity] > 0
+}
+
+// CountFindingsAtOrAbove returns the number of findings with the severity or a higher one
+func CountFindingsAtOrAbove(lines []LineLevel, severity string) int {
+	count := 0
+	for _, l := range lines {
+		if max(severityRanks[l.Severity], severityRanks[SeverityLow]) >= severityRanks[severity] {
+			count++
+		}
+	}
+	return count
+}
+
 // LineLevel represents a review comment for a specific line of code
 type LineLevel struct {
 	File           string `json:"file"`                  // Path to the file being commented on
 	Line           string `json:"content"`               // Content of the line being commented on
 	Category       string `json:"category,omitempty"`    // Category of the issue (e.g., "bug", "style", "performance")
+	Severity       string `json:"severity,omitempty"`    // Severity of the issue: high, medium or low
 	LineNumber     int    `json:"line"`                  // Line number in the file
 	LastLineNumber int    `json:"last_line"`             // Last line number for multi-line comments
 	Suggestion     string `json:"suggestion,omitempty"`  // Suggested replacement for the line
@@ -29,11 +75,27 @@ type LineLevel struct {
 	Body           string `json:"issue"`                 // Main body of the review comment
 	CommitHash     string `json:"commit_hash,omitempty"` // Commit hash for the line being commented on
 	Prompt         string `json:"prompt,omitempty"`      // Optional prompt for AI agents to fix the issue
+	Fingerprint    string `json:"fingerprint,omitempty"` // Stable identifier of the finding, parsed from posted comments
+	Topic          string `json:"topic,omitempty"`       // Identifier shared by the findings of the same issue at multiple locations
+	Scope          string `json:"scope,omitempty"`       // Scope of the finding: line (default) or file
+	Dismissed      bool   `json:"-"`                     // Whether the team dismissed the posted finding (reaction or reply)
+
+	RelatedLocations []FindingLocation `json:"-"` // Other locations of the same issue, listed on the primary comment
+	PrimaryLocation  *FindingLocation  `json:"-"` // Location of the primary comment for cross-references
 }
 
+// maxTaskLength is the maximum length of the task tracking a finding, the task only has to identify it
+const maxTaskLength = 200
+
+// dismissalRegex matches the replies dismissing a posted finding, the phrase has to start the reply as a whole word:
+// "Won't fix, it is intended" dismisses the finding, "This is not an issue I can ignore" or "Unresolved" don't
+var dismissalRegex = regexp.MustCompile(`(?i)^(?:resolved|won[’']?t fix|wontfix|dismiss(?:ed)?|false positive|not an issue|👎)(?:[^\p{L}\p{N}_]|$)`)
+
 // LineLevelFeedback represents a collection of line-level feedback items
 type LineLevelFeedback struct {
 	Lines []LineLevel `json:"line-feedback"` // List of line-level feedback items
+
+	HistoryRewritten bool `json:"-"` // The history was rewritten since the previous review, the blame of the posted findings is stale
 }
 
 // Header generates a header string for the comment with file, line and blame information
@@ -44,19 +106,58 @@ func (l LineLevel) Header(client *git.Client, commitHash string) string {
 	}
 
 	gitBlame := "unknown"
-	if client != nil {
+	if client != nil && !l.IsFileLevel() {
 		blame, err := client.GetBlameForFileLine(commitHash, l.File, l.LineNumber)
 		if err == nil {
 			gitBlame = blame
 		}
 	}
 
-	return fmt.Sprintf("[bitrise-plugin-ai-reviewer]: %s:%s:%s", l.File, lineNumber, gitBlame)
+	return fmt.Sprintf("[bitrise-plugin-ai-reviewer]: %s:%s:%s:%s", l.File, lineNumber, gitBlame, l.GetFingerprint())
+}
+
+// GetFingerprint returns a stable identifier for the finding.
+// It only depends on the file, category and the commented line content, so it survives line shifts between runs.
+func (l LineLevel) GetFingerprint() string {
+	if l.Fingerprint != "" {
+		return l.Fingerprint
+	}
+
+	content := strings.Join(strings.Fields(l.FirstLine()), " ")
+	hash := sha256.Sum256([]byte(l.File + "\n" + l.Category + "\n" + content))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// Task returns the checklist item tracking the fix of a high severity finding, empty for other severities
+func (l LineLevel) Task() string {
+	if l.Severity != SeverityHigh {
+		return ""
+	}
+
+	issue := l.Title
+	if issue == "" {
+		issue, _, _ = strings.Cut(strings.TrimSpace(l.Body), "\n")
+	}
+	task := []rune(fmt.Sprintf("Fix %s: %s", l.Location(), issue))
+	if len(task) > maxTaskLength {
+		return string(task[:maxTaskLength-1]) + "…"
+	}
+	return string(task)
+}
+
+// IsDismissalReply checks if a reply on a posted finding dismisses it
+func IsDismissalReply(body string) bool {
+	return dismissalRegex.MatchString(strings.TrimSpace(body))
+}
+
+// IsFileLevel returns true for findings about the whole file, posted as file comments instead of on a line
+func (l LineLevel) IsFileLevel() bool {
+	return l.Scope == ScopeFile
 }
 
 // String formats the complete comment with header, body and suggestion
 func (l LineLevel) String(provider string, client *git.Client, commitHash string) string {
-	if l.File == "" || l.LineNumber <= 0 || l.Body == "" {
+	if l.File == "" || (l.LineNumber <= 0 && !l.IsFileLevel()) || l.Body == "" {
 		return ""
 	}
 
@@ -65,6 +166,9 @@ func (l LineLevel) String(provider string, client *git.Client, commitHash string
 	// Setup title
 	title := []string{}
 	if category := l.getCategoryString(); category != "" {
+		if l.Severity == SeverityHigh {
+			category = "🚨 " + category + " (high severity)"
+		}
 		title = append(title, category)
 	}
 	if l.Title != "" {
@@ -74,36 +178,35 @@ func (l LineLevel) String(provider string, client *git.Client, commitHash string
 		body = append(body, fmt.Sprintf("**%s**", strings.Join(title, ": ")))
 	}
 
+	// Cross-references only point to the primary comment of the group
+	renderer := NewMarkdownRenderer(provider)
+	if l.IsCrossReference() {
+		body = append(body, fmt.Sprintf("Same issue as at `%s`, see the details there.", l.PrimaryLocation))
+		if len(l.Suggestion) > 0 {
+			body = append(body, fmt.Sprintf("🔄 Suggestion:\n%s", renderer.Suggestion(l.File, l.Line, l.Suggestion)))
+		}
+		return fmt.Sprintf("%s\n%s%s", l.Header(client, commitHash), ApplyStyle(strings.Join(body, "\n\n"))+commentFooter(renderer), commentMetadata())
+	}
+
 	// Setup issue body
 	body = append(body, l.Body)
+	if len(l.RelatedLocations) > 0 {
+		locations := make([]string, 0, len(l.RelatedLocations))
+		for _, location := range l.RelatedLocations {
+			locations = append(locations, fmt.Sprintf("- `%s`", location))
+		}
+		body = append(body, "Also found at:\n"+strings.Join(locations, "\n"))
+	}
 
 	// Setup helpers
-	if provider == "bitbucket" {
-		if len(l.Prompt) > 0 {
-			body = append(body, fmt.Sprintf("🤖 Prompt for AI Agents:\n\n```\n%s\n```\n\n", l.getAIPrompt()))
-		}
-	} else {
-		if len(l.getCategoryString()) > 0 && l.getCategoryString() != CategoryNitpick && len(l.Prompt) > 0 {
-			body = append(body, fmt.Sprintf("<details>\n<summary>🤖 Prompt for AI Agents:</summary>\n\n```\n%s\n```\n\n</details>", l.getAIPrompt()))
-		}
+	if len(l.Prompt) > 0 && l.Category != CategoryNitpick {
+		body = append(body, renderer.Collapsible("🤖 Prompt for AI Agents:", fmt.Sprintf("```\n%s\n```", l.getAIPrompt())))
 	}
 
-	if len(l.Suggestion) > 0 {
-		var suggestionStr string
-		switch provider {
-		case "bitbucket":
-			suggestionStr = "Replace with the following code:\n\n"
-			suggestionStr += "Current implementation\n"
-			suggestionStr += fmt.Sprintf("```\n%s\n```", l.Line)
-			suggestionStr += "\n\n"
-			suggestionStr += "Suggested changes\n"
-			suggestionStr += fmt.Sprintf("```\n%s\n```", l.Suggestion)
-		case "github":
-			suggestionStr = "```suggestion\n" + l.Suggestion + "\n```"
-		}
-		body = append(body, fmt.Sprintf("🔄 Suggestion:\n%s", suggestionStr))
+	if len(l.Suggestion) > 0 && !l.IsFileLevel() {
+		body = append(body, fmt.Sprintf("🔄 Suggestion:\n%s", renderer.Suggestion(l.File, l.Line, l.Suggestion)))
 	}
-	return fmt.Sprintf("%s\n%s", l.Header(client, commitHash), strings.Join(body, "\n\n"))
+	return fmt.Sprintf("%s\n%s%s", l.Header(client, commitHash), ApplyStyle(strings.Join(body, "\n\n"))+commentFooter(renderer), commentMetadata())
 }
 
 func (l LineLevel) StringForAssistant() string {
@@ -158,6 +261,14 @@ func (l LineLevel) getCategoryString() string {
 		return "🧪 Test Coverage"
 	case CategorySecurity:
 		return "🔒 Security Issue"
+	case CategoryPerformance:
+		return "⚡ Performance"
+	case CategoryMobilePerf:
+		return "📱 Mobile Performance"
+	case CategoryStoreCompliance:
+		return "🏪 Store Compliance"
+	case CategoryCopy:
+		return "✏️ Copy"
 	}
 
 	return ""
@@ -184,7 +295,13 @@ func (llf LineLevelFeedback) GetLineFeedback() []LineLevel {
 }
 
 func (ll LineLevel) getAIPrompt() string {
-	if ll.Prompt == "" || ll.File == "" || ll.LineNumber <= 0 {
+	if ll.Prompt == "" || ll.File == "" {
+		return ""
+	}
+	if ll.IsFileLevel() {
+		return WrapString(fmt.Sprintf("In %s, %s", ll.File, ll.Prompt), 80)
+	}
+	if ll.LineNumber <= 0 {
 		return ""
 	}
 
@@ -0,0 +1,133 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetFingerprint(t *testing.T) {
+	ll := LineLevel{
+		File:       "main.go",
+		Line:       "\tif x > 0 {",
+		Category:   CategoryBug,
+		LineNumber: 10,
+		Body:       "Variable x is not initialized",
+	}
+
+	fingerprint := ll.GetFingerprint()
+	if len(fingerprint) != 12 {
+		t.Errorf("Expected fingerprint of 12 characters, got %s", fingerprint)
+	}
+
+	// Moving the finding or rewording the issue should keep the fingerprint
+	moved := ll
+	moved.LineNumber = 42
+	moved.Line = "    if x > 0 {"
+	moved.Body = "x might be used before initialization"
+	if moved.GetFingerprint() != fingerprint {
+		t.Errorf("Expected fingerprint to be stable, got %s and %s", fingerprint, moved.GetFingerprint())
+	}
+
+	// A different category is a different finding
+	other := ll
+	other.Category = CategoryNitpick
+	if other.GetFingerprint() == fingerprint {
+		t.Error("Expected different fingerprint for a different category")
+	}
+
+	// Parsed fingerprints take precedence
+	parsed := LineLevel{Fingerprint: "abc123"}
+	if parsed.GetFingerprint() != "abc123" {
+		t.Errorf("Expected parsed fingerprint abc123, got %s", parsed.GetFingerprint())
+	}
+}
+
+func TestHeaderContainsFingerprint(t *testing.T) {
+	ll := LineLevel{
+		File:       "main.go",
+		Line:       "return nil",
+		LineNumber: 3,
+	}
+
+	header := ll.Header(nil, "")
+	parts := strings.Split(header, ":")
+	if len(parts) != 5 {
+		t.Fatalf("Expected 5 header parts, got %d: %s", len(parts), header)
+	}
+	if strings.TrimSpace(parts[4]) != ll.GetFingerprint() {
+		t.Errorf("Expected fingerprint %s in header, got %s", ll.GetFingerprint(), parts[4])
+	}
+}
+
+func TestIsDismissalReply(t *testing.T) {
+	dismissals := []string{"Resolved", "won't fix, this is intended", "False positive", "👎", "  Not an issue: the value is validated above"}
+	for _, body := range dismissals {
+		if !IsDismissalReply(body) {
+			t.Errorf("Expected '%s' to dismiss the finding", body)
+		}
+	}
+
+	for _, body := range []string{"Good catch, fixing it now", "Still unresolved", "This is not an issue I can ignore", "Resolvedness is unclear", "I won't fix it without a test"} {
+		if IsDismissalReply(body) {
+			t.Errorf("Expected '%s' not to dismiss the finding", body)
+		}
+	}
+}
+
+func TestTask(t *testing.T) {
+	ll := LineLevel{File: "main.go", LineNumber: 12, Severity: SeverityHigh, Body: "The token is logged\n\nMore details"}
+	if task := ll.Task(); task != "Fix main.go:12: The token is logged" {
+		t.Errorf("Unexpected task: %q", task)
+	}
+
+	ll.Body = strings.Repeat("é", 300)
+	if task := []rune(ll.Task()); len(task) != maxTaskLength {
+		t.Errorf("Expected the task to be truncated to %d characters, got %d", maxTaskLength, len(task))
+	}
+
+	ll.Severity = SeverityMedium
+	if task := ll.Task(); task != "" {
+		t.Errorf("Expected no task for a medium severity finding, got %q", task)
+	}
+}
+
+func TestFileLevelString(t *testing.T) {
+	ll := LineLevel{File: "main.go", Scope: ScopeFile, Category: CategoryRefactor, Body: "This file should be split", Prompt: "Split the file by feature", Suggestion: "ignored"}
+
+	output := ll.String(ProviderGitHub, nil, "")
+	if !strings.HasPrefix(output, "[bitrise-plugin-ai-reviewer]: main.go:0:unknown:") {
+		t.Errorf("Expected the header of a file comment, got:\n%s", output)
+	}
+	if !strings.Contains(output, "This file should be split") || !strings.Contains(output, "In main.go, Split the file by feature") {
+		t.Errorf("Expected the issue and the prompt, got:\n%s", output)
+	}
+	if strings.Contains(output, "🔄 Suggestion") {
+		t.Errorf("Expected no suggestion on a file comment, got:\n%s", output)
+	}
+	if location := ll.Location().String(); location != "main.go" {
+		t.Errorf("Expected the location of a file comment to be the file, got %s", location)
+	}
+
+	ll.Scope = ScopeLine
+	if output := ll.String(ProviderGitHub, nil, ""); output != "" {
+		t.Errorf("Expected no comment for a line finding without a line, got:\n%s", output)
+	}
+}
+
+func TestCountFindingsAtOrAbove(t *testing.T) {
+	lines := []LineLevel{
+		{Severity: SeverityHigh},
+		{Severity: SeverityMedium},
+		{Severity: SeverityLow},
+		{}, // Findings without a severity count as low
+	}
+	expected := map[string]int{SeverityHigh: 1, SeverityMedium: 2, SeverityLow: 4}
+	for severity, count := range expected {
+		if got := CountFindingsAtOrAbove(lines, severity); got != count {
+			t.Errorf("Expected %d findings at or above %s, got %d", count, severity, got)
+		}
+	}
+	if IsValidSeverity("critical") || !IsValidSeverity(SeverityMedium) {
+		t.Error("Expected only high, medium and low to be valid severities")
+	}
+}
@@ -0,0 +1,44 @@
+package common
+
+import "testing"
+
+const lineFileContent = `===== FILE: main.go =====
+package main
+
+func main() {
+	run()
+	run()
+}`
+
+const lineDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,4 @@ package main
+ func main() {
+ 	run()
++	run()
+ }`
+
+func TestGetLineNumber(t *testing.T) {
+	// The first matching line is unchanged, the added one is picked
+	lineNumber, err := GetLineNumber("main.go", []byte(lineFileContent), []byte(lineDiff), "run()")
+	if err != nil || lineNumber != 5 {
+		t.Errorf("Expected line 5, got %d (%v)", lineNumber, err)
+	}
+
+	if _, err := GetLineNumber("main.go", []byte(lineFileContent), []byte(lineDiff), "func main() {"); err == nil {
+		t.Error("Expected an error for an unchanged line")
+	}
+	if _, err := GetLineNumber("other.go", []byte(lineFileContent), []byte(lineDiff), "run()"); err == nil {
+		t.Error("Expected an error for a file without content")
+	}
+}
+
+func TestGetFullLine(t *testing.T) {
+	if line := GetFullLine(lineFileContent, lineDiff, "run()"); line != "\trun()" {
+		t.Errorf("Expected the line with its indentation, got %q", line)
+	}
+	if line := GetFullLine(lineFileContent, lineDiff, "missing()"); line != "" {
+		t.Errorf("Expected no line for a line outside the diff, got %q", line)
+	}
+}
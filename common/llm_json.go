@@ -0,0 +1,41 @@
+package common
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DecodeLLMJSON decodes the JSON object answered by the LLM into v.
+// Models often wrap the object in a markdown code block or add a sentence around it,
+// the object is cut out of the surrounding text only if the content is not valid JSON itself.
+func DecodeLLMJSON(content string, v any) error {
+	content = stripCodeFence(strings.TrimSpace(content))
+
+	err := json.Unmarshal([]byte(content), v)
+	if err == nil {
+		return nil
+	}
+
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return err
+	}
+	if json.Unmarshal([]byte(content[start:end+1]), v) != nil {
+		return err
+	}
+	return nil
+}
+
+// stripCodeFence removes the markdown code block around the content, with or without a language tag
+func stripCodeFence(content string) string {
+	if !strings.HasPrefix(content, "```") || !strings.HasSuffix(content, "```") || len(content) < 6 {
+		return content
+	}
+
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "```"), "```")
+	// The language tag runs until the end of the first line
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 && !strings.ContainsAny(content[:newline], "{[") {
+		content = content[newline+1:]
+	}
+	return strings.TrimSpace(content)
+}
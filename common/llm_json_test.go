@@ -0,0 +1,89 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+type llmJSONSample struct {
+	Summary  string   `json:"summary"`
+	Count    int      `json:"count"`
+	Passed   bool     `json:"passed"`
+	Findings []string `json:"findings"`
+}
+
+func TestDecodeLLMJSON(t *testing.T) {
+	expected := llmJSONSample{Summary: "ok", Count: 2, Passed: true}
+	cases := map[string]string{
+		"plain":               `{"summary": "ok", "count": 2, "passed": true}`,
+		"json code block":     "```json\n{\"summary\": \"ok\", \"count\": 2, \"passed\": true}\n```",
+		"untagged code block": "```\n{\"summary\": \"ok\", \"count\": 2, \"passed\": true}\n```",
+		"single line block":   "```{\"summary\": \"ok\", \"count\": 2, \"passed\": true}```",
+		"surrounding text":    "Here is the result:\n```json\n{\"summary\": \"ok\", \"count\": 2, \"passed\": true}\n```\nLet me know if you need more.",
+		"whitespace":          "\n\n  {\"summary\": \"ok\", \"count\": 2, \"passed\": true}  \n",
+	}
+	for name, content := range cases {
+		var got llmJSONSample
+		if err := DecodeLLMJSON(content, &got); err != nil {
+			t.Errorf("%s: failed to decode: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, got)
+		}
+	}
+}
+
+func TestDecodeLLMJSONRejectsGarbage(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"I could not review the changes.",
+		"```json\n{\"summary\": \"unterminated\n```",
+		"} reversed {",
+		`{"count": "not a number"}`,
+	} {
+		var got llmJSONSample
+		if err := DecodeLLMJSON(content, &got); err == nil {
+			t.Errorf("Expected an error for %q, got %+v", content, got)
+		}
+	}
+}
+
+func TestDecodeLLMJSONKeepsBackticksInValues(t *testing.T) {
+	var got llmJSONSample
+	if err := DecodeLLMJSON("```json\n{\"summary\": \"use `go vet` ```\"}\n```", &got); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if got.Summary != "use `go vet` ```" {
+		t.Errorf("Expected the backticks of the value to be kept, got %q", got.Summary)
+	}
+}
+
+// Any encoded value decodes to itself, however the model wraps it
+func TestDecodeLLMJSONRoundTrip(t *testing.T) {
+	wrappers := []func(string) string{
+		func(s string) string { return s },
+		func(s string) string { return "```json\n" + s + "\n```" },
+		func(s string) string { return "```\n" + s + "\n```" },
+		func(s string) string { return "Sure, here it is:\n" + s + "\nDone." },
+	}
+
+	property := func(sample llmJSONSample, wrapper uint8) bool {
+		encoded, err := json.Marshal(sample)
+		if err != nil {
+			return false
+		}
+		var decoded llmJSONSample
+		if err := DecodeLLMJSON(wrappers[int(wrapper)%len(wrappers)](string(encoded)), &decoded); err != nil {
+			return false
+		}
+		// Empty slices are decoded as empty, nil ones as nil
+		return reflect.DeepEqual(decoded, sample) || (len(sample.Findings) == 0 && len(decoded.Findings) == 0 &&
+			decoded.Summary == sample.Summary && decoded.Count == sample.Count && decoded.Passed == sample.Passed)
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
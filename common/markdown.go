@@ -0,0 +1,235 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported git providers of the markdown renderers
+const (
+	ProviderGitHub    = "github"
+	ProviderBitbucket = "bitbucket"
+	ProviderGitLab    = "gitlab"
+	ProviderGitea     = "gitea"
+)
+
+// MarkdownRenderer formats the provider specific markdown of the posted comments
+type MarkdownRenderer interface {
+	// Capabilities returns the features of the provider the renderer formats for
+	Capabilities() Capabilities
+	// Collapsible wraps the content in a collapsible section, or under a bold title if not supported
+	Collapsible(summary, content string) string
+	// Suggestion formats a code suggestion replacing the original lines of the file
+	Suggestion(file, original, suggestion string) string
+	// Note formats an informational callout
+	Note(text string) string
+	// LineBreaks keeps the single line breaks of the text, like the lines of a poem
+	LineBreaks(text string) string
+	// Permalink links to the lines of a file at a commit of the repository, the whole file if line is 0
+	Permalink(repoURL, commitHash, file string, line, lastLine int) string
+	// Mention formats a mention of the user
+	Mention(user string) string
+	// Diagram formats a Mermaid diagram, or its outline if not supported
+	Diagram(diagram Diagram) string
+}
+
+// NewMarkdownRenderer returns the markdown renderer of the provider, defaults to GitHub flavored markdown
+func NewMarkdownRenderer(provider string) MarkdownRenderer {
+	return newMarkdownRenderer(provider, CapabilitiesOf(provider))
+}
+
+func newMarkdownRenderer(provider string, capabilities Capabilities) MarkdownRenderer {
+	var syntax markdownSyntax = githubMarkdown{}
+	switch provider {
+	case ProviderBitbucket:
+		syntax = bitbucketMarkdown{}
+	case ProviderGitLab:
+		syntax = gitlabMarkdown{}
+	case ProviderGitea:
+		syntax = giteaMarkdown{}
+	}
+	return degradingRenderer{syntax: syntax, capabilities: capabilities}
+}
+
+// markdownSyntax is the provider specific syntax of the features the provider supports
+type markdownSyntax interface {
+	suggestion(original, suggestion string) string
+	note(text string) string
+	lineBreaks(text string) string
+	permalink(repoURL, commitHash, file string, line, lastLine int) string
+	mention(user string) string
+}
+
+// degradingRenderer uses the syntax of the provider for its supported features,
+// and maps the unsupported ones to their nearest plain markdown equivalent
+type degradingRenderer struct {
+	syntax       markdownSyntax
+	capabilities Capabilities
+}
+
+func (r degradingRenderer) Capabilities() Capabilities {
+	return r.capabilities
+}
+
+func (r degradingRenderer) Collapsible(summary, content string) string {
+	if !r.capabilities.Collapsible {
+		return fmt.Sprintf("**%s**\n\n%s", summary, content)
+	}
+	return fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n\n</details>", summary, strings.TrimRight(content, "\n"))
+}
+
+func (r degradingRenderer) Suggestion(file, original, suggestion string) string {
+	if !r.capabilities.Suggestions {
+		// The code blocks are highlighted in the language of the file
+		language := CodeLanguage(file)
+		var builder strings.Builder
+		builder.WriteString("Replace with the following code:\n\n")
+		builder.WriteString("Current implementation\n")
+		builder.WriteString(fmt.Sprintf("```%s\n%s\n```", language, original))
+		builder.WriteString("\n\n")
+		builder.WriteString("Suggested changes\n")
+		builder.WriteString(fmt.Sprintf("```%s\n%s\n```", language, suggestion))
+		return builder.String()
+	}
+	return r.syntax.suggestion(original, suggestion)
+}
+
+func (r degradingRenderer) Note(text string) string {
+	if !r.capabilities.Callouts {
+		return "> ℹ️ Note  \n" + quote(text)
+	}
+	return r.syntax.note(text)
+}
+
+func (r degradingRenderer) LineBreaks(text string) string {
+	return r.syntax.lineBreaks(text)
+}
+
+func (r degradingRenderer) Permalink(repoURL, commitHash, file string, line, lastLine int) string {
+	return r.syntax.permalink(repoURL, commitHash, file, line, lastLine)
+}
+
+func (r degradingRenderer) Mention(user string) string {
+	return r.syntax.mention(user)
+}
+
+func (r degradingRenderer) Diagram(diagram Diagram) string {
+	if !r.capabilities.Diagrams {
+		return diagram.Outline()
+	}
+	return "```mermaid\n" + diagram.Source + "\n```\n"
+}
+
+// githubMarkdown is the syntax of GitHub flavored markdown
+type githubMarkdown struct{}
+
+func (githubMarkdown) suggestion(original, suggestion string) string {
+	return "```suggestion\n" + suggestion + "\n```"
+}
+
+func (githubMarkdown) note(text string) string {
+	return "> [!NOTE]\n" + quote(text)
+}
+
+func (githubMarkdown) lineBreaks(text string) string {
+	return text
+}
+
+func (githubMarkdown) permalink(repoURL, commitHash, file string, line, lastLine int) string {
+	anchor := ""
+	if line > 0 {
+		anchor = fmt.Sprintf("#L%d", line)
+		if lastLine > line {
+			anchor += fmt.Sprintf("-L%d", lastLine)
+		}
+	}
+	return fmt.Sprintf("%s/blob/%s/%s%s", strings.TrimSuffix(repoURL, "/"), commitHash, file, anchor)
+}
+
+func (githubMarkdown) mention(user string) string {
+	return "@" + user
+}
+
+// gitlabMarkdown is the syntax of GitLab flavored markdown
+type gitlabMarkdown struct{}
+
+func (gitlabMarkdown) suggestion(original, suggestion string) string {
+	// The range is relative to the commented line, multi-line comments cover the original lines
+	lines := strings.Count(original, "\n")
+	return fmt.Sprintf("```suggestion:-%d+0\n%s\n```", lines, suggestion)
+}
+
+func (gitlabMarkdown) note(text string) string {
+	return "> [!note]\n" + quote(text)
+}
+
+func (gitlabMarkdown) lineBreaks(text string) string {
+	return text
+}
+
+func (gitlabMarkdown) permalink(repoURL, commitHash, file string, line, lastLine int) string {
+	anchor := ""
+	if line > 0 {
+		anchor = fmt.Sprintf("#L%d", line)
+		if lastLine > line {
+			anchor += fmt.Sprintf("-%d", lastLine)
+		}
+	}
+	return fmt.Sprintf("%s/-/blob/%s/%s%s", strings.TrimSuffix(repoURL, "/"), commitHash, file, anchor)
+}
+
+func (gitlabMarkdown) mention(user string) string {
+	return "@" + user
+}
+
+// bitbucketMarkdown is the syntax of Bitbucket markdown, which has no HTML, callouts or suggestions.
+// The renderer degrades those features, so the syntax falls back to GitHub for them.
+type bitbucketMarkdown struct {
+	githubMarkdown
+}
+
+func (bitbucketMarkdown) lineBreaks(text string) string {
+	// Bitbucket joins single line breaks, trailing double spaces force a break
+	return strings.ReplaceAll(text, "\n", "  \n")
+}
+
+func (bitbucketMarkdown) permalink(repoURL, commitHash, file string, line, lastLine int) string {
+	anchor := ""
+	if line > 0 {
+		anchor = fmt.Sprintf("#lines-%d", line)
+		if lastLine > line {
+			anchor += fmt.Sprintf(":%d", lastLine)
+		}
+	}
+	return fmt.Sprintf("%s/src/%s/%s%s", strings.TrimSuffix(repoURL, "/"), commitHash, file, anchor)
+}
+
+func (bitbucketMarkdown) mention(user string) string {
+	// Bitbucket Cloud mentions users by account ID
+	return "@{" + user + "}"
+}
+
+// giteaMarkdown is the syntax of Gitea and Forgejo markdown, which has no suggestions.
+// The renderer degrades those, so the syntax falls back to GitHub for them.
+type giteaMarkdown struct {
+	githubMarkdown
+}
+
+func (giteaMarkdown) permalink(repoURL, commitHash, file string, line, lastLine int) string {
+	anchor := ""
+	if line > 0 {
+		anchor = fmt.Sprintf("#L%d", line)
+		if lastLine > line {
+			anchor += fmt.Sprintf("-L%d", lastLine)
+		}
+	}
+	return fmt.Sprintf("%s/src/commit/%s/%s%s", strings.TrimSuffix(repoURL, "/"), commitHash, file, anchor)
+}
+
+func quote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
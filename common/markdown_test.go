@@ -0,0 +1,114 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownRendererCollapsible(t *testing.T) {
+	github := NewMarkdownRenderer(ProviderGitHub).Collapsible("Title", "content\n")
+	if github != "<details>\n<summary>Title</summary>\n\ncontent\n\n</details>" {
+		t.Errorf("Unexpected GitHub collapsible: %q", github)
+	}
+
+	bitbucket := NewMarkdownRenderer(ProviderBitbucket).Collapsible("Title", "content")
+	if strings.Contains(bitbucket, "<details>") || !strings.HasPrefix(bitbucket, "**Title**") {
+		t.Errorf("Unexpected Bitbucket collapsible: %q", bitbucket)
+	}
+}
+
+func TestMarkdownRendererSuggestion(t *testing.T) {
+	tests := []struct {
+		provider string
+		expected string
+	}{
+		{ProviderGitHub, "```suggestion\nb := 2\n```"},
+		{ProviderGitLab, "```suggestion:-1+0\nb := 2\n```"},
+		{ProviderBitbucket, "Current implementation\n```go\na := 1\nb := 1\n```"},
+		{ProviderGitea, "Current implementation\n```go\na := 1\nb := 1\n```"},
+	}
+
+	for _, test := range tests {
+		suggestion := NewMarkdownRenderer(test.provider).Suggestion("main.go", "a := 1\nb := 1", "b := 2")
+		if !strings.Contains(suggestion, test.expected) {
+			t.Errorf("%s: expected suggestion to contain %q, got %q", test.provider, test.expected, suggestion)
+		}
+	}
+}
+
+func TestMarkdownRendererPermalink(t *testing.T) {
+	tests := []struct {
+		provider string
+		expected string
+	}{
+		{ProviderGitHub, "https://example.com/org/repo/blob/abc123/main.go#L10-L12"},
+		{ProviderGitLab, "https://example.com/org/repo/-/blob/abc123/main.go#L10-12"},
+		{ProviderBitbucket, "https://example.com/org/repo/src/abc123/main.go#lines-10:12"},
+		{ProviderGitea, "https://example.com/org/repo/src/commit/abc123/main.go#L10-L12"},
+	}
+
+	for _, test := range tests {
+		link := NewMarkdownRenderer(test.provider).Permalink("https://example.com/org/repo/", "abc123", "main.go", 10, 12)
+		if link != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.provider, test.expected, link)
+		}
+	}
+}
+
+func TestMarkdownRendererNote(t *testing.T) {
+	note := NewMarkdownRenderer(ProviderGitHub).Note("first\nsecond")
+	if note != "> [!NOTE]\n> first\n> second" {
+		t.Errorf("Unexpected GitHub note: %q", note)
+	}
+
+	if mention := NewMarkdownRenderer(ProviderBitbucket).Mention("account-id"); mention != "@{account-id}" {
+		t.Errorf("Unexpected Bitbucket mention: %q", mention)
+	}
+}
+
+func TestSummaryAuthorMention(t *testing.T) {
+	summary := Summary{Summary: "Changes", Author: "account-id", Stats: SummaryStats{Findings: 2}}
+
+	output := summary.String(ProviderBitbucket, WithDefaultSettings())
+	if !strings.Contains(output, "@{account-id} the review is done, check the 2 findings") {
+		t.Errorf("Expected the author to be mentioned, got:\n%s", output)
+	}
+
+	summary.Author = ""
+	if output := summary.String(ProviderBitbucket, WithDefaultSettings()); strings.Contains(output, "👋") {
+		t.Errorf("Expected no mention without an author, got:\n%s", output)
+	}
+}
+
+func TestMarkdownRendererDegradesUnsupportedFeatures(t *testing.T) {
+	renderer := newMarkdownRenderer(ProviderGitHub, Capabilities{})
+
+	if collapsible := renderer.Collapsible("Title", "content"); collapsible != "**Title**\n\ncontent" {
+		t.Errorf("Expected a bold title without collapsible support, got %q", collapsible)
+	}
+	if suggestion := renderer.Suggestion("", "a := 1", "a := 2"); strings.Contains(suggestion, "```suggestion") || !strings.Contains(suggestion, "Suggested changes\n```\na := 2\n```") {
+		t.Errorf("Expected the original and suggested code without suggestion support, got %q", suggestion)
+	}
+	if note := renderer.Note("text"); note != "> ℹ️ Note  \n> text" {
+		t.Errorf("Expected a plain quote without callout support, got %q", note)
+	}
+	// Features without an equivalent keep the syntax of the provider
+	if link := renderer.Permalink("https://example.com/org/repo", "abc123", "main.go", 10, 0); link != "https://example.com/org/repo/blob/abc123/main.go#L10" {
+		t.Errorf("Expected the GitHub permalink, got %q", link)
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	if !CapabilitiesOf(ProviderGitHub).Suggestions || CapabilitiesOf(ProviderBitbucket).Suggestions {
+		t.Error("Expected suggestions on GitHub only")
+	}
+	if !CapabilitiesOf(ProviderBitbucket).MentionAuthor || CapabilitiesOf(ProviderGitHub).MentionAuthor {
+		t.Error("Expected the author to be mentioned on Bitbucket only")
+	}
+	if CapabilitiesOf("unknown") != CapabilitiesOf(ProviderGitHub) {
+		t.Error("Expected unknown providers to default to GitHub")
+	}
+	if !NewMarkdownRenderer(ProviderGitLab).Capabilities().Collapsible {
+		t.Error("Expected the renderer to carry the capabilities of the provider")
+	}
+}
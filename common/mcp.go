@@ -0,0 +1,340 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/version"
+)
+
+// mcpProtocolVersion is the Model Context Protocol revision the client speaks
+const mcpProtocolVersion = "2025-03-26"
+
+// MCPServer is a Model Context Protocol server started over stdio, e.g. a Jira, Sentry or internal knowledge server.
+// The values of the environment are expanded, so secrets can be passed by the name of an environment variable,
+// the servers are only taken from the trusted settings for this reason.
+type MCPServer struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+	Timeout int               `yaml:"timeout"` // of a single request in seconds, 120 by default
+}
+
+// MCPTool is a tool of a connected MCP server, exposed to the LLM with the server name as prefix
+type MCPTool struct {
+	Name        string // Exposed name, e.g. sentry_search_issues
+	Description string
+	InputSchema map[string]any
+
+	client *MCPClient
+	tool   string // Name of the tool on the server
+}
+
+// Call calls the tool on its server, the text content of the result is returned to the LLM.
+// Like the commands, a failed tool returns its output instead of an error, as it usually describes the issue.
+func (t MCPTool) Call(argumentsJSON string) (string, error) {
+	arguments := map[string]any{}
+	if strings.TrimSpace(argumentsJSON) != "" {
+		if err := json.Unmarshal([]byte(argumentsJSON), &arguments); err != nil {
+			return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+		}
+	}
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := t.client.request("tools/call", map[string]any{"name": t.tool, "arguments": arguments}, &result); err != nil {
+		return "", err
+	}
+
+	var texts []string
+	for _, content := range result.Content {
+		if content.Type == "text" {
+			texts = append(texts, content.Text)
+		} else {
+			texts = append(texts, fmt.Sprintf("[%s content omitted]", content.Type))
+		}
+	}
+	output := truncateOutput(strings.Join(texts, "\n"))
+
+	if result.IsError {
+		return fmt.Sprintf("%s failed:\n%s", t.Name, output), nil
+	}
+	if strings.TrimSpace(output) == "" {
+		return fmt.Sprintf("%s succeeded without output", t.Name), nil
+	}
+	return output, nil
+}
+
+// MCPClient is a JSON-RPC connection to an MCP server running as a subprocess
+type MCPClient struct {
+	server  MCPServer
+	process *exec.Cmd
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan mcpMessage
+	closed  error
+}
+
+type mcpMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ConnectMCPServer starts the server and completes the initialization handshake
+func ConnectMCPServer(server MCPServer) (*MCPClient, error) {
+	if !customToolNamePattern.MatchString(server.Name) {
+		return nil, fmt.Errorf("invalid MCP server name %q, only letters, digits, _ and - are allowed", server.Name)
+	}
+	if server.Command == "" {
+		return nil, fmt.Errorf("command of the MCP server %s cannot be empty", server.Name)
+	}
+
+	process := exec.Command(server.Command, server.Args...)
+	// Servers are often third-party packages, they only get the configured variables besides PATH and HOME
+	process.Env = minimalEnvironment()
+	for name, value := range server.Env {
+		process.Env = append(process.Env, name+"="+os.ExpandEnv(value))
+	}
+	stdin, err := process.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := process.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := process.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server %s: %v", server.Name, err)
+	}
+
+	client := &MCPClient{server: server, process: process, stdin: stdin, pending: map[int]chan mcpMessage{}}
+	go client.readMessages(stdout)
+
+	params := map[string]any{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "bitrise-plugins-ai-reviewer", "version": version.Version},
+	}
+	if err := client.request("initialize", params, nil); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to initialize MCP server %s: %v", server.Name, err)
+	}
+	if err := client.send(mcpMessage{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	logger.Infof("Connected to MCP server %s", server.Name)
+	return client, nil
+}
+
+// Tools lists the tools of the server, prefixed with the server name
+func (c *MCPClient) Tools() ([]MCPTool, error) {
+	var tools []MCPTool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var result struct {
+			Tools []struct {
+				Name        string         `json:"name"`
+				Description string         `json:"description"`
+				InputSchema map[string]any `json:"inputSchema"`
+			} `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.request("tools/list", params, &result); err != nil {
+			return nil, fmt.Errorf("failed to list the tools of MCP server %s: %v", c.server.Name, err)
+		}
+
+		for _, tool := range result.Tools {
+			if tool.InputSchema == nil {
+				tool.InputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			tools = append(tools, MCPTool{
+				Name:        mcpToolName(c.server.Name, tool.Name),
+				Description: fmt.Sprintf("[%s] %s", c.server.Name, tool.Description),
+				InputSchema: tool.InputSchema,
+				client:      c,
+				tool:        tool.Name,
+			})
+		}
+
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// mcpToolName prefixes the name of the tool with the server, replacing the characters not accepted by the LLM providers
+func mcpToolName(server, tool string) string {
+	name := []rune(server + "_" + tool)
+	for i, r := range name {
+		if !customToolNamePattern.MatchString(string(r)) {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return string(name)
+}
+
+// Close stops the server
+func (c *MCPClient) Close() {
+	c.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		c.process.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.process.Process.Kill()
+	}
+}
+
+// request sends a request and decodes the result into the response, waiting at most for the timeout of the server
+func (c *MCPClient) request(method string, params any, response any) error {
+	c.mu.Lock()
+	if c.closed != nil {
+		c.mu.Unlock()
+		return c.closed
+	}
+	c.nextID++
+	id := c.nextID
+	answer := make(chan mcpMessage, 1)
+	c.pending[id] = answer
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(mcpMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+
+	timeout := c.server.Timeout
+	if timeout == 0 {
+		timeout = commandTimeout
+	}
+
+	select {
+	case message, ok := <-answer:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.closed
+		}
+		if message.Error != nil {
+			return fmt.Errorf("%s failed: %s (%d)", method, message.Error.Message, message.Error.Code)
+		}
+		if response == nil {
+			return nil
+		}
+		return json.Unmarshal(message.Result, response)
+	case <-time.After(time.Duration(timeout) * time.Second):
+		return fmt.Errorf("%s of MCP server %s timed out after %d seconds", method, c.server.Name, timeout)
+	}
+}
+
+func (c *MCPClient) send(message mcpMessage) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.stdin.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("failed to write to MCP server %s: %v", c.server.Name, err)
+	}
+	return nil
+}
+
+// readMessages routes the responses to the pending requests until the server exits, requests of the server are ignored
+func (c *MCPClient) readMessages(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message mcpMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			logger.Debugf("Ignoring invalid message of MCP server %s: %v", c.server.Name, err)
+			continue
+		}
+		if message.ID == nil || message.Method != "" {
+			continue
+		}
+
+		c.mu.Lock()
+		if answer, ok := c.pending[*message.ID]; ok {
+			answer <- message
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = errors.New("MCP server " + c.server.Name + " exited")
+	for id, answer := range c.pending {
+		close(answer)
+		delete(c.pending, id)
+	}
+}
+
+// ConnectMCPServers connects to the servers and collects their tools.
+// A server which fails to start is skipped with a warning, the review can go on without its tools.
+func ConnectMCPServers(servers []MCPServer) ([]*MCPClient, []MCPTool) {
+	var clients []*MCPClient
+	var tools []MCPTool
+	for _, server := range servers {
+		client, err := ConnectMCPServer(server)
+		if err != nil {
+			logger.Warnf("Skipping MCP server %s: %v", server.Name, err)
+			continue
+		}
+
+		serverTools, err := client.Tools()
+		if err != nil {
+			logger.Warnf("Skipping MCP server %s: %v", server.Name, err)
+			client.Close()
+			continue
+		}
+		logger.Infof("MCP server %s provides %d tools", server.Name, len(serverTools))
+		clients = append(clients, client)
+		tools = append(tools, serverTools...)
+	}
+	return clients, tools
+}
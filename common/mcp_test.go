@@ -0,0 +1,73 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeMCPServer answers the handshake, lists a tool and answers a call, the request ids are sequential
+const fakeMCPServer = `read request
+printf '%s\n' '{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-03-26","capabilities":{"tools":{}}}}'
+read notification
+read request
+printf '%s\n' '{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}'
+printf '%s\n' '{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"search.issues","description":"Searches the issues","inputSchema":{"type":"object","properties":{"query":{"type":"string"}}}}]}}'
+read request
+case "$request" in
+  *'"query":"crash"'*) printf '%s\n' '{"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"APP-12 Crash on login"},{"type":"image","data":"..."}]}}' ;;
+  *) printf '%s\n' '{"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"unexpected request"}],"isError":true}}' ;;
+esac
+read request`
+
+func TestMCPServerEnvironment(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "secret")
+	t.Setenv("SENTRY_AUTH_TOKEN", "sentry-secret")
+
+	// The server exits before the handshake if it sees the secrets of the CI, or misses its configured variable
+	script := `[ -z "$GITHUB_TOKEN" ] && [ -z "$SENTRY_AUTH_TOKEN" ] && [ "$SENTRY_TOKEN" = "sentry-secret" ] && [ -n "$PATH" ] || exit 1
+` + fakeMCPServer
+	client, err := ConnectMCPServer(MCPServer{Name: "sentry", Command: "sh", Args: []string{"-c", script}, Env: map[string]string{"SENTRY_TOKEN": "$SENTRY_AUTH_TOKEN"}, Timeout: 5})
+	if err != nil {
+		t.Fatalf("Expected the server to get only the minimal environment, got %v", err)
+	}
+	client.Close()
+}
+
+func TestMCPClient(t *testing.T) {
+	client, err := ConnectMCPServer(MCPServer{Name: "sentry", Command: "sh", Args: []string{"-c", fakeMCPServer}, Timeout: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer client.Close()
+
+	tools, err := client.Tools()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "sentry_search_issues" || tools[0].Description != "[sentry] Searches the issues" {
+		t.Fatalf("Expected the prefixed tool of the server, got %+v", tools)
+	}
+
+	output, err := tools[0].Call(`{"query":"crash"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "APP-12 Crash on login\n[image content omitted]"; output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	// The server exits after the call
+	if _, err := tools[0].Call(`{"query":"crash"}`); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("Expected an error after the server exited, got %v", err)
+	}
+}
+
+func TestConnectMCPServersSkipsFailingServers(t *testing.T) {
+	clients, tools := ConnectMCPServers([]MCPServer{
+		{Name: "missing", Command: "this-command-does-not-exist"},
+		{Name: "invalid name", Command: "sh"},
+	})
+	if len(clients) != 0 || len(tools) != 0 {
+		t.Errorf("Expected the failing servers to be skipped, got %d clients and %d tools", len(clients), len(tools))
+	}
+}
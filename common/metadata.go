@@ -0,0 +1,30 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fallbackModel is the fallback model which produced the review, empty if the configured model did
+var fallbackModel string
+
+// SetFallbackModel records the fallback model which produced the review
+func SetFallbackModel(model string) {
+	fallbackModel = model
+}
+
+// commentMetadata returns the hidden comment recording the prompt variant and the fallback model in the posted comments
+func commentMetadata() string {
+	var fields []string
+	if promptVariant.Name != "" {
+		fields = append(fields, "prompt-variant="+promptVariant.Name)
+	}
+	if fallbackModel != "" {
+		fields = append(fields, "model="+fallbackModel)
+	}
+
+	if len(fields) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\n<!-- bitrise-plugin-ai-reviewer: %s -->", strings.Join(fields, " "))
+}
@@ -0,0 +1,177 @@
+package common
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Export formats of the review metrics
+const (
+	MetricsFormatCSV  = "csv"
+	MetricsFormatJSON = "json"
+)
+
+// MetricsPeriod are the aggregated review metrics of a period
+type MetricsPeriod struct {
+	Date              string         `json:"date,omitempty"`           // Day of the period in YYYY-MM-DD format, empty for the total
+	PromptVariant     string         `json:"prompt_variant,omitempty"` // Prompt variant of the runs, set for the per variant metrics
+	Runs              int            `json:"runs"`
+	PullRequests      int            `json:"pull_requests"`
+	Findings          map[string]int `json:"findings"`
+	CommentsPosted    int            `json:"comments_posted"`
+	CommentsDismissed int            `json:"comments_dismissed"`
+	AcceptanceRate    float64        `json:"acceptance_rate"` // Share of the posted comments not dismissed by the team
+	AvgLatencySeconds float64        `json:"avg_latency_seconds"`
+	EstimatedCostUSD  float64        `json:"estimated_cost_usd"`
+}
+
+// ReviewMetrics are the review metrics aggregated from the saved run reports
+type ReviewMetrics struct {
+	Total    MetricsPeriod   `json:"total"`
+	Daily    []MetricsPeriod `json:"daily"`
+	Variants []MetricsPeriod `json:"variants,omitempty"` // Totals per prompt variant, to compare their acceptance rates
+}
+
+// LoadRunReports reads the run reports saved in the directory and its subdirectories
+func LoadRunReports(dir string) ([]RunReportData, error) {
+	var reports []RunReportData
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), RunReportFilePrefix) || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read run report %s: %w", path, err)
+		}
+		var report RunReportData
+		if err := json.Unmarshal(content, &report); err != nil {
+			return fmt.Errorf("failed to parse run report %s: %w", path, err)
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].StartedAt.Before(reports[j].StartedAt)
+	})
+	return reports, nil
+}
+
+// AggregateMetrics aggregates the run reports into daily and total review metrics
+func AggregateMetrics(reports []RunReportData) ReviewMetrics {
+	byDate := map[string][]RunReportData{}
+	byVariant := map[string][]RunReportData{}
+	for _, report := range reports {
+		date := report.StartedAt.UTC().Format("2006-01-02")
+		byDate[date] = append(byDate[date], report)
+		if report.PromptVariant != "" {
+			byVariant[report.PromptVariant] = append(byVariant[report.PromptVariant], report)
+		}
+	}
+
+	metrics := ReviewMetrics{Total: aggregatePeriod(reports)}
+	for _, date := range sortedKeys(byDate) {
+		period := aggregatePeriod(byDate[date])
+		period.Date = date
+		metrics.Daily = append(metrics.Daily, period)
+	}
+	for _, variant := range sortedKeys(byVariant) {
+		period := aggregatePeriod(byVariant[variant])
+		period.PromptVariant = variant
+		metrics.Variants = append(metrics.Variants, period)
+	}
+	return metrics
+}
+
+// aggregatePeriod sums up the reports of a period
+func aggregatePeriod(reports []RunReportData) MetricsPeriod {
+	period := MetricsPeriod{Runs: len(reports), Findings: map[string]int{}}
+
+	// Dismissals are counted on the pull request, the latest count of each pull request is used
+	dismissed := map[string]int{}
+	var latency float64
+	for _, report := range reports {
+		for category, count := range report.Findings {
+			period.Findings[category] += count
+		}
+		period.CommentsPosted += report.CommentsPosted
+		period.EstimatedCostUSD += report.EstimatedCostUSD
+		latency += report.DurationSeconds
+
+		pullRequest := fmt.Sprintf("%s#%d", report.Repository, report.PullRequest)
+		dismissed[pullRequest] = max(dismissed[pullRequest], report.CommentsDismissed)
+	}
+
+	period.PullRequests = len(dismissed)
+	for _, count := range dismissed {
+		period.CommentsDismissed += count
+	}
+	if period.Runs > 0 {
+		period.AvgLatencySeconds = latency / float64(period.Runs)
+	}
+	if period.CommentsPosted > 0 {
+		accepted := max(period.CommentsPosted-period.CommentsDismissed, 0)
+		period.AcceptanceRate = float64(accepted) / float64(period.CommentsPosted)
+	}
+	return period
+}
+
+// Write writes the metrics in the format, CSV contains a row per day for dashboards
+func (m ReviewMetrics) Write(w io.Writer, format string) error {
+	switch format {
+	case MetricsFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+	case MetricsFormatCSV:
+		return m.writeCSV(w)
+	}
+	return fmt.Errorf("unsupported metrics format: %s", format)
+}
+
+func (m ReviewMetrics) writeCSV(w io.Writer) error {
+	categories := sortedKeys(m.Total.Findings)
+
+	header := []string{"date", "runs", "pull_requests", "findings"}
+	for _, category := range categories {
+		header = append(header, "findings_"+category)
+	}
+	header = append(header, "comments_posted", "comments_dismissed", "acceptance_rate", "avg_latency_seconds", "estimated_cost_usd")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, period := range m.Daily {
+		row := []string{period.Date, strconv.Itoa(period.Runs), strconv.Itoa(period.PullRequests), strconv.Itoa(sumCounts(period.Findings))}
+		for _, category := range categories {
+			row = append(row, strconv.Itoa(period.Findings[category]))
+		}
+		row = append(row,
+			strconv.Itoa(period.CommentsPosted),
+			strconv.Itoa(period.CommentsDismissed),
+			strconv.FormatFloat(period.AcceptanceRate, 'f', 4, 64),
+			strconv.FormatFloat(period.AvgLatencySeconds, 'f', 1, 64),
+			strconv.FormatFloat(period.EstimatedCostUSD, 'f', 4, 64),
+		)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
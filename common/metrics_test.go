@@ -0,0 +1,65 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregateMetrics(t *testing.T) {
+	day := time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+	reports := []RunReportData{
+		{StartedAt: day, Repository: "org/repo", PullRequest: 1, DurationSeconds: 30, Findings: map[string]int{"bug": 2}, CommentsPosted: 2},
+		{StartedAt: day.Add(time.Hour), Repository: "org/repo", PullRequest: 1, DurationSeconds: 50, Findings: map[string]int{"bug": 1, "security": 1}, CommentsPosted: 2, CommentsDismissed: 1},
+		{StartedAt: day.Add(24 * time.Hour), Repository: "org/repo", PullRequest: 2, DurationSeconds: 20, CommentsPosted: 1},
+	}
+
+	metrics := AggregateMetrics(reports)
+	if len(metrics.Daily) != 2 {
+		t.Fatalf("Expected 2 days, got %d", len(metrics.Daily))
+	}
+
+	first := metrics.Daily[0]
+	if first.Date != "2025-06-02" || first.Runs != 2 || first.PullRequests != 1 {
+		t.Errorf("Unexpected first day: %+v", first)
+	}
+	if first.Findings["bug"] != 3 || first.CommentsDismissed != 1 || first.AcceptanceRate != 0.75 || first.AvgLatencySeconds != 40 {
+		t.Errorf("Unexpected first day metrics: %+v", first)
+	}
+	if metrics.Total.Runs != 3 || metrics.Total.PullRequests != 2 || metrics.Total.CommentsPosted != 5 {
+		t.Errorf("Unexpected totals: %+v", metrics.Total)
+	}
+
+	var output bytes.Buffer
+	if err := metrics.Write(&output, MetricsFormatCSV); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if lines[0] != "date,runs,pull_requests,findings,findings_bug,findings_security,comments_posted,comments_dismissed,acceptance_rate,avg_latency_seconds,estimated_cost_usd" {
+		t.Errorf("Unexpected CSV header: %s", lines[0])
+	}
+	if lines[1] != "2025-06-02,2,1,4,3,1,4,1,0.7500,40.0,0.0000" {
+		t.Errorf("Unexpected CSV row: %s", lines[1])
+	}
+}
+
+func TestRunReportSaveAndLoad(t *testing.T) {
+	report := NewRunReport()
+	report.SetPullRequest("org/repo", 7)
+	report.AddFinding(CategoryBug)
+	report.CommentsPosted(1)
+
+	dir := t.TempDir()
+	if _, err := report.Save(dir); err != nil {
+		t.Fatalf("Failed to save report: %v", err)
+	}
+
+	reports, err := LoadRunReports(dir)
+	if err != nil {
+		t.Fatalf("Failed to load reports: %v", err)
+	}
+	if len(reports) != 1 || reports[0].PullRequest != 7 || reports[0].Findings[CategoryBug] != 1 {
+		t.Errorf("Unexpected loaded reports: %+v", reports)
+	}
+}
@@ -0,0 +1,212 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MigrationFinding is a risky operation found in a database migration
+type MigrationFinding struct {
+	File  string
+	Line  string // The added line, empty for file level findings
+	Issue string
+}
+
+// migrationCheck matches a risky operation on an added line of a migration.
+// The first submatch of the regex, if any, is formatted into the issue.
+type migrationCheck struct {
+	regex *regexp.Regexp
+	skip  *regexp.Regexp // Lines matching skip are safe variants of the operation
+	issue string
+}
+
+var (
+	golangMigrateRegex = regexp.MustCompile(`^\d+_[\w\-]+\.(up|down)\.sql$`)
+	foreignKeyRegex    = regexp.MustCompile(`(?i)\bforeign\s+key\s*\(\s*"?(\w+)`)
+	inlineRefRegex     = regexp.MustCompile(`(?i)^\s*(?:alter\s+table\s+\S+\s+add\s+(?:column\s+)?)?"?(\w+)"?\s+\w+.*\breferences\b`)
+)
+
+// migrationChecks are the risky operations by migration language
+var migrationChecks = map[string][]migrationCheck{
+	".sql": {
+		{regex: regexp.MustCompile(`(?i)\bdrop\s+(table|column|schema|database)\b`), issue: "destructive operation: dropping a %s loses data, make sure it is no longer used and backed up"},
+		{regex: regexp.MustCompile(`(?i)\btruncate\b`), issue: "destructive operation: truncate deletes all rows"},
+		{regex: regexp.MustCompile(`(?i)\bdelete\s+from\s+\w+\s*;`), issue: "destructive operation: delete without a where clause deletes all rows"},
+		{regex: regexp.MustCompile(`(?i)\balter\s+column\s+\w+\s+(?:set\s+data\s+)?type\b`), issue: "column type change rewrites the table and may lose data"},
+		{regex: regexp.MustCompile(`(?i)\brename\s+(?:column\s+)?\w*\s*to\b`), issue: "renaming breaks the application version running during the deployment"},
+		{regex: regexp.MustCompile(`(?i)\bcreate\s+(?:unique\s+)?index\b`), skip: regexp.MustCompile(`(?i)\bconcurrently\b`), issue: "non-concurrent index creation locks the table for writes, use create index concurrently"},
+		{regex: regexp.MustCompile(`(?i)\badd\s+column\b.*\bnot\s+null\b`), skip: regexp.MustCompile(`(?i)\bdefault\b`), issue: "adding a not null column without a default fails if the table has rows"},
+	},
+	".rb": {
+		{regex: regexp.MustCompile(`\b(drop_table|remove_column|remove_columns|remove_reference)\b`), issue: "destructive operation: %s loses data, make sure it is no longer used and backed up"},
+		{regex: regexp.MustCompile(`\b(rename_column|rename_table)\b`), issue: "%s breaks the application version running during the deployment"},
+		{regex: regexp.MustCompile(`\bchange_column\b`), issue: "column type change rewrites the table and may lose data"},
+		{regex: regexp.MustCompile(`\badd_index\b`), skip: regexp.MustCompile(`algorithm:\s*:concurrently`), issue: "non-concurrent index creation locks the table for writes, use algorithm: :concurrently"},
+		{regex: regexp.MustCompile(`\b(?:add_reference|t\.references)\b.*\bindex:\s*false`), issue: "missing index: the reference is created without an index, joins and cascading deletes will be slow"},
+		{regex: regexp.MustCompile(`\bIrreversibleMigration\b`), issue: "irreversible migration: the migration can not be rolled back"},
+	},
+}
+
+// IsMigrationFile returns true for SQL, goose, golang-migrate and ActiveRecord migration files
+func IsMigrationFile(path string) bool {
+	base := filepath.Base(path)
+	if golangMigrateRegex.MatchString(base) {
+		return true
+	}
+
+	dir := filepath.ToSlash(filepath.Dir(path))
+	if !strings.Contains(dir, "migration") && !strings.HasSuffix(dir, "db/migrate") {
+		return false
+	}
+
+	switch filepath.Ext(base) {
+	case ".sql", ".rb":
+		return true
+	case ".go":
+		// goose Go migrations
+		return !strings.HasSuffix(base, "_test.go")
+	}
+	return false
+}
+
+// ChangedMigrationFiles returns the migration files changed in the diff
+func ChangedMigrationFiles(diff string) []string {
+	var files []string
+	for line := range strings.SplitSeq(diff, "\n") {
+		if file, ok := strings.CutPrefix(line, "+++ b/"); ok && IsMigrationFile(file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// AnalyzeMigrations checks the migration files added or changed in the diff for risky operations
+func AnalyzeMigrations(diff string) []MigrationFinding {
+	var files []string
+	addedLines := map[string][]string{}
+
+	file := ""
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if IsMigrationFile(file) {
+				files = append(files, file)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "+") && IsMigrationFile(file) {
+			addedLines[file] = append(addedLines[file], line[1:])
+		}
+	}
+
+	var findings []MigrationFinding
+	for _, file := range files {
+		findings = append(findings, checkMigrationLines(file, addedLines[file])...)
+		findings = append(findings, checkDownMigration(file, addedLines)...)
+	}
+	return findings
+}
+
+// checkMigrationLines checks the added lines of a migration for destructive operations, locking and missing indexes
+func checkMigrationLines(file string, lines []string) []MigrationFinding {
+	ext := filepath.Ext(file)
+	if ext == ".go" {
+		// goose Go migrations embed SQL in strings
+		ext = ".sql"
+	}
+	content := strings.Join(lines, "\n")
+
+	var findings []MigrationFinding
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+
+		for _, check := range migrationChecks[ext] {
+			match := check.regex.FindStringSubmatch(line)
+			if match == nil || (check.skip != nil && check.skip.MatchString(line)) {
+				continue
+			}
+
+			issue := check.issue
+			if len(match) > 1 {
+				issue = fmt.Sprintf(issue, strings.ToLower(match[1]))
+			}
+			findings = append(findings, MigrationFinding{File: file, Line: line, Issue: issue})
+		}
+
+		// New foreign keys need an index on the referencing column
+		if ext == ".sql" {
+			if column := foreignKeyColumn(line); column != "" && !hasIndexOn(content, column) {
+				findings = append(findings, MigrationFinding{File: file, Line: line,
+					Issue: fmt.Sprintf("missing index: the foreign key column %s is not indexed, joins and cascading deletes will be slow", column)})
+			}
+		}
+	}
+
+	return findings
+}
+
+// checkDownMigration flags migrations which can not be rolled back.
+// The rollback of an edited migration may be out of the diff, it is looked up in the checked out repository.
+func checkDownMigration(file string, addedLines map[string][]string) []MigrationFinding {
+	content := strings.Join(addedLines[file], "\n")
+	base := filepath.Base(file)
+
+	switch {
+	case strings.HasSuffix(base, ".up.sql") && golangMigrateRegex.MatchString(base):
+		downFile := strings.TrimSuffix(file, ".up.sql") + ".down.sql"
+		if _, ok := addedLines[downFile]; ok {
+			return nil
+		}
+		if _, err := os.Stat(downFile); err != nil {
+			return []MigrationFinding{{File: file, Issue: "irreversible migration: no down migration was added for the up migration"}}
+		}
+	case strings.HasSuffix(base, ".down.sql") && golangMigrateRegex.MatchString(base):
+		if strings.TrimSpace(stripSQLComments(content)) == "" {
+			return []MigrationFinding{{File: file, Issue: "irreversible migration: the down migration is empty"}}
+		}
+	case strings.Contains(content, "+goose Up"):
+		if fileContent, err := os.ReadFile(file); err == nil {
+			content = string(fileContent)
+		}
+		parts := strings.SplitN(content, "+goose Down", 2)
+		if len(parts) < 2 || strings.TrimSpace(stripSQLComments(parts[1])) == "" {
+			return []MigrationFinding{{File: file, Issue: "irreversible migration: the goose Down section is missing or empty"}}
+		}
+	}
+
+	return nil
+}
+
+// foreignKeyColumn returns the referencing column of a foreign key constraint or inline reference
+func foreignKeyColumn(line string) string {
+	if match := foreignKeyRegex.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	if match := inlineRefRegex.FindStringSubmatch(line); match != nil && !strings.EqualFold(match[1], "constraint") {
+		return match[1]
+	}
+	return ""
+}
+
+// hasIndexOn checks if the migration creates an index starting with the column
+func hasIndexOn(content, column string) bool {
+	regex := regexp.MustCompile(`(?i)\bcreate\s+(?:unique\s+)?index\b[^;]*\(\s*"?` + regexp.QuoteMeta(column) + `\b`)
+	return regex.MatchString(content)
+}
+
+func stripSQLComments(content string) string {
+	var lines []string
+	for line := range strings.SplitSeq(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
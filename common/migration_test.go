@@ -0,0 +1,94 @@
+package common
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIsMigrationFile(t *testing.T) {
+	tests := map[string]bool{
+		"db/migrations/000001_create_users.up.sql": true,
+		"000002_add_index.down.sql":                true,
+		"db/migrate/20240101000000_add_users.rb":   true,
+		"internal/migrations/00003_backfill.go":    true,
+		"internal/migrations/migrations_test.go":   false,
+		"queries/users.sql":                        false,
+		"app/models/user.rb":                       false,
+	}
+	for path, expected := range tests {
+		if IsMigrationFile(path) != expected {
+			t.Errorf("IsMigrationFile(%q) = %v, expected %v", path, !expected, expected)
+		}
+	}
+}
+
+func TestAnalyzeMigrations(t *testing.T) {
+	diff := `--- /dev/null
++++ b/db/migrations/000003_orders.up.sql
+@@ -0,0 +1,6 @@
++CREATE TABLE orders (
++  id bigserial PRIMARY KEY,
++  user_id bigint NOT NULL REFERENCES users(id)
++);
++CREATE INDEX idx_orders_created ON orders (created_at);
++ALTER TABLE users DROP COLUMN legacy_name;
+--- /dev/null
++++ b/db/migrate/20240101000000_cleanup.rb
+@@ -0,0 +1,4 @@
++class Cleanup < ActiveRecord::Migration[7.1]
++  def change
++    add_index :orders, :status, algorithm: :concurrently
++    rename_column :users, :name, :full_name
+`
+
+	findings := AnalyzeMigrations(diff)
+	var issues []string
+	for _, finding := range findings {
+		issues = append(issues, finding.File+": "+finding.Issue)
+	}
+	output := strings.Join(issues, "\n")
+
+	expected := []string{
+		"000003_orders.up.sql: missing index: the foreign key column user_id is not indexed",
+		"000003_orders.up.sql: non-concurrent index creation",
+		"000003_orders.up.sql: destructive operation: dropping a column",
+		"000003_orders.up.sql: irreversible migration: no down migration",
+		"20240101000000_cleanup.rb: rename_column breaks",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected findings to contain %q, got:\n%s", e, output)
+		}
+	}
+	if len(findings) != len(expected) {
+		t.Errorf("Expected %d findings, got %d:\n%s", len(expected), len(findings), output)
+	}
+}
+
+func TestAnalyzeMigrationsExistingDownMigration(t *testing.T) {
+	// The down migration of the edited up migration is in the repository, out of the diff
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("db/migrations", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("db/migrations/000004_tags.down.sql", []byte("DROP TABLE tags;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := `--- a/db/migrations/000004_tags.up.sql
++++ b/db/migrations/000004_tags.up.sql
+@@ -1,1 +1,1 @@
+-CREATE TABLE tags (id bigserial PRIMARY KEY);
++CREATE TABLE tags (id bigserial PRIMARY KEY, name text);
+--- a/db/migrations/000005_labels.up.sql
++++ b/db/migrations/000005_labels.up.sql
+@@ -1,1 +1,1 @@
+-CREATE TABLE labels (id bigserial PRIMARY KEY);
++CREATE TABLE labels (id bigserial PRIMARY KEY, name text);
+`
+	findings := AnalyzeMigrations(diff)
+	if len(findings) != 1 || findings[0].File != "db/migrations/000005_labels.up.sql" {
+		t.Errorf("Expected only the migration without a down migration to be flagged, got %+v", findings)
+	}
+}
@@ -0,0 +1,128 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// rootCAs holds the system roots extended with the custom CA bundle, nil when no bundle is configured
+var rootCAs *x509.CertPool
+
+// SetCABundle loads a PEM encoded CA bundle trusted by all HTTP clients in addition to the system roots
+func SetCABundle(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle %s: %w", path, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		logger.Warnf("Failed to load system certificates, only trusting the CA bundle: %v", err)
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no valid PEM encoded certificates found in CA bundle %s", path)
+	}
+
+	logger.Infof("Using CA bundle: %s", path)
+	rootCAs = pool
+	return nil
+}
+
+// LogProxySettings logs the proxy configuration picked up from the environment
+func LogProxySettings() {
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if proxy := os.Getenv(name); proxy != "" {
+			if redacted, ok := redactProxy(proxy); ok {
+				logger.Infof("Using proxy from %s: %s", name, redacted)
+			} else {
+				logger.Infof("Using proxy from %s", name)
+			}
+			break
+		}
+	}
+	for _, name := range []string{"NO_PROXY", "no_proxy"} {
+		if noProxy := os.Getenv(name); noProxy != "" {
+			logger.Infof("Bypassing proxy for: %s", noProxy)
+			break
+		}
+	}
+}
+
+// redactProxy returns the proxy URL with its password redacted, false if it can't be parsed and may leak the credentials
+func redactProxy(proxy string) (string, bool) {
+	// Like net/http, a proxy without a scheme is an HTTP proxy
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	return u.Redacted(), true
+}
+
+// configureTransport applies the proxy and CA bundle settings to the transport
+func configureTransport(transport http.RoundTripper) http.RoundTripper {
+	if t, ok := transport.(*http.Transport); ok {
+		t.Proxy = http.ProxyFromEnvironment
+		if rootCAs != nil {
+			t.TLSClientConfig = &tls.Config{
+				RootCAs:    rootCAs,
+				MinVersion: tls.VersionTLS12,
+			}
+		}
+	}
+
+	return &networkErrorTransport{base: transport}
+}
+
+// networkErrorTransport replaces generic TLS and proxy failures with actionable error messages
+type networkErrorTransport struct {
+	base http.RoundTripper
+}
+
+func (t *networkErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	Report().APICall(req.URL.Host)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, describeNetworkError(req.URL.Host, err)
+	}
+	return resp, nil
+}
+
+// describeNetworkError wraps TLS and proxy errors with a remediation hint
+func describeNetworkError(host string, err error) error {
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var verificationErr *tls.CertificateVerificationError
+
+	switch {
+	case errors.As(err, &hostnameErr):
+		return fmt.Errorf("TLS verification failed for %s: the certificate is not valid for this host, check the configured URL: %w", host, err)
+	case errors.As(err, &invalidErr):
+		return fmt.Errorf("TLS verification failed for %s: the certificate is expired or not valid yet: %w", host, err)
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &verificationErr):
+		return fmt.Errorf("TLS verification failed for %s: the certificate is signed by an unknown authority, "+
+			"if you are behind a proxy or use a private CA provide it with --ca-bundle: %w", host, err)
+	case strings.Contains(err.Error(), "proxyconnect"):
+		return fmt.Errorf("failed to connect to %s through the proxy, check the HTTPS_PROXY and NO_PROXY environment variables: %w", host, err)
+	}
+
+	return err
+}
@@ -0,0 +1,241 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIVersionRegex matches the version field of OpenAPI 3 and Swagger 2 specs, in YAML or JSON
+var openAPIVersionRegex = regexp.MustCompile(`(?m)^\s*"?(openapi|swagger)"?\s*:\s*"?\d`)
+
+// httpMethods are the operations of an OpenAPI path item
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// isOpenAPISpec returns true if the content is an OpenAPI or Swagger spec
+func isOpenAPISpec(content string) bool {
+	return openAPIVersionRegex.MatchString(content)
+}
+
+// DiffOpenAPI returns the changes of the endpoints and schemas between the base and head versions of an OpenAPI spec
+func DiffOpenAPI(base, head string) ([]ContractChange, error) {
+	var baseSpec, headSpec map[string]any
+	if err := yaml.Unmarshal([]byte(base), &baseSpec); err != nil {
+		return nil, fmt.Errorf("failed to parse the base spec: %w", err)
+	}
+	if err := yaml.Unmarshal([]byte(head), &headSpec); err != nil {
+		return nil, fmt.Errorf("failed to parse the head spec: %w", err)
+	}
+
+	var changes []ContractChange
+	add := func(element, description string, breaking bool) {
+		changes = append(changes, ContractChange{Element: element, Description: description, Breaking: breaking})
+	}
+
+	baseOperations, headOperations := openAPIOperations(baseSpec), openAPIOperations(headSpec)
+	for _, key := range sortedKeys(baseOperations) {
+		headOperation, ok := headOperations[key]
+		if !ok {
+			add(key, "endpoint removed", true)
+			continue
+		}
+		diffOpenAPIOperation(key, baseOperations[key], headOperation, add)
+	}
+	for _, key := range sortedKeys(headOperations) {
+		if _, ok := baseOperations[key]; !ok {
+			add(key, "endpoint added", false)
+		}
+	}
+
+	baseSchemas, headSchemas := openAPISchemas(baseSpec), openAPISchemas(headSpec)
+	for _, name := range sortedKeys(baseSchemas) {
+		headSchema, ok := headSchemas[name]
+		if !ok {
+			add(name, "schema removed", true)
+			continue
+		}
+		diffOpenAPISchema(name, asMap(baseSchemas[name]), asMap(headSchema), add)
+	}
+	for _, name := range sortedKeys(headSchemas) {
+		if _, ok := baseSchemas[name]; !ok {
+			add(name, "schema added", false)
+		}
+	}
+
+	return changes, nil
+}
+
+// openAPIOperations returns the operations of the spec by "METHOD path"
+func openAPIOperations(spec map[string]any) map[string]map[string]any {
+	operations := map[string]map[string]any{}
+	for path, item := range asMap(spec["paths"]) {
+		pathItem := asMap(item)
+		for _, method := range httpMethods {
+			if operation, ok := pathItem[method]; ok {
+				operations[strings.ToUpper(method)+" "+path] = asMap(operation)
+			}
+		}
+	}
+	return operations
+}
+
+// openAPISchemas returns the reusable schemas of OpenAPI 3 or the definitions of Swagger 2 specs
+func openAPISchemas(spec map[string]any) map[string]any {
+	if schemas := asMap(asMap(spec["components"])["schemas"]); len(schemas) > 0 {
+		return schemas
+	}
+	return asMap(spec["definitions"])
+}
+
+// diffOpenAPIOperation compares the parameters, request body and responses of an operation
+func diffOpenAPIOperation(key string, base, head map[string]any, add func(element, description string, breaking bool)) {
+	baseParams, headParams := openAPIParameters(base), openAPIParameters(head)
+	for _, name := range sortedKeys(baseParams) {
+		headParam, ok := headParams[name]
+		switch {
+		case !ok:
+			add(key, fmt.Sprintf("parameter %s removed", name), false)
+		case !isRequired(baseParams[name]) && isRequired(headParam):
+			add(key, fmt.Sprintf("parameter %s became required", name), true)
+		}
+	}
+	for _, name := range sortedKeys(headParams) {
+		if _, ok := baseParams[name]; ok {
+			continue
+		}
+		if isRequired(headParams[name]) {
+			add(key, fmt.Sprintf("required parameter %s added", name), true)
+		} else {
+			add(key, fmt.Sprintf("optional parameter %s added", name), false)
+		}
+	}
+
+	if !isRequired(asMap(base["requestBody"])) && isRequired(asMap(head["requestBody"])) {
+		add(key, "request body became required", true)
+	}
+
+	baseResponses, headResponses := asMap(base["responses"]), asMap(head["responses"])
+	for _, status := range sortedKeys(baseResponses) {
+		if _, ok := headResponses[status]; !ok {
+			add(key, fmt.Sprintf("response %s removed", status), true)
+		}
+	}
+	for _, status := range sortedKeys(headResponses) {
+		if _, ok := baseResponses[status]; !ok {
+			add(key, fmt.Sprintf("response %s added", status), false)
+		}
+	}
+}
+
+// openAPIParameters returns the parameters of an operation by "location:name"
+func openAPIParameters(operation map[string]any) map[string]map[string]any {
+	parameters := map[string]map[string]any{}
+	list, _ := operation["parameters"].([]any)
+	for _, item := range list {
+		parameter := asMap(item)
+		if name, ok := parameter["name"].(string); ok {
+			parameters[fmt.Sprintf("%v:%s", parameter["in"], name)] = parameter
+		}
+	}
+	return parameters
+}
+
+// diffOpenAPISchema compares the properties, required properties and enum values of a schema
+func diffOpenAPISchema(name string, base, head map[string]any, add func(element, description string, breaking bool)) {
+	baseRequired, headRequired := stringList(base["required"]), stringList(head["required"])
+
+	baseProperties, headProperties := asMap(base["properties"]), asMap(head["properties"])
+	for _, property := range sortedKeys(baseProperties) {
+		headProperty, ok := headProperties[property]
+		switch {
+		case !ok:
+			add(name, fmt.Sprintf("property %s removed", property), true)
+		case schemaType(baseProperties[property]) != schemaType(headProperty):
+			add(name, fmt.Sprintf("property %s changed type from %s to %s", property, schemaType(baseProperties[property]), schemaType(headProperty)), true)
+		case !slices.Contains(baseRequired, property) && slices.Contains(headRequired, property):
+			add(name, fmt.Sprintf("property %s became required", property), true)
+		default:
+			for _, value := range removedEnumValues(asMap(baseProperties[property]), asMap(headProperty)) {
+				add(name, fmt.Sprintf("enum value %s of property %s removed", value, property), true)
+			}
+		}
+	}
+	for _, property := range sortedKeys(headProperties) {
+		if _, ok := baseProperties[property]; ok {
+			continue
+		}
+		if slices.Contains(headRequired, property) {
+			add(name, fmt.Sprintf("required property %s added", property), true)
+		} else {
+			add(name, fmt.Sprintf("optional property %s added", property), false)
+		}
+	}
+
+	for _, value := range removedEnumValues(base, head) {
+		add(name, fmt.Sprintf("enum value %s removed", value), true)
+	}
+}
+
+// removedEnumValues returns the enum values of the base schema missing from the head schema
+func removedEnumValues(base, head map[string]any) []string {
+	headEnum := stringList(head["enum"])
+	if len(headEnum) == 0 {
+		return nil
+	}
+
+	var removed []string
+	for _, value := range stringList(base["enum"]) {
+		if !slices.Contains(headEnum, value) {
+			removed = append(removed, value)
+		}
+	}
+	return removed
+}
+
+// schemaType returns the type or the referenced schema of a property
+func schemaType(property any) string {
+	schema := asMap(property)
+	if ref, ok := schema["$ref"].(string); ok {
+		return ref[strings.LastIndex(ref, "/")+1:]
+	}
+	typeName, ok := schema["type"].(string)
+	switch {
+	case !ok:
+		return "any"
+	case typeName == "array":
+		return "array of " + schemaType(schema["items"])
+	}
+	return typeName
+}
+
+func isRequired(value map[string]any) bool {
+	required, _ := value["required"].(bool)
+	return required
+}
+
+// asMap converts a decoded YAML or JSON object to a map with string keys, YAML keys like status codes may be integers
+func asMap(value any) map[string]any {
+	switch typed := value.(type) {
+	case map[string]any:
+		return typed
+	case map[any]any:
+		converted := make(map[string]any, len(typed))
+		for key, item := range typed {
+			converted[fmt.Sprintf("%v", key)] = item
+		}
+		return converted
+	}
+	return nil
+}
+
+func stringList(value any) []string {
+	list, _ := value.([]any)
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, fmt.Sprintf("%v", item))
+	}
+	return values
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// Output variables exported for the next steps of the Bitrise workflow
+const (
+	OutputForkPR     = "AI_REVIEWER_FORK_PR"     // true if the pull request is opened from a fork
+	OutputReportOnly = "AI_REVIEWER_REPORT_ONLY" // true if the review was not posted on the pull request
+	OutputNoChanges  = "AI_REVIEWER_NO_CHANGES"  // true if the diff had no reviewable changes and the review was skipped
+	OutputDeferred   = "AI_REVIEWER_DEFERRED"    // true if the review was queued because the LLM provider is overloaded
+)
+
+// ExportOutput exports the variable for the next steps with envman, it is skipped outside of Bitrise builds
+func ExportOutput(key, value string) error {
+	if _, err := exec.LookPath("envman"); err != nil {
+		logger.Debugf("Skipping output %s=%s, envman is not available", key, value)
+		return nil
+	}
+
+	if output, err := exec.Command("envman", "add", "--key", key, "--value", value).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to export %s: %v: %s", key, err, output)
+	}
+	logger.Debugf("Exported output %s=%s", key, value)
+	return nil
+}
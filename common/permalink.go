@@ -0,0 +1,22 @@
+package common
+
+import "fmt"
+
+// Permalinks links the files and lines referenced in the comments to the reviewed commit
+type Permalinks struct {
+	RepoURL    string
+	CommitHash string
+}
+
+// Enabled returns true if the repository and the reviewed commit are known
+func (p Permalinks) Enabled() bool {
+	return p.RepoURL != "" && p.CommitHash != ""
+}
+
+// Link returns the text as a markdown link to the lines of the file, or the text itself if links are not enabled
+func (p Permalinks) Link(renderer MarkdownRenderer, text, file string, line, lastLine int) string {
+	if !p.Enabled() || file == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, renderer.Permalink(p.RepoURL, p.CommitHash, file, line, lastLine))
+}
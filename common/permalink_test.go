@@ -0,0 +1,30 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummaryWalkthroughPermalinks(t *testing.T) {
+	summary := Summary{
+		Walkthrough: []Walkthrough{{Files: "cmd/main.go, README.md", Summary: "Adds the entry point"}},
+		Permalinks:  Permalinks{RepoURL: "https://github.com/org/repo", CommitHash: "abc123"},
+	}
+
+	output := summary.String(ProviderGitHub, WithDefaultSettings())
+	for _, expected := range []string{
+		"[cmd/main.go](https://github.com/org/repo/blob/abc123/cmd/main.go)",
+		"[README.md](https://github.com/org/repo/blob/abc123/README.md)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPermalinksDisabled(t *testing.T) {
+	link := Permalinks{}.Link(NewMarkdownRenderer(ProviderGitHub), "`10`", "main.go", 10, 0)
+	if link != "`10`" {
+		t.Errorf("Expected plain text without a repository, got %q", link)
+	}
+}
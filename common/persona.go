@@ -0,0 +1,78 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+)
+
+// PersonaMatch is a reviewer persona with the changed files it reviews
+type PersonaMatch struct {
+	Persona Persona
+	Files   []string
+}
+
+// ValidatePersonas checks that every persona has a name and at least one path
+func ValidatePersonas(personas []Persona) error {
+	seen := map[string]bool{}
+	for idx, persona := range personas {
+		if persona.Name == "" {
+			return fmt.Errorf("persona #%d has no name", idx+1)
+		}
+		if len(persona.Paths) == 0 {
+			return fmt.Errorf("persona %s has no paths", persona.Name)
+		}
+		if seen[persona.Name] {
+			return fmt.Errorf("persona %s is configured more than once", persona.Name)
+		}
+		seen[persona.Name] = true
+	}
+	return nil
+}
+
+// MatchPersonas assigns the changed files of the diff to the personas by their path globs.
+// A file is reviewed by the first persona matching it, files without a matching persona get the default voice.
+func MatchPersonas(diff string, personas []Persona) []PersonaMatch {
+	if len(personas) == 0 {
+		return nil
+	}
+
+	files := map[string][]string{}
+	for _, file := range diffparse.Parse(diff) {
+		path := file.Path()
+		for _, persona := range personas {
+			if persona.Matches(path) {
+				files[persona.Name] = append(files[persona.Name], path)
+				break
+			}
+		}
+	}
+
+	var matches []PersonaMatch
+	for _, persona := range personas {
+		if len(files[persona.Name]) > 0 {
+			matches = append(matches, PersonaMatch{Persona: persona, Files: files[persona.Name]})
+		}
+	}
+	return matches
+}
+
+// Matches checks if the file is in the area of the persona
+func (p Persona) Matches(file string) bool {
+	for _, pattern := range p.Paths {
+		if MatchGlob(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonaSummary lists which persona reviewed which files, for the summary
+func PersonaSummary(matches []PersonaMatch) string {
+	var builder strings.Builder
+	for _, match := range matches {
+		builder.WriteString(fmt.Sprintf("- **%s**: %s\n", match.Persona.Name, strings.Join(match.Files, ", ")))
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
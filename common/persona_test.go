@@ -0,0 +1,78 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+const personaDiff = `diff --git a/web/src/App.tsx b/web/src/App.tsx
+--- a/web/src/App.tsx
++++ b/web/src/App.tsx
+@@ -1 +1 @@
+-old
++new
+diff --git a/infra/main.tf b/infra/main.tf
+--- a/infra/main.tf
++++ b/infra/main.tf
+@@ -1 +1 @@
+-old
++new
+diff --git a/web/infra/deploy.yml b/web/infra/deploy.yml
+--- a/web/infra/deploy.yml
++++ b/web/infra/deploy.yml
+@@ -1 +1 @@
+-old
++new
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-old
++new
+`
+
+func TestMatchPersonas(t *testing.T) {
+	personas := []Persona{
+		{Name: "Frontend", Paths: []string{"web/**"}, Tone: "Friendly"},
+		{Name: "Infrastructure", Paths: []string{"infra/**", "**/*.yml"}},
+		{Name: "Backend", Paths: []string{"server/**"}},
+	}
+
+	matches := MatchPersonas(personaDiff, personas)
+	if len(matches) != 2 {
+		t.Fatalf("Expected the personas of the changed areas only, got %+v", matches)
+	}
+	// The first matching persona reviews the file
+	if matches[0].Persona.Name != "Frontend" || strings.Join(matches[0].Files, ",") != "web/src/App.tsx,web/infra/deploy.yml" {
+		t.Errorf("Unexpected frontend files: %+v", matches[0])
+	}
+	if matches[1].Persona.Name != "Infrastructure" || strings.Join(matches[1].Files, ",") != "infra/main.tf" {
+		t.Errorf("Unexpected infrastructure files: %+v", matches[1])
+	}
+
+	summary := PersonaSummary(matches)
+	if summary != "- **Frontend**: web/src/App.tsx, web/infra/deploy.yml\n- **Infrastructure**: infra/main.tf" {
+		t.Errorf("Unexpected persona summary:\n%s", summary)
+	}
+	if output := (Summary{Personas: summary}).String(ProviderGitHub, WithDefaultSettings()); !strings.Contains(output, "## Reviewers\n"+summary) {
+		t.Errorf("Expected the personas in the summary, got:\n%s", output)
+	}
+}
+
+func TestValidatePersonas(t *testing.T) {
+	tests := []struct {
+		personas []Persona
+		valid    bool
+	}{
+		{nil, true},
+		{[]Persona{{Name: "Frontend", Paths: []string{"web/**"}}}, true},
+		{[]Persona{{Paths: []string{"web/**"}}}, false},
+		{[]Persona{{Name: "Frontend"}}, false},
+		{[]Persona{{Name: "Frontend", Paths: []string{"web/**"}}, {Name: "Frontend", Paths: []string{"app/**"}}}, false},
+	}
+	for idx, test := range tests {
+		if err := ValidatePersonas(test.personas); (err == nil) != test.valid {
+			t.Errorf("Case %d: expected valid %v, got %v", idx, test.valid, err)
+		}
+	}
+}
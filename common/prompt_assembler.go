@@ -0,0 +1,112 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// PromptPriority orders the prompt sections, the sections of the lowest priority are trimmed first to fit the context window
+type PromptPriority int
+
+const (
+	PromptPriorityRepoContext  PromptPriority = iota // Context of the repository, e.g. the languages or the accepted suggestions
+	PromptPriorityComments                           // Comments of the previous reviews and the answers of the author
+	PromptPriorityPullRequest                        // Details of the pull request: title, description and commits
+	PromptPriorityDiff                               // Changes of the pull request
+	PromptPriorityInstructions                       // Task and review rules, never trimmed
+)
+
+const (
+	// minSectionTokens is the size of the smallest trimmed section kept, shorter ones are left out
+	minSectionTokens = 200
+	// truncationMarkerTokens is reserved for the marker of a truncated section
+	truncationMarkerTokens = 30
+)
+
+// promptSection is a part of the user prompt
+type promptSection struct {
+	name     string
+	priority PromptPriority
+	content  string
+}
+
+// PromptAssembler assembles the user prompt from sections within the budget of the context window of the model.
+// Sections are kept in the order they were added, when they don't fit the lowest priority ones are trimmed first.
+type PromptAssembler struct {
+	budget   int // in tokens
+	sections []promptSection
+}
+
+// NewPromptAssembler creates an assembler for the budget in tokens, the system prompt is sent with the user prompt so it takes from the budget
+func NewPromptAssembler(budget int, systemPrompt string) *PromptAssembler {
+	return &PromptAssembler{budget: budget - EstimateTokens(systemPrompt)}
+}
+
+// Add adds a section, empty sections are skipped
+func (a *PromptAssembler) Add(name string, priority PromptPriority, content string) {
+	if content == "" {
+		return
+	}
+	a.sections = append(a.sections, promptSection{name: name, priority: priority, content: content})
+}
+
+// String assembles the prompt. Sections over the budget are cut at a line with a truncation marker, or left out with
+// an omission marker, so the model knows the content is incomplete.
+func (a *PromptAssembler) String() string {
+	over := -a.budget
+	for _, section := range a.sections {
+		over += EstimateTokens(section.content)
+	}
+
+	// The latest section of the same priority is trimmed first, earlier ones are usually more general
+	order := make([]int, len(a.sections))
+	for idx := range order {
+		order[idx] = len(order) - 1 - idx
+	}
+	slices.SortStableFunc(order, func(x, y int) int {
+		return int(a.sections[x].priority) - int(a.sections[y].priority)
+	})
+
+	contents := make([]string, len(a.sections))
+	for idx, section := range a.sections {
+		contents[idx] = section.content
+	}
+	for _, idx := range order {
+		section := a.sections[idx]
+		if over <= 0 || section.priority == PromptPriorityInstructions {
+			break
+		}
+		tokens := EstimateTokens(section.content)
+		trimmed := trimSection(section, tokens-over)
+		over -= tokens - EstimateTokens(trimmed)
+		contents[idx] = trimmed
+	}
+	if over > 0 {
+		logger.Warnf("The prompt exceeds the budget of %d tokens by %d tokens after trimming the sections", a.budget, over)
+	}
+	return strings.Join(contents, "")
+}
+
+// trimSection cuts the section to the tokens at a line break, or leaves it out if only a small part would be kept
+func trimSection(section promptSection, tokens int) string {
+	runes := []rune(section.content)
+	keep := (tokens - truncationMarkerTokens) * CharsPerToken
+	if keep >= len(runes) {
+		return section.content
+	}
+	if tokens < minSectionTokens {
+		logger.Warnf("Leaving out the %s section of the prompt to fit the context window of the model", section.name)
+		return fmt.Sprintf("\n[The %s section is omitted to fit the context window of the model]\n", section.name)
+	}
+
+	kept := string(runes[:keep])
+	if cut := strings.LastIndex(kept, "\n"); cut > 0 {
+		kept = kept[:cut]
+	}
+	omitted := len(runes) - len([]rune(kept))
+	logger.Warnf("Truncating the %s section of the prompt by %d characters to fit the context window of the model", section.name, omitted)
+	return kept + fmt.Sprintf("\n[... %d characters of the %s section are truncated to fit the context window of the model]\n", omitted, section.name)
+}
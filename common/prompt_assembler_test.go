@@ -0,0 +1,60 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptAssembler(t *testing.T) {
+	diff := strings.Repeat("+ changed line of the pull request\n", 400) // 14000 characters
+	context := strings.Repeat("x", 2000)
+	task := "Review the pull request.\n"
+
+	// Everything fits the budget
+	assembler := NewPromptAssembler(10000, "system")
+	assembler.Add("task", PromptPriorityInstructions, task)
+	assembler.Add("languages", PromptPriorityRepoContext, context)
+	assembler.Add("diff", PromptPriorityDiff, diff)
+	assembler.Add("empty", PromptPriorityComments, "")
+	if got := assembler.String(); got != task+context+diff {
+		t.Errorf("Expected the sections in order, got %d characters", len(got))
+	}
+
+	// The repository context is left out first, then the diff is truncated at a line
+	assembler = NewPromptAssembler(2500, "")
+	assembler.Add("task", PromptPriorityInstructions, task)
+	assembler.Add("languages", PromptPriorityRepoContext, context)
+	assembler.Add("diff", PromptPriorityDiff, diff)
+	got := assembler.String()
+	if !strings.HasPrefix(got, task+"\n[The languages section is omitted to fit the context window of the model]\n") {
+		t.Errorf("Expected the task and the omitted context first, got %q", got[:200])
+	}
+	if !strings.Contains(got, "characters of the diff section are truncated to fit the context window of the model]") {
+		t.Errorf("Expected the truncation marker of the diff, got %q", got[len(got)-200:])
+	}
+	if kept, _, _ := strings.Cut(got, "\n[... "); !strings.HasSuffix(kept, "+ changed line of the pull request") {
+		t.Errorf("Expected the diff to be cut at a line break, got %q", kept[len(kept)-50:])
+	}
+	if tokens := EstimateTokens(got); tokens > 2500 {
+		t.Errorf("Expected the prompt to fit the budget, got %d tokens", tokens)
+	}
+
+	// Instructions are never trimmed
+	assembler = NewPromptAssembler(10, "")
+	assembler.Add("task", PromptPriorityInstructions, strings.Repeat("rule\n", 100))
+	if got := assembler.String(); got != strings.Repeat("rule\n", 100) {
+		t.Errorf("Expected the instructions to be kept, got %q", got)
+	}
+}
+
+func TestPromptBudget(t *testing.T) {
+	if got := PromptBudget("gpt-4o", 0); got != 64000 {
+		t.Errorf("Expected half of the context window of gpt-4o, got %d", got)
+	}
+	if got := PromptBudget("custom-model", 0); got != defaultContextWindow/2 {
+		t.Errorf("Expected the default context window for unknown models, got %d", got)
+	}
+	if got := PromptBudget("gpt-4o", 32000); got != 16000 {
+		t.Errorf("Expected the configured context window to override, got %d", got)
+	}
+}
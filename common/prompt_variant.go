@@ -0,0 +1,52 @@
+package common
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// promptVariant is the prompt variant used by the run
+var promptVariant PromptVariant
+
+// SelectPromptVariant returns the prompt variant of the run.
+// The override selects a variant by name, otherwise the variants are assigned by their traffic weights.
+// The assignment is stable for the seed, so every run of a pull request uses the same variant.
+func SelectPromptVariant(variants []PromptVariant, override, seed string) (PromptVariant, error) {
+	if override != "" {
+		for _, variant := range variants {
+			if variant.Name == override {
+				return variant, nil
+			}
+		}
+		return PromptVariant{}, fmt.Errorf("unknown prompt variant: %s", override)
+	}
+
+	total := 0
+	for _, variant := range variants {
+		total += max(variant.Weight, 0)
+	}
+	if total == 0 {
+		return PromptVariant{}, nil
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(seed))
+	bucket := int(hash.Sum32() % uint32(total))
+	for _, variant := range variants {
+		bucket -= max(variant.Weight, 0)
+		if bucket < 0 {
+			return variant, nil
+		}
+	}
+	return PromptVariant{}, nil
+}
+
+// SetPromptVariant sets the prompt variant used by the run
+func SetPromptVariant(variant PromptVariant) {
+	promptVariant = variant
+}
+
+// ActivePromptVariant returns the prompt variant used by the run, empty if no variants are configured
+func ActivePromptVariant() PromptVariant {
+	return promptVariant
+}
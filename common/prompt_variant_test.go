@@ -0,0 +1,68 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSelectPromptVariant(t *testing.T) {
+	variants := []PromptVariant{
+		{Name: "control", Weight: 50},
+		{Name: "terse", Weight: 50, Instructions: "Be terse."},
+		{Name: "manual", Weight: 0},
+	}
+
+	counts := map[string]int{}
+	for i := range 200 {
+		seed := fmt.Sprintf("org/repo#%d", i)
+		variant, err := SelectPromptVariant(variants, "", seed)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		counts[variant.Name]++
+
+		again, _ := SelectPromptVariant(variants, "", seed)
+		if again.Name != variant.Name {
+			t.Errorf("Expected a stable assignment for %s, got %s and %s", seed, variant.Name, again.Name)
+		}
+	}
+	if counts["control"] == 0 || counts["terse"] == 0 || counts["manual"] != 0 {
+		t.Errorf("Unexpected variant distribution: %v", counts)
+	}
+
+	variant, err := SelectPromptVariant(variants, "manual", "org/repo#1")
+	if err != nil || variant.Name != "manual" {
+		t.Errorf("Expected the override to select the manual variant, got %q, %v", variant.Name, err)
+	}
+
+	if _, err := SelectPromptVariant(variants, "missing", "org/repo#1"); err == nil {
+		t.Error("Expected an error for an unknown variant")
+	}
+
+	if variant, _ := SelectPromptVariant(nil, "", "org/repo#1"); variant.Name != "" {
+		t.Errorf("Expected no variant without configuration, got %q", variant.Name)
+	}
+}
+
+func TestPromptVariantMetadata(t *testing.T) {
+	SetPromptVariant(PromptVariant{Name: "terse"})
+	defer SetPromptVariant(PromptVariant{})
+
+	output := Summary{Summary: "Adds retries"}.String(ProviderGitHub, WithDefaultSettings())
+	if !strings.HasSuffix(output, "<!-- bitrise-plugin-ai-reviewer: prompt-variant=terse -->") {
+		t.Errorf("Expected the prompt variant in the summary metadata, got:\n%s", output)
+	}
+}
+
+func TestFallbackModelMetadata(t *testing.T) {
+	SetPromptVariant(PromptVariant{Name: "terse"})
+	SetFallbackModel("gpt-4.1-mini")
+	defer SetPromptVariant(PromptVariant{})
+	defer SetFallbackModel("")
+
+	output := LineLevel{File: "main.go", LineNumber: 3, Body: "Missing error check"}.String(ProviderGitHub, nil, "abc123")
+	if !strings.HasSuffix(output, "<!-- bitrise-plugin-ai-reviewer: prompt-variant=terse model=gpt-4.1-mini -->") {
+		t.Errorf("Expected the fallback model in the comment metadata, got:\n%s", output)
+	}
+}
@@ -0,0 +1,245 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	protoCommentRegex  = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	protoBlockRegex    = regexp.MustCompile(`^(message|enum|service|oneof)\s+(\w+)$`)
+	protoFieldRegex    = regexp.MustCompile(`^(?:optional\s+|required\s+|repeated\s+)?(map\s*<[^>]+>|[\w.]+)\s+(\w+)\s*=\s*(\d+)`)
+	protoEnumValue     = regexp.MustCompile(`^(\w+)\s*=\s*(-?\d+)`)
+	protoRPCRegex      = regexp.MustCompile(`^rpc\s+(\w+)\s*\(\s*(stream\s+)?([\w.]+)\s*\)\s*returns\s*\(\s*(stream\s+)?([\w.]+)\s*\)`)
+	protoReservedRegex = regexp.MustCompile(`^reserved\s+(.+)$`)
+)
+
+// protoField is a field of a proto message
+type protoField struct {
+	Name string
+	Type string
+}
+
+// protoSchema are the messages, enums and RPCs declared in a proto file
+type protoSchema struct {
+	messages map[string]map[int]protoField // Message name to the fields by number
+	reserved map[string]map[int]bool       // Message name to the reserved field numbers
+	enums    map[string]map[string]int     // Enum name to the values by name
+	rpcs     map[string]string             // Service.Method to the request and response types
+}
+
+// protoScope is an open block while parsing a proto file
+type protoScope struct {
+	kind string
+	name string // Fully qualified name of messages and enums, the service name for services
+}
+
+// parseProto parses the declarations of a proto file, options and nested blocks other than declarations are ignored
+func parseProto(content string) protoSchema {
+	schema := protoSchema{
+		messages: map[string]map[int]protoField{},
+		reserved: map[string]map[int]bool{},
+		enums:    map[string]map[string]int{},
+		rpcs:     map[string]string{},
+	}
+
+	content = protoCommentRegex.ReplaceAllString(content, "")
+	var scopes []protoScope
+	current := func() protoScope {
+		if len(scopes) == 0 {
+			return protoScope{}
+		}
+		return scopes[len(scopes)-1]
+	}
+	// message returns the name of the message the fields of the current scope belong to, oneofs belong to their message
+	message := func() string {
+		scope := current()
+		if scope.kind == "oneof" && len(scopes) > 1 {
+			scope = scopes[len(scopes)-2]
+		}
+		if scope.kind != "message" {
+			return ""
+		}
+		return scope.name
+	}
+
+	statement := strings.Builder{}
+	for _, char := range content {
+		switch char {
+		case '{':
+			text := strings.Join(strings.Fields(statement.String()), " ")
+			statement.Reset()
+
+			scope := protoScope{}
+			if match := protoBlockRegex.FindStringSubmatch(text); match != nil {
+				scope = protoScope{kind: match[1], name: match[2]}
+				if parent := current(); (match[1] == "message" || match[1] == "enum") && parent.kind == "message" {
+					scope.name = parent.name + "." + match[2]
+				}
+				switch match[1] {
+				case "message":
+					schema.messages[scope.name] = map[int]protoField{}
+				case "enum":
+					schema.enums[scope.name] = map[string]int{}
+				}
+			} else if current().kind == "service" {
+				// RPCs with options are blocks instead of statements
+				parseProtoStatement(&schema, current(), "", text)
+			}
+			scopes = append(scopes, scope)
+		case '}':
+			statement.Reset()
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case ';':
+			text := strings.Join(strings.Fields(statement.String()), " ")
+			statement.Reset()
+			parseProtoStatement(&schema, current(), message(), text)
+		default:
+			statement.WriteRune(char)
+		}
+	}
+
+	return schema
+}
+
+// parseProtoStatement records a field, reserved numbers, enum value or RPC declared in the scope
+func parseProtoStatement(schema *protoSchema, scope protoScope, message, text string) {
+	switch {
+	case message != "":
+		if match := protoReservedRegex.FindStringSubmatch(text); match != nil {
+			if schema.reserved[message] == nil {
+				schema.reserved[message] = map[int]bool{}
+			}
+			for number := range parseReservedNumbers(match[1]) {
+				schema.reserved[message][number] = true
+			}
+		} else if match := protoFieldRegex.FindStringSubmatch(text); match != nil {
+			number, _ := strconv.Atoi(match[3])
+			schema.messages[message][number] = protoField{Name: match[2], Type: strings.ReplaceAll(match[1], " ", "")}
+		}
+	case scope.kind == "enum":
+		if match := protoEnumValue.FindStringSubmatch(text); match != nil {
+			value, _ := strconv.Atoi(match[2])
+			schema.enums[scope.name][match[1]] = value
+		}
+	case scope.kind == "service":
+		if match := protoRPCRegex.FindStringSubmatch(text); match != nil {
+			schema.rpcs[scope.name+"."+match[1]] = fmt.Sprintf("(%s%s) returns (%s%s)", match[2], match[3], match[4], match[5])
+		}
+	}
+}
+
+// parseReservedNumbers parses the field numbers of a reserved statement like "2, 15, 9 to 11", reserved names are ignored
+func parseReservedNumbers(text string) map[int]bool {
+	numbers := map[int]bool{}
+	for part := range strings.SplitSeq(text, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), " to ")
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(to); err != nil {
+				// "max" is the upper bound of the field numbers, cap the range to keep the map small
+				end = start + 1000
+			}
+		}
+		for number := start; number <= end; number++ {
+			numbers[number] = true
+		}
+	}
+	return numbers
+}
+
+// DiffProto returns the changes of the messages, enums and RPCs between the base and head versions of a proto file
+func DiffProto(base, head string) []ContractChange {
+	baseSchema, headSchema := parseProto(base), parseProto(head)
+
+	var changes []ContractChange
+	add := func(element, description string, breaking bool) {
+		changes = append(changes, ContractChange{Element: element, Description: description, Breaking: breaking})
+	}
+
+	for _, name := range sortedKeys(baseSchema.messages) {
+		headFields, ok := headSchema.messages[name]
+		if !ok {
+			add(name, "message removed", true)
+			continue
+		}
+		baseFields := baseSchema.messages[name]
+		for _, number := range sortedNumbers(baseFields) {
+			baseField := baseFields[number]
+			headField, ok := headFields[number]
+			switch {
+			case !ok && headSchema.reserved[name][number]:
+				add(name, fmt.Sprintf("field %s = %d removed and reserved", baseField.Name, number), false)
+			case !ok:
+				add(name, fmt.Sprintf("field %s = %d removed without reserving the number", baseField.Name, number), true)
+			case baseField.Type != headField.Type:
+				add(name, fmt.Sprintf("field %s = %d changed type from %s to %s", baseField.Name, number, baseField.Type, headField.Type), true)
+			case baseField.Name != headField.Name:
+				add(name, fmt.Sprintf("field %d renamed from %s to %s, breaking JSON clients", number, baseField.Name, headField.Name), true)
+			}
+		}
+		for _, number := range sortedNumbers(headFields) {
+			if _, ok := baseFields[number]; !ok {
+				add(name, fmt.Sprintf("field %s = %d added", headFields[number].Name, number), false)
+			}
+		}
+	}
+	for _, name := range sortedKeys(headSchema.messages) {
+		if _, ok := baseSchema.messages[name]; !ok {
+			add(name, "message added", false)
+		}
+	}
+
+	for _, name := range sortedKeys(baseSchema.enums) {
+		headValues, ok := headSchema.enums[name]
+		if !ok {
+			add(name, "enum removed", true)
+			continue
+		}
+		for _, value := range sortedKeys(baseSchema.enums[name]) {
+			if _, ok := headValues[value]; !ok {
+				add(name, fmt.Sprintf("enum value %s removed", value), true)
+			}
+		}
+		for _, value := range sortedKeys(headValues) {
+			if _, ok := baseSchema.enums[name][value]; !ok {
+				add(name, fmt.Sprintf("enum value %s added", value), false)
+			}
+		}
+	}
+
+	for _, rpc := range sortedKeys(baseSchema.rpcs) {
+		headSignature, ok := headSchema.rpcs[rpc]
+		switch {
+		case !ok:
+			add(rpc, "RPC removed", true)
+		case headSignature != baseSchema.rpcs[rpc]:
+			add(rpc, fmt.Sprintf("RPC signature changed from %s to %s", baseSchema.rpcs[rpc], headSignature), true)
+		}
+	}
+	for _, rpc := range sortedKeys(headSchema.rpcs) {
+		if _, ok := baseSchema.rpcs[rpc]; !ok {
+			add(rpc, "RPC added", false)
+		}
+	}
+
+	return changes
+}
+
+func sortedNumbers[T any](values map[int]T) []int {
+	numbers := make([]int, 0, len(values))
+	for number := range values {
+		numbers = append(numbers, number)
+	}
+	slices.Sort(numbers)
+	return numbers
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// notCommittedAuthor is the author of the uncommitted lines in git blame
+const notCommittedAuthor = "Not Committed Yet"
+
+var (
+	emailRegex = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// blameAuthorRegex matches the author of a git blame -l line: hash, optional path, then the author before the date
+	blameAuthorRegex = regexp.MustCompile(`(?m)^(\^?[0-9a-f]{7,40}(?:\s+\S+)?\s+\()(.+?)(\s+\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} [+-]\d{4}\s+\d+\))`)
+	pseudonymRegex   = regexp.MustCompile(`\bAuthor (\d+)\b|\bauthor(\d+)@users\.invalid\b`)
+)
+
+// Pseudonymizer replaces the names and emails of the authors with pseudonyms before they reach the LLM,
+// for organizations treating developer identity as personal data. The mapping is only kept in memory for the run,
+// the posted comments get the real names back.
+type Pseudonymizer struct {
+	mu         sync.Mutex
+	enabled    bool
+	pseudonyms map[string]int // Pseudonym number of each identity, by lowercase name or email
+	identities []string       // Identity of each pseudonym number, the first one is unused
+}
+
+var pseudonymizer = NewPseudonymizer(false)
+
+// NewPseudonymizer creates a pseudonymizer, a disabled one returns everything unchanged
+func NewPseudonymizer(enabled bool) *Pseudonymizer {
+	return &Pseudonymizer{enabled: enabled, pseudonyms: map[string]int{}, identities: []string{""}}
+}
+
+// SetPseudonymizeAuthors enables the pseudonymization of the authors for the run
+func SetPseudonymizeAuthors(enabled bool) {
+	pseudonymizer = NewPseudonymizer(enabled)
+}
+
+// Pseudonyms returns the pseudonymizer of the current run
+func Pseudonyms() *Pseudonymizer {
+	return pseudonymizer
+}
+
+// Enabled returns true if the authors are pseudonymized
+func (p *Pseudonymizer) Enabled() bool {
+	return p.enabled
+}
+
+// Name returns the pseudonym of the author name or email, the same identity always gets the same pseudonym.
+// Bot accounts are not personal data, they are kept so the LLM can tell automated changes apart.
+func (p *Pseudonymizer) Name(identity string) string {
+	identity = strings.TrimSpace(identity)
+	if !p.enabled || identity == "" || identity == notCommittedAuthor || isBotAccount(identity) {
+		return identity
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := strings.ToLower(identity)
+	number, ok := p.pseudonyms[key]
+	if !ok {
+		number = len(p.identities)
+		p.pseudonyms[key] = number
+		p.identities = append(p.identities, identity)
+	}
+	if strings.Contains(identity, "@") {
+		return fmt.Sprintf("author%d@users.invalid", number)
+	}
+	return fmt.Sprintf("Author %d", number)
+}
+
+// Text replaces the emails and the known author names in free text, like a description or a commit message
+func (p *Pseudonymizer) Text(text string) string {
+	if !p.enabled || text == "" {
+		return text
+	}
+	text = emailRegex.ReplaceAllStringFunc(text, p.Name)
+
+	p.mu.Lock()
+	names := make([]string, 0, len(p.identities))
+	for _, identity := range p.identities[1:] {
+		if !strings.Contains(identity, "@") {
+			names = append(names, identity)
+		}
+	}
+	p.mu.Unlock()
+
+	// Longer names first, so a name containing another one is replaced as a whole
+	slices.SortFunc(names, func(a, b string) int { return len(b) - len(a) })
+	for _, name := range names {
+		pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(name) + `\b`)
+		text = pattern.ReplaceAllLiteralString(text, p.Name(name))
+	}
+	return text
+}
+
+// PullRequest pseudonymizes the authors of the pull request and its commits, and the identities in their texts.
+// The account ID is kept, it is only used to mention the author in the posted summary.
+func (p *Pseudonymizer) PullRequest(pr PullRequest) PullRequest {
+	if !p.enabled {
+		return pr
+	}
+	pr.Author = p.Name(pr.Author)
+	commits := make([]Commit, len(pr.Commits))
+	for idx, commit := range pr.Commits {
+		commit.Author = p.Name(commit.Author)
+		commits[idx] = commit
+	}
+	for idx := range commits {
+		commits[idx].Message = p.Text(commits[idx].Message)
+	}
+	pr.Commits = commits
+	pr.Title = p.Text(pr.Title)
+	pr.Body = p.Text(pr.Body)
+	return pr
+}
+
+// Blame pseudonymizes the authors of the git blame output
+func (p *Pseudonymizer) Blame(output string) string {
+	if !p.enabled {
+		return output
+	}
+	return blameAuthorRegex.ReplaceAllStringFunc(output, func(line string) string {
+		match := blameAuthorRegex.FindStringSubmatch(line)
+		return match[1] + p.Name(match[2]) + match[3]
+	})
+}
+
+// Restore replaces the pseudonyms with the real identities, for the content posted on the pull request
+func (p *Pseudonymizer) Restore(text string) string {
+	if !p.enabled || text == "" {
+		return text
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return pseudonymRegex.ReplaceAllStringFunc(text, func(pseudonym string) string {
+		match := pseudonymRegex.FindStringSubmatch(pseudonym)
+		number, err := strconv.Atoi(match[1] + match[2])
+		if err != nil || number <= 0 || number >= len(p.identities) {
+			return pseudonym
+		}
+		return p.identities[number]
+	})
+}
+
+// isBotAccount returns true for the accounts of automations, like dependabot[bot] or the dependency update tools
+func isBotAccount(identity string) bool {
+	identity = strings.ToLower(identity)
+	if strings.HasSuffix(identity, "[bot]") {
+		return true
+	}
+	for _, bot := range dependencyBots {
+		if strings.Contains(identity, bot) {
+			return true
+		}
+	}
+	return false
+}
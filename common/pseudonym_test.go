@@ -0,0 +1,81 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPseudonymizerName(t *testing.T) {
+	p := NewPseudonymizer(true)
+	if got := p.Name("Jane Doe"); got != "Author 1" {
+		t.Errorf("Expected Author 1, got %s", got)
+	}
+	if got := p.Name("jane@example.com"); got != "author2@users.invalid" {
+		t.Errorf("Expected author2@users.invalid, got %s", got)
+	}
+	if got := p.Name("jane doe"); got != "Author 1" {
+		t.Errorf("Expected the same pseudonym regardless of case, got %s", got)
+	}
+	for _, bot := range []string{"dependabot[bot]", "Renovate Bot", notCommittedAuthor, ""} {
+		if got := p.Name(bot); got != bot {
+			t.Errorf("Expected %q to be kept, got %s", bot, got)
+		}
+	}
+
+	if got := NewPseudonymizer(false).Name("Jane Doe"); got != "Jane Doe" {
+		t.Errorf("Expected a disabled pseudonymizer to keep the name, got %s", got)
+	}
+}
+
+func TestPseudonymizerPullRequest(t *testing.T) {
+	p := NewPseudonymizer(true)
+	pr := p.PullRequest(PullRequest{
+		Title:    "Fix the login of Jane Doe's team",
+		Body:     "Reported by john@example.com, thanks jdoe!",
+		Author:   "jdoe",
+		AuthorID: "{account-id}",
+		Commits: []Commit{
+			{Author: "Jane Doe", Message: "Fix login\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+			{Author: "dependabot[bot]", Message: "Bump the SDK"},
+		},
+	})
+
+	if pr.Author != "Author 1" || pr.AuthorID != "{account-id}" {
+		t.Errorf("Expected the author pseudonymized and the account ID kept, got %s and %s", pr.Author, pr.AuthorID)
+	}
+	if pr.Commits[0].Author != "Author 2" || pr.Commits[1].Author != "dependabot[bot]" {
+		t.Errorf("Unexpected commit authors: %s, %s", pr.Commits[0].Author, pr.Commits[1].Author)
+	}
+	if pr.Commits[0].Message != "Fix login\n\nSigned-off-by: Author 2 <author3@users.invalid>" {
+		t.Errorf("Unexpected commit message: %s", pr.Commits[0].Message)
+	}
+	if pr.Title != "Fix the login of Author 2's team" || pr.Body != "Reported by author4@users.invalid, thanks Author 1!" {
+		t.Errorf("Unexpected title and body: %s, %s", pr.Title, pr.Body)
+	}
+
+	restored := p.Restore("Author 2 and Author 12 (author4@users.invalid)")
+	if restored != "Jane Doe and Author 12 (john@example.com)" {
+		t.Errorf("Unexpected restored text: %s", restored)
+	}
+}
+
+func TestPseudonymizerBlame(t *testing.T) {
+	p := NewPseudonymizer(true)
+	output := "0123456789abcdef0123456789abcdef01234567 (Jane Doe 2025-03-10 12:00:00 +0100  1) package main\n" +
+		"^123456789abcdef0123456789abcdef01234567 old/main.go (John Smith 2024-01-02 08:30:00 -0500 2) \n" +
+		"0000000000000000000000000000000000000000 (Not Committed Yet 2025-03-11 09:00:00 +0000 3) func main() {}"
+
+	blame := p.Blame(output)
+	for _, expected := range []string{
+		"(Author 1 2025-03-10 12:00:00 +0100  1) package main",
+		"old/main.go (Author 2 2024-01-02 08:30:00 -0500 2)",
+		"(Not Committed Yet 2025-03-11",
+	} {
+		if !strings.Contains(blame, expected) {
+			t.Errorf("Expected %q in the blame, got:\n%s", expected, blame)
+		}
+	}
+	if strings.Contains(blame, "Jane") || strings.Contains(blame, "John") {
+		t.Errorf("Expected no real names in the blame, got:\n%s", blame)
+	}
+}
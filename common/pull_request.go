@@ -3,6 +3,7 @@ package common
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Label struct {
@@ -10,32 +11,33 @@ type Label struct {
 }
 
 type Commit struct {
-	CommitHash string `yaml:"commit_hash"`
-	Author     string `yaml:"author"`
-	Message    string `yaml:"message"`
-	CreatedAt  string `yaml:"created_at"`
+	CommitHash string    `yaml:"commit_hash"`
+	Author     string    `yaml:"author"`
+	Message    string    `yaml:"message"`
+	CreatedAt  time.Time `yaml:"created_at"`
 }
 
 type PullRequest struct {
-	Number     int      `yaml:"number"`
-	Title      string   `yaml:"title"`
-	Body       string   `yaml:"body"`
-	HeadBranch string   `yaml:"head_branch"`
-	BaseBranch string   `yaml:"base_branch"`
-	CreatedAt  string   `yaml:"created_at"`
-	UpdatedAt  string   `yaml:"updated_at"`
-	Author     string   `yaml:"author"`
-	Mergeable  bool     `yaml:"mergeable"`
-	Merged     bool     `yaml:"merged"`
-	Labels     []Label  `yaml:"labels"`
-	Commits    []Commit `yaml:"commits"`
+	Number     int       `yaml:"number"`
+	Title      string    `yaml:"title"`
+	Body       string    `yaml:"body"`
+	HeadBranch string    `yaml:"head_branch"`
+	BaseBranch string    `yaml:"base_branch"`
+	CreatedAt  time.Time `yaml:"created_at"`
+	UpdatedAt  time.Time `yaml:"updated_at"`
+	Author     string    `yaml:"author"`
+	AuthorID   string    `yaml:"author_id"` // Account ID of the author where mentions need it, like Bitbucket
+	Mergeable  bool      `yaml:"mergeable"`
+	Merged     bool      `yaml:"merged"`
+	Labels     []Label   `yaml:"labels"`
+	Commits    []Commit  `yaml:"commits"`
 }
 
 func (c Commit) String() string {
 	return `Commit: ` + c.CommitHash + `
 Author: ` + c.Author + `
 Message: ` + c.Message + `
-Created at: ` + c.CreatedAt + ``
+Created at: ` + FormatTimestamp(c.CreatedAt)
 }
 
 func (pr PullRequest) String() string {
@@ -51,7 +53,8 @@ func (pr PullRequest) String() string {
 
 	return `Pull Request #` + strconv.Itoa(pr.Number) + `: ` + pr.Title + `
 Author: ` + pr.Author + `
-Created at: ` + pr.CreatedAt + `
+Created at: ` + FormatTimestamp(pr.CreatedAt) + `
+Updated at: ` + FormatTimestamp(pr.UpdatedAt) + `
 Head Branch: ` + pr.HeadBranch + `
 Base Branch: ` + pr.BaseBranch + `
 Mergeable: ` + strconv.FormatBool(pr.Mergeable) + `
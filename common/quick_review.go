@@ -0,0 +1,90 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuickReviewResult is the response of the one-shot quick review
+type QuickReviewResult struct {
+	Summary  string      `json:"summary"`
+	Findings []LineLevel `json:"findings"`
+}
+
+// Applies returns true if the diff is small enough to be reviewed in quick mode
+func (q QuickReview) Applies(diff string) bool {
+	return q.MaxChangedLines > 0 && CountChangedLines(diff) <= q.MaxChangedLines
+}
+
+// CountChangedLines returns the number of added and removed lines in the diff
+func CountChangedLines(diff string) int {
+	count := 0
+	for line := range strings.SplitSeq(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			count++
+		}
+	}
+	return count
+}
+
+// ParseQuickReview parses the response of the quick review, tolerating a surrounding code block or text
+func ParseQuickReview(content string) (QuickReviewResult, error) {
+	var result QuickReviewResult
+	if err := DecodeLLMJSON(content, &result); err != nil {
+		return QuickReviewResult{}, fmt.Errorf("failed to parse quick review: %w", err)
+	}
+	if result.Summary == "" {
+		return QuickReviewResult{}, fmt.Errorf("quick review has no summary")
+	}
+
+	findings := make([]LineLevel, 0, len(result.Findings))
+	for _, finding := range result.Findings {
+		if finding.File != "" && finding.Line != "" && finding.Body != "" {
+			findings = append(findings, finding)
+		}
+	}
+	result.Findings = findings
+	return result, nil
+}
+
+// QuickString formats the compact summary of a quick review, without walkthrough and celebration
+func (s Summary) QuickString(provider string, settings Settings) string {
+	renderer := NewMarkdownRenderer(provider)
+
+	var builder strings.Builder
+	builder.WriteString(s.Header() + "\n\n")
+
+	if settings.Reviews.Summary && len(s.Summary) > 0 {
+		builder.WriteString("## Summary\n")
+		builder.WriteString(s.Summary + "\n\n")
+	}
+
+	if len(s.ContractChanges) > 0 {
+		builder.WriteString("## Contract changes\n")
+		builder.WriteString(s.ContractChanges + "\n\n")
+	}
+
+	if len(s.Compliance) > 0 {
+		builder.WriteString("## Compliance\n")
+		builder.WriteString(s.Compliance + "\n\n")
+	}
+
+	if len(s.Author) > 0 {
+		builder.WriteString(s.authorMention(renderer) + "\n\n")
+	}
+
+	if len(s.QuotaNote) > 0 {
+		builder.WriteString(renderer.Note(fmt.Sprintf("%s %d findings.", s.QuotaNote, s.Stats.Findings)))
+	} else {
+		builder.WriteString(renderer.Note(fmt.Sprintf("⚡ Quick review of a small pull request: only the diff was reviewed, %d findings.", s.Stats.Findings)))
+	}
+
+	if changelog := s.changelog(renderer); changelog != "" {
+		builder.WriteString("\n\n" + changelog)
+	}
+
+	return s.withState(ApplyStyle(builder.String()) + commentFooter(renderer) + commentMetadata())
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuickReviewApplies(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var a = 1
++var a = 2
+`
+	if count := CountChangedLines(diff); count != 2 {
+		t.Errorf("Expected 2 changed lines, got %d", count)
+	}
+	if !(QuickReview{MaxChangedLines: 2}).Applies(diff) {
+		t.Error("Expected quick review for a diff within the threshold")
+	}
+	if (QuickReview{MaxChangedLines: 1}).Applies(diff) {
+		t.Error("Expected full review for a diff above the threshold")
+	}
+	if (QuickReview{}).Applies(diff) {
+		t.Error("Expected no automatic quick review without a threshold")
+	}
+}
+
+func TestParseQuickReview(t *testing.T) {
+	result, err := ParseQuickReview("```json\n" + `{"summary": "Bumps the counter", "findings": [
+		{"file": "main.go", "content": "var a = 2", "category": "bug", "issue": "Off by one"},
+		{"file": "main.go", "content": "", "issue": "Missing line"}
+	]}` + "\n```")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Summary != "Bumps the counter" {
+		t.Errorf("Unexpected summary: %q", result.Summary)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Line != "var a = 2" || result.Findings[0].Body != "Off by one" {
+		t.Errorf("Expected only the complete finding, got %+v", result.Findings)
+	}
+
+	if _, err := ParseQuickReview(`{"findings": []}`); err == nil {
+		t.Error("Expected an error without a summary")
+	}
+}
+
+func TestSummaryQuickString(t *testing.T) {
+	summary := Summary{
+		Summary:     "Bumps the counter",
+		Walkthrough: []Walkthrough{{Files: "main.go", Summary: "Changes"}},
+		Stats:       SummaryStats{Findings: 1},
+	}
+
+	output := summary.QuickString(ProviderGitHub, WithDefaultSettings())
+	if !strings.Contains(output, "## Summary\nBumps the counter") || !strings.Contains(output, "Quick review") {
+		t.Errorf("Expected the compact summary, got:\n%s", output)
+	}
+	if strings.Contains(output, "Walkthrough") {
+		t.Errorf("Expected no walkthrough in the compact summary, got:\n%s", output)
+	}
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// releaseNotesTimeout is the timeout in seconds for fetching release notes
+	releaseNotesTimeout = 30
+	// maxReleaseNotesLength limits the release notes returned to the LLM
+	maxReleaseNotesLength = 20000
+)
+
+// githubRelease is a release of a GitHub repository
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	Body    string `json:"body"`
+}
+
+// FetchReleaseNotes returns the release notes of a GitHub hosted dependency published after fromVersion up to toVersion.
+// The repository is in the format 'owner/repo'.
+func FetchReleaseNotes(repository, fromVersion, toVersion string) (string, error) {
+	if len(strings.Split(repository, "/")) != 2 {
+		return "", errors.New("repository must be in the format 'owner/repo'")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), releaseNotesTimeout*time.Second)
+	defer cancel()
+
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=100", repository)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	// Unauthenticated requests have a lower rate limit, but the token of the code review provider is not sent to a third party
+	if token, _, err := CredentialReleaseNotes.Resolve(); err == nil {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := NewRetryableClient(DefaultRetryConfig()).StandardClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", NewAPIError("GitHub", resp.StatusCode, fmt.Errorf("failed to list releases of %s: HTTP %d", repository, resp.StatusCode))
+	}
+
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return "", fmt.Errorf("failed to decode releases: %w", err)
+	}
+
+	return formatReleaseNotes(releases, fromVersion, toVersion), nil
+}
+
+// formatReleaseNotes keeps the releases after fromVersion up to and including toVersion.
+// Releases are listed newest first by the API.
+func formatReleaseNotes(releases []githubRelease, fromVersion, toVersion string) string {
+	from := normalizeVersion(fromVersion)
+	to := normalizeVersion(toVersion)
+
+	var builder strings.Builder
+	collecting := to == ""
+	for _, release := range releases {
+		version := normalizeVersion(release.TagName)
+		if !collecting && (version == to || strings.HasSuffix(version, "/"+to) || strings.HasSuffix(version, "@"+to)) {
+			collecting = true
+		}
+		if from != "" && (version == from || strings.HasSuffix(version, "/"+from) || strings.HasSuffix(version, "@"+from)) {
+			break
+		}
+		if !collecting {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("===== RELEASE: %s =====\n", release.TagName))
+		if release.Name != "" && release.Name != release.TagName {
+			builder.WriteString(release.Name + "\n")
+		}
+		builder.WriteString(release.Body + "\n\n")
+
+		if builder.Len() > maxReleaseNotesLength {
+			builder.WriteString("... release notes truncated ...\n")
+			break
+		}
+	}
+
+	if builder.Len() == 0 {
+		return "No release notes found for the given versions."
+	}
+	return builder.String()
+}
+
+// normalizeVersion strips the common 'v' prefix from versions and tags
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
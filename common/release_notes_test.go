@@ -0,0 +1,51 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatReleaseNotes(t *testing.T) {
+	releases := []githubRelease{
+		{TagName: "v1.3.0", Body: "Drop Go 1.21"},
+		{TagName: "v1.2.0", Name: "Faster parsing", Body: "Parse in parallel"},
+		{TagName: "v1.1.0", Body: "Add the retry option"},
+		{TagName: "v1.0.0", Body: "First stable release"},
+	}
+	monorepo := []githubRelease{
+		{TagName: "cli@1.2.0", Body: "New flags"},
+		{TagName: "sdk@1.2.0", Body: "New client"},
+		{TagName: "cli@1.1.0", Body: "Old flags"},
+	}
+
+	tests := []struct {
+		name     string
+		releases []githubRelease
+		from     string
+		to       string
+		included []string
+		excluded []string
+	}{
+		{"range", releases, "1.0.0", "1.2.0", []string{"RELEASE: v1.2.0", "Faster parsing", "RELEASE: v1.1.0"}, []string{"v1.3.0", "v1.0.0"}},
+		{"prefixed versions", releases, "v1.1.0", "v1.3.0", []string{"RELEASE: v1.3.0", "RELEASE: v1.2.0"}, []string{"v1.1.0"}},
+		{"up to the latest", releases, "1.2.0", "", []string{"RELEASE: v1.3.0"}, []string{"v1.2.0"}},
+		{"unknown versions", releases, "2.0.0", "2.1.0", []string{"No release notes found"}, []string{"RELEASE"}},
+		{"monorepo tags", monorepo, "1.1.0", "1.2.0", []string{"RELEASE: cli@1.2.0"}, []string{"cli@1.1.0"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notes := formatReleaseNotes(test.releases, test.from, test.to)
+			for _, expected := range test.included {
+				if !strings.Contains(notes, expected) {
+					t.Errorf("Expected the notes to contain %q, got:\n%s", expected, notes)
+				}
+			}
+			for _, unexpected := range test.excluded {
+				if strings.Contains(notes, unexpected) {
+					t.Errorf("Expected the notes not to contain %q, got:\n%s", unexpected, notes)
+				}
+			}
+		})
+	}
+}
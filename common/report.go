@@ -0,0 +1,324 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// modelPricing holds the USD price per million prompt and completion tokens for known models
+var modelPricing = map[string][2]float64{
+	"gpt-4.1":         {2.00, 8.00},
+	"gpt-4.1-mini":    {0.40, 1.60},
+	"gpt-4.1-nano":    {0.10, 0.40},
+	"gpt-4o":          {2.50, 10.00},
+	"gpt-4o-mini":     {0.15, 0.60},
+	"o3":              {2.00, 8.00},
+	"o4-mini":         {1.10, 4.40},
+	"claude-3-sonnet": {3.00, 15.00},
+	"claude-4-sonnet": {3.00, 15.00},
+	"claude-3-haiku":  {0.80, 4.00},
+}
+
+// RunReportFilePrefix is the file name prefix of the saved run reports
+const RunReportFilePrefix = "ai-review-report-"
+
+// topToolCallsLimit is the number of the most expensive tool calls logged at the end of the run
+const topToolCallsLimit = 10
+
+// stageDuration is the time spent in a stage of the run
+type stageDuration struct {
+	Name     string
+	Duration time.Duration
+}
+
+// RunReport collects statistics about a run, printed at the end of it
+type RunReport struct {
+	mu               sync.Mutex
+	started          time.Time
+	model            string
+	promptVariant    string
+	repository       string
+	pullRequest      int
+	stages           []stageDuration
+	findings         map[string]int
+	commentsPosted   int
+	commentsUpdated  int
+	commentsSkipped  int
+	dismissed        int
+	apiCalls         map[string]int
+	promptTokens     int
+	completionTokens int
+	toolCalls        []ToolCallStats
+}
+
+var runReport = NewRunReport()
+
+// NewRunReport creates an empty run report
+func NewRunReport() *RunReport {
+	return &RunReport{
+		started:  time.Now(),
+		findings: map[string]int{},
+		apiCalls: map[string]int{},
+	}
+}
+
+// Report returns the report of the current run
+func Report() *RunReport {
+	return runReport
+}
+
+// SetModel sets the LLM model used to estimate the cost of the run
+func (r *RunReport) SetModel(model string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.model = model
+}
+
+// SetPromptVariant sets the name of the prompt variant used by the run
+func (r *RunReport) SetPromptVariant(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.promptVariant = name
+}
+
+// SetPullRequest sets the reviewed repository in the owner/repo format and the pull request number
+func (r *RunReport) SetPullRequest(repository string, pr int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.repository = repository
+	r.pullRequest = pr
+}
+
+// StartStage starts measuring a stage of the run, call the returned function when the stage is finished
+func (r *RunReport) StartStage(name string) func() {
+	start := time.Now()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.stages = append(r.stages, stageDuration{Name: name, Duration: time.Since(start)})
+	}
+}
+
+// AddFinding records a finding of the review by its category
+func (r *RunReport) AddFinding(category string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if category == "" {
+		category = "uncategorized"
+	}
+	r.findings[category]++
+}
+
+// CommentsPosted records newly posted comments
+func (r *RunReport) CommentsPosted(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commentsPosted += count
+}
+
+// CommentsUpdated records updated comments
+func (r *RunReport) CommentsUpdated(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commentsUpdated += count
+}
+
+// CommentsSkipped records comments not posted, e.g. duplicates or dismissed findings
+func (r *RunReport) CommentsSkipped(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commentsSkipped += count
+}
+
+// SetCommentsDismissed records the number of previously posted findings dismissed by the team on the pull request
+func (r *RunReport) SetCommentsDismissed(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dismissed = count
+}
+
+// APICall records an outgoing API call to the host
+func (r *RunReport) APICall(host string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apiCalls[host]++
+}
+
+// AddTokens records the token usage of an LLM call
+func (r *RunReport) AddTokens(promptTokens, completionTokens int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.promptTokens += promptTokens
+	r.completionTokens += completionTokens
+}
+
+// AddToolCall records the size of the arguments and the result of a tool call, and logs it in debug mode
+func (r *RunReport) AddToolCall(tool, arguments, result string) {
+	stats := NewToolCallStats(tool, arguments, result)
+	logger.Debugf("Tool call %s", stats)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.toolCalls = append(r.toolCalls, stats)
+}
+
+// TopToolCalls lists the tool calls adding the most tokens to the conversation, empty if no tools were called
+func (r *RunReport) TopToolCalls() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return formatTopToolCalls(r.toolCalls, topToolCallsLimit)
+}
+
+// String formats the report in a human-readable format
+func (r *RunReport) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var builder strings.Builder
+	builder.WriteString("===== AI Review Report =====\n")
+
+	if r.promptVariant != "" {
+		builder.WriteString(fmt.Sprintf("Prompt variant: %s\n", r.promptVariant))
+	}
+	builder.WriteString(fmt.Sprintf("Duration: %s\n", time.Since(r.started).Round(time.Millisecond)))
+	for _, stage := range r.stages {
+		builder.WriteString(fmt.Sprintf("  %s: %s\n", stage.Name, stage.Duration.Round(time.Millisecond)))
+	}
+
+	builder.WriteString(fmt.Sprintf("Findings: %d\n", sumCounts(r.findings)))
+	for _, category := range sortedKeys(r.findings) {
+		builder.WriteString(fmt.Sprintf("  %s: %d\n", category, r.findings[category]))
+	}
+
+	builder.WriteString(fmt.Sprintf("Comments: %d posted, %d updated, %d skipped\n",
+		r.commentsPosted, r.commentsUpdated, r.commentsSkipped))
+
+	builder.WriteString(fmt.Sprintf("API calls: %d\n", sumCounts(r.apiCalls)))
+	for _, host := range sortedKeys(r.apiCalls) {
+		builder.WriteString(fmt.Sprintf("  %s: %d\n", host, r.apiCalls[host]))
+	}
+
+	cost := "n/a"
+	if usd, ok := r.estimatedCost(); ok {
+		cost = fmt.Sprintf("$%.4f", usd)
+	}
+	builder.WriteString(fmt.Sprintf("Tokens: %d prompt, %d completion (estimated cost: %s)\n",
+		r.promptTokens, r.completionTokens, cost))
+
+	builder.WriteString("============================")
+	return builder.String()
+}
+
+// RunReportData is the machine readable form of a run report, saved as an artifact of the run.
+// CommentsDismissed is the total of the pull request at the time of the run, not only of the comments of the run.
+type RunReportData struct {
+	StartedAt         time.Time          `json:"started_at"`
+	Repository        string             `json:"repository"`
+	PullRequest       int                `json:"pull_request"`
+	Model             string             `json:"model"`
+	PromptVariant     string             `json:"prompt_variant,omitempty"`
+	DurationSeconds   float64            `json:"duration_seconds"`
+	Stages            map[string]float64 `json:"stages"`
+	Findings          map[string]int     `json:"findings"`
+	CommentsPosted    int                `json:"comments_posted"`
+	CommentsUpdated   int                `json:"comments_updated"`
+	CommentsSkipped   int                `json:"comments_skipped"`
+	CommentsDismissed int                `json:"comments_dismissed"`
+	PromptTokens      int                `json:"prompt_tokens"`
+	CompletionTokens  int                `json:"completion_tokens"`
+	EstimatedCostUSD  float64            `json:"estimated_cost_usd"`
+}
+
+// Data returns the machine readable form of the report
+func (r *RunReport) Data() RunReportData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stages := map[string]float64{}
+	for _, stage := range r.stages {
+		stages[stage.Name] += stage.Duration.Seconds()
+	}
+	findings := map[string]int{}
+	for category, count := range r.findings {
+		findings[category] = count
+	}
+	cost, _ := r.estimatedCost()
+
+	return RunReportData{
+		StartedAt:         r.started.UTC(),
+		Repository:        r.repository,
+		PullRequest:       r.pullRequest,
+		Model:             r.model,
+		PromptVariant:     r.promptVariant,
+		DurationSeconds:   time.Since(r.started).Seconds(),
+		Stages:            stages,
+		Findings:          findings,
+		CommentsPosted:    r.commentsPosted,
+		CommentsUpdated:   r.commentsUpdated,
+		CommentsSkipped:   r.commentsSkipped,
+		CommentsDismissed: r.dismissed,
+		PromptTokens:      r.promptTokens,
+		CompletionTokens:  r.completionTokens,
+		EstimatedCostUSD:  cost,
+	}
+}
+
+// Save writes the report as JSON into the directory, returns the path of the written file
+func (r *RunReport) Save(dir string) (string, error) {
+	data := r.Data()
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode run report: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create run report directory: %w", err)
+	}
+	path := filepath.Join(dir, RunReportFilePrefix+data.StartedAt.Format("20060102-150405")+".json")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write run report: %w", err)
+	}
+	return path, nil
+}
+
+// estimatedCost returns the estimated USD cost of the token usage, false for models with unknown pricing
+func (r *RunReport) estimatedCost() (float64, bool) {
+	pricing, ok := modelPricing[r.model]
+	if !ok {
+		return 0, false
+	}
+	return float64(r.promptTokens)/1e6*pricing[0] + float64(r.completionTokens)/1e6*pricing[1], true
+}
+
+// Print writes the report to stdout, and the most expensive tool calls to the debug log for tuning the budgets
+func (r *RunReport) Print() {
+	if topToolCalls := r.TopToolCalls(); topToolCalls != "" {
+		logger.Debug(topToolCalls)
+	}
+	fmt.Println(r.String())
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+func sortedKeys[T any](counts map[string]T) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunReportString(t *testing.T) {
+	report := NewRunReport()
+	report.SetModel("gpt-4.1")
+	report.StartStage("LLM review")()
+	report.AddFinding(CategoryBug)
+	report.AddFinding(CategoryBug)
+	report.AddFinding(CategoryNitpick)
+	report.CommentsPosted(2)
+	report.CommentsUpdated(1)
+	report.CommentsSkipped(1)
+	report.APICall("api.github.com")
+	report.APICall("api.openai.com")
+	report.APICall("api.openai.com")
+	report.AddTokens(1000000, 100000)
+
+	output := report.String()
+	expected := []string{
+		"  LLM review: ",
+		"Findings: 3\n",
+		"  bug: 2\n",
+		"  nitpick: 1\n",
+		"Comments: 2 posted, 1 updated, 1 skipped\n",
+		"API calls: 3\n",
+		"  api.openai.com: 2\n",
+		"Tokens: 1000000 prompt, 100000 completion (estimated cost: $2.8000)\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected report to contain %q, got:\n%s", e, output)
+		}
+	}
+}
+
+func TestRunReportUnknownModelCost(t *testing.T) {
+	report := NewRunReport()
+	report.SetModel("unknown-model")
+
+	if !strings.Contains(report.String(), "estimated cost: n/a") {
+		t.Errorf("Expected unknown cost for unknown model, got:\n%s", report.String())
+	}
+}
+
+func TestRunReportTopToolCalls(t *testing.T) {
+	report := NewRunReport()
+	if report.TopToolCalls() != "" {
+		t.Errorf("Expected no tool calls, got:\n%s", report.TopToolCalls())
+	}
+
+	for i := range 12 {
+		report.AddToolCall("read_file", fmt.Sprintf(`{"path": "file%d.go"}`, i), strings.Repeat("x", i*100))
+	}
+	report.AddToolCall("get_git_diff", `{}`, strings.Repeat("x", 4000))
+
+	output := report.TopToolCalls()
+	lines := strings.Split(output, "\n")
+	if len(lines) != 11 || lines[0] != "Top 10 of 13 tool calls by estimated tokens:" {
+		t.Fatalf("Expected the top 10 tool calls, got:\n%s", output)
+	}
+	if lines[1] != "  1. ~1001 tokens, get_git_diff {}" {
+		t.Errorf("Expected the diff as the most expensive call, got %q", lines[1])
+	}
+	if lines[2] != `  2. ~281 tokens, read_file {"path": "file11.go"}` {
+		t.Errorf("Expected the largest file second, got %q", lines[2])
+	}
+}
+
+func TestToolCallStats(t *testing.T) {
+	stats := NewToolCallStats("read_file", `{"path": "main.go"}`, "package main 🚀")
+	expected := "read_file: arguments 19 chars (~5 tokens), result 14 chars (~4 tokens)"
+	if stats.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stats.String())
+	}
+	if EstimateTokens("") != 0 || EstimateTokens("abcde") != 2 {
+		t.Errorf("Unexpected token estimates")
+	}
+}
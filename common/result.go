@@ -0,0 +1,115 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ResultFileName is the default file name of the result of a run
+const ResultFileName = "result.json"
+
+// Statuses of a run in the result file
+const (
+	ResultStatusOK            = "ok"
+	ResultStatusFindings      = "findings"
+	ResultStatusConfigError   = "config_error"
+	ResultStatusProviderError = "provider_error"
+	ResultStatusLLMError      = "llm_error"
+	ResultStatusError         = "error"
+)
+
+// RunResult is the machine readable outcome of a run, written even when the run fails for the workflows to branch on
+type RunResult struct {
+	Status                 string         `json:"status"`
+	ExitCode               int            `json:"exit_code"`
+	Repository             string         `json:"repository,omitempty"`
+	PullRequest            int            `json:"pull_request,omitempty"`
+	Findings               int            `json:"findings"`
+	FindingsByCategory     map[string]int `json:"findings_by_category"`
+	FindingsAboveThreshold int            `json:"findings_above_threshold"`
+	CommentsPosted         int            `json:"comments_posted"`
+	CommentsUpdated        int            `json:"comments_updated"`
+	CommentsSkipped        int            `json:"comments_skipped"`
+	Error                  *ResultError   `json:"error,omitempty"`
+}
+
+// ResultError holds the details of the error failing the run
+type ResultError struct {
+	Message     string `json:"message"`
+	Category    string `json:"category,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Service     string `json:"service,omitempty"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// NewRunResult creates the result of a run from its report and the error it failed with, nil if it succeeded
+func NewRunResult(report RunReportData, err error) RunResult {
+	result := RunResult{
+		Status:             ResultStatusOK,
+		ExitCode:           ExitCode(err),
+		Repository:         report.Repository,
+		PullRequest:        report.PullRequest,
+		Findings:           sumCounts(report.Findings),
+		FindingsByCategory: report.Findings,
+		CommentsPosted:     report.CommentsPosted,
+		CommentsUpdated:    report.CommentsUpdated,
+		CommentsSkipped:    report.CommentsSkipped,
+	}
+	if result.FindingsByCategory == nil {
+		result.FindingsByCategory = map[string]int{}
+	}
+	if err == nil {
+		return result
+	}
+
+	category := Category(err)
+	result.Error = &ResultError{
+		Message:     err.Error(),
+		Category:    string(category),
+		Remediation: Remediation(err),
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		result.Error.Kind = string(apiErr.Kind)
+		result.Error.Service = apiErr.Service
+		result.Error.StatusCode = apiErr.StatusCode
+	}
+	var findingsErr *FindingsError
+	if errors.As(err, &findingsErr) {
+		result.FindingsAboveThreshold = findingsErr.Count
+	}
+
+	switch category {
+	case ErrorCategoryFindings:
+		result.Status = ResultStatusFindings
+	case ErrorCategoryConfig:
+		result.Status = ResultStatusConfigError
+	case ErrorCategoryProvider:
+		result.Status = ResultStatusProviderError
+	case ErrorCategoryLLM:
+		result.Status = ResultStatusLLMError
+	default:
+		result.Status = ResultStatusError
+	}
+	return result
+}
+
+// Save writes the result as JSON to the path, creating its directory
+func (r RunResult) Save(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create result directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunResult(t *testing.T) {
+	report := RunReportData{
+		Repository:     "bitrise-io/uploader",
+		PullRequest:    42,
+		Findings:       map[string]int{CategoryBug: 2, CategoryNitpick: 1},
+		CommentsPosted: 3,
+	}
+
+	result := NewRunResult(report, nil)
+	if result.Status != ResultStatusOK || result.ExitCode != ExitCodeOK || result.Findings != 3 || result.Error != nil {
+		t.Errorf("Unexpected result of a successful run %+v", result)
+	}
+
+	result = NewRunResult(report, &FindingsError{Count: 1, Message: "1 findings of high severity or higher"})
+	if result.Status != ResultStatusFindings || result.ExitCode != ExitCodeFindings || result.FindingsAboveThreshold != 1 {
+		t.Errorf("Unexpected result of a run failing on findings %+v", result)
+	}
+
+	err := NewProviderError(WrapError("Error posting line feedback", NewAPIError("GitHub", http.StatusForbidden, errors.New("forbidden"))))
+	result = NewRunResult(RunReportData{}, err)
+	if result.Status != ResultStatusProviderError || result.ExitCode != ExitCodeProvider {
+		t.Errorf("Unexpected result of a provider failure %+v", result)
+	}
+	if result.Error == nil || result.Error.Message != "Error posting line feedback" || result.Error.Kind != string(ErrorKindPermission) ||
+		result.Error.Service != "GitHub" || result.Error.StatusCode != http.StatusForbidden || result.Error.Remediation == "" {
+		t.Errorf("Unexpected error details %+v", result.Error)
+	}
+	if result.FindingsByCategory == nil {
+		t.Error("Expected empty findings instead of null")
+	}
+}
+
+func TestRunResultSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy", ResultFileName)
+	if err := NewRunResult(RunReportData{}, NewConfigError(errors.New("invalid flag"))).Save(path); err != nil {
+		t.Fatalf("Failed to save the result: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the result: %v", err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(content, &saved); err != nil {
+		t.Fatalf("Failed to decode the result: %v", err)
+	}
+	if saved["status"] != ResultStatusConfigError || saved["exit_code"] != float64(ExitCodeConfig) {
+		t.Errorf("Unexpected saved result %s", content)
+	}
+}
@@ -1,6 +1,9 @@
 package common
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
@@ -46,9 +49,21 @@ func NewRetryableClient(config RetryConfig) *retryablehttp.Client {
 		retryClient.CheckRetry = config.CheckRetry
 	}
 
+	// Requests skipped by an open circuit are not retried
+	checkRetry := retryClient.CheckRetry
+	retryClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if errors.Is(err, ErrCircuitOpen) {
+			return false, err
+		}
+		return checkRetry(ctx, resp, err)
+	}
+
 	// Add logging for retries
 	retryClient.Logger = &zapRetryLogger{}
 
+	// Apply proxy and CA bundle settings, and skip the requests to unavailable hosts
+	retryClient.HTTPClient.Transport = &circuitBreakerTransport{base: configureTransport(retryClient.HTTPClient.Transport)}
+
 	return retryClient
 }
 
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// ReviewLockHeader starts the marker comment of the run reviewing the pull request
+const ReviewLockHeader = "[bitrise-plugin-ai-reviewer]: review-lock"
+
+// reviewLockRegex matches the hidden holder of the lock in the marker comment
+var reviewLockRegex = regexp.MustCompile(`<!-- review-lock: (\S+) (\S+) -->`)
+
+// ReviewLock configures the lock keeping concurrent runs, like the ones of a push and a manual trigger, from reviewing the same pull request
+type ReviewLock struct {
+	Minutes     int `yaml:"minutes"`      // Expiry of the lock, for runs killed before releasing it, 0 disables the lock
+	WaitMinutes int `yaml:"wait_minutes"` // Time to wait for the running review before skipping the run, 0 skips right away
+}
+
+// LockHolder is the run holding the review lock of the pull request
+type LockHolder struct {
+	RunID     string
+	ExpiresAt time.Time
+	BuildURL  string // Build of the run, linked from the marker comment
+}
+
+// NewLockHolder creates the holder of the lock for this run, expiring after the duration
+func NewLockHolder(expiry time.Duration) LockHolder {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return LockHolder{
+		RunID:     hex.EncodeToString(id),
+		ExpiresAt: time.Now().Add(expiry).UTC().Truncate(time.Second),
+		BuildURL:  os.Getenv("BITRISE_BUILD_URL"),
+	}
+}
+
+// ParseLockHolder parses the holder of the lock from the marker comment, false if there is none
+func ParseLockHolder(body string) (LockHolder, bool) {
+	match := reviewLockRegex.FindStringSubmatch(body)
+	if match == nil {
+		return LockHolder{}, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, match[2])
+	if err != nil {
+		return LockHolder{}, false
+	}
+	return LockHolder{RunID: match[1], ExpiresAt: expiresAt}, true
+}
+
+// HeldAgainst checks if the lock is held by another run than the one with the run ID, expired locks are free
+func (h LockHolder) HeldAgainst(runID string, now time.Time) bool {
+	return h.RunID != "" && h.RunID != runID && now.Before(h.ExpiresAt)
+}
+
+// Body formats the marker comment of the lock, with the holder hidden from the readers
+func (h LockHolder) Body() string {
+	running := "A review of this pull request is running"
+	if h.BuildURL != "" {
+		running = fmt.Sprintf("A review of this pull request is [running](%s)", h.BuildURL)
+	}
+	return fmt.Sprintf("%s\n\n⏳ %s, the other runs wait for it to finish. This comment is deleted when it is done.\n\n<!-- review-lock: %s %s -->",
+		ReviewLockHeader, running, h.RunID, h.ExpiresAt.Format(time.RFC3339))
+}
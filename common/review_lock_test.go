@@ -0,0 +1,49 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockHolderRoundTrip(t *testing.T) {
+	holder := NewLockHolder(30 * time.Minute)
+	body := holder.Body()
+	if !strings.HasPrefix(body, ReviewLockHeader) {
+		t.Errorf("Expected the body to start with the header, got %q", body)
+	}
+
+	parsed, ok := ParseLockHolder(strings.TrimPrefix(body, ReviewLockHeader))
+	if !ok || parsed.RunID != holder.RunID || !parsed.ExpiresAt.Equal(holder.ExpiresAt) {
+		t.Errorf("Expected %+v, got %+v", holder, parsed)
+	}
+
+	if _, ok := ParseLockHolder("Review in progress"); ok {
+		t.Error("Expected no holder without the marker")
+	}
+}
+
+func TestLockHolderHeldAgainst(t *testing.T) {
+	now := time.Now()
+	holder := LockHolder{RunID: "abc", ExpiresAt: now.Add(time.Minute)}
+
+	tests := []struct {
+		name     string
+		holder   LockHolder
+		runID    string
+		expected bool
+	}{
+		{"held by another run", holder, "def", true},
+		{"held by the run", holder, "abc", false},
+		{"expired", LockHolder{RunID: "abc", ExpiresAt: now.Add(-time.Minute)}, "def", false},
+		{"no holder", LockHolder{}, "def", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.holder.HeldAgainst(test.runID, now); got != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
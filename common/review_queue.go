@@ -0,0 +1,124 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DeferredNote replaces the under review note of the reviews queued because the LLM provider is overloaded
+const DeferredNote = "⏳ The review is deferred, the LLM provider is overloaded. It is queued and posted when the provider recovers."
+
+// QueuedReview is a review deferred because the LLM provider was overloaded, rerun by the process-queue command
+type QueuedReview struct {
+	CodeReview  string    `json:"code_review"`
+	Repository  string    `json:"repository"`
+	PullRequest int       `json:"pull_request"`
+	Commit      string    `json:"commit"`
+	Args        []string  `json:"args"` // Flags of the summarize command, without --commit and --queue-file
+	Reason      string    `json:"reason"`
+	QueuedAt    time.Time `json:"queued_at"`
+	Attempts    int       `json:"attempts"`
+}
+
+// ReviewQueue is the queue of the deferred reviews, shared between the builds through the cache
+type ReviewQueue struct {
+	Reviews []QueuedReview `json:"reviews"`
+}
+
+// LoadReviewQueue reads the queue, a missing file is an empty queue
+func LoadReviewQueue(path string) (*ReviewQueue, error) {
+	queue := &ReviewQueue{Reviews: []QueuedReview{}}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return queue, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review queue: %w", err)
+	}
+	if err := json.Unmarshal(content, queue); err != nil {
+		return nil, fmt.Errorf("failed to parse review queue %s: %w", path, err)
+	}
+	return queue, nil
+}
+
+// Save writes the queue
+func (q *ReviewQueue) Save(path string) error {
+	content, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode review queue: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create review queue directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write review queue: %w", err)
+	}
+	return nil
+}
+
+// Enqueue adds the review, replacing the queued review of the same pull request as only its latest commit is reviewed
+func (q *ReviewQueue) Enqueue(review QueuedReview) {
+	if review.QueuedAt.IsZero() {
+		review.QueuedAt = now().UTC().Truncate(time.Second)
+	}
+	for idx, queued := range q.Reviews {
+		if queued.samePullRequest(review) {
+			review.Attempts = queued.Attempts
+			q.Reviews[idx] = review
+			return
+		}
+	}
+	q.Reviews = append(q.Reviews, review)
+}
+
+// Retried counts a rerun of the queued review of the pull request which was deferred again
+func (q *ReviewQueue) Retried(review QueuedReview) {
+	for idx, queued := range q.Reviews {
+		if queued.samePullRequest(review) {
+			q.Reviews[idx].Attempts++
+		}
+	}
+}
+
+// Remove removes the queued review of the pull request
+func (q *ReviewQueue) Remove(review QueuedReview) {
+	q.Reviews = slices.DeleteFunc(q.Reviews, review.samePullRequest)
+}
+
+// Expired checks if the review was queued longer than the max age, the pull request has likely moved on since
+func (r QueuedReview) Expired(maxAge time.Duration) bool {
+	return maxAge > 0 && now().Sub(r.QueuedAt) > maxAge
+}
+
+// String identifies the review in the logs, like "github my-org/my-repo#12 at 1a2b3c4"
+func (r QueuedReview) String() string {
+	return fmt.Sprintf("%s %s#%d at %s", r.CodeReview, r.Repository, r.PullRequest, shortCommit(r.Commit))
+}
+
+func (r QueuedReview) samePullRequest(other QueuedReview) bool {
+	return r.CodeReview == other.CodeReview && strings.EqualFold(r.Repository, other.Repository) && r.PullRequest == other.PullRequest
+}
+
+// IsOverloadError checks if the LLM call failed because the provider is rate limited, overloaded or unavailable.
+// These recover without changes to the review, unlike invalid credentials or too large requests.
+func IsOverloadError(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Kind {
+	case ErrorKindRateLimit, ErrorKindOverloaded, ErrorKindUnavailable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReviewQueue(t *testing.T) {
+	reference := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reference }
+	defer func() { now = time.Now }()
+
+	path := filepath.Join(t.TempDir(), "cache", "queue.json")
+	queue, err := LoadReviewQueue(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(queue.Reviews) != 0 {
+		t.Fatalf("Expected an empty queue, got %+v", queue.Reviews)
+	}
+
+	queue.Enqueue(QueuedReview{CodeReview: "github", Repository: "acme/app", PullRequest: 42, Commit: "1111111aaa", Args: []string{"--pr=42"}})
+	queue.Enqueue(QueuedReview{CodeReview: "github", Repository: "acme/app", PullRequest: 7, Commit: "2222222bbb"})
+	queue.Retried(QueuedReview{CodeReview: "github", Repository: "acme/app", PullRequest: 42})
+	// A new push replaces the queued commit of the pull request, keeping the attempts
+	queue.Enqueue(QueuedReview{CodeReview: "github", Repository: "Acme/App", PullRequest: 42, Commit: "3333333ccc"})
+	if err := queue.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded, err := LoadReviewQueue(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(loaded.Reviews) != 2 {
+		t.Fatalf("Expected 2 queued reviews, got %+v", loaded.Reviews)
+	}
+	first := loaded.Reviews[0]
+	if first.Commit != "3333333ccc" || first.Attempts != 1 || !first.QueuedAt.Equal(reference) {
+		t.Errorf("Expected the latest commit with 1 attempt, got %+v", first)
+	}
+	if got := first.String(); got != "github Acme/App#42 at 3333333" {
+		t.Errorf("Unexpected string: %s", got)
+	}
+
+	now = func() time.Time { return reference.Add(73 * time.Hour) }
+	if !first.Expired(72*time.Hour) || first.Expired(0) {
+		t.Error("Expected the review to expire after the max age only")
+	}
+
+	loaded.Remove(first)
+	if len(loaded.Reviews) != 1 || loaded.Reviews[0].PullRequest != 7 {
+		t.Errorf("Expected only the review of #7 to remain, got %+v", loaded.Reviews)
+	}
+}
+
+func TestIsOverloadError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limit", NewAPIError("OpenAI", 429, errors.New("slow down")), true},
+		{"overloaded", fmt.Errorf("wrapped: %w", NewAPIError("Anthropic", 529, errors.New("overloaded"))), true},
+		{"circuit open", &APIError{Kind: ErrorKindUnavailable, Service: "api.openai.com", Err: ErrCircuitOpen}, true},
+		{"auth", NewAPIError("OpenAI", 401, errors.New("invalid key")), false},
+		{"too large", NewAPIError("OpenAI", 413, errors.New("too large")), false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOverloadError(tt.err); got != tt.want {
+				t.Errorf("IsOverloadError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// Periods of the review quota
+const (
+	QuotaPeriodDay  = "day"
+	QuotaPeriodWeek = "week"
+)
+
+// ReviewQuota limits the full reviews of a pull request within a rolling period, bounding the cost of busy pull requests.
+// Over the quota the quick review runs instead. The times of the full reviews are kept in the state of the summary comment.
+type ReviewQuota struct {
+	MaxReviews int    `yaml:"max_reviews"` // Maximum number of full reviews within the period, 0 disables the quota
+	Period     string `yaml:"period"`      // day or week, defaults to day
+}
+
+// PeriodName returns the period of the quota, defaults to day
+func (q ReviewQuota) PeriodName() string {
+	if q.Period == "" {
+		return QuotaPeriodDay
+	}
+	return q.Period
+}
+
+// Window returns the length of the rolling period of the quota
+func (q ReviewQuota) Window() time.Duration {
+	if q.PeriodName() == QuotaPeriodWeek {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Validate checks the period of the quota
+func (q ReviewQuota) Validate() error {
+	if q.Period != "" && q.Period != QuotaPeriodDay && q.Period != QuotaPeriodWeek {
+		return fmt.Errorf("invalid review quota period %q, must be %s or %s", q.Period, QuotaPeriodDay, QuotaPeriodWeek)
+	}
+	return nil
+}
+
+// Recent returns the full reviews within the period of the quota
+func (q ReviewQuota) Recent(reviews []time.Time) []time.Time {
+	since := now().Add(-q.Window())
+	var recent []time.Time
+	for _, reviewedAt := range reviews {
+		if reviewedAt.After(since) {
+			recent = append(recent, reviewedAt)
+		}
+	}
+	return recent
+}
+
+// Exceeded checks if the pull request got the maximum number of full reviews within the period
+func (q ReviewQuota) Exceeded(reviews []time.Time) bool {
+	return q.MaxReviews > 0 && len(q.Recent(reviews)) >= q.MaxReviews
+}
+
+// Track returns the full reviews to keep in the summary state, with the current run if it is a full review
+func (q ReviewQuota) Track(reviews []time.Time, fullReview bool) []time.Time {
+	if q.MaxReviews <= 0 {
+		return nil
+	}
+	recent := q.Recent(reviews)
+	if fullReview {
+		recent = append(recent, now().UTC().Truncate(time.Second))
+	}
+	return recent
+}
+
+// Note describes the reached quota in the summary of the quick review
+func (q ReviewQuota) Note() string {
+	return fmt.Sprintf("⏳ Review quota reached: this pull request got %d full reviews in the last %s, only the diff was reviewed. The full review resumes when the quota frees up.", q.MaxReviews, q.PeriodName())
+}
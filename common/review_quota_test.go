@@ -0,0 +1,77 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReviewQuota(t *testing.T) {
+	reference := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reference }
+	defer func() { now = time.Now }()
+
+	reviews := []time.Time{
+		reference.Add(-30 * time.Hour),
+		reference.Add(-5 * time.Hour),
+		reference.Add(-time.Hour),
+	}
+
+	daily := ReviewQuota{MaxReviews: 2}
+	if !daily.Exceeded(reviews) {
+		t.Error("Expected 2 reviews in the last day to reach the daily quota")
+	}
+	if (ReviewQuota{MaxReviews: 3}).Exceeded(reviews) {
+		t.Error("Expected 2 reviews in the last day to be within a quota of 3")
+	}
+	if (ReviewQuota{}).Exceeded(reviews) {
+		t.Error("Expected no quota without max_reviews")
+	}
+	if !(ReviewQuota{MaxReviews: 3, Period: QuotaPeriodWeek}).Exceeded(reviews) {
+		t.Error("Expected 3 reviews in the last week to reach the weekly quota")
+	}
+
+	tracked := daily.Track(reviews, true)
+	if len(tracked) != 3 || !tracked[2].Equal(reference) {
+		t.Errorf("Expected the reviews of the last day and the current one, got %v", tracked)
+	}
+	if tracked := daily.Track(reviews, false); len(tracked) != 2 {
+		t.Errorf("Expected the quick review not to be counted, got %v", tracked)
+	}
+	if tracked := (ReviewQuota{}).Track(reviews, true); tracked != nil {
+		t.Errorf("Expected no tracking without a quota, got %v", tracked)
+	}
+}
+
+func TestReviewQuotaValidate(t *testing.T) {
+	for _, period := range []string{"", QuotaPeriodDay, QuotaPeriodWeek} {
+		if err := (ReviewQuota{Period: period}).Validate(); err != nil {
+			t.Errorf("Expected period %q to be valid, got %v", period, err)
+		}
+	}
+	if err := (ReviewQuota{Period: "month"}).Validate(); err == nil {
+		t.Error("Expected an error for an unknown period")
+	}
+}
+
+func TestReviewQuotaState(t *testing.T) {
+	reference := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reference }
+	defer func() { now = time.Now }()
+
+	quota := ReviewQuota{MaxReviews: 1}
+	summary := Summary{
+		Summary:   "Quick summary",
+		Tracking:  SummaryState{Commit: "abc1234", FullReviews: quota.Track(nil, true)},
+		QuotaNote: quota.Note(),
+	}
+	body := summary.QuickString(ProviderGitHub, WithDefaultSettings())
+	if !strings.Contains(body, "Review quota reached: this pull request got 1 full reviews in the last day") {
+		t.Errorf("Expected the quota note instead of the quick review note, got %s", body)
+	}
+
+	state := ParseSummaryState(body)
+	if !quota.Exceeded(state.FullReviews) {
+		t.Errorf("Expected the full reviews to be read back from the summary, got %v", state.FullReviews)
+	}
+}
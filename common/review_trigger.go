@@ -0,0 +1,90 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+// Review modes requested with the AI-Review commit trailer or pull request label
+const (
+	ReviewTriggerSkip     = "skip"     // Skip the run
+	ReviewTriggerFull     = "full"     // Full review with the assertive profile, even over the review quota
+	ReviewTriggerSecurity = "security" // Only report security findings
+)
+
+// reviewTriggerKey is the key of the commit trailer and the prefix of the label, like "AI-Review: skip"
+const reviewTriggerKey = "ai-review"
+
+// reviewTriggerRegex matches the trailer or the label, the separator of labels can also be a slash or a dash
+var reviewTriggerRegex = regexp.MustCompile(`(?i)^ai-review\s*[:/-]\s*(\w+)$`)
+
+// ReviewTrigger is the review mode requested by the author of the pull request
+type ReviewTrigger struct {
+	Mode   string // skip, full or security, empty if none was requested
+	Source string // The commit trailer or the label requesting the mode
+}
+
+// ParseReviewTrigger reads the requested review mode from the trailers of the reviewed commit, then from the labels.
+// The trailer only applies to the run of its commit, the label to every run while it is set.
+// The reviewed commit is the head commit of the pull request if it is not found among the commits.
+func ParseReviewTrigger(pr PullRequest, commitHash string) ReviewTrigger {
+	if len(pr.Commits) > 0 {
+		commit := pr.Commits[len(pr.Commits)-1]
+		for _, candidate := range pr.Commits {
+			if commitHash != "" && git.SameCommit(candidate.CommitHash, commitHash) {
+				commit = candidate
+			}
+		}
+		for _, line := range trailers(commit.Message) {
+			if mode := reviewTriggerMode(line); mode != "" {
+				return ReviewTrigger{Mode: mode, Source: "the trailer of commit " + shortCommit(commit.CommitHash)}
+			}
+		}
+	}
+
+	for _, label := range pr.Labels {
+		if mode := reviewTriggerMode(label.Name); mode != "" {
+			return ReviewTrigger{Mode: mode, Source: "the " + label.Name + " label"}
+		}
+	}
+	return ReviewTrigger{}
+}
+
+// reviewTriggerMode returns the known mode of the trailer or label, empty for other ones
+func reviewTriggerMode(value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(strings.ToLower(value), reviewTriggerKey) {
+		return ""
+	}
+	match := reviewTriggerRegex.FindStringSubmatch(value)
+	if match == nil {
+		return ""
+	}
+	switch mode := strings.ToLower(match[1]); mode {
+	case ReviewTriggerSkip, ReviewTriggerFull, ReviewTriggerSecurity:
+		return mode
+	}
+	return ""
+}
+
+// trailers returns the lines of the last paragraph of the commit message, where git puts the trailers
+func trailers(message string) []string {
+	paragraphs := strings.Split(strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n")), "\n\n")
+	if len(paragraphs) < 2 {
+		return nil
+	}
+	return strings.Split(paragraphs[len(paragraphs)-1], "\n")
+}
+
+// SecurityFindings returns the findings of the security category
+func SecurityFindings(findings []LineLevel) []LineLevel {
+	var security []LineLevel
+	for _, finding := range findings {
+		if finding.Category == CategorySecurity {
+			security = append(security, finding)
+		}
+	}
+	return security
+}
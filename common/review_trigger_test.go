@@ -0,0 +1,52 @@
+package common
+
+import "testing"
+
+func TestParseReviewTrigger(t *testing.T) {
+	commits := []Commit{
+		{CommitHash: "aaaaaaa111", Message: "Add the parser\n\nAI-Review: full"},
+		{CommitHash: "bbbbbbb222", Message: "Fix the docs\n\nSigned-off-by: Jane Doe <jane@example.com>\nai-review: Skip"},
+	}
+	tests := map[string]struct {
+		pr       PullRequest
+		commit   string
+		expected ReviewTrigger
+	}{
+		"trailer of the head commit": {
+			pr:       PullRequest{Commits: commits},
+			expected: ReviewTrigger{Mode: ReviewTriggerSkip, Source: "the trailer of commit bbbbbbb"},
+		},
+		"trailer of the reviewed commit": {
+			pr:       PullRequest{Commits: commits},
+			commit:   "aaaaaaa111",
+			expected: ReviewTrigger{Mode: ReviewTriggerFull, Source: "the trailer of commit aaaaaaa"},
+		},
+		"label": {
+			pr:       PullRequest{Labels: []Label{{Name: "bug"}, {Name: "ai-review/security"}}},
+			expected: ReviewTrigger{Mode: ReviewTriggerSecurity, Source: "the ai-review/security label"},
+		},
+		"trailer before the label": {
+			pr:       PullRequest{Commits: commits[:1], Labels: []Label{{Name: "AI-Review: skip"}}},
+			expected: ReviewTrigger{Mode: ReviewTriggerFull, Source: "the trailer of commit aaaaaaa"},
+		},
+		"subject is not a trailer": {
+			pr: PullRequest{Commits: []Commit{{CommitHash: "ccccccc333", Message: "AI-Review: skip"}}},
+		},
+		"unknown mode": {
+			pr: PullRequest{Labels: []Label{{Name: "ai-review: later"}}},
+		},
+	}
+	for name, test := range tests {
+		if got := ParseReviewTrigger(test.pr, test.commit); got != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", name, test.expected, got)
+		}
+	}
+}
+
+func TestSecurityFindings(t *testing.T) {
+	findings := []LineLevel{{File: "a.go", Category: CategoryBug}, {File: "b.go", Category: CategorySecurity}}
+	security := SecurityFindings(findings)
+	if len(security) != 1 || security[0].File != "b.go" {
+		t.Errorf("Expected only the security finding, got %+v", security)
+	}
+}
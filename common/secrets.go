@@ -0,0 +1,86 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// redactedSecret replaces the literal credentials of the posted texts
+const redactedSecret = "[REDACTED]"
+
+// secretPattern matches a kind of literal credential, the value is the last group or the whole match
+type secretPattern struct {
+	Kind  string
+	Regex *regexp.Regexp
+}
+
+// secretPatterns are the well-known formats of credentials, and the literal values assigned to credential names
+var secretPatterns = []secretPattern{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"LLM API key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}\b`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\b`)},
+	{"hardcoded credential", regexp.MustCompile(`(?i)(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)["']?\s*[:=]+\s*["']([^"'\s]{8,})["']`)},
+}
+
+// secretPlaceholderMarkers mark the values of credential names that are not literal credentials
+var secretPlaceholderMarkers = []string{"${", "$(", "{{", "<", "%s", "xxx", "***", "your", "example", "changeme", "placeholder", "redacted", "dummy", "test", "fake"}
+
+// SecretFinding is a literal credential found in a text
+type SecretFinding struct {
+	Kind  string
+	Value string
+}
+
+// ScanSecrets returns the literal credentials of the text, placeholders and references to variables are skipped.
+// A value matching multiple patterns is returned once, with the kind of the most specific pattern.
+func ScanSecrets(text string) []SecretFinding {
+	var findings []SecretFinding
+	found := map[string]bool{}
+	for _, pattern := range secretPatterns {
+		for _, match := range pattern.Regex.FindAllStringSubmatch(text, -1) {
+			value := match[len(match)-1]
+			if found[value] || isSecretPlaceholder(value) {
+				continue
+			}
+			found[value] = true
+			findings = append(findings, SecretFinding{Kind: pattern.Kind, Value: value})
+		}
+	}
+	return findings
+}
+
+// IntroducedSecrets returns the literal credentials of the suggestion missing from the original code.
+// LLMs sometimes replace a placeholder or an environment variable with a value seen in the context.
+func IntroducedSecrets(original, suggestion string) []SecretFinding {
+	var introduced []SecretFinding
+	for _, finding := range ScanSecrets(suggestion) {
+		if !strings.Contains(original, finding.Value) {
+			introduced = append(introduced, finding)
+		}
+	}
+	return introduced
+}
+
+// RedactSecrets replaces the literal credentials of the text, returns the text and the number of replaced credentials
+func RedactSecrets(text string) (string, int) {
+	findings := ScanSecrets(text)
+	for _, finding := range findings {
+		text = strings.ReplaceAll(text, finding.Value, redactedSecret)
+	}
+	return text, len(findings)
+}
+
+func isSecretPlaceholder(value string) bool {
+	lower := strings.ToLower(value)
+	for _, marker := range secretPlaceholderMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// sentryTimeout is the timeout in seconds for a request to Sentry
+	sentryTimeout = 30
+	// maxSentryFiles limits the files searched in a single tool call, as every file is a separate search
+	maxSentryFiles = 10
+	// maxSentryIssuesPerFile limits the issues listed for a file
+	maxSentryIssuesPerFile = 5
+)
+
+// sentryIssue is an issue of a Sentry project
+type sentryIssue struct {
+	ShortID   string `json:"shortId"`
+	Title     string `json:"title"`
+	Culprit   string `json:"culprit"`
+	Count     string `json:"count"`
+	UserCount int    `json:"userCount"`
+	LastSeen  string `json:"lastSeen"`
+	Permalink string `json:"permalink"`
+}
+
+// FetchRecentErrors returns the unresolved errors of the Sentry project with a stack frame in the files.
+// Files are matched by their name, as the paths of the frames depend on the build of the app.
+func FetchRecentErrors(sentry Sentry, files []string) (string, error) {
+	if !sentry.Enabled() {
+		return "", errors.New("sentry organization and project are not configured")
+	}
+	if len(files) == 0 {
+		return "", errors.New("at least one file must be provided")
+	}
+
+	token, _, err := CredentialSentry.Resolve()
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if len(files) > maxSentryFiles {
+		builder.WriteString(fmt.Sprintf("Only the first %d files are searched.\n\n", maxSentryFiles))
+		files = files[:maxSentryFiles]
+	}
+
+	for _, file := range files {
+		issues, err := fetchSentryIssues(sentry, token, path.Base(file))
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString(fmt.Sprintf("===== %s =====\n", file))
+		if len(issues) == 0 {
+			builder.WriteString(fmt.Sprintf("No unresolved %s errors in the last %s.\n\n", sentry.Environment, sentry.Period))
+			continue
+		}
+		for _, issue := range issues {
+			builder.WriteString(fmt.Sprintf("- %s %s", issue.ShortID, issue.Title))
+			if issue.Culprit != "" {
+				builder.WriteString(" in " + issue.Culprit)
+			}
+			builder.WriteString(fmt.Sprintf(": %s events, %d users, last seen %s\n  %s\n", issue.Count, issue.UserCount, issue.LastSeen, issue.Permalink))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String(), nil
+}
+
+// fetchSentryIssues searches the unresolved issues of the environment with a stack frame in the file, most frequent first
+func fetchSentryIssues(sentry Sentry, token, fileName string) ([]sentryIssue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sentryTimeout*time.Second)
+	defer cancel()
+
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf(`is:unresolved stack.filename:"*%s"`, fileName))
+	query.Set("environment", sentry.Environment)
+	query.Set("statsPeriod", sentry.Period)
+	query.Set("sort", "freq")
+	query.Set("limit", fmt.Sprint(maxSentryIssuesPerFile))
+
+	apiURL := fmt.Sprintf("%s/api/0/projects/%s/%s/issues/?%s", strings.TrimSuffix(sentry.URL, "/"),
+		url.PathEscape(sentry.Organization), url.PathEscape(sentry.Project), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := NewRetryableClient(DefaultRetryConfig()).StandardClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAPIError("Sentry", resp.StatusCode, fmt.Errorf("failed to search the issues of %s: HTTP %d", fileName, resp.StatusCode))
+	}
+
+	var issues []sentryIssue
+	if err := json.NewDecoder(resp.Body).Decode(&issues); err != nil {
+		return nil, fmt.Errorf("failed to decode issues: %w", err)
+	}
+	if len(issues) > maxSentryIssuesPerFile {
+		issues = issues[:maxSentryIssuesPerFile]
+	}
+	return issues, nil
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchRecentErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/0/projects/acme/ios-app/issues/" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer sentry-token" {
+			t.Errorf("Expected the token to be sent, got %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("environment") != "production" || r.URL.Query().Get("statsPeriod") != "14d" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+
+		if r.URL.Query().Get("query") == `is:unresolved stack.filename:"*LoginViewController.swift"` {
+			fmt.Fprint(w, `[{"shortId":"IOS-12","title":"EXC_BAD_ACCESS","culprit":"LoginViewController.login()","count":"1204","userCount":312,"lastSeen":"2026-10-15T09:00:00Z","permalink":"https://sentry.io/issues/12/"}]`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	t.Setenv(CredentialSentry.Env, "sentry-token")
+	sentry := WithDefaultSettings().Sentry
+	sentry.URL = server.URL
+	sentry.Organization = "acme"
+	sentry.Project = "ios-app"
+
+	output, err := FetchRecentErrors(sentry, []string{"App/Login/LoginViewController.swift", "App/Settings.swift"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"===== App/Login/LoginViewController.swift =====\n- IOS-12 EXC_BAD_ACCESS in LoginViewController.login(): 1204 events, 312 users",
+		"===== App/Settings.swift =====\nNo unresolved production errors in the last 14d.",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestFetchRecentErrorsNotConfigured(t *testing.T) {
+	if _, err := FetchRecentErrors(WithDefaultSettings().Sentry, []string{"main.go"}); err == nil {
+		t.Error("Expected an error without a Sentry project")
+	}
+}
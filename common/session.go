@@ -0,0 +1,221 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionFilePrefix is the file name prefix of the saved review sessions
+const SessionFilePrefix = "ai-review-session-"
+
+// Roles of the messages of a saved conversation
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// SessionToolCall is a tool call requested by the LLM
+type SessionToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// SessionMessage is a provider independent message of the conversation with the LLM
+type SessionMessage struct {
+	Role       string            `json:"role"`
+	Content    string            `json:"content"`
+	ToolCalls  []SessionToolCall `json:"tool_calls,omitempty"`   // Tool calls of assistant messages
+	ToolCallID string            `json:"tool_call_id,omitempty"` // Tool call answered by tool messages
+}
+
+// ReviewSession is the full state of a run: the conversation with the LLM and the feedback it posted.
+// It is saved encrypted, as the conversation contains the source code of the repository.
+type ReviewSession struct {
+	mu            sync.Mutex
+	StartedAt     time.Time        `json:"started_at"`
+	CodeReview    string           `json:"code_review"`
+	Repository    string           `json:"repository"`
+	PullRequest   int              `json:"pull_request"`
+	CommitHash    string           `json:"commit_hash"`
+	Provider      string           `json:"provider"`
+	Model         string           `json:"model"`
+	Messages      []SessionMessage `json:"messages"`
+	SummaryHeader string           `json:"summary_header,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	LineFeedback  []LineLevel      `json:"line_feedback,omitempty"`
+}
+
+var reviewSession = NewReviewSession()
+
+// NewReviewSession creates an empty review session
+func NewReviewSession() *ReviewSession {
+	return &ReviewSession{StartedAt: time.Now()}
+}
+
+// Session returns the session of the current run
+func Session() *ReviewSession {
+	return reviewSession
+}
+
+// SetPullRequest sets the reviewed pull request and the code review provider it is posted to
+func (s *ReviewSession) SetPullRequest(codeReview, repository string, pr int, commitHash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.CodeReview = codeReview
+	s.Repository = repository
+	s.PullRequest = pr
+	s.CommitHash = commitHash
+}
+
+// SetModel sets the LLM provider and the model which produced the conversation
+func (s *ReviewSession) SetModel(provider, model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Provider = provider
+	s.Model = model
+}
+
+// SetConversation replaces the saved conversation, the latest state of the conversation is the most complete one
+func (s *ReviewSession) SetConversation(messages []SessionMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages = slices.Clone(messages)
+}
+
+// SetSummary sets the posted summary comment
+func (s *ReviewSession) SetSummary(header, summary string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SummaryHeader = header
+	s.Summary = summary
+}
+
+// SetLineFeedback sets the line feedback to post, before grouping the related findings
+func (s *ReviewSession) SetLineFeedback(lines []LineLevel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LineFeedback = slices.Clone(lines)
+}
+
+// Save encrypts the session with the key and writes it into the directory, returns the path of the written file
+func (s *ReviewSession) Save(dir, key string) (string, error) {
+	s.mu.Lock()
+	content, err := json.Marshal(s)
+	s.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	encrypted, err := encryptSession(key, content)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt session: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create session directory: %w", err)
+	}
+	path := filepath.Join(dir, SessionFilePrefix+s.StartedAt.UTC().Format("20060102-150405")+".bin")
+	if err := os.WriteFile(path, encrypted, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	return path, nil
+}
+
+// LoadSession reads and decrypts a saved session
+func LoadSession(path, key string) (*ReviewSession, error) {
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	content, err := decryptSession(key, encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &ReviewSession{}
+	if err := json.Unmarshal(content, session); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return session, nil
+}
+
+// Transcript formats the conversation of the session for reading
+func (s *ReviewSession) Transcript() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Review of %s#%d at %s by %s (%s), started at %s\n",
+		s.Repository, s.PullRequest, s.CommitHash, s.Model, s.Provider, s.StartedAt.UTC().Format(time.RFC3339)))
+
+	for _, message := range s.Messages {
+		role := message.Role
+		if message.ToolCallID != "" {
+			role += " " + message.ToolCallID
+		}
+		builder.WriteString(fmt.Sprintf("\n===== %s =====\n", role))
+		if message.Content != "" {
+			builder.WriteString(message.Content + "\n")
+		}
+		for _, toolCall := range message.ToolCalls {
+			builder.WriteString(fmt.Sprintf("→ %s %s(%s)\n", toolCall.ID, toolCall.Name, toolCall.Arguments))
+		}
+	}
+	return builder.String()
+}
+
+// sessionCipher derives the AES-256-GCM cipher of the sessions from the key
+func sessionCipher(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("session key cannot be empty")
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptSession(key string, content []byte) ([]byte, error) {
+	aead, err := sessionCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, content, nil), nil
+}
+
+func decryptSession(key string, encrypted []byte) ([]byte, error) {
+	aead, err := sessionCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encrypted) < aead.NonceSize() {
+		return nil, errors.New("session file is corrupted")
+	}
+	nonce, ciphertext := encrypted[:aead.NonceSize()], encrypted[aead.NonceSize():]
+	content, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt session, check the session key")
+	}
+	return content, nil
+}
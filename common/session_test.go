@@ -0,0 +1,70 @@
+package common
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReviewSessionSaveAndLoad(t *testing.T) {
+	session := NewReviewSession()
+	session.SetPullRequest("github", "my-org/my-repo", 42, "abc123")
+	session.SetModel("openai", "gpt-4.1")
+	session.SetConversation([]SessionMessage{
+		{Role: RoleSystem, Content: "You are Bit Bot"},
+		{Role: RoleUser, Content: "Review the pull request"},
+		{Role: RoleAssistant, ToolCalls: []SessionToolCall{{ID: "call_1", Name: "get_git_diff", Arguments: `{"target":"main"}`}}},
+		{Role: RoleTool, ToolCallID: "call_1", Content: "+func secret() {}"},
+	})
+	session.SetSummary("[bitrise-plugin-ai-reviewer]: summary", "## Summary")
+	session.SetLineFeedback([]LineLevel{{File: "main.go", LineNumber: 3, Body: "Missing error handling", Category: CategoryBug}})
+
+	dir := t.TempDir()
+	path, err := session.Save(dir, "secret-key")
+	if err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	// The conversation contains source code, it must not be readable without the key
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read session: %v", err)
+	}
+	if strings.Contains(string(content), "func secret") {
+		t.Error("Expected the session file to be encrypted")
+	}
+
+	loaded, err := LoadSession(path, "secret-key")
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Messages, session.Messages) || !reflect.DeepEqual(loaded.LineFeedback, session.LineFeedback) {
+		t.Errorf("Expected the loaded session to match the saved one, got %+v", loaded)
+	}
+	if loaded.Repository != "my-org/my-repo" || loaded.PullRequest != 42 || loaded.Summary != "## Summary" {
+		t.Errorf("Unexpected session details: %+v", loaded)
+	}
+
+	if _, err := LoadSession(path, "wrong-key"); err == nil {
+		t.Error("Expected an error for a wrong session key")
+	}
+}
+
+func TestReviewSessionTranscript(t *testing.T) {
+	session := NewReviewSession()
+	session.SetConversation([]SessionMessage{
+		{Role: RoleAssistant, Content: "Let me check the diff", ToolCalls: []SessionToolCall{{ID: "call_1", Name: "get_git_diff", Arguments: `{"target":"main"}`}}},
+		{Role: RoleTool, ToolCallID: "call_1", Content: "+func main() {}"},
+	})
+
+	transcript := session.Transcript()
+	for _, expected := range []string{
+		"===== assistant =====\nLet me check the diff\n→ call_1 get_git_diff({\"target\":\"main\"})",
+		"===== tool call_1 =====\n+func main() {}",
+	} {
+		if !strings.Contains(transcript, expected) {
+			t.Errorf("Expected the transcript to contain %q, got:\n%s", expected, transcript)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package common
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
 	"gopkg.in/yaml.v3"
@@ -13,35 +16,238 @@ const (
 	ProfileAssertive = "assertive"
 )
 
+const (
+	CelebrationHaiku    = "haiku"
+	CelebrationLimerick = "limerick"
+	CelebrationNone     = "none"
+	CelebrationCustom   = "custom"
+)
+
+const (
+	RulePackIOS     = "ios"
+	RulePackAndroid = "android"
+)
+
+// Roles of the tools of the LLM, configurable per command
+const (
+	ToolRoleInitializer = "initializer" // Called first, before any other tool
+	ToolRoleHelper      = "helper"      // Called any time during the review
+	ToolRoleFinalizer   = "finalizer"   // Called any time, and the only tools offered when the review runs out of tool calls
+	ToolRoleDisabled    = "disabled"    // Never offered to the LLM
+)
+
+// CommandSummarize is the name of the review command in the tool settings
+const CommandSummarize = "summarize"
+
+// ToolRoles overrides the default roles of the tools, by tool name
+type ToolRoles map[string]string
+
+// ToolSettings are the tool roles of the commands, by command name
+type ToolSettings map[string]ToolRoles
+
+// Disabled returns the names of the disabled tools
+func (r ToolRoles) Disabled() []string {
+	var disabled []string
+	for name, role := range r {
+		if role == ToolRoleDisabled {
+			disabled = append(disabled, name)
+		}
+	}
+	slices.Sort(disabled)
+	return disabled
+}
+
+type Celebration struct {
+	Type   string `yaml:"type"`
+	Title  string `yaml:"title"`
+	Prompt string `yaml:"prompt"`
+}
+
 type Reviews struct {
-	Profile             string `yaml:"profile"`
-	Summary             bool   `yaml:"summary"`
-	Walkthrough         bool   `yaml:"walkthrough"`
-	CollapseWalkthrough bool   `yaml:"collapse_walkthrough"`
-	Haiku               bool   `yaml:"haiku"`
-	PathFilters         string `yaml:"path_filters"`
-	PathInstructions    string `yaml:"path_instructions"`
+	Profile                string            `yaml:"profile"`
+	Summary                bool              `yaml:"summary"`
+	Walkthrough            bool              `yaml:"walkthrough"`
+	CollapseWalkthrough    bool              `yaml:"collapse_walkthrough"`
+	Haiku                  bool              `yaml:"haiku"` // Deprecated: disables the celebration section when false
+	PathFilters            string            `yaml:"path_filters"`
+	PathInstructions       string            `yaml:"path_instructions"`
+	DocumentationDrift     bool              `yaml:"documentation_drift"`
+	SummaryTemplate        string            `yaml:"summary_template"`
+	Celebration            Celebration       `yaml:"celebration"`
+	VerifySuggestions      bool              `yaml:"verify_suggestions"`      // Asks the LLM to check the code suggestions in extra requests, the syntax is always checked
+	ClarificationQuestions int               `yaml:"clarification_questions"` // Maximum number of questions to the author, 0 disables them
+	FailOnSeverity         string            `yaml:"fail_on_severity"`        // Fails the run with exit code 10 on findings of this severity or higher, empty never fails
+	FileContentBudget      int               `yaml:"file_content_budget"`     // Maximum total size of the changed files read in bytes, 0 disables it
+	DebounceMinutes        int               `yaml:"debounce_minutes"`        // Skips the run if the pull request was reviewed this many minutes ago, 0 disables it
+	CompactSummary         CompactSummary    `yaml:"compact_summary"`         // Thresholds of the single-file and tiny pull requests summarized in a short paragraph
+	Quota                  ReviewQuota       `yaml:"quota"`                   // Maximum number of full reviews of a pull request per day or week
+	CommitStatus           bool              `yaml:"commit_status"`           // Sets the ai-review/verdict commit status by the findings and the fail_on policies
+	CondenseDiffLines      int               `yaml:"condense_diff_lines"`     // Replaces the mechanical changes of diffs with more changed lines with descriptions, 0 disables it
+	Lock                   ReviewLock        `yaml:"lock"`                    // Keeps concurrent runs from reviewing the same pull request
+	WalkthroughGroups      WalkthroughGroups `yaml:"walkthrough_groups"`      // Groups the walkthrough of large pull requests by directory
+}
+
+type Compliance struct {
+	Enabled            bool     `yaml:"enabled"`
+	DisallowedLicenses []string `yaml:"disallowed_licenses"`
+	FlagCopyleft       bool     `yaml:"flag_copyleft"`
+	Blocking           bool     `yaml:"blocking"`
+}
+
+type GlossaryTerm struct {
+	Term  string   `yaml:"term"`
+	Avoid []string `yaml:"avoid"`
+}
+
+type CopyReview struct {
+	Enabled  bool           `yaml:"enabled"`
+	Glossary []GlossaryTerm `yaml:"glossary"`
+}
+
+type HotPaths struct {
+	Paths   []string `yaml:"paths"`
+	Symbols []string `yaml:"symbols"`
+}
+
+type FeatureFlags struct {
+	SDKPatterns      []string `yaml:"sdk_patterns"`
+	NamingConvention string   `yaml:"naming_convention"`
+}
+
+// TODOTracking lists the added TODO comments in the summary, and flags the ones without an issue reference
+type TODOTracking struct {
+	Enabled      bool   `yaml:"enabled"`
+	IssuePattern string `yaml:"issue_pattern"`
+}
+
+type AppSize struct {
+	WarnIncreaseKB      int64   `yaml:"warn_increase_kb"`
+	WarnIncreasePercent float64 `yaml:"warn_increase_percent"`
+}
+
+// Sentry is the project of the production errors returned by the get_recent_errors tool
+type Sentry struct {
+	URL          string `yaml:"url"`
+	Organization string `yaml:"organization"`
+	Project      string `yaml:"project"`
+	Environment  string `yaml:"environment"`
+	Period       string `yaml:"period"` // e.g. 24h or 14d
+}
+
+// Enabled returns true if the project is configured
+func (s Sentry) Enabled() bool {
+	return s.Organization != "" && s.Project != ""
+}
+
+type QuickReview struct {
+	MaxChangedLines int    `yaml:"max_changed_lines"`
+	Model           string `yaml:"model"`
+}
+
+type ModelOptions struct {
+	Temperature       *float64 `yaml:"temperature"`
+	TopP              *float64 `yaml:"top_p"`
+	ReasoningEffort   string   `yaml:"reasoning_effort"`
+	MaxThinkingTokens int      `yaml:"max_thinking_tokens"`
+	ContextWindow     int      `yaml:"context_window"` // in tokens, for models not known by the plugin
+}
+
+type ModelFallback struct {
+	Provider  string `yaml:"provider"`
+	Model     string `yaml:"model"`
+	APIKeyEnv string `yaml:"api_key_env"`
+}
+
+type PromptVariant struct {
+	Name         string `yaml:"name"`
+	Weight       int    `yaml:"weight"`
+	Instructions string `yaml:"instructions"`
+}
+
+// Persona is a reviewer voice for the files matching its path globs, like the frontend or the infrastructure of a monorepo
+type Persona struct {
+	Name         string   `yaml:"name"`
+	Paths        []string `yaml:"paths"`
+	Tone         string   `yaml:"tone"`
+	Instructions string   `yaml:"instructions"`
 }
 
 type Settings struct {
-	Language string  `yaml:"language"`
-	Tone     string  `yaml:"tone_instructions"`
-	Reviews  Reviews `yaml:"reviews"`
+	ConfigURL      string          `yaml:"config_url"`
+	Language       string          `yaml:"language"`
+	Timezone       string          `yaml:"timezone"`
+	Tone           string          `yaml:"tone_instructions"`
+	Style          string          `yaml:"style"`
+	Identity       Identity        `yaml:"identity"`
+	SecretsFree    bool            `yaml:"secrets_free"`
+	Pseudonymize   bool            `yaml:"pseudonymize_authors"`
+	RulePacks      []string        `yaml:"rule_packs"`
+	Reviews        Reviews         `yaml:"reviews"`
+	Compliance     Compliance      `yaml:"compliance"`
+	CopyReview     CopyReview      `yaml:"copy_review"`
+	HotPaths       HotPaths        `yaml:"hot_paths"`
+	FeatureFlags   FeatureFlags    `yaml:"feature_flags"`
+	AppSize        AppSize         `yaml:"app_size"`
+	QuickReview    QuickReview     `yaml:"quick_review"`
+	PromptVariants []PromptVariant `yaml:"prompt_variants"`
+	Personas       []Persona       `yaml:"personas"`
+	BranchPolicies []BranchPolicy  `yaml:"branch_policies"`
+	TODOs          TODOTracking    `yaml:"todos"`
+	ModelOptions   ModelOptions    `yaml:"model_options"`
+	ModelFallbacks []ModelFallback `yaml:"model_fallbacks"`
+	ExternalRepos  []string        `yaml:"external_repos"`
+	Timeouts       Timeouts        `yaml:"timeouts"`
+	Tools          ToolSettings    `yaml:"tools"`
+	CustomTools    []CustomTool    `yaml:"custom_tools"`
+	Formatters     []Formatter     `yaml:"formatters"`
+	Languages      Languages       `yaml:"languages"`
+	MCPServers     []MCPServer     `yaml:"mcp_servers"`
+	Sentry         Sentry          `yaml:"sentry"`
 }
 
 func WithDefaultSettings() Settings {
 	return Settings{
 		Language: "en-US",
+		Style:    StyleRich,
 		Reviews: Reviews{
 			Summary:             true,
 			Walkthrough:         true,
 			CollapseWalkthrough: true,
 			Haiku:               true,
+			Celebration:         Celebration{Type: CelebrationHaiku},
+			DocumentationDrift:  true,
 			Profile:             ProfileChill,
+			FileContentBudget:   20 * 1024 * 1024,
+			CompactSummary:      CompactSummary{MaxFiles: 1, MaxChangedLines: 30},
+			CondenseDiffLines:   1000,
+			Lock:                ReviewLock{WaitMinutes: 10},
+			WalkthroughGroups:   WalkthroughGroups{MinFiles: 100, Depth: 1, MaxRows: 20},
+		},
+		Compliance: Compliance{
+			FlagCopyleft: true,
+		},
+		Timeouts: Timeouts{
+			LLMCall:      60,
+			ProviderCall: 60,
+			GitCommand:   120,
+		},
+		Sentry: Sentry{
+			URL:         "https://sentry.io",
+			Environment: "production",
+			Period:      "14d",
 		},
 	}
 }
 
+// repositoryCommandsAllowed allows the repository settings file to configure the commands run by the plugin
+var repositoryCommandsAllowed = false
+
+// AllowRepositoryCommands allows the commands of the repository settings file, like the commands of the custom formatters.
+// By default they are only taken from the shared configuration of the environment, as a pull request can change the repository file.
+func AllowRepositoryCommands(allowed bool) {
+	repositoryCommandsAllowed = allowed
+}
+
 func WithYamlFile() Settings {
 	settings := WithDefaultSettings()
 
@@ -62,20 +268,120 @@ func WithYamlFile() Settings {
 		return nil
 	})
 
+	var data []byte
 	switch filePath {
 	case "":
 		logger.Infof("No YAML file found in the current directory or subdirectories. Using default settings.")
 	default:
 		logger.Infof("Using settings from YAML file: %s", filePath)
-		data, err := os.ReadFile(filePath)
-		if err == nil {
-			if err := yaml.Unmarshal(data, &settings); err != nil {
-				logger.Warnf("Failed to parse YAML file %s, switching back to default settings: %v", filePath, err)
-			}
-		} else {
+		var err error
+		data, err = os.ReadFile(filePath)
+		if err != nil {
 			logger.Warnf("Failed to read YAML file %s, switching back to default settings: %v", filePath, err)
+			data = nil
+		}
+	}
+
+	// The shared configuration sits beneath the repository level settings
+	configURL := getSharedConfigURL(data)
+	if configURL != "" {
+		settings = withSharedConfig(settings, configURL)
+	}
+
+	// Only the commands of the shared configuration set in the environment are trusted,
+	// the repository file and its config_url can be changed by the pull request
+	trusted := WithDefaultSettings()
+	if configURL != "" && configURL == os.Getenv(SharedConfigURLEnv) {
+		trusted = settings
+	}
+
+	if data != nil {
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			logger.Warnf("Failed to parse YAML file %s, switching back to default settings: %v", filePath, err)
 		}
 	}
 
+	if !repositoryCommandsAllowed {
+		settings = withTrustedCommands(settings, trusted)
+	}
 	return settings
 }
+
+// withTrustedCommands drops the commands of the settings which are not in the trusted settings, along with the roles of the dropped tools.
+// The formatters with a preset fall back to the command of the preset.
+func withTrustedCommands(settings, trusted Settings) Settings {
+	var formatters []Formatter
+	for _, formatter := range settings.Formatters {
+		isTrusted := func(other Formatter) bool { return other.Name == formatter.Name && other.Command == formatter.Command }
+		if formatter.Command != "" && !slices.ContainsFunc(trusted.Formatters, isTrusted) {
+			if _, ok := formatterPresets[formatter.Name]; !ok {
+				logger.Warnf("Skipping formatter %s, the commands of the repository settings are only run with --allow-repository-commands", formatter.Name)
+				continue
+			}
+			logger.Warnf("Running the preset of formatter %s, the commands of the repository settings are only run with --allow-repository-commands", formatter.Name)
+			formatter.Command = ""
+		}
+		formatters = append(formatters, formatter)
+	}
+	settings.Formatters = formatters
+
+	// MCP servers get the expanded secrets of their env, a changed server is not started
+	var servers []MCPServer
+	for _, server := range settings.MCPServers {
+		isTrusted := func(other MCPServer) bool { return reflect.DeepEqual(other, server) }
+		if !slices.ContainsFunc(trusted.MCPServers, isTrusted) {
+			logger.Warnf("Skipping MCP server %s, the commands of the repository settings are only run with --allow-repository-commands", server.Name)
+			continue
+		}
+		servers = append(servers, server)
+	}
+	settings.MCPServers = servers
+
+	// Custom tools run their command with the arguments of the LLM, a changed tool is not offered
+	var tools []CustomTool
+	for _, tool := range settings.CustomTools {
+		isTrusted := func(other CustomTool) bool { return reflect.DeepEqual(other, tool) }
+		if !slices.ContainsFunc(trusted.CustomTools, isTrusted) {
+			logger.Warnf("Skipping custom tool %s, the commands of the repository settings are only run with --allow-repository-commands", tool.Name)
+			settings.Tools = withoutToolRoles(settings.Tools, tool.Name)
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	settings.CustomTools = tools
+	return settings
+}
+
+// withoutToolRoles returns a copy of the tool settings without the roles of the tool
+func withoutToolRoles(settings ToolSettings, name string) ToolSettings {
+	filtered := ToolSettings{}
+	for command, roles := range settings {
+		filtered[command] = ToolRoles{}
+		for tool, role := range roles {
+			if tool != name {
+				filtered[command][tool] = role
+			}
+		}
+	}
+	return filtered
+}
+
+// IsExternalRepoAllowed returns true if the repository in the owner/repo format is on the external repository allowlist
+func (s Settings) IsExternalRepoAllowed(repository string) bool {
+	for _, allowed := range s.ExternalRepos {
+		if strings.EqualFold(allowed, repository) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMobileRulePack returns true if any of the mobile rule packs is enabled
+func (s Settings) HasMobileRulePack() bool {
+	for _, pack := range s.RulePacks {
+		if pack == RulePackIOS || pack == RulePackAndroid {
+			return true
+		}
+	}
+	return false
+}
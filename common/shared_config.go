@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SharedConfigURLEnv is the environment variable to set the shared configuration URL
+	SharedConfigURLEnv = "REVIEW_CONFIG_URL"
+	// SharedConfigTokenEnv is the environment variable holding the token to fetch a private shared configuration
+	SharedConfigTokenEnv = "REVIEW_CONFIG_TOKEN"
+	// sharedConfigTimeout is the timeout in seconds for fetching the shared configuration
+	sharedConfigTimeout = 30
+)
+
+// getSharedConfigURL returns the shared configuration URL from the repository settings,
+// falling back to the environment variable
+func getSharedConfigURL(data []byte) string {
+	var repoSettings struct {
+		ConfigURL string `yaml:"config_url"`
+	}
+	if err := yaml.Unmarshal(data, &repoSettings); err == nil && repoSettings.ConfigURL != "" {
+		return repoSettings.ConfigURL
+	}
+
+	return os.Getenv(SharedConfigURLEnv)
+}
+
+// withSharedConfig applies the organization level configuration fetched from configURL on top of settings.
+// If the configuration can not be fetched or parsed, settings are returned unchanged.
+func withSharedConfig(settings Settings, configURL string) Settings {
+	logger.Infof("Fetching shared settings from: %s", configURL)
+
+	data, err := fetchSharedConfig(configURL)
+	if err != nil {
+		logger.Warnf("Failed to fetch shared settings from %s, ignoring it: %v", configURL, err)
+		return settings
+	}
+
+	shared := settings
+	if err := yaml.Unmarshal(data, &shared); err != nil {
+		logger.Warnf("Failed to parse shared settings from %s, ignoring it: %v", configURL, err)
+		return settings
+	}
+	shared.ConfigURL = configURL
+
+	return shared
+}
+
+// fetchSharedConfig downloads the raw shared configuration
+func fetchSharedConfig(configURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedConfigTimeout*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", configURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token := os.Getenv(SharedConfigTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := NewRetryableClient(DefaultRetryConfig()).StandardClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
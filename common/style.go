@@ -0,0 +1,103 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	StyleRich  = "rich"
+	StylePlain = "plain"
+)
+
+// leadingSpaceRegex matches the space left at the start of bold text and summaries by a removed emoji
+var leadingSpaceRegex = regexp.MustCompile(`((?:^|\s)\*\*|<summary>)\s+`)
+
+// outputStyle is the visual style of the posted comments
+var outputStyle = StyleRich
+
+// SetStyle sets the visual style of the posted comments, plain strips emojis and decorative markdown
+func SetStyle(style string) {
+	outputStyle = style
+	if style != StylePlain {
+		outputStyle = StyleRich
+	}
+}
+
+// ApplyStyle formats a comment with the configured style.
+// Code blocks are kept unchanged, as the emojis in them may be part of the code.
+func ApplyStyle(markdown string) string {
+	if outputStyle != StylePlain {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	styled := make([]string, 0, len(lines))
+	inCodeBlock := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeBlock = !inCodeBlock
+			styled = append(styled, line)
+			continue
+		}
+		if inCodeBlock {
+			styled = append(styled, line)
+			continue
+		}
+
+		switch strings.TrimSpace(line) {
+		case "---":
+			// Decorative horizontal rules
+			continue
+		case "> [!NOTE]":
+			line = "> Note:"
+		}
+
+		styled = append(styled, stripEmojis(line))
+	}
+
+	return strings.Join(styled, "\n")
+}
+
+// stripEmojis removes emojis and the spaces left behind by them
+func stripEmojis(line string) string {
+	var builder strings.Builder
+	for _, r := range line {
+		if !isEmoji(r) {
+			builder.WriteRune(r)
+		}
+	}
+
+	stripped := builder.String()
+	if stripped == line {
+		return line
+	}
+
+	// Collapse the double spaces left behind, keeping the indentation and markdown line breaks
+	indentation := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	trailing := ""
+	if strings.HasSuffix(line, "  ") {
+		trailing = "  "
+	}
+	content := strings.Join(strings.Fields(stripped), " ")
+	content = leadingSpaceRegex.ReplaceAllString(content, "$1")
+	return indentation + content + trailing
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Pictographs, emoticons, transport and map symbols, flags
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // Stars, arrows and squares used as emojis
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // Technical symbols like ⌛ and ⏰
+		return true
+	case r == 0xFE0F || r == 0x200D || r == 0x20E3: // Variation selector, zero width joiner, keycap
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // Tags of subdivision flags
+		return true
+	}
+	return false
+}
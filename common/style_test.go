@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestApplyStyle(t *testing.T) {
+	defer SetStyle(StyleRich)
+
+	markdown := "**🐛 Bug: Missing check**\n\n" +
+		"Use **strict** mode ✅ here\n" +
+		"<summary>🤖 Prompt for AI Agents:</summary>\n" +
+		"---\n" +
+		"> [!NOTE]\n" +
+		"```suggestion\nlog(\"🚀 done\")\n```"
+
+	SetStyle(StyleRich)
+	if styled := ApplyStyle(markdown); styled != markdown {
+		t.Errorf("Expected rich style to keep the markdown unchanged, got:\n%s", styled)
+	}
+
+	SetStyle(StylePlain)
+	expected := "**Bug: Missing check**\n\n" +
+		"Use **strict** mode here\n" +
+		"<summary>Prompt for AI Agents:</summary>\n" +
+		"> Note:\n" +
+		"```suggestion\nlog(\"🚀 done\")\n```"
+	if styled := ApplyStyle(markdown); styled != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, styled)
+	}
+}
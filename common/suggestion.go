@@ -0,0 +1,85 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// suggestionContextLines is the number of lines shown around the commented lines when verifying a suggestion
+const suggestionContextLines = 15
+
+// SuggestionVerdict is the result of the verification of a suggestion
+type SuggestionVerdict struct {
+	Applies           bool   `json:"applies"`            // The suggestion replaces the commented lines cleanly
+	PreservesBehavior bool   `json:"preserves_behavior"` // The suggestion only changes what the comment describes
+	Reason            string `json:"reason"`
+}
+
+// Passed returns true if the suggestion can be posted
+func (v SuggestionVerdict) Passed() bool {
+	return v.Applies && v.PreservesBehavior
+}
+
+// ParseSuggestionVerdict parses the verdict of the LLM, tolerating a surrounding code block or text
+func ParseSuggestionVerdict(content string) (SuggestionVerdict, error) {
+	var verdict SuggestionVerdict
+	if err := DecodeLLMJSON(content, &verdict); err != nil {
+		return SuggestionVerdict{}, fmt.Errorf("failed to parse suggestion verdict: %w", err)
+	}
+	return verdict, nil
+}
+
+// commentedLines returns the zero based range of the commented lines in the file
+func (l LineLevel) commentedLines(lineCount int) (int, int, error) {
+	first := l.LineNumber
+	last := max(l.LastLineNumber, first)
+	if first < 1 || last > lineCount {
+		return 0, 0, fmt.Errorf("lines %d-%d are out of the file of %d lines", first, last, lineCount)
+	}
+	return first - 1, last, nil
+}
+
+// ApplySuggestion returns the file content with the suggestion replacing the commented lines
+func ApplySuggestion(content string, ll LineLevel) (string, error) {
+	lines := strings.Split(content, "\n")
+	start, end, err := ll.commentedLines(len(lines))
+	if err != nil {
+		return "", err
+	}
+
+	applied := append([]string{}, lines[:start]...)
+	applied = append(applied, strings.Split(ll.Suggestion, "\n")...)
+	applied = append(applied, lines[end:]...)
+	return strings.Join(applied, "\n"), nil
+}
+
+// SuggestionContext returns the commented lines with the surrounding code, each line prefixed with its line number
+func SuggestionContext(content string, ll LineLevel) (string, error) {
+	lines := strings.Split(content, "\n")
+	start, end, err := ll.commentedLines(len(lines))
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	for i := max(start-suggestionContextLines, 0); i < min(end+suggestionContextLines, len(lines)); i++ {
+		builder.WriteString(fmt.Sprintf("%d: %s\n", i+1, lines[i]))
+	}
+	return builder.String(), nil
+}
+
+// CheckSuggestionSyntax checks that the file still parses with the suggestion applied, returns a *SyntaxError if not.
+// Go, JSON and YAML files are parsed, Swift, Kotlin and Java files are checked for balanced brackets, other languages pass.
+// Files which didn't parse before the suggestion are not checked, like JSON with comments.
+func CheckSuggestionSyntax(file, original, applied string) error {
+	checker, ok := syntaxCheckers[strings.ToLower(filepath.Ext(file))]
+	if !ok || checker.check(file, original) != nil {
+		return nil
+	}
+
+	if err := checker.check(file, applied); err != nil {
+		return &SyntaxError{Err: err, Heuristic: checker.heuristic}
+	}
+	return nil
+}
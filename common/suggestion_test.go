@@ -0,0 +1,85 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplySuggestion(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tprintln(\"a\")\n\tprintln(\"b\")\n}\n"
+	ll := LineLevel{File: "main.go", LineNumber: 4, LastLineNumber: 5, Suggestion: "\tprintln(\"ab\")"}
+
+	applied, err := ApplySuggestion(content, ll)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "package main\n\nfunc main() {\n\tprintln(\"ab\")\n}\n"
+	if applied != expected {
+		t.Errorf("Expected %q, got %q", expected, applied)
+	}
+	if err := CheckSuggestionSyntax(ll.File, content, applied); err != nil {
+		t.Errorf("Expected valid syntax, got %v", err)
+	}
+
+	ll.Suggestion = "\tprintln(\"ab\")\n}"
+	broken, _ := ApplySuggestion(content, ll)
+	if err := CheckSuggestionSyntax(ll.File, content, broken); err == nil {
+		t.Error("Expected a syntax error for the duplicated closing bracket")
+	}
+
+	ll.LineNumber = 10
+	if _, err := ApplySuggestion(content, ll); err == nil {
+		t.Error("Expected an error for lines out of the file")
+	}
+}
+
+func TestParseSuggestionVerdict(t *testing.T) {
+	verdict, err := ParseSuggestionVerdict("```json\n{\"applies\": true, \"preserves_behavior\": false, \"reason\": \"drops the error\"}\n```")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if verdict.Passed() || verdict.Reason != "drops the error" {
+		t.Errorf("Unexpected verdict: %+v", verdict)
+	}
+
+	if _, err := ParseSuggestionVerdict("looks good"); err == nil {
+		t.Error("Expected an error for a non JSON response")
+	}
+}
+
+func TestCheckSuggestionSyntax(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		original  string
+		applied   string
+		wantErr   bool
+		heuristic bool
+	}{
+		{"valid json", "config.json", `{"a": 1}`, `{"a": 2}`, false, false},
+		{"broken json", "config.json", `{"a": 1}`, `{"a": 2,}`, true, false},
+		{"json with comments", "tsconfig.json", "{\n// comment\n\"a\": 1}", "{\n// comment\n\"a\": 2,}", false, false},
+		{"valid yaml", "ci.yml", "a: 1\n---\nb: 2\n", "a: 2\n---\nb: 2\n", false, false},
+		{"broken yaml", "ci.yaml", "a:\n  b: 1\n", "a:\n  b: 1\n c: 2\n", true, false},
+		{"valid swift", "App.swift", "func a() {\n}\n", "func a() {\n  print(\"}\") // }\n}\n", false, false},
+		{"broken swift", "App.swift", "func a() {\n}\n", "func a() {\n  if b {\n}\n", true, true},
+		{"kotlin multi-line string", "App.kt", "val a = 1\n", "val a = \"\"\"\n{\n\"\"\"\n", false, false},
+		{"kotlin char literal", "App.kt", "val a = 1\n", "val a = '{'\n", false, false},
+		{"nested comment", "App.swift", "let a = 1\n", "/* a /* { */ */\nlet a = 1\n", false, false},
+		{"broken java", "App.java", "class A {}\n", "class A {)\n", true, true},
+		{"other language", "script.rb", "def a\nend\n", "def a\n", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckSuggestionSyntax(test.file, test.original, test.applied)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Expected error %v, got %v", test.wantErr, err)
+			}
+			var syntaxErr *SyntaxError
+			if err != nil && (!errors.As(err, &syntaxErr) || syntaxErr.Heuristic != test.heuristic) {
+				t.Errorf("Expected a syntax error with heuristic %v, got %v", test.heuristic, err)
+			}
+		})
+	}
+}
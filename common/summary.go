@@ -3,19 +3,43 @@ package common
 import (
 	"fmt"
 	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
 )
 
 // Walkthrough represents information about changes to specific files
 type Walkthrough struct {
 	Files   string `json:"files"`   // List of files changed
 	Summary string `json:"summary"` // Summary of the changes
+	Impact  string `json:"-"`       // Estimated impact of the changes, set when the walkthrough is ranked
 }
 
 // Summary represents a comprehensive review summary with multiple components
 type Summary struct {
-	Summary     string        `json:"summary"`     // Overall summary of the changes
-	Walkthrough []Walkthrough `json:"walkthrough"` // Detailed walkthrough of individual file changes
-	Haiku       string        `json:"haiku"`       // Haiku celebrating the changes
+	Summary         string                `json:"summary"`                    // Overall summary of the changes
+	Walkthrough     []Walkthrough         `json:"walkthrough"`                // Detailed walkthrough of individual file changes
+	Celebration     string                `json:"celebration"`                // Haiku, limerick or custom section celebrating the changes
+	MergeConfidence string                `json:"merge_confidence,omitempty"` // Merge confidence verdict for dependency updates
+	Compliance      string                `json:"compliance,omitempty"`       // License compliance of the added dependencies
+	ContractChanges string                `json:"contract_changes,omitempty"` // Compatibility of the changed API contracts
+	CIConfigReview  string                `json:"ci_config_review,omitempty"` // Review of the Bitrise CI configuration changes
+	Performance     string                `json:"performance,omitempty"`      // Performance review of the changed hot paths
+	FeatureFlags    string                `json:"feature_flags,omitempty"`    // Review of the introduced and removed feature flags
+	AppSize         string                `json:"app_size,omitempty"`         // App size impact of the changes
+	Assets          string                `json:"assets,omitempty"`           // Changed images and localization files
+	Personas        string                `json:"personas,omitempty"`         // Reviewer personas of the changed areas
+	Languages       string                `json:"languages,omitempty"`        // Share of the languages in the changed lines, like "70% Go, 20% YAML"
+	ReleaseRisk     string                `json:"release_risk,omitempty"`     // Release risk of pull requests targeting a release branch
+	QuotaNote       string                `json:"-"`                          // Replaces the note of the quick review when the review quota was reached
+	Applied         int                   `json:"-"`                          // Suggestions of the reviews applied in the repository this quarter
+	Diagram         Diagram               `json:"-"`                          // Diagram of the changed structure or call flow
+	TODOs           []TODOComment         `json:"-"`                          // TODO comments added by the changes
+	Stats           SummaryStats          `json:"-"`                          // Statistics of the review
+	Permalinks      Permalinks            `json:"-"`                          // Links the referenced files to the reviewed commit
+	Author          string                `json:"-"`                          // User ID of the pull request author to mention
+	Impacts         map[string]FileImpact `json:"-"`                          // Impact of the changed files, ranks the walkthrough
+	Tracking        SummaryState          `json:"-"`                          // Commit and changed files of this review, embedded in the comment
+	Previous        SummaryState          `json:"-"`                          // State of the previously posted summary
 }
 
 // Header returns the HTML comment that identifies this as a summary from the plugin
@@ -25,65 +49,167 @@ func (s Summary) Header() string {
 
 // String formats the complete summary as a markdown string
 func (s Summary) String(provider string, settings Settings) string {
-	var builder strings.Builder
-	builder.WriteString(s.Header() + "\n\n")
-
-	if provider == "github" {
-		if settings.Reviews.CollapseWalkthrough {
-			builder.WriteString("<details>\n")
-			builder.WriteString("<summary>📝 Summary of changes</summary>\n\n")
+	if summaryTemplate != nil {
+		summary, err := s.renderTemplate(provider, settings)
+		if err == nil {
+			return s.withState(ApplyStyle(summary) + commentFooter(NewMarkdownRenderer(provider)) + commentMetadata())
 		}
+		logger.Warnf("Failed to render the summary template, falling back to the default layout: %v", err)
 	}
 
+	renderer := NewMarkdownRenderer(provider)
+
+	var sections strings.Builder
+
 	if settings.Reviews.Summary && len(s.Summary) > 0 {
-		builder.WriteString(s.Header())
-		builder.WriteString("\n\n## Summary\n")
-		builder.WriteString(s.Summary + "\n")
+		sections.WriteString(s.Header())
+		sections.WriteString("\n\n## Summary\n")
+		sections.WriteString(s.Summary + "\n")
 	}
 
-	if settings.Reviews.Walkthrough && len(s.Walkthrough) > 0 {
-		builder.WriteString("\n\n## Walkthrough\n")
-		builder.WriteString(formatWalkthrough(s.Walkthrough) + "\n")
+	if len(s.Diagram.Source) > 0 {
+		sections.WriteString("\n\n## Architecture\n")
+		sections.WriteString(renderer.Diagram(s.Diagram))
 	}
 
-	if provider == "github" {
-		if settings.Reviews.CollapseWalkthrough {
-			builder.WriteString("</details>\n\n")
-		}
+	if len(s.AppSize) > 0 {
+		sections.WriteString("\n" + s.AppSize + "\n")
+	}
+
+	if len(s.ReleaseRisk) > 0 {
+		sections.WriteString("\n\n## Release risk\n")
+		sections.WriteString(s.ReleaseRisk + "\n")
+	}
+
+	if len(s.MergeConfidence) > 0 {
+		sections.WriteString("\n\n## Merge confidence\n")
+		sections.WriteString(s.MergeConfidence + "\n")
+	}
+
+	if len(s.Performance) > 0 {
+		sections.WriteString("\n\n## Performance\n")
+		sections.WriteString(s.Performance + "\n")
+	}
+
+	if len(s.Assets) > 0 {
+		sections.WriteString("\n\n## Assets\n")
+		sections.WriteString(s.Assets + "\n")
 	}
 
-	if settings.Reviews.Haiku && len(s.Haiku) > 0 {
-		haiku := s.Haiku
-		if provider == "bitbucket" {
-			haiku = strings.ReplaceAll(haiku, "\n", "  \n")
+	if len(s.TODOs) > 0 {
+		sections.WriteString("\n\n## New TODOs\n")
+		sections.WriteString(s.todoSection(renderer))
+	}
+
+	if len(s.Personas) > 0 {
+		sections.WriteString("\n\n## Reviewers\n")
+		sections.WriteString(s.Personas + "\n")
+	}
+
+	if len(s.CIConfigReview) > 0 {
+		sections.WriteString("\n\n## CI config review\n")
+		sections.WriteString(s.CIConfigReview + "\n")
+	}
+
+	if len(s.Compliance) > 0 {
+		sections.WriteString("\n\n## Compliance\n")
+		sections.WriteString(s.Compliance + "\n")
+	}
+
+	if len(s.ContractChanges) > 0 {
+		sections.WriteString("\n\n## Contract changes\n")
+		sections.WriteString(s.ContractChanges + "\n")
+	}
+
+	if settings.Reviews.Walkthrough && len(s.Walkthrough) > 0 {
+		sections.WriteString("\n\n## Walkthrough\n")
+		if len(s.Languages) > 0 {
+			sections.WriteString("_Languages: " + s.Languages + "_\n\n")
+		}
+		sections.WriteString(formatWalkthroughSection(rankWalkthrough(s.Walkthrough, s.Impacts), settings.Reviews.WalkthroughGroups, renderer, s.fileLinker(renderer)) + "\n")
+
+		if len(s.FeatureFlags) > 0 {
+			sections.WriteString("\n### Feature flags\n")
+			sections.WriteString(s.FeatureFlags + "\n")
 		}
+	} else if len(s.FeatureFlags) > 0 {
+		sections.WriteString("\n\n## Feature flags\n")
+		sections.WriteString(s.FeatureFlags + "\n")
+	}
+
+	var builder strings.Builder
+	builder.WriteString(s.Header() + "\n\n")
+
+	// The walkthrough is collapsed only where supported, a bold title would not save any space
+	if settings.Reviews.CollapseWalkthrough && renderer.Capabilities().Collapsible {
+		builder.WriteString(renderer.Collapsible("📝 Summary of changes", sections.String()) + "\n\n")
+	} else {
+		builder.WriteString(sections.String())
+	}
+
+	if len(s.Author) > 0 {
+		builder.WriteString(s.authorMention(renderer) + "\n\n")
+	}
+
+	if settings.SecretsFree {
+		builder.WriteString("> 🔒 Secrets-free mode: only the diff was shared with the AI. ")
+		builder.WriteString("Findings may miss context from the rest of the codebase, such as other usages of the changed code or the pull request description.\n\n")
+	}
+
+	if s.Applied > 0 {
+		builder.WriteString(fmt.Sprintf("> 💡 %d suggestions of the reviews were applied in this repository this quarter.\n\n", s.Applied))
+	}
+
+	if celebration := settings.GetCelebration(); celebration.Enabled() && len(s.Celebration) > 0 {
+		content := renderer.LineBreaks(s.Celebration)
 
 		builder.WriteString("---\n")
-		builder.WriteString("### Haiku\n")
-		builder.WriteString(haiku + "\n")
+		builder.WriteString("### " + celebration.GetTitle() + "\n")
+		builder.WriteString(content + "\n")
 	}
 
-	return builder.String()
+	if changelog := s.changelog(renderer); changelog != "" {
+		builder.WriteString("\n" + changelog)
+	}
+
+	return s.withState(ApplyStyle(builder.String()) + commentFooter(renderer) + commentMetadata())
+}
+
+// authorMention notifies the author of the pull request about the findings
+func (s Summary) authorMention(renderer MarkdownRenderer) string {
+	if s.Stats.Findings == 0 {
+		return fmt.Sprintf("👋 %s the review is done, no issues were found.", renderer.Mention(s.Author))
+	}
+	return fmt.Sprintf("👋 %s the review is done, check the %d findings in the comments.", renderer.Mention(s.Author), s.Stats.Findings)
 }
 
 // InitiatedString returns a message indicating the review has started
 func (s Summary) InitiatedString(provider string) string {
 	var builder strings.Builder
 	builder.WriteString(s.Header() + "\n\n")
-	switch provider {
-	case "github":
-		builder.WriteString("> [!NOTE]\n")
-	default:
-		builder.WriteString("> ℹ️ Note  \n")
+	builder.WriteString(NewMarkdownRenderer(provider).Note(identity.GetName() + " is reviewing the PR, please wait..."))
+
+	return ApplyStyle(builder.String())
+}
+
+// NoteString formats a summary with only a note, posted when the review does not run, like for a diff without changes
+func (s Summary) NoteString(provider, note string) string {
+	renderer := NewMarkdownRenderer(provider)
+
+	var builder strings.Builder
+	builder.WriteString(s.Header() + "\n\n")
+	builder.WriteString(renderer.Note(note))
+
+	if changelog := s.changelog(renderer); changelog != "" {
+		builder.WriteString("\n\n" + changelog)
 	}
-	builder.WriteString("> Bitrise AI is reviewing the PR, please wait...")
 
-	return builder.String()
+	return s.withState(ApplyStyle(builder.String()) + commentFooter(renderer) + commentMetadata())
 }
 
 // formatFilePaths splits file paths by comma, truncates each if longer than maxLength,
 // and rejoins them with comma
-func formatFilePaths(files string, maxLength int) string {
+func formatFilePaths(files string, maxLength int, link func(text, file string) string) string {
 	if len(files) == 0 {
 		return ""
 	}
@@ -100,31 +226,45 @@ func formatFilePaths(files string, maxLength int) string {
 			// Find the position to start truncating from
 			truncStart := len(path) - maxLength + 3
 			if truncStart < 0 {
-				paths[i] = path
+				paths[i] = link(path, path)
 			} else {
-				paths[i] = "..." + path[truncStart:]
+				paths[i] = link("..."+path[truncStart:], path)
 			}
 		} else {
-			paths[i] = path
+			paths[i] = link(path, path)
 		}
 	}
 
 	return strings.Join(paths, ", ")
 }
 
-// formatWalkthrough creates a markdown table from walkthrough data
-func formatWalkthrough(walkthrough []Walkthrough) string {
+// formatWalkthrough creates a markdown table from walkthrough data, with an impact column for ranked walkthroughs
+func formatWalkthrough(walkthrough []Walkthrough, link func(text, file string) string) string {
 	if len(walkthrough) == 0 {
 		return ""
 	}
 
+	ranked := false
+	for _, w := range walkthrough {
+		ranked = ranked || w.Impact != ""
+	}
+
 	var builder strings.Builder
-	builder.WriteString("| File | Summary |\n")
-	builder.WriteString("|------|---------|\n")
+	if ranked {
+		builder.WriteString("| File | Impact | Summary |\n")
+		builder.WriteString("|------|--------|---------|\n")
+	} else {
+		builder.WriteString("| File | Summary |\n")
+		builder.WriteString("|------|---------|\n")
+	}
 
 	for _, w := range walkthrough {
 		builder.WriteString("| ")
-		builder.WriteString(formatFilePaths(w.Files, 40))
+		builder.WriteString(formatFilePaths(w.Files, 40, link))
+		if ranked {
+			builder.WriteString(" | ")
+			builder.WriteString(w.Impact)
+		}
 		builder.WriteString(" | ")
 		builder.WriteString(w.Summary)
 		builder.WriteString(" |\n")
@@ -132,3 +272,10 @@ func formatWalkthrough(walkthrough []Walkthrough) string {
 
 	return builder.String()
 }
+
+// fileLinker returns a function linking the files to the reviewed commit
+func (s Summary) fileLinker(renderer MarkdownRenderer) func(text, file string) string {
+	return func(text, file string) string {
+		return s.Permalinks.Link(renderer, text, file, 0, 0)
+	}
+}
@@ -0,0 +1,256 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+// summaryStatePrefix starts the hidden comment with the state of the posted summary
+const summaryStatePrefix = "<!-- bitrise-plugin-ai-reviewer-state: "
+
+// maxChangelogEntries is the number of updates listed at the bottom of the summary
+const maxChangelogEntries = 5
+
+// SummaryState records what the posted summary was generated from.
+// Later runs keep the sections of the unchanged files, so the summary comment does not change on every push.
+type SummaryState struct {
+	Commit      string            `json:"commit"`
+	Files       map[string]string `json:"files"`           // Hash of the changes of each file
+	Blobs       map[string]string `json:"blobs,omitempty"` // Blob hash of each file after the changes
+	Summary     string            `json:"summary,omitempty"`
+	Walkthrough []Walkthrough     `json:"walkthrough,omitempty"`
+	Celebration string            `json:"celebration,omitempty"`
+	Changelog   []string          `json:"changelog,omitempty"`
+	ReviewedAt  time.Time         `json:"reviewed_at,omitzero"`
+	FullReviews []time.Time       `json:"full_reviews,omitempty"` // Times of the full reviews counted by the review quota
+}
+
+// DiffFileHashes hashes the changed lines of each file of the diff.
+// Line numbers are left out, so rebasing the pull request keeps the hashes of the unchanged files.
+func DiffFileHashes(diff string) map[string]string {
+	hashes := map[string]string{}
+	for _, file := range diffparse.Parse(diff) {
+		hash := sha256.New()
+		hash.Write([]byte(file.Status + "\n"))
+		for _, hunk := range file.Hunks {
+			for _, changed := range hunk.Lines {
+				if changed.Kind != diffparse.LineContext {
+					fmt.Fprintf(hash, "%d%s\n", changed.Kind, changed.Content)
+				}
+			}
+		}
+		hashes[file.Path()] = hex.EncodeToString(hash.Sum(nil))[:16]
+	}
+	return hashes
+}
+
+// DiffFileBlobs returns the blob hash of each file after the changes, from the index lines of the diff.
+// Deleted files and diffs without index lines have no blob hash.
+func DiffFileBlobs(diff string) map[string]string {
+	blobs := map[string]string{}
+	for _, file := range diffparse.Parse(diff) {
+		if file.NewBlob != "" && strings.Trim(file.NewBlob, "0") != "" {
+			blobs[file.Path()] = file.NewBlob
+		}
+	}
+	return blobs
+}
+
+// ParseSummaryState reads the state of a posted summary, empty if the comment has none
+func ParseSummaryState(body string) SummaryState {
+	_, encoded, found := strings.Cut(body, summaryStatePrefix)
+	if !found {
+		return SummaryState{}
+	}
+	encoded, _, found = strings.Cut(encoded, " -->")
+	if !found {
+		return SummaryState{}
+	}
+
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return SummaryState{}
+	}
+	var state SummaryState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return SummaryState{}
+	}
+	return state
+}
+
+// ReviewedWithin checks if the summary was posted within the window, debouncing the reviews of rapid pushes
+func (s SummaryState) ReviewedWithin(window time.Duration) bool {
+	return window > 0 && !s.ReviewedAt.IsZero() && now().Sub(s.ReviewedAt) < window
+}
+
+// ReuseUnchanged keeps the text of the previous summary for the sections whose files did not change:
+// the walkthrough rows of the unchanged files, and the summary and celebration if no file changed.
+// A line is added to the changelog when the summary is updated for a new commit.
+func (s Summary) ReuseUnchanged() Summary {
+	previous := s.Previous
+	if s.Tracking.Commit == "" || len(previous.Files) == 0 {
+		return s
+	}
+
+	if maps.Equal(s.Tracking.Files, previous.Files) {
+		if previous.Summary != "" {
+			s.Summary = previous.Summary
+		}
+		if previous.Celebration != "" {
+			s.Celebration = previous.Celebration
+		}
+	}
+
+	previousRows := map[string]string{}
+	for _, row := range previous.Walkthrough {
+		previousRows[row.Files] = row.Summary
+	}
+	walkthrough := make([]Walkthrough, len(s.Walkthrough))
+	for idx, row := range s.Walkthrough {
+		walkthrough[idx] = row
+		if summary, ok := previousRows[row.Files]; ok && s.unchangedFiles(row.Files) {
+			walkthrough[idx].Summary = summary
+		}
+	}
+	s.Walkthrough = walkthrough
+
+	s.Tracking.Changelog = previous.Changelog
+	if previous.Commit != "" && !git.SameCommit(previous.Commit, s.Tracking.Commit) {
+		entry := fmt.Sprintf("Updated for commit %s", shortCommit(s.Tracking.Commit))
+		switch changed := s.changedFiles(); {
+		case changed == 1:
+			entry += " (1 file changed)"
+		case changed > 1:
+			entry += fmt.Sprintf(" (%d files changed)", changed)
+		}
+		s.Tracking.Changelog = append(append([]string{}, previous.Changelog...), entry)
+		if len(s.Tracking.Changelog) > maxChangelogEntries {
+			s.Tracking.Changelog = s.Tracking.Changelog[len(s.Tracking.Changelog)-maxChangelogEntries:]
+		}
+	}
+	return s
+}
+
+// CachedWalkthrough returns the walkthrough rows of the previous summary whose files did not change since then.
+// They are reused instead of asking the LLM to describe the same files again.
+func (s Summary) CachedWalkthrough() []Walkthrough {
+	if s.Tracking.Commit == "" || len(s.Previous.Files) == 0 {
+		return nil
+	}
+	var cached []Walkthrough
+	for _, row := range s.Previous.Walkthrough {
+		if s.unchangedFiles(row.Files) {
+			cached = append(cached, Walkthrough{Files: row.Files, Summary: row.Summary})
+		}
+	}
+	return cached
+}
+
+// WithCachedWalkthrough adds the cached walkthrough rows of the files the LLM left out of the walkthrough
+func (s Summary) WithCachedWalkthrough() Summary {
+	covered := map[string]bool{}
+	for _, row := range s.Walkthrough {
+		for _, file := range strings.Split(row.Files, ",") {
+			covered[strings.TrimSpace(file)] = true
+		}
+	}
+
+	walkthrough := slices.Clone(s.Walkthrough)
+	for _, row := range s.CachedWalkthrough() {
+		missing := false
+		for _, file := range strings.Split(row.Files, ",") {
+			missing = missing || !covered[strings.TrimSpace(file)]
+		}
+		if missing {
+			walkthrough = append(walkthrough, row)
+		}
+	}
+	s.Walkthrough = walkthrough
+	return s
+}
+
+// unchangedFiles checks if the comma separated files are the same as in the previous summary
+func (s Summary) unchangedFiles(files string) bool {
+	for _, file := range strings.Split(files, ",") {
+		if !s.unchangedFile(strings.TrimSpace(file)) {
+			return false
+		}
+	}
+	return true
+}
+
+// unchangedFile checks if the file has the same content, or the same changes after a rebase, as in the previous summary
+func (s Summary) unchangedFile(file string) bool {
+	hash, ok := s.Tracking.Files[file]
+	if !ok {
+		return false
+	}
+	if blob := s.Tracking.Blobs[file]; blob != "" && blob == s.Previous.Blobs[file] {
+		return true
+	}
+	return hash == s.Previous.Files[file]
+}
+
+// changedFiles counts the files changed, added or removed since the previous summary
+func (s Summary) changedFiles() int {
+	changed := 0
+	for file, hash := range s.Tracking.Files {
+		if s.Previous.Files[file] != hash {
+			changed++
+		}
+	}
+	for file := range s.Previous.Files {
+		if _, ok := s.Tracking.Files[file]; !ok {
+			changed++
+		}
+	}
+	return changed
+}
+
+// changelog formats the updates of the summary, one line each
+func (s Summary) changelog(renderer MarkdownRenderer) string {
+	if len(s.Tracking.Changelog) == 0 {
+		return ""
+	}
+	lines := make([]string, len(s.Tracking.Changelog))
+	for idx, entry := range s.Tracking.Changelog {
+		lines[idx] = "_" + entry + "_"
+	}
+	return renderer.LineBreaks(strings.Join(lines, "\n")) + "\n"
+}
+
+// withState embeds the state of the summary in the comment right after the header,
+// so it is kept in the first part of split comments
+func (s Summary) withState(body string) string {
+	if s.Tracking.Commit == "" || !strings.HasPrefix(body, s.Header()) {
+		return body
+	}
+
+	state := s.Tracking
+	state.Summary = s.Summary
+	state.Walkthrough = s.Walkthrough
+	state.Celebration = s.Celebration
+	state.ReviewedAt = now().UTC().Truncate(time.Second)
+	content, err := json.Marshal(state)
+	if err != nil {
+		return body
+	}
+	return s.Header() + "\n" + summaryStatePrefix + base64.StdEncoding.EncodeToString(content) + " -->" + strings.TrimPrefix(body, s.Header())
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
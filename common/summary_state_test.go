@@ -0,0 +1,217 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const stateDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,2 @@
+ package main
+-var a = 1
++var a = 2
+diff --git a/util.go b/util.go
+--- a/util.go
++++ b/util.go
+@@ -1,2 +1,3 @@
+ package main
++func util() {}
+`
+
+func TestDiffFileHashesIgnoreLineNumbers(t *testing.T) {
+	hashes := DiffFileHashes(stateDiff)
+	if len(hashes) != 2 || hashes["main.go"] == "" || hashes["util.go"] == "" {
+		t.Fatalf("Expected a hash for each file, got %v", hashes)
+	}
+
+	rebased := DiffFileHashes(strings.Replace(stateDiff, "@@ -1,2 +1,2 @@", "@@ -10,2 +10,2 @@", 1))
+	if rebased["main.go"] != hashes["main.go"] {
+		t.Error("Expected moved but unchanged lines to keep the hash")
+	}
+
+	changed := DiffFileHashes(strings.Replace(stateDiff, "+var a = 2", "+var a = 3", 1))
+	if changed["main.go"] == hashes["main.go"] || changed["util.go"] != hashes["util.go"] {
+		t.Error("Expected only the hash of the changed file to change")
+	}
+}
+
+func TestSummaryStateRoundTrip(t *testing.T) {
+	summary := Summary{
+		Summary:     "Bumps a --> b",
+		Walkthrough: []Walkthrough{{Files: "main.go", Summary: "Changes a"}},
+		Celebration: "A haiku",
+		Tracking:    SummaryState{Commit: "abc1234def", Files: DiffFileHashes(stateDiff)},
+	}
+
+	body := summary.String(ProviderGitHub, WithDefaultSettings())
+	if !strings.HasPrefix(body, summary.Header()+"\n"+summaryStatePrefix) {
+		t.Errorf("Expected the state right after the header, got:\n%s", body)
+	}
+
+	state := ParseSummaryState(body)
+	if state.Commit != "abc1234def" || state.Summary != "Bumps a --> b" || state.Celebration != "A haiku" || len(state.Files) != 2 {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+	if ParseSummaryState("[bitrise-plugin-ai-reviewer]: summary\n\nNo state").Commit != "" {
+		t.Error("Expected an empty state for comments without one")
+	}
+}
+
+func TestSummaryReuseUnchanged(t *testing.T) {
+	hashes := DiffFileHashes(stateDiff)
+	previous := SummaryState{
+		Commit:      "aaaaaaa111",
+		Files:       hashes,
+		Summary:     "Previous summary",
+		Walkthrough: []Walkthrough{{Files: "main.go", Summary: "Previous main"}, {Files: "util.go", Summary: "Previous util"}},
+		Celebration: "Previous haiku",
+	}
+
+	// A new commit without changes to the diff, like a rebase, keeps every section
+	summary := Summary{
+		Summary:     "New summary",
+		Walkthrough: []Walkthrough{{Files: "util.go", Summary: "New util"}, {Files: "main.go", Summary: "New main"}},
+		Celebration: "New haiku",
+		Tracking:    SummaryState{Commit: "bbbbbbb222", Files: hashes},
+		Previous:    previous,
+	}.ReuseUnchanged()
+	if summary.Summary != "Previous summary" || summary.Celebration != "Previous haiku" {
+		t.Errorf("Expected the summary and the haiku to be kept, got %q and %q", summary.Summary, summary.Celebration)
+	}
+	if summary.Walkthrough[0].Summary != "Previous util" || summary.Walkthrough[1].Summary != "Previous main" {
+		t.Errorf("Expected the walkthrough to be kept, got %+v", summary.Walkthrough)
+	}
+	if len(summary.Tracking.Changelog) != 1 || summary.Tracking.Changelog[0] != "Updated for commit bbbbbbb" {
+		t.Errorf("Unexpected changelog: %v", summary.Tracking.Changelog)
+	}
+
+	// Changing a file regenerates its row, the summary and the haiku
+	changed := DiffFileHashes(strings.Replace(stateDiff, "+var a = 2", "+var a = 3", 1))
+	summary = Summary{
+		Summary:     "New summary",
+		Walkthrough: []Walkthrough{{Files: "main.go", Summary: "New main"}, {Files: "util.go", Summary: "New util"}},
+		Celebration: "New haiku",
+		Tracking:    SummaryState{Commit: "ccccccc333", Files: changed},
+		Previous:    previous,
+	}.ReuseUnchanged()
+	if summary.Summary != "New summary" || summary.Celebration != "New haiku" {
+		t.Error("Expected the summary and the haiku to be regenerated")
+	}
+	if summary.Walkthrough[0].Summary != "New main" || summary.Walkthrough[1].Summary != "Previous util" {
+		t.Errorf("Expected only the row of the changed file to be regenerated, got %+v", summary.Walkthrough)
+	}
+	if summary.Tracking.Changelog[0] != "Updated for commit ccccccc (1 file changed)" {
+		t.Errorf("Unexpected changelog: %v", summary.Tracking.Changelog)
+	}
+	if output := summary.String(ProviderGitHub, WithDefaultSettings()); !strings.Contains(output, "_Updated for commit ccccccc (1 file changed)_") {
+		t.Errorf("Expected the changelog in the summary, got:\n%s", output)
+	}
+}
+
+func TestSummaryReuseUnchangedSameCommit(t *testing.T) {
+	previous := SummaryState{Commit: "abc1234", Files: map[string]string{"main.go": "1"}, Changelog: []string{"Updated for commit abc1234"}}
+	summary := Summary{Tracking: SummaryState{Commit: "abc1234def", Files: map[string]string{"main.go": "1"}}, Previous: previous}.ReuseUnchanged()
+	if len(summary.Tracking.Changelog) != 1 {
+		t.Errorf("Expected no new changelog entry for a rerun on the same commit, got %v", summary.Tracking.Changelog)
+	}
+
+	for idx := range 10 {
+		previous.Changelog = append(previous.Changelog, "entry "+string(rune('a'+idx)))
+	}
+	summary = Summary{Tracking: SummaryState{Commit: "fff0000", Files: map[string]string{}}, Previous: previous}.ReuseUnchanged()
+	if len(summary.Tracking.Changelog) != maxChangelogEntries || summary.Tracking.Changelog[maxChangelogEntries-1] != "Updated for commit fff0000 (1 file changed)" {
+		t.Errorf("Expected the latest %d entries, got %v", maxChangelogEntries, summary.Tracking.Changelog)
+	}
+}
+
+func TestSummaryStateReviewedWithin(t *testing.T) {
+	reviewedAt := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reviewedAt }
+	defer func() { now = time.Now }()
+
+	body := Summary{Tracking: SummaryState{Commit: "abc1234"}}.String(ProviderGitHub, WithDefaultSettings())
+	state := ParseSummaryState(body)
+	if !state.ReviewedAt.Equal(reviewedAt) {
+		t.Fatalf("Expected the review time in the state, got %v", state.ReviewedAt)
+	}
+
+	now = func() time.Time { return reviewedAt.Add(4 * time.Minute) }
+	if !state.ReviewedWithin(5 * time.Minute) {
+		t.Error("Expected a review 4 minutes ago to be within a 5 minute window")
+	}
+	if state.ReviewedWithin(3 * time.Minute) {
+		t.Error("Expected a review 4 minutes ago to be outside of a 3 minute window")
+	}
+	if state.ReviewedWithin(0) {
+		t.Error("Expected no debouncing without a window")
+	}
+	if (SummaryState{}).ReviewedWithin(time.Hour) {
+		t.Error("Expected no debouncing without a previous review")
+	}
+}
+
+func TestDiffFileBlobs(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-var a = 1
++var a = 2
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 1111111..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+`
+	blobs := DiffFileBlobs(diff)
+	if len(blobs) != 1 || blobs["main.go"] != "bf269f4" {
+		t.Errorf("Expected the blob hash of the changed file only, got %v", blobs)
+	}
+	if len(DiffFileBlobs(stateDiff)) != 0 {
+		t.Error("Expected no blob hashes for a diff without index lines")
+	}
+}
+
+func TestSummaryCachedWalkthrough(t *testing.T) {
+	hashes := DiffFileHashes(stateDiff)
+	changed := DiffFileHashes(strings.Replace(stateDiff, "+var a = 2", "+var a = 3", 1))
+	previous := SummaryState{
+		Commit:      "aaaaaaa111",
+		Files:       hashes,
+		Blobs:       map[string]string{"main.go": "1111111", "util.go": "2222222"},
+		Walkthrough: []Walkthrough{{Files: "main.go", Summary: "Previous main"}, {Files: "util.go", Summary: "Previous util"}},
+	}
+
+	// The changes of main.go are different, but its content is the same, like after the base branch was merged
+	summary := Summary{
+		Walkthrough: []Walkthrough{{Files: "util.go", Summary: "New util"}},
+		Tracking:    SummaryState{Commit: "bbbbbbb222", Files: changed, Blobs: map[string]string{"main.go": "1111111", "util.go": "3333333"}},
+		Previous:    previous,
+	}
+	cached := summary.CachedWalkthrough()
+	if len(cached) != 2 {
+		t.Fatalf("Expected the rows with the same content or the same changes to be cached, got %+v", cached)
+	}
+
+	summary.Tracking.Files = map[string]string{"main.go": changed["main.go"], "util.go": "changed"}
+	summary.Tracking.Blobs["main.go"] = "4444444"
+	if cached := summary.CachedWalkthrough(); len(cached) != 0 {
+		t.Errorf("Expected no cached rows for the changed files, got %+v", cached)
+	}
+
+	// The rows the LLM left out are added from the cache
+	summary.Tracking.Files, summary.Tracking.Blobs = hashes, nil
+	merged := summary.WithCachedWalkthrough()
+	if len(merged.Walkthrough) != 2 || merged.Walkthrough[0].Summary != "New util" || merged.Walkthrough[1].Summary != "Previous main" {
+		t.Errorf("Expected the cached row of main.go to be added, got %+v", merged.Walkthrough)
+	}
+	if len(Summary{Walkthrough: summary.Walkthrough}.WithCachedWalkthrough().Walkthrough) != 1 {
+		t.Error("Expected no cached rows without a previous summary")
+	}
+}
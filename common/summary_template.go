@@ -0,0 +1,133 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// summaryTemplate is the custom layout of the summary comment, nil for the default layout
+var summaryTemplate *template.Template
+
+// SummaryStats holds statistics about the review shown in the summary
+type SummaryStats struct {
+	FilesChanged int
+	Findings     int
+}
+
+// SummaryTemplateData is the data model available in custom summary templates
+type SummaryTemplateData struct {
+	Provider         string
+	Summary          string
+	Walkthrough      []Walkthrough
+	WalkthroughTable string // The walkthrough formatted as a markdown table
+	Celebration      string
+	CelebrationTitle string
+	MergeConfidence  string
+	Compliance       string
+	ContractChanges  string
+	CIConfigReview   string
+	Performance      string
+	FeatureFlags     string
+	AppSize          string
+	Assets           string
+	Personas         string   // Reviewer personas of the changed areas
+	Languages        string   // Share of the languages in the changed lines, like "70% Go, 20% YAML"
+	ReleaseRisk      string   // Release risk of pull requests targeting a release branch
+	Diagram          string   // Diagram of the changed structure, a mermaid code block or an outline depending on the provider
+	TODOs            string   // The added TODO comments with links to their lines
+	AuthorMention    string   // Mention of the pull request author, empty if not mentioned
+	Changelog        []string // Updates of the summary for later commits, like "Updated for commit abc1234"
+	Stats            SummaryStats
+	Applied          int // Suggestions of the reviews applied in the repository this quarter
+	SecretsFree      bool
+}
+
+// LoadSummaryTemplate parses and validates the custom summary template.
+// On error the default layout stays in use.
+func LoadSummaryTemplate(path string) error {
+	summaryTemplate = nil
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read summary template %s: %w", path, err)
+	}
+
+	tmpl, err := template.New("summary").Funcs(template.FuncMap{
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse summary template %s: %w", path, err)
+	}
+
+	// Execute with sample data to catch references to unknown fields
+	sample := SummaryTemplateData{
+		Provider:    "github",
+		Summary:     "Summary",
+		Walkthrough: []Walkthrough{{Files: "main.go", Summary: "Changes"}},
+		Celebration: "Celebration",
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return fmt.Errorf("invalid summary template %s: %w", path, err)
+	}
+
+	logger.Infof("Using summary template: %s", path)
+	summaryTemplate = tmpl
+	return nil
+}
+
+// renderTemplate renders the summary with the custom template
+func (s Summary) renderTemplate(provider string, settings Settings) (string, error) {
+	walkthrough := rankWalkthrough(s.Walkthrough, s.Impacts)
+	renderer := NewMarkdownRenderer(provider)
+	data := SummaryTemplateData{
+		Provider:         provider,
+		Summary:          s.Summary,
+		Walkthrough:      walkthrough,
+		WalkthroughTable: formatWalkthroughSection(walkthrough, settings.Reviews.WalkthroughGroups, renderer, s.fileLinker(renderer)),
+		Celebration:      s.Celebration,
+		CelebrationTitle: settings.GetCelebration().GetTitle(),
+		MergeConfidence:  s.MergeConfidence,
+		Compliance:       s.Compliance,
+		ContractChanges:  s.ContractChanges,
+		CIConfigReview:   s.CIConfigReview,
+		Performance:      s.Performance,
+		FeatureFlags:     s.FeatureFlags,
+		AppSize:          s.AppSize,
+		Assets:           s.Assets,
+		Personas:         s.Personas,
+		Languages:        s.Languages,
+		ReleaseRisk:      s.ReleaseRisk,
+		TODOs:            s.todoSection(NewMarkdownRenderer(provider)),
+		Changelog:        s.Tracking.Changelog,
+		Stats:            s.Stats,
+		Applied:          s.Applied,
+		SecretsFree:      settings.SecretsFree,
+	}
+	if len(s.Author) > 0 {
+		data.AuthorMention = NewMarkdownRenderer(provider).Mention(s.Author)
+	}
+	if len(s.Diagram.Source) > 0 {
+		data.Diagram = NewMarkdownRenderer(provider).Diagram(s.Diagram)
+	}
+	if data.Stats.FilesChanged == 0 {
+		data.Stats.FilesChanged = len(s.Walkthrough)
+	}
+
+	var buffer bytes.Buffer
+	if err := summaryTemplate.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+
+	// The header is always kept, it identifies the comment on later runs
+	return s.Header() + "\n\n" + buffer.String(), nil
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSummaryTemplate(t *testing.T) {
+	defer LoadSummaryTemplate("")
+
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "summary.tmpl")
+	content := `# Acme review
+{{ .Summary }}
+Files: {{ .Stats.FilesChanged }}, findings: {{ .Stats.Findings }}
+{{ .WalkthroughTable }}{{ if .Celebration }}{{ .Celebration }}{{ end }}
+_Powered by Acme_`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	if err := LoadSummaryTemplate(path); err != nil {
+		t.Fatalf("Expected valid template, got %v", err)
+	}
+
+	summary := Summary{
+		Summary:     "Adds retries",
+		Walkthrough: []Walkthrough{{Files: "retry.go", Summary: "Retry logic"}},
+		Stats:       SummaryStats{Findings: 2},
+	}
+	output := summary.String("github", WithDefaultSettings())
+
+	expected := []string{
+		summary.Header() + "\n\n# Acme review",
+		"Adds retries",
+		"Files: 1, findings: 2",
+		"| retry.go | Retry logic |",
+		"_Powered by Acme_",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+}
+
+func TestSummaryTemplate_Invalid(t *testing.T) {
+	defer LoadSummaryTemplate("")
+
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "summary.tmpl")
+	os.WriteFile(path, []byte("{{ .UnknownField }}"), 0644)
+
+	if err := LoadSummaryTemplate(path); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+
+	// The default layout is used
+	output := Summary{Summary: "Adds retries"}.String("github", WithDefaultSettings())
+	if !strings.Contains(output, "## Summary") {
+		t.Errorf("Expected default layout, got:\n%s", output)
+	}
+}
@@ -0,0 +1,182 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// codeLanguages are the code block languages of the file extensions, for syntax highlighting
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".swift": "swift",
+	".kt":    "kotlin",
+	".kts":   "kotlin",
+	".java":  "java",
+	".m":     "objectivec",
+	".js":    "javascript",
+	".ts":    "typescript",
+	".py":    "python",
+	".rb":    "ruby",
+	".sh":    "bash",
+	".json":  "json",
+	".yml":   "yaml",
+	".yaml":  "yaml",
+}
+
+// syntaxCheckers check the syntax of the files by extension, the files of other languages are not checked
+var syntaxCheckers = map[string]syntaxChecker{
+	".go":    {check: checkGoSyntax},
+	".json":  {check: checkJSONSyntax},
+	".yml":   {check: checkYAMLSyntax},
+	".yaml":  {check: checkYAMLSyntax},
+	".swift": {check: checkBracketBalance, heuristic: true},
+	".kt":    {check: checkBracketBalance, heuristic: true},
+	".kts":   {check: checkBracketBalance, heuristic: true},
+	".java":  {check: checkBracketBalance, heuristic: true},
+}
+
+type syntaxChecker struct {
+	check     func(file, content string) error
+	heuristic bool // The check can report valid code, like a bracket in an unusual literal
+}
+
+// SyntaxError is a syntax error of a file, introduced by a suggestion
+type SyntaxError struct {
+	Err       error
+	Heuristic bool // Found by a heuristic check, the code may still be valid
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Heuristic {
+		return fmt.Sprintf("the file may not compile with the suggestion: %v", e.Err)
+	}
+	return fmt.Sprintf("the file does not compile with the suggestion: %v", e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// CodeLanguage returns the code block language of the file, empty if unknown
+func CodeLanguage(file string) string {
+	return codeLanguages[strings.ToLower(filepath.Ext(file))]
+}
+
+func checkGoSyntax(file, content string) error {
+	_, err := parser.ParseFile(token.NewFileSet(), file, content, parser.AllErrors)
+	return err
+}
+
+func checkJSONSyntax(file, content string) error {
+	var value any
+	return json.Unmarshal([]byte(content), &value)
+}
+
+// checkYAMLSyntax parses every document of the file
+func checkYAMLSyntax(file, content string) error {
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(content)))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// checkBracketBalance checks that the brackets of C-like languages are balanced, skipping the comments and the string and character literals.
+// Nested block comments and multi-line strings of Swift and Kotlin are supported.
+func checkBracketBalance(file, content string) error {
+	pairs := map[byte]byte{')': '(', ']': '[', '}': '{'}
+	var stack []byte
+	line := 1
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				return checkUnclosed(stack)
+			}
+			i += end - 1
+		case strings.HasPrefix(content[i:], "/*"):
+			depth := 0
+			for ; i < len(content); i++ {
+				if strings.HasPrefix(content[i:], "/*") {
+					depth++
+					i++
+				} else if strings.HasPrefix(content[i:], "*/") {
+					depth--
+					i++
+					if depth == 0 {
+						break
+					}
+				} else if content[i] == '\n' {
+					line++
+				}
+			}
+			if depth > 0 {
+				return fmt.Errorf("unclosed block comment")
+			}
+		case strings.HasPrefix(content[i:], `"""`):
+			end := strings.Index(content[i+3:], `"""`)
+			if end < 0 {
+				return fmt.Errorf("unclosed multi-line string on line %d", line)
+			}
+			line += strings.Count(content[i:i+3+end], "\n")
+			i += end + 5
+		case c == '"' || c == '\'':
+			end := closingQuote(content, i)
+			if end < 0 {
+				return fmt.Errorf("unclosed string on line %d", line)
+			}
+			i = end
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+		case pairs[c] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[c] {
+				return fmt.Errorf("unexpected %c on line %d", c, line)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return checkUnclosed(stack)
+}
+
+// closingQuote returns the index of the quote closing the literal starting at start, -1 if the line ends before it
+func closingQuote(content string, start int) int {
+	quote := content[start]
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '\n':
+			return -1
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func checkUnclosed(stack []byte) error {
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %c", stack[len(stack)-1])
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts configures the time budgets of the phases of a run in seconds, 0 disables the timeout
+type Timeouts struct {
+	LLMCall      int `yaml:"llm_call"`      // A single request to the LLM provider
+	ProviderCall int `yaml:"provider_call"` // A single request to the code review provider
+	GitCommand   int `yaml:"git_command"`   // A single git command
+	TotalRun     int `yaml:"total_run"`     // The whole review, the LLM calls stop when it runs out
+}
+
+// runDeadline is the end of the total run budget, zero when the run has no budget
+var runDeadline time.Time
+
+// SetRunDeadline sets the end of the total run budget, the zero time removes the budget
+func SetRunDeadline(deadline time.Time) {
+	runDeadline = deadline
+}
+
+// RunDeadlineExceeded checks if the total run budget is used up
+func RunDeadlineExceeded() bool {
+	return !runDeadline.IsZero() && !time.Now().Before(runDeadline)
+}
+
+// TimeoutContext creates a context timing out after the given seconds, 0 disables the timeout
+func TimeoutContext(seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+}
+
+// RunTimeoutContext creates a context like TimeoutContext, capped at the end of the total run budget
+func RunTimeoutContext(seconds int) (context.Context, context.CancelFunc) {
+	ctx, cancel := TimeoutContext(seconds)
+	if runDeadline.IsZero() {
+		return ctx, cancel
+	}
+
+	deadlineCtx, deadlineCancel := context.WithDeadline(ctx, runDeadline)
+	return deadlineCtx, func() {
+		deadlineCancel()
+		cancel()
+	}
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithDefaultSettingsTimeouts(t *testing.T) {
+	timeouts := WithDefaultSettings().Timeouts
+
+	if timeouts.LLMCall != 60 || timeouts.ProviderCall != 60 || timeouts.GitCommand != 120 {
+		t.Errorf("Unexpected default timeouts: %+v", timeouts)
+	}
+	if timeouts.TotalRun != 0 {
+		t.Errorf("Expected the total run timeout to be disabled by default, got %d", timeouts.TotalRun)
+	}
+}
+
+func TestTimeoutContext(t *testing.T) {
+	ctx, cancel := TimeoutContext(0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline for a disabled timeout")
+	}
+
+	ctx, cancel = TimeoutContext(30)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > 30*time.Second {
+		t.Errorf("Expected a deadline within 30 seconds, got %v", deadline)
+	}
+}
+
+func TestRunTimeoutContext(t *testing.T) {
+	t.Cleanup(func() { SetRunDeadline(time.Time{}) })
+
+	runDeadline := time.Now().Add(5 * time.Second)
+	SetRunDeadline(runDeadline)
+
+	tests := []struct {
+		name    string
+		seconds int
+	}{
+		{name: "longer than the run budget", seconds: 60},
+		{name: "disabled", seconds: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := RunTimeoutContext(tt.seconds)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if !ok || deadline.After(runDeadline) {
+				t.Errorf("Expected the deadline to be capped at %v, got %v", runDeadline, deadline)
+			}
+		})
+	}
+
+	if RunDeadlineExceeded() {
+		t.Error("Expected the run deadline not to be exceeded yet")
+	}
+	SetRunDeadline(time.Now().Add(-time.Second))
+	if !RunDeadlineExceeded() {
+		t.Error("Expected the run deadline to be exceeded")
+	}
+}
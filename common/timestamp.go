@@ -0,0 +1,79 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// displayLocation is the time zone of the timestamps in the prompts and comments
+var displayLocation = time.UTC
+
+// now returns the current time, relative times are measured from it
+var now = time.Now
+
+// SetTimezone sets the time zone of the displayed timestamps by its IANA name, like Europe/Budapest, UTC if empty
+func SetTimezone(name string) error {
+	if name == "" {
+		displayLocation = time.UTC
+		return nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown timezone %s: %w", name, err)
+	}
+	displayLocation = location
+	return nil
+}
+
+// ParseTimestamp parses the ISO-8601 timestamps of the provider APIs, returns the zero time if the value is not valid
+func ParseTimestamp(value string) time.Time {
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return timestamp
+}
+
+// FormatTimestamp formats the time as ISO-8601 in the configured time zone, followed by its age,
+// e.g. "2025-03-01T10:00:00+01:00 (3 hours ago)". The age lets the model reason about stale pull requests.
+func FormatTimestamp(timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s (%s)", timestamp.In(displayLocation).Format(time.RFC3339), RelativeTime(timestamp))
+}
+
+// RelativeTime describes the time relative to now, like "3 hours ago" or "in 2 days"
+func RelativeTime(timestamp time.Time) string {
+	elapsed := now().Sub(timestamp)
+	future := elapsed < 0
+	if future {
+		elapsed = -elapsed
+	}
+
+	var count int
+	var unit string
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		count, unit = int(elapsed/time.Minute), "minute"
+	case elapsed < 24*time.Hour:
+		count, unit = int(elapsed/time.Hour), "hour"
+	case elapsed < 30*24*time.Hour:
+		count, unit = int(elapsed/(24*time.Hour)), "day"
+	case elapsed < 365*24*time.Hour:
+		count, unit = int(elapsed/(30*24*time.Hour)), "month"
+	default:
+		count, unit = int(elapsed/(365*24*time.Hour)), "year"
+	}
+	if count > 1 {
+		unit += "s"
+	}
+
+	if future {
+		return fmt.Sprintf("in %d %s", count, unit)
+	}
+	return fmt.Sprintf("%d %s ago", count, unit)
+}
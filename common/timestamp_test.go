@@ -0,0 +1,74 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelativeTime(t *testing.T) {
+	reference := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reference }
+	defer func() { now = time.Now }()
+
+	tests := []struct {
+		ago      time.Duration
+		expected string
+	}{
+		{10 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{3 * time.Hour, "3 hours ago"},
+		{49 * time.Hour, "2 days ago"},
+		{45 * 24 * time.Hour, "1 month ago"},
+		{800 * 24 * time.Hour, "2 years ago"},
+		{-2 * time.Hour, "in 2 hours"},
+	}
+	for _, test := range tests {
+		if relative := RelativeTime(reference.Add(-test.ago)); relative != test.expected {
+			t.Errorf("%v ago: expected %q, got %q", test.ago, test.expected, relative)
+		}
+	}
+}
+
+func TestFormatTimestampInTimezone(t *testing.T) {
+	reference := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reference }
+	defer func() { now = time.Now }()
+	defer SetTimezone("")
+
+	if err := SetTimezone("Asia/Tokyo"); err != nil {
+		t.Fatalf("Failed to set the timezone: %v", err)
+	}
+	if formatted := FormatTimestamp(reference.Add(-3 * time.Hour)); formatted != "2025-03-10T18:00:00+09:00 (3 hours ago)" {
+		t.Errorf("Unexpected timestamp: %s", formatted)
+	}
+	if formatted := FormatTimestamp(time.Time{}); formatted != "unknown" {
+		t.Errorf("Expected unknown for the zero time, got %s", formatted)
+	}
+
+	if err := SetTimezone("Mars/Olympus_Mons"); err == nil {
+		t.Error("Expected an error for an unknown timezone")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	// Bitbucket timestamps have microseconds and a numeric offset
+	parsed := ParseTimestamp("2025-03-10T11:00:00.123456+00:00")
+	if !parsed.Equal(time.Date(2025, 3, 10, 11, 0, 0, 123456000, time.UTC)) {
+		t.Errorf("Unexpected parsed time: %v", parsed)
+	}
+	if !ParseTimestamp("yesterday").IsZero() {
+		t.Error("Expected the zero time for an invalid timestamp")
+	}
+}
+
+func TestPullRequestStringTimestamps(t *testing.T) {
+	reference := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reference }
+	defer func() { now = time.Now }()
+
+	output := PullRequest{Number: 1, CreatedAt: reference.Add(-72 * time.Hour), UpdatedAt: reference.Add(-time.Hour)}.String()
+	if !strings.Contains(output, "Created at: 2025-03-07T12:00:00Z (3 days ago)\nUpdated at: 2025-03-10T11:00:00Z (1 hour ago)") {
+		t.Errorf("Expected ISO-8601 timestamps with their age, got:\n%s", output)
+	}
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/diffparse"
+)
+
+// DefaultTODOIssuePattern matches issue references: #123, JIRA-123 or a link
+const DefaultTODOIssuePattern = `#\d+|\b[A-Z][A-Z0-9]+-\d+\b|https?://\S+`
+
+// todoRegex matches a TODO, FIXME or HACK marker in a comment, with the text after it
+var todoRegex = regexp.MustCompile(`(?://|#|/\*|\*|--|<!--|;)\s*\b(TODO|FIXME|HACK)\b(.*)`)
+
+// TODOComment is a TODO, FIXME or HACK comment added by the changes
+type TODOComment struct {
+	File     string
+	Line     int
+	Marker   string // TODO, FIXME or HACK
+	Text     string // The text of the comment after the marker
+	Content  string // The whole added line
+	HasIssue bool   // Whether the comment references an issue
+}
+
+// CompileTODOIssuePattern compiles the regex of the issue references, the default one if empty
+func CompileTODOIssuePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = DefaultTODOIssuePattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// FindTODOs returns the TODO, FIXME and HACK comments added in the diff.
+// The comments are checked for an issue reference with the issue pattern.
+func FindTODOs(diff string, issuePattern *regexp.Regexp) []TODOComment {
+	var todos []TODOComment
+	for _, file := range diffparse.Parse(diff) {
+		for _, line := range file.AddedLines() {
+			match := todoRegex.FindStringSubmatch(line.Content)
+			if match == nil {
+				continue
+			}
+
+			text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(match[2]), "-->"))
+			text = strings.TrimSpace(strings.TrimSuffix(text, "*/"))
+			todos = append(todos, TODOComment{
+				File:     file.Path(),
+				Line:     line.NewNumber,
+				Marker:   match[1],
+				Text:     strings.TrimLeft(text, ": "),
+				Content:  line.Content,
+				HasIssue: issuePattern != nil && issuePattern.MatchString(match[0]),
+			})
+		}
+	}
+	return todos
+}
+
+// TODOFindings returns a nitpick for each added TODO comment without an issue reference
+func TODOFindings(todos []TODOComment) []LineLevel {
+	var findings []LineLevel
+	for _, todo := range todos {
+		if todo.HasIssue {
+			continue
+		}
+		findings = append(findings, LineLevel{
+			File:       todo.File,
+			Line:       todo.Content,
+			LineNumber: todo.Line,
+			Category:   CategoryNitpick,
+			Severity:   SeverityLow,
+			Title:      fmt.Sprintf("%s without an issue", todo.Marker),
+			Body:       fmt.Sprintf("Reference an issue in the %s comment, e.g. `%s(#123): ...`, so the work is tracked after the merge.", todo.Marker, todo.Marker),
+		})
+	}
+	return findings
+}
+
+// todoSection lists the added TODO comments with links to their lines
+func (s Summary) todoSection(renderer MarkdownRenderer) string {
+	var builder strings.Builder
+	for _, todo := range s.TODOs {
+		location := s.Permalinks.Link(renderer, fmt.Sprintf("`%s:%d`", todo.File, todo.Line), todo.File, todo.Line, 0)
+		builder.WriteString(fmt.Sprintf("- %s **%s** %s", location, todo.Marker, todo.Text))
+		if !todo.HasIssue {
+			builder.WriteString(" _(no issue)_")
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
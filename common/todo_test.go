@@ -0,0 +1,63 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const todoDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,6 @@
+ package main
++// TODO: handle the timeout
++// FIXME(#42): retry on failure
++var todoList = []string{} // not a marker: TODOs
++# HACK JIRA-7 works around the SDK bug
+ func main() {}
+diff --git a/web/index.html b/web/index.html
+--- a/web/index.html
++++ b/web/index.html
+@@ -1 +1,2 @@
+ <html>
++<!-- TODO: https://example.com/issues/1 -->
+`
+
+func TestFindTODOs(t *testing.T) {
+	pattern, _ := CompileTODOIssuePattern("")
+	todos := FindTODOs(todoDiff, pattern)
+	if len(todos) != 4 {
+		t.Fatalf("Expected 4 TODO comments, got %+v", todos)
+	}
+
+	expected := []TODOComment{
+		{File: "main.go", Line: 2, Marker: "TODO", Text: "handle the timeout"},
+		{File: "main.go", Line: 3, Marker: "FIXME", Text: "(#42): retry on failure", HasIssue: true},
+		{File: "main.go", Line: 5, Marker: "HACK", Text: "JIRA-7 works around the SDK bug", HasIssue: true},
+		{File: "web/index.html", Line: 2, Marker: "TODO", Text: "https://example.com/issues/1", HasIssue: true},
+	}
+	for idx, todo := range todos {
+		todo.Content = ""
+		if todo != expected[idx] {
+			t.Errorf("Expected %+v, got %+v", expected[idx], todo)
+		}
+	}
+
+	findings := TODOFindings(todos)
+	if len(findings) != 1 || findings[0].Category != CategoryNitpick || findings[0].Line != "// TODO: handle the timeout" || findings[0].LineNumber != 2 {
+		t.Errorf("Expected a nitpick for the TODO without an issue, got %+v", findings)
+	}
+}
+
+func TestSummaryNewTODOs(t *testing.T) {
+	summary := Summary{
+		TODOs:      FindTODOs(todoDiff, regexp.MustCompile(DefaultTODOIssuePattern)),
+		Permalinks: Permalinks{RepoURL: "https://github.com/org/repo", CommitHash: "abc123"},
+	}
+
+	output := summary.String(ProviderGitHub, WithDefaultSettings())
+	if !strings.Contains(output, "## New TODOs\n- [`main.go:2`](https://github.com/org/repo/blob/abc123/main.go#L2) **TODO** handle the timeout _(no issue)_\n") {
+		t.Errorf("Expected the TODOs with permalinks in the summary, got:\n%s", output)
+	}
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharsPerToken is the average number of characters of a token, used to estimate the tokens of a text
+const CharsPerToken = 4
+
+// maxToolArgumentsLength limits the arguments shown in the list of the most expensive tool calls
+const maxToolArgumentsLength = 80
+
+// ToolCallStats is the size of the arguments and the result of a tool call
+type ToolCallStats struct {
+	Tool          string
+	Arguments     string
+	ArgumentChars int
+	ResultChars   int
+}
+
+// EstimateTokens estimates the number of tokens of a text from its length
+func EstimateTokens(text string) int {
+	return estimateTokens(utf8.RuneCountInString(text))
+}
+
+func estimateTokens(chars int) int {
+	return (chars + CharsPerToken - 1) / CharsPerToken
+}
+
+// NewToolCallStats measures the arguments and the result of a tool call
+func NewToolCallStats(tool, arguments, result string) ToolCallStats {
+	return ToolCallStats{
+		Tool:          tool,
+		Arguments:     arguments,
+		ArgumentChars: utf8.RuneCountInString(arguments),
+		ResultChars:   utf8.RuneCountInString(result),
+	}
+}
+
+// Tokens returns the estimated tokens the tool call added to the conversation
+func (s ToolCallStats) Tokens() int {
+	return estimateTokens(s.ArgumentChars) + estimateTokens(s.ResultChars)
+}
+
+// String formats the sizes of the tool call
+func (s ToolCallStats) String() string {
+	return fmt.Sprintf("%s: arguments %d chars (~%d tokens), result %d chars (~%d tokens)",
+		s.Tool, s.ArgumentChars, estimateTokens(s.ArgumentChars), s.ResultChars, estimateTokens(s.ResultChars))
+}
+
+// formatTopToolCalls lists the tool calls adding the most tokens to the conversation, with their arguments
+func formatTopToolCalls(calls []ToolCallStats, limit int) string {
+	if len(calls) == 0 {
+		return ""
+	}
+
+	sorted := make([]ToolCallStats, len(calls))
+	copy(sorted, calls)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Tokens() > sorted[j].Tokens()
+	})
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Top %d of %d tool calls by estimated tokens:\n", len(sorted), len(calls)))
+	for idx, call := range sorted {
+		arguments := strings.Join(strings.Fields(call.Arguments), " ")
+		if len(arguments) > maxToolArgumentsLength {
+			arguments = arguments[:maxToolArgumentsLength] + "..."
+		}
+		builder.WriteString(fmt.Sprintf("  %d. ~%d tokens, %s %s\n", idx+1, call.Tokens(), call.Tool, arguments))
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PluginReleasesURL is the feed of the latest release of the plugin, the Bitrise plugin registry installs its binaries
+const PluginReleasesURL = "https://api.github.com/repos/bitrise-io/bitrise-plugins-ai-reviewer/releases/latest"
+
+// updateCheckTimeout is the timeout in seconds of the update check, it must not hold up the command
+const updateCheckTimeout = 10
+
+// FetchLatestPluginVersion returns the version of the latest release published on the releases feed
+func FetchLatestPluginVersion(releasesURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", releasesURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := NewRetryableClient(DefaultRetryConfig()).StandardClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", NewAPIError("GitHub", resp.StatusCode, fmt.Errorf("failed to get the latest release: HTTP %d", resp.StatusCode))
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to decode the latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("the latest release has no tag")
+	}
+	return normalizeVersion(release.TagName), nil
+}
+
+// IsNewerVersion checks if the latest version is newer than the current one, comparing the numeric parts.
+// Pre-release and build suffixes are ignored, development builds are never outdated.
+func IsNewerVersion(latest, current string) bool {
+	latestParts := versionParts(latest)
+	currentParts := versionParts(current)
+	if latestParts == nil || currentParts == nil {
+		return false
+	}
+
+	for i := range max(len(latestParts), len(currentParts)) {
+		var l, c int
+		if i < len(latestParts) {
+			l = latestParts[i]
+		}
+		if i < len(currentParts) {
+			c = currentParts[i]
+		}
+		if l != c {
+			return l > c
+		}
+	}
+	return false
+}
+
+// versionParts returns the numeric parts of a version like v1.2.3-rc1, nil if it is not numeric
+func versionParts(version string) []int {
+	version = normalizeVersion(version)
+	if idx := strings.IndexAny(version, "-+"); idx >= 0 {
+		version = version[:idx]
+	}
+
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, number)
+	}
+	return parts
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchLatestPluginVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "v0.3.0", "name": "0.3.0"}`))
+	}))
+	defer server.Close()
+
+	latest, err := FetchLatestPluginVersion(server.URL)
+	if err != nil || latest != "0.3.0" {
+		t.Errorf("Expected 0.3.0, got %q, %v", latest, err)
+	}
+}
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest   string
+		current  string
+		expected bool
+	}{
+		{"0.3.0", "0.2.2", true},
+		{"v0.2.10", "0.2.9", true},
+		{"1.0", "0.9.9", true},
+		{"0.2.2", "0.2.2", false},
+		{"0.2.1", "0.2.2", false},
+		{"0.2.2", "0.2.2-next", false},
+		{"0.2.3-rc1", "0.2.2", true},
+		{"0.3.0", "dev", false},
+		{"", "0.2.2", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNewerVersion(tt.latest, tt.current); got != tt.expected {
+			t.Errorf("IsNewerVersion(%q, %q) = %t, expected %t", tt.latest, tt.current, got, tt.expected)
+		}
+	}
+}
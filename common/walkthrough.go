@@ -0,0 +1,101 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// ParseWalkthrough parses the walkthrough of the post_summary tool, a JSON array of {files, summary} objects.
+// Models still sending the former "file: change summary" lines are parsed leniently.
+func ParseWalkthrough(raw json.RawMessage) ([]Walkthrough, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return []Walkthrough{}, nil
+	}
+
+	if raw[0] == '"' {
+		var lines string
+		if err := json.Unmarshal(raw, &lines); err != nil {
+			return nil, fmt.Errorf("invalid walkthrough: %v", err)
+		}
+		return ParseWalkthroughLines(lines), nil
+	}
+
+	var rows []Walkthrough
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("invalid walkthrough, expected an array of {files, summary} objects: %v", err)
+	}
+	walkthrough := make([]Walkthrough, 0, len(rows))
+	for _, row := range rows {
+		row.Files, row.Summary = cleanWalkthroughFiles(row.Files), strings.TrimSpace(row.Summary)
+		if row.Files == "" || row.Summary == "" {
+			logger.Warnf("Skipping walkthrough row without files or summary: %+v", row)
+			continue
+		}
+		walkthrough = append(walkthrough, row)
+	}
+	return walkthrough, nil
+}
+
+// ParseWalkthroughLines parses "file: change summary" lines, skipping the lines without a summary.
+// Markdown list markers are ignored, and drive letters of Windows paths are not taken as the separator.
+func ParseWalkthroughLines(lines string) []Walkthrough {
+	walkthrough := make([]Walkthrough, 0)
+	for line := range strings.SplitSeq(lines, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*•"))
+		if line == "" {
+			continue
+		}
+
+		separator := walkthroughSeparator(line)
+		if separator < 0 {
+			logger.Warnf("Skipping walkthrough line without a 'file: change summary' separator: %s", line)
+			continue
+		}
+		files, summary := cleanWalkthroughFiles(line[:separator]), strings.TrimSpace(line[separator+1:])
+		if files == "" || summary == "" {
+			logger.Warnf("Skipping walkthrough line without files or summary: %s", line)
+			continue
+		}
+		walkthrough = append(walkthrough, Walkthrough{Files: files, Summary: summary})
+	}
+	return walkthrough
+}
+
+// walkthroughSeparator returns the index of the colon separating the files from the summary, -1 if there is none.
+// A colon followed by whitespace is preferred, then the first colon which is not the drive of a Windows path.
+func walkthroughSeparator(line string) int {
+	if index := strings.Index(line, ": "); index >= 0 {
+		return index
+	}
+	if index := strings.Index(line, ":\t"); index >= 0 {
+		return index
+	}
+	for index, char := range line {
+		if char == ':' && !isDriveColon(line, index) {
+			return index
+		}
+	}
+	return -1
+}
+
+// isDriveColon checks if the colon at the index is the drive of a Windows path, like C:\ or C:/
+func isDriveColon(line string, index int) bool {
+	if index == 0 || index+1 >= len(line) || (line[index+1] != '\\' && line[index+1] != '/') {
+		return false
+	}
+	letter := line[index-1]
+	isLetter := (letter >= 'a' && letter <= 'z') || (letter >= 'A' && letter <= 'Z')
+	startsPath := index == 1 || strings.ContainsRune(" ,`", rune(line[index-2]))
+	return isLetter && startsPath
+}
+
+// cleanWalkthroughFiles removes the markdown around the files of a walkthrough row
+func cleanWalkthroughFiles(files string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(files), "`*"))
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rootGroup is the group of the files at the root of the repository
+const rootGroup = "(root)"
+
+// WalkthroughGroups groups the walkthrough of large pull requests by directory, a single table of hundreds of rows is unreadable
+type WalkthroughGroups struct {
+	MinFiles int `yaml:"min_files"` // Groups the walkthrough of pull requests changing at least this many files, 0 disables grouping
+	Depth    int `yaml:"depth"`     // Number of leading directories naming the group, like 2 for packages/api, defaults to 1
+	MaxRows  int `yaml:"max_rows"`  // Rows shown per group, the rest is counted in an "and N more files" line, 0 shows every row
+}
+
+// walkthroughGroup is the rows of the walkthrough under the same directory
+type walkthroughGroup struct {
+	Name  string
+	Rows  []Walkthrough
+	Files int
+}
+
+// formatWalkthroughSection formats the walkthrough as a table, or as collapsible tables per directory for large pull requests
+func formatWalkthroughSection(walkthrough []Walkthrough, groups WalkthroughGroups, renderer MarkdownRenderer, link func(text, file string) string) string {
+	if groups.MinFiles <= 0 || walkthroughFileCount(walkthrough) < groups.MinFiles {
+		return formatWalkthrough(walkthrough, link)
+	}
+
+	var builder strings.Builder
+	for _, group := range groupWalkthrough(walkthrough, groups.Depth) {
+		rows, hidden := group.Rows, 0
+		if groups.MaxRows > 0 && len(rows) > groups.MaxRows {
+			for _, row := range rows[groups.MaxRows:] {
+				hidden += len(walkthroughFiles(row))
+			}
+			rows = rows[:groups.MaxRows]
+		}
+
+		content := formatWalkthrough(rows, link)
+		if hidden > 0 {
+			content += fmt.Sprintf("\n_…and %d more files_\n", hidden)
+		}
+		builder.WriteString(renderer.Collapsible(fmt.Sprintf("%s (%d files)", group.Name, group.Files), content) + "\n\n")
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// groupWalkthrough groups the rows by the directory of their first file, in the order of their first row,
+// so the group of the highest ranked row comes first
+func groupWalkthrough(walkthrough []Walkthrough, depth int) []walkthroughGroup {
+	if depth <= 0 {
+		depth = 1
+	}
+
+	var groups []walkthroughGroup
+	index := map[string]int{}
+	for _, row := range walkthrough {
+		files := walkthroughFiles(row)
+		name := rootGroup
+		if len(files) > 0 {
+			name = walkthroughGroupName(files[0], depth)
+		}
+
+		idx, ok := index[name]
+		if !ok {
+			idx = len(groups)
+			index[name] = idx
+			groups = append(groups, walkthroughGroup{Name: name})
+		}
+		groups[idx].Rows = append(groups[idx].Rows, row)
+		groups[idx].Files += len(files)
+	}
+	return groups
+}
+
+// walkthroughGroupName returns the leading directories of the file, the root group for the files at the root
+func walkthroughGroupName(file string, depth int) string {
+	dirs := strings.Split(strings.TrimPrefix(file, "/"), "/")
+	dirs = dirs[:len(dirs)-1]
+	if len(dirs) == 0 {
+		return rootGroup
+	}
+	if len(dirs) > depth {
+		dirs = dirs[:depth]
+	}
+	return strings.Join(dirs, "/") + "/"
+}
+
+// walkthroughFiles returns the files of the row, listed separated by commas
+func walkthroughFiles(row Walkthrough) []string {
+	var files []string
+	for file := range strings.SplitSeq(row.Files, ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// walkthroughFileCount returns the number of files listed in the walkthrough
+func walkthroughFileCount(walkthrough []Walkthrough) int {
+	count := 0
+	for _, row := range walkthrough {
+		count += len(walkthroughFiles(row))
+	}
+	return count
+}
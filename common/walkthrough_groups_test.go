@@ -0,0 +1,55 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGroupWalkthrough(t *testing.T) {
+	walkthrough := []Walkthrough{
+		{Files: "cmd/main.go", Summary: "Adds the flag"},
+		{Files: "README.md", Summary: "Documents the flag"},
+		{Files: "cmd/flags.go, cmd/root.go", Summary: "Parses the flag"},
+		{Files: "packages/api/server.go", Summary: "Serves the flag"},
+	}
+
+	groups := groupWalkthrough(walkthrough, 1)
+	names := []string{}
+	for _, group := range groups {
+		names = append(names, fmt.Sprintf("%s:%d", group.Name, group.Files))
+	}
+	if strings.Join(names, " ") != "cmd/:3 (root):1 packages/:1" {
+		t.Errorf("Unexpected groups %v", names)
+	}
+
+	if groups := groupWalkthrough(walkthrough, 2); groups[2].Name != "packages/api/" || groups[0].Name != "cmd/" {
+		t.Errorf("Expected the groups of two directories, got %+v", groups)
+	}
+}
+
+func TestFormatWalkthroughSection(t *testing.T) {
+	walkthrough := []Walkthrough{}
+	for idx := range 5 {
+		walkthrough = append(walkthrough, Walkthrough{Files: fmt.Sprintf("common/file%d.go", idx), Summary: "Changes"})
+	}
+	walkthrough = append(walkthrough, Walkthrough{Files: "cmd/main.go", Summary: "Changes"})
+
+	renderer := NewMarkdownRenderer(ProviderGitHub)
+	link := func(text, file string) string { return text }
+
+	// Small pull requests keep the single table
+	if section := formatWalkthroughSection(walkthrough, WalkthroughGroups{MinFiles: 10}, renderer, link); section != formatWalkthrough(walkthrough, link) {
+		t.Errorf("Expected a single table, got %s", section)
+	}
+
+	section := formatWalkthroughSection(walkthrough, WalkthroughGroups{MinFiles: 6, MaxRows: 3}, renderer, link)
+	for _, expected := range []string{"common/ (5 files)", "cmd/ (1 files)", "_…and 2 more files_", "file2.go"} {
+		if !strings.Contains(section, expected) {
+			t.Errorf("Expected the section to contain %q, got %s", expected, section)
+		}
+	}
+	if strings.Contains(section, "file3.go") || strings.Count(section, "<details>") != 2 {
+		t.Errorf("Expected two collapsible groups with the capped rows, got %s", section)
+	}
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseWalkthroughArray(t *testing.T) {
+	raw := json.RawMessage(`[
+		{"files": "main.go, cmd/root.go", "summary": "Adds the date filter: from and to"},
+		{"files": "` + "`util.go`" + `", "summary": " Extracts the helpers "},
+		{"files": "empty.go", "summary": ""}
+	]`)
+	walkthrough, err := ParseWalkthrough(raw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Walkthrough{
+		{Files: "main.go, cmd/root.go", Summary: "Adds the date filter: from and to"},
+		{Files: "util.go", Summary: "Extracts the helpers"},
+	}
+	if !reflect.DeepEqual(walkthrough, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, walkthrough)
+	}
+
+	if _, err := ParseWalkthrough(json.RawMessage(`{"files": "main.go"}`)); err == nil {
+		t.Error("Expected an error for an object instead of an array")
+	}
+	if walkthrough, err := ParseWalkthrough(nil); err != nil || len(walkthrough) != 0 {
+		t.Errorf("Expected an empty walkthrough for a missing one, got %+v, %v", walkthrough, err)
+	}
+}
+
+func TestParseWalkthroughLines(t *testing.T) {
+	lines := "main.go: Implemented search filtering by date: from and to\n" +
+		"- `cmd/root.go`: Updated CLI commands\n" +
+		"\n" +
+		"a line without a separator\n" +
+		"config.yml:Reads the new option\n"
+	raw, _ := json.Marshal(lines)
+
+	walkthrough, err := ParseWalkthrough(raw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Walkthrough{
+		{Files: "main.go", Summary: "Implemented search filtering by date: from and to"},
+		{Files: "cmd/root.go", Summary: "Updated CLI commands"},
+		{Files: "config.yml", Summary: "Reads the new option"},
+	}
+	if !reflect.DeepEqual(walkthrough, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, walkthrough)
+	}
+}
+
+func TestParseWalkthroughLinesWindowsPaths(t *testing.T) {
+	tests := map[string]Walkthrough{
+		`C:\src\app\main.go: Adds the date filter`:                  {Files: `C:\src\app\main.go`, Summary: "Adds the date filter"},
+		`C:\src\main.go, D:/lib/util.go: Extracts the helpers`:      {Files: `C:\src\main.go, D:/lib/util.go`, Summary: "Extracts the helpers"},
+		`C:\src\main.go:Adds the date filter`:                       {Files: `C:\src\main.go`, Summary: "Adds the date filter"},
+		"- `C:\\src\\main.go`: Adds the date filter on C:\\ drives": {Files: `C:\src\main.go`, Summary: `Adds the date filter on C:\ drives`},
+	}
+	for line, expected := range tests {
+		walkthrough := ParseWalkthroughLines(line)
+		if len(walkthrough) != 1 || walkthrough[0] != expected {
+			t.Errorf("Expected %+v for %q, got %+v", expected, line, walkthrough)
+		}
+	}
+
+	if walkthrough := ParseWalkthroughLines(`C:\src\main.go`); len(walkthrough) != 0 {
+		t.Errorf("Expected the drive colon not to be a separator, got %+v", walkthrough)
+	}
+}
@@ -0,0 +1,56 @@
+package common
+
+// Permissions of the provider token checked by the preflight
+const (
+	PermissionComment = "comment" // Post the summary and the replies
+	PermissionReview  = "review"  // Create reviews with the line comments
+	PermissionLabels  = "labels"  // Edit the labels of the pull request
+)
+
+// WriteAccess is the result of the preflight check whether the token can post on the pull request
+type WriteAccess struct {
+	Fork        bool         // The pull request is opened from a fork
+	CanWrite    bool         // The token can post comments on the pull request
+	Reason      string       // Why the token can't post, empty if it can
+	Permissions []Permission // Permissions of the token on the pull request, the ones the provider doesn't have are left out
+}
+
+// Permission is a permission of the provider token, with how to grant it if it is missing.
+// Permissions the provider doesn't report, like the ones of fine-grained tokens, are assumed to be granted.
+type Permission struct {
+	Name        string
+	Granted     bool
+	Required    bool   // The review can't be posted without it
+	Remediation string // How to grant the missing permission
+}
+
+// Granted returns true if the token has the permission, permissions the provider doesn't have are not granted
+func (a WriteAccess) Granted(name string) bool {
+	for _, permission := range a.Permissions {
+		if permission.Name == name {
+			return permission.Granted
+		}
+	}
+	return false
+}
+
+// Missing returns the permissions the token doesn't have
+func (a WriteAccess) Missing() []Permission {
+	var missing []Permission
+	for _, permission := range a.Permissions {
+		if !permission.Granted {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
+
+// MissingRequired returns true if the token misses a permission the review can't be posted without
+func (a WriteAccess) MissingRequired() bool {
+	for _, permission := range a.Missing() {
+		if permission.Required {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,326 @@
+// Package diffparse parses unified git diffs into files, hunks and lines
+package diffparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Statuses of a changed file
+const (
+	StatusModified = "modified"
+	StatusAdded    = "added"
+	StatusDeleted  = "deleted"
+	StatusRenamed  = "renamed"
+)
+
+// devNull is the path of the missing side of added and deleted files
+const devNull = "/dev/null"
+
+// LineKind is the kind of a line of a hunk
+type LineKind int
+
+// Kinds of the lines of a hunk
+const (
+	LineContext LineKind = iota
+	LineAdded
+	LineDeleted
+)
+
+// hunkHeaderRegex matches the ranges and the section heading of a hunk header, e.g. @@ -1,3 +1,4 @@ func main() {
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// Line is a line of a hunk, numbered in the old file for context and deleted lines and in the new file for context and added lines
+type Line struct {
+	Kind      LineKind
+	Content   string // Without the +, - or space prefix and the trailing carriage return
+	OldNumber int    // 0 for added lines
+	NewNumber int    // 0 for deleted lines
+	NoNewline bool   // The line is the last line of its file without a newline at the end
+}
+
+// Hunk is a contiguous block of changes
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string // Enclosing declaration after the ranges of the header, if any
+	Lines    []Line
+}
+
+// FileDiff is the diff of a single file
+type FileDiff struct {
+	OldPath string // Empty for added files
+	NewPath string // Empty for deleted files
+	Status  string
+	OldMode string
+	NewMode string
+	OldBlob string // Abbreviated blob hash before the change, from the index line
+	NewBlob string // Abbreviated blob hash after the change, from the index line
+	Binary  bool
+	Hunks   []Hunk
+	Raw     string // The section of the diff changing the file, starting with its diff --git line if it has one
+}
+
+// Path returns the path of the file after the change, or before it for deleted files
+func (f FileDiff) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// AddedLines returns the added lines of the file
+func (f FileDiff) AddedLines() []Line {
+	var lines []Line
+	for _, hunk := range f.Hunks {
+		for _, line := range hunk.Lines {
+			if line.Kind == LineAdded {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+// DeletedLines returns the deleted lines of the file
+func (f FileDiff) DeletedLines() []Line {
+	var lines []Line
+	for _, hunk := range f.Hunks {
+		for _, line := range hunk.Lines {
+			if line.Kind == LineDeleted {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+// Parse parses a unified diff, with or without the git extended headers.
+// Lines are read up to the line counts of the hunk headers, so removed lines looking like file headers are kept in the hunk.
+func Parse(diff string) []FileDiff {
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+	var raw []string
+	oldLine, newLine, oldRemaining, newRemaining := 0, 0, 0, 0
+
+	finishFile := func() {
+		if file == nil {
+			return
+		}
+		file.Raw = strings.Join(raw, "\n")
+		files = append(files, *file)
+		file, hunk, raw = nil, nil, nil
+	}
+
+	lines := strings.Split(diff, "\n")
+	for i, rawLine := range lines {
+		line := strings.TrimSuffix(rawLine, "\r")
+
+		// Lines of a hunk, up to its line counts. Other lines end the hunk early, in case the counts are wrong.
+		var prefix byte = ' '
+		if line != "" {
+			prefix = line[0]
+		}
+		if hunk != nil && (oldRemaining > 0 || newRemaining > 0) && strings.IndexByte(" +-\\", prefix) >= 0 {
+			if line == "" && i == len(lines)-1 {
+				break
+			}
+			raw = append(raw, rawLine)
+
+			switch prefix {
+			case '+':
+				hunk.Lines = append(hunk.Lines, Line{Kind: LineAdded, Content: line[1:], NewNumber: newLine})
+				newLine++
+				newRemaining--
+			case '-':
+				hunk.Lines = append(hunk.Lines, Line{Kind: LineDeleted, Content: line[1:], OldNumber: oldLine})
+				oldLine++
+				oldRemaining--
+			case '\\':
+				markNoNewline(hunk)
+			default:
+				// Some tools strip the space of empty context lines
+				content := ""
+				if line != "" {
+					content = line[1:]
+				}
+				hunk.Lines = append(hunk.Lines, Line{Kind: LineContext, Content: content, OldNumber: oldLine, NewNumber: newLine})
+				oldLine++
+				newLine++
+				oldRemaining--
+				newRemaining--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			finishFile()
+			file = &FileDiff{Status: StatusModified}
+			file.OldPath, file.NewPath = parseGitHeader(strings.TrimPrefix(line, "diff --git "))
+		case strings.HasPrefix(line, "--- ") && (file == nil || len(file.Hunks) > 0) && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			// A diff without the git headers starts a file at its --- line
+			finishFile()
+			file = &FileDiff{Status: StatusModified}
+		}
+
+		if file == nil {
+			continue
+		}
+		raw = append(raw, rawLine)
+
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			match := hunkHeaderRegex.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			file.Hunks = append(file.Hunks, Hunk{
+				OldStart: atoi(match[1]),
+				OldLines: count(match[2]),
+				NewStart: atoi(match[3]),
+				NewLines: count(match[4]),
+				Section:  match[5],
+			})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			oldLine, newLine = hunk.OldStart, hunk.NewStart
+			oldRemaining, newRemaining = hunk.OldLines, hunk.NewLines
+		case strings.HasPrefix(line, `\`) && hunk != nil:
+			markNoNewline(hunk)
+		case strings.HasPrefix(line, "--- "):
+			if path := parsePath(strings.TrimPrefix(line, "--- "), "a/"); path != devNull {
+				file.OldPath = path
+			} else {
+				file.OldPath = ""
+				file.Status = StatusAdded
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if path := parsePath(strings.TrimPrefix(line, "+++ "), "b/"); path != devNull {
+				file.NewPath = path
+			} else {
+				file.NewPath = ""
+				file.Status = StatusDeleted
+			}
+		case strings.HasPrefix(line, "new file mode "):
+			file.Status = StatusAdded
+			file.OldPath = ""
+			file.NewMode = strings.TrimPrefix(line, "new file mode ")
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.Status = StatusDeleted
+			file.NewPath = ""
+			file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+		case strings.HasPrefix(line, "old mode "):
+			file.OldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			file.NewMode = strings.TrimPrefix(line, "new mode ")
+		case strings.HasPrefix(line, "rename from "):
+			file.OldPath = parsePath(strings.TrimPrefix(line, "rename from "), "")
+			file.Status = StatusRenamed
+		case strings.HasPrefix(line, "rename to "):
+			file.NewPath = parsePath(strings.TrimPrefix(line, "rename to "), "")
+			file.Status = StatusRenamed
+		case strings.HasPrefix(line, "index "):
+			// index <old>..<new> <mode> carries the mode of unchanged modes
+			fields := strings.Fields(line)
+			if len(fields) >= 2 {
+				file.OldBlob, file.NewBlob, _ = strings.Cut(fields[1], "..")
+			}
+			if len(fields) == 3 {
+				file.OldMode, file.NewMode = fields[2], fields[2]
+			}
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			file.Binary = true
+		}
+	}
+	finishFile()
+
+	return files
+}
+
+// FindFile returns the diff of the file by its path after the change, or before it for deleted files
+func FindFile(files []FileDiff, path string) (FileDiff, bool) {
+	for _, file := range files {
+		if file.Path() == path {
+			return file, true
+		}
+	}
+	return FileDiff{}, false
+}
+
+// markNoNewline marks the last line of the hunk as missing the newline at the end of the file
+func markNoNewline(hunk *Hunk) {
+	if len(hunk.Lines) > 0 {
+		hunk.Lines[len(hunk.Lines)-1].NoNewline = true
+	}
+}
+
+// parseGitHeader returns the paths of the diff --git line, which is ambiguous for paths with spaces.
+// The paths are overridden by the ---, +++ and rename lines when the diff has them.
+func parseGitHeader(header string) (string, string) {
+	if strings.HasPrefix(header, `"`) {
+		if oldPath, rest, ok := cutQuoted(header); ok {
+			return strings.TrimPrefix(oldPath, "a/"), parsePath(strings.TrimSpace(rest), "b/")
+		}
+	}
+
+	// Without renames both paths are the same, which splits the header in the middle
+	if half := (len(header) - 1) / 2; len(header)%2 == 1 && header[half] == ' ' &&
+		strings.TrimPrefix(header[:half], "a/") == strings.TrimPrefix(header[half+1:], "b/") {
+		return strings.TrimPrefix(header[:half], "a/"), strings.TrimPrefix(header[half+1:], "b/")
+	}
+
+	if index := strings.LastIndex(header, " b/"); index >= 0 {
+		return strings.TrimPrefix(header[:index], "a/"), header[index+3:]
+	}
+	return "", ""
+}
+
+// parsePath unquotes the path of a header and strips its prefix and the timestamp of non-git diffs
+func parsePath(path, prefix string) string {
+	if strings.HasPrefix(path, `"`) {
+		if unquoted, _, ok := cutQuoted(path); ok {
+			path = unquoted
+		}
+	} else if before, _, found := strings.Cut(path, "\t"); found {
+		path = before
+	}
+	if path == devNull {
+		return path
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// cutQuoted unquotes the C-style quoted string at the start of the text, git quotes paths with special characters
+func cutQuoted(text string) (string, string, bool) {
+	for i := 1; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if text[i] == '"' {
+			unquoted, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return "", "", false
+			}
+			return unquoted, text[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+func atoi(value string) int {
+	number, _ := strconv.Atoi(value)
+	return number
+}
+
+// count returns the line count of a hunk range, which is 1 if omitted
+func count(value string) int {
+	if value == "" {
+		return 1
+	}
+	return atoi(value)
+}
@@ -0,0 +1,255 @@
+package diffparse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseModifiedFile(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@ package main
+ package main
+-var a = 1
++var a = 2
++var b = 3
+
+ func main() {}
+@@ -10 +11 @@ func run() {
+-	return
++	return nil
+`
+	files := Parse(diff)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	file := files[0]
+	if file.OldPath != "main.go" || file.NewPath != "main.go" || file.Status != StatusModified || file.OldMode != "100644" || file.NewMode != "100644" || file.OldBlob != "83db48f" || file.NewBlob != "bf269f4" {
+		t.Errorf("Unexpected file %+v", file)
+	}
+	if file.Raw != diff {
+		t.Errorf("Expected the raw section to be the whole diff, got:\n%s", file.Raw)
+	}
+
+	if len(file.Hunks) != 2 {
+		t.Fatalf("Expected 2 hunks, got %d", len(file.Hunks))
+	}
+	first := file.Hunks[0]
+	if first.OldStart != 1 || first.OldLines != 4 || first.NewStart != 1 || first.NewLines != 5 || first.Section != "package main" {
+		t.Errorf("Unexpected hunk header %+v", first)
+	}
+	expected := []Line{
+		{Kind: LineContext, Content: "package main", OldNumber: 1, NewNumber: 1},
+		{Kind: LineDeleted, Content: "var a = 1", OldNumber: 2},
+		{Kind: LineAdded, Content: "var a = 2", NewNumber: 2},
+		{Kind: LineAdded, Content: "var b = 3", NewNumber: 3},
+		{Kind: LineContext, Content: "", OldNumber: 3, NewNumber: 4},
+		{Kind: LineContext, Content: "func main() {}", OldNumber: 4, NewNumber: 5},
+	}
+	if !reflect.DeepEqual(first.Lines, expected) {
+		t.Errorf("Expected lines %+v, got %+v", expected, first.Lines)
+	}
+
+	// Omitted counts default to 1
+	second := file.Hunks[1]
+	if second.OldLines != 1 || second.NewLines != 1 || len(second.Lines) != 2 || second.Lines[1].NewNumber != 11 {
+		t.Errorf("Unexpected hunk %+v", second)
+	}
+
+	added := file.AddedLines()
+	if len(added) != 3 || added[2].Content != "\treturn nil" {
+		t.Errorf("Unexpected added lines %+v", added)
+	}
+}
+
+func TestParseAddedDeletedAndBinaryFiles(t *testing.T) {
+	diff := `diff --git a/old.go b/old.go
+deleted file mode 100644
+index 1111111..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+diff --git a/new.go b/new.go
+new file mode 100755
+index 0000000..2222222
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main
+diff --git a/logo.png b/logo.png
+index 3333333..4444444 100644
+Binary files a/logo.png and b/logo.png differ`
+
+	files := Parse(diff)
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %d", len(files))
+	}
+	if deleted := files[0]; deleted.Status != StatusDeleted || deleted.Path() != "old.go" || deleted.NewPath != "" || deleted.OldMode != "100644" {
+		t.Errorf("Unexpected deleted file %+v", deleted)
+	}
+	if added := files[1]; added.Status != StatusAdded || added.Path() != "new.go" || added.OldPath != "" || added.NewMode != "100755" {
+		t.Errorf("Unexpected added file %+v", added)
+	}
+	if binary := files[2]; !binary.Binary || binary.Path() != "logo.png" || len(binary.Hunks) != 0 {
+		t.Errorf("Unexpected binary file %+v", binary)
+	}
+	if !strings.HasPrefix(files[1].Raw, "diff --git a/new.go b/new.go\n") || !strings.HasSuffix(files[1].Raw, "\n+package main") {
+		t.Errorf("Unexpected raw section:\n%s", files[1].Raw)
+	}
+}
+
+func TestParseRenameAndModeChange(t *testing.T) {
+	diff := `diff --git a/docs/old name.md b/docs/new name.md
+similarity index 90%
+rename from docs/old name.md
+rename to docs/new name.md
+index 1111111..2222222 100644
+--- a/docs/old name.md
++++ b/docs/new name.md
+@@ -1 +1 @@
+-# Old
++# New
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+diff --git "a/caf\303\251.txt" "b/caf\303\251.txt"
+index 3333333..4444444 100644
+--- "a/caf\303\251.txt"
++++ "b/caf\303\251.txt"
+@@ -1 +1 @@
+-a
++b`
+
+	files := Parse(diff)
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %d", len(files))
+	}
+	if renamed := files[0]; renamed.Status != StatusRenamed || renamed.OldPath != "docs/old name.md" || renamed.NewPath != "docs/new name.md" {
+		t.Errorf("Unexpected renamed file %+v", renamed)
+	}
+	if mode := files[1]; mode.Path() != "script.sh" || mode.OldMode != "100644" || mode.NewMode != "100755" || len(mode.Hunks) != 0 {
+		t.Errorf("Unexpected mode change %+v", mode)
+	}
+	if quoted := files[2]; quoted.OldPath != "café.txt" || quoted.NewPath != "café.txt" {
+		t.Errorf("Expected the quoted paths to be unquoted, got %+v", quoted)
+	}
+}
+
+func TestParsePureRename(t *testing.T) {
+	files := Parse(`diff --git a/a.go b/b.go
+similarity index 100%
+rename from a.go
+rename to b.go`)
+	if len(files) != 1 || files[0].Status != StatusRenamed || files[0].OldPath != "a.go" || files[0].NewPath != "b.go" {
+		t.Errorf("Unexpected rename %+v", files)
+	}
+}
+
+func TestParseNoNewlineAtEndOfFile(t *testing.T) {
+	files := Parse(`diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ first
+-second
+\ No newline at end of file
++second
+\ No newline at end of file`)
+
+	lines := files[0].Hunks[0].Lines
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %+v", lines)
+	}
+	if lines[0].NoNewline || !lines[1].NoNewline || !lines[2].NoNewline {
+		t.Errorf("Expected the last old and new lines to be marked, got %+v", lines)
+	}
+}
+
+func TestParseCRLF(t *testing.T) {
+	diff := "diff --git a/win.txt b/win.txt\r\n--- a/win.txt\r\n+++ b/win.txt\r\n@@ -1 +1,2 @@\r\n-old\r\n+new\r\n+line\r\n"
+	files := Parse(diff)
+	if len(files) != 1 || files[0].NewPath != "win.txt" {
+		t.Fatalf("Unexpected files %+v", files)
+	}
+	added := files[0].AddedLines()
+	if len(added) != 2 || added[0].Content != "new" || added[1].Content != "line" || added[1].NewNumber != 2 {
+		t.Errorf("Expected the carriage returns to be stripped, got %+v", added)
+	}
+	if files[0].Raw != diff {
+		t.Error("Expected the raw section to keep the carriage returns")
+	}
+}
+
+func TestParseDeletedLinesLookingLikeHeaders(t *testing.T) {
+	// A removed SQL comment and a removed line starting with "+++ " look like file headers
+	files := Parse(`diff --git a/schema.sql b/schema.sql
+--- a/schema.sql
++++ b/schema.sql
+@@ -1,3 +1,1 @@
+--- drop the legacy table
+-+++ counter
+ SELECT 1;
+diff --git a/other.sql b/other.sql
+--- a/other.sql
++++ b/other.sql
+@@ -1 +1 @@
+-a
++b`)
+
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(files))
+	}
+	lines := files[0].Hunks[0].Lines
+	if len(lines) != 3 || lines[0].Content != "-- drop the legacy table" || lines[1].Content != "+++ counter" {
+		t.Errorf("Expected the removed lines to stay in the hunk, got %+v", lines)
+	}
+	if files[0].OldPath != "schema.sql" {
+		t.Errorf("Expected the path not to be overridden by removed lines, got %s", files[0].OldPath)
+	}
+}
+
+func TestParseWithoutGitHeaders(t *testing.T) {
+	files := Parse(`--- a/one.txt	2026-10-16 10:00:00
++++ b/one.txt	2026-10-16 10:05:00
+@@ -1 +1 @@
+-a
++b
+--- a/two.txt
++++ b/two.txt
+@@ -1 +1 @@
+-c
++d`)
+
+	if len(files) != 2 || files[0].Path() != "one.txt" || files[1].Path() != "two.txt" {
+		t.Fatalf("Unexpected files %+v", files)
+	}
+	if file, ok := FindFile(files, "two.txt"); !ok || file.AddedLines()[0].Content != "d" {
+		t.Errorf("Expected to find two.txt, got %+v", file)
+	}
+	if _, ok := FindFile(files, "three.txt"); ok {
+		t.Error("Expected no diff for an unchanged file")
+	}
+}
+
+func TestParseWrongHunkCounts(t *testing.T) {
+	// The counts of the first hunk are too large, the next file header ends it
+	files := Parse(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+-var a = 1
++var a = 2
+diff --git a/new.go b/new.go
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main`)
+
+	if len(files) != 2 || len(files[0].Hunks[0].Lines) != 2 || files[1].Path() != "new.go" {
+		t.Errorf("Expected the hunk to end at the next file, got %+v", files)
+	}
+}
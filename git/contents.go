@@ -0,0 +1,150 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// fileContentWorkers is the number of files read in parallel
+const fileContentWorkers = 8
+
+// fileContentBudget is the maximum total size of the file contents in bytes, 0 disables it
+var fileContentBudget int
+
+// SetFileContentBudget sets the maximum total size of the file contents returned by GetFileContents, 0 disables it
+func SetFileContentBudget(bytes int) {
+	fileContentBudget = bytes
+}
+
+// lowPriorityFiles are generated or vendored files, truncated before the source files when the contents exceed the budget
+var lowPriorityFiles = []string{
+	"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "Podfile.lock", "Gemfile.lock", "Cargo.lock", "go.sum",
+	"composer.lock", "Package.resolved", "gradle.lockfile", "*.min.js", "*.min.css", "*.pbxproj", "*.snap",
+}
+
+// lowPriorityDirs are directories of generated or vendored files
+var lowPriorityDirs = []string{"vendor/", "node_modules/", "Pods/", "dist/", "build/", "generated/"}
+
+// GetFileContentsOfFiles returns the contents of the given files at the commit,
+// for diffs not generated from the git history.
+// Files are read in parallel, the output keeps the order of the files.
+func (c *Client) GetFileContentsOfFiles(commitHash string, files []string) (string, error) {
+	contents := make([]string, len(files))
+	errs := make([]error, len(files))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(fileContentWorkers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				logger.Debug("Processing file:", files[idx])
+				contents[idx], errs[idx] = c.GetFileContent(commitHash, files[idx])
+			}
+		}()
+	}
+	for idx := range files {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+
+	for idx, err := range errs {
+		if err != nil {
+			errMsg := fmt.Sprintf("error getting file content for %s: %v", files[idx], err)
+			logger.Errorf(errMsg)
+			return "", errors.New(errMsg)
+		}
+	}
+
+	budgeted := applyContentBudget(files, contents, fileContentBudget)
+
+	fileOutput := []string{}
+	for idx, filePath := range files {
+		if contents[idx] == "" {
+			logger.Warn("File not found or empty:", filePath)
+			continue
+		}
+		if budgeted[idx] == "" {
+			continue
+		}
+		fileOutput = append(fileOutput, fmt.Sprintf("===== FILE: %s =====\n%s\n===== END =====\n\n", filePath, budgeted[idx]))
+	}
+
+	return strings.Join(fileOutput, "\n\n"), nil
+}
+
+// applyContentBudget truncates the contents to fit the budget. Source files get the budget before the generated ones,
+// and smaller files before larger ones, so the budget covers as many files as possible.
+// Truncated files keep their first lines, the lines past the budget can not get comments.
+func applyContentBudget(files, contents []string, budget int) []string {
+	total := 0
+	for _, content := range contents {
+		total += len(content)
+	}
+	if budget <= 0 || total <= budget {
+		return contents
+	}
+
+	order := make([]int, len(files))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if IsGeneratedFile(files[a]) != IsGeneratedFile(files[b]) {
+			return !IsGeneratedFile(files[a])
+		}
+		return len(contents[a]) < len(contents[b])
+	})
+
+	truncated := make([]string, len(contents))
+	remaining := budget
+	for _, idx := range order {
+		content := contents[idx]
+		if len(content) <= remaining {
+			truncated[idx] = content
+			remaining -= len(content)
+			continue
+		}
+
+		// Cut at the last full line within the remaining budget
+		kept := content[:remaining]
+		if cut := strings.LastIndexByte(kept, '\n'); cut >= 0 {
+			kept = kept[:cut]
+		} else {
+			kept = ""
+		}
+		remaining -= len(kept)
+		if kept == "" {
+			logger.Warnf("Skipped %s, the file contents exceed the budget of %d bytes", files[idx], budget)
+		} else {
+			logger.Warnf("Truncated %s to %d of %d bytes, the file contents exceed the budget of %d bytes", files[idx], len(kept), len(content), budget)
+		}
+		truncated[idx] = kept
+	}
+	return truncated
+}
+
+// IsGeneratedFile checks if the file is generated or vendored, like the lock files and the files of the vendor directories
+func IsGeneratedFile(filePath string) bool {
+	for _, dir := range lowPriorityDirs {
+		if strings.HasPrefix(filePath, dir) || strings.Contains(filePath, "/"+dir) {
+			return true
+		}
+	}
+	name := path.Base(filePath)
+	for _, pattern := range lowPriorityFiles {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
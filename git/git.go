@@ -2,10 +2,14 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
 )
@@ -17,6 +21,14 @@ const (
 	DefaultDiffAlgorithm = "minimal"
 )
 
+// commandTimeout is the timeout of a single git command, 0 disables it
+var commandTimeout time.Duration
+
+// SetCommandTimeout sets the timeout of the git commands run by DefaultRunner, 0 disables it
+func SetCommandTimeout(timeout time.Duration) {
+	commandTimeout = timeout
+}
+
 // Runner defines an interface for running git commands
 type Runner interface {
 	Run(name string, args ...string) (string, error)
@@ -25,7 +37,7 @@ type Runner interface {
 // Ensure DefaultRunner implements Runner interface
 var _ Runner = (*DefaultRunner)(nil)
 
-// DefaultRunner implements the Runner interface using exec.Command
+// DefaultRunner implements the Runner interface using exec.CommandContext
 type DefaultRunner struct {
 	RepoPath string
 }
@@ -41,7 +53,13 @@ func NewDefaultRunner(repoPath string) *DefaultRunner {
 // Run executes a git command and returns its output
 func (r *DefaultRunner) Run(name string, args ...string) (string, error) {
 	logger.Debugf("Running git command: %s %s", name, strings.Join(args, " "))
-	cmd := exec.Command(name, args...)
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if commandTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+	}
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
 	if r.RepoPath != "" {
 		cmd.Dir = r.RepoPath
 	}
@@ -51,6 +69,11 @@ func (r *DefaultRunner) Run(name string, args ...string) (string, error) {
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		errMsg := fmt.Sprintf("command timed out after %s", commandTimeout)
+		logger.Errorf("Git command failed: %s", errMsg)
+		return "", errors.New(errMsg)
+	}
 	if err != nil {
 		errMsg := fmt.Sprintf("error running command: %s\nstderr: %s", err, stderr.String())
 		logger.Errorf("Git command failed: %s", errMsg)
@@ -64,13 +87,23 @@ func (r *DefaultRunner) Run(name string, args ...string) (string, error) {
 // Client provides Git operations for AI code review
 type Client struct {
 	runner Runner
+
+	blameMu    sync.Mutex
+	blameCache map[string]blameResult // Blame of the files by commit and path, a file is blamed once per run
+}
+
+// blameResult is the commit of each line of a blamed file, or the error of the blame
+type blameResult struct {
+	commits map[int]string
+	err     error
 }
 
 // NewClient creates a new Git client
 func NewClient(runner Runner) *Client {
 	logger.Debug("Creating new Git client")
 	return &Client{
-		runner: runner,
+		runner:     runner,
+		blameCache: map[string]blameResult{},
 	}
 }
 
@@ -125,27 +158,11 @@ func (c *Client) GetFileContents(commitHash, targetBranch string) (string, error
 		return "", errors.New(errMsg)
 	}
 
-	fileOutput := []string{}
-	for _, filePath := range files {
-		logger.Debug("Processing file:", filePath)
-		output, err := c.GetFileContent(commitHash, filePath)
-		if err != nil {
-			errMsg := fmt.Sprintf("error getting file content for %s: %v", filePath, err)
-			logger.Errorf(errMsg)
-			return "", errors.New(errMsg)
-		}
-		if output == "" {
-			logger.Warn("File not found or empty:", filePath)
-			continue
-		}
-		fileOutput = append(fileOutput, fmt.Sprintf("===== FILE: %s =====\n%s\n===== END =====\n\n", filePath, output))
-	}
-
-	return strings.Join(fileOutput, "\n\n"), nil
+	return c.GetFileContentsOfFiles(commitHash, files)
 }
 
 // GetBlameForFileLine retrieves the commit hash that last modified the specified line in a file.
-// Returns the commit hash responsible for the given line.
+// The file is blamed once per commit, later lines are served from the cache.
 func (c *Client) GetBlameForFileLine(commitHash string, filePath string, lineNumber int) (string, error) {
 	if commitHash == "" || filePath == "" || lineNumber <= 0 {
 		errMsg := "commit hash, file path and line number cannot be empty"
@@ -153,20 +170,87 @@ func (c *Client) GetBlameForFileLine(commitHash string, filePath string, lineNum
 		return "", errors.New(errMsg)
 	}
 
-	output, err := c.runner.Run("git", "blame", "-L", fmt.Sprintf("%d,%d", lineNumber, lineNumber), commitHash, "--", filePath)
+	blame := c.blameFile(commitHash, filePath)
+	if blame.err != nil {
+		return "", blame.err
+	}
+
+	commit, ok := blame.commits[lineNumber]
+	if !ok {
+		errMsg := fmt.Sprintf("no blame for line %d of %s, the file has %d lines", lineNumber, filePath, len(blame.commits))
+		logger.Error(errMsg)
+		return "", errors.New(errMsg)
+	}
+	return commit, nil
+}
+
+// blameFile returns the blame of the whole file at the commit, running git blame only on the first call
+func (c *Client) blameFile(commitHash, filePath string) blameResult {
+	key := commitHash + ":" + filePath
+
+	c.blameMu.Lock()
+	defer c.blameMu.Unlock()
+	if c.blameCache == nil {
+		c.blameCache = map[string]blameResult{}
+	}
+	if blame, ok := c.blameCache[key]; ok {
+		return blame
+	}
+
+	var blame blameResult
+	output, err := c.runner.Run("git", "blame", "--porcelain", commitHash, "--", filePath)
 	if err != nil {
 		errMsg := fmt.Sprintf("error getting blame for file line: %v", err)
 		logger.Errorf(errMsg)
-		return "", errors.New(errMsg)
+		blame.err = errors.New(errMsg)
+	} else {
+		blame.commits, blame.err = parsePorcelainBlame(output)
+		if blame.err != nil {
+			logger.Error(blame.err.Error())
+		}
 	}
 
-	parts := strings.Split(output, " ")
-	if len(parts) < 2 {
-		errMsg := "invalid blame output, expected at least 2 parts"
-		logger.Error(errMsg)
-		return "", errors.New(errMsg)
+	c.blameCache[key] = blame
+	return blame
+}
+
+// parsePorcelainBlame returns the commit of each line from the output of git blame --porcelain.
+// Each line starts with a "<commit> <original line> <final line> [<lines in group>]" header,
+// followed by the details of the commit on its first occurrence and the content of the line prefixed with a tab.
+func parsePorcelainBlame(output string) (map[int]string, error) {
+	commits := map[int]string{}
+	expectHeader := true
+	for line := range strings.SplitSeq(output, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			expectHeader = true
+			continue
+		}
+		if !expectHeader || line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 || len(fields[0]) != 40 {
+			return nil, fmt.Errorf("invalid blame output, expected a line header, got: %s", line)
+		}
+		finalLine, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid line number in blame output: %s", line)
+		}
+		commits[finalLine] = fields[0]
+		expectHeader = false
 	}
-	return parts[0], nil
+	return commits, nil
+}
+
+// SameCommit returns true if the hashes identify the same commit.
+// Comments posted by older versions hold the abbreviated hash of git blame, prefixed with ^ for boundary commits.
+func SameCommit(a, b string) bool {
+	a, b = strings.TrimPrefix(a, "^"), strings.TrimPrefix(b, "^")
+	if len(a) < 7 || len(b) < 7 {
+		return a == b
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
 }
 
 // GetCommitHash returns the provided commit hash or the current commit hash if none is provided.
@@ -238,7 +322,16 @@ func (c *Client) GetDiffWithMergeBase(commitHash, branchName string, fileOnly bo
 		return "", errors.New(errMsg)
 	}
 
-	// Find the merge base
+	mergeBase, err := c.GetMergeBase(commitHash, branchName)
+	if err != nil {
+		return "", err
+	}
+
+	return c.getDiff(fmt.Sprintf("%s..%s", mergeBase, commitHash), fileOnly)
+}
+
+// GetMergeBase returns the best common ancestor of the commit and the provided branch
+func (c *Client) GetMergeBase(commitHash, branchName string) (string, error) {
 	mergeBase, err := c.runner.Run("git", "merge-base", commitHash, branchName)
 	if err != nil {
 		errMsg := fmt.Sprintf("error finding merge base between %s and %s: %v", commitHash, branchName, err)
@@ -246,7 +339,39 @@ func (c *Client) GetDiffWithMergeBase(commitHash, branchName string, fileOnly bo
 		return "", errors.New(errMsg)
 	}
 
-	return c.getDiff(fmt.Sprintf("%s..%s", mergeBase, commitHash), fileOnly)
+	return mergeBase, nil
+}
+
+// IsAncestor checks if the ancestor commit is in the history of the commit.
+// Returns an error if the history can't tell: a commit is missing, like the ones replaced by a force push,
+// or the checkout is shallow and the history may be cut before the ancestor.
+func (c *Client) IsAncestor(ancestor, commitHash string) (bool, error) {
+	for _, commit := range []string{ancestor, commitHash} {
+		if _, err := c.runner.Run("git", "cat-file", "-e", commit+"^{commit}"); err != nil {
+			return false, fmt.Errorf("commit %s is not in the checkout: %w", commit, err)
+		}
+	}
+	if shallow, err := c.runner.Run("git", "rev-parse", "--is-shallow-repository"); err != nil || shallow == "true" {
+		return false, errors.New("the checkout is shallow")
+	}
+
+	// merge-base exits with 1 if the commit is not an ancestor
+	_, err := c.runner.Run("git", "merge-base", "--is-ancestor", ancestor, commitHash)
+	return err == nil, nil
+}
+
+// SearchCommitMessages returns the latest commits of the history whose message contains all the terms, ignoring the case,
+// with the fields of the format separated by the unit separator and the commits by the record separator
+func (c *Client) SearchCommitMessages(terms []string, limit int) (string, error) {
+	if len(terms) == 0 {
+		return "", errors.New("terms cannot be empty")
+	}
+
+	args := []string{"log", "-i", "--fixed-strings", "--all-match", "--date=short", fmt.Sprintf("-n%d", limit), "--format=%h%x1f%ad%x1f%s%x1f%b%x1e"}
+	for _, term := range terms {
+		args = append(args, "--grep="+term)
+	}
+	return c.runner.Run("git", args...)
 }
 
 // GetCurrentCommitHash returns the hash of the current commit
@@ -254,6 +379,19 @@ func (c *Client) GetCurrentCommitHash() (string, error) {
 	return c.runner.Run("git", "rev-parse", "HEAD")
 }
 
+// Checkout checks out the commit detached, fetching it from origin first if it is not in the clone
+func (c *Client) Checkout(commitHash string) error {
+	if _, err := c.runner.Run("git", "cat-file", "-e", commitHash+"^{commit}"); err != nil {
+		if _, err := c.runner.Run("git", "fetch", "origin", commitHash); err != nil {
+			return fmt.Errorf("failed to fetch commit %s: %w", commitHash, err)
+		}
+	}
+	if _, err := c.runner.Run("git", "checkout", "--detach", commitHash); err != nil {
+		return fmt.Errorf("failed to check out commit %s: %w", commitHash, err)
+	}
+	return nil
+}
+
 // GetChangedFiles returns a list of files changed between two commits
 func (c *Client) GetChangedFiles(from, to string) ([]string, error) {
 	if from == "" || to == "" {
@@ -290,6 +428,22 @@ func (c *Client) GetFileContent(commitHash, filePath string) (string, error) {
 	return output, nil
 }
 
+// GetFileSize returns the size of the file at the ref in bytes
+func (c *Client) GetFileSize(ref, filePath string) (int64, error) {
+	if ref == "" || filePath == "" {
+		errMsg := "ref and file path cannot be empty"
+		logger.Error(errMsg)
+		return 0, errors.New(errMsg)
+	}
+
+	output, err := c.runner.Run("git", "cat-file", "-s", fmt.Sprintf("%s:%s", ref, filePath))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(output, 10, 64)
+}
+
 // GetChangedFilesForCommit returns a list of files changed in the given commit or compared to the merge base
 func (c *Client) getChangedFilesForCommit(commitHash, targetBranch string) ([]string, error) {
 	var diff string
@@ -1,11 +1,8 @@
 package llm
 
 import (
-	"context"
 	"errors"
 	"fmt"
-	"strings"
-	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -14,15 +11,22 @@ import (
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"
 )
 
+// minThinkingTokens is the smallest extended thinking budget accepted by the API
+const minThinkingTokens = 1024
+
 // AnthropicModel implements the LLM interface using Anthropic's API
 type AnthropicModel struct {
 	client       anthropic.Client
 	modelName    string
 	maxTokens    int
 	apiTimeout   int // in seconds
+	temperature  *float64
+	topP         *float64
+	thinking     int // Budget of extended thinking tokens, 0 disables thinking
 	GitProvider  *review.Reviewer
 	Settings     *common.Settings
 	LineFeedback []common.LineLevel
+	Sections     common.Summary
 }
 
 // NewAnthropic creates a new Anthropic client
@@ -45,34 +49,60 @@ func NewAnthropic(apiKey string, opts ...Option) (*AnthropicModel, error) {
 		option.WithHTTPClient(standardClient),
 	)
 
+	options, err := newConfig(Config{
+		Model:      "claude-3-sonnet", // Default model
+		MaxTokens:  4000,              // Default max tokens
+		APITimeout: 30,                // Default timeout in seconds
+	}, opts...)
+	if err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
 	model := &AnthropicModel{
-		client:     client,
-		modelName:  "claude-3-sonnet", // Default model
-		maxTokens:  4000,              // Default max tokens
-		apiTimeout: 30,                // Default timeout in seconds
-	}
-
-	// Apply options
-	for _, opt := range opts {
-		switch opt.Type {
-		case ModelNameOption:
-			if modelName, ok := opt.Value.(string); ok {
-				model.modelName = modelName
-			}
-		case MaxTokensOption:
-			if maxTokens, ok := opt.Value.(int); ok {
-				model.maxTokens = maxTokens
-			}
-		case APITimeoutOption:
-			if timeout, ok := opt.Value.(int); ok {
-				model.apiTimeout = timeout
-			}
-		}
+		client:      client,
+		modelName:   options.Model,
+		maxTokens:   options.MaxTokens,
+		apiTimeout:  options.APITimeout,
+		temperature: options.Temperature,
+		topP:        options.TopP,
+		thinking:    options.MaxThinkingTokens,
+	}
+
+	if err := model.validateOptions(options.ReasoningEffort); err != nil {
+		logger.Error(err.Error())
+		return nil, err
 	}
 
 	return model, nil
 }
 
+// validateOptions checks that the model supports the sampling and thinking options
+func (a *AnthropicModel) validateOptions(effort string) error {
+	if effort != "" {
+		return fmt.Errorf("reasoning_effort is not supported by Anthropic models, use max_thinking_tokens")
+	}
+	if a.temperature != nil && (*a.temperature < 0 || *a.temperature > 1) {
+		return fmt.Errorf("temperature must be between 0 and 1 for Anthropic models, got %g", *a.temperature)
+	}
+	if a.topP != nil && (*a.topP < 0 || *a.topP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1, got %g", *a.topP)
+	}
+
+	if a.thinking > 0 {
+		if a.modelName == "claude-3-haiku" {
+			return fmt.Errorf("extended thinking is not supported by %s", a.modelName)
+		}
+		if a.thinking < minThinkingTokens || a.thinking >= a.maxTokens {
+			return fmt.Errorf("max_thinking_tokens must be at least %d and less than the max tokens (%d), got %d", minThinkingTokens, a.maxTokens, a.thinking)
+		}
+		if a.temperature != nil || a.topP != nil {
+			return fmt.Errorf("temperature and top_p can't be set with extended thinking")
+		}
+	}
+	return nil
+}
+
 func (a *AnthropicModel) SetGitProvider(gitProvider *review.Reviewer) {
 	a.GitProvider = gitProvider
 }
@@ -81,17 +111,90 @@ func (a *AnthropicModel) SetSettings(settings *common.Settings) {
 	a.Settings = settings
 }
 
-// Prompt sends a request to Anthropic and returns the response
-func (a *AnthropicModel) Prompt(req Request) Response {
-	logger.Debugf("Sending prompt to Anthropic model: %s", a.modelName)
+func (a *AnthropicModel) SetSummarySections(sections common.Summary) {
+	a.Sections = sections
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(a.apiTimeout)*time.Second)
+// CheckAuth validates the API key by listing the available models
+func (a *AnthropicModel) CheckAuth() error {
+	ctx, cancel := common.RunTimeoutContext(a.apiTimeout)
 	defer cancel()
 
-	userContent := []string{req.UserPrompt}
+	if _, err := a.client.Models.List(ctx, anthropic.ModelListParams{Limit: anthropic.Int(1)}); err != nil {
+		var apiErr *anthropic.Error
+		if errors.As(err, &apiErr) {
+			return common.NewAPIError("Anthropic", apiErr.StatusCode, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Prompt sends a request to Anthropic and returns the response
+func (a *AnthropicModel) Prompt(req Request) Response {
+	resp := a.Complete(req)
+	if resp.Error == nil {
+		common.Session().SetConversation([]common.SessionMessage{
+			{Role: common.RoleSystem, Content: req.SystemPrompt},
+			{Role: common.RoleUser, Content: req.UserPrompt},
+			{Role: common.RoleAssistant, Content: resp.Content},
+		})
+	}
+	return resp
+}
+
+// Complete sends a request without tools to Anthropic, prompts are sent the same way as tools are not supported
+func (a *AnthropicModel) Complete(req Request) Response {
 	logger.Debug("Including message in Anthropic prompt")
 	logger.Debug(req.UserPrompt)
 
+	return a.send(req.SystemPrompt, []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewTextBlock(req.UserPrompt)),
+	})
+}
+
+// Continue sends the conversation of a saved session with a follow-up message.
+// Tool calls of the conversation are sent as text, as they were made with another provider.
+func (a *AnthropicModel) Continue(messages []common.SessionMessage) Response {
+	var system string
+	var params []anthropic.MessageParam
+	for _, message := range messages {
+		content := message.Content
+		role := anthropic.MessageParamRoleUser
+		switch message.Role {
+		case common.RoleSystem:
+			system = message.Content
+			continue
+		case common.RoleAssistant:
+			role = anthropic.MessageParamRoleAssistant
+			for _, toolCall := range message.ToolCalls {
+				content += fmt.Sprintf("\n\nCalled %s with %s", toolCall.Name, toolCall.Arguments)
+			}
+		case common.RoleTool:
+			content = fmt.Sprintf("Result of the tool call %s:\n%s", message.ToolCallID, message.Content)
+		}
+
+		// Consecutive messages of the same role are merged, the roles have to alternate
+		if len(params) > 0 && params[len(params)-1].Role == role {
+			params[len(params)-1].Content = append(params[len(params)-1].Content, anthropic.NewTextBlock(content))
+			continue
+		}
+		params = append(params, anthropic.MessageParam{
+			Role:    role,
+			Content: []anthropic.ContentBlockParamUnion{anthropic.NewTextBlock(content)},
+		})
+	}
+
+	return a.send(system, params)
+}
+
+// send sends the messages to Anthropic and returns the text response
+func (a *AnthropicModel) send(systemPrompt string, messages []anthropic.MessageParam) Response {
+	logger.Debugf("Sending prompt to Anthropic model: %s", a.modelName)
+
+	ctx, cancel := common.RunTimeoutContext(a.apiTimeout)
+	defer cancel()
+
 	// Convert model name string to anthropic.Model
 	var model anthropic.Model
 	switch a.modelName {
@@ -106,7 +209,7 @@ func (a *AnthropicModel) Prompt(req Request) Response {
 	}
 
 	logger.Debug("Including system message in Anthropic prompt")
-	logger.Debug(req.SystemPrompt)
+	logger.Debug(systemPrompt)
 
 	logger.Debugf("Using Anthropic model: %s with max tokens: %d", a.modelName, a.maxTokens)
 
@@ -115,16 +218,18 @@ func (a *AnthropicModel) Prompt(req Request) Response {
 		Model:     model,
 		MaxTokens: int64(a.maxTokens),
 		System: []anthropic.TextBlockParam{
-			{Text: req.SystemPrompt},
-		},
-		Messages: []anthropic.MessageParam{
-			{
-				Role: anthropic.MessageParamRoleUser,
-				Content: []anthropic.ContentBlockParamUnion{
-					anthropic.NewTextBlock(strings.Join(userContent, "\n\n")),
-				},
-			},
+			{Text: systemPrompt},
 		},
+		Messages: messages,
+	}
+	if a.temperature != nil {
+		messageParams.Temperature = anthropic.Float(*a.temperature)
+	}
+	if a.topP != nil {
+		messageParams.TopP = anthropic.Float(*a.topP)
+	}
+	if a.thinking > 0 {
+		messageParams.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(a.thinking))
 	}
 
 	// Make the API call
@@ -132,11 +237,18 @@ func (a *AnthropicModel) Prompt(req Request) Response {
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to create message with Anthropic: %v", err)
 		logger.Errorf(errMsg)
+
+		var apiErr *anthropic.Error
+		if errors.As(err, &apiErr) {
+			err = common.NewAPIError("Anthropic", apiErr.StatusCode, err)
+		}
 		return Response{
-			Error: errors.New(errMsg),
+			Error: common.WrapError(errMsg, err),
 		}
 	}
 
+	common.Report().AddTokens(int(message.Usage.InputTokens), int(message.Usage.OutputTokens))
+
 	// Extract text content from the response
 	var content string
 	for _, block := range message.Content {
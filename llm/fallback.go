@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"
+)
+
+// contextLengthInstructions are added to the system prompt after a model ran out of its context window
+const contextLengthInstructions = "\n- A previous attempt ran out of the context window. Only read the files needed to review the changes, and request the diff of single files instead of the whole diff."
+
+// fallbackLLM switches to the next model of the fallback chain when the active model fails
+type fallbackLLM struct {
+	LLM
+	model     string
+	fallbacks []common.ModelFallback
+	options   []Option
+
+	gitProvider *review.Reviewer
+	settings    *common.Settings
+	sections    common.Summary
+}
+
+func newFallbackLLM(client LLM, model string, fallbacks []common.ModelFallback, options []Option) *fallbackLLM {
+	return &fallbackLLM{
+		LLM:       client,
+		model:     model,
+		fallbacks: fallbacks,
+		options:   options,
+	}
+}
+
+func (f *fallbackLLM) SetGitProvider(gitProvider *review.Reviewer) {
+	f.gitProvider = gitProvider
+	f.LLM.SetGitProvider(gitProvider)
+}
+
+func (f *fallbackLLM) SetSettings(settings *common.Settings) {
+	f.settings = settings
+	f.LLM.SetSettings(settings)
+}
+
+func (f *fallbackLLM) SetSummarySections(sections common.Summary) {
+	f.sections = sections
+	f.LLM.SetSummarySections(sections)
+}
+
+// Prompt sends the request to the active model, falling back to the next model on failure
+func (f *fallbackLLM) Prompt(req Request) Response {
+	return f.run(req, LLM.Prompt)
+}
+
+// Complete sends the request without tools to the active model, falling back to the next model on failure
+func (f *fallbackLLM) Complete(req Request) Response {
+	return f.run(req, LLM.Complete)
+}
+
+func (f *fallbackLLM) run(req Request, send func(LLM, Request) Response) Response {
+	for {
+		resp := send(f.LLM, req)
+		if resp.Error == nil || !shouldFallback(resp.Error) {
+			return resp
+		}
+
+		logger.Warnf("Model %s failed: %v", f.model, resp.Error)
+		if !f.next() {
+			return resp
+		}
+
+		if isContextLengthError(resp.Error) && !strings.Contains(req.SystemPrompt, contextLengthInstructions) {
+			req.SystemPrompt += contextLengthInstructions
+		}
+	}
+}
+
+// next switches to the next model of the chain which can be created, returns false if the chain is exhausted
+func (f *fallbackLLM) next() bool {
+	for len(f.fallbacks) > 0 {
+		fallback := f.fallbacks[0]
+		f.fallbacks = f.fallbacks[1:]
+
+		// The key of the provider is used unless the fallback sets its own variable
+		var apiKey string
+		var err error
+		if fallback.APIKeyEnv != "" {
+			apiKey, err = getAPIKeyFromEnv(fallback.APIKeyEnv)
+		} else {
+			apiKey, err = getAPIKey(fallback.Provider)
+		}
+		if err != nil {
+			logger.Warnf("Skipping fallback model %s: %v", fallback.Model, err)
+			continue
+		}
+
+		client, err := newClient(fallback.Provider, apiKey, append(slices.Clone(f.options), WithModel(fallback.Model)))
+		if err != nil {
+			logger.Warnf("Skipping fallback model %s: %v", fallback.Model, err)
+			continue
+		}
+		if f.gitProvider != nil {
+			client.SetGitProvider(f.gitProvider)
+		}
+		if f.settings != nil {
+			client.SetSettings(f.settings)
+		}
+		client.SetSummarySections(f.sections)
+
+		logger.Infof("Falling back from %s to %s (%s)", f.model, fallback.Model, fallback.Provider)
+		f.LLM = client
+		f.model = fallback.Model
+		common.SetFallbackModel(fallback.Model)
+		common.Report().SetModel(fallback.Model)
+		common.Session().SetModel(fallback.Provider, fallback.Model)
+		return true
+	}
+	return false
+}
+
+// shouldFallback returns true for failures another model may not have: rate limits, overloaded servers and exceeded context windows.
+// The HTTP client already retried these, so the model failed repeatedly.
+func shouldFallback(err error) bool {
+	var apiErr *common.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.Kind {
+	case common.ErrorKindRateLimit, common.ErrorKindOverloaded, common.ErrorKindPayloadTooLarge:
+		return true
+	}
+	return false
+}
+
+func isContextLengthError(err error) bool {
+	var apiErr *common.APIError
+	return errors.As(err, &apiErr) && apiErr.Kind == common.ErrorKindPayloadTooLarge
+}
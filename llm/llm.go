@@ -3,7 +3,9 @@ package llm
 import (
 	"errors"
 	"fmt"
-	"os"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
@@ -15,44 +17,112 @@ const (
 	ProviderAnthropic = "anthropic"
 )
 
-// OptionType defines the type of option
-type OptionType string
+// apiKeyEnv is the environment variable of the legacy API key shared by the providers
+const apiKeyEnv = "LLM_API_KEY"
 
-// Available option types
-const (
-	ModelNameOption  OptionType = "model"
-	MaxTokensOption  OptionType = "max_tokens"
-	APITimeoutOption OptionType = "api_timeout"
-)
+// providerCredentials are the API keys of the providers, used before the legacy LLM_API_KEY
+var providerCredentials = map[string]common.Credential{
+	ProviderOpenAI:    common.CredentialOpenAI,
+	ProviderAnthropic: common.CredentialAnthropic,
+}
 
-// Option represents a generic configuration option for any LLM provider
-type Option struct {
-	Type  OptionType
-	Value any
+// quickModels are the fast models of the providers used for quick reviews
+var quickModels = map[string]string{
+	ProviderOpenAI:    "gpt-4.1-mini",
+	ProviderAnthropic: "claude-3-haiku",
 }
 
-// WithModel creates an option to set the model name
-func WithModel(model string) Option {
-	return Option{
-		Type:  ModelNameOption,
-		Value: model,
+// QuickModel returns the fast model of the provider used for quick reviews
+func QuickModel(providerName string) string {
+	return quickModels[providerName]
+}
+
+// secretsFreeTools are the only tools available in secrets-free mode, none of them share more than the diff
+var secretsFreeTools = map[string]bool{
+	"get_git_diff":       true,
+	"post_summary":       true,
+	"post_line_feedback": true,
+}
+
+// toolNames are the tools of the LLM, their roles can be overridden in the settings
+var toolNames = []string{
+	"list_directory",
+	"get_git_diff",
+	"read_file",
+	"search_codebase",
+	"get_git_blame",
+	"get_pull_request_details",
+	"get_release_notes",
+	"search_history",
+	"get_recent_errors",
+	"run_command",
+	"read_external_repo_file",
+	"ask_clarification_questions",
+	"post_summary",
+	"post_line_feedback",
+}
+
+// defaultToolRole returns the role of the tool if not overridden, the summary is posted at the end of the review
+func defaultToolRole(name string) string {
+	if name == "post_summary" {
+		return common.ToolRoleFinalizer
 	}
+	return common.ToolRoleHelper
 }
 
-// WithMaxTokens creates an option to set the max tokens
-func WithMaxTokens(maxTokens int) Option {
-	return Option{
-		Type:  MaxTokensOption,
-		Value: maxTokens,
+// ValidateCustomTools checks the custom tools, their names cannot clash with the built-in tools or each other
+func ValidateCustomTools(tools []common.CustomTool) error {
+	names := map[string]bool{}
+	for _, tool := range tools {
+		if err := tool.Validate(); err != nil {
+			return err
+		}
+		if slices.Contains(toolNames, tool.Name) {
+			return fmt.Errorf("custom tool %s clashes with a built-in tool", tool.Name)
+		}
+		if names[tool.Name] {
+			return fmt.Errorf("custom tool %s is defined more than once", tool.Name)
+		}
+		names[tool.Name] = true
 	}
+	return nil
 }
 
-// WithAPITimeout creates an option to set the API timeout in seconds
-func WithAPITimeout(timeout int) Option {
-	return Option{
-		Type:  APITimeoutOption,
-		Value: timeout,
+// ValidateToolRoles checks the overridden tool roles of a command, the custom tools can be given roles as well
+func ValidateToolRoles(roles common.ToolRoles, customTools []common.CustomTool) error {
+	available := slices.Clone(toolNames)
+	for _, tool := range customTools {
+		available = append(available, tool.Name)
+	}
+
+	initializers := 0
+	for name, role := range roles {
+		if !slices.Contains(available, name) {
+			return fmt.Errorf("unknown tool %s, available tools: %s", name, strings.Join(available, ", "))
+		}
+		switch role {
+		case common.ToolRoleInitializer:
+			initializers++
+		case common.ToolRoleHelper, common.ToolRoleFinalizer, common.ToolRoleDisabled:
+		default:
+			return fmt.Errorf("invalid role %s of %s, must be %s, %s, %s or %s", role, name,
+				common.ToolRoleInitializer, common.ToolRoleHelper, common.ToolRoleFinalizer, common.ToolRoleDisabled)
+		}
 	}
+	if initializers > 1 {
+		return fmt.Errorf("only one tool can be the %s, got %d", common.ToolRoleInitializer, initializers)
+	}
+
+	for _, name := range available {
+		role, ok := roles[name]
+		if !ok {
+			role = defaultToolRole(name)
+		}
+		if role == common.ToolRoleFinalizer {
+			return nil
+		}
+	}
+	return fmt.Errorf("at least one tool must be the %s", common.ToolRoleFinalizer)
 }
 
 // Request represents the data needed to generate a prompt for the LLM
@@ -76,29 +146,67 @@ type Tools struct {
 type LLM interface {
 	// Prompt sends a request to the language model and returns its response
 	Prompt(req Request) Response
+	// Complete sends a single request without tools and returns the text response
+	Complete(req Request) Response
+	// Continue sends the conversation of a saved session without running tools and returns the text response
+	Continue(messages []common.SessionMessage) Response
 	SetGitProvider(gitProvider *review.Reviewer)
 	SetSettings(settings *common.Settings)
+	// SetSummarySections sets the summary sections computed by the plugin, merged into the summary posted by the LLM
+	SetSummarySections(sections common.Summary)
 	GetLineFeedback() []common.LineLevel
+	// CheckAuth validates the API key against the provider API
+	CheckAuth() error
 }
 
-func getAPIKey() (string, error) {
-	apiKey := os.Getenv("LLM_API_KEY")
-	if apiKey == "" {
-		errMsg := "LLM_API_KEY environment variable is not set"
-		logger.Error(errMsg)
-		return "", errors.New(errMsg)
+// APIKeyCredential returns the credential of the API key of the provider: its own key, e.g. OPENAI_API_KEY,
+// or the legacy LLM_API_KEY if the provider has no key set
+func APIKeyCredential(providerName string) common.Credential {
+	if credential, ok := providerCredentials[providerName]; ok {
+		if _, _, err := credential.Resolve(); err == nil {
+			return credential
+		}
+	}
+	return common.CredentialLLM
+}
+
+// getAPIKey resolves the API key of the provider, falling back to the legacy LLM_API_KEY
+func getAPIKey(providerName string) (string, error) {
+	credential := APIKeyCredential(providerName)
+	apiKey, source, err := credential.Resolve()
+	if err != nil {
+		if providerCredential, ok := providerCredentials[providerName]; ok {
+			err = fmt.Errorf("%w, or the %s environment variable of the provider", err, providerCredential.Env)
+		}
+		logger.Error(err.Error())
+		return "", err
 	}
-	logger.Debug("Successfully retrieved LLM API key")
+	logger.Debugf("Successfully retrieved %s from %s", credential.Name, source)
+	return apiKey, nil
+}
+
+// getAPIKeyFromEnv resolves the API key of the environment variable, falling back to the other credential sources
+func getAPIKeyFromEnv(env string) (string, error) {
+	credential := common.Credential{Name: env, Env: env}
+	for _, known := range append([]common.Credential{common.CredentialLLM}, slices.Collect(maps.Values(providerCredentials))...) {
+		if known.Env == env {
+			credential = known
+		}
+	}
+
+	apiKey, source, err := credential.Resolve()
+	if err != nil {
+		logger.Error(err.Error())
+		return "", err
+	}
+	logger.Debugf("Successfully retrieved %s from %s", credential.Name, source)
 	return apiKey, nil
 }
 
 func NewLLM(providerName, modelName string, opts ...Option) (LLM, error) {
 	logger.Infof("Creating new LLM client with provider: %s, model: %s", providerName, modelName)
 
-	var llmClient LLM
-	var err error
-
-	apiKey, err := getAPIKey()
+	apiKey, err := getAPIKey(providerName)
 	if err != nil {
 		logger.Errorf("Failed to get API key: %v", err)
 		return nil, err
@@ -110,6 +218,25 @@ func NewLLM(providerName, modelName string, opts ...Option) (LLM, error) {
 		WithAPITimeout(60),
 	}
 	options = append(options, opts...)
+
+	llmClient, err := newClient(providerName, apiKey, options)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Successfully created LLM client with provider: %s, model: %s", providerName, modelName)
+
+	if config, _ := newConfig(Config{}, options...); len(config.Fallbacks) > 0 {
+		logger.Infof("Falling back to %d models on failure", len(config.Fallbacks))
+		return newFallbackLLM(llmClient, modelName, config.Fallbacks, options), nil
+	}
+	return llmClient, nil
+}
+
+// newClient creates the client of the provider
+func newClient(providerName, apiKey string, options []Option) (LLM, error) {
+	var llmClient LLM
+	var err error
+
 	switch providerName {
 	case ProviderOpenAI:
 		logger.Debug("Initializing OpenAI client")
@@ -123,9 +250,7 @@ func NewLLM(providerName, modelName string, opts ...Option) (LLM, error) {
 		err = errors.New(errMsg)
 	}
 
-	if err == nil {
-		logger.Infof("Successfully created LLM client with provider: %s, model: %s", providerName, modelName)
-	} else {
+	if err != nil {
 		logger.Errorf("Failed to create LLM client: %v", err)
 	}
 
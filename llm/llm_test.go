@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+func TestAPIKeyCredential(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("LLM_API_KEY", "legacy-key")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "anthropic-key")
+
+	// Providers without their own key use the legacy key
+	if credential := APIKeyCredential(ProviderOpenAI); credential != common.CredentialLLM {
+		t.Errorf("Expected the legacy credential for OpenAI, got %s", credential.Env)
+	}
+	if credential := APIKeyCredential(ProviderAnthropic); credential != common.CredentialAnthropic {
+		t.Errorf("Expected the Anthropic credential, got %s", credential.Env)
+	}
+
+	apiKey, err := getAPIKey(ProviderAnthropic)
+	if err != nil || apiKey != "anthropic-key" {
+		t.Errorf("Expected the Anthropic key, got %q (%v)", apiKey, err)
+	}
+	apiKey, err = getAPIKey(ProviderOpenAI)
+	if err != nil || apiKey != "legacy-key" {
+		t.Errorf("Expected the legacy key, got %q (%v)", apiKey, err)
+	}
+}
+
+func TestReadExternalRepoFilePathTraversal(t *testing.T) {
+	model := &OpenAIModel{Settings: &common.Settings{ExternalRepos: []string{"my-org/api"}}}
+
+	for _, path := range []string{"../secrets.yml", "docs/../../other/repo", "/etc/passwd", ".."} {
+		_, err := model.processReadExternalRepoFileToolCall(`{"repository": "my-org/api", "path": "` + path + `"}`)
+		if err == nil || !strings.Contains(err.Error(), "relative to the repository root") {
+			t.Errorf("Expected %s to be rejected, got %v", path, err)
+		}
+	}
+
+	// Paths inside the repository pass the check, and fail on the missing provider
+	if _, err := model.processReadExternalRepoFileToolCall(`{"repository": "my-org/api", "path": "docs/../api.yml"}`); err == nil || !strings.Contains(err.Error(), "git provider") {
+		t.Errorf("Expected the path inside the repository to be allowed, got %v", err)
+	}
+}
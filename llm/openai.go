@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/agent"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
@@ -16,18 +18,8 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
-// Custom type for context keys to avoid string collisions
-type contextKey string
-
-const (
-	emptyContentKey  string     = "emptyContent"
-	toolCallDepthKey contextKey = "toolCallDepth"
-	messagesKey      contextKey = "messages"
-	maxToolCallDepth int        = 10
-	ToolUseRequired  string     = "required"
-	ToolUseAuto      string     = "auto"
-	ToolUseDisabled  string     = "none"
-)
+// maxToolCallDepth is the number of requests of a review, the finalizer tools are forced in the last one
+const maxToolCallDepth = 10
 
 // OpenAIModel implements the LLM interface using OpenAI's API
 type OpenAIModel struct {
@@ -35,9 +27,16 @@ type OpenAIModel struct {
 	modelName    string
 	maxTokens    int
 	apiTimeout   int // in seconds
+	temperature  *float64
+	topP         *float64
+	effort       string // Reasoning effort of reasoning models
+	toolRoles    common.ToolRoles
+	customTools  []common.CustomTool
+	mcpTools     []common.MCPTool
 	GitProvider  *review.Reviewer
 	Settings     *common.Settings
 	LineFeedback []common.LineLevel
+	Sections     common.Summary
 }
 
 // NewOpenAI creates a new OpenAI client
@@ -55,37 +54,97 @@ func NewOpenAI(apiKey string, opts ...Option) (*OpenAIModel, error) {
 	config := openai.DefaultConfig(apiKey)
 	config.HTTPClient = retryClient.StandardClient()
 
+	options, err := newConfig(Config{
+		Model:      "gpt-4.1", // Default model
+		MaxTokens:  4000,      // Default max tokens
+		APITimeout: 30,        // Default timeout in seconds
+	}, opts...)
+	if err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
 	model := &OpenAIModel{
-		client:     openai.NewClientWithConfig(config),
-		modelName:  "gpt-4.1", // Default model
-		maxTokens:  4000,      // Default max tokens
-		apiTimeout: 30,        // Default timeout in seconds
+		client:      openai.NewClientWithConfig(config),
+		modelName:   options.Model,
+		maxTokens:   options.MaxTokens,
+		apiTimeout:  options.APITimeout,
+		temperature: options.Temperature,
+		topP:        options.TopP,
+		effort:      options.ReasoningEffort,
+		toolRoles:   options.ToolRoles,
+		customTools: options.CustomTools,
+		mcpTools:    options.MCPTools,
 	}
 
 	logger.Debugf("OpenAI client initialized with model: %s, max tokens: %d, timeout: %d seconds",
 		model.modelName, model.maxTokens, model.apiTimeout)
 
-	// Apply options
-	for _, opt := range opts {
-		switch opt.Type {
-		case ModelNameOption:
-			if modelName, ok := opt.Value.(string); ok {
-				model.modelName = modelName
-			}
-		case MaxTokensOption:
-			if maxTokens, ok := opt.Value.(int); ok {
-				model.maxTokens = maxTokens
-			}
-		case APITimeoutOption:
-			if timeout, ok := opt.Value.(int); ok {
-				model.apiTimeout = timeout
-			}
-		}
+	if err := model.validateOptions(options.MaxThinkingTokens); err != nil {
+		logger.Error(err.Error())
+		return nil, err
 	}
 
 	return model, nil
 }
 
+// isReasoningModel returns true for the o-series and GPT-5 models, which take a reasoning effort instead of sampling parameters
+func (o *OpenAIModel) isReasoningModel() bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(o.modelName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOptions checks that the model supports the sampling and reasoning options
+func (o *OpenAIModel) validateOptions(thinkingTokens int) error {
+	if thinkingTokens > 0 {
+		return fmt.Errorf("max_thinking_tokens is not supported by OpenAI models, use reasoning_effort with a reasoning model")
+	}
+	if o.temperature != nil && (*o.temperature < 0 || *o.temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2 for OpenAI models, got %g", *o.temperature)
+	}
+	if o.topP != nil && (*o.topP < 0 || *o.topP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1, got %g", *o.topP)
+	}
+
+	if o.isReasoningModel() {
+		if o.temperature != nil || o.topP != nil {
+			return fmt.Errorf("temperature and top_p are not supported by the reasoning model %s, use reasoning_effort", o.modelName)
+		}
+	} else if o.effort != "" {
+		return fmt.Errorf("reasoning_effort is only supported by reasoning models (o-series, gpt-5), not by %s", o.modelName)
+	}
+	switch o.effort {
+	case "", ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh:
+		return nil
+	}
+	return fmt.Errorf("reasoning_effort must be low, medium or high, got %s", o.effort)
+}
+
+// applyModelOptions sets the sampling parameters of the request, or the reasoning effort for reasoning models
+func (o *OpenAIModel) applyModelOptions(req *openai.ChatCompletionRequest) {
+	if o.isReasoningModel() {
+		// Reasoning models reject sampling parameters and the max_tokens field
+		req.Temperature = 0
+		req.TopP = 0
+		req.MaxCompletionTokens = req.MaxTokens
+		req.MaxTokens = 0
+		req.ReasoningEffort = o.effort
+		return
+	}
+
+	// Zero values are omitted from the request, the smallest float is sent to ask for deterministic sampling
+	if o.temperature != nil {
+		req.Temperature = max(float32(*o.temperature), math.SmallestNonzeroFloat32)
+	}
+	if o.topP != nil {
+		req.TopP = max(float32(*o.topP), math.SmallestNonzeroFloat32)
+	}
+}
+
 func (o *OpenAIModel) SetGitProvider(gitProvider *review.Reviewer) {
 	o.GitProvider = gitProvider
 }
@@ -94,193 +153,211 @@ func (o *OpenAIModel) SetSettings(settings *common.Settings) {
 	o.Settings = settings
 }
 
-func (o *OpenAIModel) promptWithContext(ctx context.Context, req Request, toolMessages []openai.ChatCompletionMessage, toolChoice string) Response {
-	// Create base messages with system and user prompts
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: req.SystemPrompt,
-		},
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: req.UserPrompt,
-		},
-	}
+func (o *OpenAIModel) SetSummarySections(sections common.Summary) {
+	o.Sections = sections
+}
 
-	// Add any tool messages from previous calls to maintain context
-	if len(toolMessages) > 0 {
-		messages = append(messages, toolMessages...)
-	}
+// Prompt runs the review agent, the model calls the review tools until it posts the review with the finalizer tools
+func (o *OpenAIModel) Prompt(req Request) Response {
+	o.LineFeedback = []common.LineLevel{}
 
-	// Create and send the completion request
-	forceSummary := false
-	depth, ok := ctx.Value(toolCallDepthKey).(int)
-	if !ok {
-		depth = 1
-	}
-	if depth == maxToolCallDepth {
-		logger.Warn("Reaching maximum tool call recursion depth, forcing summary")
-		forceSummary = true
-		toolChoice = ToolUseRequired
-	}
-	if depth > maxToolCallDepth {
-		logger.Warn("Maximum tool call recursion depth reached, stopping further tool calls")
-		toolChoice = ToolUseDisabled
+	runner := agent.NewRunner(o, reviewTools{model: o}, agent.Budget{MaxSteps: maxToolCallDepth, StepTimeout: o.apiTimeout})
+	result, err := runner.Run(agent.Goal{SystemPrompt: req.SystemPrompt, UserPrompt: req.UserPrompt})
+	if err != nil {
+		return Response{Error: err}
 	}
+	return Response{Content: result.Content, ToolCalls: result.ToolCalls}
+}
 
-	chatReq := o.createChatCompletionRequest(messages, toolChoice, forceSummary)
-
+// Send sends a step of the agent to OpenAI and returns the reply with its tool calls
+func (o *OpenAIModel) Send(ctx context.Context, step agent.Step) (common.SessionMessage, error) {
+	chatReq := o.createChatCompletionRequest(fromSessionMessages(step.Messages), step.Tools, step.Choice)
 	logger.Infof("Sending request to OpenAI with model %s, max tokens %d, tools enabled: %v",
 		o.modelName, o.maxTokens, len(chatReq.Tools) > 0)
-	logger.Debug("System prompt: " + req.SystemPrompt)
-	logger.Debug("User prompt: " + req.UserPrompt)
 
 	resp, err := o.client.CreateChatCompletion(ctx, chatReq)
 	if err != nil {
-		return o.handleAPIError(fmt.Sprintf("failed to create chat completion: %v", err), nil)
+		return common.SessionMessage{}, apiError(fmt.Sprintf("failed to create chat completion: %v", err), openAIError(err))
 	}
+	common.Report().AddTokens(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 
 	if len(resp.Choices) == 0 {
-		return o.handleAPIError("OpenAI response contained no choices", nil)
+		return common.SessionMessage{}, apiError("OpenAI response contained no choices", nil)
 	}
+	return toSessionMessages([]openai.ChatCompletionMessage{resp.Choices[0].Message})[0], nil
+}
 
-	// Check for tool calls in the response and handle them if present
-	if len(resp.Choices[0].Message.ToolCalls) > 0 {
-		logger.Debug("Tool call detected in response")
-		return o.handleToolCalls(ctx, resp, req)
-	}
+// CheckAuth validates the API key by listing the available models
+func (o *OpenAIModel) CheckAuth() error {
+	ctx, cancel := common.RunTimeoutContext(o.apiTimeout)
+	defer cancel()
 
-	// Return the standard response
-	responseContent := resp.Choices[0].Message.Content
-	if responseContent == "" {
-		responseContent = emptyContentKey
+	if _, err := o.client.ListModels(ctx); err != nil {
+		return openAIError(err)
 	}
+	return nil
+}
 
-	return Response{
-		Content: responseContent,
+// Complete sends a single request without tools to OpenAI and returns the text response
+func (o *OpenAIModel) Complete(req Request) Response {
+	chatReq := openai.ChatCompletionRequest{
+		Model: o.modelName,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: req.SystemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: req.UserPrompt},
+		},
+		MaxTokens:   o.maxTokens,
+		Temperature: 0,
 	}
+	o.applyModelOptions(&chatReq)
+
+	return o.complete(chatReq)
 }
 
-// Prompt sends a request to OpenAI and returns the response
-func (o *OpenAIModel) Prompt(req Request) Response {
-	// Create context with timeout and initialize it with empty message history
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.apiTimeout)*time.Second)
-	defer cancel()
+// Continue sends the conversation of a saved session with a follow-up message, without running any tools
+func (o *OpenAIModel) Continue(messages []common.SessionMessage) Response {
+	// The tools are listed for the tool calls of the conversation, but can't be called
+	chatReq := o.createChatCompletionRequest(fromSessionMessages(messages), reviewTools{model: o}.Tools(false), agent.ToolChoice{Mode: agent.ChoiceNone})
+	return o.complete(chatReq)
+}
 
-	// Initialize with empty message history and depth 1
-	ctx = context.WithValue(ctx, messagesKey, []openai.ChatCompletionMessage{})
-	ctx = context.WithValue(ctx, toolCallDepthKey, 1)
+// complete sends the request and returns the text response
+func (o *OpenAIModel) complete(chatReq openai.ChatCompletionRequest) Response {
+	ctx, cancel := common.RunTimeoutContext(o.apiTimeout)
+	defer cancel()
 
-	o.LineFeedback = []common.LineLevel{}
+	resp, err := o.client.CreateChatCompletion(ctx, chatReq)
+	if err != nil {
+		return Response{Error: apiError(fmt.Sprintf("failed to create chat completion: %v", err), openAIError(err))}
+	}
+	common.Report().AddTokens(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 
-	return o.promptWithContext(ctx, req, nil, ToolUseRequired)
+	if len(resp.Choices) == 0 {
+		return Response{Error: apiError("OpenAI response contained no choices", nil)}
+	}
+	return Response{Content: resp.Choices[0].Message.Content}
 }
 
 func (o *OpenAIModel) GetLineFeedback() []common.LineLevel {
 	return o.LineFeedback
 }
 
-// handleToolCalls processes any tool calls in the response and sends follow-up requests if needed
-func (o *OpenAIModel) handleToolCalls(ctx context.Context, resp openai.ChatCompletionResponse, originalReq Request) Response {
-	// Get current recursion depth from context or start at 1
-	depth, ok := ctx.Value(toolCallDepthKey).(int)
-	if !ok {
-		depth = 1
-	}
-
-	// Get existing message history from context if available
-	var existingMessages []openai.ChatCompletionMessage
-	if ctxMessages, ok := ctx.Value(messagesKey).([]openai.ChatCompletionMessage); ok && len(ctxMessages) > 0 {
-		existingMessages = ctxMessages
-		logger.Debugf("Retrieved %d existing messages from context", len(existingMessages))
-	}
-
-	// Log and process all tool calls
-	toolCalls := resp.Choices[0].Message.ToolCalls
+// reviewTools is the toolset of the review agent, the tools read the repository and post the review
+type reviewTools struct {
+	model *OpenAIModel
+}
 
-	// First, collect all new messages from this tool call sequence
-	// Ensure content is never null as OpenAI API requires a string value
-	messageContent := resp.Choices[0].Message.Content
-	if messageContent == "" {
-		messageContent = emptyContentKey
+// Tools returns the allowed tools, only the finalizer ones when finalize is set
+func (t reviewTools) Tools(finalize bool) []agent.Tool {
+	var tools []agent.Tool
+	for _, tool := range t.model.getTools(finalize) {
+		tools = append(tools, agent.Tool{Name: tool.Function.Name, Description: tool.Function.Description, Parameters: tool.Function.Parameters})
 	}
+	return tools
+}
 
-	newMessages := []openai.ChatCompletionMessage{
-		{
-			Role:      openai.ChatMessageRoleAssistant,
-			Content:   messageContent,
-			ToolCalls: resp.Choices[0].Message.ToolCalls,
-		},
+// Initializer returns the allowed tool of the initializer role, forced in the first request
+func (t reviewTools) Initializer() string {
+	for name, role := range t.model.toolRoles {
+		if role == common.ToolRoleInitializer && t.model.isToolAllowed(name) {
+			return name
+		}
 	}
+	return ""
+}
 
-	// Process each tool call and add the results
-	for _, tool := range toolCalls {
-		var result string
-		var err error
+// Call dispatches the tool call to its handler
+func (t reviewTools) Call(name, arguments string) (string, error) {
+	return t.model.executeToolCall(name, arguments)
+}
 
-		// Dispatch to appropriate tool handler
-		switch tool.Function.Name {
-		case "list_directory":
-			result, err = o.processListDirToolCall(tool.Function.Arguments)
-		case "get_git_diff":
-			result, err = o.processGitDiffToolCall(tool.Function.Arguments)
-		case "read_file":
-			result, err = o.processReadFileToolCall(tool.Function.Arguments)
-		case "search_codebase":
-			result, err = o.processSearchCodebaseToolCall(tool.Function.Arguments)
-		case "get_git_blame":
-			result, err = o.processGitBlameToolCall(tool.Function.Arguments)
-		case "get_pull_request_details":
-			result, err = o.processGetPullRequestDetailsToolCall(tool.Function.Arguments)
-		case "post_summary":
-			result, err = o.processPostSummaryToolCall(tool.Function.Arguments)
-		case "post_line_feedback":
-			result, err = o.processPostLineFeedbackToolCall(tool.Function.Arguments)
-		default:
-			err = fmt.Errorf("unknown tool: %s", tool.Function.Name)
+// executeToolCall dispatches the tool call to its handler
+func (o *OpenAIModel) executeToolCall(name, arguments string) (string, error) {
+	if !o.isToolAllowed(name) {
+		return "", fmt.Errorf("tool %s is disabled by the settings", name)
+	}
+
+	// Dispatch to appropriate tool handler
+	switch name {
+	case "list_directory":
+		return o.processListDirToolCall(arguments)
+	case "get_git_diff":
+		return o.processGitDiffToolCall(arguments)
+	case "read_file":
+		return o.processReadFileToolCall(arguments)
+	case "search_codebase":
+		return o.processSearchCodebaseToolCall(arguments)
+	case "get_git_blame":
+		return o.processGitBlameToolCall(arguments)
+	case "get_pull_request_details":
+		return o.processGetPullRequestDetailsToolCall(arguments)
+	case "get_release_notes":
+		return o.processGetReleaseNotesToolCall(arguments)
+	case "search_history":
+		return o.processSearchHistoryToolCall(arguments)
+	case "get_recent_errors":
+		return o.processGetRecentErrorsToolCall(arguments)
+	case "run_command":
+		return o.processRunCommandToolCall(arguments)
+	case "read_external_repo_file":
+		return o.processReadExternalRepoFileToolCall(arguments)
+	case "ask_clarification_questions":
+		return o.processAskClarificationQuestionsToolCall(arguments)
+	case "post_summary":
+		return o.processPostSummaryToolCall(arguments)
+	case "post_line_feedback":
+		return o.processPostLineFeedbackToolCall(arguments)
+	default:
+		for _, custom := range o.customTools {
+			if custom.Name == name {
+				logger.Infof("🤖 Running custom tool %s", custom.Name)
+				return custom.Run(arguments)
+			}
 		}
-
-		// Add the tool response message
-		newMessages = append(newMessages, createToolResponse(tool.ID, result, err))
+		for _, mcpTool := range o.mcpTools {
+			if mcpTool.Name == name {
+				logger.Infof("🤖 Calling MCP tool %s", mcpTool.Name)
+				return mcpTool.Call(arguments)
+			}
+		}
+		return "", fmt.Errorf("unknown tool: %s", name)
 	}
+}
 
-	// Combine existing messages with new ones to maintain full conversation history
-	allMessages := append(existingMessages, newMessages...)
-
-	// Create and send follow-up request with tool results
-	toolChoice := ToolUseAuto
-
-	// Create new context with incremented depth and message history
-	newCtx, cancel := context.WithTimeout(context.Background(), time.Duration(o.apiTimeout)*time.Second)
-	defer cancel()
-	newCtx = context.WithValue(newCtx, toolCallDepthKey, depth+1)
-	newCtx = context.WithValue(newCtx, messagesKey, allMessages)
-
-	// Log conversation state for debugging
-	logger.Debugf("Sending next prompt with %d total accumulated messages at depth %d", len(allMessages), depth+1)
-
-	// Get response from the next recursive prompt with full conversation history
-	nextResponse := o.promptWithContext(newCtx, originalReq, allMessages, toolChoice)
-
-	// If there was an error in the next call, return it directly
-	if nextResponse.Error != nil {
-		return nextResponse
+// getCelebration returns the configured celebration section of the summary
+func (o *OpenAIModel) getCelebration() common.Celebration {
+	if o.Settings == nil {
+		return common.WithDefaultSettings().GetCelebration()
 	}
+	return o.Settings.GetCelebration()
+}
 
-	// Return the combined response including all tool calls and history
-	responseContent := nextResponse.Content
-	if responseContent == "" {
-		responseContent = emptyContentKey
+// getPostSummaryRequired returns the required parameters of post_summary
+func (o *OpenAIModel) getPostSummaryRequired() []string {
+	required := []string{"repo_owner", "repo_name", "pr_number", "summary", "walkthrough"}
+	if o.getCelebration().Enabled() {
+		required = append(required, "celebration")
 	}
+	return required
+}
 
-	return Response{
-		Content:   responseContent,
-		ToolCalls: resp.Choices[0].Message.ToolCalls,
+// getCategoryDescription lists the finding categories, including the ones of the enabled rule packs
+func (o *OpenAIModel) getCategoryDescription() string {
+	categories := "bug, refactor, improvement, documentation, nitpick, test coverage, security"
+	if o.Settings != nil && o.Settings.HasMobileRulePack() {
+		categories += ", " + common.CategoryMobilePerf + ", " + common.CategoryStoreCompliance
 	}
+	if o.Settings != nil && (len(o.Settings.HotPaths.Paths) > 0 || len(o.Settings.HotPaths.Symbols) > 0) {
+		categories += ", " + common.CategoryPerformance
+	}
+	if o.Settings != nil && o.Settings.CopyReview.Enabled {
+		categories += ", " + common.CategoryCopy
+	}
+	return "The category of the feedback from: " + categories + "."
 }
 
-// getTools returns the list of available tools
-func (o *OpenAIModel) getTools(forceSummary bool) []openai.Tool {
+// getTools returns the list of available tools, only the finalizer tools when finalizing
+func (o *OpenAIModel) getTools(finalize bool) []openai.Tool {
 	// List directory
 	ListDirTool := openai.Tool{
 		Type: openai.ToolTypeFunction,
@@ -311,7 +388,7 @@ func (o *OpenAIModel) getTools(forceSummary bool) []openai.Tool {
 		Type: openai.ToolTypeFunction,
 		Function: &openai.FunctionDefinition{
 			Name:        "get_git_diff",
-			Description: "Gets the diff between two git references (commits, branches, or tags) showing code changes",
+			Description: "Gets the diff between two git references (commits, branches, or tags) showing code changes. In large diffs, the mechanical changes like generated files, reordered imports and renames are replaced with a line starting with [condensed], read the file if you need them",
 			Parameters: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -493,6 +570,181 @@ func (o *OpenAIModel) getTools(forceSummary bool) []openai.Tool {
 		},
 	}
 
+	getReleaseNotesTool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "get_release_notes",
+			Description: "Fetches the upstream release notes of a dependency hosted on GitHub, published after from_version up to and including to_version",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository": map[string]interface{}{
+						"type":        "string",
+						"description": "The GitHub repository of the dependency in the format 'owner/repo' (e.g., 'spf13/cobra')",
+					},
+					"from_version": map[string]interface{}{
+						"type":        "string",
+						"description": "The version the dependency is updated from",
+					},
+					"to_version": map[string]interface{}{
+						"type":        "string",
+						"description": "The version the dependency is updated to",
+					},
+				},
+				"required": []string{"repository", "from_version", "to_version"},
+				"examples": []map[string]interface{}{
+					{
+						"repository":   "spf13/cobra",
+						"from_version": "v1.8.0",
+						"to_version":   "v1.9.1",
+					},
+				},
+			},
+		},
+	}
+
+	searchHistoryTool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "search_history",
+			Description: "Searches the merged pull requests and the reverted commits of the repository for keywords of the change, to find out if a similar approach was tried and reverted before",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repo_owner": map[string]interface{}{
+						"type":        "string",
+						"description": "The owner of the repository (e.g., 'bitrise-io')",
+					},
+					"repo_name": map[string]interface{}{
+						"type":        "string",
+						"description": "The name of the repository (e.g., 'bitrise-plugins-ai-reviewer')",
+					},
+					"keywords": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Distinctive keywords of the change, like the names of the changed components or the approach, at most 5",
+					},
+				},
+				"required": []string{"repo_owner", "repo_name", "keywords"},
+				"examples": []map[string]interface{}{
+					{
+						"repo_owner": "bitrise-io",
+						"repo_name":  "bitrise-plugins-ai-reviewer",
+						"keywords":   []string{"response cache", "retry backoff"},
+					},
+				},
+			},
+		},
+	}
+
+	getRecentErrorsTool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "get_recent_errors",
+			Description: "Lists the unresolved production errors reported to Sentry with a stack frame in the given files, most frequent first",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"files": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "The changed files to look up, relative to the repository root",
+					},
+				},
+				"required": []string{"files"},
+				"examples": []map[string]interface{}{
+					{
+						"files": []string{"App/Login/LoginViewController.swift"},
+					},
+				},
+			},
+		},
+	}
+
+	runCommandTool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "run_command",
+			Description: "Runs a whitelisted validation command on a path of the repository and returns its output",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"command": map[string]interface{}{
+						"type":        "string",
+						"enum":        common.AllowedCommands(),
+						"description": "The command to run: 'terraform validate' on a Terraform module directory, or 'kubeval' on a Kubernetes manifest file",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The path relative to the repository root to run the command on",
+					},
+				},
+				"required": []string{"command", "path"},
+				"examples": []map[string]interface{}{
+					{
+						"command": "terraform validate",
+						"path":    "infra/modules/storage",
+					},
+				},
+			},
+		},
+	}
+
+	readExternalRepoFileTool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "read_external_repo_file",
+			Description: "Reads a file from the default branch of another repository of the organization, e.g. to check the consumers of a changed API contract",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository": map[string]interface{}{
+						"type":        "string",
+						"enum":        o.getExternalRepos(),
+						"description": "The repository in the format 'owner/repo', only the listed repositories can be read",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The path of the file relative to the repository root",
+					},
+				},
+				"required": []string{"repository", "path"},
+			},
+		},
+	}
+
+	askClarificationQuestionsTool := openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "ask_clarification_questions",
+			Description: "Posts questions to the author of the pull request about ambiguous changes, instead of guessing their intent. The answers are provided on the next review",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repo_owner": map[string]interface{}{
+						"type":        "string",
+						"description": "The owner of the repository (e.g., 'bitrise-io')",
+					},
+					"repo_name": map[string]interface{}{
+						"type":        "string",
+						"description": "The name of the repository (e.g., 'bitrise-plugins-ai-reviewer')",
+					},
+					"pr_number": map[string]interface{}{
+						"type":        "integer",
+						"description": "The pull request number to ask the questions on",
+					},
+					"questions": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"maxItems":    o.getClarificationQuestionLimit(),
+						"description": "Short, specific questions, e.g. 'Is the retry count change in client.go intentional?'",
+					},
+				},
+				"required": []string{"repo_owner", "repo_name", "pr_number", "questions"},
+			},
+		},
+	}
+
 	postSummaryTool := openai.Tool{
 		Type: openai.ToolTypeFunction,
 		Function: &openai.FunctionDefinition{
@@ -518,23 +770,64 @@ func (o *OpenAIModel) getTools(forceSummary bool) []openai.Tool {
 						"description": "A high-level, to-the-point, short summary of the overall change instead of specific files within 80 words.",
 					},
 					"walkthrough": map[string]interface{}{
-						"type":        "integer",
-						"description": "Files that changed and the change summary separated by ':'. Group files with similar changes together to save space. Separate lines by \n.",
+						"type":        "array",
+						"description": "The files that changed with the summary of their changes. Group files with similar changes together in one row to save space.",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"files": map[string]interface{}{
+									"type":        "string",
+									"description": "The changed files of the row, separated by commas",
+								},
+								"summary": map[string]interface{}{
+									"type":        "string",
+									"description": "The summary of the changes of the files",
+								},
+							},
+							"required": []string{"files", "summary"},
+						},
 					},
-					"haiku": map[string]interface{}{
+					"celebration": map[string]interface{}{
 						"type":        "string",
-						"description": "A whimsical, short haiku to celebrate the changes as 'Bit Bot'. Format the haiku as a quote using the '>' symbol and feel free to use emojis where relevant.",
+						"description": o.getCelebration().GetInstructions(),
+					},
+					"merge_confidence": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional, only for dependency updates: the merge confidence verdict (high, medium or low) followed by a short justification.",
+					},
+					"ci_config_review": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional, only if bitrise.yml or step.yml files changed: the review of the CI configuration changes as a markdown list.",
+					},
+					"performance": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional, only if hot paths changed: the performance review of the hot path changes as a markdown list.",
+					},
+					"feature_flags": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional, only if feature flags were introduced or removed: the review of the flags as a markdown list.",
+					},
+					"release_risk": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional, only if the pull request targets a release branch: the release risk (high, medium or low) followed by the reasons as a markdown list.",
+					},
+					"diagram": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional, only if the changes restructure packages or significantly change call flows: a Mermaid flowchart, classDiagram or sequenceDiagram of the new structure, without a code block, at most 80 lines.",
 					},
 				},
-				"required": []string{"repo_owner", "repo_name", "pr_number", "summary", "walkthrough", "haiku"},
+				"required": o.getPostSummaryRequired(),
 				"examples": []map[string]interface{}{
 					{
-						"repo_owner":  "bitrise-io",
-						"repo_name":   "bitrise-plugins-ai-reviewer",
-						"pr_number":   42,
-						"summary":     "This PR implements a new feature that allows users to filter search results by date.",
-						"walkthrough": "main.go: Implemented search filtering by date\ncmd/root.go: Updated CLI commands to support new filter options",
-						"haiku":       "> New tools in the breeze\n> Codebase whispers, search, blame, fetch—\n> Review magic grows 🌱🤖",
+						"repo_owner": "bitrise-io",
+						"repo_name":  "bitrise-plugins-ai-reviewer",
+						"pr_number":  42,
+						"summary":    "This PR implements a new feature that allows users to filter search results by date.",
+						"walkthrough": []map[string]interface{}{
+							{"files": "main.go", "summary": "Implemented search filtering by date"},
+							{"files": "cmd/root.go", "summary": "Updated CLI commands to support new filter options"},
+						},
+						"celebration": "> New tools in the breeze\n> Codebase whispers, search, blame, fetch—\n> Review magic grows 🌱🤖",
 					},
 				},
 			},
@@ -571,11 +864,25 @@ func (o *OpenAIModel) getTools(forceSummary bool) []openai.Tool {
 					},
 					"category": map[string]interface{}{
 						"type":        "string",
-						"description": "The category of the feedback from: bug, refactor, improvement, documentation, nitpick, test coverage, security.",
+						"description": o.getCategoryDescription(),
+					},
+					"severity": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{common.SeverityHigh, common.SeverityMedium, common.SeverityLow},
+						"description": "Optional severity of the issue, use high for issues that can cause data loss, outages or security incidents.",
+					},
+					"scope": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{common.ScopeLine, common.ScopeFile},
+						"description": "Optional scope of the issue, use file for issues about the whole file (e.g., 'this file should be split') instead of picking an arbitrary line. Defaults to line.",
 					},
 					"line": map[string]interface{}{
 						"type":        "string",
-						"description": "The exact line from the diff hunk that you are commenting on.",
+						"description": "The exact line from the diff hunk that you are commenting on. Leave empty for file scoped issues.",
+					},
+					"topic": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional short identifier of the issue, the same for every location of an issue found in multiple places (e.g., 'unclosed-response-body').",
 					},
 					"prompt": map[string]interface{}{
 						"type":        "string",
@@ -586,7 +893,7 @@ func (o *OpenAIModel) getTools(forceSummary bool) []openai.Tool {
 						"description": "An optional suggestion for how to fix the issue. If provided, it should be a complete code snippet that can be applied directly to the file.",
 					},
 				},
-				"required": []string{"repo_owner", "repo_name", "pr_number", "file", "issue", "category", "line", "prompt"},
+				"required": []string{"repo_owner", "repo_name", "pr_number", "file", "issue", "category", "prompt"},
 				"examples": []map[string]interface{}{
 					{
 						"repo_owner": "bitrise-io",
@@ -604,10 +911,82 @@ func (o *OpenAIModel) getTools(forceSummary bool) []openai.Tool {
 		},
 	}
 
-	if forceSummary {
-		return []openai.Tool{postSummaryTool}
+	allTools := []openai.Tool{ListDirTool, gitDiffTool, readFileTool, searchCodebaseTool, gitBlameTool, getPullRequestDetailsTool, getReleaseNotesTool, searchHistoryTool, getRecentErrorsTool, runCommandTool, readExternalRepoFileTool, askClarificationQuestionsTool, postSummaryTool, postLineFeedbackTool}
+	for _, custom := range o.customTools {
+		allTools = append(allTools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        custom.Name,
+				Description: custom.Description,
+				Parameters:  custom.Schema(),
+			},
+		})
+	}
+	for _, mcpTool := range o.mcpTools {
+		allTools = append(allTools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        mcpTool.Name,
+				Description: mcpTool.Description,
+				Parameters:  mcpTool.InputSchema,
+			},
+		})
+	}
+
+	tools := []openai.Tool{}
+	for _, tool := range allTools {
+		if !o.isToolAllowed(tool.Function.Name) {
+			continue
+		}
+		if finalize && o.toolRole(tool.Function.Name) != common.ToolRoleFinalizer {
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// toolRole returns the role of the tool, overridden by the settings
+func (o *OpenAIModel) toolRole(name string) string {
+	if role, ok := o.toolRoles[name]; ok {
+		return role
+	}
+	return defaultToolRole(name)
+}
+
+// isToolAllowed checks if the tool can be used with the current settings
+func (o *OpenAIModel) isToolAllowed(name string) bool {
+	if o.toolRole(name) == common.ToolRoleDisabled {
+		return false
+	}
+	if o.Settings != nil && o.Settings.SecretsFree {
+		return secretsFreeTools[name]
 	}
-	return []openai.Tool{ListDirTool, gitDiffTool, readFileTool, searchCodebaseTool, gitBlameTool, getPullRequestDetailsTool, postSummaryTool, postLineFeedbackTool}
+	switch name {
+	case "read_external_repo_file":
+		return len(o.getExternalRepos()) > 0
+	case "ask_clarification_questions":
+		return o.getClarificationQuestionLimit() > 0
+	case "get_recent_errors":
+		return o.Settings != nil && o.Settings.Sentry.Enabled()
+	}
+	return true
+}
+
+// getClarificationQuestionLimit returns the maximum number of questions to the author, 0 if asking questions is disabled
+func (o *OpenAIModel) getClarificationQuestionLimit() int {
+	if o.Settings == nil {
+		return 0
+	}
+	return o.Settings.Reviews.ClarificationQuestions
+}
+
+// getExternalRepos returns the allowlist of the repositories readable by the read_external_repo_file tool
+func (o *OpenAIModel) getExternalRepos() []string {
+	if o.Settings == nil {
+		return nil
+	}
+	return o.Settings.ExternalRepos
 }
 
 func (o *OpenAIModel) processListDirToolCall(argumentsJSON string) (string, error) {
@@ -672,7 +1051,20 @@ func (o *OpenAIModel) processGitDiffToolCall(argumentsJSON string) (string, erro
 		return "No changes found in diff.", nil
 	}
 
-	return output, nil
+	return o.condenseDiff(common.StripAssetDiffs(output)), nil
+}
+
+// condenseDiff replaces the mechanical changes of large diffs with descriptions, saving the tokens of refactors
+func (o *OpenAIModel) condenseDiff(diff string) string {
+	settings := common.WithDefaultSettings()
+	if o.Settings != nil {
+		settings = *o.Settings
+	}
+	condensed, count := common.CondenseDiff(diff, settings.Reviews.CondenseDiffLines)
+	if count > 0 {
+		logger.Infof("Condensed %d mechanical changes of the diff, from about %d to %d tokens", count, common.EstimateTokens(diff), common.EstimateTokens(condensed))
+	}
+	return condensed
 }
 
 // processReadFileToolCall extracts parameters and reads the specified file
@@ -701,6 +1093,11 @@ func (o *OpenAIModel) processReadFileToolCall(argumentsJSON string) (string, err
 		return "", fmt.Errorf("invalid path: %s", args.Path)
 	}
 
+	// Images are binary noise for the LLM, their changes are summarized separately
+	if common.IsImageResource(cleanPath) {
+		return fmt.Sprintf("%s is an image, its contents can't be read.", cleanPath), nil
+	}
+
 	var content string
 	var err error
 
@@ -818,7 +1215,7 @@ func (o *OpenAIModel) processGitBlameToolCall(argumentsJSON string) (string, err
 		return "No blame information found for the specified file and lines.", nil
 	}
 
-	return output, nil
+	return common.Pseudonyms().Blame(output), nil
 }
 
 func (o *OpenAIModel) processGetPullRequestDetailsToolCall(argumentsJSON string) (string, error) {
@@ -850,14 +1247,227 @@ func (o *OpenAIModel) processGetPullRequestDetailsToolCall(argumentsJSON string)
 	return pullRequestDetails.String(), nil
 }
 
+func (o *OpenAIModel) processGetReleaseNotesToolCall(argumentsJSON string) (string, error) {
+	var args struct {
+		Repository  string `json:"repository"`
+		FromVersion string `json:"from_version"`
+		ToVersion   string `json:"to_version"`
+	}
+
+	if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
+		return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+	}
+
+	logger.Infof("🤖 Getting release notes of %s from %s to %s", args.Repository, args.FromVersion, args.ToVersion)
+
+	if args.Repository == "" || args.ToVersion == "" {
+		return "", fmt.Errorf("repository and to_version must be provided")
+	}
+
+	releaseNotes, err := common.FetchReleaseNotes(args.Repository, args.FromVersion, args.ToVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to get release notes: %v", err)
+	}
+
+	return releaseNotes, nil
+}
+
+// maxHistoryKeywords limits the keywords of a history search, the provider search queries have a maximum length
+const maxHistoryKeywords = 5
+
+func (o *OpenAIModel) processSearchHistoryToolCall(argumentsJSON string) (string, error) {
+	var args struct {
+		RepoOwner string   `json:"repo_owner"`
+		RepoName  string   `json:"repo_name"`
+		Keywords  []string `json:"keywords"`
+	}
+
+	if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
+		return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+	}
+
+	logger.Infof("🤖 Searching the history for %s", strings.Join(args.Keywords, ", "))
+
+	if args.RepoOwner == "" || args.RepoName == "" || len(args.Keywords) == 0 {
+		return "", fmt.Errorf("repo_owner, repo_name and keywords must be provided")
+	}
+	if len(args.Keywords) > maxHistoryKeywords {
+		args.Keywords = args.Keywords[:maxHistoryKeywords]
+	}
+
+	return common.CachedHistorySearch(args.Keywords, func() (string, error) {
+		git := git.NewClient(git.NewDefaultRunner("."))
+		var reverts []common.RevertedCommit
+		seen := map[string]bool{}
+		for _, keyword := range args.Keywords {
+			output, err := git.SearchCommitMessages([]string{"revert", keyword}, common.MaxHistoryResults)
+			if err != nil {
+				return "", fmt.Errorf("failed to search the reverted commits: %v", err)
+			}
+			for _, commit := range common.ParseRevertedCommits(output) {
+				if !seen[commit.Hash] && len(reverts) < common.MaxHistoryResults {
+					seen[commit.Hash] = true
+					reverts = append(reverts, commit)
+				}
+			}
+		}
+
+		// The reverted commits are still useful without a code review provider
+		var pullRequests []common.HistoryPullRequest
+		if o.GitProvider != nil {
+			var err error
+			pullRequests, err = (*o.GitProvider).SearchMergedPullRequests(args.RepoOwner, args.RepoName, args.Keywords)
+			if err != nil {
+				return "", fmt.Errorf("failed to search the merged pull requests: %v", err)
+			}
+		}
+
+		return common.Pseudonyms().Text(common.FormatHistory(pullRequests, reverts)), nil
+	})
+}
+
+func (o *OpenAIModel) processGetRecentErrorsToolCall(argumentsJSON string) (string, error) {
+	var args struct {
+		Files []string `json:"files"`
+	}
+
+	if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
+		return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+	}
+
+	logger.Infof("🤖 Getting recent errors of %s", strings.Join(args.Files, ", "))
+
+	if len(args.Files) == 0 {
+		return "", fmt.Errorf("files must be provided")
+	}
+
+	errorsOfFiles, err := common.FetchRecentErrors(o.Settings.Sentry, args.Files)
+	if err != nil {
+		return "", fmt.Errorf("failed to get recent errors: %v", err)
+	}
+
+	return errorsOfFiles, nil
+}
+
+func (o *OpenAIModel) processRunCommandToolCall(argumentsJSON string) (string, error) {
+	var args struct {
+		Command string `json:"command"`
+		Path    string `json:"path"`
+	}
+
+	if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
+		return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+	}
+
+	logger.Infof("🤖 Running %s on %s", args.Command, args.Path)
+
+	if args.Command == "" || args.Path == "" {
+		return "", fmt.Errorf("command and path must be provided")
+	}
+
+	output, err := common.RunCommand(args.Command, args.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to run command: %v", err)
+	}
+
+	return output, nil
+}
+
+func (o *OpenAIModel) processReadExternalRepoFileToolCall(argumentsJSON string) (string, error) {
+	var args struct {
+		Repository string `json:"repository"`
+		Path       string `json:"path"`
+	}
+
+	if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
+		return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+	}
+
+	logger.Infof("🤖 Reading file `%s` of %s", args.Path, args.Repository)
+
+	if args.Repository == "" || args.Path == "" {
+		return "", fmt.Errorf("repository and path must be provided")
+	}
+
+	if o.Settings == nil || !o.Settings.IsExternalRepoAllowed(args.Repository) {
+		return "", fmt.Errorf("repository %s is not on the external repository allowlist", args.Repository)
+	}
+
+	// The path is appended to the API URL of the provider, it must not leave the repository
+	filePath := path.Clean(args.Path)
+	if path.IsAbs(filePath) || filePath == ".." || strings.HasPrefix(filePath, "../") {
+		return "", fmt.Errorf("path must be relative to the repository root")
+	}
+
+	repoOwner, repoName, found := strings.Cut(args.Repository, "/")
+	if !found || repoOwner == "" || repoName == "" {
+		return "", fmt.Errorf("repository must be in the format 'owner/repo'")
+	}
+
+	if o.GitProvider == nil {
+		return "", fmt.Errorf("git provider is not initialized, cannot read files of other repositories")
+	}
+
+	content, err := (*o.GitProvider).GetRepositoryFile(repoOwner, repoName, filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read external repository file: %v", err)
+	}
+
+	return content, nil
+}
+
+func (o *OpenAIModel) processAskClarificationQuestionsToolCall(argumentsJSON string) (string, error) {
+	var args struct {
+		RepoOwner string   `json:"repo_owner"`
+		RepoName  string   `json:"repo_name"`
+		PRNumber  int      `json:"pr_number"`
+		Questions []string `json:"questions"`
+	}
+
+	if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
+		return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+	}
+
+	logger.Infof("🤖 Asking %d clarification questions", len(args.Questions))
+
+	if args.RepoOwner == "" || args.RepoName == "" || args.PRNumber <= 0 {
+		return "", fmt.Errorf("repo_owner, repo_name, and pr_number must be provided")
+	}
+
+	if len(args.Questions) == 0 {
+		return "", fmt.Errorf("questions must be provided")
+	}
+
+	if limit := o.getClarificationQuestionLimit(); len(args.Questions) > limit {
+		return "", fmt.Errorf("at most %d questions can be asked, keep the most important ones", limit)
+	}
+
+	if o.GitProvider == nil {
+		return "", fmt.Errorf("git provider is not initialized, cannot post the questions")
+	}
+
+	body := common.FormatClarificationQuestions((*o.GitProvider).GetProvider(), args.Questions)
+	if err := (*o.GitProvider).PostSummary(args.RepoOwner, args.RepoName, args.PRNumber, common.ClarificationHeader, body); err != nil {
+		return "", fmt.Errorf("failed to post the questions: %v", err)
+	}
+
+	return "Questions posted successfully, don't report findings which depend on the answers", nil
+}
+
 func (o *OpenAIModel) processPostSummaryToolCall(argumentsJSON string) (string, error) {
 	var args struct {
-		RepoOwner   string `json:"repo_owner"`
-		RepoName    string `json:"repo_name"`
-		PRNumber    int    `json:"pr_number"`
-		Summary     string `json:"summary"`
-		Walkthrough string `json:"walkthrough"`
-		Haiku       string `json:"haiku"`
+		RepoOwner       string          `json:"repo_owner"`
+		RepoName        string          `json:"repo_name"`
+		PRNumber        int             `json:"pr_number"`
+		Summary         string          `json:"summary"`
+		Walkthrough     json.RawMessage `json:"walkthrough"`
+		Celebration     string          `json:"celebration"`
+		MergeConfidence string          `json:"merge_confidence,omitempty"`
+		CIConfigReview  string          `json:"ci_config_review,omitempty"`
+		Performance     string          `json:"performance,omitempty"`
+		FeatureFlags    string          `json:"feature_flags,omitempty"`
+		ReleaseRisk     string          `json:"release_risk,omitempty"`
+		Diagram         string          `json:"diagram,omitempty"`
 	}
 
 	if err := json.Unmarshal([]byte(argumentsJSON), &args); err != nil {
@@ -878,35 +1488,46 @@ func (o *OpenAIModel) processPostSummaryToolCall(argumentsJSON string) (string,
 		return "", fmt.Errorf("git provider is not initialized, cannot fetch PR details")
 	}
 
-	walkthrough := make([]common.Walkthrough, 0)
-	for line := range strings.SplitSeq(args.Walkthrough, "\n") {
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			return "", fmt.Errorf("invalid walkthrough format, expected 'file: change summary', got: %s", line)
+	// The diagram is validated before posting, so the summary can be posted again with a fixed diagram
+	var diagram common.Diagram
+	if args.Diagram != "" {
+		var err error
+		if diagram, err = common.ParseDiagram(args.Diagram); err != nil {
+			return "", fmt.Errorf("invalid diagram, fix it or leave it out: %v", err)
 		}
-		filePath := strings.TrimSpace(parts[0])
-		changeSummary := strings.TrimSpace(parts[1])
-
-		walkthrough = append(walkthrough, common.Walkthrough{
-			Files:   filePath,
-			Summary: changeSummary,
-		})
 	}
 
-	summary := common.Summary{
-		Summary:     args.Summary,
-		Walkthrough: walkthrough,
-		Haiku:       args.Haiku,
-	}
+	walkthrough, err := common.ParseWalkthrough(args.Walkthrough)
+	if err != nil {
+		return "", err
+	}
+
+	summary := o.Sections
+	summary.Summary = args.Summary
+	// The files unchanged since the previous summary were left out of the walkthrough, their rows are cached
+	summary.Walkthrough = walkthrough
+	summary = summary.WithCachedWalkthrough()
+	summary.Celebration = args.Celebration
+	summary.MergeConfidence = args.MergeConfidence
+	summary.CIConfigReview = args.CIConfigReview
+	summary.Performance = args.Performance
+	summary.FeatureFlags = args.FeatureFlags
+	summary.Diagram = diagram
+	// The notes of the branch policy, like the checklist state, follow the assessment
+	summary.ReleaseRisk = common.FormatReleaseRisk(args.ReleaseRisk, summary.ReleaseRisk)
+	summary.Stats = common.SummaryStats{
+		FilesChanged: len(summary.Walkthrough),
+		Findings:     len(o.LineFeedback),
+	}
+
+	// Keep the text of the sections whose files did not change since the previous summary
+	summary = summary.ReuseUnchanged()
 
 	headerStr := summary.Header()
 	summaryStr := summary.String((*o.GitProvider).GetProvider(), *o.Settings)
 
-	err := (*o.GitProvider).PostSummary(args.RepoOwner, args.RepoName, args.PRNumber, headerStr, summaryStr)
+	common.Session().SetSummary(headerStr, summaryStr)
+	err = (*o.GitProvider).PostSummary(args.RepoOwner, args.RepoName, args.PRNumber, headerStr, summaryStr)
 	if err != nil {
 		return "", fmt.Errorf("failed to post summary: %v", err)
 	}
@@ -922,7 +1543,10 @@ func (o *OpenAIModel) processPostLineFeedbackToolCall(argumentsJSON string) (str
 		File       string `json:"file"`
 		Issue      string `json:"issue"`
 		Category   string `json:"category"`
+		Severity   string `json:"severity,omitempty"`
+		Scope      string `json:"scope,omitempty"`
 		Line       string `json:"line"`
+		Topic      string `json:"topic,omitempty"`
 		Prompt     string `json:"prompt"`
 		Suggestion string `json:"suggestion,omitempty"`
 	}
@@ -936,15 +1560,26 @@ func (o *OpenAIModel) processPostLineFeedbackToolCall(argumentsJSON string) (str
 		return "", fmt.Errorf("repo_owner, repo_name, and pr_number must be provided")
 	}
 
-	if args.File == "" || args.Line == "" || args.Issue == "" {
-		return "", fmt.Errorf("file, line and issue must be provided")
+	if args.File == "" || args.Issue == "" {
+		return "", fmt.Errorf("file and issue must be provided")
+	}
+
+	if args.Scope == common.ScopeFile {
+		// File comments have no line to replace
+		args.Line = ""
+		args.Suggestion = ""
+	} else if args.Line == "" {
+		return "", fmt.Errorf("line must be provided, or use the file scope for issues about the whole file")
 	}
 
 	lineFeedback := common.LineLevel{
 		File:       args.File,
 		Body:       args.Issue,
 		Category:   args.Category,
+		Severity:   args.Severity,
+		Scope:      args.Scope,
 		Line:       args.Line,
+		Topic:      args.Topic,
 		Prompt:     args.Prompt,
 		Suggestion: args.Suggestion,
 	}
@@ -955,41 +1590,55 @@ func (o *OpenAIModel) processPostLineFeedbackToolCall(argumentsJSON string) (str
 }
 
 // createChatCompletionRequest creates a standard chat completion request with common settings
-func (o *OpenAIModel) createChatCompletionRequest(messages []openai.ChatCompletionMessage, toolChoice string, forceSummary bool) openai.ChatCompletionRequest {
-	return openai.ChatCompletionRequest{
+func (o *OpenAIModel) createChatCompletionRequest(messages []openai.ChatCompletionMessage, tools []agent.Tool, choice agent.ToolChoice) openai.ChatCompletionRequest {
+	req := openai.ChatCompletionRequest{
 		Model:       o.modelName,
 		Messages:    messages,
 		MaxTokens:   o.maxTokens,
 		Temperature: 0.2,
-		Tools:       o.getTools(forceSummary),
-		ToolChoice:  toolChoice,
+		Tools:       []openai.Tool{},
+		ToolChoice:  choice.Mode,
+	}
+	for _, tool := range tools {
+		req.Tools = append(req.Tools, openai.Tool{
+			Type:     openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters},
+		})
 	}
+	if choice.Tool != "" {
+		req.ToolChoice = openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: choice.Tool}}
+	}
+	o.applyModelOptions(&req)
+	return req
 }
 
-// handleAPIError creates a standard error response
-func (o *OpenAIModel) handleAPIError(errMsg string, toolCalls interface{}) Response {
+// apiError creates the error of a failed request, keeping the cause in the error chain
+func apiError(errMsg string, cause error) error {
 	logger.Error(errMsg)
-	return Response{
-		Error:     errors.New(errMsg),
-		ToolCalls: toolCalls,
+	if cause != nil {
+		return common.WrapError(errMsg, cause)
 	}
+	return errors.New(errMsg)
 }
 
-// createToolResponse creates a message with the tool response, handling any errors
-func createToolResponse(toolID string, content string, err error) openai.ChatCompletionMessage {
-	if err != nil {
-		logger.Warnf("Tool call %s failed: %v", toolID, err)
-		content = fmt.Sprintf("Error: %v", err)
-	}
-
-	if content == "" {
-		logger.Warnf("Tool call %s returned empty content, using placeholder", toolID)
-		content = emptyContentKey
-	}
-
-	return openai.ChatCompletionMessage{
-		Role:       openai.ChatMessageRoleTool,
-		Content:    content,
-		ToolCallID: toolID,
+// openAIError converts a failed OpenAI API call into a typed error
+func openAIError(err error) error {
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.Code == "context_length_exceeded" {
+			return &common.APIError{
+				Kind:       common.ErrorKindPayloadTooLarge,
+				Service:    "OpenAI",
+				StatusCode: apiErr.HTTPStatusCode,
+				Err:        err,
+			}
+		}
+		return common.NewAPIError("OpenAI", apiErr.HTTPStatusCode, err)
+	case errors.As(err, &requestErr):
+		return common.NewAPIError("OpenAI", requestErr.HTTPStatusCode, err)
 	}
+	return err
 }
@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// Reasoning efforts of the OpenAI reasoning models
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// Config is the configuration of an LLM client, built by applying the options over the defaults of the provider
+type Config struct {
+	Model      string
+	MaxTokens  int
+	APITimeout int // in seconds
+	Fallbacks  []common.ModelFallback
+	// Sampling and reasoning options, validated by the provider for the model
+	Temperature       *float64
+	TopP              *float64
+	ReasoningEffort   string
+	MaxThinkingTokens int
+	// Overridden roles of the tools and the extra tools, validated after all options are applied
+	ToolRoles   common.ToolRoles
+	CustomTools []common.CustomTool
+	MCPTools    []common.MCPTool
+
+	err error // First invalid option, reported when the client is created
+}
+
+// Option configures an LLM client
+type Option func(*Config)
+
+// newConfig applies the options over the defaults, failing on the first option with an invalid value
+func newConfig(defaults Config, opts ...Option) (Config, error) {
+	config := defaults
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.err == nil {
+		config.err = validateTools(config)
+	}
+	return config, config.err
+}
+
+// WithModel creates an option to set the model name
+func WithModel(model string) Option {
+	return func(c *Config) {
+		c.Model = model
+	}
+}
+
+// WithMaxTokens creates an option to set the max tokens
+func WithMaxTokens(maxTokens int) Option {
+	return func(c *Config) {
+		c.MaxTokens = maxTokens
+	}
+}
+
+// WithAPITimeout creates an option to set the API timeout in seconds
+func WithAPITimeout(timeout int) Option {
+	return func(c *Config) {
+		c.APITimeout = timeout
+	}
+}
+
+// WithFallbacks creates an option to set the models to fall back to when the model fails
+func WithFallbacks(fallbacks []common.ModelFallback) Option {
+	return func(c *Config) {
+		c.Fallbacks = fallbacks
+	}
+}
+
+// WithTemperature creates an option to set the sampling temperature
+func WithTemperature(temperature float64) Option {
+	return func(c *Config) {
+		c.Temperature = &temperature
+	}
+}
+
+// WithTopP creates an option to set the nucleus sampling probability mass
+func WithTopP(topP float64) Option {
+	return func(c *Config) {
+		c.TopP = &topP
+	}
+}
+
+// WithReasoningEffort creates an option to set the reasoning effort of reasoning models
+func WithReasoningEffort(effort string) Option {
+	return func(c *Config) {
+		c.ReasoningEffort = effort
+	}
+}
+
+// WithMaxThinkingTokens creates an option to set the extended thinking budget of the model
+func WithMaxThinkingTokens(tokens int) Option {
+	return func(c *Config) {
+		c.MaxThinkingTokens = tokens
+	}
+}
+
+// validateTools checks the custom tools and the tool roles, the roles can refer to the custom tools
+func validateTools(c Config) error {
+	if err := ValidateCustomTools(c.CustomTools); err != nil {
+		return fmt.Errorf("invalid custom tools: %w", err)
+	}
+	if err := ValidateToolRoles(c.ToolRoles, c.CustomTools); err != nil {
+		return fmt.Errorf("invalid tool roles: %w", err)
+	}
+	for _, tool := range c.MCPTools {
+		if slices.Contains(toolNames, tool.Name) || slices.ContainsFunc(c.CustomTools, func(custom common.CustomTool) bool { return custom.Name == tool.Name }) {
+			return fmt.Errorf("MCP tool %s clashes with another tool", tool.Name)
+		}
+	}
+	return nil
+}
+
+// WithToolRoles creates an option to override the roles of the tools
+func WithToolRoles(roles common.ToolRoles) Option {
+	return func(c *Config) {
+		c.ToolRoles = roles
+	}
+}
+
+// WithCustomTools creates an option to add the shell command backed tools of the settings
+func WithCustomTools(tools []common.CustomTool) Option {
+	return func(c *Config) {
+		c.CustomTools = tools
+	}
+}
+
+// WithMCPTools creates an option to add the tools of the connected MCP servers
+func WithMCPTools(tools []common.MCPTool) Option {
+	return func(c *Config) {
+		c.MCPTools = tools
+	}
+}
+
+// OptionType defines the type of option
+type OptionType string
+
+// Available option types
+const (
+	ModelNameOption         OptionType = "model"
+	MaxTokensOption         OptionType = "max_tokens"
+	APITimeoutOption        OptionType = "api_timeout"
+	FallbacksOption         OptionType = "fallbacks"
+	TemperatureOption       OptionType = "temperature"
+	TopPOption              OptionType = "top_p"
+	ReasoningEffortOption   OptionType = "reasoning_effort"
+	MaxThinkingTokensOption OptionType = "max_thinking_tokens"
+)
+
+// NewOption creates an option from its type and an untyped value.
+// A value of the wrong type fails the creation of the client instead of being ignored.
+//
+// Deprecated: use the typed With* options instead.
+func NewOption(optionType OptionType, value any) Option {
+	return func(c *Config) {
+		var ok bool
+		switch optionType {
+		case ModelNameOption:
+			var model string
+			if model, ok = value.(string); ok {
+				WithModel(model)(c)
+			}
+		case MaxTokensOption:
+			var maxTokens int
+			if maxTokens, ok = value.(int); ok {
+				WithMaxTokens(maxTokens)(c)
+			}
+		case APITimeoutOption:
+			var timeout int
+			if timeout, ok = value.(int); ok {
+				WithAPITimeout(timeout)(c)
+			}
+		case FallbacksOption:
+			var fallbacks []common.ModelFallback
+			if fallbacks, ok = value.([]common.ModelFallback); ok {
+				WithFallbacks(fallbacks)(c)
+			}
+		case TemperatureOption:
+			var temperature float64
+			if temperature, ok = value.(float64); ok {
+				WithTemperature(temperature)(c)
+			}
+		case TopPOption:
+			var topP float64
+			if topP, ok = value.(float64); ok {
+				WithTopP(topP)(c)
+			}
+		case ReasoningEffortOption:
+			var effort string
+			if effort, ok = value.(string); ok {
+				WithReasoningEffort(effort)(c)
+			}
+		case MaxThinkingTokensOption:
+			var tokens int
+			if tokens, ok = value.(int); ok {
+				WithMaxThinkingTokens(tokens)(c)
+			}
+		default:
+			if c.err == nil {
+				c.err = fmt.Errorf("unknown option %q", optionType)
+			}
+			return
+		}
+
+		if !ok && c.err == nil {
+			c.err = fmt.Errorf("invalid value %v (%T) for option %q", value, value, optionType)
+		}
+	}
+}
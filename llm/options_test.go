@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+func TestOptions(t *testing.T) {
+	fallbacks := []common.ModelFallback{{Provider: ProviderAnthropic, Model: "claude-sonnet-4"}}
+	temperature, topP := 0.0, 0.9
+	expected := Config{
+		Model:             "gpt-4.1-mini",
+		MaxTokens:         8000,
+		APITimeout:        90,
+		Fallbacks:         fallbacks,
+		Temperature:       &temperature,
+		TopP:              &topP,
+		ReasoningEffort:   ReasoningEffortHigh,
+		MaxThinkingTokens: 2048,
+	}
+
+	config, err := newConfig(Config{Model: "gpt-4.1", MaxTokens: 4000, APITimeout: 30},
+		WithModel("gpt-4.1-mini"),
+		WithMaxTokens(8000),
+		WithAPITimeout(90),
+		WithFallbacks(fallbacks),
+		WithTemperature(0),
+		WithTopP(0.9),
+		WithReasoningEffort(ReasoningEffortHigh),
+		WithMaxThinkingTokens(2048),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config)
+	}
+
+	legacy, err := newConfig(Config{},
+		NewOption(ModelNameOption, "gpt-4.1-mini"),
+		NewOption(MaxTokensOption, 8000),
+		NewOption(APITimeoutOption, 90),
+		NewOption(FallbacksOption, fallbacks),
+		NewOption(TemperatureOption, 0.0),
+		NewOption(TopPOption, 0.9),
+		NewOption(ReasoningEffortOption, ReasoningEffortHigh),
+		NewOption(MaxThinkingTokensOption, 2048),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(legacy, expected) {
+		t.Errorf("Expected the legacy options to match %+v, got %+v", expected, legacy)
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	config, err := newConfig(Config{Model: "gpt-4.1", MaxTokens: 4000, APITimeout: 30})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Model != "gpt-4.1" || config.MaxTokens != 4000 || config.APITimeout != 30 {
+		t.Errorf("Expected the defaults, got %+v", config)
+	}
+	if config.Temperature != nil || config.TopP != nil {
+		t.Errorf("Expected no sampling parameters by default, got %+v", config)
+	}
+
+	// Later options override earlier ones, the fallback chain relies on it
+	config, _ = newConfig(config, WithModel("o3"), WithModel("o4-mini"))
+	if config.Model != "o4-mini" {
+		t.Errorf("Expected the last model option to win, got %s", config.Model)
+	}
+}
+
+func TestNewOptionInvalidValue(t *testing.T) {
+	if _, err := newConfig(Config{}, NewOption(MaxTokensOption, "4000")); err == nil {
+		t.Error("Expected an error for a mistyped value")
+	}
+	if _, err := newConfig(Config{}, NewOption(TemperatureOption, 1)); err == nil {
+		t.Error("Expected an error for an int temperature")
+	}
+	if _, err := newConfig(Config{}, NewOption("unknown", 1)); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+	if _, err := NewOpenAI("key", NewOption(ModelNameOption, 4)); err == nil {
+		t.Error("Expected the client creation to fail on a mistyped value")
+	}
+}
+
+func TestWithToolRoles(t *testing.T) {
+	roles := common.ToolRoles{"list_directory": common.ToolRoleInitializer, "read_file": common.ToolRoleDisabled}
+	config, err := newConfig(Config{}, WithToolRoles(roles))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config.ToolRoles, roles) {
+		t.Errorf("Expected tool roles %v, got %v", roles, config.ToolRoles)
+	}
+
+	invalid := map[string]common.ToolRoles{
+		"unknown tool":         {"run_tests": common.ToolRoleHelper},
+		"invalid role":         {"read_file": "sometimes"},
+		"multiple initializer": {"read_file": common.ToolRoleInitializer, "search_codebase": common.ToolRoleInitializer},
+		"no finalizer":         {"post_summary": common.ToolRoleDisabled},
+	}
+	for name, roles := range invalid {
+		if _, err := newConfig(Config{}, WithToolRoles(roles)); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestWithCustomTools(t *testing.T) {
+	swiftlint := common.CustomTool{Name: "run_swiftlint", Description: "Lints Swift files", Command: "swiftlint lint"}
+
+	// Custom tools can be given roles, regardless of the order of the options
+	config, err := newConfig(Config{},
+		WithToolRoles(common.ToolRoles{"run_swiftlint": common.ToolRoleInitializer}),
+		WithCustomTools([]common.CustomTool{swiftlint}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(config.CustomTools) != 1 || config.ToolRoles["run_swiftlint"] != common.ToolRoleInitializer {
+		t.Errorf("Expected the custom tool with its role, got %+v", config)
+	}
+
+	builtIn := common.CustomTool{Name: "read_file", Description: "Reads a file", Command: "cat"}
+	if _, err := newConfig(Config{}, WithCustomTools([]common.CustomTool{builtIn})); err == nil {
+		t.Error("Expected an error for a custom tool clashing with a built-in tool")
+	}
+	if _, err := newConfig(Config{}, WithCustomTools([]common.CustomTool{swiftlint, swiftlint})); err == nil {
+		t.Error("Expected an error for a duplicated custom tool")
+	}
+}
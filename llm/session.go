@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/sashabaranov/go-openai"
+)
+
+// toSessionMessages converts the OpenAI conversation into the provider independent messages of the saved sessions
+func toSessionMessages(messages []openai.ChatCompletionMessage) []common.SessionMessage {
+	result := make([]common.SessionMessage, 0, len(messages))
+	for _, message := range messages {
+		sessionMessage := common.SessionMessage{
+			Role:       message.Role,
+			Content:    message.Content,
+			ToolCallID: message.ToolCallID,
+		}
+		for _, toolCall := range message.ToolCalls {
+			sessionMessage.ToolCalls = append(sessionMessage.ToolCalls, common.SessionToolCall{
+				ID:        toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			})
+		}
+		result = append(result, sessionMessage)
+	}
+	return result
+}
+
+// fromSessionMessages converts the messages of a saved session into an OpenAI conversation
+func fromSessionMessages(messages []common.SessionMessage) []openai.ChatCompletionMessage {
+	result := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, message := range messages {
+		chatMessage := openai.ChatCompletionMessage{
+			Role:       message.Role,
+			Content:    message.Content,
+			ToolCallID: message.ToolCallID,
+		}
+		for _, toolCall := range message.ToolCalls {
+			chatMessage.ToolCalls = append(chatMessage.ToolCalls, openai.ToolCall{
+				ID:   toolCall.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      toolCall.Name,
+					Arguments: toolCall.Arguments,
+				},
+			})
+		}
+		result = append(result, chatMessage)
+	}
+	return result
+}
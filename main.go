@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/cmd"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
 	_ "github.com/bitrise-io/bitrise-plugins-ai-reviewer/review"  // Import for PR review functionality
 	_ "github.com/bitrise-io/bitrise-plugins-ai-reviewer/version" // Import for version info
@@ -16,8 +17,17 @@ func main() {
 
 	logger.Info("Starting Bitrise AI Reviewer")
 
-	if err := cmd.Execute(); err != nil {
+	err := cmd.Execute()
+	// Requests skipped during an outage may not fail the run, list them in a single error either way
+	if circuitErr := common.OpenCircuitsError(); circuitErr != nil {
+		logger.Warnf("%v", circuitErr)
+	}
+	if err != nil {
 		logger.Errorf("Execution failed: %v", err)
-		os.Exit(1)
+		if remediation := common.Remediation(err); remediation != "" {
+			logger.Errorf("How to fix: %s", remediation)
+		}
+		logger.Sync()
+		os.Exit(common.ExitCode(err))
 	}
 }
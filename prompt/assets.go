@@ -0,0 +1,19 @@
+package prompt
+
+import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetAssetPrompt returns the instructions for the statically analyzed images and localization files
+func GetAssetPrompt(analysis *common.AssetAnalysis) string {
+	if analysis == nil {
+		return ""
+	}
+
+	return `
+## Assets
+The changed images, asset catalogs and localization files are analyzed separately, the size changes, missing translations and unused assets are reported in the summary.
+- Do not post feedback about image sizes, asset catalog metadata or missing translations.
+- Image contents are not shared with you, do not try to read them.
+`
+}
@@ -0,0 +1,30 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetBranchPolicyPrompt returns the guidance of the stricter policy of the target branch
+func GetBranchPolicyPrompt(policy *common.BranchPolicy, branch string) string {
+	if policy == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(`
+## Release branch
+The pull request targets %s, a branch with a stricter review policy. Changes here ship soon with little time to catch regressions.
+`, branch))
+	if len(policy.FailOnCategories) > 0 {
+		builder.WriteString(fmt.Sprintf("- Findings of the %s categories block the merge, report them only when they are real issues.\n", strings.Join(policy.FailOnCategories, ", ")))
+	}
+	if policy.ReleaseRisk {
+		builder.WriteString(`- Assess the release risk of the changes and include it as release_risk in post_summary: high, medium or low, followed by the reasons.
+  Consider the size and the blast radius of the changes, changed public APIs and data migrations, missing tests, and whether the change can be rolled back.
+`)
+	}
+	return builder.String()
+}
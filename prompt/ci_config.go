@@ -0,0 +1,24 @@
+package prompt
+
+import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetCIConfigPrompt returns the instructions for reviewing Bitrise CI configuration changes
+func GetCIConfigPrompt(analysis *common.CIConfigAnalysis) string {
+	if analysis == nil {
+		return ""
+	}
+
+	return `
+## CI config review
+The pull request changes the Bitrise CI configuration. Review these changes separately and include the result as ci_config_review in post_summary.
+- Report schema validation errors first, they break the builds.
+- Flag removed or misconfigured cache steps, they make the builds slower.
+- Flag secrets or secret environment variables made available to workflows triggered by pull requests, builds of untrusted forks could leak them.
+- Flag machine type and stack changes, explain the expected credit and billing impact.
+- Flag steps without pinned versions and removed steps other workflows depend on.
+- Post line feedback on the configuration lines as usual.
+### Static analysis
+` + analysis.String()
+}
@@ -0,0 +1,27 @@
+package prompt
+
+import (
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetClarificationPrompt returns the answers of the author to the questions of the previous review
+func GetClarificationPrompt(clarification common.Clarification) string {
+	if !clarification.Answered() {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n## Answers of the author\n")
+	builder.WriteString("The previous review asked the author of the pull request these questions:\n")
+	for _, question := range clarification.Questions {
+		builder.WriteString("- " + question + "\n")
+	}
+	builder.WriteString("The author answered:\n")
+	for _, answer := range clarification.Answers {
+		builder.WriteString("> " + strings.ReplaceAll(strings.TrimSpace(answer), "\n", "\n> ") + "\n\n")
+	}
+	builder.WriteString("Take the answers into account: don't report intended changes as issues, and don't ask the answered questions again.\n")
+	return builder.String()
+}
@@ -0,0 +1,9 @@
+package prompt
+
+// GetCompactSummaryPrompt returns the guidance of the summary of single-file and tiny pull requests
+func GetCompactSummaryPrompt() string {
+	return `
+## Compact summary
+The pull request is small, its summary is shown without a walkthrough. Keep the summary to one short paragraph of at most three sentences.
+`
+}
@@ -0,0 +1,19 @@
+package prompt
+
+import "github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+
+// GetContractPrompt returns the instructions for reviewing the changed API contracts
+func GetContractPrompt(analysis *common.ContractAnalysis) string {
+	if analysis == nil {
+		return ""
+	}
+
+	return `
+## API contract changes
+The pull request changes OpenAPI specs or proto files. The structural diff below is already listed in the summary, don't repeat it there.
+- Post line feedback on every breaking change, explain which clients break and suggest a backward compatible alternative, like deprecating instead of removing, reserving removed field numbers or adding a new version of the endpoint.
+- Check that the implementation and the clients changed in the pull request match the new contract.
+- Mention if a breaking change looks intentional but the API version was not bumped.
+### Structural diff
+` + analysis.String() + "\n"
+}
@@ -0,0 +1,38 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetCopyReviewPrompt returns the instructions for reviewing user-facing strings and markdown files
+func GetCopyReviewPrompt(settings common.Settings) string {
+	if !settings.CopyReview.Enabled {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`
+## Copy review
+Besides the code review, review the user-facing text of the changes: added or changed string literals shown to users (UI labels, error and log messages, localization files) and markdown files.
+- Flag typos, grammar mistakes and inconsistent terminology.
+- Post these findings with the "copy" category only, never mix them with code issues in the same feedback.
+- Suggest the corrected text as the suggestion.
+- Do not flag identifiers, keys, URLs or code in the strings.
+`)
+
+	if len(settings.CopyReview.Glossary) > 0 {
+		builder.WriteString("### Glossary\nUse the preferred terms of the team:\n")
+		for _, term := range settings.CopyReview.Glossary {
+			if len(term.Avoid) > 0 {
+				builder.WriteString(fmt.Sprintf("- \"%s\" instead of \"%s\"\n", term.Term, strings.Join(term.Avoid, "\", \"")))
+			} else {
+				builder.WriteString(fmt.Sprintf("- \"%s\"\n", term.Term))
+			}
+		}
+	}
+
+	return builder.String()
+}
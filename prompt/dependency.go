@@ -0,0 +1,31 @@
+package prompt
+
+import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+func GetDependencyUpdatePrompt(settings common.Settings, repoOwner, repoName, pr, commitHash, destBranch string) string {
+	return `Provide your final response with the following content:
+## Pull Request Details
+- **Repository**: ` + repoOwner + `/` + repoName + `
+- **Pull Request**: ` + pr + `
+- **Commit Hash**: ` + commitHash + `
+- **Destination Branch**: ` + destBranch + `
+- **Type**: Automated dependency update (e.g. Dependabot, Renovate)
+## During review
+- get the diff to identify the bumped dependencies and their old and new versions
+- get_release_notes for each bumped dependency to learn what changed between the versions
+- look for breaking changes, deprecations, security fixes and migration steps mentioned in the release notes
+- if a breaking change affects the codebase, search for the affected usages and post_line_feedback on the dependency change
+- post_summary at the end of the review with a merge_confidence verdict
+- for the summary include: ` + getSummary(settings) + `
+## Finished
+Once line feedbacks and summary posted you should reply with a "done" message, and do not call any more tools.
+## Guidelines
+- Do not post nitpicks or style feedback, focus on the impact of the update.
+- The merge_confidence verdict is high, medium or low, followed by a short justification referencing the release notes.
+- If the release notes can not be found, say so and base the verdict on the size of the version bump.
+- Avoid additional commentary as the response will be added as a comment on the pull request.
+## Task
+Can you review the dependency update PR ` + pr + ` on repo ` + repoOwner + `/` + repoName + ` (commit: ` + commitHash + `, branch: ` + destBranch + `)?`
+}
@@ -1,13 +1,22 @@
 package prompt
 
+import "github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+
 func GetDiffPrompt(diffContent string) string {
 	return `
 
 ===== PR DIFF =====
 
-` + diffContent + `
+` + common.StripAssetDiffs(diffContent) + `
 
 ===== PR DIFF END =====
 
 `
 }
+
+// GetProviderDiffPrompt inlines the diff fetched from the code review provider, as get_git_diff can't produce it without the git history
+func GetProviderDiffPrompt(diffContent string) string {
+	return `
+## Diff of the pull request
+The git history of the target branch is not available, so get_git_diff fails. Use the diff below instead, the files of the pull request can still be read.` + GetDiffPrompt(diffContent)
+}
@@ -0,0 +1,36 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetDocDriftPrompt returns the instructions for detecting documentation not updated with the changes
+func GetDocDriftPrompt(references []common.DocReference) string {
+	var builder strings.Builder
+	builder.WriteString(`
+## Documentation drift
+- When the behavior, parameters or return values of an exported function, type or class change, check that its doc comment still describes it.
+- If the doc comment is outdated, post_line_feedback with the "documentation" category on the declaration and suggest the updated doc comment.
+- Only flag documentation that is wrong after the change, not missing documentation of unchanged code.
+`)
+
+	if len(references) == 0 {
+		return builder.String()
+	}
+
+	builder.WriteString("The following changed symbols are referenced in the documentation of the repository. ")
+	builder.WriteString("Read the documentation where it was not updated, and if it no longer matches the changes, ")
+	builder.WriteString("post a \"documentation\" finding on the changed declaration with the suggested documentation text in the issue description.\n")
+	for _, reference := range references {
+		status := "not updated"
+		if reference.DocsChanged {
+			status = "updated in this pull request"
+		}
+		builder.WriteString(fmt.Sprintf("- `%s` (%s): %s, %s\n", reference.Symbol, reference.File, strings.Join(reference.Docs, ", "), status))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,40 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetFeatureFlagPrompt returns the instructions for reviewing the introduced and removed feature flags
+func GetFeatureFlagPrompt(settings common.Settings, changes common.FeatureFlagChanges, remainingUsages map[string]string) string {
+	if changes.IsEmpty() {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n## Feature flags\n")
+
+	if len(changes.Added) > 0 {
+		builder.WriteString("Introduced flags: " + strings.Join(changes.Added, ", ") + "\n")
+		if settings.FeatureFlags.NamingConvention != "" {
+			builder.WriteString("- Check the flag names follow the naming convention: " + settings.FeatureFlags.NamingConvention + "\n")
+		}
+		builder.WriteString("- Check the code behaves as before when the flag is off, or the flag is not found (default-off).\n")
+		builder.WriteString("- Check both the enabled and disabled code paths are covered by tests.\n")
+	}
+
+	if len(changes.Removed) > 0 {
+		builder.WriteString("Removed flags: " + strings.Join(changes.Removed, ", ") + "\n")
+		builder.WriteString("- Check the removed flags are fully cleaned up: dead code paths, tests, configuration and documentation.\n")
+		for _, flag := range changes.Removed {
+			if usages, ok := remainingUsages[flag]; ok {
+				builder.WriteString(fmt.Sprintf("The removed flag %s is still referenced:\n```\n%s\n```\n", flag, usages))
+			}
+		}
+	}
+
+	builder.WriteString("Post the issues as line feedback, and summarize the flag changes as feature_flags in post_summary.\n")
+	return builder.String()
+}
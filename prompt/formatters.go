@@ -0,0 +1,25 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetFormattersPrompt returns the instructions to leave the formatting to the local formatters which checked the changes
+func GetFormattersPrompt(results []common.FormatterResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+
+	names := make([]string, len(results))
+	for idx, result := range results {
+		names[idx] = result.Name
+	}
+	return fmt.Sprintf(`
+## Formatting
+The formatting of the changed files is checked with %s, the issues are posted in a separate comment.
+- Do not comment on formatting, indentation, whitespace or import order.
+`, strings.Join(names, ", "))
+}
@@ -0,0 +1,33 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetHotPathPrompt returns the stricter performance guidance for changes touching the hot paths
+func GetHotPathPrompt(matches []common.HotPathMatch) string {
+	if len(matches) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`
+## Hot paths
+The following changes touch performance critical code marked as hot paths by the team:
+`)
+	for _, match := range matches {
+		builder.WriteString(fmt.Sprintf("- %s (matches '%s')\n", match.File, match.Pattern))
+	}
+	builder.WriteString(`Apply stricter performance guidance to these changes and post the issues with the "performance" category:
+- Allocations in loops: growing slices or maps without preallocation, string concatenation, boxing and closures created per iteration.
+- N+1 calls: API, database or file system calls inside loops that could be batched or cached.
+- Lock contention: locks held during I/O or long computations, coarse-grained locks on shared state, unnecessary synchronization.
+- Algorithmic complexity increases, redundant work, and unbounded caches or buffers.
+Summarize the performance impact of the hot path changes as performance in post_summary.
+`)
+
+	return builder.String()
+}
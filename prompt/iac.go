@@ -0,0 +1,44 @@
+package prompt
+
+import (
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// iacRules holds the review guidance per infrastructure-as-code tool
+var iacRules = map[string]string{
+	common.IaCTerraform: `
+### Terraform
+- Run 'terraform validate' with run_command on the changed module directories and report the errors as "bug".
+- Flag publicly accessible storage (public buckets, ACLs, missing public access blocks), security groups open to 0.0.0.0/0, and disabled encryption at rest as "security".
+- Flag hardcoded credentials, missing state locking, unpinned provider and module versions, and resources replaced instead of updated in place.`,
+	common.IaCKubernetes: `
+### Kubernetes
+- Run 'kubeval' with run_command on the changed manifests and report the errors as "bug".
+- Flag containers without resource requests and limits, missing liveness and readiness probes, and images using the latest tag as "bug".
+- Flag privileged containers, running as root, host network or host path mounts, and secrets in plain environment variables as "security".`,
+	common.IaCHelm: `
+### Helm
+- Flag templates not using the configured values for resources, image tags and security contexts.
+- Flag chart version not bumped when templates changed, and values.yaml defaults that are insecure (e.g. privileged, public ingress without TLS) as "security".`,
+}
+
+// GetIaCPrompt returns the review guidance for the infrastructure-as-code changes
+func GetIaCPrompt(changes common.IaCChanges) string {
+	tools := changes.Tools()
+	if len(tools) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`
+## Infrastructure-as-code review
+The pull request changes infrastructure-as-code files, review them for misconfigurations.
+`)
+	for _, tool := range tools {
+		builder.WriteString("Changed " + tool + " files: " + strings.Join(changes[tool], ", "))
+		builder.WriteString(iacRules[tool] + "\n")
+	}
+	return builder.String()
+}
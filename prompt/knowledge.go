@@ -0,0 +1,33 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetAcceptedSuggestionsPrompt returns the suggestions of earlier reviews the team applied, to suggest code in the style they adopt
+func GetAcceptedSuggestionsPrompt(examples []common.KnowledgeSuggestion) string {
+	if len(examples) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`
+## Accepted suggestions
+The team applied these suggestions of earlier reviews. Prefer their style in your suggestions, and avoid styles the team did not adopt.
+`)
+	for _, example := range examples {
+		builder.WriteString(fmt.Sprintf("\n### %s", example.File))
+		if example.Category != "" {
+			builder.WriteString(" (" + example.Category + ")")
+		}
+		builder.WriteString("\n")
+		if example.Original != "" {
+			builder.WriteString("Original:\n```\n" + example.Original + "\n```\n")
+		}
+		builder.WriteString("Applied suggestion:\n```\n" + example.Suggestion + "\n```\n")
+	}
+	return builder.String()
+}
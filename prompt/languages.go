@@ -0,0 +1,16 @@
+package prompt
+
+import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetLanguagesPrompt returns the languages of the changes, so the review applies their idioms
+func GetLanguagesPrompt(languages common.LanguageBreakdown) string {
+	if len(languages.Languages) == 0 {
+		return ""
+	}
+	return `
+## Languages
+The changed lines are ` + languages.String() + `. Apply the idioms and the common pitfalls of these languages, and use their code block languages in the suggestions.
+`
+}
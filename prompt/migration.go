@@ -0,0 +1,36 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetMigrationPrompt returns the instructions for reviewing the database migrations of the changes
+func GetMigrationPrompt(files []string, findings []common.MigrationFinding) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`
+## Database migration safety
+The pull request changes database migrations: ` + strings.Join(files, ", ") + `
+Check them for destructive operations, missing indexes on new foreign keys, non-concurrent index creation on existing tables, table locking operations and irreversible down migrations.
+Post these issues with the "bug" category and "high" severity, and explain the safe alternative (e.g. expand and contract, concurrent index creation, batched backfills).
+`)
+
+	if len(findings) > 0 {
+		builder.WriteString("Static analysis flagged the following, verify each and post the confirmed ones:\n")
+		for _, finding := range findings {
+			if finding.Line != "" {
+				builder.WriteString(fmt.Sprintf("- %s: `%s`: %s\n", finding.File, strings.TrimSpace(finding.Line), finding.Issue))
+			} else {
+				builder.WriteString(fmt.Sprintf("- %s: %s\n", finding.File, finding.Issue))
+			}
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,33 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetPersonaPrompt returns the guidance of the reviewer personas of the changed areas
+func GetPersonaPrompt(matches []common.PersonaMatch) string {
+	if len(matches) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`
+## Reviewer personas
+The team configured a reviewer persona for each area of the repository. Review each group of files with the tone and standards of its persona,
+instead of the default voice, and write the line feedback of these files in the voice of the persona. Files not listed here get the default voice.
+`)
+	for _, match := range matches {
+		builder.WriteString(fmt.Sprintf("\n### %s\n", match.Persona.Name))
+		builder.WriteString(fmt.Sprintf("Files: %s\n", strings.Join(match.Files, ", ")))
+		if match.Persona.Tone != "" {
+			builder.WriteString(fmt.Sprintf("Tone: %s\n", match.Persona.Tone))
+		}
+		if match.Persona.Instructions != "" {
+			builder.WriteString(strings.TrimSpace(match.Persona.Instructions) + "\n")
+		}
+	}
+	return builder.String()
+}
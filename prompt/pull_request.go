@@ -0,0 +1,56 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// maxDescriptionLength caps the description of the pull request in the prompt, get_pull_request_details returns it in full
+const maxDescriptionLength = 4000
+
+// GetPullRequestContextPrompt returns the details of the pull request fetched before the review,
+// saving the round trip of calling get_pull_request_details first
+func GetPullRequestContextPrompt(pr common.PullRequest) string {
+	if pr.Title == "" && pr.Body == "" {
+		return ""
+	}
+
+	labels := make([]string, len(pr.Labels))
+	for i, label := range pr.Labels {
+		labels[i] = label.Name
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n## Pull request context\n")
+	builder.WriteString("The details of the pull request, call get_pull_request_details only for the commits or a truncated description.\n")
+	builder.WriteString(fmt.Sprintf("- **Title**: %s\n", pr.Title))
+	if pr.Author != "" {
+		builder.WriteString(fmt.Sprintf("- **Author**: %s\n", pr.Author))
+	}
+	if pr.HeadBranch != "" || pr.BaseBranch != "" {
+		builder.WriteString(fmt.Sprintf("- **Branches**: %s into %s\n", pr.HeadBranch, pr.BaseBranch))
+	}
+	if !pr.CreatedAt.IsZero() {
+		builder.WriteString(fmt.Sprintf("- **Opened**: %s\n", common.FormatTimestamp(pr.CreatedAt)))
+	}
+	if !pr.UpdatedAt.IsZero() {
+		builder.WriteString(fmt.Sprintf("- **Last updated**: %s\n", common.FormatTimestamp(pr.UpdatedAt)))
+	}
+	if len(labels) > 0 {
+		builder.WriteString(fmt.Sprintf("- **Labels**: %s\n", strings.Join(labels, ", ")))
+	}
+
+	builder.WriteString("### Description\n")
+	description := strings.TrimSpace(pr.Body)
+	switch {
+	case description == "":
+		builder.WriteString("The author did not describe the changes.\n")
+	case len([]rune(description)) > maxDescriptionLength:
+		builder.WriteString(string([]rune(description)[:maxDescriptionLength]) + "\n[Description truncated]\n")
+	default:
+		builder.WriteString(description + "\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,44 @@
+package prompt
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetQuickReviewSystemPrompt returns the system prompt of the one-shot quick review, which has no tools
+func GetQuickReviewSystemPrompt(settings common.Settings) string {
+	tone := "You are Bit Bot, a code reviewer trained to assist development teams."
+	if settings.Tone != "" {
+		tone = settings.Tone
+	}
+
+	systemPrompt := tone + `
+You are doing a quick review of a small pull request. Only the diff is shared with you, you don't have access to other files or tools.
+Do not make assumptions about code outside of the diff.
+` + getProfile(settings) + `
+- Focus feedback on correctness, logic and security, skip style issues and nitpicks.
+- Respond only with a JSON object, don't wrap it in a code block.`
+	if settings.Style == common.StylePlain {
+		systemPrompt += "\n- Do not use emojis or decorative formatting in any of your responses."
+	}
+	if settings.Language != "" && settings.Language != "en-US" {
+		systemPrompt += fmt.Sprintf("\n- Use %s language.", settings.Language)
+	}
+	if variant := common.ActivePromptVariant(); variant.Instructions != "" {
+		systemPrompt += "\n" + variant.Instructions
+	}
+
+	return systemPrompt
+}
+
+// GetQuickReviewPrompt returns the task of the quick review, the diff is inlined after it with GetDiffPrompt
+func GetQuickReviewPrompt() string {
+	return `Review the changes of the diff below.
+## Response format
+{"summary": "one or two sentences about the changes", "findings": [{"file": "path of the file", "content": "the exact line of the diff, without the +/- prefix", "category": "bug|security|performance|improvement|documentation|test coverage", "issue": "short description of the issue", "suggestion": "optional replacement of the line"}]}
+## Guidelines
+- Only report issues you are confident about, an empty findings list is fine.
+- Only include lines present in the diff hunk. Do not make up or synthesize lines.
+- Leave the suggestion empty unless it is a complete, correct replacement of the line.`
+}
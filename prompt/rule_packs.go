@@ -0,0 +1,47 @@
+package prompt
+
+import (
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// rulePacks holds the platform-specific review guidance of the opt-in rule packs
+var rulePacks = map[string]string{
+	common.RulePackIOS: `
+## iOS review rules
+- Info.plist changes: flag new or changed usage description keys (NS*UsageDescription) that are missing, vague or not localized, new background modes, and App Transport Security exceptions. Use "store-compliance".
+- Entitlements changes: flag new capabilities (push, iCloud, keychain groups, associated domains) as they need matching provisioning profiles and App Store review. Use "store-compliance".
+- Privacy manifest (PrivacyInfo.xcprivacy): flag usage of required reason APIs (e.g. UserDefaults, file timestamps, disk space) without a declared reason. Use "store-compliance".
+- SwiftUI and UIKit: flag UI updates outside of the main thread, missing @MainActor on view models updating published state, and heavy work in view bodies or initializers. Use "mobile-perf" or "bug".
+- Flag synchronous network or disk I/O on the main thread, retain cycles in closures capturing self, and large images decoded on the main thread. Use "mobile-perf".
+- Deployment target or Swift version bumps: mention the impact on the supported devices.`,
+	common.RulePackAndroid: `
+## Android review rules
+- ProGuard/R8 rules: flag overly broad -keep or -dontwarn rules that disable shrinking, and missing keep rules for classes used via reflection or serialization. Use "mobile-perf" or "bug".
+- AndroidManifest.xml: flag new dangerous permissions, exported components without permissions, and cleartext traffic or debuggable flags. Use "store-compliance" or "security".
+- Gradle version bumps (AGP, Gradle wrapper, Kotlin, compileSdk, targetSdk, minSdk): mention the migration needed and the Play Store target API level requirements. Use "store-compliance".
+- Flag disk or network I/O on the main thread, work in onCreate or Application startup that delays the first frame, and leaked Activity or Context references. Use "mobile-perf".
+- Jetpack Compose: flag unstable parameters causing unnecessary recomposition and side effects outside of effect handlers. Use "mobile-perf".`,
+}
+
+// getRulePacks returns the review guidance of the rule packs enabled in the settings
+func getRulePacks(settings common.Settings) string {
+	var builder strings.Builder
+	for _, name := range settings.RulePacks {
+		pack, ok := rulePacks[name]
+		if !ok {
+			logger.Warnf("Unknown rule pack: %s", name)
+			continue
+		}
+		builder.WriteString(pack)
+	}
+
+	if builder.Len() > 0 {
+		builder.WriteString(`
+- Besides the default categories use "mobile-perf" for mobile performance issues and "store-compliance" for App Store and Play Store policy issues.`)
+	}
+
+	return builder.String()
+}
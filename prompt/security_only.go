@@ -0,0 +1,9 @@
+package prompt
+
+// GetSecurityOnlyPrompt returns the guidance of the security-only review requested by the author
+func GetSecurityOnlyPrompt() string {
+	return `
+## Security-only review
+The author requested a security-only review. Only post line feedback for security issues, with the "security" category: injection, authentication and authorization flaws, secrets in the code, unsafe deserialization, insecure transport or storage, and vulnerable dependency usage. Keep the summary and the walkthrough as usual, but leave out the other findings.
+`
+}
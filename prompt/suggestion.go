@@ -0,0 +1,38 @@
+package prompt
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetSuggestionVerificationSystemPrompt returns the system prompt of the suggestion verification pass
+func GetSuggestionVerificationSystemPrompt() string {
+	return `You are verifying code suggestions of a code review before they are posted.
+Authors often apply suggestions without reading them carefully, so a suggestion must be correct as is.
+- The suggestion replaces exactly the commented lines, check that the result is valid code, with matching brackets, indentation and no duplicated or lost lines.
+- Check that the suggestion only changes what the review comment describes, and does not alter any other behavior.
+- Respond only with a JSON object, don't wrap it in a code block: {"applies": true|false, "preserves_behavior": true|false, "reason": "short explanation"}`
+}
+
+// GetSuggestionVerificationPrompt returns the prompt asking if the suggestion applies cleanly and preserves behavior
+func GetSuggestionVerificationPrompt(ll common.LineLevel, context string) string {
+	lines := fmt.Sprintf("%d", ll.LineNumber)
+	if ll.IsMultiline() {
+		lines = fmt.Sprintf("%d-%d", ll.LineNumber, ll.LastLineNumber)
+	}
+
+	return fmt.Sprintf(`Does this suggestion apply cleanly and preserve behavior?
+
+File: %s
+Commented lines: %s
+
+Review comment:
+%s
+
+Code around the commented lines:
+%s
+Suggested replacement of the commented lines:
+%s
+`, ll.File, lines, ll.Body, context, ll.Suggestion)
+}
@@ -38,8 +38,8 @@ func getSummary(settings common.Settings) string {
 		if settings.Reviews.Walkthrough {
 			include = append(include, "walkthrough")
 		}
-		if settings.Reviews.Haiku {
-			include = append(include, "haiku")
+		if celebration := settings.GetCelebration(); celebration.Enabled() {
+			include = append(include, "celebration ("+strings.ToLower(celebration.GetTitle())+")")
 		}
 
 		return strings.Join(include, ", ")
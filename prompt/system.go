@@ -2,6 +2,7 @@ package prompt
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 )
@@ -12,10 +13,19 @@ func GetSystemPrompt(settings common.Settings) string {
 - Focus feedback on correctness, logic, performance, maintainability, and security.
 - Ignore minor code style issues unless they cause confusion or bugs.
 - If the PR is excellent, end your summary with a positive remark or emoji.
-- Format full response as a well formatted, valid JSON object, don't wrap it in a code block`
+- Format full response as a well formatted, valid JSON object, don't wrap it in a code block` + getRulePacks(settings)
+	if settings.Style == common.StylePlain {
+		basePrompt += "\n- Do not use emojis or decorative formatting in any of your responses."
+	}
 	if settings.Language != "" && settings.Language != "en-US" {
 		basePrompt += fmt.Sprintf("\n- Use %s language.", settings.Language)
 	}
+	if disabled := settings.Tools[common.CommandSummarize].Disabled(); len(disabled) > 0 {
+		basePrompt += fmt.Sprintf("\n- The following tools are disabled, don't try to use them: %s.", strings.Join(disabled, ", "))
+	}
+	if variant := common.ActivePromptVariant(); variant.Instructions != "" {
+		basePrompt += "\n" + variant.Instructions
+	}
 
 	return basePrompt
 }
@@ -43,21 +53,48 @@ Please keep going until the user’s query is completely resolved, before ending
 Use tools specified below, do NOT guess or make up an answer.
 You MUST plan extensively before each function call, and reflect extensively on the outcomes of the previous function calls. DO NOT do this entire process by making function calls only, as this can impair your ability to solve the problem and think insightfully.
 Code changes suggested should be validated and should not break the code when applied.
+` + getToolsAndProcess(settings)
+}
+
+func getToolsAndProcess(settings common.Settings) string {
+	if settings.SecretsFree {
+		return `
+## You have the following tools:
+- get_git_diff: See what changed between branches or commits.
+- post_line_feedback: Use to post line-level feedback on specific lines of code, including suggestions for improvement.
+- post_summary: Use to post a summary of the review findings, including the walkthrough and celebration section.
+
+Only the diff is shared with you, you don't have access to other files or details of the repository.
+Do not make assumptions about code outside of the diff.
+
+## Core Review Process:
+1. **During Review**
+- Get the diff to see what changed
+- After identifying the issues, immediately call post_line_feedback for it, using the exact lines from the diff.
+- If the same issue occurs in multiple places, post each location with the same topic, they are grouped into a single comment.
+- For issues about a whole file, like a file that should be split, use the file scope instead of picking an arbitrary line.
+2. **After Review**
+- Post a summary of the review findings, including the walkthrough and celebration section.`
+	}
 
+	return `
 ## You have the following tools:
-- get_pull_request_details: Use to get details about the pull request, such as title, description, and author.
+- get_pull_request_details: Use to get details about the pull request missing from the request, such as the commits.
 - list_directory: Use to understand the project structure or locate files.
 - get_git_diff: See what changed between branches or commits.
 - read_file: Use to read any file if the diff is unclear.
 - search_codebase: Use if a function, class, or symbol appears in the diff and you want to know where else it is used or defined.
 - get_git_blame: Use to see who last modified a line or to understand why a change was made.
+- get_release_notes: Use on dependency updates to read the upstream release notes of the bumped versions.
+- search_history: Use when the change takes a notable approach, like adding a cache, changing retries or swapping a library, to check if it was tried and reverted before. If it was, warn about it and link the old pull request.
+- run_command: Use to validate changed infrastructure-as-code with terraform validate or kubeval.` + getRecentErrorsTool(settings) + getExternalRepoTool(settings) + getClarificationTool(settings) + getCustomTools(settings) + `
 - post_line_feedback: Use to post line-level feedback on specific lines of code, including suggestions for improvement.
-- post_summary: Use to post a summary of the review findings, including any haiku or walkthrough.
+- post_summary: Use to post a summary of the review findings, including the walkthrough and celebration section.
 
 
 ## Core Review Process:
 1. **Before Review**
-- Get the pull request details first to understand the context.
+- Read the pull request context of the request to understand the intent of the changes, get the pull request details only if it is missing.
 2. **During Review**
 - Get the diff to see what changed
 - If the diff references a function not defined there, search for it in the codebase.
@@ -65,6 +102,47 @@ Code changes suggested should be validated and should not break the code when ap
 - If you want to suggest a refactor, search for all usages.
 - If you need context about why something is written a certain way, use blame.
 - After identifying the issues, immediately call post_line_feedback for it, using the exact lines from the diff.
+- If the same issue occurs in multiple places, post each location with the same topic, they are grouped into a single comment.
+- For issues about a whole file, like a file that should be split, use the file scope instead of picking an arbitrary line.
 3. **After Review**
-- Post a summary of the review findings, including any haiku or walkthrough.`
+- Post a summary of the review findings, including the walkthrough and celebration section.`
+}
+
+func getRecentErrorsTool(settings common.Settings) string {
+	if !settings.Sentry.Enabled() {
+		return ""
+	}
+	return "\n- get_recent_errors: Use to check if the changed files are implicated in production errors. " +
+		"If a modified function appears in an active error, point it out and suggest extra caution and tests."
+}
+
+func getExternalRepoTool(settings common.Settings) string {
+	if len(settings.ExternalRepos) == 0 {
+		return ""
+	}
+	return "\n- read_external_repo_file: Use to read files of other services (" + strings.Join(settings.ExternalRepos, ", ") + "). " +
+		"If the changes touch an API contract (endpoints, schemas, shared types), check its consumers before claiming a change is safe."
+}
+
+func getCustomTools(settings common.Settings) string {
+	var tools strings.Builder
+	roles := settings.Tools[common.CommandSummarize]
+	for _, tool := range settings.CustomTools {
+		if roles[tool.Name] == common.ToolRoleDisabled {
+			continue
+		}
+		tools.WriteString("\n- " + tool.Name + ": " + tool.Description)
+	}
+	for _, server := range settings.MCPServers {
+		tools.WriteString("\n- " + server.Name + "_*: Tools of the " + server.Name + " MCP server, use them to look up the context of the changes.")
+	}
+	return tools.String()
+}
+
+func getClarificationTool(settings common.Settings) string {
+	if settings.Reviews.ClarificationQuestions <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n- ask_clarification_questions: Use instead of guessing when the intent of a change is ambiguous, e.g. \"Is the retry count change intentional?\". "+
+		"Ask at most %d questions in a single call, and only about intent that can't be determined from the code.", settings.Reviews.ClarificationQuestions)
 }
@@ -0,0 +1,20 @@
+package prompt
+
+import (
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// GetCachedWalkthroughPrompt returns the guidance to leave the files unchanged since the previous review out of the walkthrough
+func GetCachedWalkthroughPrompt(cached []common.Walkthrough) string {
+	var builder strings.Builder
+	builder.WriteString(`
+## Cached walkthrough
+These files did not change since the previous review, their walkthrough rows are reused. Leave them out of the walkthrough, only describe the other files. Keep the summary consistent with these rows:
+`)
+	for _, row := range cached {
+		builder.WriteString("- " + row.Files + ": " + row.Summary + "\n")
+	}
+	return builder.String()
+}
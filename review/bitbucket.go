@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -42,6 +43,9 @@ type CommentResponse struct {
 		Raw string `json:"raw"`
 	} `json:"content"`
 	CreatedOn time.Time `json:"created_on"`
+	Parent    *struct {
+		ID int `json:"id"`
+	} `json:"parent,omitempty"`
 }
 
 // NewBitbucket creates a new Bitbucket reviewer client
@@ -81,13 +85,357 @@ func (bb *Bitbucket) GetProvider() string {
 	return ProviderBitbucket
 }
 
-func (bb *Bitbucket) SupportCollapsibleMarkdown() bool {
-	return false
+// GetRepositoryURL returns the web URL of the repository
+func (bb *Bitbucket) GetRepositoryURL(repoOwner, repoName string) string {
+	return fmt.Sprintf("https://bitbucket.org/%s/%s", repoOwner, repoName)
 }
 
+// GetPullRequestDetails returns the details and the commits of the pull request
 func (bb *Bitbucket) GetPullRequestDetails(repoOwner, repoName string, pr int) (common.PullRequest, error) {
-	// TODO
-	return common.PullRequest{}, nil
+	logger.Infof("Fetching pull request details for PR #%d in %s/%s", pr, repoOwner, repoName)
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	prURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d", bb.BaseURL, repoOwner, repoName, pr)
+	body, err := bb.get(ctx, prURL)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to get pull request details: %v", err)
+		logger.Error(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, err)
+	}
+
+	type branch struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+	}
+	var prDetails struct {
+		ID          int    `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		State       string `json:"state"`
+		Author      struct {
+			DisplayName string `json:"display_name"`
+			AccountID   string `json:"account_id"`
+		} `json:"author"`
+		Source      branch `json:"source"`
+		Destination branch `json:"destination"`
+		CreatedOn   string `json:"created_on"`
+		UpdatedOn   string `json:"updated_on"`
+	}
+	if err := json.Unmarshal(body, &prDetails); err != nil {
+		errMsg := fmt.Sprintf("failed to parse pull request details: %v", err)
+		logger.Error(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, err)
+	}
+
+	body, err = bb.get(ctx, prURL+"/commits")
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to list pull request commits: %v", err)
+		logger.Error(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, err)
+	}
+	var commitsList struct {
+		Values []struct {
+			Hash    string `json:"hash"`
+			Message string `json:"message"`
+			Date    string `json:"date"`
+			Author  struct {
+				Raw string `json:"raw"`
+			} `json:"author"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(body, &commitsList); err != nil {
+		errMsg := fmt.Sprintf("failed to parse pull request commits: %v", err)
+		logger.Error(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, err)
+	}
+
+	commits := make([]common.Commit, 0, len(commitsList.Values))
+	for _, commit := range commitsList.Values {
+		commits = append(commits, common.Commit{
+			CommitHash: commit.Hash,
+			Author:     commit.Author.Raw,
+			Message:    commit.Message,
+			CreatedAt:  common.ParseTimestamp(commit.Date),
+		})
+	}
+
+	return common.PullRequest{
+		Number:     prDetails.ID,
+		Title:      prDetails.Title,
+		Body:       prDetails.Description,
+		HeadBranch: prDetails.Source.Branch.Name,
+		BaseBranch: prDetails.Destination.Branch.Name,
+		CreatedAt:  common.ParseTimestamp(prDetails.CreatedOn),
+		UpdatedAt:  common.ParseTimestamp(prDetails.UpdatedOn),
+		Author:     prDetails.Author.DisplayName,
+		AuthorID:   prDetails.Author.AccountID,
+		Mergeable:  prDetails.State == "OPEN",
+		Merged:     prDetails.State == "MERGED",
+		Labels:     []common.Label{},
+		Commits:    commits,
+	}, nil
+}
+
+// GetRepositoryFile returns the content of a file on the default branch of a repository
+func (bb *Bitbucket) GetRepositoryFile(repoOwner, repoName, path string) (string, error) {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	// The source endpoint needs a revision, look up the default branch first
+	repoURL := fmt.Sprintf("%s/repositories/%s/%s", bb.BaseURL, repoOwner, repoName)
+	body, err := bb.get(ctx, repoURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get repository %s/%s: %w", repoOwner, repoName, err)
+	}
+	var repository struct {
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	}
+	if err := json.Unmarshal(body, &repository); err != nil {
+		return "", fmt.Errorf("failed to parse repository %s/%s: %w", repoOwner, repoName, err)
+	}
+
+	srcURL := fmt.Sprintf("%s/src/%s/%s", repoURL, url.PathEscape(repository.MainBranch.Name), escapePath(path))
+	content, err := bb.get(ctx, srcURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s from %s/%s: %w", path, repoOwner, repoName, err)
+	}
+	return string(content), nil
+}
+
+// GetPullRequestDiff returns the diff of the pull request from the Bitbucket API, without the need of the git history
+func (bb *Bitbucket) GetPullRequestDiff(repoOwner, repoName string, pr int) (string, error) {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	diffURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/diff", bb.BaseURL, repoOwner, repoName, pr)
+	diff, err := bb.get(ctx, diffURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the diff of PR #%d in %s/%s: %w", pr, repoOwner, repoName, err)
+	}
+	return string(diff), nil
+}
+
+// CheckAuth validates the API token by getting the authenticated user
+func (bb *Bitbucket) CheckAuth() error {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	_, err := bb.get(ctx, bb.BaseURL+"/user")
+	return err
+}
+
+// SearchMergedPullRequests filters the merged pull requests of the repository by their title and description
+func (bb *Bitbucket) SearchMergedPullRequests(repoOwner, repoName string, keywords []string) ([]common.HistoryPullRequest, error) {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	var filters []string
+	for _, keyword := range keywords {
+		keyword = strconv.Quote(keyword)
+		filters = append(filters, fmt.Sprintf("title ~ %s OR description ~ %s", keyword, keyword))
+	}
+	query := fmt.Sprintf(`state = "MERGED" AND (%s)`, strings.Join(filters, " OR "))
+	apiURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests?state=MERGED&sort=-updated_on&pagelen=%d&q=%s",
+		bb.BaseURL, repoOwner, repoName, common.MaxHistoryResults, url.QueryEscape(query))
+
+	body, err := bb.get(ctx, apiURL)
+	if err != nil {
+		return nil, common.WrapError("failed to search pull requests", err)
+	}
+
+	var result struct {
+		Values []struct {
+			ID          int    `json:"id"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			UpdatedOn   string `json:"updated_on"`
+			Links       struct {
+				HTML struct {
+					Href string `json:"href"`
+				} `json:"html"`
+			} `json:"links"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, common.WrapError("failed to parse pull requests", err)
+	}
+
+	pullRequests := make([]common.HistoryPullRequest, 0, len(result.Values))
+	for _, pr := range result.Values {
+		pullRequests = append(pullRequests, common.HistoryPullRequest{
+			Number:      pr.ID,
+			Title:       pr.Title,
+			Description: pr.Description,
+			URL:         pr.Links.HTML.Href,
+			// Merged pull requests are not updated after the merge
+			MergedAt: common.ParseTimestamp(pr.UpdatedOn),
+		})
+	}
+	return pullRequests, nil
+}
+
+// CheckWriteAccess detects fork pull requests, and the permissions of access tokens from their scopes
+func (bb *Bitbucket) CheckWriteAccess(repoOwner, repoName string, pr int) (common.WriteAccess, error) {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	prURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d", bb.BaseURL, repoOwner, repoName, pr)
+	body, header, err := bb.getWithHeader(ctx, prURL)
+	if err != nil {
+		return common.WriteAccess{}, common.WrapError("failed to get pull request details", err)
+	}
+
+	type endpoint struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	var prDetails struct {
+		Source      endpoint `json:"source"`
+		Destination endpoint `json:"destination"`
+	}
+	if err := json.Unmarshal(body, &prDetails); err != nil {
+		return common.WriteAccess{}, common.WrapError("failed to parse pull request details", err)
+	}
+
+	scopes := header.Get(oauthScopesHeader)
+	access := common.WriteAccess{
+		Fork:        prDetails.Source.Repository.FullName != prDetails.Destination.Repository.FullName,
+		Permissions: bitbucketPermissions(scopes),
+	}
+	access.CanWrite = access.Granted(common.PermissionComment)
+	if !access.CanWrite {
+		access.Reason = fmt.Sprintf("the token has the %q scopes, commenting needs pullrequest", scopes)
+	}
+	return access, nil
+}
+
+// createTask creates a pull request task on the comment, Bitbucket tracks the open tasks of the pull request
+func (bb *Bitbucket) createTask(ctx context.Context, repoOwner, repoName string, pr, commentID int, content string) error {
+	var task struct {
+		Content struct {
+			Raw string `json:"raw"`
+		} `json:"content"`
+		Comment struct {
+			ID int `json:"id"`
+		} `json:"comment"`
+	}
+	task.Content.Raw = content
+	task.Comment.ID = commentID
+
+	jsonData, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	apiURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/tasks", bb.BaseURL, repoOwner, repoName, pr)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := bb.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return common.NewAPIError("Bitbucket", resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// bitbucketBuildStates are the build status states of the commit status states
+var bitbucketBuildStates = map[string]string{
+	common.CommitStatusPending: "INPROGRESS",
+	common.CommitStatusSuccess: "SUCCESSFUL",
+	common.CommitStatusFailure: "FAILED",
+	common.CommitStatusError:   "FAILED",
+}
+
+// SetCommitStatus sets the build status of the commit, which can be required by the merge checks.
+// The status needs a link, the repository is linked outside of Bitrise builds.
+func (bb *Bitbucket) SetCommitStatus(repoOwner, repoName, commitHash string, status common.CommitStatus) error {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	url := status.TargetURL
+	if url == "" {
+		url = bb.GetRepositoryURL(repoOwner, repoName)
+	}
+	jsonData, err := json.Marshal(map[string]string{
+		"key":         strings.ReplaceAll(common.CommitStatusContext, "/", "-"),
+		"name":        common.CommitStatusContext,
+		"state":       bitbucketBuildStates[status.State],
+		"description": status.Description,
+		"url":         url,
+	})
+	if err != nil {
+		return err
+	}
+
+	apiURL := fmt.Sprintf("%s/repositories/%s/%s/commit/%s/statuses/build", bb.BaseURL, repoOwner, repoName, commitHash)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := bb.client.Do(req)
+	if err != nil {
+		return common.WrapError("failed to set commit status", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return common.WrapError("failed to set commit status", common.NewAPIError("Bitbucket", resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)))
+	}
+	return nil
+}
+
+// GetForcePushes returns no force pushes, the activity of Bitbucket pull requests doesn't tell force pushes apart.
+// Rewritten histories are detected from the git history.
+func (bb *Bitbucket) GetForcePushes(repoOwner, repoName string, pr int) ([]common.ForcePush, error) {
+	return nil, nil
+}
+
+// MigrateComments moves no comments, Bitbucket comments have no diff hunk to find their code in the new commit.
+// The findings posted before the rewrite are still matched by their fingerprint, they are not posted again.
+func (bb *Bitbucket) MigrateComments(client *git.Client, repoOwner, repoName string, pr int, commitHash string) (int, error) {
+	logger.Info("Bitbucket keeps the inline comments on the lines they were posted on, they are not moved")
+	return 0, nil
+}
+
+// get sends a GET request to the Bitbucket API and returns the response body
+func (bb *Bitbucket) get(ctx context.Context, apiURL string) ([]byte, error) {
+	body, _, err := bb.getWithHeader(ctx, apiURL)
+	return body, err
+}
+
+// getWithHeader sends a GET request to the Bitbucket API and returns the response body and header
+func (bb *Bitbucket) getWithHeader(ctx context.Context, apiURL string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := bb.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, common.NewAPIError("Bitbucket", resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode))
+	}
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.Header, err
 }
 
 // getComments retrieves all comments for a pull request
@@ -107,7 +455,7 @@ func (bb *Bitbucket) getComments(ctx context.Context, repoOwner, repoName string
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get comments: HTTP %d", resp.StatusCode)
+		return nil, common.NewAPIError("Bitbucket", resp.StatusCode, fmt.Errorf("failed to get comments: HTTP %d", resp.StatusCode))
 	}
 
 	// Parse the response
@@ -144,6 +492,50 @@ func (bb *Bitbucket) getComment(comments []CommentResponse, header string) (int,
 	return 0, nil
 }
 
+// GetClarification returns the questions asked from the author and the replies to the questions comment
+func (bb *Bitbucket) GetClarification(repoOwner, repoName string, pr int) (common.Clarification, error) {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := bb.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
+		logger.Errorf(errMsg)
+		return common.Clarification{}, common.WrapError(errMsg, err)
+	}
+
+	var clarification common.Clarification
+	questionsID := 0
+	for _, c := range comments {
+		if strings.HasPrefix(c.Content.Raw, common.ClarificationHeader) {
+			clarification.Questions = common.ParseClarificationQuestions(c.Content.Raw)
+			questionsID = c.ID
+		}
+	}
+	if questionsID == 0 {
+		return clarification, nil
+	}
+
+	for _, c := range comments {
+		if c.Parent != nil && c.Parent.ID == questionsID && !common.IsPluginComment(c.Content.Raw) {
+			clarification.Answers = append(clarification.Answers, c.Content.Raw)
+		}
+	}
+	return clarification, nil
+}
+
+// GetCommentBody returns the body of the comment with the header without the header, empty if there is none
+func (bb *Bitbucket) GetCommentBody(repoOwner, repoName string, pr int, header string) (string, error) {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := bb.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return "", err
+	}
+	return bb.getCommentBodyWithoutHeader(comments, header)
+}
+
 func (bb *Bitbucket) PostSummaryUnderReview(repoOwner, repoName string, pr int, header string) error {
 	logger.Infof("Summary under update for PR #%d in %s/%s", pr, repoOwner, repoName)
 
@@ -155,14 +547,14 @@ func (bb *Bitbucket) PostSummaryUnderReview(repoOwner, repoName string, pr int,
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	commentBody, err := bb.getCommentBodyWithoutHeader(comments, header)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to check existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	underReviewStr := common.Summary{}.InitiatedString(bb.GetProvider())
@@ -170,7 +562,7 @@ func (bb *Bitbucket) PostSummaryUnderReview(repoOwner, repoName string, pr int,
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to post summary under review: %v", err)
 		logger.Error(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	return nil
@@ -188,16 +580,42 @@ func (bb *Bitbucket) PostSummary(repoOwner, repoName string, pr int, header, bod
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	commentID, err := bb.getComment(comments, header)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to check existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+
+	parts := common.SplitComment(header, body, bb.Capabilities().MaxCommentLength)
+	if len(parts) > 1 {
+		logger.Warnf("The summary is longer than a comment, posting it in %d parts", len(parts))
+	}
+
+	if err := bb.saveComment(ctx, repoOwner, repoName, pr, commentID, parts[0]); err != nil {
+		return err
 	}
 
+	if commentID > 0 {
+		logger.Infof("Updated existing summary comment for PR #%d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsUpdated(1)
+	} else {
+		logger.Infof("Posted new summary comment for PR #%d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsPosted(1)
+	}
+
+	if err := bb.postContinuations(ctx, comments, repoOwner, repoName, pr, header, parts[1:]); err != nil {
+		return err
+	}
+	common.Audit().RecordComment(common.AuditComment{Kind: common.AuditKindSummary, Body: body})
+	return nil
+}
+
+// saveComment updates the comment, or creates a new one when the comment ID is zero
+func (bb *Bitbucket) saveComment(ctx context.Context, repoOwner, repoName string, pr, commentID int, body string) error {
 	// For regular summary comments (without inline feedback), we can use a simpler structure
 	// that only includes the content field for both new and updated comments
 	commentData := struct {
@@ -216,7 +634,7 @@ func (bb *Bitbucket) PostSummary(repoOwner, repoName string, pr int, header, bod
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to marshal comment data: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	var req *http.Request
@@ -239,7 +657,7 @@ func (bb *Bitbucket) PostSummary(repoOwner, repoName string, pr int, header, bod
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to create request: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -248,7 +666,7 @@ func (bb *Bitbucket) PostSummary(repoOwner, repoName string, pr int, header, bod
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to send request: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 	defer resp.Body.Close()
 
@@ -257,15 +675,98 @@ func (bb *Bitbucket) PostSummary(repoOwner, repoName string, pr int, header, bod
 		fmt.Println(string(body))
 		errMsg := fmt.Sprintf("Failed to post comment: HTTP %d", resp.StatusCode)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.NewAPIError("Bitbucket", resp.StatusCode, errors.New(errMsg))
 	}
 
-	if commentID > 0 {
-		logger.Infof("Updated existing summary comment for PR #%d in %s/%s", pr, repoOwner, repoName)
-	} else {
-		logger.Infof("Posted new summary comment for PR #%d in %s/%s", pr, repoOwner, repoName)
+	return nil
+}
+
+// postContinuations posts the parts of a split comment after the first one,
+// updating the parts posted by the previous run and deleting the ones no longer needed
+func (bb *Bitbucket) postContinuations(ctx context.Context, comments []CommentResponse, repoOwner, repoName string, pr int, header string, continuations []string) error {
+	existing := map[int]int{}
+	for _, c := range comments {
+		if part, ok := common.ContinuationPart(c.Content.Raw, header); ok {
+			existing[part] = c.ID
+		}
+	}
+
+	for idx, body := range continuations {
+		part := idx + 2
+		commentID := existing[part]
+		delete(existing, part)
+		if err := bb.saveComment(ctx, repoOwner, repoName, pr, commentID, body); err != nil {
+			return err
+		}
+		if commentID > 0 {
+			common.Report().CommentsUpdated(1)
+		} else {
+			common.Report().CommentsPosted(1)
+		}
+	}
+
+	for part, commentID := range existing {
+		logger.Debugf("Deleting part %d of the summary, the summary got shorter", part)
+		if err := bb.deleteComment(ctx, repoOwner, repoName, pr, commentID); err != nil {
+			logger.Warnf("Failed to delete part %d of the previous summary: %v", part, err)
+		}
+	}
+	return nil
+}
+
+// deleteComment deletes a comment of the pull request
+func (bb *Bitbucket) deleteComment(ctx context.Context, repoOwner, repoName string, pr, commentID int) error {
+	apiURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/comments/%d",
+		bb.BaseURL, repoOwner, repoName, pr, commentID)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", apiURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := bb.client.Do(req)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode >= 300 {
+		return common.NewAPIError("Bitbucket", resp.StatusCode, fmt.Errorf("failed to delete comment: HTTP %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// SaveComment adds or updates the comment with the header, unlike the summary it is not recorded in the report
+func (bb *Bitbucket) SaveComment(repoOwner, repoName string, pr int, header, body string) error {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := bb.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return err
+	}
+	commentID, err := bb.getComment(comments, header)
+	if err != nil {
+		return err
+	}
+	return bb.saveComment(ctx, repoOwner, repoName, pr, commentID, body)
+}
+
+// DeleteComment deletes every comment with the header
+func (bb *Bitbucket) DeleteComment(repoOwner, repoName string, pr int, header string) error {
+	ctx, cancel := bb.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := bb.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(c.Content.Raw, header) {
+			if err := bb.deleteComment(ctx, repoOwner, repoName, pr, c.ID); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -282,7 +783,7 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	// Get existing review comments
@@ -291,34 +792,60 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to get existing review comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
+	recordDismissedFindings(existingComments)
 
-	// Track feedback that will be posted
+	// Track feedback that will be posted, with the tasks to create on the comments of high severity findings
 	lineComments := []PRComment{}
+	lineTasks := []string{}
+	lineAudits := []common.AuditComment{}
 
 	logger.Infof("Processing %d line feedback items", len(lineFeedback.GetLineFeedback()))
 	for _, ll := range lineFeedback.GetLineFeedback() {
 		skip := false
 
-		if ll.File == "" || ll.LineNumber <= 0 {
+		if ll.File == "" || (ll.LineNumber <= 0 && !ll.IsFileLevel()) {
 			logger.Warnf("Skipping invalid line feedback - file: %s, line: %d", ll.File, ll.LineNumber)
 			continue
 		}
 
+		if IsDismissedFinding(existingComments, ll) {
+			logger.Infof("Skipping dismissed finding for file: %s, line: %d", ll.File, ll.LineNumber)
+			common.Report().CommentsSkipped(1)
+			continue
+		}
+
+		// File comments only have the path, without a line range
+		if ll.IsFileLevel() {
+			if IsPostedFileComment(existingComments, ll) {
+				logger.Infof("Skipping existing file comment for file: %s", ll.File)
+				common.Report().CommentsSkipped(1)
+				continue
+			}
+
+			comment := PRComment{}
+			comment.Content.Raw = ll.String(bb.GetProvider(), client, commitHash)
+			comment.Inline.Path = ll.File
+			lineComments = append(lineComments, comment)
+			lineTasks = append(lineTasks, ll.Task())
+			lineAudits = append(lineAudits, common.NewLineAuditComment(ll, comment.Content.Raw))
+			continue
+		}
+
 		logger.Debugf("Getting blame for file: %s, line: %d", ll.File, ll.LineNumber)
 		blame, err := client.GetBlameForFileLine(commitHash, ll.File, ll.LineNumber)
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to get blame for line: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
 
 		// Check if we already have a comment for this location
 		for _, existingComment := range existingComments {
 			if ll.File == existingComment.File &&
 				ll.LineNumber >= existingComment.LineNumber && ll.LastLineNumber <= existingComment.LastLineNumber &&
-				blame == existingComment.CommitHash {
+				git.SameCommit(blame, existingComment.CommitHash) {
 				logger.Infof("Skipping existing comment for file: %s, line: %d", ll.File, ll.LineNumber)
 				logger.Debugf("Existing comment: line number: %d, last line number: %d, commit hash: %s", existingComment.LineNumber, existingComment.LastLineNumber, existingComment.CommitHash)
 				logger.Debugf("Line feedback: line number: %d, last line number: %d, commit hash: %s", ll.LineNumber, ll.LastLineNumber, blame)
@@ -327,7 +854,14 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 			}
 		}
 
+		// The blame of the posted findings is stale after a rebase, they are matched by their fingerprint instead
+		if !skip && lineFeedback.HistoryRewritten && IsPostedFinding(existingComments, ll) {
+			logger.Infof("Skipping finding posted before the history was rewritten for file: %s, line: %d", ll.File, ll.LineNumber)
+			skip = true
+		}
+
 		if skip {
+			common.Report().CommentsSkipped(1)
 			continue
 		}
 
@@ -336,10 +870,11 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to check existing comments: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
 
 		if commentID > 0 {
+			common.Report().CommentsSkipped(1)
 			continue
 		}
 
@@ -368,6 +903,8 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 		}
 
 		lineComments = append(lineComments, comment)
+		lineTasks = append(lineTasks, ll.Task())
+		lineAudits = append(lineAudits, common.NewLineAuditComment(ll, reviewBody))
 	}
 
 	// Process nitpick comments
@@ -380,11 +917,12 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 	}
 
 	// Format nitpick comments for display
-	nitpickComments := FormatNitpickComments(bb.GetProvider(), nitpickCommentsByFile)
+	permalinks := common.Permalinks{RepoURL: bb.GetRepositoryURL(repoOwner, repoName), CommitHash: commitHash}
+	nitpickComments := FormatNitpickComments(bb.GetProvider(), permalinks, nitpickCommentsByFile)
 
 	// Post all line comments
 	if len(lineComments) > 0 {
-		for _, comment := range lineComments {
+		for idx, comment := range lineComments {
 			jsonData, err := json.Marshal(comment)
 			if err != nil {
 				logger.Errorf("Failed to marshal comment data: %v", err)
@@ -410,6 +948,16 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 
 			if resp.StatusCode >= 300 {
 				logger.Errorf("Failed to post comment: HTTP %d", resp.StatusCode)
+			} else {
+				common.Report().CommentsPosted(1)
+				common.Audit().RecordComment(lineAudits[idx])
+
+				var posted CommentResponse
+				if task := lineTasks[idx]; task != "" && json.NewDecoder(resp.Body).Decode(&posted) == nil {
+					if err := bb.createTask(ctx, repoOwner, repoName, pr, posted.ID, task); err != nil {
+						logger.Warnf("Failed to create task for %s: %v", comment.Inline.Path, err)
+					}
+				}
 			}
 
 			resp.Body.Close()
@@ -418,7 +966,7 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 
 	// Post nitpick comments as a summary comment if they exist
 	if len(nitpickComments) > 0 {
-		overallReviewStr := FormatOverallReview(len(lineComments), nitpickComments)
+		overallReviewStr := FormatOverallReview(bb.GetProvider(), len(lineComments), nitpickComments)
 
 		nitpickPRComment := PRComment{
 			Content: struct {
@@ -432,7 +980,7 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to marshal nitpick comment data: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
 
 		apiURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/comments",
@@ -442,7 +990,7 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to create request for nitpick comments: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
 
 		req.Header.Set("Content-Type", "application/json")
@@ -451,15 +999,17 @@ func (bb *Bitbucket) PostLineFeedback(client *git.Client, repoOwner, repoName st
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to send request for nitpick comments: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode >= 300 {
 			errMsg := fmt.Sprintf("Failed to post nitpick comments: HTTP %d", resp.StatusCode)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.NewAPIError("Bitbucket", resp.StatusCode, errors.New(errMsg))
 		}
+		common.Report().CommentsPosted(1)
+		common.Audit().RecordComment(common.AuditComment{Kind: common.AuditKindReview, Body: overallReviewStr})
 	}
 
 	logger.Infof("Posted line feedback for PR %d in %s/%s", pr, repoOwner, repoName)
@@ -473,28 +1023,28 @@ func (bb *Bitbucket) GetReviewRequestComments(repoOwner, repoName string, pr int
 
 	lineReviews := []common.LineLevel{}
 
-	apiURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/comments?fields=values.content,values.inline,values.id",
+	apiURL := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/comments?fields=values.content,values.inline,values.id,values.parent,values.resolution",
 		bb.BaseURL, repoOwner, repoName, pr)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to create request for comments: %v", err)
 		logger.Errorf(errMsg)
-		return nil, errors.New(errMsg)
+		return nil, common.WrapError(errMsg, err)
 	}
 
 	resp, err := bb.client.Do(req)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to get comments: %v", err)
 		logger.Errorf(errMsg)
-		return nil, errors.New(errMsg)
+		return nil, common.WrapError(errMsg, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		errMsg := fmt.Sprintf("Failed to get comments: HTTP %d", resp.StatusCode)
 		logger.Errorf(errMsg)
-		return nil, errors.New(errMsg)
+		return nil, common.NewAPIError("Bitbucket", resp.StatusCode, errors.New(errMsg))
 	}
 
 	type Comment struct {
@@ -507,6 +1057,9 @@ func (bb *Bitbucket) GetReviewRequestComments(repoOwner, repoName string, pr int
 			From int    `json:"from,omitempty"`
 			To   int    `json:"to,omitempty"`
 		} `json:"inline,omitempty"`
+		Parent *struct {
+			ID int `json:"id"`
+		} `json:"parent,omitempty"`
 	}
 
 	var commentsResponse struct {
@@ -516,10 +1069,24 @@ func (bb *Bitbucket) GetReviewRequestComments(repoOwner, repoName string, pr int
 	if err := json.NewDecoder(resp.Body).Decode(&commentsResponse); err != nil {
 		errMsg := fmt.Sprintf("Failed to decode comment response: %v", err)
 		logger.Errorf(errMsg)
-		return nil, errors.New(errMsg)
+		return nil, common.WrapError(errMsg, err)
+	}
+
+	// Collect the comments dismissed by a reply
+	dismissedIDs := make(map[int]bool)
+	for _, comment := range commentsResponse.Values {
+		if comment.Parent != nil && common.IsDismissalReply(comment.Content.Raw) {
+			logger.Debugf("Comment %d dismissed by reply %d", comment.Parent.ID, comment.ID)
+			dismissedIDs[comment.Parent.ID] = true
+		}
 	}
 
 	for _, comment := range commentsResponse.Values {
+		// Skip replies to other comments
+		if comment.Parent != nil {
+			continue
+		}
+
 		// Skip comments without inline information
 		if comment.Inline.Path == "" {
 			continue
@@ -561,12 +1128,19 @@ func (bb *Bitbucket) GetReviewRequestComments(repoOwner, repoName string, pr int
 		blame := strings.TrimSpace(parts[3])
 		blame = strings.TrimSpace(strings.Split(blame, " ")[0])
 
+		fingerprint := ""
+		if len(parts) > 4 {
+			fingerprint = strings.TrimSpace(parts[4])
+		}
+
 		lineReviews = append(lineReviews, common.LineLevel{
 			File:           file,
 			LineNumber:     firstLine,
 			LastLineNumber: lastLine,
 			CommitHash:     blame,
 			Body:           strings.Join(lines[1:], "\n"),
+			Fingerprint:    fingerprint,
+			Dismissed:      dismissedIDs[comment.ID],
 		})
 	}
 
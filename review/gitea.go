@@ -0,0 +1,873 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// giteaPageSize is the page size of the paginated Gitea endpoints, the default maximum of the servers
+const giteaPageSize = 50
+
+// Gitea implements the Reviewer interface for Gitea PRs, Forgejo has the same API
+type Gitea struct {
+	*BaseReviewer
+	client *http.Client
+	apiURL string
+}
+
+// giteaComment is a comment of a Gitea pull request, issue comments and review comments share its fields
+type giteaComment struct {
+	ID               int       `json:"id"`
+	Body             string    `json:"body"`
+	Path             string    `json:"path,omitempty"`
+	Position         int       `json:"position,omitempty"`
+	OriginalPosition int       `json:"original_position,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Resolver         *struct {
+		Login string `json:"login"`
+	} `json:"resolver,omitempty"`
+}
+
+// giteaReviewComment is a line comment of a new Gitea pull request review.
+// Gitea reviews have no multiline comments, the comments are anchored to the last line of their range.
+type giteaReviewComment struct {
+	Path        string `json:"path"`
+	Body        string `json:"body"`
+	NewPosition int    `json:"new_position"`
+}
+
+// giteaRepository is the repository of a branch of a Gitea pull request
+type giteaRepository struct {
+	FullName    string          `json:"full_name"`
+	Private     bool            `json:"private"`
+	Permissions map[string]bool `json:"permissions"`
+}
+
+// giteaPullRequest is a Gitea pull request
+type giteaPullRequest struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	HTMLURL   string `json:"html_url"`
+	Mergeable bool   `json:"mergeable"`
+	Merged    bool   `json:"merged"`
+	User      struct {
+		Login    string `json:"login"`
+		FullName string `json:"full_name"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Head struct {
+		Ref  string          `json:"ref"`
+		Repo giteaRepository `json:"repo"`
+	} `json:"head"`
+	Base struct {
+		Ref  string          `json:"ref"`
+		Repo giteaRepository `json:"repo"`
+	} `json:"base"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	MergedAt  *time.Time `json:"merged_at"`
+}
+
+// NewGitea creates a new Gitea reviewer client, the base URL of the server is required
+func NewGitea(opts ...Option) (Reviewer, error) {
+	logger.Debug("Creating new Gitea reviewer client")
+
+	baseReviewer, err := NewBaseReviewer(ProviderGitea, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseReviewer.BaseURL == "" {
+		errMsg := "Gitea base URL is required, set GITEA_URL to the URL of the server"
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+	// The URL of the server is expected, the URL of its API is accepted too
+	baseReviewer.BaseURL = strings.TrimSuffix(strings.TrimSuffix(baseReviewer.BaseURL, "/"), "/api/v1")
+
+	gt := &Gitea{
+		BaseReviewer: baseReviewer,
+		client:       common.NewRetryableClient(common.DefaultRetryConfig()).StandardClient(),
+		apiURL:       baseReviewer.BaseURL + "/api/v1",
+	}
+
+	logger.Debug("Gitea reviewer client created successfully")
+	return gt, nil
+}
+
+// GetProvider returns the name of the review provider
+func (gt *Gitea) GetProvider() string {
+	return ProviderGitea
+}
+
+// GetRepositoryURL returns the web URL of the repository
+func (gt *Gitea) GetRepositoryURL(repoOwner, repoName string) string {
+	return fmt.Sprintf("%s/%s/%s", gt.BaseURL, repoOwner, repoName)
+}
+
+// repoURL returns the API URL of the repository
+func (gt *Gitea) repoURL(repoOwner, repoName string) string {
+	return fmt.Sprintf("%s/repos/%s/%s", gt.apiURL, url.PathEscape(repoOwner), url.PathEscape(repoName))
+}
+
+// GetPullRequestDetails returns the details and the commits of the pull request
+func (gt *Gitea) GetPullRequestDetails(repoOwner, repoName string, pr int) (common.PullRequest, error) {
+	logger.Infof("Fetching pull request details for PR #%d in %s/%s", pr, repoOwner, repoName)
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	prDetails, err := gt.getPullRequest(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to get pull request details: %v", err)
+		logger.Error(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, err)
+	}
+
+	body, err := gt.do(ctx, "GET", fmt.Sprintf("%s/pulls/%d/commits?limit=%d", gt.repoURL(repoOwner, repoName), pr, giteaPageSize), nil)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to list pull request commits: %v", err)
+		logger.Error(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, err)
+	}
+	var commitsList []struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Message string `json:"message"`
+			Author  struct {
+				Name string `json:"name"`
+				Date string `json:"date"`
+			} `json:"author"`
+		} `json:"commit"`
+	}
+	if err := json.Unmarshal(body, &commitsList); err != nil {
+		errMsg := fmt.Sprintf("failed to parse pull request commits: %v", err)
+		logger.Error(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, err)
+	}
+
+	commits := make([]common.Commit, 0, len(commitsList))
+	for _, commit := range commitsList {
+		commits = append(commits, common.Commit{
+			CommitHash: commit.SHA,
+			Author:     commit.Commit.Author.Name,
+			Message:    commit.Commit.Message,
+			CreatedAt:  common.ParseTimestamp(commit.Commit.Author.Date),
+		})
+	}
+
+	labels := make([]common.Label, 0, len(prDetails.Labels))
+	for _, label := range prDetails.Labels {
+		labels = append(labels, common.Label{Name: label.Name})
+	}
+
+	author := prDetails.User.FullName
+	if author == "" {
+		author = prDetails.User.Login
+	}
+
+	return common.PullRequest{
+		Number:     prDetails.Number,
+		Title:      prDetails.Title,
+		Body:       prDetails.Body,
+		HeadBranch: prDetails.Head.Ref,
+		BaseBranch: prDetails.Base.Ref,
+		CreatedAt:  prDetails.CreatedAt,
+		UpdatedAt:  prDetails.UpdatedAt,
+		Author:     author,
+		Mergeable:  prDetails.Mergeable,
+		Merged:     prDetails.Merged,
+		Labels:     labels,
+		Commits:    commits,
+	}, nil
+}
+
+// getPullRequest gets the pull request from the Gitea API
+func (gt *Gitea) getPullRequest(ctx context.Context, repoOwner, repoName string, pr int) (giteaPullRequest, error) {
+	var prDetails giteaPullRequest
+	body, err := gt.do(ctx, "GET", fmt.Sprintf("%s/pulls/%d", gt.repoURL(repoOwner, repoName), pr), nil)
+	if err != nil {
+		return prDetails, err
+	}
+	err = json.Unmarshal(body, &prDetails)
+	return prDetails, err
+}
+
+// GetRepositoryFile returns the content of a file on the default branch of a repository
+func (gt *Gitea) GetRepositoryFile(repoOwner, repoName, path string) (string, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	// The raw endpoint reads the default branch without a ref
+	content, err := gt.do(ctx, "GET", fmt.Sprintf("%s/raw/%s", gt.repoURL(repoOwner, repoName), escapePath(path)), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s from %s/%s: %w", path, repoOwner, repoName, err)
+	}
+	return string(content), nil
+}
+
+// GetPullRequestDiff returns the diff of the pull request from the Gitea API, without the need of the git history
+func (gt *Gitea) GetPullRequestDiff(repoOwner, repoName string, pr int) (string, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	diff, err := gt.do(ctx, "GET", fmt.Sprintf("%s/pulls/%d.diff", gt.repoURL(repoOwner, repoName), pr), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the diff of PR #%d in %s/%s: %w", pr, repoOwner, repoName, err)
+	}
+	return string(diff), nil
+}
+
+// CheckAuth validates the API token by getting the authenticated user
+func (gt *Gitea) CheckAuth() error {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	_, err := gt.do(ctx, "GET", gt.apiURL+"/user", nil)
+	return err
+}
+
+// SearchMergedPullRequests filters the latest closed pull requests of the repository by their title and description,
+// the pull request list of Gitea has no keyword search
+func (gt *Gitea) SearchMergedPullRequests(repoOwner, repoName string, keywords []string) ([]common.HistoryPullRequest, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	apiURL := fmt.Sprintf("%s/pulls?state=closed&sort=recentupdate&limit=%d", gt.repoURL(repoOwner, repoName), giteaPageSize)
+	body, err := gt.do(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, common.WrapError("failed to search pull requests", err)
+	}
+
+	var result []giteaPullRequest
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, common.WrapError("failed to parse pull requests", err)
+	}
+
+	pullRequests := make([]common.HistoryPullRequest, 0, common.MaxHistoryResults)
+	for _, pr := range result {
+		if !pr.Merged || pr.MergedAt == nil || !containsAnyKeyword(pr.Title+"\n"+pr.Body, keywords) {
+			continue
+		}
+		pullRequests = append(pullRequests, common.HistoryPullRequest{
+			Number:      pr.Number,
+			Title:       pr.Title,
+			Description: pr.Body,
+			URL:         pr.HTMLURL,
+			MergedAt:    *pr.MergedAt,
+		})
+		if len(pullRequests) == common.MaxHistoryResults {
+			break
+		}
+	}
+	return pullRequests, nil
+}
+
+// containsAnyKeyword checks if the text contains any of the keywords, ignoring the case
+func containsAnyKeyword(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckWriteAccess detects fork pull requests, and the permissions of the user of the token on the repository
+func (gt *Gitea) CheckWriteAccess(repoOwner, repoName string, pr int) (common.WriteAccess, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	prDetails, err := gt.getPullRequest(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return common.WriteAccess{}, common.WrapError("failed to get pull request details", err)
+	}
+
+	access := common.WriteAccess{
+		Fork:        prDetails.Head.Repo.FullName != prDetails.Base.Repo.FullName,
+		Permissions: giteaPermissions(prDetails.Base.Repo.Permissions),
+	}
+	access.CanWrite = access.Granted(common.PermissionComment)
+	if !access.CanWrite {
+		access.Reason = "the user of the token has no access to the repository"
+	}
+	return access, nil
+}
+
+// SetCommitStatus sets the commit status of the review, Gitea uses the states of GitHub
+func (gt *Gitea) SetCommitStatus(repoOwner, repoName, commitHash string, status common.CommitStatus) error {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	payload := map[string]string{
+		"context":     common.CommitStatusContext,
+		"state":       status.State,
+		"description": status.Description,
+		"target_url":  status.TargetURL,
+	}
+	if _, err := gt.do(ctx, "POST", fmt.Sprintf("%s/statuses/%s", gt.repoURL(repoOwner, repoName), commitHash), payload); err != nil {
+		return common.WrapError("failed to set commit status", err)
+	}
+	return nil
+}
+
+// GetForcePushes returns the force pushes of the head branch from the timeline of the pull request.
+// Gitea records every push of the branch, the force pushes are marked in the body of the event.
+func (gt *Gitea) GetForcePushes(repoOwner, repoName string, pr int) ([]common.ForcePush, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	var forcePushes []common.ForcePush
+	for page := 1; ; page++ {
+		apiURL := fmt.Sprintf("%s/issues/%d/timeline?page=%d&limit=%d", gt.repoURL(repoOwner, repoName), pr, page, giteaPageSize)
+		body, err := gt.do(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return nil, common.WrapError("failed to get the timeline of the pull request", err)
+		}
+
+		var events []struct {
+			Type      string    `json:"type"`
+			Body      string    `json:"body"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, common.WrapError("failed to parse the timeline of the pull request", err)
+		}
+
+		for _, event := range events {
+			if event.Type != "pull_push" {
+				continue
+			}
+			var push struct {
+				IsForcePush bool `json:"is_force_push"`
+			}
+			if json.Unmarshal([]byte(event.Body), &push) == nil && push.IsForcePush {
+				forcePushes = append(forcePushes, common.ForcePush{At: event.CreatedAt})
+			}
+		}
+
+		if len(events) < giteaPageSize {
+			return forcePushes, nil
+		}
+	}
+}
+
+// MigrateComments moves no comments, Gitea reviews can't be changed after they are submitted.
+// Outdated comments stay on the review, and the findings are still matched by their fingerprint, they are not posted again.
+func (gt *Gitea) MigrateComments(client *git.Client, repoOwner, repoName string, pr int, commitHash string) (int, error) {
+	logger.Info("Gitea keeps the outdated review comments on their review, they are not moved")
+	return 0, nil
+}
+
+// do sends a request to the Gitea API with the JSON payload if it's not nil, and returns the response body
+func (gt *Gitea) do(ctx context.Context, method, apiURL string, payload any) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+gt.ApiToken)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := gt.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, common.NewAPIError("Gitea", resp.StatusCode, fmt.Errorf("%s %s: HTTP %d", method, req.URL.Path, resp.StatusCode))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// getComments retrieves the comments of the pull request, without its review comments
+func (gt *Gitea) getComments(ctx context.Context, repoOwner, repoName string, pr int) ([]giteaComment, error) {
+	body, err := gt.do(ctx, "GET", fmt.Sprintf("%s/issues/%d/comments", gt.repoURL(repoOwner, repoName), pr), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []giteaComment
+	if err := json.Unmarshal(body, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (gt *Gitea) getCommentBodyWithoutHeader(comments []giteaComment, header string) string {
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, header) {
+			return strings.TrimSpace(strings.TrimPrefix(c.Body, header))
+		}
+	}
+	return ""
+}
+
+// getComment returns the ID of the comment with the header, 0 if there is none
+func (gt *Gitea) getComment(comments []giteaComment, header string) int {
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, header) {
+			return c.ID
+		}
+	}
+	return 0
+}
+
+// GetClarification returns the questions asked from the author and the comments posted since,
+// Gitea pull request comments have no threads
+func (gt *Gitea) GetClarification(repoOwner, repoName string, pr int) (common.Clarification, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := gt.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
+		logger.Errorf(errMsg)
+		return common.Clarification{}, common.WrapError(errMsg, err)
+	}
+
+	var clarification common.Clarification
+	var askedAt time.Time
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, common.ClarificationHeader) {
+			clarification.Questions = common.ParseClarificationQuestions(c.Body)
+			askedAt = c.UpdatedAt
+		}
+	}
+	if len(clarification.Questions) == 0 {
+		return clarification, nil
+	}
+
+	for _, c := range comments {
+		if c.CreatedAt.After(askedAt) && !common.IsPluginComment(c.Body) {
+			clarification.Answers = append(clarification.Answers, c.Body)
+		}
+	}
+	return clarification, nil
+}
+
+// GetCommentBody returns the body of the comment with the header without the header, empty if there is none
+func (gt *Gitea) GetCommentBody(repoOwner, repoName string, pr int, header string) (string, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := gt.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return "", err
+	}
+	return gt.getCommentBodyWithoutHeader(comments, header), nil
+}
+
+func (gt *Gitea) PostSummaryUnderReview(repoOwner, repoName string, pr int, header string) error {
+	logger.Infof("Summary under update for PR #%d in %s/%s", pr, repoOwner, repoName)
+
+	commentBody, err := gt.GetCommentBody(repoOwner, repoName, pr, header)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
+		logger.Errorf(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+
+	underReviewStr := common.Summary{}.InitiatedString(gt.GetProvider())
+	if err := gt.PostSummary(repoOwner, repoName, pr, header, underReviewStr+"\n\n"+commentBody); err != nil {
+		errMsg := fmt.Sprintf("Failed to post summary under review: %v", err)
+		logger.Error(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+	return nil
+}
+
+// PostSummary adds or updates a summary comment on a Gitea pull request
+func (gt *Gitea) PostSummary(repoOwner, repoName string, pr int, header, body string) error {
+	logger.Infof("Posting summary to PR #%d in %s/%s", pr, repoOwner, repoName)
+
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	logger.Debug("Fetching existing comments to check for duplicates")
+	comments, err := gt.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
+		logger.Errorf(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+
+	commentID := gt.getComment(comments, header)
+	parts := common.SplitComment(header, body, gt.Capabilities().MaxCommentLength)
+	if len(parts) > 1 {
+		logger.Warnf("The summary is longer than a comment, posting it in %d parts", len(parts))
+	}
+
+	if err := gt.saveComment(ctx, repoOwner, repoName, pr, commentID, parts[0]); err != nil {
+		errMsg := fmt.Sprintf("Failed to post summary: %v", err)
+		logger.Errorf(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+
+	if commentID > 0 {
+		logger.Infof("Updated existing summary comment for PR #%d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsUpdated(1)
+	} else {
+		logger.Infof("Posted new summary comment for PR #%d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsPosted(1)
+	}
+
+	if err := gt.postContinuations(ctx, comments, repoOwner, repoName, pr, header, parts[1:]); err != nil {
+		return err
+	}
+	common.Audit().RecordComment(common.AuditComment{Kind: common.AuditKindSummary, Body: body})
+	return nil
+}
+
+// saveComment updates the comment, or creates a new one when the comment ID is zero
+func (gt *Gitea) saveComment(ctx context.Context, repoOwner, repoName string, pr, commentID int, body string) error {
+	payload := map[string]string{"body": body}
+	if commentID > 0 {
+		logger.Debugf("Updating existing comment with ID: %d", commentID)
+		_, err := gt.do(ctx, "PATCH", fmt.Sprintf("%s/issues/comments/%d", gt.repoURL(repoOwner, repoName), commentID), payload)
+		return err
+	}
+
+	logger.Debug("Creating new comment")
+	_, err := gt.do(ctx, "POST", fmt.Sprintf("%s/issues/%d/comments", gt.repoURL(repoOwner, repoName), pr), payload)
+	return err
+}
+
+// postContinuations posts the parts of a split comment after the first one,
+// updating the parts posted by the previous run and deleting the ones no longer needed
+func (gt *Gitea) postContinuations(ctx context.Context, comments []giteaComment, repoOwner, repoName string, pr int, header string, continuations []string) error {
+	existing := map[int]int{}
+	for _, c := range comments {
+		if part, ok := common.ContinuationPart(c.Body, header); ok {
+			existing[part] = c.ID
+		}
+	}
+
+	for idx, body := range continuations {
+		part := idx + 2
+		commentID := existing[part]
+		delete(existing, part)
+		if err := gt.saveComment(ctx, repoOwner, repoName, pr, commentID, body); err != nil {
+			return err
+		}
+		if commentID > 0 {
+			common.Report().CommentsUpdated(1)
+		} else {
+			common.Report().CommentsPosted(1)
+		}
+	}
+
+	for part, commentID := range existing {
+		logger.Debugf("Deleting part %d of the summary, the summary got shorter", part)
+		if err := gt.deleteComment(ctx, repoOwner, repoName, commentID); err != nil {
+			logger.Warnf("Failed to delete part %d of the previous summary: %v", part, err)
+		}
+	}
+	return nil
+}
+
+// SaveComment adds or updates the comment with the header, unlike the summary it is not recorded in the report
+func (gt *Gitea) SaveComment(repoOwner, repoName string, pr int, header, body string) error {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := gt.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return err
+	}
+	return gt.saveComment(ctx, repoOwner, repoName, pr, gt.getComment(comments, header), body)
+}
+
+// DeleteComment deletes every comment with the header
+func (gt *Gitea) DeleteComment(repoOwner, repoName string, pr int, header string) error {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := gt.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, header) {
+			if err := gt.deleteComment(ctx, repoOwner, repoName, c.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteComment deletes a comment of the pull request
+func (gt *Gitea) deleteComment(ctx context.Context, repoOwner, repoName string, commentID int) error {
+	_, err := gt.do(ctx, "DELETE", fmt.Sprintf("%s/issues/comments/%d", gt.repoURL(repoOwner, repoName), commentID), nil)
+	return err
+}
+
+// PostLineFeedback posts the line comments in a review of the Gitea pull request
+func (gt *Gitea) PostLineFeedback(client *git.Client, repoOwner, repoName string, pr int, commitHash string, lineFeedback common.LineLevelFeedback) error {
+	logger.Infof("Posting line feedback to PR #%d in %s/%s, commit: %s", pr, repoOwner, repoName, commitHash)
+
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	logger.Debug("Getting existing review comments")
+	existingComments, err := gt.GetReviewRequestComments(repoOwner, repoName, pr)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to get existing review comments: %v", err)
+		logger.Errorf(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+	recordDismissedFindings(existingComments)
+
+	reviewComments := []giteaReviewComment{}
+	reviewAudits := []common.AuditComment{}
+	fileComments := []common.LineLevel{}
+
+	logger.Infof("Processing %d line feedback items", len(lineFeedback.GetLineFeedback()))
+	for _, ll := range lineFeedback.GetLineFeedback() {
+		skip := false
+
+		if ll.File == "" || (ll.LineNumber <= 0 && !ll.IsFileLevel()) {
+			logger.Warnf("Skipping invalid line feedback - file: %s, line: %d", ll.File, ll.LineNumber)
+			continue
+		}
+
+		if IsDismissedFinding(existingComments, ll) {
+			logger.Infof("Skipping dismissed finding for file: %s, line: %d", ll.File, ll.LineNumber)
+			common.Report().CommentsSkipped(1)
+			continue
+		}
+
+		// File comments can't be part of a Gitea review, they are posted as pull request comments
+		if ll.IsFileLevel() {
+			if IsPostedFileComment(existingComments, ll) {
+				logger.Infof("Skipping existing file comment for file: %s", ll.File)
+				common.Report().CommentsSkipped(1)
+				continue
+			}
+			fileComments = append(fileComments, ll)
+			continue
+		}
+
+		logger.Debugf("Getting blame for file: %s, line: %d", ll.File, ll.LineNumber)
+		blame, err := client.GetBlameForFileLine(commitHash, ll.File, ll.LineNumber)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to get blame for line: %v", err)
+			logger.Errorf(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+
+		for _, existingComment := range existingComments {
+			if ll.File == existingComment.File &&
+				ll.LineNumber >= existingComment.LineNumber && ll.LastLineNumber <= existingComment.LastLineNumber &&
+				git.SameCommit(blame, existingComment.CommitHash) {
+				logger.Infof("Skipping existing comment for file: %s, line: %d", ll.File, ll.LineNumber)
+				skip = true
+				break
+			}
+		}
+
+		// The blame of the posted findings is stale after a rebase, they are matched by their fingerprint instead
+		if !skip && lineFeedback.HistoryRewritten && IsPostedFinding(existingComments, ll) {
+			logger.Infof("Skipping finding posted before the history was rewritten for file: %s, line: %d", ll.File, ll.LineNumber)
+			skip = true
+		}
+
+		if skip {
+			common.Report().CommentsSkipped(1)
+			continue
+		}
+
+		position := ll.LineNumber
+		if ll.IsMultiline() {
+			position = ll.LastLineNumber
+		}
+		body := ll.String(gt.GetProvider(), client, commitHash)
+		reviewComments = append(reviewComments, giteaReviewComment{
+			Path:        ll.File,
+			Body:        body,
+			NewPosition: position,
+		})
+		reviewAudits = append(reviewAudits, common.NewLineAuditComment(ll, body))
+	}
+
+	nitpickCommentsByFile, err := ProcessLineFeedbackItems(gt.GetProvider(), client, commitHash, existingComments, lineFeedback)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to process line feedback items: %v", err)
+		logger.Errorf(errMsg)
+		return errors.New(errMsg)
+	}
+
+	permalinks := common.Permalinks{RepoURL: gt.GetRepositoryURL(repoOwner, repoName), CommitHash: commitHash}
+	nitpickComments := FormatNitpickComments(gt.GetProvider(), permalinks, nitpickCommentsByFile)
+
+	if len(reviewComments) > 0 || len(nitpickComments) > 0 {
+		overallReviewStr := FormatOverallReview(gt.GetProvider(), len(reviewComments)+len(fileComments), nitpickComments)
+		review := map[string]any{
+			"commit_id": commitHash,
+			"body":      overallReviewStr,
+			"event":     "COMMENT",
+			"comments":  reviewComments,
+		}
+		if _, err := gt.do(ctx, "POST", fmt.Sprintf("%s/pulls/%d/reviews", gt.repoURL(repoOwner, repoName), pr), review); err != nil {
+			errMsg := fmt.Sprintf("Failed to post line feedback: %v", err)
+			logger.Error(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		logger.Infof("Posted line feedback for PR %d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsPosted(len(reviewComments))
+		common.Audit().RecordComment(common.AuditComment{Kind: common.AuditKindReview, Body: overallReviewStr})
+		for _, audit := range reviewAudits {
+			common.Audit().RecordComment(audit)
+		}
+	}
+
+	for _, ll := range fileComments {
+		body := ll.String(gt.GetProvider(), client, commitHash)
+		if err := gt.saveComment(ctx, repoOwner, repoName, pr, 0, body); err != nil {
+			errMsg := fmt.Sprintf("Failed to post file comment for %s: %v", ll.File, err)
+			logger.Error(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		common.Report().CommentsPosted(1)
+		common.Audit().RecordComment(common.NewLineAuditComment(ll, body))
+	}
+
+	return nil
+}
+
+// GetReviewRequestComments retrieves the findings posted on the pull request, the line comments of the reviews
+// and the file comments posted as pull request comments.
+// Gitea review comments have no replies, answers are comments on the same line in a later review.
+func (gt *Gitea) GetReviewRequestComments(repoOwner, repoName string, pr int) ([]common.LineLevel, error) {
+	ctx, cancel := gt.CreateTimeoutContext()
+	defer cancel()
+
+	body, err := gt.do(ctx, "GET", fmt.Sprintf("%s/pulls/%d/reviews?limit=%d", gt.repoURL(repoOwner, repoName), pr, giteaPageSize), nil)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to list reviews: %v", err)
+		logger.Errorf(errMsg)
+		return nil, common.WrapError(errMsg, err)
+	}
+	var reviews []struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &reviews); err != nil {
+		errMsg := fmt.Sprintf("Failed to decode reviews: %v", err)
+		logger.Errorf(errMsg)
+		return nil, common.WrapError(errMsg, err)
+	}
+
+	var reviewComments []giteaComment
+	for _, review := range reviews {
+		body, err := gt.do(ctx, "GET", fmt.Sprintf("%s/pulls/%d/reviews/%d/comments", gt.repoURL(repoOwner, repoName), pr, review.ID), nil)
+		if err != nil {
+			logger.Errorf("Failed to list review comments: %v", err)
+			continue
+		}
+		var comments []giteaComment
+		if err := json.Unmarshal(body, &comments); err != nil {
+			logger.Errorf("Failed to decode review comments: %v", err)
+			continue
+		}
+		reviewComments = append(reviewComments, comments...)
+	}
+
+	// Collect the lines with a comment dismissing the finding
+	dismissedLines := make(map[string]bool)
+	for _, comment := range reviewComments {
+		if !common.IsPluginComment(comment.Body) && common.IsDismissalReply(comment.Body) {
+			dismissedLines[fmt.Sprintf("%s:%d", comment.Path, comment.OriginalPosition)] = true
+		}
+	}
+
+	lineReviews := []common.LineLevel{}
+	for _, comment := range reviewComments {
+		ll, ok := parseGiteaFinding(comment.Body)
+		if !ok {
+			continue
+		}
+		ll.Dismissed = comment.Resolver != nil || dismissedLines[fmt.Sprintf("%s:%d", comment.Path, comment.OriginalPosition)]
+		lineReviews = append(lineReviews, ll)
+	}
+
+	comments, err := gt.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
+		logger.Errorf(errMsg)
+		return nil, common.WrapError(errMsg, err)
+	}
+	for _, comment := range comments {
+		if ll, ok := parseGiteaFinding(comment.Body); ok && ll.LineNumber == 0 {
+			lineReviews = append(lineReviews, ll)
+		}
+	}
+
+	return lineReviews, nil
+}
+
+// parseGiteaFinding parses the header of a posted finding: the file, the line range, the blame and the fingerprint
+func parseGiteaFinding(body string) (common.LineLevel, bool) {
+	lines := strings.Split(body, "\n")
+	if len(lines) < 2 || !strings.Contains(lines[0], "bitrise-plugin-ai-reviewer") {
+		return common.LineLevel{}, false
+	}
+
+	parts := strings.Split(lines[0], ":")
+	if len(parts) < 4 {
+		return common.LineLevel{}, false
+	}
+
+	var firstLine, lastLine int
+	first, last, multiline := strings.Cut(strings.TrimSpace(parts[2]), "-")
+	if _, err := fmt.Sscanf(first, "%d", &firstLine); err != nil {
+		return common.LineLevel{}, false
+	}
+	lastLine = firstLine
+	if multiline {
+		if _, err := fmt.Sscanf(last, "%d", &lastLine); err != nil {
+			return common.LineLevel{}, false
+		}
+	}
+
+	fingerprint := ""
+	if len(parts) > 4 {
+		fingerprint = strings.TrimSpace(parts[4])
+	}
+
+	return common.LineLevel{
+		File:           strings.TrimSpace(parts[1]),
+		LineNumber:     firstLine,
+		LastLineNumber: lastLine,
+		CommitHash:     strings.TrimSpace(strings.Split(strings.TrimSpace(parts[3]), " ")[0]),
+		Body:           strings.Join(lines[1:], "\n"),
+		Fingerprint:    fingerprint,
+	}, true
+}
@@ -0,0 +1,150 @@
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+const giteaTestBlame = `1111111111111111111111111111111111111111 1 1 3
+author Alice
+summary Initial commit
+filename main.go
+	package main
+1111111111111111111111111111111111111111 2 2
+
+1111111111111111111111111111111111111111 3 3
+	func main() {}
+`
+
+// blameRunner answers git blame of main.go at the head commit, and fails the other commands
+type blameRunner struct{}
+
+func (blameRunner) Run(name string, args ...string) (string, error) {
+	if strings.Join(args, " ") == "blame --porcelain head -- main.go" {
+		return giteaTestBlame, nil
+	}
+	return "", errors.New("exit status 1")
+}
+
+// newGiteaTestServer serves the reviews and comments of pull request 1 of owner/repo, and records the posted reviews
+func newGiteaTestServer(t *testing.T, reviewComments string, posted *[]map[string]any) *Gitea {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/repos/owner/repo/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token gitea-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `[{"id": 7}]`)
+	})
+	mux.HandleFunc("GET /api/v1/repos/owner/repo/pulls/1/reviews/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, reviewComments)
+	})
+	mux.HandleFunc("GET /api/v1/repos/owner/repo/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("POST /api/v1/repos/owner/repo/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		review := map[string]any{}
+		if err := json.Unmarshal(body, &review); err != nil {
+			t.Errorf("Failed to decode the posted review: %v", err)
+		}
+		*posted = append(*posted, review)
+		fmt.Fprint(w, `{"id": 8}`)
+	})
+	mux.HandleFunc("GET /api/v1/repos/owner/repo/raw/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.EscapedPath())
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	t.Setenv(common.CredentialGitea.Env, "gitea-token")
+	reviewer, err := NewGitea(WithBaseURL(server.URL + "/api/v1"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return reviewer.(*Gitea)
+}
+
+func TestGiteaPostLineFeedback(t *testing.T) {
+	var posted []map[string]any
+	gitea := newGiteaTestServer(t, `[]`, &posted)
+
+	feedback := common.LineLevelFeedback{Lines: []common.LineLevel{
+		{File: "main.go", Line: "package main\n\nfunc main() {}", LineNumber: 1, LastLineNumber: 3, Category: common.CategoryBug, Severity: "high", Body: "The main function is empty"},
+	}}
+	if err := gitea.PostLineFeedback(git.NewClient(blameRunner{}), "owner", "repo", 1, "head", feedback); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(posted) != 1 {
+		t.Fatalf("Expected a single review, got %d", len(posted))
+	}
+	if posted[0]["commit_id"] != "head" || posted[0]["event"] != "COMMENT" {
+		t.Errorf("Expected a comment review of the head commit, got %v", posted[0])
+	}
+	comments, _ := posted[0]["comments"].([]any)
+	if len(comments) != 1 {
+		t.Fatalf("Expected a single line comment, got %v", posted[0]["comments"])
+	}
+	comment := comments[0].(map[string]any)
+	// Gitea comments have no line ranges, the comment is anchored to the last line of the range
+	if comment["path"] != "main.go" || comment["new_position"] != float64(3) {
+		t.Errorf("Expected the comment on the last line of main.go, got %v", comment)
+	}
+	if body, _ := comment["body"].(string); !strings.HasPrefix(body, "[bitrise-plugin-ai-reviewer]: main.go:1-3:1111111111111111111111111111111111111111:") {
+		t.Errorf("Expected the header of the finding, got %q", body)
+	}
+}
+
+func TestGiteaGetReviewRequestComments(t *testing.T) {
+	var posted []map[string]any
+	reviewComments := `[
+		{"id": 1, "path": "main.go", "original_position": 3, "body": "[bitrise-plugin-ai-reviewer]: main.go:1-3:1111111:fp-empty\nThe main function is empty"},
+		{"id": 2, "path": "main.go", "original_position": 3, "body": "Won't fix, it is a placeholder"},
+		{"id": 3, "path": "main.go", "original_position": 1, "body": "[bitrise-plugin-ai-reviewer]: main.go:1:1111111:fp-package\nRename the package"},
+		{"id": 4, "path": "main.go", "original_position": 1, "body": "This is not an issue I can ignore"}
+	]`
+	gitea := newGiteaTestServer(t, reviewComments, &posted)
+
+	findings, err := gitea.GetReviewRequestComments("owner", "repo", 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected the two posted findings, got %+v", findings)
+	}
+
+	empty, pkg := findings[0], findings[1]
+	if empty.File != "main.go" || empty.LineNumber != 1 || empty.LastLineNumber != 3 || empty.Fingerprint != "fp-empty" || empty.CommitHash != "1111111" {
+		t.Errorf("Unexpected finding %+v", empty)
+	}
+	if !empty.Dismissed {
+		t.Error("Expected the finding with a dismissal reply on its line to be dismissed")
+	}
+	if pkg.LineNumber != 1 || pkg.LastLineNumber != 1 || pkg.Dismissed {
+		t.Errorf("Expected the single line finding not to be dismissed, got %+v", pkg)
+	}
+}
+
+func TestGiteaGetRepositoryFile(t *testing.T) {
+	var posted []map[string]any
+	gitea := newGiteaTestServer(t, `[]`, &posted)
+
+	// The segments of the path are escaped, they can't add a query or a fragment to the URL
+	content, err := gitea.GetRepositoryFile("owner", "repo", "/docs/api spec?.md")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if content != "/api/v1/repos/owner/repo/raw/docs/api%20spec%3F.md" {
+		t.Errorf("Expected the escaped path, got %q", content)
+	}
+}
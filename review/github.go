@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
@@ -57,19 +59,19 @@ func NewGitHub(opts ...Option) (Reviewer, error) {
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to join API URL path: %v", err)
 			logger.Errorf(errMsg)
-			return nil, errors.New(errMsg)
+			return nil, common.WrapError(errMsg, err)
 		}
 		uploadsURL, err := url.JoinPath(gh.BaseURL, "uploads")
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to join uploads URL path: %v", err)
 			logger.Errorf(errMsg)
-			return nil, errors.New(errMsg)
+			return nil, common.WrapError(errMsg, err)
 		}
 		client, err := github.NewEnterpriseClient(apiURL, uploadsURL, tc)
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to create GitHub Enterprise client: %v", err)
 			logger.Error(errMsg)
-			return nil, errors.New(errMsg)
+			return nil, common.WrapError(errMsg, err)
 		}
 		gh.client = client
 	} else {
@@ -85,8 +87,266 @@ func (gh *GitHub) GetProvider() string {
 	return ProviderGitHub
 }
 
-func (gh *GitHub) SupportCollapsibleMarkdown() bool {
-	return true
+// GetRepositoryURL returns the web URL of the repository
+func (gh *GitHub) GetRepositoryURL(repoOwner, repoName string) string {
+	serverURL := "https://github.com"
+	if gh.BaseURL != "" {
+		serverURL = strings.TrimSuffix(gh.BaseURL, "/")
+	}
+	return fmt.Sprintf("%s/%s/%s", serverURL, repoOwner, repoName)
+}
+
+// GetRepositoryFile returns the content of a file on the default branch of a repository
+func (gh *GitHub) GetRepositoryFile(repoOwner, repoName, path string) (string, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	fileContent, _, _, err := gh.client.Repositories.GetContents(ctx, repoOwner, repoName, path, nil)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to get %s from %s/%s: %v", path, repoOwner, repoName, err)
+		logger.Error(errMsg)
+		return "", common.WrapError(errMsg, gh.apiError(err))
+	}
+	if fileContent == nil {
+		return "", fmt.Errorf("%s in %s/%s is a directory", path, repoOwner, repoName)
+	}
+
+	return fileContent.GetContent()
+}
+
+// GetPullRequestDiff returns the diff of the pull request from the GitHub API, without the need of the git history
+func (gh *GitHub) GetPullRequestDiff(repoOwner, repoName string, pr int) (string, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	diff, _, err := gh.client.PullRequests.GetRaw(ctx, repoOwner, repoName, pr, github.RawOptions{Type: github.Diff})
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to get the diff of PR #%d in %s/%s: %v", pr, repoOwner, repoName, err)
+		logger.Error(errMsg)
+		return "", common.WrapError(errMsg, gh.apiError(err))
+	}
+
+	return diff, nil
+}
+
+// CheckAuth validates the API token by getting the authenticated user
+func (gh *GitHub) CheckAuth() error {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	if _, _, err := gh.client.Users.Get(ctx, ""); err != nil {
+		return gh.apiError(err)
+	}
+	return nil
+}
+
+// SearchMergedPullRequests searches the merged pull requests of the repository with the issue search API
+func (gh *GitHub) SearchMergedPullRequests(repoOwner, repoName string, keywords []string) ([]common.HistoryPullRequest, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	terms := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		terms = append(terms, strconv.Quote(keyword))
+	}
+	query := fmt.Sprintf("repo:%s/%s is:pr is:merged %s", repoOwner, repoName, strings.Join(terms, " OR "))
+
+	result, _, err := gh.client.Search.Issues(ctx, query, &github.SearchOptions{
+		Sort:        "updated",
+		ListOptions: github.ListOptions{PerPage: common.MaxHistoryResults},
+	})
+	if err != nil {
+		return nil, common.WrapError("failed to search pull requests", gh.apiError(err))
+	}
+
+	pullRequests := make([]common.HistoryPullRequest, 0, len(result.Issues))
+	for _, issue := range result.Issues {
+		pullRequests = append(pullRequests, common.HistoryPullRequest{
+			Number:      issue.GetNumber(),
+			Title:       issue.GetTitle(),
+			Description: issue.GetBody(),
+			URL:         issue.GetHTMLURL(),
+			// Merged pull requests are closed by the merge
+			MergedAt: issue.GetClosedAt(),
+		})
+	}
+	return pullRequests, nil
+}
+
+// CheckWriteAccess detects fork pull requests, and the permissions of classic tokens from their scopes and the role of their user
+func (gh *GitHub) CheckWriteAccess(repoOwner, repoName string, pr int) (common.WriteAccess, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	prDetails, _, err := gh.client.PullRequests.Get(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return common.WriteAccess{}, common.WrapError("failed to get pull request details", gh.apiError(err))
+	}
+	repository, resp, err := gh.client.Repositories.Get(ctx, repoOwner, repoName)
+	if err != nil {
+		return common.WriteAccess{}, common.WrapError("failed to get repository", gh.apiError(err))
+	}
+
+	header := resp.Header.Get(oauthScopesHeader)
+	// The head repository of a deleted fork is missing
+	access := common.WriteAccess{
+		Fork:        prDetails.GetHead().GetRepo().GetFullName() != prDetails.GetBase().GetRepo().GetFullName(),
+		Permissions: githubPermissions(header, repository.GetPrivate(), repository.Permissions),
+	}
+	access.CanWrite = access.Granted(common.PermissionComment)
+	if !access.CanWrite {
+		scopes := []string{"repo"}
+		if !repository.GetPrivate() {
+			scopes = append(scopes, "public_repo")
+		}
+		access.Reason = fmt.Sprintf("the token has the %q scopes, commenting needs %s", header, strings.Join(scopes, " or "))
+	}
+	return access, nil
+}
+
+// SetCommitStatus creates the status of the commit, GitHub shows the latest status of the context
+func (gh *GitHub) SetCommitStatus(repoOwner, repoName, commitHash string, status common.CommitStatus) error {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	repoStatus := &github.RepoStatus{
+		State:       github.String(status.State),
+		Context:     github.String(common.CommitStatusContext),
+		Description: github.String(status.Description),
+	}
+	if status.TargetURL != "" {
+		repoStatus.TargetURL = github.String(status.TargetURL)
+	}
+	if _, _, err := gh.client.Repositories.CreateStatus(ctx, repoOwner, repoName, commitHash, repoStatus); err != nil {
+		return common.WrapError("failed to set commit status", gh.apiError(err))
+	}
+	return nil
+}
+
+// GetForcePushes returns the force pushes of the head branch from the timeline of the pull request.
+// go-github doesn't decode the force push events, the timeline is read with a plain request.
+func (gh *GitHub) GetForcePushes(repoOwner, repoName string, pr int) ([]common.ForcePush, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	var forcePushes []common.ForcePush
+	for page := 1; page > 0; {
+		req, err := gh.client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/issues/%d/timeline?per_page=100&page=%d", repoOwner, repoName, pr, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var events []struct {
+			Event     string    `json:"event"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		resp, err := gh.client.Do(ctx, req, &events)
+		if err != nil {
+			return nil, common.WrapError("failed to list the timeline events", gh.apiError(err))
+		}
+		for _, event := range events {
+			if event.Event == "head_ref_force_pushed" {
+				forcePushes = append(forcePushes, common.ForcePush{At: event.CreatedAt})
+			}
+		}
+		page = resp.NextPage
+	}
+	return forcePushes, nil
+}
+
+// MigrateComments reposts the outdated line comments of the reviewer at the line of their code at the commit.
+// The commented code is the last line of the diff hunk of the comment. Comments with replies are kept in place
+// to keep the conversation, the others are deleted after they are reposted.
+func (gh *GitHub) MigrateComments(client *git.Client, repoOwner, repoName string, pr int, commitHash string) (int, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	comments, _, err := gh.client.PullRequests.ListComments(ctx, repoOwner, repoName, pr, &github.PullRequestListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return 0, common.WrapError("failed to list review comments", gh.apiError(err))
+	}
+
+	replied := map[int64]bool{}
+	for _, comment := range comments {
+		if comment.InReplyTo != nil {
+			replied[comment.GetInReplyTo()] = true
+		}
+	}
+
+	moved := 0
+	for _, comment := range comments {
+		// Comments on lines of the current diff are not outdated
+		if comment.InReplyTo != nil || comment.Line != nil || !strings.HasPrefix(comment.GetBody(), "[bitrise-plugin-ai-reviewer]: ") {
+			continue
+		}
+
+		content, err := client.GetFileContent(commitHash, comment.GetPath())
+		if err != nil {
+			logger.Debugf("Not moving the comment %d, %s is not in the commit: %v", comment.GetID(), comment.GetPath(), err)
+			continue
+		}
+		lastLine := common.RelocateLine(content, common.CommentedLine(comment.GetDiffHunk()), comment.GetOriginalLine())
+		if lastLine == 0 {
+			logger.Infof("The commented code of %s:%d changed, the comment is not moved", comment.GetPath(), comment.GetOriginalLine())
+			continue
+		}
+		firstLine := lastLine
+		if comment.OriginalStartLine != nil {
+			firstLine = max(lastLine-(comment.GetOriginalLine()-comment.GetOriginalStartLine()), 1)
+		}
+
+		blame, err := client.GetBlameForFileLine(commitHash, comment.GetPath(), firstLine)
+		if err != nil {
+			blame = "unknown"
+		}
+		movedComment := &github.PullRequestComment{
+			Body:     github.String(common.MoveCommentHeader(comment.GetBody(), firstLine, lastLine, blame)),
+			CommitID: github.String(commitHash),
+			Path:     github.String(comment.GetPath()),
+			Line:     github.Int(lastLine),
+			Side:     github.String("RIGHT"),
+		}
+		if firstLine < lastLine {
+			movedComment.StartLine = github.Int(firstLine)
+			movedComment.StartSide = github.String("RIGHT")
+		}
+		// Lines outside of the diff of the pull request can't be commented
+		if _, _, err := gh.client.PullRequests.CreateComment(ctx, repoOwner, repoName, pr, movedComment); err != nil {
+			logger.Infof("Failed to move the comment of %s:%d to line %d: %v", comment.GetPath(), comment.GetOriginalLine(), lastLine, err)
+			continue
+		}
+		logger.Infof("Moved the comment of %s:%d to line %d", comment.GetPath(), comment.GetOriginalLine(), lastLine)
+		moved++
+
+		if !replied[comment.GetID()] {
+			if _, err := gh.client.PullRequests.DeleteComment(ctx, repoOwner, repoName, comment.GetID()); err != nil {
+				logger.Warnf("Failed to delete the moved comment %d: %v", comment.GetID(), err)
+			}
+		}
+	}
+	common.Report().CommentsUpdated(moved)
+	return moved, nil
+}
+
+// apiError converts a failed GitHub API call into a typed error
+func (gh *GitHub) apiError(err error) error {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	var responseErr *github.ErrorResponse
+
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseRateLimitErr):
+		return &common.APIError{
+			Kind:       common.ErrorKindRateLimit,
+			Service:    "GitHub",
+			StatusCode: http.StatusForbidden,
+			Err:        err,
+		}
+	case errors.As(err, &responseErr) && responseErr.Response != nil:
+		return common.NewAPIError("GitHub", responseErr.Response.StatusCode, err)
+	}
+	return err
 }
 
 func (gh *GitHub) GetPullRequestDetails(repoOwner, repoName string, pr int) (common.PullRequest, error) {
@@ -104,12 +364,16 @@ func (gh *GitHub) GetPullRequestDetails(repoOwner, repoName string, pr int) (com
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to get pull request details: %v", err)
 		logger.Error(errMsg)
-		return common.PullRequest{}, errors.New(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, gh.apiError(err))
 	}
 
 	owner := ""
 	if prDetails.GetUser() != nil {
 		owner = prDetails.GetUser().GetName()
+		if owner == "" {
+			// Bots and users without a public name only have a login
+			owner = prDetails.GetUser().GetLogin()
+		}
 	}
 
 	labels := make([]common.Label, 0)
@@ -123,7 +387,7 @@ func (gh *GitHub) GetPullRequestDetails(repoOwner, repoName string, pr int) (com
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to list pull request commits: %v", err)
 		logger.Error(errMsg)
-		return common.PullRequest{}, errors.New(errMsg)
+		return common.PullRequest{}, common.WrapError(errMsg, gh.apiError(err))
 	}
 
 	commits := make([]common.Commit, 0)
@@ -132,6 +396,7 @@ func (gh *GitHub) GetPullRequestDetails(repoOwner, repoName string, pr int) (com
 			CommitHash: commit.GetSHA(),
 			Author:     commit.GetCommit().GetAuthor().GetName(),
 			Message:    commit.GetCommit().GetMessage(),
+			CreatedAt:  commit.GetCommit().GetAuthor().GetDate(),
 		})
 	}
 
@@ -141,8 +406,8 @@ func (gh *GitHub) GetPullRequestDetails(repoOwner, repoName string, pr int) (com
 		Body:       prDetails.GetBody(),
 		HeadBranch: prDetails.Head.GetRef(),
 		BaseBranch: prDetails.Base.GetRef(),
-		CreatedAt:  prDetails.GetCreatedAt().String(),
-		UpdatedAt:  prDetails.GetUpdatedAt().String(),
+		CreatedAt:  prDetails.GetCreatedAt(),
+		UpdatedAt:  prDetails.GetUpdatedAt(),
 		Author:     owner,
 		Mergeable:  prDetails.GetMergeable(),
 		Merged:     prDetails.GetMerged(),
@@ -207,6 +472,51 @@ func (gh *GitHub) ListComments(repoOwner, repoName string, pr int) ([]string, er
 	return commentBodies, nil
 }
 
+// GetClarification returns the questions asked from the author and the comments posted since the questions were last updated.
+// Pull request comments are not threaded on GitHub, so every later comment not posted by the plugin is an answer.
+func (gh *GitHub) GetClarification(repoOwner, repoName string, pr int) (common.Clarification, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := gh.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
+		logger.Errorf(errMsg)
+		return common.Clarification{}, common.WrapError(errMsg, gh.apiError(err))
+	}
+
+	var clarification common.Clarification
+	var askedAt time.Time
+	for _, c := range comments {
+		if strings.HasPrefix(c.GetBody(), common.ClarificationHeader) {
+			clarification.Questions = common.ParseClarificationQuestions(c.GetBody())
+			askedAt = c.GetUpdatedAt()
+		}
+	}
+	if len(clarification.Questions) == 0 {
+		return clarification, nil
+	}
+
+	for _, c := range comments {
+		if c.GetCreatedAt().After(askedAt) && !common.IsPluginComment(c.GetBody()) {
+			clarification.Answers = append(clarification.Answers, c.GetBody())
+		}
+	}
+	return clarification, nil
+}
+
+// GetCommentBody returns the body of the comment with the header without the header, empty if there is none
+func (gh *GitHub) GetCommentBody(repoOwner, repoName string, pr int, header string) (string, error) {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := gh.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return "", gh.apiError(err)
+	}
+	return gh.getCommentBodyWithoutHeader(comments, header)
+}
+
 func (gh *GitHub) PostSummaryUnderReview(repoOwner, repoName string, pr int, header string) error {
 	logger.Infof("Summary under update for PR #%d in %s/%s", pr, repoOwner, repoName)
 
@@ -218,14 +528,14 @@ func (gh *GitHub) PostSummaryUnderReview(repoOwner, repoName string, pr int, hea
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, gh.apiError(err))
 	}
 
 	commentBody, err := gh.getCommentBodyWithoutHeader(comments, header)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to check existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	underReviewStr := common.Summary{}.InitiatedString(gh.GetProvider())
@@ -233,7 +543,7 @@ func (gh *GitHub) PostSummaryUnderReview(repoOwner, repoName string, pr int, hea
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to post summary under review: %v", err)
 		logger.Error(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
 
 	return nil
@@ -250,18 +560,23 @@ func (gh *GitHub) PostSummary(repoOwner, repoName string, pr int, header, body s
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, gh.apiError(err))
 	}
 
 	commentID, err := gh.getComment(comments, header)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to check existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
+	}
+
+	parts := common.SplitComment(header, body, gh.Capabilities().MaxCommentLength)
+	if len(parts) > 1 {
+		logger.Warnf("The summary is longer than a comment, posting it in %d parts", len(parts))
 	}
 
 	comment := &github.IssueComment{
-		Body: &body,
+		Body: &parts[0],
 	}
 
 	if commentID > 0 {
@@ -277,9 +592,10 @@ func (gh *GitHub) PostSummary(repoOwner, repoName string, pr int, header, body s
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to update existing summary comment: %v", err)
 			logger.Error(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, gh.apiError(err))
 		}
 		logger.Infof("Updated existing summary comment for PR #%d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsUpdated(1)
 	} else {
 		_, _, err = gh.client.Issues.CreateComment(
 			ctx,
@@ -292,11 +608,98 @@ func (gh *GitHub) PostSummary(repoOwner, repoName string, pr int, header, body s
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to post summary comment: %v", err)
 			logger.Error(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, gh.apiError(err))
 		}
 		logger.Infof("Posted new summary comment for PR %d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsPosted(1)
+	}
+
+	if err := gh.postContinuations(ctx, comments, repoOwner, repoName, pr, header, parts[1:]); err != nil {
+		return err
+	}
+	common.Audit().RecordComment(common.AuditComment{Kind: common.AuditKindSummary, Body: body})
+	return nil
+}
+
+// postContinuations posts the parts of a split comment after the first one,
+// updating the parts posted by the previous run and deleting the ones no longer needed
+func (gh *GitHub) postContinuations(ctx context.Context, comments []*github.IssueComment, repoOwner, repoName string, pr int, header string, continuations []string) error {
+	existing := map[int]int64{}
+	for _, c := range comments {
+		if part, ok := common.ContinuationPart(c.GetBody(), header); ok {
+			existing[part] = c.GetID()
+		}
+	}
+
+	for idx, body := range continuations {
+		part := idx + 2
+		comment := &github.IssueComment{Body: &body}
+		if commentID, ok := existing[part]; ok {
+			delete(existing, part)
+			if _, _, err := gh.client.Issues.EditComment(ctx, repoOwner, repoName, commentID, comment); err != nil {
+				errMsg := fmt.Sprintf("Failed to update part %d of the summary: %v", part, err)
+				logger.Error(errMsg)
+				return common.WrapError(errMsg, gh.apiError(err))
+			}
+			common.Report().CommentsUpdated(1)
+			continue
+		}
+		if _, _, err := gh.client.Issues.CreateComment(ctx, repoOwner, repoName, pr, comment); err != nil {
+			errMsg := fmt.Sprintf("Failed to post part %d of the summary: %v", part, err)
+			logger.Error(errMsg)
+			return common.WrapError(errMsg, gh.apiError(err))
+		}
+		common.Report().CommentsPosted(1)
+	}
+
+	for part, commentID := range existing {
+		logger.Debugf("Deleting part %d of the summary, the summary got shorter", part)
+		if _, err := gh.client.Issues.DeleteComment(ctx, repoOwner, repoName, commentID); err != nil {
+			logger.Warnf("Failed to delete part %d of the previous summary: %v", part, err)
+		}
+	}
+	return nil
+}
+
+// SaveComment adds or updates the comment with the header, unlike the summary it is not recorded in the report
+func (gh *GitHub) SaveComment(repoOwner, repoName string, pr int, header, body string) error {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
+
+	comments, err := gh.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return gh.apiError(err)
+	}
+	commentID, err := gh.getComment(comments, header)
+	if err != nil {
+		return err
+	}
+
+	comment := &github.IssueComment{Body: &body}
+	if commentID > 0 {
+		_, _, err = gh.client.Issues.EditComment(ctx, repoOwner, repoName, commentID, comment)
+	} else {
+		_, _, err = gh.client.Issues.CreateComment(ctx, repoOwner, repoName, pr, comment)
 	}
+	return gh.apiError(err)
+}
+
+// DeleteComment deletes every comment with the header
+func (gh *GitHub) DeleteComment(repoOwner, repoName string, pr int, header string) error {
+	ctx, cancel := gh.CreateTimeoutContext()
+	defer cancel()
 
+	comments, err := gh.getComments(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return gh.apiError(err)
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(c.GetBody(), header) {
+			if _, err := gh.client.Issues.DeleteComment(ctx, repoOwner, repoName, c.GetID()); err != nil {
+				return gh.apiError(err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -312,40 +715,60 @@ func (gh *GitHub) PostLineFeedback(client *git.Client, repoOwner, repoName strin
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to list existing comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, gh.apiError(err))
 	}
 
 	reviewComments := make([]*github.DraftReviewComment, 0)
+	reviewAudits := make([]common.AuditComment, 0)
+	fileComments := make([]common.LineLevel, 0)
 
 	logger.Debug("Getting existing review comments")
 	addedComments, err := gh.GetReviewRequestComments(repoOwner, repoName, pr)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to get existing review comments: %v", err)
 		logger.Errorf(errMsg)
-		return errors.New(errMsg)
+		return common.WrapError(errMsg, err)
 	}
+	recordDismissedFindings(addedComments)
 
 	logger.Infof("Processing %d line feedback items", len(lineFeedback.GetLineFeedback()))
 	for _, ll := range lineFeedback.GetLineFeedback() {
 		skip := false
 
-		if ll.File == "" || ll.LineNumber <= 0 {
+		if ll.File == "" || (ll.LineNumber <= 0 && !ll.IsFileLevel()) {
 			logger.Warnf("Skipping invalid line feedback - file: %s, line: %d", ll.File, ll.LineNumber)
 			continue
 		}
 
+		if IsDismissedFinding(addedComments, ll) {
+			logger.Infof("Skipping dismissed finding for file: %s, line: %d", ll.File, ll.LineNumber)
+			common.Report().CommentsSkipped(1)
+			continue
+		}
+
+		// File comments can't be part of a review, they are posted separately
+		if ll.IsFileLevel() {
+			if IsPostedFileComment(addedComments, ll) {
+				logger.Infof("Skipping existing file comment for file: %s", ll.File)
+				common.Report().CommentsSkipped(1)
+				continue
+			}
+			fileComments = append(fileComments, ll)
+			continue
+		}
+
 		logger.Debugf("Getting blame for file: %s, line: %d", ll.File, ll.LineNumber)
 		blame, err := client.GetBlameForFileLine(commitHash, ll.File, ll.LineNumber)
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to get blame for line: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
 
 		for _, existingComment := range addedComments {
 			if ll.File == existingComment.File &&
 				ll.LineNumber >= existingComment.LineNumber && ll.LastLineNumber <= existingComment.LastLineNumber &&
-				blame == existingComment.CommitHash {
+				git.SameCommit(blame, existingComment.CommitHash) {
 				logger.Infof("Skipping existing comment for file: %s, line: %d", ll.File, ll.LineNumber)
 				logger.Debugf("Existing comment:	line number: %d, last line number: %d, commit hash: %s", existingComment.LineNumber, existingComment.LastLineNumber, existingComment.CommitHash)
 				logger.Debugf("Line feedback: 		line number: %d, last line number: %d, commit hash: %s", ll.LineNumber, ll.LastLineNumber, blame)
@@ -354,7 +777,14 @@ func (gh *GitHub) PostLineFeedback(client *git.Client, repoOwner, repoName strin
 			}
 		}
 
+		// The blame of the posted findings is stale after a rebase, they are matched by their fingerprint instead
+		if !skip && lineFeedback.HistoryRewritten && IsPostedFinding(addedComments, ll) {
+			logger.Infof("Skipping finding posted before the history was rewritten for file: %s, line: %d", ll.File, ll.LineNumber)
+			skip = true
+		}
+
 		if skip {
+			common.Report().CommentsSkipped(1)
 			continue
 		}
 
@@ -362,10 +792,11 @@ func (gh *GitHub) PostLineFeedback(client *git.Client, repoOwner, repoName strin
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to check existing comments: %v", err)
 			logger.Errorf(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, err)
 		}
 
 		if commentID > 0 {
+			common.Report().CommentsSkipped(1)
 			continue
 		}
 
@@ -382,6 +813,7 @@ func (gh *GitHub) PostLineFeedback(client *git.Client, repoOwner, repoName strin
 		}
 
 		reviewComments = append(reviewComments, reviewComment)
+		reviewAudits = append(reviewAudits, common.NewLineAuditComment(ll, reviewBody))
 	}
 
 	// Process nitpick comments
@@ -394,10 +826,11 @@ func (gh *GitHub) PostLineFeedback(client *git.Client, repoOwner, repoName strin
 	}
 
 	// Format nitpick comments for display
-	nitpickComments := FormatNitpickComments(gh.GetProvider(), nitpickCommentsByFile)
+	permalinks := common.Permalinks{RepoURL: gh.GetRepositoryURL(repoOwner, repoName), CommitHash: commitHash}
+	nitpickComments := FormatNitpickComments(gh.GetProvider(), permalinks, nitpickCommentsByFile)
 
 	if len(reviewComments) > 0 || len(nitpickComments) > 0 {
-		overallReviewStr := FormatOverallReview(len(reviewComments), nitpickComments)
+		overallReviewStr := FormatOverallReview(gh.GetProvider(), len(reviewComments)+len(fileComments), nitpickComments)
 		review := &github.PullRequestReviewRequest{
 			CommitID: &commitHash,
 			Body:     &overallReviewStr,
@@ -416,25 +849,67 @@ func (gh *GitHub) PostLineFeedback(client *git.Client, repoOwner, repoName strin
 		if err != nil {
 			errMsg := fmt.Sprintf("Failed to post line feedback: %v", err)
 			logger.Error(errMsg)
-			return errors.New(errMsg)
+			return common.WrapError(errMsg, gh.apiError(err))
 		}
 		logger.Infof("Posted line feedback for PR %d in %s/%s", pr, repoOwner, repoName)
+		common.Report().CommentsPosted(len(reviewComments))
+		common.Audit().RecordComment(common.AuditComment{Kind: common.AuditKindReview, Body: overallReviewStr})
+		for _, audit := range reviewAudits {
+			common.Audit().RecordComment(audit)
+		}
+	}
+
+	for _, ll := range fileComments {
+		body := ll.String(gh.GetProvider(), client, commitHash)
+		if err := gh.postFileComment(ctx, repoOwner, repoName, pr, commitHash, ll.File, body); err != nil {
+			errMsg := fmt.Sprintf("Failed to post file comment for %s: %v", ll.File, err)
+			logger.Error(errMsg)
+			return common.WrapError(errMsg, err)
+		}
+		common.Report().CommentsPosted(1)
+		common.Audit().RecordComment(common.NewLineAuditComment(ll, body))
 	}
 
 	return nil
 }
 
+// postFileComment posts a comment on the whole file, go-github doesn't support the subject_type of file comments
+func (gh *GitHub) postFileComment(ctx context.Context, repoOwner, repoName string, pr int, commitHash, path, body string) error {
+	comment := struct {
+		Body        string `json:"body"`
+		CommitID    string `json:"commit_id"`
+		Path        string `json:"path"`
+		SubjectType string `json:"subject_type"`
+	}{
+		Body:        body,
+		CommitID:    commitHash,
+		Path:        path,
+		SubjectType: "file",
+	}
+
+	req, err := gh.client.NewRequest("POST", fmt.Sprintf("repos/%s/%s/pulls/%d/comments", repoOwner, repoName, pr), comment)
+	if err != nil {
+		return err
+	}
+	if _, err := gh.client.Do(ctx, req, nil); err != nil {
+		return gh.apiError(err)
+	}
+	return nil
+}
+
 func (gh *GitHub) GetReviewRequestComments(repoOwner, repoName string, pr int) ([]common.LineLevel, error) {
 	ctx, cancel := gh.CreateTimeoutContext()
 	defer cancel()
 
 	lineReviews := make([]common.LineLevel, 0)
+	lineReviewIDs := make([]int64, 0)
+	dismissedIDs := make(map[int64]bool)
 
 	reviews, _, err := gh.client.PullRequests.ListReviews(ctx, repoOwner, repoName, pr, nil)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to list reviews: %v", err)
 		logger.Errorf(errMsg)
-		return nil, errors.New(errMsg)
+		return nil, common.WrapError(errMsg, gh.apiError(err))
 	}
 
 	for _, review := range reviews {
@@ -450,8 +925,12 @@ func (gh *GitHub) GetReviewRequestComments(repoOwner, repoName string, pr int) (
 		}
 
 		for _, comment := range comments {
-			// Skip replies to other comments
+			// Skip replies to other comments, but remember if they dismiss the finding
 			if comment.InReplyTo != nil {
+				if common.IsDismissalReply(comment.GetBody()) {
+					logger.Debugf("Comment %d dismissed by reply %d", *comment.InReplyTo, comment.GetID())
+					dismissedIDs[*comment.InReplyTo] = true
+				}
 				logger.Debugf("Skipping reply to another comment: %d", *comment.InReplyTo)
 				continue
 			}
@@ -489,16 +968,31 @@ func (gh *GitHub) GetReviewRequestComments(repoOwner, repoName string, pr int) (
 				blame := strings.TrimSpace(parts[3])
 				blame = strings.TrimSpace(strings.Split(blame, " ")[0])
 
+				fingerprint := ""
+				if len(parts) > 4 {
+					fingerprint = strings.TrimSpace(parts[4])
+				}
+
 				lineReviews = append(lineReviews, common.LineLevel{
 					File:           file,
 					LineNumber:     firstLine,
 					LastLineNumber: lastLine,
 					CommitHash:     blame,
 					Body:           strings.Join(lines[1:], "\n"),
+					Fingerprint:    fingerprint,
+					Dismissed:      comment.GetReactions().GetMinusOne() > 0,
 				})
+				lineReviewIDs = append(lineReviewIDs, comment.GetID())
 			}
 		}
 	}
 
+	// Replies may belong to a later review than the comment they answer
+	for idx, id := range lineReviewIDs {
+		if dismissedIDs[id] {
+			lineReviews[idx].Dismissed = true
+		}
+	}
+
 	return lineReviews, nil
 }
@@ -0,0 +1,66 @@
+package review
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// updateGolden rewrites the golden files with the current output, run `go test ./common/ ./review/ -update` after intended formatting changes
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// assertGolden compares the output with the golden file of the test in testdata/golden
+func assertGolden(t *testing.T, name, output string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".md")
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s, run the tests with -update to create it: %v", path, err)
+	}
+	if output != string(expected) {
+		t.Errorf("Output differs from %s, run the tests with -update if the change is intended.\nExpected:\n%s\nGot:\n%s", path, expected, output)
+	}
+}
+
+func TestFormatOverallReviewGolden(t *testing.T) {
+	defer common.SetStyle(common.StyleRich)
+
+	// A single file keeps the order of the nitpick comments stable
+	nitpicksByFile := map[string][]common.LineLevel{
+		"uploader/retry.go": {
+			{File: "uploader/retry.go", LineNumber: 5, Title: "Naming", Body: "Go constants use mixedCaps.", Category: common.CategoryNitpick},
+			{File: "uploader/retry.go", LineNumber: 12, LastLineNumber: 14, Line: "a\nb\nc", Title: "Comment", Body: "The comment repeats the code.", Category: common.CategoryNitpick},
+		},
+	}
+	permalinks := common.Permalinks{RepoURL: "https://github.com/bitrise-io/uploader", CommitHash: "abc123"}
+
+	tests := []struct {
+		name       string
+		provider   string
+		style      string
+		permalinks common.Permalinks
+		nitpicks   map[string][]common.LineLevel
+	}{
+		{"overall_github", ProviderGitHub, common.StyleRich, permalinks, nitpicksByFile},
+		{"overall_github_plain", ProviderGitHub, common.StylePlain, permalinks, nitpicksByFile},
+		{"overall_github_without_nitpicks", ProviderGitHub, common.StyleRich, permalinks, nil},
+		{"overall_bitbucket", ProviderBitbucket, common.StyleRich, common.Permalinks{}, nitpicksByFile},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			common.SetStyle(test.style)
+			nitpicks := FormatNitpickComments(test.provider, test.permalinks, test.nitpicks)
+			assertGolden(t, test.name, FormatOverallReview(test.provider, 3, nitpicks))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package review
+
+import (
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// lockPollInterval is the time between the checks of a lock held by another run
+var lockPollInterval = 15 * time.Second
+
+// lockSettleTime is the time to wait after posting the lock, for the runs posting it at the same time to see the same holder
+var lockSettleTime = 2 * time.Second
+
+// AcquireReviewLock takes the review lock of the pull request, waiting at most the wait time for the run holding it.
+// Returns false if another run still holds the lock.
+// The lock is the marker comment of the holder. The runs posting it at the same time overwrite each other,
+// and read it back after the settle time: only the holder posting it last gets the lock.
+func AcquireReviewLock(reviewer Reviewer, repoOwner, repoName string, pr int, holder common.LockHolder, wait time.Duration) (bool, error) {
+	deadline := time.Now().Add(wait)
+	posted := false
+	for {
+		body, err := reviewer.GetCommentBody(repoOwner, repoName, pr, common.ReviewLockHeader)
+		if err != nil {
+			return false, common.WrapError("failed to read the review lock", err)
+		}
+
+		current, _ := common.ParseLockHolder(body)
+		switch {
+		case current.RunID == holder.RunID:
+			logger.Debugf("Took the review lock until %s", common.FormatTimestamp(holder.ExpiresAt))
+			return true, nil
+		case posted && current.RunID == "":
+			logger.Warn("The posted review lock can't be read back, reviewing without it")
+			return true, nil
+		case !current.HeldAgainst(holder.RunID, time.Now()):
+			posted = true
+			if err := reviewer.SaveComment(repoOwner, repoName, pr, common.ReviewLockHeader, holder.Body()); err != nil {
+				return false, common.WrapError("failed to post the review lock", err)
+			}
+			time.Sleep(lockSettleTime)
+			continue
+		case time.Now().After(deadline):
+			return false, nil
+		}
+
+		logger.Infof("Another run is reviewing the pull request, waiting for it until %s", common.FormatTimestamp(deadline))
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// ReleaseReviewLock deletes the lock if the run still holds it, the lock of a run taking over an expired lock is kept
+func ReleaseReviewLock(reviewer Reviewer, repoOwner, repoName string, pr int, holder common.LockHolder) {
+	body, err := reviewer.GetCommentBody(repoOwner, repoName, pr, common.ReviewLockHeader)
+	if err != nil {
+		logger.Warnf("Failed to read the review lock, it is released when it expires: %v", err)
+		return
+	}
+	if current, _ := common.ParseLockHolder(body); current.RunID != holder.RunID {
+		logger.Warnf("The review lock expired and was taken by another run, keeping it")
+		return
+	}
+	if err := reviewer.DeleteComment(repoOwner, repoName, pr, common.ReviewLockHeader); err != nil {
+		logger.Warnf("Failed to release the review lock, it is released when it expires: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package review
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// lockReviewer keeps the marker comments in memory, the first comment with a header wins like on the providers
+type lockReviewer struct {
+	Reviewer
+	comments  []string
+	afterSave func()
+}
+
+func (r *lockReviewer) GetCommentBody(repoOwner, repoName string, pr int, header string) (string, error) {
+	for _, comment := range r.comments {
+		if strings.HasPrefix(comment, header) {
+			return strings.TrimSpace(strings.TrimPrefix(comment, header)), nil
+		}
+	}
+	return "", nil
+}
+
+func (r *lockReviewer) SaveComment(repoOwner, repoName string, pr int, header, body string) error {
+	if r.afterSave != nil {
+		defer r.afterSave()
+	}
+	for idx, comment := range r.comments {
+		if strings.HasPrefix(comment, header) {
+			r.comments[idx] = body
+			return nil
+		}
+	}
+	r.comments = append(r.comments, body)
+	return nil
+}
+
+func (r *lockReviewer) DeleteComment(repoOwner, repoName string, pr int, header string) error {
+	r.comments = nil
+	return nil
+}
+
+func TestAcquireReviewLock(t *testing.T) {
+	lockPollInterval, lockSettleTime = 0, 0
+
+	reviewer := &lockReviewer{}
+	first := common.NewLockHolder(time.Hour)
+	if acquired, err := AcquireReviewLock(reviewer, "owner", "repo", 1, first, 0); err != nil || !acquired {
+		t.Fatalf("Expected the free lock to be acquired, got %v, %v", acquired, err)
+	}
+
+	second := common.NewLockHolder(time.Hour)
+	if acquired, err := AcquireReviewLock(reviewer, "owner", "repo", 1, second, 0); err != nil || acquired {
+		t.Errorf("Expected the held lock not to be acquired, got %v, %v", acquired, err)
+	}
+
+	ReleaseReviewLock(reviewer, "owner", "repo", 1, second)
+	if len(reviewer.comments) != 1 {
+		t.Error("Expected the lock of another run to be kept")
+	}
+	ReleaseReviewLock(reviewer, "owner", "repo", 1, first)
+	if acquired, err := AcquireReviewLock(reviewer, "owner", "repo", 1, second, 0); err != nil || !acquired {
+		t.Errorf("Expected the released lock to be acquired, got %v, %v", acquired, err)
+	}
+}
+
+func TestAcquireReviewLockRace(t *testing.T) {
+	lockPollInterval, lockSettleTime = 0, 0
+
+	// Another run reading the free lock at the same time posts its lock right after this one
+	other := common.NewLockHolder(time.Hour)
+	reviewer := &lockReviewer{}
+	reviewer.afterSave = func() {
+		reviewer.afterSave = nil
+		reviewer.comments[0] = other.Body()
+	}
+
+	if acquired, err := AcquireReviewLock(reviewer, "owner", "repo", 1, common.NewLockHolder(time.Hour), 0); err != nil || acquired {
+		t.Errorf("Expected the lock posted last to win, got %v, %v", acquired, err)
+	}
+}
+
+func TestAcquireReviewLockExpired(t *testing.T) {
+	lockPollInterval, lockSettleTime = 0, 0
+
+	reviewer := &lockReviewer{comments: []string{common.NewLockHolder(-time.Minute).Body()}}
+	if acquired, err := AcquireReviewLock(reviewer, "owner", "repo", 1, common.NewLockHolder(time.Hour), 0); err != nil || !acquired {
+		t.Errorf("Expected the expired lock to be taken over, got %v, %v", acquired, err)
+	}
+}
@@ -0,0 +1,80 @@
+package review
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// Option configures a review provider
+type Option func(*BaseReviewer)
+
+// WithAPIToken creates an option to set the API token
+func WithAPIToken(token string) Option {
+	return func(br *BaseReviewer) {
+		br.ApiToken = token
+		logger.Debugf("%s API token configured", br.Provider)
+	}
+}
+
+// WithTimeout creates an option to set the API timeout in seconds
+func WithTimeout(timeout int) Option {
+	return func(br *BaseReviewer) {
+		br.Timeout = timeout
+		logger.Debugf("%s API timeout set to %d seconds", br.Provider, timeout)
+	}
+}
+
+// WithBaseURL creates an option to set the base URL for GitHub Enterprise
+func WithBaseURL(baseURL string) Option {
+	return func(br *BaseReviewer) {
+		br.BaseURL = baseURL
+		logger.Debugf("%s base URL configured: %s", br.Provider, baseURL)
+	}
+}
+
+// OptionType defines the type of option for review providers
+type OptionType string
+
+// Available option types
+const (
+	APITokenOption OptionType = "api_token"
+	TimeoutOption  OptionType = "timeout"
+	BaseURLOption  OptionType = "base_url"
+)
+
+// NewOption creates an option from its type and an untyped value.
+// A value of the wrong type fails the creation of the reviewer instead of being ignored.
+//
+// Deprecated: use the typed With* options instead.
+func NewOption(optionType OptionType, value any) Option {
+	return func(br *BaseReviewer) {
+		var ok bool
+		switch optionType {
+		case APITokenOption:
+			var token string
+			if token, ok = value.(string); ok {
+				WithAPIToken(token)(br)
+			}
+		case TimeoutOption:
+			var timeout int
+			if timeout, ok = value.(int); ok {
+				WithTimeout(timeout)(br)
+			}
+		case BaseURLOption:
+			var baseURL string
+			if baseURL, ok = value.(string); ok {
+				WithBaseURL(baseURL)(br)
+			}
+		default:
+			if br.optionErr == nil {
+				br.optionErr = fmt.Errorf("unknown option %q", optionType)
+			}
+			return
+		}
+
+		if !ok && br.optionErr == nil {
+			br.optionErr = fmt.Errorf("invalid value %v (%T) for option %q", value, value, optionType)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package review
+
+import "testing"
+
+func TestOptions(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "env-token")
+
+	reviewer, err := NewBaseReviewer(ProviderGitHub,
+		WithAPIToken("token"),
+		WithTimeout(120),
+		WithBaseURL("https://github.example.com"),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reviewer.ApiToken != "token" || reviewer.Timeout != 120 || reviewer.BaseURL != "https://github.example.com" {
+		t.Errorf("Expected the options to be applied, got %+v", reviewer)
+	}
+
+	legacy, err := NewBaseReviewer(ProviderGitHub,
+		NewOption(APITokenOption, "token"),
+		NewOption(TimeoutOption, 120),
+		NewOption(BaseURLOption, "https://github.example.com"),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *legacy != *reviewer {
+		t.Errorf("Expected the legacy options to match %+v, got %+v", reviewer, legacy)
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	t.Setenv("BITBUCKET_TOKEN", "env-token")
+
+	reviewer, err := NewBaseReviewer(ProviderBitbucket)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reviewer.ApiToken != "env-token" || reviewer.Timeout != 60 || reviewer.BaseURL != "" {
+		t.Errorf("Expected the defaults, got %+v", reviewer)
+	}
+}
+
+func TestNewOptionInvalidValue(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "env-token")
+
+	if _, err := NewBaseReviewer(ProviderGitHub, NewOption(TimeoutOption, "60")); err == nil {
+		t.Error("Expected an error for a mistyped value")
+	}
+	if _, err := NewBaseReviewer(ProviderGitHub, NewOption("unknown", "value")); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+}
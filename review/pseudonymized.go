@@ -0,0 +1,41 @@
+package review
+
+import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+)
+
+// pseudonymized hides the identity of the authors of the pull request from the LLM.
+// The details of the pull request are pseudonymized, the posted review gets the real names back.
+type pseudonymized struct {
+	Reviewer
+	pseudonyms *common.Pseudonymizer
+}
+
+// NewPseudonymized wraps the reviewer to pseudonymize the authors with the pseudonymizer of the run
+func NewPseudonymized(reviewer Reviewer, pseudonyms *common.Pseudonymizer) Reviewer {
+	return pseudonymized{Reviewer: reviewer, pseudonyms: pseudonyms}
+}
+
+func (p pseudonymized) GetPullRequestDetails(repoOwner, repoName string, pr int) (common.PullRequest, error) {
+	details, err := p.Reviewer.GetPullRequestDetails(repoOwner, repoName, pr)
+	if err != nil {
+		return details, err
+	}
+	return p.pseudonyms.PullRequest(details), nil
+}
+
+func (p pseudonymized) PostSummary(repoOwner, repoName string, pr int, header, body string) error {
+	return p.Reviewer.PostSummary(repoOwner, repoName, pr, header, p.pseudonyms.Restore(body))
+}
+
+func (p pseudonymized) PostLineFeedback(client *git.Client, repoOwner, repoName string, pr int, commitHash string, lineFeedback common.LineLevelFeedback) error {
+	lines := make([]common.LineLevel, len(lineFeedback.Lines))
+	for idx, ll := range lineFeedback.Lines {
+		ll.Title = p.pseudonyms.Restore(ll.Title)
+		ll.Body = p.pseudonyms.Restore(ll.Body)
+		lines[idx] = ll
+	}
+	lineFeedback.Lines = lines
+	return p.Reviewer.PostLineFeedback(client, repoOwner, repoName, pr, commitHash, lineFeedback)
+}
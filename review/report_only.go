@@ -0,0 +1,53 @@
+package review
+
+import (
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/logger"
+)
+
+// reportOnly reads the pull request from the provider, but logs the review instead of posting it.
+// Used for tokens which can't post, like the read-only tokens of fork pull request builds.
+type reportOnly struct {
+	Reviewer
+}
+
+// NewReportOnly wraps the reviewer to log the summary and the findings instead of posting them
+func NewReportOnly(reviewer Reviewer) Reviewer {
+	return reportOnly{Reviewer: reviewer}
+}
+
+func (r reportOnly) PostSummaryUnderReview(repoOwner, repoName string, pr int, header string) error {
+	return nil
+}
+
+func (r reportOnly) PostSummary(repoOwner, repoName string, pr int, header, body string) error {
+	logger.Infof("Report-only mode, the summary is not posted:\n%s", body)
+	return nil
+}
+
+func (r reportOnly) SaveComment(repoOwner, repoName string, pr int, header, body string) error {
+	return nil
+}
+
+func (r reportOnly) DeleteComment(repoOwner, repoName string, pr int, header string) error {
+	return nil
+}
+
+func (r reportOnly) PostLineFeedback(client *git.Client, repoOwner, repoName string, pr int, commitHash string, lineFeedback common.LineLevelFeedback) error {
+	logger.Infof("Report-only mode, %d findings are not posted:", len(lineFeedback.Lines))
+	for _, ll := range lineFeedback.Lines {
+		logger.Infof("  %s:%d: [%s] %s", ll.File, ll.LineNumber, ll.Category, ll.Title)
+	}
+	return nil
+}
+
+func (r reportOnly) SetCommitStatus(repoOwner, repoName, commitHash string, status common.CommitStatus) error {
+	logger.Infof("Report-only mode, the %s commit status is not set: %s, %s", common.CommitStatusContext, status.State, status.Description)
+	return nil
+}
+
+func (r reportOnly) MigrateComments(client *git.Client, repoOwner, repoName string, pr int, commitHash string) (int, error) {
+	logger.Info("Report-only mode, the comments outdated by the rewritten history are not moved")
+	return 0, nil
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
 	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/git"
@@ -15,56 +15,19 @@ import (
 )
 
 const (
-	ProviderGitHub    = "github"
-	ProviderBitbucket = "bitbucket"
+	ProviderGitHub    = common.ProviderGitHub
+	ProviderBitbucket = common.ProviderBitbucket
+	ProviderGitea     = common.ProviderGitea
 )
 
-// OptionType defines the type of option for review providers
-type OptionType string
-
-// Available option types
-const (
-	APITokenOption OptionType = "api_token"
-	TimeoutOption  OptionType = "timeout"
-	BaseURLOption  OptionType = "base_url"
-)
-
-// Option represents a generic configuration option for any review provider
-type Option struct {
-	Type  OptionType
-	Value any
-}
-
-// WithAPIToken creates an option to set the API token
-func WithAPIToken(token string) Option {
-	return Option{
-		Type:  APITokenOption,
-		Value: token,
-	}
-}
-
-// WithTimeout creates an option to set the API timeout in seconds
-func WithTimeout(timeout int) Option {
-	return Option{
-		Type:  TimeoutOption,
-		Value: timeout,
-	}
-}
-
-// WithBaseURL creates an option to set the base URL for GitHub Enterprise
-func WithBaseURL(baseURL string) Option {
-	return Option{
-		Type:  BaseURLOption,
-		Value: baseURL,
-	}
-}
-
 // BaseReviewer contains common fields and methods shared by all reviewer implementations
 type BaseReviewer struct {
 	Provider string
 	ApiToken string
 	Timeout  int
 	BaseURL  string
+
+	optionErr error // First invalid option, reported when the reviewer is created
 }
 
 // NewBaseReviewer creates a new base reviewer with common options applied
@@ -83,23 +46,11 @@ func NewBaseReviewer(provider string, opts ...Option) (*BaseReviewer, error) {
 
 	// Apply additional options
 	for _, opt := range opts {
-		switch opt.Type {
-		case APITokenOption:
-			if token, ok := opt.Value.(string); ok {
-				baseReviewer.ApiToken = token
-				logger.Debugf("%s API token configured", provider)
-			}
-		case TimeoutOption:
-			if timeout, ok := opt.Value.(int); ok {
-				baseReviewer.Timeout = timeout
-				logger.Debugf("%s API timeout set to %d seconds", provider, timeout)
-			}
-		case BaseURLOption:
-			if baseURL, ok := opt.Value.(string); ok {
-				baseReviewer.BaseURL = baseURL
-				logger.Debugf("%s base URL configured: %s", provider, baseURL)
-			}
-		}
+		opt(baseReviewer)
+	}
+	if baseReviewer.optionErr != nil {
+		logger.Error(baseReviewer.optionErr.Error())
+		return nil, baseReviewer.optionErr
 	}
 
 	// Validate required options
@@ -112,19 +63,23 @@ func NewBaseReviewer(provider string, opts ...Option) (*BaseReviewer, error) {
 	return baseReviewer, nil
 }
 
+// Capabilities returns the features of the provider the posted comments can rely on
+func (br *BaseReviewer) Capabilities() common.Capabilities {
+	return common.CapabilitiesOf(br.Provider)
+}
+
 // CreateTimeoutContext creates a timeout context for API calls
 func (br *BaseReviewer) CreateTimeoutContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), time.Duration(br.Timeout)*time.Second)
+	return common.TimeoutContext(br.Timeout)
 }
 
-// FormatNitpickComments formats nitpick comments for display in PR summaries
-func FormatNitpickComments(provider string, nitpickCommentsByFile map[string][]common.LineLevel) []string {
+// FormatNitpickComments formats nitpick comments for display in PR summaries, linking the lines to the reviewed commit
+func FormatNitpickComments(provider string, permalinks common.Permalinks, nitpickCommentsByFile map[string][]common.LineLevel) []string {
+	renderer := common.NewMarkdownRenderer(provider)
 	nitpickComments := []string{}
 
 	for filepath, comments := range nitpickCommentsByFile {
 		content := strings.Builder{}
-		content.WriteString("<details>\n")
-		content.WriteString("<summary>" + filepath + " (" + strconv.Itoa(len(comments)) + ")</summary>\n\n")
 
 		for _, c := range comments {
 			line := fmt.Sprintf("%d", c.LineNumber)
@@ -132,12 +87,12 @@ func FormatNitpickComments(provider string, nitpickCommentsByFile map[string][]c
 				line = line + "-" + fmt.Sprintf("%d", c.LastLineNumber)
 			}
 			content.WriteString("<!-- bitrise-plugin-ai-reviewer: " + filepath + ":" + line + " -->\n")
-			content.WriteString("`" + line + "`: **" + c.Title + "**\n\n")
+			content.WriteString(permalinks.Link(renderer, "`"+line+"`", filepath, c.LineNumber, c.LastLineNumber) + ": **" + c.Title + "**\n\n")
 			content.WriteString(c.Body + "\n\n")
 		}
-		content.WriteString("</details>\n\n")
 
-		nitpickComments = append(nitpickComments, content.String())
+		summary := filepath + " (" + strconv.Itoa(len(comments)) + ")"
+		nitpickComments = append(nitpickComments, renderer.Collapsible(summary, content.String())+"\n\n")
 	}
 
 	return nitpickComments
@@ -158,6 +113,10 @@ func ProcessLineFeedbackItems(
 		if ll.File == "" || ll.LineNumber <= 0 {
 			continue
 		}
+		if IsDismissedFinding(existingComments, ll) {
+			logger.Infof("Skipping dismissed nitpick for file: %s, line: %d", ll.File, ll.LineNumber)
+			continue
+		}
 		if nitpickCommentsByFile[ll.File] == nil {
 			nitpickCommentsByFile[ll.File] = []common.LineLevel{}
 		}
@@ -167,46 +126,130 @@ func ProcessLineFeedbackItems(
 	return nitpickCommentsByFile, nil
 }
 
+// recordDismissedFindings records the number of findings dismissed by the team in the run report
+func recordDismissedFindings(existingComments []common.LineLevel) {
+	dismissed := 0
+	for _, existingComment := range existingComments {
+		if existingComment.Dismissed {
+			dismissed++
+		}
+	}
+	common.Report().SetCommentsDismissed(dismissed)
+}
+
+// IsDismissedFinding checks if the team has dismissed a previously posted finding with the same fingerprint
+func IsDismissedFinding(existingComments []common.LineLevel, ll common.LineLevel) bool {
+	fingerprint := ll.GetFingerprint()
+	for _, existingComment := range existingComments {
+		if existingComment.Dismissed && existingComment.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPostedFileComment checks if the file comment of the finding was already posted, file comments have no line to compare
+func IsPostedFileComment(existingComments []common.LineLevel, ll common.LineLevel) bool {
+	fingerprint := ll.GetFingerprint()
+	for _, existingComment := range existingComments {
+		if existingComment.File == ll.File && existingComment.LineNumber == 0 && existingComment.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPostedFinding checks if the finding was already posted at any line of the file.
+// Used after the history was rewritten, the posted findings can't be matched by the blame of their lines then.
+func IsPostedFinding(existingComments []common.LineLevel, ll common.LineLevel) bool {
+	fingerprint := ll.GetFingerprint()
+	for _, existingComment := range existingComments {
+		if existingComment.File == ll.File && existingComment.LineNumber > 0 && existingComment.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
 // Reviewer defines the interface for code review interactions
 type Reviewer interface {
 	GetProvider() string
+	// Capabilities returns the features of the provider, unsupported ones are degraded by the markdown renderer
+	Capabilities() common.Capabilities
 	GetPullRequestDetails(repoOwner, repoName string, pr int) (common.PullRequest, error)
-	SupportCollapsibleMarkdown() bool
+	GetRepositoryURL(repoOwner, repoName string) string
+	// GetRepositoryFile returns the content of a file on the default branch of a repository
+	GetRepositoryFile(repoOwner, repoName, path string) (string, error)
 	// ListComments(repoOwner, repoName string, pr int) ([]string, error)
+	// GetPullRequestDiff returns the diff of the pull request from the provider API, for checkouts without the git history
+	GetPullRequestDiff(repoOwner, repoName string, pr int) (string, error)
+	// GetCommentBody returns the body of the comment with the header without the header, empty if there is none
+	GetCommentBody(repoOwner, repoName string, pr int, header string) (string, error)
 	PostSummaryUnderReview(repoOwner, repoName string, pr int, header string) error
 	PostSummary(repoOwner, repoName string, pr int, header, body string) error
+	// SaveComment adds or updates the comment with the header, for the marker comments not recorded in the report
+	SaveComment(repoOwner, repoName string, pr int, header, body string) error
+	// DeleteComment deletes every comment with the header
+	DeleteComment(repoOwner, repoName string, pr int, header string) error
 	PostLineFeedback(client *git.Client, repoOwner, repoName string, pr int, commitHash string, lineFeedback common.LineLevelFeedback) error
 	GetReviewRequestComments(repoOwner, repoName string, pr int) ([]common.LineLevel, error)
+	// GetClarification returns the questions asked from the author and the replies posted since
+	GetClarification(repoOwner, repoName string, pr int) (common.Clarification, error)
+	// CheckAuth validates the API token against the provider API
+	CheckAuth() error
+	// SearchMergedPullRequests returns the latest merged pull requests with any of the keywords in their title or description
+	SearchMergedPullRequests(repoOwner, repoName string, keywords []string) ([]common.HistoryPullRequest, error)
+	// CheckWriteAccess detects fork pull requests and the permissions of the token on the pull request
+	CheckWriteAccess(repoOwner, repoName string, pr int) (common.WriteAccess, error)
+	// SetCommitStatus sets the verdict of the review on the commit, replacing the previous status of the review
+	SetCommitStatus(repoOwner, repoName, commitHash string, status common.CommitStatus) error
+	// GetForcePushes returns the force pushes of the head branch, empty if the provider doesn't report them
+	GetForcePushes(repoOwner, repoName string, pr int) ([]common.ForcePush, error)
+	// MigrateComments moves the line comments outdated by a rewritten history to the lines of their code at the commit.
+	// Returns the number of moved comments.
+	MigrateComments(client *git.Client, repoOwner, repoName string, pr int, commitHash string) (int, error)
 }
 
-// getAPIToken retrieves the API token from environment variables based on provider
-func getAPIToken(provider string) (string, error) {
-	var apiToken string
-	var envVarName string
+// escapePath escapes each segment of the repository file path for the URL of a provider API
+func escapePath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for idx, segment := range segments {
+		segments[idx] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
 
-	switch provider {
-	case ProviderGitHub:
-		envVarName = "GITHUB_TOKEN"
-		apiToken = os.Getenv(envVarName)
-	case ProviderBitbucket:
-		envVarName = "BITBUCKET_TOKEN"
-		apiToken = os.Getenv(envVarName)
-	default:
-		errMsg := fmt.Sprintf("Unsupported provider: %s", provider)
-		logger.Error(errMsg)
-		return "", errors.New(errMsg)
+// getAPIToken resolves the API token of the provider
+func getAPIToken(provider string) (string, error) {
+	credential, err := ProviderCredential(provider)
+	if err != nil {
+		logger.Error(err.Error())
+		return "", err
 	}
 
-	if apiToken == "" {
-		errMsg := fmt.Sprintf("%s environment variable is not set", envVarName)
-		logger.Error(errMsg)
-		return "", errors.New(errMsg)
+	apiToken, source, err := credential.Resolve()
+	if err != nil {
+		logger.Error(err.Error())
+		return "", err
 	}
 
-	logger.Debugf("Successfully retrieved %s API token", provider)
+	logger.Debugf("Successfully retrieved %s API token from %s", provider, source)
 	return apiToken, nil
 }
 
+// ProviderCredential returns the credential of the review provider
+func ProviderCredential(provider string) (common.Credential, error) {
+	switch provider {
+	case ProviderGitHub:
+		return common.CredentialGitHub, nil
+	case ProviderBitbucket:
+		return common.CredentialBitbucket, nil
+	case ProviderGitea:
+		return common.CredentialGitea, nil
+	}
+	return common.Credential{}, fmt.Errorf("unsupported provider: %s", provider)
+}
+
 // NewReviewer creates a new review provider client
 func NewReviewer(providerName string, opts ...Option) (Reviewer, error) {
 	logger.Infof("Creating new reviewer with provider: %s", providerName)
@@ -232,6 +275,12 @@ func NewReviewer(providerName string, opts ...Option) (Reviewer, error) {
 		options = append(options, WithBaseURL(githubURL))
 	}
 
+	// Gitea and Forgejo are self-hosted, the URL of the server is always needed
+	if giteaURL := os.Getenv("GITEA_URL"); giteaURL != "" && providerName == ProviderGitea {
+		logger.Infof("Using Gitea URL: %s", giteaURL)
+		options = append(options, WithBaseURL(giteaURL))
+	}
+
 	options = append(options, opts...)
 
 	switch providerName {
@@ -241,6 +290,9 @@ func NewReviewer(providerName string, opts ...Option) (Reviewer, error) {
 	case ProviderBitbucket:
 		logger.Debug("Initializing Bitbucket reviewer")
 		reviewer, err = NewBitbucket(options...)
+	case ProviderGitea:
+		logger.Debug("Initializing Gitea reviewer")
+		reviewer, err = NewGitea(options...)
 	default:
 		errMsg := fmt.Sprintf("unsupported review provider: %s", providerName)
 		logger.Error(errMsg)
@@ -257,22 +309,20 @@ func NewReviewer(providerName string, opts ...Option) (Reviewer, error) {
 }
 
 // FormatOverallReview formats the overall review comment including nitpick comments
-func FormatOverallReview(actionableCommentCount int, nitpickComments []string) string {
+func FormatOverallReview(provider string, actionableCommentCount int, nitpickComments []string) string {
 	overallReview := strings.Builder{}
 	overallReview.WriteString("_This is an AI-generated review. Please review it carefully._\n\n")
 	overallReview.WriteString(fmt.Sprintf("**Actionable comments posted: %d**\n\n", actionableCommentCount))
 
 	if len(nitpickComments) > 0 {
-		overallReview.WriteString("<details>\n")
-		overallReview.WriteString("<summary>🧹 Nitpick comments</summary>\n")
-		overallReview.WriteString(strings.Join(nitpickComments, "\n\n---\n\n"))
-		overallReview.WriteString("</details>\n\n")
+		renderer := common.NewMarkdownRenderer(provider)
+		overallReview.WriteString(renderer.Collapsible("🧹 Nitpick comments", strings.Join(nitpickComments, "\n\n---\n\n")) + "\n\n")
 	}
 
-	return overallReview.String()
+	return common.ApplyStyle(overallReview.String())
 }
 
 // CreateCommonPRComment formats a common PR comment for line feedback
 func CreateCommonPRComment(provider string, nitpickComments []string, commentCount int) string {
-	return FormatOverallReview(commentCount, nitpickComments)
+	return FormatOverallReview(provider, commentCount, nitpickComments)
 }
@@ -0,0 +1,72 @@
+package review
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+// oauthScopesHeader lists the scopes of the token in the responses of GitHub classic tokens and Bitbucket access tokens.
+// Fine-grained tokens don't report their permissions, they are assumed to be able to post.
+const oauthScopesHeader = "X-OAuth-Scopes"
+
+// hasAnyScope returns true if the scopes header lists one of the scopes, or if the header is missing
+func hasAnyScope(header string, scopes ...string) bool {
+	if strings.TrimSpace(header) == "" {
+		return true
+	}
+	for scope := range strings.SplitSeq(header, ",") {
+		if slices.Contains(scopes, strings.TrimSpace(scope)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remediations of the missing permissions
+const (
+	githubWriteRemediation    = "add the repo scope to the token (public_repo for public repositories), or grant Pull requests: Read and write to the fine-grained token or GitHub App"
+	githubLabelsRemediation   = "grant Issues: Read and write to the token, and at least the triage role on the repository to its user"
+	bitbucketWriteRemediation = "create the access token with the Pull requests: Write scope"
+	giteaWriteRemediation     = "create the token with the write:issue and write:repository scopes, for a user with access to the repository"
+	giteaLabelsRemediation    = "grant write access to the repository to the user of the token"
+)
+
+// githubPermissions checks the permissions of the token from the scopes of classic tokens and the repository role of its user.
+// The role is missing for GitHub App tokens.
+func githubPermissions(scopesHeader string, private bool, role map[string]bool) []common.Permission {
+	scopes := []string{"repo"}
+	if !private {
+		scopes = append(scopes, "public_repo")
+	}
+	scoped := hasAnyScope(scopesHeader, scopes...)
+
+	canTriage := len(role) == 0 || role["admin"] || role["maintain"] || role["push"] || role["triage"]
+	return []common.Permission{
+		{Name: common.PermissionComment, Granted: scoped, Required: true, Remediation: githubWriteRemediation},
+		{Name: common.PermissionReview, Granted: scoped, Required: true, Remediation: githubWriteRemediation},
+		{Name: common.PermissionLabels, Granted: scoped && canTriage, Remediation: githubLabelsRemediation},
+	}
+}
+
+// bitbucketPermissions checks the permissions of the token from its scopes, Bitbucket pull requests have no labels
+func bitbucketPermissions(scopesHeader string) []common.Permission {
+	scoped := hasAnyScope(scopesHeader, "pullrequest", "pullrequest:write")
+	return []common.Permission{
+		{Name: common.PermissionComment, Granted: scoped, Required: true, Remediation: bitbucketWriteRemediation},
+		{Name: common.PermissionReview, Granted: scoped, Required: true, Remediation: bitbucketWriteRemediation},
+	}
+}
+
+// giteaPermissions checks the permissions of the user of the token on the repository.
+// Gitea doesn't report the scopes of the token, any user with read access can comment and review.
+func giteaPermissions(role map[string]bool) []common.Permission {
+	canWrite := len(role) == 0 || role["admin"] || role["push"]
+	canRead := canWrite || role["pull"]
+	return []common.Permission{
+		{Name: common.PermissionComment, Granted: canRead, Required: true, Remediation: giteaWriteRemediation},
+		{Name: common.PermissionReview, Granted: canRead, Required: true, Remediation: giteaWriteRemediation},
+		{Name: common.PermissionLabels, Granted: canWrite, Remediation: giteaLabelsRemediation},
+	}
+}
@@ -0,0 +1,87 @@
+package review
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise-plugins-ai-reviewer/common"
+)
+
+func TestHasAnyScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		scopes   []string
+		expected bool
+	}{
+		{"fine-grained token", "", []string{"repo"}, true},
+		{"matching scope", "read:org, repo, workflow", []string{"repo"}, true},
+		{"public repository", "public_repo", []string{"repo", "public_repo"}, true},
+		{"read-only token", "read:org, read:user", []string{"repo", "public_repo"}, false},
+		{"no prefix match", "repository", []string{"repo"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hasAnyScope(test.header, test.scopes...); got != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestGitHubPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		private  bool
+		role     map[string]bool
+		expected map[string]bool
+		missing  bool
+	}{
+		{"fine-grained token", "", true, nil, map[string]bool{common.PermissionComment: true, common.PermissionReview: true, common.PermissionLabels: true}, false},
+		{"classic token", "repo", true, map[string]bool{"push": true}, map[string]bool{common.PermissionComment: true, common.PermissionReview: true, common.PermissionLabels: true}, false},
+		{"read-only role", "public_repo", false, map[string]bool{"pull": true}, map[string]bool{common.PermissionComment: true, common.PermissionReview: true, common.PermissionLabels: false}, false},
+		{"public scope on private repository", "public_repo", true, nil, map[string]bool{common.PermissionComment: false, common.PermissionReview: false, common.PermissionLabels: false}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			access := common.WriteAccess{Permissions: githubPermissions(test.header, test.private, test.role)}
+			for name, granted := range test.expected {
+				if access.Granted(name) != granted {
+					t.Errorf("Expected %s granted %v", name, granted)
+				}
+			}
+			if access.MissingRequired() != test.missing {
+				t.Errorf("Expected missing required %v, got %v", test.missing, access.MissingRequired())
+			}
+		})
+	}
+}
+
+func TestBitbucketPermissions(t *testing.T) {
+	access := common.WriteAccess{Permissions: bitbucketPermissions("repository, pullrequest:write")}
+	if !access.Granted(common.PermissionComment) || !access.Granted(common.PermissionReview) || len(access.Missing()) != 0 {
+		t.Errorf("Expected the write scope to grant every permission, got %+v", access.Permissions)
+	}
+	if access.Granted(common.PermissionLabels) {
+		t.Error("Expected no labels permission on Bitbucket")
+	}
+
+	access = common.WriteAccess{Permissions: bitbucketPermissions("repository")}
+	if !access.MissingRequired() || len(access.Missing()) != 2 || access.Missing()[0].Remediation == "" {
+		t.Errorf("Expected the missing permissions with their remediation, got %+v", access.Missing())
+	}
+}
+
+func TestGiteaPermissions(t *testing.T) {
+	access := common.WriteAccess{Permissions: giteaPermissions(map[string]bool{"pull": true})}
+	if !access.Granted(common.PermissionComment) || !access.Granted(common.PermissionReview) || access.Granted(common.PermissionLabels) {
+		t.Errorf("Expected read access to comment and review without labels, got %+v", access.Permissions)
+	}
+
+	access = common.WriteAccess{Permissions: giteaPermissions(map[string]bool{"pull": false, "push": false, "admin": false})}
+	if !access.MissingRequired() {
+		t.Errorf("Expected the missing permissions without access to the repository, got %+v", access.Permissions)
+	}
+}
@@ -1,5 +1,31 @@
 package version
 
+import "runtime"
+
 // Version holds the current version of the plugin.
 // It can be set at build time using ldflags.
 var Version = "0.2.2"
+
+// Commit is the SHA of the commit the plugin was built from, set at build time using ldflags
+var Commit = ""
+
+// BuildDate is the RFC 3339 date of the build, set at build time using ldflags
+var BuildDate = ""
+
+// Info is the build metadata of the plugin
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata of the plugin
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}